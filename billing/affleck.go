@@ -4,6 +4,7 @@ import (
 	"evsys/entity"
 	"evsys/internal"
 	"fmt"
+	"sync"
 )
 
 type Database interface {
@@ -16,6 +17,7 @@ type Affleck struct {
 	database    Database
 	logger      internal.LogHandler
 	defaultPlan *entity.PaymentPlan
+	mutex       sync.Mutex
 }
 
 func NewAffleck() *Affleck {
@@ -95,6 +97,32 @@ func (a *Affleck) OnMeterValue(transaction *entity.Transaction, meterValue *enti
 	return nil
 }
 
+// Tariff returns the payment plan a transaction of username would be charged by now, to be shown
+// to the driver before charging starts; nil when there is none
+func (a *Affleck) Tariff(username string) *entity.PaymentPlan {
+	if a.database == nil {
+		return nil
+	}
+	if username != "" {
+		plan, _ := a.database.GetUserPaymentPlan(username)
+		if plan != nil && plan.IsCurrentTimeRange() {
+			return plan
+		}
+	}
+	return a.defaultPaymentPlan()
+}
+
+// defaultPaymentPlan reads the default plan once it is first needed, and again while there is none;
+// transactions of several charge points ask for it at the same time
+func (a *Affleck) defaultPaymentPlan() *entity.PaymentPlan {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.defaultPlan == nil {
+		a.defaultPlan, _ = a.database.GetDefaultPaymentPlan()
+	}
+	return a.defaultPlan
+}
+
 func (a *Affleck) choosePaymentPlan(transaction *entity.Transaction) error {
 	if a.database == nil {
 		return nil
@@ -105,15 +133,7 @@ func (a *Affleck) choosePaymentPlan(transaction *entity.Transaction) error {
 	if transaction.Username == "" {
 		return nil
 	}
-	plan, _ := a.database.GetUserPaymentPlan(transaction.Username)
-	if plan != nil && plan.IsCurrentTimeRange() {
-		transaction.Plan = plan
-		return nil
-	}
-	if a.defaultPlan == nil {
-		a.defaultPlan, _ = a.database.GetDefaultPaymentPlan()
-	}
-	transaction.Plan = a.defaultPlan
+	transaction.Plan = a.Tariff(transaction.Username)
 	if transaction.Plan == nil {
 		return fmt.Errorf("no payment plan for %s", transaction.Username)
 	}
@@ -0,0 +1,518 @@
+package campaign
+
+import (
+	"encoding/json"
+	"evsys/entity"
+	"evsys/internal"
+	"evsys/ocpp/common"
+	"evsys/ocpp/v16/firmware"
+	"evsys/types"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	featureName = "FirmwareCampaign"
+
+	// API commands served by the manager; they address a set of charge points rather than one
+	StartFeatureName  = "StartFirmwareCampaign"
+	GetFeatureName    = "GetFirmwareCampaign"
+	CancelFeatureName = "CancelFirmwareCampaign"
+
+	defaultMaxConcurrent = 10
+	// sendTimeout bounds the wait for the (empty) UpdateFirmware answer. A charge point that stays
+	// silent may still have taken the request, so silence is recorded as sent, not as failure.
+	sendTimeout = 30 * time.Second
+	// offlineRetryInterval is how long a charge point that was offline when its turn came is left
+	// alone before the next attempt.
+	offlineRetryInterval = 5 * time.Minute
+	// targetTimeout is how long a charge point may go without reporting progress, counted from the
+	// retrieve date, before its slot is given to the next one.
+	targetTimeout = 3 * time.Hour
+	tickInterval  = time.Minute
+)
+
+// Spec is the API payload of StartFirmwareCampaign. Charge points are selected by model, vendor
+// and location - all given selectors must match - or listed explicitly.
+type Spec struct {
+	Location       string          `json:"location"`
+	RetrieveDate   *types.DateTime `json:"retrieveDate,omitempty"`
+	Retries        *int            `json:"retries,omitempty"`
+	RetryInterval  *int            `json:"retryInterval,omitempty"`
+	Version        string          `json:"version,omitempty"`
+	Model          string          `json:"model,omitempty"`
+	Vendor         string          `json:"vendor,omitempty"`
+	LocationId     string          `json:"locationId,omitempty"`
+	ChargePointIds []string        `json:"chargePointIds,omitempty"`
+	MaxConcurrent  int             `json:"maxConcurrent,omitempty"`
+}
+
+// Report is a campaign as returned by the API, with its targets counted per status.
+type Report struct {
+	*entity.FirmwareCampaign
+	Progress map[string]int `json:"progress"`
+}
+
+// Manager rolls firmware out to a set of charge points, keeping at most MaxConcurrent of them
+// between UpdateFirmware and an outcome at any time, and follows each one through its
+// FirmwareStatusNotifications to the version it reports on the next boot.
+type Manager struct {
+	database  Repository
+	server    Handler
+	log       internal.LogHandler
+	campaigns map[int]*entity.FirmwareCampaign // campaigns with targets still to be decided
+	lastId    int
+	// fields rather than constants so a test can drive the timeouts without waiting them out
+	sendTimeout   time.Duration
+	targetTimeout time.Duration
+	now           func() time.Time
+	mutex         sync.Mutex
+}
+
+func NewManager(database Repository, server Handler, log internal.LogHandler) *Manager {
+	return &Manager{
+		database:      database,
+		server:        server,
+		log:           log,
+		campaigns:     make(map[int]*entity.FirmwareCampaign),
+		sendTimeout:   sendTimeout,
+		targetTimeout: targetTimeout,
+		now:           time.Now,
+	}
+}
+
+// Load continues the ids of the previous process and picks up the campaigns it left running. It has
+// to run before the API accepts commands: a campaign numbered from scratch would take the id of a
+// stored one.
+func (m *Manager) Load() error {
+	if m.database == nil {
+		return nil
+	}
+	last, err := m.database.GetLastFirmwareCampaign()
+	if err != nil {
+		return fmt.Errorf("load last firmware campaign: %v", err)
+	}
+	running, err := m.database.GetRunningFirmwareCampaigns()
+	if err != nil {
+		return fmt.Errorf("load running firmware campaigns: %v", err)
+	}
+	m.mutex.Lock()
+	if last != nil {
+		m.lastId = last.Id
+	}
+	for _, c := range running {
+		m.campaigns[c.Id] = c
+	}
+	m.mutex.Unlock()
+	if len(running) > 0 {
+		m.log.FeatureEvent(featureName, "", fmt.Sprintf("resumed %d running campaigns", len(running)))
+	}
+	return nil
+}
+
+// OnSystemStart checks the running campaigns periodically for the lifetime of the process.
+func (m *Manager) OnSystemStart() {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+	for {
+		m.tick()
+		<-ticker.C
+	}
+}
+
+// HandleCommand serves the campaign API commands and returns the JSON answer.
+func (m *Manager) HandleCommand(command, payload string) ([]byte, error) {
+	switch command {
+	case StartFeatureName:
+		var spec Spec
+		if err := json.Unmarshal([]byte(payload), &spec); err != nil {
+			return nil, fmt.Errorf("invalid payload")
+		}
+		report, err := m.Start(&spec)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(report)
+	case GetFeatureName:
+		if strings.TrimSpace(payload) == "" {
+			return json.Marshal(m.List())
+		}
+		id, err := strconv.Atoi(strings.TrimSpace(payload))
+		if err != nil {
+			return nil, fmt.Errorf("invalid campaign id")
+		}
+		report, err := m.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(report)
+	case CancelFeatureName:
+		id, err := strconv.Atoi(strings.TrimSpace(payload))
+		if err != nil {
+			return nil, fmt.Errorf("invalid campaign id")
+		}
+		report, err := m.Cancel(id)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(report)
+	default:
+		return nil, fmt.Errorf("unknown campaign command: %s", command)
+	}
+}
+
+func (m *Manager) Start(spec *Spec) (*Report, error) {
+	if m.database == nil {
+		return nil, fmt.Errorf("firmware campaigns need the database")
+	}
+	if u, err := url.ParseRequestURI(spec.Location); err != nil || u.Scheme == "" {
+		return nil, fmt.Errorf("invalid firmware location")
+	}
+	if spec.Model == "" && spec.Vendor == "" && spec.LocationId == "" && len(spec.ChargePointIds) == 0 {
+		return nil, fmt.Errorf("select charge points by model, vendor, location or id")
+	}
+	chargePoints, err := m.database.GetChargePoints()
+	if err != nil {
+		return nil, fmt.Errorf("get charge points: %v", err)
+	}
+	selected := selectChargePoints(chargePoints, spec)
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no charge points match the selection")
+	}
+
+	now := m.now()
+	c := &entity.FirmwareCampaign{
+		Location:      spec.Location,
+		Version:       spec.Version,
+		RetrieveDate:  now,
+		Retries:       spec.Retries,
+		RetryInterval: spec.RetryInterval,
+		Model:         spec.Model,
+		Vendor:        spec.Vendor,
+		LocationId:    spec.LocationId,
+		MaxConcurrent: spec.MaxConcurrent,
+		Status:        entity.CampaignRunning,
+		TimeCreated:   now,
+		Targets:       make([]*entity.FirmwareTarget, 0, len(selected)),
+	}
+	if spec.RetrieveDate != nil {
+		c.RetrieveDate = spec.RetrieveDate.Time
+	}
+	if c.MaxConcurrent <= 0 {
+		c.MaxConcurrent = defaultMaxConcurrent
+	}
+	for _, cp := range selected {
+		target := &entity.FirmwareTarget{
+			ChargePointId:   cp.Id,
+			Status:          entity.TargetPending,
+			PreviousVersion: cp.FirmwareVersion,
+			TimeUpdated:     now,
+		}
+		if c.Version != "" && cp.FirmwareVersion == c.Version {
+			target.Status = entity.TargetVerified
+			target.ReportedVersion = cp.FirmwareVersion
+			target.Info = "already running the version"
+		}
+		c.Targets = append(c.Targets, target)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.lastId++
+	c.Id = m.lastId
+	if err = m.database.AddFirmwareCampaign(c); err != nil {
+		return nil, fmt.Errorf("save campaign: %v", err)
+	}
+	m.campaigns[c.Id] = c
+	// the location is left out of the log: a firmware URL may carry credentials
+	m.log.FeatureEvent(featureName, "", fmt.Sprintf("campaign #%d: %d charge points, %d at a time",
+		c.Id, len(c.Targets), c.MaxConcurrent))
+	m.dispatch(c)
+	return report(c), nil
+}
+
+func (m *Manager) Get(id int) (*Report, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if c, ok := m.campaigns[id]; ok {
+		return report(c), nil
+	}
+	if m.database == nil {
+		return nil, fmt.Errorf("campaign %d not found", id)
+	}
+	c, err := m.database.GetFirmwareCampaign(id)
+	if err != nil {
+		return nil, fmt.Errorf("campaign %d not found", id)
+	}
+	return report(c), nil
+}
+
+// List returns the campaigns still in progress.
+func (m *Manager) List() []*Report {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	reports := make([]*Report, 0, len(m.campaigns))
+	for _, c := range m.campaigns {
+		reports = append(reports, report(c))
+	}
+	return reports
+}
+
+// Cancel stops a campaign from sending UpdateFirmware to the charge points still waiting for their
+// turn. Charge points already updating cannot be called back in OCPP 1.6 and are followed to
+// their outcome.
+func (m *Manager) Cancel(id int) (*Report, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	c, ok := m.campaigns[id]
+	if !ok || c.Status != entity.CampaignRunning {
+		return nil, fmt.Errorf("campaign %d is not running", id)
+	}
+	c.Status = entity.CampaignCancelled
+	for _, t := range c.Targets {
+		if t.Status == entity.TargetPending || t.Status == entity.TargetOffline {
+			m.setStatus(t, entity.TargetCancelled, "")
+		}
+	}
+	m.log.FeatureEvent(featureName, "", fmt.Sprintf("campaign #%d cancelled", c.Id))
+	m.update(c)
+	return report(c), nil
+}
+
+// OnFirmwareStatus follows a charge point through the statuses it reports while updating.
+func (m *Manager) OnFirmwareStatus(chargePointId string, status firmware.Status) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, c := range m.campaigns {
+		t := activeTarget(c, chargePointId)
+		if t == nil {
+			continue
+		}
+		switch status {
+		case firmware.StatusDownloading, firmware.StatusDownloaded, firmware.StatusInstalling, firmware.StatusInstalled,
+			firmware.StatusDownloadFailed, firmware.StatusInstallationFailed:
+			m.setStatus(t, string(status), "")
+		default:
+			continue
+		}
+		m.log.FeatureEvent(featureName, chargePointId, fmt.Sprintf("campaign #%d: %s", c.Id, status))
+		m.dispatch(c)
+	}
+}
+
+/*
+OnBootNotification decides the outcome for a charge point that boots while updating.
+
+A charge point that already reports the expected version is verified whatever it reported before;
+some chargers install without sending every intermediate status. Otherwise a boot only counts once
+installation has started: a charger may well reboot while still downloading. Without an expected
+version, any change from the version the campaign started with is accepted.
+*/
+func (m *Manager) OnBootNotification(chargePointId string, firmwareVersion string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, c := range m.campaigns {
+		t := activeTarget(c, chargePointId)
+		if t == nil || t.Status == entity.TargetSending {
+			continue
+		}
+		t.ReportedVersion = firmwareVersion
+		switch {
+		case c.Version != "" && firmwareVersion == c.Version:
+			m.setStatus(t, entity.TargetVerified, "")
+		case t.Status != entity.TargetInstalling && t.Status != entity.TargetInstalled:
+			continue
+		case c.Version == "" && firmwareVersion != t.PreviousVersion:
+			m.setStatus(t, entity.TargetVerified, "")
+		default:
+			m.setStatus(t, entity.TargetVersionMismatch, fmt.Sprintf("expected %q", c.Version))
+		}
+		m.log.FeatureEvent(featureName, chargePointId, fmt.Sprintf("campaign #%d: %s; version %s", c.Id, t.Status, firmwareVersion))
+		m.dispatch(c)
+	}
+}
+
+// tick times out charge points that stopped reporting and retries those that were offline.
+func (m *Manager) tick() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := m.now()
+	for _, c := range m.campaigns {
+		for _, t := range c.Targets {
+			if !t.IsActive() || t.Status == entity.TargetSending {
+				continue
+			}
+			since := c.RetrieveDate
+			if t.TimeUpdated.After(since) {
+				since = t.TimeUpdated
+			}
+			if now.Sub(since) > m.targetTimeout {
+				m.setStatus(t, entity.TargetTimedOut, fmt.Sprintf("no progress after %s", t.Status))
+			}
+		}
+		m.dispatch(c)
+	}
+}
+
+// dispatch sends UpdateFirmware to as many waiting charge points as the campaign has free slots,
+// closes the campaign once every target has an outcome, and stores it. Called with m.mutex held.
+func (m *Manager) dispatch(c *entity.FirmwareCampaign) {
+	if c.Status == entity.CampaignRunning {
+		active := 0
+		for _, t := range c.Targets {
+			if t.IsActive() {
+				active++
+			}
+		}
+		now := m.now()
+		for _, t := range c.Targets {
+			if active >= c.MaxConcurrent {
+				break
+			}
+			if t.Status == entity.TargetOffline && now.Sub(t.TimeUpdated) < offlineRetryInterval {
+				continue
+			}
+			if t.Status != entity.TargetPending && t.Status != entity.TargetOffline {
+				continue
+			}
+			m.setStatus(t, entity.TargetSending, "")
+			active++
+			go m.send(c.Id, t.ChargePointId, m.updateRequest(c))
+		}
+	}
+
+	done := true
+	for _, t := range c.Targets {
+		if !t.IsDone() {
+			done = false
+			break
+		}
+	}
+	if done {
+		if c.Status == entity.CampaignRunning {
+			c.Status = entity.CampaignFinished
+		}
+		c.TimeFinished = m.now()
+		delete(m.campaigns, c.Id)
+		m.log.FeatureEvent(featureName, "", fmt.Sprintf("campaign #%d %s: %v", c.Id, strings.ToLower(c.Status), c.Progress()))
+	}
+	m.update(c)
+}
+
+func (m *Manager) send(campaignId int, chargePointId string, request *firmware.UpdateFirmwareRequest) {
+	response, release, err := m.server.SendRequestWithResponse(chargePointId, request)
+	info := ""
+	status := entity.TargetSent
+	if err != nil {
+		status = entity.TargetOffline
+		info = err.Error()
+	} else {
+		select {
+		case answer := <-response:
+			if answer.Err != nil {
+				status = entity.TargetRejected
+				info = answer.Err.Error()
+			}
+		case <-time.After(m.sendTimeout):
+			info = "no response to UpdateFirmware"
+		}
+		release()
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	c, ok := m.campaigns[campaignId]
+	if !ok {
+		return
+	}
+	t := activeTarget(c, chargePointId)
+	if t == nil || t.Status != entity.TargetSending {
+		return
+	}
+	m.setStatus(t, status, info)
+	m.log.FeatureEvent(firmware.UpdateFirmwareFeatureName, chargePointId, fmt.Sprintf("campaign #%d: %s %s", campaignId, status, info))
+	m.dispatch(c)
+}
+
+func (m *Manager) updateRequest(c *entity.FirmwareCampaign) *firmware.UpdateFirmwareRequest {
+	request := firmware.NewUpdateFirmwareRequest(c.Location, types.NewDateTime(c.RetrieveDate))
+	request.Retries = c.Retries
+	request.RetryInterval = c.RetryInterval
+	return request
+}
+
+func (m *Manager) setStatus(t *entity.FirmwareTarget, status, info string) {
+	t.Status = status
+	t.Info = info
+	t.TimeUpdated = m.now()
+}
+
+func (m *Manager) update(c *entity.FirmwareCampaign) {
+	if m.database == nil {
+		return
+	}
+	if err := m.database.UpdateFirmwareCampaign(c); err != nil {
+		m.log.Error(fmt.Sprintf("update firmware campaign #%d", c.Id), err)
+	}
+}
+
+func activeTarget(c *entity.FirmwareCampaign, chargePointId string) *entity.FirmwareTarget {
+	for _, t := range c.Targets {
+		if t.ChargePointId == chargePointId && t.IsActive() {
+			return t
+		}
+	}
+	return nil
+}
+
+// selectChargePoints picks the charge points a campaign addresses. Campaigns send the 1.6
+// UpdateFirmware, so 2.x stations are left out whatever the selection says.
+func selectChargePoints(chargePoints []*entity.ChargePoint, spec *Spec) []*entity.ChargePoint {
+	listed := make(map[string]bool, len(spec.ChargePointIds))
+	for _, id := range spec.ChargePointIds {
+		listed[id] = true
+	}
+	selected := make([]*entity.ChargePoint, 0)
+	for _, cp := range chargePoints {
+		if common.ProtocolVersion(cp.ProtocolVersion).IsOCPP2() {
+			continue
+		}
+		if len(listed) > 0 && !listed[cp.Id] {
+			continue
+		}
+		if spec.Model != "" && !strings.EqualFold(cp.Model, spec.Model) {
+			continue
+		}
+		if spec.Vendor != "" && !strings.EqualFold(cp.Vendor, spec.Vendor) {
+			continue
+		}
+		if spec.LocationId != "" && cp.LocationId != spec.LocationId {
+			continue
+		}
+		selected = append(selected, cp)
+	}
+	return selected
+}
+
+// report copies the campaign, so it can be encoded after the lock is released.
+func report(c *entity.FirmwareCampaign) *Report {
+	snapshot := *c
+	snapshot.Targets = make([]*entity.FirmwareTarget, len(c.Targets))
+	for i, t := range c.Targets {
+		target := *t
+		snapshot.Targets[i] = &target
+	}
+	return &Report{FirmwareCampaign: &snapshot, Progress: c.Progress()}
+}
@@ -0,0 +1,300 @@
+package campaign
+
+import (
+	"errors"
+	"evsys/entity"
+	"evsys/ocpp"
+	"evsys/ocpp/common"
+	"evsys/ocpp/v16/firmware"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubRepo keeps stored campaigns, so a finished one can still be read back through Get.
+type stubRepo struct {
+	chargePoints []*entity.ChargePoint
+	campaigns    map[int]*entity.FirmwareCampaign
+	lastErr      error
+}
+
+func (s *stubRepo) GetChargePoints() ([]*entity.ChargePoint, error) {
+	return s.chargePoints, nil
+}
+
+func (s *stubRepo) GetLastFirmwareCampaign() (*entity.FirmwareCampaign, error) {
+	if s.lastErr != nil {
+		return nil, s.lastErr
+	}
+	var last *entity.FirmwareCampaign
+	for _, c := range s.campaigns {
+		if last == nil || c.Id > last.Id {
+			last = c
+		}
+	}
+	return last, nil
+}
+
+func (s *stubRepo) GetFirmwareCampaign(id int) (*entity.FirmwareCampaign, error) {
+	if c, ok := s.campaigns[id]; ok {
+		return c, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func (s *stubRepo) GetRunningFirmwareCampaigns() ([]*entity.FirmwareCampaign, error) {
+	return nil, nil
+}
+
+// The manager only writes with its mutex held, so the map needs no guard of its own.
+func (s *stubRepo) AddFirmwareCampaign(c *entity.FirmwareCampaign) error {
+	s.campaigns[c.Id] = c
+	return nil
+}
+
+func (s *stubRepo) UpdateFirmwareCampaign(c *entity.FirmwareCampaign) error {
+	s.campaigns[c.Id] = c
+	return nil
+}
+
+// stubServer answers every UpdateFirmware at once, except for charge points listed as offline.
+// The manager sends from its own goroutines, hence the mutex.
+type stubServer struct {
+	mutex   sync.Mutex
+	offline map[string]bool
+	sent    []string
+}
+
+func (s *stubServer) SendRequestWithResponse(clientId string, _ ocpp.Request) (<-chan ocpp.Answer, func(), error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.offline[clientId] {
+		return nil, nil, errors.New("charge point not available")
+	}
+	s.sent = append(s.sent, clientId)
+	response := make(chan ocpp.Answer, 1)
+	response <- ocpp.Answer{Payload: "{}"}
+	return response, func() {}, nil
+}
+
+func (s *stubServer) sentTo() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string{}, s.sent...)
+}
+
+type stubLog struct{}
+
+func (stubLog) FeatureEvent(_, _, _ string) {}
+func (stubLog) RawDataEvent(_, _ string)    {}
+func (stubLog) Debug(_ string)              {}
+func (stubLog) Warn(_ string)               {}
+func (stubLog) Error(_ string, _ error)     {}
+
+func newTestManager(chargePoints ...*entity.ChargePoint) (*Manager, *stubServer) {
+	server := &stubServer{offline: map[string]bool{}}
+	return NewManager(&stubRepo{chargePoints: chargePoints, campaigns: map[int]*entity.FirmwareCampaign{}}, server, stubLog{}), server
+}
+
+func chargePoint(id, model, version string) *entity.ChargePoint {
+	return &entity.ChargePoint{Id: id, Model: model, FirmwareVersion: version}
+}
+
+// targetStatus reads a target's status the way the API does.
+func targetStatus(t *testing.T, m *Manager, campaignId int, chargePointId string) string {
+	t.Helper()
+	r, err := m.Get(campaignId)
+	if err != nil {
+		t.Fatalf("get campaign: %v", err)
+	}
+	for _, target := range r.Targets {
+		if target.ChargePointId == chargePointId {
+			return target.Status
+		}
+	}
+	t.Fatalf("%s is not a target of campaign %d", chargePointId, campaignId)
+	return ""
+}
+
+// waitForStatus blocks until a target reaches status; the answer to UpdateFirmware is handled on
+// the goroutine that sent it.
+func waitForStatus(t *testing.T, m *Manager, campaignId int, chargePointId, status string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if targetStatus(t, m, campaignId, chargePointId) == status {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("%s status = %s, want %s", chargePointId, targetStatus(t, m, campaignId, chargePointId), status)
+}
+
+func TestCampaignSelectsByModelAndSkipsUpToDate(t *testing.T) {
+	m, server := newTestManager(
+		chargePoint("CP1", "AC22", "1.0"),
+		chargePoint("CP2", "AC22", "2.0"),
+		chargePoint("CP3", "DC50", "1.0"),
+	)
+	r, err := m.Start(&Spec{Location: "https://fw.example.com/ac22.bin", Version: "2.0", Model: "ac22"})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if len(r.Targets) != 2 {
+		t.Fatalf("campaign has %d targets, want the 2 AC22 charge points", len(r.Targets))
+	}
+	if status := targetStatus(t, m, r.Id, "CP2"); status != entity.TargetVerified {
+		t.Errorf("CP2 already on 2.0: status = %s, want %s", status, entity.TargetVerified)
+	}
+	waitForStatus(t, m, r.Id, "CP1", entity.TargetSent)
+	if sent := server.sentTo(); len(sent) != 1 || sent[0] != "CP1" {
+		t.Errorf("UpdateFirmware sent to %v, want only CP1", sent)
+	}
+}
+
+// A campaign sends the 1.6 UpdateFirmware, which a 2.x station cannot take.
+func TestCampaignSkipsOCPP2Stations(t *testing.T) {
+	station201 := chargePoint("CP2", "AC22", "1.0")
+	station201.ProtocolVersion = string(common.OCPP201)
+	station21 := chargePoint("CP3", "AC22", "1.0")
+	station21.ProtocolVersion = string(common.OCPP21)
+	station16 := chargePoint("CP4", "AC22", "1.0")
+	station16.ProtocolVersion = string(common.OCPP16)
+	m, server := newTestManager(chargePoint("CP1", "AC22", "1.0"), station201, station21, station16)
+
+	r, err := m.Start(&Spec{Location: "https://fw.example.com/ac22.bin", Version: "2.0", Model: "AC22"})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if len(r.Targets) != 2 {
+		t.Fatalf("campaign has %d targets, want the 2 charge points on 1.6", len(r.Targets))
+	}
+	waitForStatus(t, m, r.Id, "CP1", entity.TargetSent)
+	waitForStatus(t, m, r.Id, "CP4", entity.TargetSent)
+	for _, id := range server.sentTo() {
+		if id == "CP2" || id == "CP3" {
+			t.Errorf("UpdateFirmware sent to the 2.x station %s", id)
+		}
+	}
+
+	if _, err = m.Start(&Spec{Location: "https://fw.example.com/ac22.bin", ChargePointIds: []string{"CP2"}}); err == nil {
+		t.Error("a campaign of 2.x stations only must be refused")
+	}
+}
+
+func TestCampaignRejectsMissingSelection(t *testing.T) {
+	m, _ := newTestManager(chargePoint("CP1", "AC22", "1.0"))
+	if _, err := m.Start(&Spec{Location: "https://fw.example.com/ac22.bin"}); err == nil {
+		t.Error("a campaign without any selector must not address every charge point")
+	}
+	if _, err := m.Start(&Spec{Location: "fw.bin", Model: "AC22"}); err == nil {
+		t.Error("a location without a scheme must be refused")
+	}
+}
+
+func TestCampaignKeepsConcurrencyBound(t *testing.T) {
+	m, server := newTestManager(
+		chargePoint("CP1", "AC22", "1.0"),
+		chargePoint("CP2", "AC22", "1.0"),
+		chargePoint("CP3", "AC22", "1.0"),
+	)
+	r, err := m.Start(&Spec{Location: "https://fw.example.com/ac22.bin", Model: "AC22", MaxConcurrent: 2})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	waitForStatus(t, m, r.Id, "CP1", entity.TargetSent)
+	waitForStatus(t, m, r.Id, "CP2", entity.TargetSent)
+	if status := targetStatus(t, m, r.Id, "CP3"); status != entity.TargetPending {
+		t.Fatalf("CP3 status = %s, want %s while both slots are taken", status, entity.TargetPending)
+	}
+
+	m.OnFirmwareStatus("CP1", firmware.StatusDownloading)
+	if status := targetStatus(t, m, r.Id, "CP3"); status != entity.TargetPending {
+		t.Fatalf("CP3 status = %s, want %s: a downloading charge point still holds its slot", status, entity.TargetPending)
+	}
+
+	m.OnFirmwareStatus("CP1", firmware.StatusDownloadFailed)
+	waitForStatus(t, m, r.Id, "CP3", entity.TargetSent)
+	if sent := server.sentTo(); len(sent) != 3 {
+		t.Errorf("UpdateFirmware sent %d times, want 3", len(sent))
+	}
+}
+
+func TestCampaignVerifiesVersionOnBoot(t *testing.T) {
+	m, _ := newTestManager(
+		chargePoint("CP1", "AC22", "1.0"),
+		chargePoint("CP2", "AC22", "1.0"),
+	)
+	r, err := m.Start(&Spec{Location: "https://fw.example.com/ac22.bin", Version: "2.0", Model: "AC22"})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	waitForStatus(t, m, r.Id, "CP1", entity.TargetSent)
+	waitForStatus(t, m, r.Id, "CP2", entity.TargetSent)
+
+	// a reboot while downloading says nothing about the installation
+	m.OnFirmwareStatus("CP1", firmware.StatusDownloading)
+	m.OnBootNotification("CP1", "1.0")
+	if status := targetStatus(t, m, r.Id, "CP1"); status != entity.TargetDownloading {
+		t.Fatalf("CP1 status = %s after a boot mid-download, want %s", status, entity.TargetDownloading)
+	}
+
+	m.OnFirmwareStatus("CP1", firmware.StatusInstalling)
+	m.OnBootNotification("CP1", "2.0")
+	m.OnFirmwareStatus("CP2", firmware.StatusInstalled)
+	m.OnBootNotification("CP2", "1.0")
+
+	report, err := m.Get(r.Id)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if report.Progress[entity.TargetVerified] != 1 || report.Progress[entity.TargetVersionMismatch] != 1 {
+		t.Errorf("progress = %v, want one verified and one version mismatch", report.Progress)
+	}
+	if report.Status != entity.CampaignFinished {
+		t.Errorf("campaign status = %s, want %s", report.Status, entity.CampaignFinished)
+	}
+}
+
+func TestCampaignWaitsForOfflineChargePoint(t *testing.T) {
+	m, server := newTestManager(chargePoint("CP1", "AC22", "1.0"))
+	server.offline["CP1"] = true
+
+	r, err := m.Start(&Spec{Location: "https://fw.example.com/ac22.bin", ChargePointIds: []string{"CP1"}})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	waitForStatus(t, m, r.Id, "CP1", entity.TargetOffline)
+
+	// the next check comes before the retry interval is up
+	m.tick()
+	if status := targetStatus(t, m, r.Id, "CP1"); status != entity.TargetOffline {
+		t.Errorf("CP1 status = %s, want %s until the retry interval has passed", status, entity.TargetOffline)
+	}
+	if report, _ := m.Get(r.Id); report.Status != entity.CampaignRunning {
+		t.Errorf("campaign status = %s, want it running while a charge point is offline", report.Status)
+	}
+}
+
+func TestCampaignIdsContinueAfterLoad(t *testing.T) {
+	repo := &stubRepo{
+		chargePoints: []*entity.ChargePoint{chargePoint("CP1", "AC22", "1.0")},
+		campaigns:    map[int]*entity.FirmwareCampaign{7: {Id: 7}},
+	}
+	m := NewManager(repo, &stubServer{offline: map[string]bool{}}, stubLog{})
+	if err := m.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	r, err := m.Start(&Spec{Location: "https://fw.example.com/ac22.bin", Version: "2.0", Model: "AC22"})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if r.Id != 8 {
+		t.Errorf("campaign id = %d, want 8 after the stored 7", r.Id)
+	}
+
+	repo.lastErr = errors.New("timeout")
+	if err = NewManager(repo, &stubServer{}, stubLog{}).Load(); err == nil {
+		t.Error("Load ignored a failing database")
+	}
+}
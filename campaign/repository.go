@@ -0,0 +1,12 @@
+package campaign
+
+import "evsys/entity"
+
+type Repository interface {
+	GetChargePoints() ([]*entity.ChargePoint, error)
+	GetLastFirmwareCampaign() (*entity.FirmwareCampaign, error)
+	GetFirmwareCampaign(id int) (*entity.FirmwareCampaign, error)
+	GetRunningFirmwareCampaigns() ([]*entity.FirmwareCampaign, error)
+	AddFirmwareCampaign(campaign *entity.FirmwareCampaign) error
+	UpdateFirmwareCampaign(campaign *entity.FirmwareCampaign) error
+}
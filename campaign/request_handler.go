@@ -0,0 +1,14 @@
+package campaign
+
+import (
+	"evsys/ocpp"
+)
+
+type Handler interface {
+	// SendRequestWithResponse queues a request and returns the channel carrying
+	// the charge point's answer, its raw CallResult payload or its CallError. The
+	// error reports only whether the request could be queued, which is how an
+	// offline charge point is told apart from a slow one. release must be called
+	// once the caller stops listening.
+	SendRequestWithResponse(clientId string, request ocpp.Request) (response <-chan ocpp.Answer, release func(), err error)
+}
@@ -0,0 +1,619 @@
+package customerdata
+
+import (
+	"encoding/json"
+	"evsys/entity"
+	"evsys/internal"
+	"evsys/ocpp"
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+	"evsys/ocpp/v201/diagnostics"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	featureName = "CustomerData"
+
+	// API commands served by the manager
+	InformationFeatureName = "RequestCustomerInformation"
+	ExportFeatureName      = "ExportCustomerData"
+	EraseFeatureName       = "EraseCustomerData"
+
+	// Anonymized takes the place of the id tag of an erased customer in the records that are kept,
+	// such as the transactions billing still needs
+	Anonymized = "anonymized"
+
+	// minLogTagLength is the shortest id tag looked for in the log; a shorter one matches unrelated
+	// numbers in the text, and those lines would be exported or rewritten along with the customer's.
+	minLogTagLength = 6
+
+	sendTimeout = 30 * time.Second
+	// retryDelay gives a charge point that has just booted time to settle before the erasures it
+	// missed are sent.
+	retryDelay = 10 * time.Second
+	// partTimeout is how long a report may wait for its next part; the parts that arrived are
+	// dropped after it.
+	partTimeout  = 10 * time.Minute
+	tickInterval = time.Minute
+)
+
+// InformationSpec is the API payload of RequestCustomerInformation: it asks one OCPP 2.0.1 charge
+// point to report, clear, or both, what it holds about an id tag.
+type InformationSpec struct {
+	ChargePointId string `json:"chargePointId"`
+	IdTag         string `json:"idTag"`
+	TokenType     string `json:"tokenType,omitempty"` // ISO14443 when empty
+	Report        bool   `json:"report"`
+	Clear         bool   `json:"clear"`
+}
+
+// CustomerSpec is the API payload of ExportCustomerData and EraseCustomerData. The token type is
+// what the charge points are asked to clear; ISO14443 when empty.
+type CustomerSpec struct {
+	IdTag     string `json:"idTag"`
+	TokenType string `json:"tokenType,omitempty"`
+}
+
+// InformationStatus is the charge point's answer to a CustomerInformation request.
+type InformationStatus struct {
+	ChargePointId string `json:"chargePointId"`
+	RequestId     int    `json:"requestId"`
+	Status        string `json:"status"`
+}
+
+// Report is everything the central system holds about an id tag.
+type Report struct {
+	IdTag        string                        `json:"idTag"`
+	GeneratedAt  time.Time                     `json:"generatedAt"`
+	UserTag      *entity.UserTag               `json:"userTag,omitempty"`
+	Transactions []*entity.Transaction         `json:"transactions"`
+	Reservations []*entity.Reservation         `json:"reservations"`
+	LocalLists   []string                      `json:"localLists"` // charge points whose local list holds the tag
+	LogMessages  []*internal.FeatureLogMessage `json:"logMessages"`
+	// LogMessagesSkipped is set for a tag too short to be told apart in the text of the log
+	LogMessagesSkipped bool                          `json:"logMessagesSkipped,omitempty"`
+	StationReports     []*entity.CustomerInformation `json:"stationReports"`
+}
+
+// Erasure tells what an EraseCustomerData changed.
+type Erasure struct {
+	UserTag            bool            `json:"userTag"`      // the tag was deleted
+	Transactions       int             `json:"transactions"` // anonymized
+	Reservations       int             `json:"reservations"` // anonymized
+	StationReports     int             `json:"stationReports"`
+	LogMessages        int             `json:"logMessages"` // anonymized
+	LogMessagesSkipped bool            `json:"logMessagesSkipped,omitempty"`
+	LocalLists         []StationResult `json:"localLists"`
+	Stations           []StationResult `json:"stations"` // asked to clear the customer
+}
+
+// StationResult is the outcome on one charge point; Pending ones are retried when it next boots.
+type StationResult struct {
+	ChargePointId string `json:"chargePointId"`
+	Status        string `json:"status"`
+}
+
+const statusPending = "Pending"
+
+// report collects the NotifyCustomerInformation parts of one request until the last of them has
+// arrived.
+type report struct {
+	idTag       string
+	parts       map[int]string
+	lastSeqNo   int // of the part with tbc false, -1 until it arrives
+	generatedAt time.Time
+	updated     time.Time
+}
+
+type reportKey struct {
+	chargePointId string
+	requestId     int
+}
+
+// pending is what an erasure could not do on a charge point that was not connected.
+type pending struct {
+	tokens    []v201.IdToken
+	localList bool
+}
+
+// Manager answers the requests of customers about their data. It exports what the central system
+// holds about an id tag, asks OCPP 2.0.1 charge points for what they hold, and erases a customer:
+// the user tag goes, the local lists are synced without it, the charge points that saw it clear it,
+// and the records that must be kept have the tag and the user replaced with Anonymized.
+type Manager struct {
+	database   Repository
+	server     Handler
+	localLists LocalLists
+	log        internal.LogHandler
+	reports    map[reportKey]*report
+	pending    map[string]*pending
+	requestId  int
+	// fields rather than constants so a test can drive the schedule without waiting it out
+	retryDelay  time.Duration
+	sendTimeout time.Duration
+	now         func() time.Time
+	mutex       sync.Mutex
+}
+
+func NewManager(database Repository, server Handler, localLists LocalLists, log internal.LogHandler) *Manager {
+	return &Manager{
+		database:    database,
+		server:      server,
+		localLists:  localLists,
+		log:         log,
+		reports:     make(map[reportKey]*report),
+		pending:     make(map[string]*pending),
+		retryDelay:  retryDelay,
+		sendTimeout: sendTimeout,
+		now:         time.Now,
+	}
+}
+
+// OnSystemStart drops the reports that stopped halfway.
+func (m *Manager) OnSystemStart() {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+	for {
+		<-ticker.C
+		m.tick()
+	}
+}
+
+// HandleCommand serves the customer data API commands and returns the JSON answer.
+func (m *Manager) HandleCommand(command, payload string) ([]byte, error) {
+	switch command {
+	case InformationFeatureName:
+		var spec InformationSpec
+		if err := json.Unmarshal([]byte(payload), &spec); err != nil {
+			return nil, fmt.Errorf("invalid payload")
+		}
+		status, err := m.RequestInformation(&spec)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(status)
+	case ExportFeatureName:
+		var spec CustomerSpec
+		if err := json.Unmarshal([]byte(payload), &spec); err != nil {
+			return nil, fmt.Errorf("invalid payload")
+		}
+		report, err := m.Export(&spec)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(report)
+	case EraseFeatureName:
+		var spec CustomerSpec
+		if err := json.Unmarshal([]byte(payload), &spec); err != nil {
+			return nil, fmt.Errorf("invalid payload")
+		}
+		erasure, err := m.Erase(&spec)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(erasure)
+	default:
+		return nil, fmt.Errorf("unknown customer data command: %s", command)
+	}
+}
+
+// RequestInformation sends CustomerInformation to a charge point and waits for its answer; a report
+// follows in NotifyCustomerInformation messages and is stored with the id tag.
+func (m *Manager) RequestInformation(spec *InformationSpec) (*InformationStatus, error) {
+	if spec.ChargePointId == "" {
+		return nil, fmt.Errorf("charge point id is required")
+	}
+	token, err := idToken(spec.IdTag, spec.TokenType)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mutex.Lock()
+	requestId := m.nextRequestId()
+	m.mutex.Unlock()
+	request := &diagnostics.CustomerInformationRequest{
+		RequestId: requestId,
+		Report:    spec.Report,
+		Clear:     spec.Clear,
+		IdToken:   &token,
+	}
+	if err = request.Validate(); err != nil {
+		return nil, err
+	}
+
+	// registered before the request goes out: the first part may arrive ahead of the answer
+	key := reportKey{chargePointId: spec.ChargePointId, requestId: requestId}
+	if spec.Report {
+		m.mutex.Lock()
+		m.reports[key] = &report{idTag: spec.IdTag, parts: make(map[int]string), lastSeqNo: -1, updated: m.now()}
+		m.mutex.Unlock()
+	}
+
+	status, err := m.send(spec.ChargePointId, request)
+	if status != diagnostics.CustomerInformationStatusAccepted {
+		m.mutex.Lock()
+		delete(m.reports, key)
+		m.mutex.Unlock()
+	}
+	if err != nil {
+		return nil, err
+	}
+	m.log.FeatureEvent(request.GetFeatureName(), spec.ChargePointId, fmt.Sprintf("request #%d: report=%v, clear=%v: %s",
+		requestId, spec.Report, spec.Clear, status))
+	return &InformationStatus{ChargePointId: spec.ChargePointId, RequestId: requestId, Status: string(status)}, nil
+}
+
+// OnNotifyCustomerInformation takes a part of a report. Once every part up to the one without tbc
+// has arrived, in whatever order, the report is stored.
+func (m *Manager) OnNotifyCustomerInformation(chargePointId string, request *diagnostics.NotifyCustomerInformationRequest) {
+	m.mutex.Lock()
+	key := reportKey{chargePointId: chargePointId, requestId: request.RequestId}
+	r, ok := m.reports[key]
+	if !ok {
+		// not asked for by the manager, such as a CustomerInformation sent before a restart
+		r = &report{parts: make(map[int]string), lastSeqNo: -1}
+		m.reports[key] = r
+	}
+	r.parts[request.SeqNo] = request.Data
+	r.updated = m.now()
+	if request.GeneratedAt.After(r.generatedAt) {
+		r.generatedAt = request.GeneratedAt
+	}
+	if !request.Tbc {
+		r.lastSeqNo = request.SeqNo
+	}
+	if r.lastSeqNo < 0 {
+		m.mutex.Unlock()
+		return
+	}
+	var data strings.Builder
+	for seqNo := 0; seqNo <= r.lastSeqNo; seqNo++ {
+		part, found := r.parts[seqNo]
+		if !found {
+			m.mutex.Unlock()
+			return
+		}
+		data.WriteString(part)
+	}
+	delete(m.reports, key)
+	m.mutex.Unlock()
+
+	m.log.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("request #%d: %d parts, %d characters",
+		request.RequestId, r.lastSeqNo+1, data.Len()))
+	if m.database == nil {
+		return
+	}
+	err := m.database.AddCustomerInformation(&entity.CustomerInformation{
+		ChargePointId: chargePointId,
+		RequestId:     request.RequestId,
+		IdTag:         r.idTag,
+		Data:          data.String(),
+		GeneratedAt:   r.generatedAt,
+		TimeReceived:  m.now(),
+	})
+	if err != nil {
+		m.log.Error(fmt.Sprintf("save customer information of %s", chargePointId), err)
+	}
+}
+
+// Export gathers everything the central system holds about an id tag.
+func (m *Manager) Export(spec *CustomerSpec) (*Report, error) {
+	if m.database == nil {
+		return nil, fmt.Errorf("customer data needs the database")
+	}
+	if spec.IdTag == "" {
+		return nil, fmt.Errorf("id tag is required")
+	}
+	idTag := spec.IdTag
+	report := &Report{
+		IdTag:          idTag,
+		GeneratedAt:    m.now(),
+		Transactions:   make([]*entity.Transaction, 0),
+		Reservations:   make([]*entity.Reservation, 0),
+		LocalLists:     make([]string, 0),
+		LogMessages:    make([]*internal.FeatureLogMessage, 0),
+		StationReports: make([]*entity.CustomerInformation, 0),
+	}
+	if tag, err := m.database.GetUserTag(idTag); err == nil && tag != nil {
+		report.UserTag = tag
+	}
+	transactions, err := m.database.GetCustomerTransactions(idTag)
+	if err != nil {
+		return nil, fmt.Errorf("get transactions: %v", err)
+	}
+	report.Transactions = append(report.Transactions, transactions...)
+	reservations, err := m.database.GetCustomerReservations(idTag)
+	if err != nil {
+		return nil, fmt.Errorf("get reservations: %v", err)
+	}
+	report.Reservations = append(report.Reservations, reservations...)
+	lists, err := m.database.GetLocalAuthListsWithTag(idTag)
+	if err != nil {
+		return nil, fmt.Errorf("get local lists: %v", err)
+	}
+	for _, list := range lists {
+		report.LocalLists = append(report.LocalLists, list.ChargePointId)
+	}
+	sort.Strings(report.LocalLists)
+	if len(idTag) < minLogTagLength {
+		report.LogMessagesSkipped = true
+	} else {
+		messages, err := m.database.FindLogMessages(logPattern(idTag))
+		if err != nil {
+			return nil, fmt.Errorf("find log messages: %v", err)
+		}
+		report.LogMessages = append(report.LogMessages, messages...)
+	}
+	stationReports, err := m.database.GetCustomerInformation(idTag)
+	if err != nil {
+		return nil, fmt.Errorf("get customer information: %v", err)
+	}
+	report.StationReports = append(report.StationReports, stationReports...)
+
+	m.log.FeatureEvent(featureName, "", fmt.Sprintf("export: %d transactions, %d reservations, %d local lists, %d log lines, %d station reports",
+		len(report.Transactions), len(report.Reservations), len(report.LocalLists), len(report.LogMessages), len(report.StationReports)))
+	return report, nil
+}
+
+/*
+Erase removes a customer, identified by an id tag, from the central system and the charge points.
+
+The customer must have no running transaction and no active reservation: both still need the tag.
+The user tag is deleted first, so the local lists that hold it are synced without it. The OCPP 2.0.1
+charge points the tag was used on, or whose list held it, are asked to clear it with
+CustomerInformation. What is kept for billing and statistics - transactions, reservations, the log -
+has the tag and the user replaced with Anonymized, and the reports charge points sent about the
+customer are deleted. A charge point that is not connected is done when it next boots; the answer
+lists it as Pending. An erasure may be repeated; what is gone already is not counted again.
+*/
+func (m *Manager) Erase(spec *CustomerSpec) (*Erasure, error) {
+	if m.database == nil {
+		return nil, fmt.Errorf("customer data needs the database")
+	}
+	token, err := idToken(spec.IdTag, spec.TokenType)
+	if err != nil {
+		return nil, err
+	}
+	idTag := spec.IdTag
+
+	transactions, err := m.database.GetCustomerTransactions(idTag)
+	if err != nil {
+		return nil, fmt.Errorf("get transactions: %v", err)
+	}
+	stations := make(map[string]bool)
+	for _, transaction := range transactions {
+		if !transaction.IsFinished {
+			return nil, fmt.Errorf("transaction %d of the customer is running", transaction.Id)
+		}
+		stations[transaction.ChargePointId] = true
+	}
+	reservations, err := m.database.GetCustomerReservations(idTag)
+	if err != nil {
+		return nil, fmt.Errorf("get reservations: %v", err)
+	}
+	now := m.now()
+	for _, reservation := range reservations {
+		if reservation.Status == entity.ReservationActive && reservation.ExpiryDate.After(now) {
+			return nil, fmt.Errorf("reservation %d of the customer is active; cancel it first", reservation.Id)
+		}
+	}
+	lists, err := m.database.GetLocalAuthListsWithTag(idTag)
+	if err != nil {
+		return nil, fmt.Errorf("get local lists: %v", err)
+	}
+
+	erasure := &Erasure{LocalLists: make([]StationResult, 0), Stations: make([]StationResult, 0)}
+	if tag, _ := m.database.GetUserTag(idTag); tag != nil {
+		if err = m.database.DeleteUserTag(idTag); err != nil {
+			return nil, fmt.Errorf("delete user tag: %v", err)
+		}
+		erasure.UserTag = true
+	}
+
+	listIds := make([]string, 0, len(lists))
+	for _, list := range lists {
+		listIds = append(listIds, list.ChargePointId)
+		stations[list.ChargePointId] = true
+	}
+	sort.Strings(listIds)
+	for _, chargePointId := range listIds {
+		status := m.syncLocalList(chargePointId)
+		if status == statusPending {
+			m.addPending(chargePointId, nil, true)
+		}
+		erasure.LocalLists = append(erasure.LocalLists, StationResult{ChargePointId: chargePointId, Status: status})
+	}
+
+	stationIds := make([]string, 0, len(stations))
+	for chargePointId := range stations {
+		stationIds = append(stationIds, chargePointId)
+	}
+	sort.Strings(stationIds)
+	for _, chargePointId := range stationIds {
+		if !m.isOCPP2(chargePointId) {
+			continue
+		}
+		status := m.clear(chargePointId, token)
+		if status == statusPending {
+			m.addPending(chargePointId, &token, false)
+		}
+		erasure.Stations = append(erasure.Stations, StationResult{ChargePointId: chargePointId, Status: status})
+	}
+
+	if erasure.Transactions, err = m.database.AnonymizeCustomerTransactions(idTag, Anonymized); err != nil {
+		return nil, fmt.Errorf("anonymize transactions: %v", err)
+	}
+	if erasure.Reservations, err = m.database.AnonymizeCustomerReservations(idTag, Anonymized); err != nil {
+		return nil, fmt.Errorf("anonymize reservations: %v", err)
+	}
+	m.dropReports(idTag)
+	if erasure.StationReports, err = m.database.DeleteCustomerInformation(idTag); err != nil {
+		return nil, fmt.Errorf("delete customer information: %v", err)
+	}
+	if len(idTag) < minLogTagLength {
+		erasure.LogMessagesSkipped = true
+	} else if erasure.LogMessages, err = m.database.AnonymizeLogMessages(logPattern(idTag), idTag, Anonymized); err != nil {
+		return nil, fmt.Errorf("anonymize log messages: %v", err)
+	}
+
+	// the tag itself stays out of the log
+	m.log.FeatureEvent(featureName, "", fmt.Sprintf("customer erased: %d transactions, %d reservations, %d log lines, %d local lists, %d stations",
+		erasure.Transactions, erasure.Reservations, erasure.LogMessages, len(erasure.LocalLists), len(erasure.Stations)))
+	return erasure, nil
+}
+
+// OnChargePointBoot finishes the erasures a charge point missed while it was not connected.
+func (m *Manager) OnChargePointBoot(chargePointId string) {
+	m.mutex.Lock()
+	p, ok := m.pending[chargePointId]
+	if ok {
+		delete(m.pending, chargePointId)
+	}
+	m.mutex.Unlock()
+	if !ok {
+		return
+	}
+
+	time.AfterFunc(m.retryDelay, func() {
+		if p.localList && m.syncLocalList(chargePointId) == statusPending {
+			m.addPending(chargePointId, nil, true)
+		}
+		for i := range p.tokens {
+			if m.clear(chargePointId, p.tokens[i]) == statusPending {
+				m.addPending(chargePointId, &p.tokens[i], false)
+			}
+		}
+	})
+}
+
+// tick drops the reports that stopped halfway.
+func (m *Manager) tick() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	now := m.now()
+	for key, r := range m.reports {
+		if now.Sub(r.updated) >= partTimeout {
+			m.log.FeatureEvent(featureName, key.chargePointId, fmt.Sprintf("report #%d dropped: %d parts, no more arrived", key.requestId, len(r.parts)))
+			delete(m.reports, key)
+		}
+	}
+}
+
+// clear asks a charge point to delete what it holds about a token and returns its answer, or
+// Pending when it could not be asked.
+func (m *Manager) clear(chargePointId string, token v201.IdToken) string {
+	m.mutex.Lock()
+	requestId := m.nextRequestId()
+	m.mutex.Unlock()
+	request := &diagnostics.CustomerInformationRequest{RequestId: requestId, Clear: true, IdToken: &token}
+	status, err := m.send(chargePointId, request)
+	if err != nil {
+		m.log.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("request #%d: clear pending: %s", requestId, err))
+		return statusPending
+	}
+	m.log.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("request #%d: clear: %s", requestId, status))
+	return string(status)
+}
+
+// syncLocalList sends the local list of a charge point without the deleted tag and returns the
+// status of the update, or Pending when it could not be sent.
+func (m *Manager) syncLocalList(chargePointId string) string {
+	if m.localLists == nil {
+		return statusPending
+	}
+	result, err := m.localLists.SyncLocalList(chargePointId, false)
+	if err != nil {
+		m.log.FeatureEvent(featureName, chargePointId, fmt.Sprintf("local list sync pending: %s", err))
+		return statusPending
+	}
+	return string(result.Status)
+}
+
+// send delivers a CustomerInformation request and returns the charge point's answer to it.
+func (m *Manager) send(chargePointId string, request *diagnostics.CustomerInformationRequest) (diagnostics.CustomerInformationStatusType, error) {
+	response, release, err := m.server.SendRequestWithResponse(chargePointId, request)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	var reply ocpp.Answer
+	select {
+	case reply = <-response:
+	case <-time.After(m.sendTimeout):
+		return "", fmt.Errorf("no response to %s", request.GetFeatureName())
+	}
+	if reply.Err != nil {
+		return "", fmt.Errorf("%s refused: %w", request.GetFeatureName(), reply.Err)
+	}
+	var answer diagnostics.CustomerInformationResponse
+	if err = json.Unmarshal([]byte(reply.Payload), &answer); err != nil || answer.Status == "" {
+		return "", fmt.Errorf("invalid response to %s", request.GetFeatureName())
+	}
+	return answer.Status, nil
+}
+
+func (m *Manager) addPending(chargePointId string, token *v201.IdToken, localList bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	p, ok := m.pending[chargePointId]
+	if !ok {
+		p = &pending{}
+		m.pending[chargePointId] = p
+	}
+	if token != nil {
+		p.tokens = append(p.tokens, *token)
+	}
+	p.localList = p.localList || localList
+}
+
+// dropReports forgets the reports about an id tag that are still arriving.
+func (m *Manager) dropReports(idTag string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for key, r := range m.reports {
+		if r.idTag == idTag {
+			delete(m.reports, key)
+		}
+	}
+}
+
+// isOCPP2 tells whether a charge point last booted with OCPP 2.0.1 or later, and so takes
+// CustomerInformation.
+func (m *Manager) isOCPP2(chargePointId string) bool {
+	chargePoint, err := m.database.GetChargePoint(chargePointId)
+	if err != nil || chargePoint == nil {
+		return false
+	}
+	return common.ProtocolVersion(chargePoint.ProtocolVersion).IsOCPP2()
+}
+
+// nextRequestId starts from the clock, so ids of requests sent before a restart are not reused.
+// Called with m.mutex held.
+func (m *Manager) nextRequestId() int {
+	if m.requestId == 0 {
+		m.requestId = int(m.now().Unix() % 1000000000)
+	}
+	m.requestId++
+	return m.requestId
+}
+
+func idToken(idTag, tokenType string) (v201.IdToken, error) {
+	if idTag == "" {
+		return v201.IdToken{}, fmt.Errorf("id tag is required")
+	}
+	token := v201.IdToken{IdToken: idTag, Type: v201.IdTokenTypeISO14443}
+	if tokenType != "" {
+		token.Type = v201.IdTokenType(tokenType)
+	}
+	return token, nil
+}
+
+// logPattern matches an id tag in the text of a log line, but not as a part of a longer word.
+func logPattern(idTag string) string {
+	return `(^|[^0-9A-Za-z])` + regexp.QuoteMeta(idTag) + `([^0-9A-Za-z]|$)`
+}
@@ -0,0 +1,476 @@
+package customerdata
+
+import (
+	"errors"
+	"evsys/entity"
+	"evsys/internal"
+	"evsys/ocpp"
+	"evsys/ocpp/v16/localauth"
+	"evsys/ocpp/v201"
+	"evsys/ocpp/v201/diagnostics"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubRepo keeps the customer data in memory.
+type stubRepo struct {
+	mutex        sync.Mutex
+	protocols    map[string]string // charge point id → protocol version
+	tags         map[string]*entity.UserTag
+	lists        map[string][]string // charge point id → id tags on its local list
+	transactions []*entity.Transaction
+	reservations []*entity.Reservation
+	logs         []*internal.FeatureLogMessage
+	information  []*entity.CustomerInformation
+}
+
+func (s *stubRepo) GetChargePoint(id string) (*entity.ChargePoint, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	protocol, ok := s.protocols[id]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return &entity.ChargePoint{Id: id, ProtocolVersion: protocol}, nil
+}
+
+func (s *stubRepo) GetUserTag(idTag string) (*entity.UserTag, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	tag, ok := s.tags[idTag]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return tag, nil
+}
+
+func (s *stubRepo) DeleteUserTag(idTag string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.tags, idTag)
+	return nil
+}
+
+func (s *stubRepo) GetLocalAuthListsWithTag(idTag string) ([]*entity.LocalAuthList, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var lists []*entity.LocalAuthList
+	for chargePointId, tags := range s.lists {
+		for _, tag := range tags {
+			if tag == idTag {
+				lists = append(lists, &entity.LocalAuthList{ChargePointId: chargePointId})
+			}
+		}
+	}
+	return lists, nil
+}
+
+func (s *stubRepo) GetCustomerTransactions(idTag string) ([]*entity.Transaction, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var transactions []*entity.Transaction
+	for _, transaction := range s.transactions {
+		if customerTagMatches(transaction.IdTag, idTag) {
+			transactions = append(transactions, transaction)
+		}
+	}
+	return transactions, nil
+}
+
+func (s *stubRepo) AnonymizeCustomerTransactions(idTag, placeholder string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	count := 0
+	for _, transaction := range s.transactions {
+		if customerTagMatches(transaction.IdTag, idTag) {
+			transaction.IdTag, transaction.IdTagNote, transaction.Username, transaction.UserTag = placeholder, "", "", nil
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *stubRepo) GetCustomerReservations(idTag string) ([]*entity.Reservation, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var reservations []*entity.Reservation
+	for _, reservation := range s.reservations {
+		if customerTagMatches(reservation.IdTag, idTag) {
+			reservations = append(reservations, reservation)
+		}
+	}
+	return reservations, nil
+}
+
+func (s *stubRepo) AnonymizeCustomerReservations(idTag, placeholder string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	count := 0
+	for _, reservation := range s.reservations {
+		if customerTagMatches(reservation.IdTag, idTag) {
+			reservation.IdTag = placeholder
+			count++
+		}
+	}
+	return count, nil
+}
+
+// customerTagMatches matches a stored id tag the way the database does, prefix of the charge point
+// or not
+func customerTagMatches(stored, idTag string) bool {
+	return regexp.MustCompile(entity.IdTagPattern(idTag)).MatchString(stored)
+}
+
+func (s *stubRepo) FindLogMessages(pattern string) ([]*internal.FeatureLogMessage, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	expression := regexp.MustCompile(pattern)
+	var messages []*internal.FeatureLogMessage
+	for _, message := range s.logs {
+		if expression.MatchString(message.Text) {
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
+
+func (s *stubRepo) AnonymizeLogMessages(pattern, text, placeholder string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	expression := regexp.MustCompile(pattern)
+	count := 0
+	for _, message := range s.logs {
+		if expression.MatchString(message.Text) {
+			message.Text = strings.ReplaceAll(message.Text, text, placeholder)
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *stubRepo) AddCustomerInformation(information *entity.CustomerInformation) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.information = append(s.information, information)
+	return nil
+}
+
+func (s *stubRepo) GetCustomerInformation(idTag string) ([]*entity.CustomerInformation, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var reports []*entity.CustomerInformation
+	for _, information := range s.information {
+		if information.IdTag == idTag {
+			reports = append(reports, information)
+		}
+	}
+	return reports, nil
+}
+
+func (s *stubRepo) DeleteCustomerInformation(idTag string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	kept := s.information[:0]
+	for _, information := range s.information {
+		if information.IdTag != idTag {
+			kept = append(kept, information)
+		}
+	}
+	deleted := len(s.information) - len(kept)
+	s.information = kept
+	return deleted, nil
+}
+
+// stubServer answers every request with answer, except on charge points listed as offline, and
+// keeps what it was sent.
+type stubServer struct {
+	mutex   sync.Mutex
+	offline map[string]bool
+	answer  string
+	sent    map[string][]ocpp.Request
+}
+
+func (s *stubServer) SendRequestWithResponse(clientId string, request ocpp.Request) (<-chan ocpp.Answer, func(), error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.offline[clientId] {
+		return nil, nil, errors.New("charge point not available")
+	}
+	s.sent[clientId] = append(s.sent[clientId], request)
+	response := make(chan ocpp.Answer, 1)
+	response <- ocpp.Answer{Payload: s.answer}
+	return response, func() {}, nil
+}
+
+func (s *stubServer) sentTo(clientId string) []ocpp.Request {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]ocpp.Request{}, s.sent[clientId]...)
+}
+
+func (s *stubServer) setOffline(clientId string, offline bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.offline[clientId] = offline
+}
+
+// stubLists drops the deleted tags from the lists of the repository, as a sync would, unless the
+// charge point is offline on the server.
+type stubLists struct {
+	repo   *stubRepo
+	server *stubServer
+}
+
+func (s *stubLists) SyncLocalList(chargePointId string, _ bool) (*localauth.SyncResult, error) {
+	s.server.mutex.Lock()
+	offline := s.server.offline[chargePointId]
+	s.server.mutex.Unlock()
+	if offline {
+		return nil, errors.New("charge point not available")
+	}
+	s.repo.mutex.Lock()
+	defer s.repo.mutex.Unlock()
+	var kept []string
+	for _, tag := range s.repo.lists[chargePointId] {
+		if _, ok := s.repo.tags[tag]; ok {
+			kept = append(kept, tag)
+		}
+	}
+	s.repo.lists[chargePointId] = kept
+	return &localauth.SyncResult{Status: localauth.UpdateStatusAccepted}, nil
+}
+
+// stubLog keeps the text of the events it was given.
+type stubLog struct {
+	mutex sync.Mutex
+	lines []string
+}
+
+func (l *stubLog) FeatureEvent(_, _, text string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.lines = append(l.lines, text)
+}
+func (l *stubLog) RawDataEvent(_, _ string) {}
+func (l *stubLog) Debug(_ string)           {}
+func (l *stubLog) Warn(_ string)            {}
+func (l *stubLog) Error(_ string, _ error)  {}
+
+const customerTag = "04A2B3C4"
+
+func newTestManager() (*Manager, *stubRepo, *stubServer, *stubLog) {
+	repo := &stubRepo{
+		protocols: map[string]string{"cp16": "ocpp1.6", "cp201": "ocpp2.0.1", "cp21": "ocpp2.1"},
+		tags:      map[string]*entity.UserTag{customerTag: {IdTag: customerTag, Username: "jane", Local: true}},
+		lists:     map[string][]string{"cp16": {customerTag, "OTHER123"}, "cp21": {customerTag}},
+		transactions: []*entity.Transaction{
+			{Id: 1, ChargePointId: "cp16", IdTag: customerTag, IdTagNote: "Jane's card", Username: "jane", IsFinished: true},
+			{Id: 2, ChargePointId: "cp201", IdTag: customerTag, Username: "jane", IsFinished: true},
+			{Id: 3, ChargePointId: "cp201", IdTag: "OTHER123", Username: "john", IsFinished: false},
+		},
+		reservations: []*entity.Reservation{{Id: 5, ChargePointId: "cp201", IdTag: customerTag, Status: entity.ReservationUsed}},
+		logs: []*internal.FeatureLogMessage{
+			{Feature: "Authorize", Text: "id tag " + customerTag + ": Accepted"},
+			{Feature: "Authorize", Text: "id tag " + customerTag + "99: Accepted"},
+			{Feature: "Heartbeat", Text: "v2.0.1"},
+		},
+	}
+	server := &stubServer{offline: map[string]bool{}, answer: `{"status":"Accepted"}`, sent: map[string][]ocpp.Request{}}
+	log := &stubLog{}
+	m := NewManager(repo, server, &stubLists{repo: repo, server: server}, log)
+	now := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	m.retryDelay = 0
+	return m, repo, server, log
+}
+
+// waitForRequest blocks until a charge point was sent count requests; the erasures retried after a
+// boot go out on a goroutine of their own.
+func waitForRequest(t *testing.T, server *stubServer, chargePointId string, count int) []ocpp.Request {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if sent := server.sentTo(chargePointId); len(sent) >= count {
+			return sent
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("%s was sent %d requests, want %d", chargePointId, len(server.sentTo(chargePointId)), count)
+	return nil
+}
+
+func TestEraseAnonymizesAndClearsStations(t *testing.T) {
+	m, repo, server, log := newTestManager()
+
+	erasure, err := m.Erase(&CustomerSpec{IdTag: customerTag})
+	if err != nil {
+		t.Fatalf("Erase() error = %v", err)
+	}
+	if !erasure.UserTag || erasure.Transactions != 2 || erasure.Reservations != 1 || erasure.LogMessages != 1 {
+		t.Errorf("erasure %+v, want the tag, 2 transactions, 1 reservation and 1 log line", erasure)
+	}
+	if len(erasure.LocalLists) != 2 || erasure.LocalLists[0].Status != string(localauth.UpdateStatusAccepted) {
+		t.Errorf("local lists %+v, want cp16 and cp21 synced", erasure.LocalLists)
+	}
+	if lists, _ := repo.GetLocalAuthListsWithTag(customerTag); len(lists) != 0 {
+		t.Errorf("%d local lists still hold the tag", len(lists))
+	}
+
+	// the 2.x stations the tag was used on or listed by are cleared; 1.6 has no CustomerInformation
+	if len(erasure.Stations) != 2 || erasure.Stations[0].ChargePointId != "cp201" || erasure.Stations[1].ChargePointId != "cp21" {
+		t.Fatalf("stations %+v, want cp201 and cp21", erasure.Stations)
+	}
+	sent := server.sentTo("cp201")
+	request, ok := sent[0].(*diagnostics.CustomerInformationRequest)
+	if len(sent) != 1 || !ok || !request.Clear || request.Report || request.IdToken.IdToken != customerTag || request.IdToken.Type != v201.IdTokenTypeISO14443 {
+		t.Errorf("cp201 was sent %+v, want a clear of the ISO14443 tag", sent)
+	}
+	if len(server.sentTo("cp16")) != 0 {
+		t.Error("a 1.6 charge point was sent CustomerInformation")
+	}
+
+	if transaction := repo.transactions[0]; transaction.IdTag != Anonymized || transaction.Username != "" || transaction.IdTagNote != "" {
+		t.Errorf("transaction %+v still identifies the customer", transaction)
+	}
+	if repo.transactions[2].IdTag != "OTHER123" || repo.transactions[2].Username != "john" {
+		t.Error("the transaction of another customer was changed")
+	}
+	if repo.logs[0].Text != "id tag "+Anonymized+": Accepted" || repo.logs[1].Text != "id tag "+customerTag+"99: Accepted" {
+		t.Errorf("log lines %q and %q, want only the first anonymized", repo.logs[0].Text, repo.logs[1].Text)
+	}
+	for _, line := range log.lines {
+		if strings.Contains(line, customerTag) {
+			t.Errorf("the erasure logged the tag: %q", line)
+		}
+	}
+}
+
+func TestEraseRefusesRunningTransaction(t *testing.T) {
+	m, repo, server, _ := newTestManager()
+
+	if _, err := m.Erase(&CustomerSpec{IdTag: "OTHER123"}); err == nil {
+		t.Fatal("Erase() error = nil, want the running transaction to stop it")
+	}
+	if repo.transactions[2].IdTag != "OTHER123" || len(server.sentTo("cp201")) != 0 {
+		t.Error("a refused erasure changed data")
+	}
+}
+
+func TestPrefixedTagIsExportedAndErased(t *testing.T) {
+	m, repo, _, _ := newTestManager()
+	repo.transactions = append(repo.transactions,
+		&entity.Transaction{Id: 4, ChargePointId: "cp16", IdTag: "app:" + customerTag, Username: "jane", IsFinished: false})
+	repo.reservations = append(repo.reservations,
+		&entity.Reservation{Id: 6, ChargePointId: "cp16", IdTag: "app:" + customerTag, Status: entity.ReservationUsed})
+
+	report, err := m.Export(&CustomerSpec{IdTag: customerTag})
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if len(report.Transactions) != 3 || len(report.Reservations) != 2 {
+		t.Errorf("report holds %d transactions and %d reservations, want the prefixed ones too",
+			len(report.Transactions), len(report.Reservations))
+	}
+
+	if _, err = m.Erase(&CustomerSpec{IdTag: customerTag}); err == nil {
+		t.Fatal("Erase() error = nil, want the running transaction of the prefixed tag to stop it")
+	}
+	repo.transactions[3].IsFinished = true
+	erasure, err := m.Erase(&CustomerSpec{IdTag: customerTag})
+	if err != nil {
+		t.Fatalf("Erase() error = %v", err)
+	}
+	if erasure.Transactions != 3 || erasure.Reservations != 2 {
+		t.Errorf("erasure %+v, want 3 transactions and 2 reservations", erasure)
+	}
+	if repo.transactions[3].IdTag != Anonymized || repo.reservations[1].IdTag != Anonymized {
+		t.Errorf("prefixed tag kept: transaction %q, reservation %q", repo.transactions[3].IdTag, repo.reservations[1].IdTag)
+	}
+}
+
+func TestOfflineStationIsErasedOnBoot(t *testing.T) {
+	m, repo, server, _ := newTestManager()
+	server.setOffline("cp21", true)
+
+	erasure, err := m.Erase(&CustomerSpec{IdTag: customerTag})
+	if err != nil {
+		t.Fatalf("Erase() error = %v", err)
+	}
+	if erasure.LocalLists[1].Status != statusPending || erasure.Stations[1].Status != statusPending {
+		t.Fatalf("cp21 erasure %+v / %+v, want both pending", erasure.LocalLists[1], erasure.Stations[1])
+	}
+
+	server.setOffline("cp21", false)
+	m.OnChargePointBoot("cp21")
+	sent := waitForRequest(t, server, "cp21", 1)
+	if request, ok := sent[0].(*diagnostics.CustomerInformationRequest); !ok || !request.Clear || request.IdToken.IdToken != customerTag {
+		t.Errorf("cp21 was sent %+v after its boot, want the pending clear", sent[0])
+	}
+	if lists, _ := repo.GetLocalAuthListsWithTag(customerTag); len(lists) != 0 {
+		t.Error("the local list of cp21 was not synced after its boot")
+	}
+
+	// done once: a second boot sends nothing
+	m.OnChargePointBoot("cp21")
+	time.Sleep(20 * time.Millisecond)
+	if len(server.sentTo("cp21")) != 1 {
+		t.Errorf("cp21 was sent %d requests, want the clear once", len(server.sentTo("cp21")))
+	}
+}
+
+func TestStationReportIsStitchedAndExported(t *testing.T) {
+	m, repo, server, _ := newTestManager()
+
+	status, err := m.RequestInformation(&InformationSpec{ChargePointId: "cp201", IdTag: customerTag, Report: true})
+	if err != nil || status.Status != "Accepted" {
+		t.Fatalf("RequestInformation() = %+v, %v, want Accepted", status, err)
+	}
+	if request := server.sentTo("cp201")[0].(*diagnostics.CustomerInformationRequest); !request.Report || request.Clear {
+		t.Errorf("sent %+v, want a report only", request)
+	}
+
+	generatedAt := time.Date(2024, 5, 1, 7, 59, 0, 0, time.UTC)
+	m.OnNotifyCustomerInformation("cp201", &diagnostics.NotifyCustomerInformationRequest{
+		RequestId: status.RequestId, SeqNo: 1, Data: "sessions: 2", GeneratedAt: generatedAt})
+	if len(repo.information) != 0 {
+		t.Fatal("stored a report with its first part missing")
+	}
+	m.OnNotifyCustomerInformation("cp201", &diagnostics.NotifyCustomerInformationRequest{
+		RequestId: status.RequestId, SeqNo: 0, Tbc: true, Data: "cache: valid; ", GeneratedAt: generatedAt})
+
+	report, err := m.Export(&CustomerSpec{IdTag: customerTag})
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if len(report.StationReports) != 1 || report.StationReports[0].Data != "cache: valid; sessions: 2" {
+		t.Errorf("station reports %+v, want the parts joined in order", report.StationReports)
+	}
+	if report.UserTag == nil || len(report.Transactions) != 2 || len(report.Reservations) != 1 || len(report.LogMessages) != 1 {
+		t.Errorf("report has tag %v, %d transactions, %d reservations, %d log lines; want the tag, 2, 1, 1",
+			report.UserTag, len(report.Transactions), len(report.Reservations), len(report.LogMessages))
+	}
+	if len(report.LocalLists) != 2 || report.LocalLists[0] != "cp16" {
+		t.Errorf("local lists %v, want cp16 and cp21", report.LocalLists)
+	}
+
+	erasure, err := m.Erase(&CustomerSpec{IdTag: customerTag})
+	if err != nil || erasure.StationReports != 1 {
+		t.Errorf("Erase() = %+v, %v, want the station report deleted", erasure, err)
+	}
+}
+
+func TestShortTagLeavesLogAlone(t *testing.T) {
+	m, repo, _, _ := newTestManager()
+	repo.logs = append(repo.logs, &internal.FeatureLogMessage{Text: "connector 12 available"})
+
+	erasure, err := m.Erase(&CustomerSpec{IdTag: "12"})
+	if err != nil {
+		t.Fatalf("Erase() error = %v", err)
+	}
+	if !erasure.LogMessagesSkipped || erasure.LogMessages != 0 || repo.logs[3].Text != "connector 12 available" {
+		t.Errorf("erasure %+v rewrote %q, want the log skipped", erasure, repo.logs[3].Text)
+	}
+}
@@ -0,0 +1,24 @@
+package customerdata
+
+import (
+	"evsys/entity"
+	"evsys/internal"
+)
+
+type Repository interface {
+	GetChargePoint(id string) (*entity.ChargePoint, error)
+	GetUserTag(idTag string) (*entity.UserTag, error)
+	DeleteUserTag(idTag string) error
+	GetLocalAuthListsWithTag(idTag string) ([]*entity.LocalAuthList, error)
+	GetCustomerTransactions(idTag string) ([]*entity.Transaction, error)
+	// AnonymizeCustomerTransactions replaces the id tag, its note, the username and the user tag of
+	// the transactions, and returns how many were changed
+	AnonymizeCustomerTransactions(idTag, placeholder string) (int, error)
+	GetCustomerReservations(idTag string) ([]*entity.Reservation, error)
+	AnonymizeCustomerReservations(idTag, placeholder string) (int, error)
+	FindLogMessages(pattern string) ([]*internal.FeatureLogMessage, error)
+	AnonymizeLogMessages(pattern, text, placeholder string) (int, error)
+	AddCustomerInformation(information *entity.CustomerInformation) error
+	GetCustomerInformation(idTag string) ([]*entity.CustomerInformation, error)
+	DeleteCustomerInformation(idTag string) (int, error)
+}
@@ -0,0 +1,19 @@
+package customerdata
+
+import (
+	"evsys/ocpp"
+	"evsys/ocpp/v16/localauth"
+)
+
+type Handler interface {
+	// SendRequestWithResponse queues a request and returns the channel carrying
+	// the charge point's answer, its raw CallResult payload or its CallError; the
+	// error means the charge point is not connected. release must be called once
+	// the caller stops listening.
+	SendRequestWithResponse(clientId string, request ocpp.Request) (response <-chan ocpp.Answer, release func(), err error)
+}
+
+// LocalLists brings the local authorization list of a charge point in line with the stored tags.
+type LocalLists interface {
+	SyncLocalList(chargePointId string, full bool) (*localauth.SyncResult, error)
+}
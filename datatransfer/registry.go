@@ -0,0 +1,109 @@
+package datatransfer
+
+import (
+	"encoding/json"
+	"evsys/internal"
+	"evsys/ocpp/v16/core"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Message is a DataTransfer received from a charge point.
+type Message struct {
+	ChargePointId string
+	VendorId      string
+	MessageId     string
+	Data          interface{}
+}
+
+// Decode unmarshals the message data into v. Most chargers send a JSON document wrapped in the
+// string the specification defines data as; both that and plain JSON values are accepted.
+func (m *Message) Decode(v interface{}) error {
+	if m.Data == nil {
+		return fmt.Errorf("no data")
+	}
+	if text, ok := m.Data.(string); ok {
+		return json.Unmarshal([]byte(text), v)
+	}
+	raw, err := json.Marshal(m.Data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// Result is what a handler answers. Events are passed to the system event listeners after the
+// answer; charge point id and time are filled in when left empty.
+type Result struct {
+	Status core.DataTransferStatus
+	Data   interface{}
+	Events []*Event
+}
+
+type Event struct {
+	Kind    internal.Event
+	Message *internal.EventMessage
+}
+
+func Accepted(data interface{}) *Result {
+	return &Result{Status: core.DataTransferStatusAccepted, Data: data}
+}
+
+// HandlerFunc processes one kind of DataTransfer message. A returned error is logged and answered
+// with Rejected.
+type HandlerFunc func(message *Message) (*Result, error)
+
+// Registry dispatches DataTransfer messages to handlers by vendor and message id.
+type Registry struct {
+	vendors map[string]map[string]HandlerFunc
+	mutex   sync.RWMutex
+}
+
+func NewRegistry() *Registry {
+	return &Registry{vendors: make(map[string]map[string]HandlerFunc)}
+}
+
+// Register adds a handler for a vendor's message id; an empty message id takes every message of
+// the vendor without a handler of its own. Vendor ids are matched case-insensitively, as chargers
+// are not consistent about their own.
+func (r *Registry) Register(vendorId, messageId string, handler HandlerFunc) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	vendor := strings.ToLower(vendorId)
+	if r.vendors[vendor] == nil {
+		r.vendors[vendor] = make(map[string]HandlerFunc)
+	}
+	r.vendors[vendor][messageId] = handler
+}
+
+// Handle finds the handler for a message and runs it; UnknownVendorId and UnknownMessageId are
+// answered for messages nobody registered for.
+func (r *Registry) Handle(message *Message) (*Result, error) {
+	r.mutex.RLock()
+	messages, ok := r.vendors[strings.ToLower(message.VendorId)]
+	var handler HandlerFunc
+	if ok {
+		handler, ok = messages[message.MessageId]
+		if !ok {
+			handler, ok = messages[""]
+		}
+	}
+	r.mutex.RUnlock()
+
+	if messages == nil {
+		return &Result{Status: core.DataTransferStatusUnknownVendorId}, nil
+	}
+	if !ok {
+		return &Result{Status: core.DataTransferStatusUnknownMessageId}, nil
+	}
+	result, err := handler(message)
+	if err != nil {
+		return &Result{Status: core.DataTransferStatusRejected}, err
+	}
+	if result == nil {
+		result = Accepted(nil)
+	}
+	return result, nil
+}
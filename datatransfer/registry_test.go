@@ -0,0 +1,108 @@
+package datatransfer
+
+import (
+	"errors"
+	"evsys/internal"
+	"evsys/ocpp/v16/core"
+	"testing"
+)
+
+type readerStatus struct {
+	Reader string `json:"reader"`
+	Errors int    `json:"errors"`
+}
+
+func TestRegistryDispatch(t *testing.T) {
+	r := NewRegistry()
+	r.Register("Acme", "ReaderStatus", func(m *Message) (*Result, error) {
+		var status readerStatus
+		if err := m.Decode(&status); err != nil {
+			return nil, err
+		}
+		return Accepted(status.Reader), nil
+	})
+	r.Register("Acme", "Fails", func(m *Message) (*Result, error) {
+		return nil, errors.New("broken")
+	})
+	r.Register("Other", "", func(m *Message) (*Result, error) {
+		return nil, nil
+	})
+
+	tests := []struct {
+		name       string
+		message    Message
+		wantStatus core.DataTransferStatus
+		wantData   interface{}
+		wantErr    bool
+	}{
+		{
+			name:       "data as a JSON string",
+			message:    Message{VendorId: "Acme", MessageId: "ReaderStatus", Data: `{"reader":"front","errors":2}`},
+			wantStatus: core.DataTransferStatusAccepted, wantData: "front",
+		},
+		{
+			name:       "data as a JSON object, vendor in other case",
+			message:    Message{VendorId: "ACME", MessageId: "ReaderStatus", Data: map[string]interface{}{"reader": "rear"}},
+			wantStatus: core.DataTransferStatusAccepted, wantData: "rear",
+		},
+		{
+			name:       "unknown vendor",
+			message:    Message{VendorId: "Nobody", MessageId: "ReaderStatus"},
+			wantStatus: core.DataTransferStatusUnknownVendorId,
+		},
+		{
+			name:       "unknown message of a known vendor",
+			message:    Message{VendorId: "Acme", MessageId: "Display"},
+			wantStatus: core.DataTransferStatusUnknownMessageId,
+		},
+		{
+			name:       "vendor handler takes any message",
+			message:    Message{VendorId: "Other", MessageId: "Anything"},
+			wantStatus: core.DataTransferStatusAccepted,
+		},
+		{
+			name:       "undecodable data is rejected",
+			message:    Message{VendorId: "Acme", MessageId: "ReaderStatus", Data: "not json"},
+			wantStatus: core.DataTransferStatusRejected, wantErr: true,
+		},
+		{
+			name:       "handler error is rejected",
+			message:    Message{VendorId: "Acme", MessageId: "Fails"},
+			wantStatus: core.DataTransferStatusRejected, wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := r.Handle(&tt.message)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if result.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", result.Status, tt.wantStatus)
+			}
+			if result.Data != tt.wantData {
+				t.Errorf("data = %v, want %v", result.Data, tt.wantData)
+			}
+		})
+	}
+}
+
+func TestHandlerEventsAreReturned(t *testing.T) {
+	r := NewRegistry()
+	r.Register("Acme", "ReaderStatus", func(m *Message) (*Result, error) {
+		result := Accepted(nil)
+		result.Events = append(result.Events, &Event{
+			Kind:    internal.Alert,
+			Message: &internal.EventMessage{Info: "RFID reader failing"},
+		})
+		return result, nil
+	})
+
+	result, err := r.Handle(&Message{ChargePointId: "CP1", VendorId: "Acme", MessageId: "ReaderStatus"})
+	if err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	if len(result.Events) != 1 || result.Events[0].Kind != internal.Alert {
+		t.Errorf("events = %v, want the alert the handler raised", result.Events)
+	}
+}
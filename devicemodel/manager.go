@@ -0,0 +1,596 @@
+package devicemodel
+
+import (
+	"encoding/json"
+	"evsys/entity"
+	"evsys/internal"
+	"evsys/ocpp"
+	"evsys/ocpp/v201"
+	"evsys/ocpp/v201/monitoring"
+	"evsys/ocpp/v201/provisioning"
+	"evsys/types"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	featureName = "DeviceModel"
+
+	// API commands served by the manager
+	QueryFeatureName   = "QueryDeviceVariables"
+	HistoryFeatureName = "GetDeviceVariableHistory"
+	ReportFeatureName  = "RequestDeviceReport"
+
+	// reportDelay gives a charge point that has just booted time to send its status before it is
+	// asked for its whole device model.
+	reportDelay = 10 * time.Second
+	sendTimeout = 30 * time.Second
+	// partTimeout is how long a report may wait for its next part; the parts that arrived are
+	// dropped after it, and the next refresh asks again.
+	partTimeout = 10 * time.Minute
+	// refreshInterval is how old the stored inventory of a charge point may get before it is asked
+	// again; changes made on the charge point itself are only seen in a new report.
+	refreshInterval = 24 * time.Hour
+	// retryInterval is how long a charge point that could not be asked is left alone.
+	retryInterval = 15 * time.Minute
+	tickInterval  = time.Minute
+)
+
+// ReportSpec is the API payload of RequestDeviceReport. Without component criteria or variables the
+// charge point is asked for a base report, FullInventory by default; with them, for GetReport.
+type ReportSpec struct {
+	ChargePointId     string                                `json:"chargePointId"`
+	ReportBase        provisioning.ReportBaseType           `json:"reportBase,omitempty"`
+	ComponentCriteria []provisioning.ComponentCriterionType `json:"componentCriteria,omitempty"`
+	ComponentVariable []provisioning.ComponentVariableType  `json:"componentVariable,omitempty"`
+}
+
+// HistorySpec is the API payload of GetDeviceVariableHistory; without a start time the whole
+// history is returned.
+type HistorySpec struct {
+	ChargePointId string          `json:"chargePointId"`
+	Since         *types.DateTime `json:"since,omitempty"`
+}
+
+// ReportStatus is the charge point's answer to a report request.
+type ReportStatus struct {
+	ChargePointId string `json:"chargePointId"`
+	RequestId     int    `json:"requestId"`
+	Status        string `json:"status"`
+}
+
+// report collects the NotifyReport parts of one request until the last of them has arrived.
+type report struct {
+	full        bool // a FullInventory: what it leaves out is gone from the charge point
+	parts       map[int][]provisioning.ReportData
+	lastSeqNo   int // of the part with tbc false, -1 until it arrives
+	generatedAt time.Time
+	updated     time.Time
+}
+
+type reportKey struct {
+	chargePointId string
+	requestId     int
+}
+
+// station is what the manager knows of a charge point that booted while it was running.
+type station struct {
+	lastInventory time.Time
+	lastRequest   time.Time
+	requesting    bool
+}
+
+// Manager keeps the device model of the OCPP 2.0.1 charge points, one record per attribute of a
+// variable. It asks a charge point for its full inventory after each boot and once a day, stitches
+// the parts of every report together, and stores what differs from the stored model along with a
+// history of the changes.
+type Manager struct {
+	database  Repository
+	server    Handler
+	log       internal.LogHandler
+	reports   map[reportKey]*report
+	stations  map[string]*station
+	requestId int
+	// fields rather than constants so a test can drive the schedule without waiting it out
+	reportDelay time.Duration
+	sendTimeout time.Duration
+	now         func() time.Time
+	mutex       sync.Mutex
+}
+
+func NewManager(database Repository, server Handler, log internal.LogHandler) *Manager {
+	return &Manager{
+		database:    database,
+		server:      server,
+		log:         log,
+		reports:     make(map[reportKey]*report),
+		stations:    make(map[string]*station),
+		reportDelay: reportDelay,
+		sendTimeout: sendTimeout,
+		now:         time.Now,
+	}
+}
+
+// OnSystemStart refreshes the inventories that got old and drops reports that stopped halfway.
+func (m *Manager) OnSystemStart() {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+	for {
+		<-ticker.C
+		m.tick()
+	}
+}
+
+// HandleCommand serves the device model API commands and returns the JSON answer.
+func (m *Manager) HandleCommand(command, payload string) ([]byte, error) {
+	switch command {
+	case QueryFeatureName:
+		var query entity.DeviceVariableQuery
+		if err := json.Unmarshal([]byte(payload), &query); err != nil {
+			return nil, fmt.Errorf("invalid payload")
+		}
+		variables, err := m.Query(&query)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(variables)
+	case HistoryFeatureName:
+		var spec HistorySpec
+		if err := json.Unmarshal([]byte(payload), &spec); err != nil {
+			return nil, fmt.Errorf("invalid payload")
+		}
+		changes, err := m.History(&spec)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(changes)
+	case ReportFeatureName:
+		var spec ReportSpec
+		if err := json.Unmarshal([]byte(payload), &spec); err != nil {
+			return nil, fmt.Errorf("invalid payload")
+		}
+		status, err := m.RequestReport(&spec)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(status)
+	default:
+		return nil, fmt.Errorf("unknown device model command: %s", command)
+	}
+}
+
+// Query finds attributes across the charge points, such as the firmware version of every one.
+func (m *Manager) Query(query *entity.DeviceVariableQuery) ([]*entity.DeviceVariable, error) {
+	if m.database == nil {
+		return nil, fmt.Errorf("the device model needs the database")
+	}
+	if query.ChargePointId == "" && query.Component == "" && query.Variable == "" {
+		return nil, fmt.Errorf("give a charge point, a component or a variable")
+	}
+	variables, err := m.database.FindDeviceVariables(query)
+	if err != nil {
+		return nil, fmt.Errorf("find device variables: %v", err)
+	}
+	if variables == nil {
+		variables = make([]*entity.DeviceVariable, 0)
+	}
+	return variables, nil
+}
+
+func (m *Manager) History(spec *HistorySpec) ([]*entity.DeviceVariableChange, error) {
+	if m.database == nil {
+		return nil, fmt.Errorf("the device model needs the database")
+	}
+	if spec.ChargePointId == "" {
+		return nil, fmt.Errorf("charge point id is required")
+	}
+	var since time.Time
+	if spec.Since != nil {
+		since = spec.Since.Time
+	}
+	changes, err := m.database.GetDeviceVariableChanges(spec.ChargePointId, since)
+	if err != nil {
+		return nil, fmt.Errorf("get device variable changes: %v", err)
+	}
+	if changes == nil {
+		changes = make([]*entity.DeviceVariableChange, 0)
+	}
+	return changes, nil
+}
+
+// RequestReport asks a charge point for a report and waits for its answer; the report itself
+// follows in NotifyReport messages.
+func (m *Manager) RequestReport(spec *ReportSpec) (*ReportStatus, error) {
+	if spec.ChargePointId == "" {
+		return nil, fmt.Errorf("charge point id is required")
+	}
+	selective := len(spec.ComponentCriteria) > 0 || len(spec.ComponentVariable) > 0
+	if selective && spec.ReportBase != "" {
+		return nil, fmt.Errorf("give either a report base or components, not both")
+	}
+	base := spec.ReportBase
+	if !selective && base == "" {
+		base = provisioning.ReportBaseFullInventory
+	}
+
+	m.mutex.Lock()
+	requestId := m.nextRequestId()
+	m.mutex.Unlock()
+
+	var request ocpp.Request
+	if selective {
+		getReport := &provisioning.GetReportRequest{
+			RequestId:         requestId,
+			ComponentCriteria: spec.ComponentCriteria,
+			ComponentVariable: spec.ComponentVariable,
+		}
+		if err := getReport.Validate(); err != nil {
+			return nil, err
+		}
+		request = getReport
+	} else {
+		getBaseReport := &provisioning.GetBaseReportRequest{RequestId: requestId, ReportBase: base}
+		if err := getBaseReport.Validate(); err != nil {
+			return nil, err
+		}
+		request = getBaseReport
+	}
+
+	// registered before the request goes out: the first part may arrive ahead of the answer
+	key := reportKey{chargePointId: spec.ChargePointId, requestId: requestId}
+	m.mutex.Lock()
+	m.reports[key] = &report{
+		full:      base == provisioning.ReportBaseFullInventory,
+		parts:     make(map[int][]provisioning.ReportData),
+		lastSeqNo: -1,
+		updated:   m.now(),
+	}
+	m.mutex.Unlock()
+
+	status, err := m.send(spec.ChargePointId, request)
+	if status != provisioning.GenericDeviceModelStatusAccepted {
+		m.mutex.Lock()
+		delete(m.reports, key)
+		m.mutex.Unlock()
+	}
+	if err != nil {
+		return nil, err
+	}
+	m.log.FeatureEvent(request.GetFeatureName(), spec.ChargePointId, fmt.Sprintf("report #%d: %s", requestId, status))
+	return &ReportStatus{ChargePointId: spec.ChargePointId, RequestId: requestId, Status: string(status)}, nil
+}
+
+// OnChargePointBoot asks a charge point that booted for its full inventory: it may have come back
+// with new firmware or a reset configuration. Reports it was sending before are dropped.
+func (m *Manager) OnChargePointBoot(chargePointId string) {
+	m.mutex.Lock()
+	for key := range m.reports {
+		if key.chargePointId == chargePointId {
+			delete(m.reports, key)
+		}
+	}
+	st := m.station(chargePointId)
+	st.requesting = true
+	st.lastRequest = m.now()
+	m.mutex.Unlock()
+
+	time.AfterFunc(m.reportDelay, func() { m.requestInventory(chargePointId) })
+}
+
+// OnNotifyReport takes a part of a report. Once every part up to the one without tbc has arrived,
+// in whatever order, the report is stored.
+func (m *Manager) OnNotifyReport(chargePointId string, request *provisioning.NotifyReportRequest) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	key := reportKey{chargePointId: chargePointId, requestId: request.RequestId}
+	r, ok := m.reports[key]
+	if !ok {
+		// not asked for by the manager, such as a GetBaseReport sent from the API directly
+		r = &report{parts: make(map[int][]provisioning.ReportData), lastSeqNo: -1}
+		m.reports[key] = r
+	}
+	r.parts[request.SeqNo] = request.ReportData
+	r.updated = m.now()
+	if request.GeneratedAt.After(r.generatedAt) {
+		r.generatedAt = request.GeneratedAt
+	}
+	if !request.Tbc {
+		r.lastSeqNo = request.SeqNo
+	}
+	if r.lastSeqNo < 0 {
+		return
+	}
+	for seqNo := 0; seqNo <= r.lastSeqNo; seqNo++ {
+		if _, ok = r.parts[seqNo]; !ok {
+			return
+		}
+	}
+	delete(m.reports, key)
+	m.store(chargePointId, request.RequestId, r)
+}
+
+// OnNotifyEvent keeps the actual values that monitors and hard-wired notifications report between
+// two inventories.
+func (m *Manager) OnNotifyEvent(chargePointId string, events []monitoring.EventDataType) {
+	if m.database == nil || len(events) == 0 {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	known, err := m.known(chargePointId)
+	if err != nil {
+		m.log.Error(fmt.Sprintf("device model of %s", chargePointId), err)
+		return
+	}
+	now := m.now()
+	var updated []*entity.DeviceVariable
+	var changes []*entity.DeviceVariableChange
+	for _, event := range events {
+		variable := &entity.DeviceVariable{
+			AttributeType: string(provisioning.AttributeTypeActual),
+			Value:         event.ActualValue,
+			ReportedAt:    event.Timestamp,
+			TimeUpdated:   now,
+		}
+		identify(variable, chargePointId, event.Component.Name, event.Component.Instance, event.Component.Evse, event.Variable.Name, event.Variable.Instance)
+		old, found := known[variable.Key()]
+		if found {
+			if old.Value == variable.Value {
+				continue
+			}
+			// the event carries the value only; the rest is as last reported
+			value := *old
+			value.Value = variable.Value
+			value.ReportedAt = variable.ReportedAt
+			value.TimeUpdated = now
+			variable = &value
+		}
+		known[variable.Key()] = variable
+		updated = append(updated, variable)
+		changes = append(changes, change(variable, old, monitoring.NotifyEventFeatureName, 0, now))
+	}
+	m.save(chargePointId, updated, nil, changes)
+}
+
+// tick refreshes the inventories that got old and drops the reports that stopped halfway.
+func (m *Manager) tick() {
+	m.mutex.Lock()
+	now := m.now()
+	for key, r := range m.reports {
+		if now.Sub(r.updated) >= partTimeout {
+			m.log.FeatureEvent(featureName, key.chargePointId, fmt.Sprintf("report #%d dropped: %d parts, no more arrived", key.requestId, len(r.parts)))
+			delete(m.reports, key)
+		}
+	}
+	var due []string
+	for chargePointId, st := range m.stations {
+		if st.requesting || now.Sub(st.lastInventory) < refreshInterval || now.Sub(st.lastRequest) < retryInterval {
+			continue
+		}
+		st.requesting = true
+		st.lastRequest = now
+		due = append(due, chargePointId)
+	}
+	m.mutex.Unlock()
+
+	for _, chargePointId := range due {
+		go m.requestInventory(chargePointId)
+	}
+}
+
+func (m *Manager) requestInventory(chargePointId string) {
+	_, err := m.RequestReport(&ReportSpec{ChargePointId: chargePointId})
+	if err != nil {
+		m.log.FeatureEvent(provisioning.GetBaseReportFeatureName, chargePointId, fmt.Sprintf("inventory not requested: %s", err))
+	}
+	m.mutex.Lock()
+	m.station(chargePointId).requesting = false
+	m.mutex.Unlock()
+}
+
+// send delivers a report request and returns the charge point's answer to it.
+func (m *Manager) send(chargePointId string, request ocpp.Request) (provisioning.GenericDeviceModelStatusType, error) {
+	response, release, err := m.server.SendRequestWithResponse(chargePointId, request)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	var reply ocpp.Answer
+	select {
+	case reply = <-response:
+	case <-time.After(m.sendTimeout):
+		return "", fmt.Errorf("no response to %s", request.GetFeatureName())
+	}
+	if reply.Err != nil {
+		return "", fmt.Errorf("%s refused: %w", request.GetFeatureName(), reply.Err)
+	}
+	var answer provisioning.GetReportResponse
+	if err = json.Unmarshal([]byte(reply.Payload), &answer); err != nil || answer.Status == "" {
+		return "", fmt.Errorf("invalid response to %s", request.GetFeatureName())
+	}
+	return answer.Status, nil
+}
+
+// store compares a complete report with the stored model and saves what differs. Only a full
+// inventory removes the attributes it does not mention. Called with m.mutex held.
+func (m *Manager) store(chargePointId string, requestId int, r *report) {
+	if r.full {
+		m.station(chargePointId).lastInventory = m.now()
+	}
+	if m.database == nil {
+		return
+	}
+	known, err := m.known(chargePointId)
+	if err != nil {
+		m.log.Error(fmt.Sprintf("device model of %s", chargePointId), err)
+		return
+	}
+
+	now := m.now()
+	reported := make(map[string]bool)
+	var updated, removed []*entity.DeviceVariable
+	var changes []*entity.DeviceVariableChange
+	count := 0
+	for seqNo := 0; seqNo <= r.lastSeqNo; seqNo++ {
+		for _, data := range r.parts[seqNo] {
+			for _, attribute := range data.VariableAttribute {
+				variable := reportedVariable(chargePointId, &data, &attribute, r.generatedAt, now)
+				key := variable.Key()
+				reported[key] = true
+				count++
+				old, found := known[key]
+				if found && sameAttribute(old, variable) {
+					continue
+				}
+				updated = append(updated, variable)
+				if !found || old.Value != variable.Value {
+					changes = append(changes, change(variable, old, provisioning.NotifyReportFeatureName, requestId, now))
+				}
+			}
+		}
+	}
+	if r.full {
+		for key, old := range known {
+			if reported[key] {
+				continue
+			}
+			removed = append(removed, old)
+			changes = append(changes, &entity.DeviceVariableChange{
+				ChargePointId: chargePointId,
+				Key:           key,
+				Change:        entity.DeviceVariableRemoved,
+				OldValue:      old.Value,
+				Source:        provisioning.NotifyReportFeatureName,
+				RequestId:     requestId,
+				Time:          now,
+			})
+		}
+	}
+	m.save(chargePointId, updated, removed, changes)
+	m.log.FeatureEvent(featureName, chargePointId, fmt.Sprintf("report #%d: %d attributes, %d saved, %d removed, %d changes",
+		requestId, count, len(updated), len(removed), len(changes)))
+}
+
+// save writes the attributes and the history of their changes. Called with m.mutex held.
+func (m *Manager) save(chargePointId string, updated, removed []*entity.DeviceVariable, changes []*entity.DeviceVariableChange) {
+	if len(updated) > 0 {
+		if err := m.database.SaveDeviceVariables(updated); err != nil {
+			m.log.Error(fmt.Sprintf("save device model of %s", chargePointId), err)
+			return
+		}
+	}
+	if len(removed) > 0 {
+		if err := m.database.DeleteDeviceVariables(removed); err != nil {
+			m.log.Error(fmt.Sprintf("remove from device model of %s", chargePointId), err)
+			return
+		}
+	}
+	if len(changes) > 0 {
+		sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+		if err := m.database.AddDeviceVariableChanges(changes); err != nil {
+			m.log.Error(fmt.Sprintf("device model history of %s", chargePointId), err)
+		}
+	}
+}
+
+// known returns the stored attributes of a charge point by key.
+func (m *Manager) known(chargePointId string) (map[string]*entity.DeviceVariable, error) {
+	variables, err := m.database.GetDeviceVariables(chargePointId)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]*entity.DeviceVariable, len(variables))
+	for _, variable := range variables {
+		known[variable.Key()] = variable
+	}
+	return known, nil
+}
+
+// station returns the schedule of a charge point, adding it on first use. Called with m.mutex held.
+func (m *Manager) station(chargePointId string) *station {
+	st, ok := m.stations[chargePointId]
+	if !ok {
+		st = &station{}
+		m.stations[chargePointId] = st
+	}
+	return st
+}
+
+// nextRequestId starts from the clock, so ids of reports requested before a restart are not
+// reused. Called with m.mutex held.
+func (m *Manager) nextRequestId() int {
+	if m.requestId == 0 {
+		m.requestId = int(m.now().Unix() % 1000000000)
+	}
+	m.requestId++
+	return m.requestId
+}
+
+func reportedVariable(chargePointId string, data *provisioning.ReportData, attribute *provisioning.VariableAttribute, reportedAt, now time.Time) *entity.DeviceVariable {
+	variable := &entity.DeviceVariable{
+		AttributeType: string(attribute.Type),
+		Value:         attribute.Value,
+		Mutability:    string(attribute.Mutability),
+		Persistent:    attribute.Persistent,
+		Constant:      attribute.Constant,
+		ReportedAt:    reportedAt,
+		TimeUpdated:   now,
+	}
+	if variable.AttributeType == "" {
+		variable.AttributeType = string(provisioning.AttributeTypeActual)
+	}
+	identify(variable, chargePointId, data.Component.Name, data.Component.Instance, data.Component.Evse, data.Variable.Name, data.Variable.Instance)
+	if c := data.VariableCharacteristics; c != nil {
+		variable.DataType = string(c.DataType)
+		variable.Unit = c.Unit
+		variable.MinLimit = c.MinLimit
+		variable.MaxLimit = c.MaxLimit
+		variable.ValuesList = c.ValuesList
+		variable.SupportsMonitoring = c.SupportsMonitoring
+	}
+	return variable
+}
+
+func identify(variable *entity.DeviceVariable, chargePointId, component, componentInstance string, evse *v201.EVSE, name, instance string) {
+	variable.ChargePointId = chargePointId
+	variable.Component = component
+	variable.ComponentInstance = componentInstance
+	variable.Variable = name
+	variable.VariableInstance = instance
+	if evse != nil {
+		evseId := evse.Id
+		variable.EvseId = &evseId
+		variable.ConnectorId = evse.ConnectorId
+	}
+}
+
+// sameAttribute tells whether a report leaves an attribute as stored; when it was reported is not
+// part of it.
+func sameAttribute(stored, reported *entity.DeviceVariable) bool {
+	a, b := *stored, *reported
+	a.ReportedAt, b.ReportedAt = time.Time{}, time.Time{}
+	a.TimeUpdated, b.TimeUpdated = time.Time{}, time.Time{}
+	return reflect.DeepEqual(a, b)
+}
+
+func change(variable, old *entity.DeviceVariable, source string, requestId int, now time.Time) *entity.DeviceVariableChange {
+	c := &entity.DeviceVariableChange{
+		ChargePointId: variable.ChargePointId,
+		Key:           variable.Key(),
+		Change:        entity.DeviceVariableAdded,
+		NewValue:      variable.Value,
+		Source:        source,
+		RequestId:     requestId,
+		Time:          now,
+	}
+	if old != nil {
+		c.Change = entity.DeviceVariableChanged
+		c.OldValue = old.Value
+	}
+	return c
+}
@@ -0,0 +1,372 @@
+package devicemodel
+
+import (
+	"encoding/json"
+	"errors"
+	"evsys/entity"
+	"evsys/ocpp"
+	"evsys/ocpp/v201"
+	"evsys/ocpp/v201/monitoring"
+	"evsys/ocpp/v201/provisioning"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubRepo keeps the device model in memory by charge point and key.
+type stubRepo struct {
+	mutex     sync.Mutex
+	variables map[string]map[string]*entity.DeviceVariable
+	changes   []*entity.DeviceVariableChange
+}
+
+func (s *stubRepo) GetDeviceVariables(chargePointId string) ([]*entity.DeviceVariable, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var variables []*entity.DeviceVariable
+	for _, variable := range s.variables[chargePointId] {
+		stored := *variable
+		variables = append(variables, &stored)
+	}
+	return variables, nil
+}
+
+func (s *stubRepo) FindDeviceVariables(query *entity.DeviceVariableQuery) ([]*entity.DeviceVariable, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var variables []*entity.DeviceVariable
+	for chargePointId, model := range s.variables {
+		if query.ChargePointId != "" && query.ChargePointId != chargePointId {
+			continue
+		}
+		for _, variable := range model {
+			if (query.Component == "" || query.Component == variable.Component) &&
+				(query.Variable == "" || query.Variable == variable.Variable) &&
+				(query.AttributeType == "" || query.AttributeType == variable.AttributeType) &&
+				(query.Value == "" || query.Value == variable.Value) {
+				variables = append(variables, variable)
+			}
+		}
+	}
+	return variables, nil
+}
+
+func (s *stubRepo) SaveDeviceVariables(variables []*entity.DeviceVariable) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, variable := range variables {
+		if s.variables[variable.ChargePointId] == nil {
+			s.variables[variable.ChargePointId] = make(map[string]*entity.DeviceVariable)
+		}
+		s.variables[variable.ChargePointId][variable.Key()] = variable
+	}
+	return nil
+}
+
+func (s *stubRepo) DeleteDeviceVariables(variables []*entity.DeviceVariable) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, variable := range variables {
+		delete(s.variables[variable.ChargePointId], variable.Key())
+	}
+	return nil
+}
+
+func (s *stubRepo) AddDeviceVariableChanges(changes []*entity.DeviceVariableChange) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.changes = append(s.changes, changes...)
+	return nil
+}
+
+func (s *stubRepo) GetDeviceVariableChanges(chargePointId string, since time.Time) ([]*entity.DeviceVariableChange, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var changes []*entity.DeviceVariableChange
+	for _, c := range s.changes {
+		if c.ChargePointId == chargePointId && !c.Time.Before(since) {
+			changes = append(changes, c)
+		}
+	}
+	return changes, nil
+}
+
+func (s *stubRepo) value(chargePointId, key string) (string, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	variable, ok := s.variables[chargePointId][key]
+	if !ok {
+		return "", false
+	}
+	return variable.Value, true
+}
+
+// stubServer answers every report request with answer, except on charge points listed as offline,
+// and keeps what it was sent.
+type stubServer struct {
+	mutex   sync.Mutex
+	offline map[string]bool
+	answer  string
+	sent    map[string][]ocpp.Request
+}
+
+func (s *stubServer) SendRequestWithResponse(clientId string, request ocpp.Request) (<-chan ocpp.Answer, func(), error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.offline[clientId] {
+		return nil, nil, errors.New("charge point not available")
+	}
+	s.sent[clientId] = append(s.sent[clientId], request)
+	response := make(chan ocpp.Answer, 1)
+	response <- ocpp.Answer{Payload: s.answer}
+	return response, func() {}, nil
+}
+
+func (s *stubServer) sentTo(clientId string) []ocpp.Request {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]ocpp.Request{}, s.sent[clientId]...)
+}
+
+type stubLog struct{}
+
+func (stubLog) FeatureEvent(_, _, _ string) {}
+func (stubLog) RawDataEvent(_, _ string)    {}
+func (stubLog) Debug(_ string)              {}
+func (stubLog) Warn(_ string)               {}
+func (stubLog) Error(_ string, _ error)     {}
+
+func newTestManager() (*Manager, *stubRepo, *stubServer, *time.Time) {
+	repo := &stubRepo{variables: map[string]map[string]*entity.DeviceVariable{}}
+	server := &stubServer{offline: map[string]bool{}, answer: `{"status":"Accepted"}`, sent: map[string][]ocpp.Request{}}
+	m := NewManager(repo, server, stubLog{})
+	now := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	m.reportDelay = 0
+	return m, repo, server, &now
+}
+
+func reportData(component string, evseId int, variable, value string) provisioning.ReportData {
+	data := provisioning.ReportData{
+		Component: v201.Component{Name: component},
+		Variable:  v201.Variable{Name: variable},
+		VariableAttribute: []provisioning.VariableAttribute{{
+			Type:       provisioning.AttributeTypeActual,
+			Value:      value,
+			Mutability: provisioning.MutabilityReadOnly,
+		}},
+	}
+	if evseId > 0 {
+		data.Component.Evse = &v201.EVSE{Id: evseId}
+	}
+	return data
+}
+
+func notifyReport(requestId, seqNo int, tbc bool, data ...provisioning.ReportData) *provisioning.NotifyReportRequest {
+	return &provisioning.NotifyReportRequest{
+		RequestId:   requestId,
+		GeneratedAt: time.Date(2024, 5, 1, 7, 59, 0, 0, time.UTC),
+		ReportData:  data,
+		SeqNo:       seqNo,
+		Tbc:         tbc,
+	}
+}
+
+// waitForRequest blocks until the manager has sent a charge point count report requests; the
+// request after a boot goes out on a goroutine of its own.
+func waitForRequest(t *testing.T, server *stubServer, chargePointId string, count int) []ocpp.Request {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if sent := server.sentTo(chargePointId); len(sent) >= count {
+			return sent
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("%s was sent %d requests, want %d", chargePointId, len(server.sentTo(chargePointId)), count)
+	return nil
+}
+
+func TestReportPartsAreStitchedInAnyOrder(t *testing.T) {
+	m, repo, _, _ := newTestManager()
+
+	m.OnNotifyReport("cp1", notifyReport(7, 1, true, reportData("EVSE", 1, "Power", "22000")))
+	m.OnNotifyReport("cp1", notifyReport(7, 2, false, reportData("SecurityCtrlr", 0, "SecurityProfile", "2")))
+	if _, ok := repo.value("cp1", "EVSE@1/Power#Actual"); ok {
+		t.Fatal("stored a report with its first part missing")
+	}
+	m.OnNotifyReport("cp1", notifyReport(7, 0, true, reportData("ChargingStation", 0, "Model", "X1")))
+
+	for key, want := range map[string]string{
+		"ChargingStation/Model#Actual":         "X1",
+		"EVSE@1/Power#Actual":                  "22000",
+		"SecurityCtrlr/SecurityProfile#Actual": "2",
+	} {
+		if value, ok := repo.value("cp1", key); !ok || value != want {
+			t.Errorf("%s = %q, %v; want %q", key, value, ok, want)
+		}
+	}
+	if len(repo.changes) != 3 || repo.changes[0].Change != entity.DeviceVariableAdded || repo.changes[0].RequestId != 7 {
+		t.Errorf("changes %+v, want three additions of report 7", repo.changes)
+	}
+	if len(m.reports) != 0 {
+		t.Errorf("%d reports left open", len(m.reports))
+	}
+}
+
+// Only a full inventory tells which attributes are gone; a report asked for from outside the
+// manager only adds and changes.
+func TestFullInventoryRecordsChanges(t *testing.T) {
+	m, repo, server, now := newTestManager()
+
+	m.OnChargePointBoot("cp1")
+	sent := waitForRequest(t, server, "cp1", 1)
+	first, ok := sent[0].(*provisioning.GetBaseReportRequest)
+	if !ok || first.ReportBase != provisioning.ReportBaseFullInventory {
+		t.Fatalf("sent %+v after the boot, want a FullInventory base report", sent[0])
+	}
+	m.OnNotifyReport("cp1", notifyReport(first.RequestId, 0, false,
+		reportData("ChargingStation", 0, "Model", "X1"),
+		reportData("OCPPCommCtrlr", 0, "HeartbeatInterval", "300")))
+
+	*now = now.Add(time.Hour)
+	m.OnNotifyReport("cp1", notifyReport(99, 0, false, reportData("OCPPCommCtrlr", 0, "HeartbeatInterval", "60")))
+	if value, _ := repo.value("cp1", "ChargingStation/Model#Actual"); value != "X1" {
+		t.Fatal("a partial report removed what it did not mention")
+	}
+
+	status, err := m.RequestReport(&ReportSpec{ChargePointId: "cp1"})
+	if err != nil || status.Status != "Accepted" {
+		t.Fatalf("RequestReport: %+v, %v", status, err)
+	}
+	m.OnNotifyReport("cp1", notifyReport(status.RequestId, 0, false, reportData("OCPPCommCtrlr", 0, "HeartbeatInterval", "60")))
+
+	if _, ok := repo.value("cp1", "ChargingStation/Model#Actual"); ok {
+		t.Error("the full inventory left a variable the charge point no longer has")
+	}
+	history, err := m.History(&HistorySpec{ChargePointId: "cp1"})
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if len(history) != 4 {
+		t.Fatalf("history %+v, want 2 additions, a change and a removal", history)
+	}
+	changed, removed := history[2], history[3]
+	if changed.Change != entity.DeviceVariableChanged || changed.OldValue != "300" || changed.NewValue != "60" || changed.RequestId != 99 {
+		t.Errorf("change %+v, want HeartbeatInterval from 300 to 60", changed)
+	}
+	if removed.Change != entity.DeviceVariableRemoved || removed.Key != "ChargingStation/Model#Actual" {
+		t.Errorf("change %+v, want the model removed", removed)
+	}
+}
+
+func TestUnchangedReportIsNotSaved(t *testing.T) {
+	m, repo, _, now := newTestManager()
+
+	m.OnNotifyReport("cp1", notifyReport(1, 0, false, reportData("ChargingStation", 0, "Model", "X1")))
+	saved := repo.variables["cp1"]["ChargingStation/Model#Actual"].TimeUpdated
+
+	*now = now.Add(time.Hour)
+	m.OnNotifyReport("cp1", notifyReport(2, 0, false, reportData("ChargingStation", 0, "Model", "X1")))
+	if !repo.variables["cp1"]["ChargingStation/Model#Actual"].TimeUpdated.Equal(saved) || len(repo.changes) != 1 {
+		t.Errorf("an unchanged attribute was saved again: %d changes", len(repo.changes))
+	}
+}
+
+func TestEventUpdatesActualValue(t *testing.T) {
+	m, repo, _, _ := newTestManager()
+	m.OnNotifyReport("cp1", notifyReport(1, 0, false, reportData("EVSE", 1, "AvailabilityState", "Available")))
+
+	m.OnNotifyEvent("cp1", []monitoring.EventDataType{{
+		EventId:     1,
+		Timestamp:   time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC),
+		Trigger:     monitoring.EventTriggerDelta,
+		ActualValue: "Occupied",
+		Component:   v201.Component{Name: "EVSE", Evse: &v201.EVSE{Id: 1}},
+		Variable:    v201.Variable{Name: "AvailabilityState"},
+	}})
+
+	variable := repo.variables["cp1"]["EVSE@1/AvailabilityState#Actual"]
+	if variable.Value != "Occupied" || variable.Mutability != string(provisioning.MutabilityReadOnly) {
+		t.Errorf("variable %+v, want Occupied with the reported mutability", variable)
+	}
+	last := repo.changes[len(repo.changes)-1]
+	if last.Source != monitoring.NotifyEventFeatureName || last.OldValue != "Available" {
+		t.Errorf("change %+v, want the event recorded", last)
+	}
+}
+
+func TestRejectedRequestDropsReport(t *testing.T) {
+	m, _, server, _ := newTestManager()
+	server.answer = `{"status":"NotSupported"}`
+
+	status, err := m.RequestReport(&ReportSpec{
+		ChargePointId:     "cp1",
+		ComponentVariable: []provisioning.ComponentVariableType{{Component: v201.Component{Name: "EVSE"}}},
+	})
+	if err != nil || status.Status != "NotSupported" {
+		t.Fatalf("RequestReport: %+v, %v", status, err)
+	}
+	if _, ok := server.sentTo("cp1")[0].(*provisioning.GetReportRequest); !ok {
+		t.Errorf("sent %+v, want GetReport for a component", server.sentTo("cp1")[0])
+	}
+	if len(m.reports) != 0 {
+		t.Errorf("%d reports kept for a rejected request", len(m.reports))
+	}
+}
+
+func TestInventoryRefreshedAndStaleReportDropped(t *testing.T) {
+	m, _, server, now := newTestManager()
+	server.offline["cp1"] = true
+
+	m.OnChargePointBoot("cp1")
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		m.mutex.Lock()
+		requesting := m.stations["cp1"].requesting
+		m.mutex.Unlock()
+		if !requesting {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	m.OnNotifyReport("cp1", notifyReport(5, 0, true))
+
+	server.mutex.Lock()
+	server.offline["cp1"] = false
+	server.mutex.Unlock()
+	*now = now.Add(retryInterval)
+	m.tick()
+
+	sent := waitForRequest(t, server, "cp1", 1)
+	if _, ok := sent[0].(*provisioning.GetBaseReportRequest); !ok {
+		t.Errorf("sent %+v, want the inventory asked for again", sent[0])
+	}
+	m.mutex.Lock()
+	_, open := m.reports[reportKey{chargePointId: "cp1", requestId: 5}]
+	m.mutex.Unlock()
+	if open {
+		t.Error("a report waiting longer than partTimeout for its next part was kept")
+	}
+}
+
+func TestQueryAcrossChargePoints(t *testing.T) {
+	m, _, _, _ := newTestManager()
+	m.OnNotifyReport("cp1", notifyReport(1, 0, false, reportData("ChargingStation", 0, "FirmwareVersion", "1.2")))
+	m.OnNotifyReport("cp2", notifyReport(1, 0, false, reportData("ChargingStation", 0, "FirmwareVersion", "1.3")))
+
+	data, err := m.HandleCommand(QueryFeatureName, `{"variable":"FirmwareVersion","value":"1.2"}`)
+	if err != nil {
+		t.Fatalf("HandleCommand: %v", err)
+	}
+	var variables []*entity.DeviceVariable
+	if err = json.Unmarshal(data, &variables); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(variables) != 1 || variables[0].ChargePointId != "cp1" {
+		t.Errorf("found %+v, want the variable of cp1 only", variables)
+	}
+	if _, err = m.HandleCommand(QueryFeatureName, `{}`); err == nil {
+		t.Error("a query without a charge point, component or variable was served")
+	}
+}
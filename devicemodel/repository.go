@@ -0,0 +1,17 @@
+package devicemodel
+
+import (
+	"evsys/entity"
+	"time"
+)
+
+type Repository interface {
+	GetDeviceVariables(chargePointId string) ([]*entity.DeviceVariable, error)
+	FindDeviceVariables(query *entity.DeviceVariableQuery) ([]*entity.DeviceVariable, error)
+	// SaveDeviceVariables replaces each attribute with the same charge point, component, variable
+	// and attribute type, or adds it
+	SaveDeviceVariables(variables []*entity.DeviceVariable) error
+	DeleteDeviceVariables(variables []*entity.DeviceVariable) error
+	AddDeviceVariableChanges(changes []*entity.DeviceVariableChange) error
+	GetDeviceVariableChanges(chargePointId string, since time.Time) ([]*entity.DeviceVariableChange, error)
+}
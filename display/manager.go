@@ -0,0 +1,535 @@
+package display
+
+import (
+	"encoding/json"
+	"evsys/entity"
+	"evsys/internal"
+	"evsys/ocpp"
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+	"evsys/ocpp/v201/displaymessage"
+	"evsys/types"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	featureName = "DisplayMessages"
+
+	// API commands served by the manager; they address the charge points of a location rather than one
+	ScheduleFeatureName = "ScheduleDisplayMessage"
+	GetFeatureName      = "GetScheduledDisplayMessages"
+	CancelFeatureName   = "CancelDisplayMessage"
+
+	// maxLanguages bounds the contents of a message. Each language is installed on the charge point
+	// as a message of its own, numbered Id*maxLanguages + index, so the ids never collide.
+	maxLanguages = 10
+	// sendTimeout bounds the wait for each answer. A silent charge point is tried again later, as
+	// installing a message with the same id only replaces it.
+	sendTimeout = 30 * time.Second
+	// offlineRetryInterval is how long a charge point that could not be reached is left alone
+	// before the next attempt.
+	offlineRetryInterval = 5 * time.Minute
+	tickInterval         = time.Minute
+)
+
+// Spec is the API payload of ScheduleDisplayMessage. A message without a start time is shown at
+// once, one without an end time until it is cancelled.
+type Spec struct {
+	LocationId string                   `json:"locationId"`
+	Priority   string                   `json:"priority,omitempty"`
+	State      string                   `json:"state,omitempty"`
+	StartTime  *types.DateTime          `json:"startTime,omitempty"`
+	EndTime    *types.DateTime          `json:"endTime,omitempty"`
+	Messages   []*entity.DisplayContent `json:"messages"`
+}
+
+// Report is a message as returned by the API, with its charge points counted per status.
+type Report struct {
+	*entity.DisplayMessage
+	Delivery map[string]int `json:"delivery"`
+}
+
+// Manager shows scheduled messages on the displays of the OCPP 2.0.1 charge points of a location:
+// it installs a message when its start time comes, removes it when its end time passes or it is
+// cancelled, and installs it again on a charge point that reboots in the meantime.
+type Manager struct {
+	database Repository
+	server   Handler
+	log      internal.LogHandler
+	messages map[int]*entity.DisplayMessage // messages still to be shown, now or later
+	lastId   int
+	// fields rather than constants so a test can drive the schedule without waiting it out
+	sendTimeout time.Duration
+	now         func() time.Time
+	mutex       sync.Mutex
+}
+
+func NewManager(database Repository, server Handler, log internal.LogHandler) *Manager {
+	return &Manager{
+		database:    database,
+		server:      server,
+		log:         log,
+		messages:    make(map[int]*entity.DisplayMessage),
+		sendTimeout: sendTimeout,
+		now:         time.Now,
+	}
+}
+
+// Load continues the ids of the previous process and picks up the messages it left to show. It has
+// to run before the API accepts commands: a message numbered from scratch would take the id of a
+// stored one, and the message ids derived from it on the stations.
+func (m *Manager) Load() error {
+	if m.database == nil {
+		return nil
+	}
+	last, err := m.database.GetLastDisplayMessage()
+	if err != nil {
+		return fmt.Errorf("load last display message: %v", err)
+	}
+	current, err := m.database.GetCurrentDisplayMessages()
+	if err != nil {
+		return fmt.Errorf("load current display messages: %v", err)
+	}
+	m.mutex.Lock()
+	if last != nil {
+		m.lastId = last.Id
+	}
+	for _, message := range current {
+		m.messages[message.Id] = message
+	}
+	m.mutex.Unlock()
+	if len(current) > 0 {
+		m.log.FeatureEvent(featureName, "", fmt.Sprintf("resumed %d display messages", len(current)))
+	}
+	return nil
+}
+
+// OnSystemStart follows the schedule for the lifetime of the process.
+func (m *Manager) OnSystemStart() {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+	for {
+		m.tick()
+		<-ticker.C
+	}
+}
+
+// HandleCommand serves the display message API commands and returns the JSON answer.
+func (m *Manager) HandleCommand(command, payload string) ([]byte, error) {
+	switch command {
+	case ScheduleFeatureName:
+		var spec Spec
+		if err := json.Unmarshal([]byte(payload), &spec); err != nil {
+			return nil, fmt.Errorf("invalid payload")
+		}
+		report, err := m.Schedule(&spec)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(report)
+	case GetFeatureName:
+		if strings.TrimSpace(payload) == "" {
+			return json.Marshal(m.List())
+		}
+		id, err := strconv.Atoi(strings.TrimSpace(payload))
+		if err != nil {
+			return nil, fmt.Errorf("invalid message id")
+		}
+		report, err := m.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(report)
+	case CancelFeatureName:
+		id, err := strconv.Atoi(strings.TrimSpace(payload))
+		if err != nil {
+			return nil, fmt.Errorf("invalid message id")
+		}
+		report, err := m.Cancel(id)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(report)
+	default:
+		return nil, fmt.Errorf("unknown display message command: %s", command)
+	}
+}
+
+func (m *Manager) Schedule(spec *Spec) (*Report, error) {
+	if m.database == nil {
+		return nil, fmt.Errorf("display messages need the database")
+	}
+	if spec.LocationId == "" {
+		return nil, fmt.Errorf("location id is required")
+	}
+	if len(spec.Messages) == 0 || len(spec.Messages) > maxLanguages {
+		return nil, fmt.Errorf("give from 1 to %d messages, one per language", maxLanguages)
+	}
+
+	now := m.now()
+	message := &entity.DisplayMessage{
+		LocationId:  spec.LocationId,
+		Priority:    spec.Priority,
+		State:       spec.State,
+		StartTime:   now,
+		Status:      entity.DisplayMessageScheduled,
+		TimeCreated: now,
+		Stations:    make([]*entity.DisplayStation, 0),
+	}
+	if message.Priority == "" {
+		message.Priority = string(v201.MessagePriorityNormalCycle)
+	}
+	if spec.StartTime != nil {
+		message.StartTime = spec.StartTime.Time
+	}
+	if spec.EndTime != nil {
+		end := spec.EndTime.Time
+		if !end.After(now) {
+			return nil, fmt.Errorf("the message would have ended already")
+		}
+		message.EndTime = &end
+	}
+	languages := make(map[string]bool, len(spec.Messages))
+	for _, content := range spec.Messages {
+		if content == nil {
+			return nil, fmt.Errorf("empty message")
+		}
+		if languages[content.Language] {
+			return nil, fmt.Errorf("more than one message in language %q", content.Language)
+		}
+		languages[content.Language] = true
+		if content.Format == "" {
+			content.Format = displaymessage.MessageFormatUTF8
+		}
+		message.Contents = append(message.Contents, content)
+	}
+	// the charge point would refuse what does not validate here, one charge point at a time
+	for _, request := range setRequests(message) {
+		if err := request.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.lastId++
+	message.Id = m.lastId
+	if err := m.database.AddDisplayMessage(message); err != nil {
+		return nil, fmt.Errorf("save display message: %v", err)
+	}
+	m.messages[message.Id] = message
+	m.log.FeatureEvent(featureName, "", fmt.Sprintf("message #%d for location %s: %d languages from %s",
+		message.Id, message.LocationId, len(message.Contents), message.StartTime.Format(time.RFC3339)))
+	m.dispatch(message)
+	return report(message), nil
+}
+
+func (m *Manager) Get(id int) (*Report, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if message, ok := m.messages[id]; ok {
+		return report(message), nil
+	}
+	if m.database == nil {
+		return nil, fmt.Errorf("display message %d not found", id)
+	}
+	message, err := m.database.GetDisplayMessage(id)
+	if err != nil {
+		return nil, fmt.Errorf("display message %d not found", id)
+	}
+	return report(message), nil
+}
+
+// List returns the messages still to be shown, now or later.
+func (m *Manager) List() []*Report {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	reports := make([]*Report, 0, len(m.messages))
+	for _, message := range m.messages {
+		reports = append(reports, report(message))
+	}
+	return reports
+}
+
+// Cancel removes a message from the displays it was installed on, ahead of its end time.
+func (m *Manager) Cancel(id int) (*Report, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	message, ok := m.messages[id]
+	if !ok {
+		return nil, fmt.Errorf("display message %d is not current", id)
+	}
+	message.Status = entity.DisplayMessageCancelled
+	m.log.FeatureEvent(featureName, "", fmt.Sprintf("message #%d cancelled", message.Id))
+	m.dispatch(message)
+	return report(message), nil
+}
+
+// OnChargePointBoot installs the active messages of its location again on a charge point that has
+// booted: a reboot may have wiped them, and a charge point new to the location has none yet.
+func (m *Manager) OnChargePointBoot(chargePointId string) {
+	if m.database == nil {
+		return
+	}
+	chargePoint, err := m.database.GetChargePoint(chargePointId)
+	if err != nil || chargePoint == nil {
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, message := range m.messages {
+		if message.Status != entity.DisplayMessageActive || message.LocationId != chargePoint.LocationId {
+			continue
+		}
+		station := findStation(message, chargePointId)
+		if station == nil {
+			station = &entity.DisplayStation{ChargePointId: chargePointId}
+			message.Stations = append(message.Stations, station)
+		} else if station.Status == entity.DisplaySending {
+			continue
+		}
+		m.setStatus(station, entity.DisplayPending, "")
+		m.dispatch(message)
+	}
+}
+
+// tick starts and ends messages on schedule and retries charge points that could not be reached.
+func (m *Manager) tick() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, message := range m.messages {
+		m.dispatch(message)
+	}
+}
+
+// dispatch moves a message along its schedule: on its start time it is sent to the charge points of
+// the location, from then on to those still waiting, and once it is over it is removed from every
+// charge point that may show it. Called with m.mutex held.
+func (m *Manager) dispatch(message *entity.DisplayMessage) {
+	now := m.now()
+	if message.Status == entity.DisplayMessageScheduled && !now.Before(message.StartTime) {
+		message.Status = entity.DisplayMessageActive
+		m.addStations(message)
+		m.log.FeatureEvent(featureName, "", fmt.Sprintf("message #%d active on %d charge points", message.Id, len(message.Stations)))
+	}
+	if message.Status == entity.DisplayMessageActive && message.EndTime != nil && !now.Before(*message.EndTime) {
+		message.Status = entity.DisplayMessageEnded
+		m.log.FeatureEvent(featureName, "", fmt.Sprintf("message #%d ended", message.Id))
+	}
+
+	switch message.Status {
+	case entity.DisplayMessageActive:
+		for _, station := range message.Stations {
+			if station.Status == entity.DisplayOffline && now.Sub(station.TimeUpdated) < offlineRetryInterval {
+				continue
+			}
+			if station.Status != entity.DisplayPending && station.Status != entity.DisplayOffline {
+				continue
+			}
+			m.setStatus(station, entity.DisplaySending, "")
+			go m.send(message.Id, station.ChargePointId, setRequests(message))
+		}
+	case entity.DisplayMessageEnded, entity.DisplayMessageCancelled:
+		for _, station := range message.Stations {
+			switch station.Status {
+			case entity.DisplayPending, entity.DisplayOffline, entity.DisplayCleared:
+				continue
+			}
+			// a message still being sent is cleared once the charge point has answered, see send
+			if station.Status != entity.DisplaySending {
+				go m.clear(station.ChargePointId, messageIds(message))
+			}
+			m.setStatus(station, entity.DisplayCleared, "")
+		}
+		delete(m.messages, message.Id)
+	}
+	m.update(message)
+}
+
+// addStations adds the OCPP 2.0.1 charge points of the message's location; display messages do not
+// exist in OCPP 1.6. Called with m.mutex held.
+func (m *Manager) addStations(message *entity.DisplayMessage) {
+	if m.database == nil {
+		return
+	}
+	chargePoints, err := m.database.GetChargePoints()
+	if err != nil {
+		m.log.Error(fmt.Sprintf("display message #%d: get charge points", message.Id), err)
+		return
+	}
+	for _, chargePoint := range chargePoints {
+		if chargePoint.LocationId != message.LocationId || !common.ProtocolVersion(chargePoint.ProtocolVersion).IsOCPP2() {
+			continue
+		}
+		if findStation(message, chargePoint.Id) != nil {
+			continue
+		}
+		message.Stations = append(message.Stations, &entity.DisplayStation{
+			ChargePointId: chargePoint.Id,
+			Status:        entity.DisplayPending,
+			TimeUpdated:   m.now(),
+		})
+	}
+}
+
+// send installs every language of a message on a charge point and records the outcome: Accepted
+// when every language was, otherwise the first answer that was not.
+func (m *Manager) send(messageId int, chargePointId string, requests []*displaymessage.SetDisplayMessageRequest) {
+	status := entity.DisplayAccepted
+	info := ""
+	for _, request := range requests {
+		response, release, err := m.server.SendRequestWithResponse(chargePointId, request)
+		if err != nil {
+			status = entity.DisplayOffline
+			info = err.Error()
+			break
+		}
+		var reply ocpp.Answer
+		select {
+		case reply = <-response:
+		case <-time.After(m.sendTimeout):
+		}
+		release()
+		if reply.Err != nil {
+			status = string(displaymessage.DisplayMessageStatusRejected)
+			info = reply.Err.Error()
+			break
+		}
+		if reply.Payload == "" {
+			status = entity.DisplayOffline
+			info = "no response to SetDisplayMessage"
+			break
+		}
+		var answer displaymessage.SetDisplayMessageResponse
+		if err = json.Unmarshal([]byte(reply.Payload), &answer); err != nil {
+			status = entity.DisplayOffline
+			info = "invalid response to SetDisplayMessage"
+			break
+		}
+		if answer.Status != displaymessage.DisplayMessageStatusAccepted {
+			status = string(answer.Status)
+			info = fmt.Sprintf("message %d, language %q", request.Message.Id, request.Message.Message.Language)
+			break
+		}
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	message, ok := m.messages[messageId]
+	if !ok {
+		// ended or cancelled while being sent
+		if status != entity.DisplayOffline {
+			ids := make([]int, 0, len(requests))
+			for _, request := range requests {
+				ids = append(ids, request.Message.Id)
+			}
+			go m.clear(chargePointId, ids)
+		}
+		return
+	}
+	station := findStation(message, chargePointId)
+	if station == nil || station.Status != entity.DisplaySending {
+		return
+	}
+	m.setStatus(station, status, info)
+	m.log.FeatureEvent(displaymessage.SetDisplayMessageFeatureName, chargePointId, fmt.Sprintf("message #%d: %s %s", messageId, status, info))
+	m.update(message)
+}
+
+// clear removes the messages from a charge point; one that cannot be reached will have dropped them
+// on its own by the end time, or shows them until it is told otherwise.
+func (m *Manager) clear(chargePointId string, ids []int) {
+	for _, id := range ids {
+		response, release, err := m.server.SendRequestWithResponse(chargePointId, &displaymessage.ClearDisplayMessageRequest{Id: id})
+		if err != nil {
+			m.log.FeatureEvent(displaymessage.ClearDisplayMessageFeatureName, chargePointId, fmt.Sprintf("message %d not cleared: %s", id, err))
+			return
+		}
+		select {
+		case <-response:
+		case <-time.After(m.sendTimeout):
+		}
+		release()
+	}
+	m.log.FeatureEvent(displaymessage.ClearDisplayMessageFeatureName, chargePointId, fmt.Sprintf("messages %v cleared", ids))
+}
+
+func (m *Manager) setStatus(station *entity.DisplayStation, status, info string) {
+	station.Status = status
+	station.Info = info
+	station.TimeUpdated = m.now()
+}
+
+func (m *Manager) update(message *entity.DisplayMessage) {
+	if m.database == nil {
+		return
+	}
+	if err := m.database.UpdateDisplayMessage(message); err != nil {
+		m.log.Error(fmt.Sprintf("update display message #%d", message.Id), err)
+	}
+}
+
+// setRequests builds the SetDisplayMessage of every language of a message.
+func setRequests(message *entity.DisplayMessage) []*displaymessage.SetDisplayMessageRequest {
+	ids := messageIds(message)
+	requests := make([]*displaymessage.SetDisplayMessageRequest, 0, len(message.Contents))
+	for i, content := range message.Contents {
+		start := message.StartTime
+		requests = append(requests, &displaymessage.SetDisplayMessageRequest{Message: displaymessage.MessageInfoType{
+			Id:            ids[i],
+			Priority:      v201.MessagePriorityType(message.Priority),
+			State:         v201.MessageStateType(message.State),
+			StartDateTime: &start,
+			EndDateTime:   message.EndTime,
+			Message: v201.MessageContent{
+				Content:  content.Content,
+				Format:   content.Format,
+				Language: content.Language,
+			},
+		}})
+	}
+	return requests
+}
+
+// messageIds are the ids the languages of a message are installed under on the charge point.
+func messageIds(message *entity.DisplayMessage) []int {
+	ids := make([]int, len(message.Contents))
+	for i := range message.Contents {
+		ids[i] = message.Id*maxLanguages + i
+	}
+	return ids
+}
+
+func findStation(message *entity.DisplayMessage, chargePointId string) *entity.DisplayStation {
+	for _, station := range message.Stations {
+		if station.ChargePointId == chargePointId {
+			return station
+		}
+	}
+	return nil
+}
+
+// report copies the message, so it can be encoded after the lock is released.
+func report(message *entity.DisplayMessage) *Report {
+	snapshot := *message
+	snapshot.Stations = make([]*entity.DisplayStation, len(message.Stations))
+	delivery := make(map[string]int)
+	for i, s := range message.Stations {
+		station := *s
+		snapshot.Stations[i] = &station
+		delivery[s.Status]++
+	}
+	return &Report{DisplayMessage: &snapshot, Delivery: delivery}
+}
@@ -0,0 +1,267 @@
+package display
+
+import (
+	"errors"
+	"evsys/entity"
+	"evsys/ocpp"
+	"evsys/ocpp/v201/displaymessage"
+	"evsys/types"
+	"sync"
+	"testing"
+	"time"
+)
+
+type stubRepo struct {
+	chargePoints []*entity.ChargePoint
+	messages     map[int]*entity.DisplayMessage
+	lastErr      error
+}
+
+func (s *stubRepo) GetChargePoints() ([]*entity.ChargePoint, error) {
+	return s.chargePoints, nil
+}
+
+func (s *stubRepo) GetChargePoint(id string) (*entity.ChargePoint, error) {
+	for _, cp := range s.chargePoints {
+		if cp.Id == id {
+			return cp, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+func (s *stubRepo) GetLastDisplayMessage() (*entity.DisplayMessage, error) {
+	if s.lastErr != nil {
+		return nil, s.lastErr
+	}
+	var last *entity.DisplayMessage
+	for _, message := range s.messages {
+		if last == nil || message.Id > last.Id {
+			last = message
+		}
+	}
+	return last, nil
+}
+
+func (s *stubRepo) GetDisplayMessage(id int) (*entity.DisplayMessage, error) {
+	if message, ok := s.messages[id]; ok {
+		return message, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func (s *stubRepo) GetCurrentDisplayMessages() ([]*entity.DisplayMessage, error) {
+	return nil, nil
+}
+
+// The manager only writes with its mutex held, so the map needs no guard of its own.
+func (s *stubRepo) AddDisplayMessage(message *entity.DisplayMessage) error {
+	s.messages[message.Id] = message
+	return nil
+}
+
+func (s *stubRepo) UpdateDisplayMessage(message *entity.DisplayMessage) error {
+	s.messages[message.Id] = message
+	return nil
+}
+
+// stubServer accepts every message, except on charge points listed as offline or with an answer
+// of their own, and keeps what it was sent.
+type stubServer struct {
+	mutex   sync.Mutex
+	offline map[string]bool
+	answers map[string]string
+	sent    map[string][]ocpp.Request
+}
+
+func (s *stubServer) SendRequestWithResponse(clientId string, request ocpp.Request) (<-chan ocpp.Answer, func(), error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.offline[clientId] {
+		return nil, nil, errors.New("charge point not available")
+	}
+	s.sent[clientId] = append(s.sent[clientId], request)
+	answer := `{"status":"Accepted"}`
+	if a, ok := s.answers[clientId]; ok {
+		answer = a
+	}
+	response := make(chan ocpp.Answer, 1)
+	response <- ocpp.Answer{Payload: answer}
+	return response, func() {}, nil
+}
+
+func (s *stubServer) sentTo(clientId string) []ocpp.Request {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]ocpp.Request{}, s.sent[clientId]...)
+}
+
+type stubLog struct{}
+
+func (stubLog) FeatureEvent(_, _, _ string) {}
+func (stubLog) RawDataEvent(_, _ string)    {}
+func (stubLog) Debug(_ string)              {}
+func (stubLog) Warn(_ string)               {}
+func (stubLog) Error(_ string, _ error)     {}
+
+func newTestManager(chargePoints ...*entity.ChargePoint) (*Manager, *stubServer, *time.Time) {
+	server := &stubServer{offline: map[string]bool{}, answers: map[string]string{}, sent: map[string][]ocpp.Request{}}
+	m := NewManager(&stubRepo{chargePoints: chargePoints, messages: map[int]*entity.DisplayMessage{}}, server, stubLog{})
+	now := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	return m, server, &now
+}
+
+func chargePoint(id, locationId, protocol string) *entity.ChargePoint {
+	return &entity.ChargePoint{Id: id, LocationId: locationId, ProtocolVersion: protocol}
+}
+
+// waitForStatus blocks until a charge point reaches status; the answer to SetDisplayMessage is
+// handled on the goroutine that sent it.
+func waitForStatus(t *testing.T, m *Manager, messageId int, chargePointId, status string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	current := ""
+	for time.Now().Before(deadline) {
+		r, err := m.Get(messageId)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if station := findStation(r.DisplayMessage, chargePointId); station != nil {
+			current = station.Status
+			if current == status {
+				return
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("%s status = %q, want %s", chargePointId, current, status)
+}
+
+func TestMessageIsShownOnScheduleInEveryLanguage(t *testing.T) {
+	m, server, now := newTestManager(
+		chargePoint("CP1", "L1", "ocpp2.0.1"),
+		chargePoint("CP2", "L1", "ocpp1.6"),
+		chargePoint("CP3", "L2", "ocpp2.0.1"),
+	)
+	start := now.Add(time.Hour)
+	r, err := m.Schedule(&Spec{
+		LocationId: "L1",
+		StartTime:  types.NewDateTime(start),
+		Messages: []*entity.DisplayContent{
+			{Language: "en", Content: "Maintenance tonight"},
+			{Language: "es", Content: "Mantenimiento esta noche"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Schedule: %v", err)
+	}
+	if r.Status != entity.DisplayMessageScheduled || len(r.Stations) != 0 {
+		t.Fatalf("report %+v, want a scheduled message not sent anywhere", r.DisplayMessage)
+	}
+
+	*now = start
+	m.tick()
+	waitForStatus(t, m, r.Id, "CP1", entity.DisplayAccepted)
+	sent := server.sentTo("CP1")
+	if len(sent) != 2 {
+		t.Fatalf("sent %d requests to CP1, want one per language", len(sent))
+	}
+	second := sent[1].(*displaymessage.SetDisplayMessageRequest).Message
+	if second.Id != r.Id*maxLanguages+1 || second.Message.Language != "es" || second.Message.Format != displaymessage.MessageFormatUTF8 {
+		t.Errorf("second message %+v, want the Spanish one in UTF8", second)
+	}
+	if len(server.sentTo("CP2")) != 0 || len(server.sentTo("CP3")) != 0 {
+		t.Error("message sent to a 1.6 charge point or to another location")
+	}
+}
+
+func TestMessageIsReinstalledAfterBootAndClearedAtEnd(t *testing.T) {
+	m, server, now := newTestManager(chargePoint("CP1", "L1", "ocpp2.0.1"), chargePoint("CP2", "L1", "ocpp2.0.1"))
+	server.answers["CP2"] = `{"status":"NotSupportedPriority"}`
+	end := now.Add(2 * time.Hour)
+	r, err := m.Schedule(&Spec{
+		LocationId: "L1",
+		Priority:   "AlwaysFront",
+		EndTime:    types.NewDateTime(end),
+		Messages:   []*entity.DisplayContent{{Content: "0.35 EUR/kWh"}},
+	})
+	if err != nil {
+		t.Fatalf("Schedule: %v", err)
+	}
+	waitForStatus(t, m, r.Id, "CP1", entity.DisplayAccepted)
+	waitForStatus(t, m, r.Id, "CP2", "NotSupportedPriority")
+
+	m.OnChargePointBoot("CP1")
+	waitForStatus(t, m, r.Id, "CP1", entity.DisplayAccepted)
+	if sent := server.sentTo("CP1"); len(sent) != 2 {
+		t.Fatalf("sent %d requests to CP1, want the message again after the boot", len(sent))
+	}
+
+	*now = end
+	m.tick()
+	report, err := m.Get(r.Id)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if report.Status != entity.DisplayMessageEnded || report.Delivery[entity.DisplayCleared] != 2 {
+		t.Errorf("report %+v with delivery %v, want the message ended and cleared", report.DisplayMessage, report.Delivery)
+	}
+	if len(m.List()) != 0 {
+		t.Error("an ended message is still listed")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for len(server.sentTo("CP1")) < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	sent := server.sentTo("CP1")
+	if clear, ok := sent[len(sent)-1].(*displaymessage.ClearDisplayMessageRequest); !ok || clear.Id != r.Id*maxLanguages {
+		t.Errorf("last request to CP1 %+v, want the message cleared", sent[len(sent)-1])
+	}
+}
+
+func TestScheduleRefusesInvalidMessages(t *testing.T) {
+	m, _, now := newTestManager()
+	tests := []struct {
+		name string
+		spec Spec
+	}{
+		{"no location", Spec{Messages: []*entity.DisplayContent{{Content: "Welcome"}}}},
+		{"no messages", Spec{LocationId: "L1"}},
+		{"language twice", Spec{LocationId: "L1", Messages: []*entity.DisplayContent{{Language: "en", Content: "Hi"}, {Language: "en", Content: "Hello"}}}},
+		{"unknown priority", Spec{LocationId: "L1", Priority: "Urgent", Messages: []*entity.DisplayContent{{Content: "Welcome"}}}},
+		{"ended", Spec{LocationId: "L1", EndTime: types.NewDateTime(now.Add(-time.Minute)), Messages: []*entity.DisplayContent{{Content: "Welcome"}}}},
+	}
+	for _, tt := range tests {
+		if _, err := m.Schedule(&tt.spec); err == nil {
+			t.Errorf("%s: message scheduled", tt.name)
+		}
+	}
+}
+
+func TestMessageIdsContinueAfterLoad(t *testing.T) {
+	repo := &stubRepo{
+		chargePoints: []*entity.ChargePoint{chargePoint("CP1", "L1", "ocpp2.0.1")},
+		messages:     map[int]*entity.DisplayMessage{3: {Id: 3}},
+	}
+	m := NewManager(repo, &stubServer{offline: map[string]bool{}, answers: map[string]string{}, sent: map[string][]ocpp.Request{}}, stubLog{})
+	if err := m.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	r, err := m.Schedule(&Spec{
+		LocationId: "L1",
+		StartTime:  types.NewDateTime(time.Now().Add(time.Hour)),
+		Messages:   []*entity.DisplayContent{{Language: "en", Content: "Maintenance tonight"}},
+	})
+	if err != nil {
+		t.Fatalf("Schedule: %v", err)
+	}
+	if r.Id != 4 {
+		t.Errorf("message id = %d, want 4 after the stored 3", r.Id)
+	}
+
+	repo.lastErr = errors.New("timeout")
+	if err = NewManager(repo, &stubServer{}, stubLog{}).Load(); err == nil {
+		t.Error("Load ignored a failing database")
+	}
+}
@@ -0,0 +1,13 @@
+package display
+
+import "evsys/entity"
+
+type Repository interface {
+	GetChargePoints() ([]*entity.ChargePoint, error)
+	GetChargePoint(id string) (*entity.ChargePoint, error)
+	GetLastDisplayMessage() (*entity.DisplayMessage, error)
+	GetDisplayMessage(id int) (*entity.DisplayMessage, error)
+	GetCurrentDisplayMessages() ([]*entity.DisplayMessage, error)
+	AddDisplayMessage(message *entity.DisplayMessage) error
+	UpdateDisplayMessage(message *entity.DisplayMessage) error
+}
@@ -0,0 +1,13 @@
+package display
+
+import (
+	"evsys/ocpp"
+)
+
+type Handler interface {
+	// SendRequestWithResponse queues a request and returns the channel carrying
+	// the charge point's answer, its raw CallResult payload or its CallError; the
+	// error means the charge point is not connected. release must be called once
+	// the caller stops listening.
+	SendRequestWithResponse(clientId string, request ocpp.Request) (response <-chan ocpp.Answer, release func(), err error)
+}
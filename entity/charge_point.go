@@ -32,6 +32,7 @@ type ChargePoint struct {
 	Connectors       []*Connector           `json:"connectors,omitempty" bson:"connectors,omitempty"`
 	ProtocolVersion  string                 `json:"protocol_version,omitempty" bson:"protocol_version,omitempty"` // OCPP protocol version: "ocpp1.6", "ocpp2.0.1", "ocpp2.1"
 	DeviceModel      map[string]interface{} `json:"device_model,omitempty" bson:"device_model,omitempty"`         // OCPP 2.0.1+ hierarchical device model
+	PasswordHash     string                 `json:"-" bson:"password_hash,omitempty"`                             // bcrypt hash of the HTTP Basic authentication password
 }
 
 // EvseId returns the unique identifier for an EVSE as needed for OCPI.
@@ -0,0 +1,29 @@
+package entity
+
+import "time"
+
+// ChargingNeeds is what an ISO 15118 EV reported about its session: the energy it wants and when
+// its driver leaves. It is kept on the transaction, and replaced when the EV reports again.
+type ChargingNeeds struct {
+	EnergyTransfer string     `json:"energy_transfer" bson:"energy_transfer"` // DC, AC_single_phase, AC_two_phase or AC_three_phase
+	EnergyAmount   int        `json:"energy_amount" bson:"energy_amount"`     // Wh requested
+	DepartureTime  *time.Time `json:"departure_time,omitempty" bson:"departure_time,omitempty"`
+	MaxCurrent     int        `json:"max_current,omitempty" bson:"max_current,omitempty"` // A, per phase on AC
+	MaxPower       int        `json:"max_power,omitempty" bson:"max_power,omitempty"`     // W, reported on DC only
+	StateOfCharge  *int       `json:"state_of_charge,omitempty" bson:"state_of_charge,omitempty"`
+	TimeReceived   time.Time  `json:"time_received" bson:"time_received"`
+}
+
+// RequiredPower is the average power, in W, that delivers the requested energy by the departure
+// time; the higher it is, the more urgent the session. It is 0 when the EV named no departure time
+// or wants no energy. A departure time already past counts as one minute away.
+func (n *ChargingNeeds) RequiredPower(now time.Time) int {
+	if n == nil || n.DepartureTime == nil || n.EnergyAmount <= 0 {
+		return 0
+	}
+	left := n.DepartureTime.Sub(now)
+	if left < time.Minute {
+		left = time.Minute
+	}
+	return int(float64(n.EnergyAmount) / left.Hours())
+}
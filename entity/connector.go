@@ -31,14 +31,16 @@ type Connector struct {
 }
 
 // Statuses reported for an installed charging profile. The first three are
-// OCPP's own; the rest describe answers that never arrived or made no sense,
-// which are just as much a failure to enforce a limit.
+// OCPP's own; the rest describe answers that never arrived, made no sense or
+// refused the request as a whole, which are just as much a failure to enforce
+// a limit.
 const (
 	ProfileStatusAccepted     = "Accepted"
 	ProfileStatusRejected     = "Rejected"
 	ProfileStatusNotSupported = "NotSupported"
 	ProfileStatusNoResponse   = "NoResponse"
 	ProfileStatusUnreadable   = "Unreadable"
+	ProfileStatusCallError    = "CallError"
 )
 
 // ProfileVerdict is what a charge point said about a charging profile, kept so
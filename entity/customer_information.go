@@ -0,0 +1,16 @@
+package entity
+
+import "time"
+
+// CustomerInformation is what an OCPP 2.0.1 charge point reported about a customer in answer to a
+// CustomerInformation request, the parts of its NotifyCustomerInformation messages joined in order.
+type CustomerInformation struct {
+	ChargePointId string `json:"charge_point_id" bson:"charge_point_id"`
+	RequestId     int    `json:"request_id" bson:"request_id"`
+	// IdTag is the customer the report was asked for; empty for a report not requested through
+	// the central system
+	IdTag        string    `json:"id_tag" bson:"id_tag"`
+	Data         string    `json:"data" bson:"data"`
+	GeneratedAt  time.Time `json:"generated_at" bson:"generated_at"`
+	TimeReceived time.Time `json:"time_received" bson:"time_received"`
+}
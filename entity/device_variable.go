@@ -0,0 +1,83 @@
+package entity
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Kinds of change in the device model history
+const (
+	DeviceVariableAdded   = "Added"
+	DeviceVariableChanged = "Changed"
+	DeviceVariableRemoved = "Removed"
+)
+
+// DeviceVariable is one attribute of a variable in the device model of an OCPP 2.0.1 charge point,
+// as last reported by the charge point. A variable has up to four attributes - Actual, Target, MinSet
+// and MaxSet - and each is a record of its own.
+type DeviceVariable struct {
+	ChargePointId      string    `json:"charge_point_id" bson:"charge_point_id"`
+	Component          string    `json:"component" bson:"component"`
+	ComponentInstance  string    `json:"component_instance,omitempty" bson:"component_instance"`
+	EvseId             *int      `json:"evse_id,omitempty" bson:"evse_id"`
+	ConnectorId        *int      `json:"connector_id,omitempty" bson:"connector_id"`
+	Variable           string    `json:"variable" bson:"variable"`
+	VariableInstance   string    `json:"variable_instance,omitempty" bson:"variable_instance"`
+	AttributeType      string    `json:"attribute_type" bson:"attribute_type"`
+	Value              string    `json:"value" bson:"value"`
+	Mutability         string    `json:"mutability,omitempty" bson:"mutability,omitempty"`
+	Persistent         *bool     `json:"persistent,omitempty" bson:"persistent,omitempty"`
+	Constant           *bool     `json:"constant,omitempty" bson:"constant,omitempty"`
+	DataType           string    `json:"data_type,omitempty" bson:"data_type,omitempty"`
+	Unit               string    `json:"unit,omitempty" bson:"unit,omitempty"`
+	MinLimit           *float64  `json:"min_limit,omitempty" bson:"min_limit,omitempty"`
+	MaxLimit           *float64  `json:"max_limit,omitempty" bson:"max_limit,omitempty"`
+	ValuesList         string    `json:"values_list,omitempty" bson:"values_list,omitempty"`
+	SupportsMonitoring bool      `json:"supports_monitoring,omitempty" bson:"supports_monitoring,omitempty"`
+	ReportedAt         time.Time `json:"reported_at" bson:"reported_at"` // generatedAt of the report or event that carried the value
+	TimeUpdated        time.Time `json:"time_updated" bson:"time_updated"`
+}
+
+// Key names the attribute within its charge point as Component[:instance][@evse[:connector]]/Variable[:instance]#Type.
+func (v *DeviceVariable) Key() string {
+	var key strings.Builder
+	key.WriteString(v.Component)
+	if v.ComponentInstance != "" {
+		key.WriteString(":" + v.ComponentInstance)
+	}
+	if v.EvseId != nil {
+		key.WriteString(fmt.Sprintf("@%d", *v.EvseId))
+		if v.ConnectorId != nil {
+			key.WriteString(fmt.Sprintf(":%d", *v.ConnectorId))
+		}
+	}
+	key.WriteString("/" + v.Variable)
+	if v.VariableInstance != "" {
+		key.WriteString(":" + v.VariableInstance)
+	}
+	key.WriteString("#" + v.AttributeType)
+	return key.String()
+}
+
+// DeviceVariableChange records an attribute that appeared, changed its value or was dropped from the
+// device model of a charge point.
+type DeviceVariableChange struct {
+	ChargePointId string    `json:"charge_point_id" bson:"charge_point_id"`
+	Key           string    `json:"key" bson:"key"` // see DeviceVariable.Key
+	Change        string    `json:"change" bson:"change"`
+	OldValue      string    `json:"old_value,omitempty" bson:"old_value,omitempty"`
+	NewValue      string    `json:"new_value,omitempty" bson:"new_value,omitempty"`
+	Source        string    `json:"source" bson:"source"`                             // the message that carried the change, NotifyReport or NotifyEvent
+	RequestId     int       `json:"request_id,omitempty" bson:"request_id,omitempty"` // of the report
+	Time          time.Time `json:"time" bson:"time"`
+}
+
+// DeviceVariableQuery selects attributes across the charge points; empty fields match everything.
+type DeviceVariableQuery struct {
+	ChargePointId string `json:"chargePointId,omitempty"`
+	Component     string `json:"component,omitempty"`
+	Variable      string `json:"variable,omitempty"`
+	AttributeType string `json:"attributeType,omitempty"`
+	Value         string `json:"value,omitempty"`
+}
@@ -0,0 +1,55 @@
+package entity
+
+import "time"
+
+const (
+	DisplayMessageScheduled = "Scheduled"
+	DisplayMessageActive    = "Active"
+	DisplayMessageEnded     = "Ended"
+	DisplayMessageCancelled = "Cancelled"
+)
+
+// Display statuses of a charge point. Sent, Offline and Cleared are set by the scheduler; any other
+// status is the charge point's answer to SetDisplayMessage, such as Accepted or NotSupportedState.
+const (
+	DisplayPending  = "Pending"
+	DisplaySending  = "Sending"
+	DisplayOffline  = "Offline"
+	DisplayAccepted = "Accepted"
+	DisplayCleared  = "Cleared"
+)
+
+// DisplayMessage is a message shown on the displays of the OCPP 2.0.1 charge points of a location
+// between its start and end times, in one or more languages.
+type DisplayMessage struct {
+	Id          int               `json:"message_id" bson:"message_id"`
+	LocationId  string            `json:"location_id" bson:"location_id"`
+	Priority    string            `json:"priority" bson:"priority"`
+	State       string            `json:"state,omitempty" bson:"state,omitempty"` // charge point state the message is limited to
+	StartTime   time.Time         `json:"start_time" bson:"start_time"`
+	EndTime     *time.Time        `json:"end_time,omitempty" bson:"end_time,omitempty"` // none keeps the message until cancelled
+	Contents    []*DisplayContent `json:"contents" bson:"contents"`
+	Status      string            `json:"status" bson:"status"`
+	TimeCreated time.Time         `json:"time_created" bson:"time_created"`
+	Stations    []*DisplayStation `json:"stations" bson:"stations"`
+}
+
+// DisplayContent is the text of a message in one language.
+type DisplayContent struct {
+	Language string `json:"language,omitempty" bson:"language,omitempty"`
+	Format   string `json:"format" bson:"format"`
+	Content  string `json:"content" bson:"content"`
+}
+
+// DisplayStation is the delivery of a message to one charge point.
+type DisplayStation struct {
+	ChargePointId string    `json:"charge_point_id" bson:"charge_point_id"`
+	Status        string    `json:"status" bson:"status"`
+	Info          string    `json:"info,omitempty" bson:"info,omitempty"`
+	TimeUpdated   time.Time `json:"time_updated" bson:"time_updated"`
+}
+
+// IsCurrent reports whether the message is still to be shown, now or later.
+func (m *DisplayMessage) IsCurrent() bool {
+	return m.Status == DisplayMessageScheduled || m.Status == DisplayMessageActive
+}
@@ -0,0 +1,42 @@
+package entity
+
+import "time"
+
+// ExternalLimit is a charging limit a system outside the central system, such as a local EMS or
+// the grid operator, set on a 2.x charge point, as the charge point reported it in
+// NotifyChargingLimit. There is one per EVSE and source, replaced when the source sets a new one;
+// EVSE 0 stands for the whole charge point. The periods keep the unit they were reported in.
+type ExternalLimit struct {
+	ChargePointId string        `json:"charge_point_id" bson:"charge_point_id"`
+	EvseId        int           `json:"evse_id" bson:"evse_id"`
+	Source        string        `json:"source" bson:"source"` // EMS, Other, SO or CSO
+	GridCritical  bool          `json:"grid_critical,omitempty" bson:"grid_critical,omitempty"`
+	Periods       []LimitPeriod `json:"periods" bson:"periods"`
+	TimeReceived  time.Time     `json:"time_received" bson:"time_received"`
+}
+
+// LimitPeriod is a limit from Start until End, or open-ended without an End
+type LimitPeriod struct {
+	Start  time.Time  `json:"start" bson:"start"`
+	End    *time.Time `json:"end,omitempty" bson:"end,omitempty"`
+	Limit  float64    `json:"limit" bson:"limit"`
+	Unit   string     `json:"unit" bson:"unit"`                         // A or W
+	Phases int        `json:"phases,omitempty" bson:"phases,omitempty"` // as reported; 0 when not
+}
+
+// InForce is the periods of the limit that apply at a time
+func (l *ExternalLimit) InForce(now time.Time) []LimitPeriod {
+	var periods []LimitPeriod
+	for _, period := range l.Periods {
+		if period.Start.After(now) || (period.End != nil && !period.End.After(now)) {
+			continue
+		}
+		periods = append(periods, period)
+	}
+	return periods
+}
+
+// AppliesTo tells whether the limit covers an EVSE, directly or as a limit of the whole charge point
+func (l *ExternalLimit) AppliesTo(evseId int) bool {
+	return l.EvseId == 0 || l.EvseId == evseId
+}
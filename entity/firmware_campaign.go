@@ -0,0 +1,81 @@
+package entity
+
+import "time"
+
+const (
+	CampaignRunning   = "Running"
+	CampaignFinished  = "Finished"
+	CampaignCancelled = "Cancelled"
+)
+
+// Firmware target statuses. Downloading through Installed are reported by the charge point; the
+// rest are set by the campaign manager. Verified and VersionMismatch are decided on the first
+// BootNotification after installation.
+const (
+	TargetPending            = "Pending"
+	TargetOffline            = "Offline"
+	TargetSending            = "Sending"
+	TargetSent               = "Sent"
+	TargetDownloading        = "Downloading"
+	TargetDownloaded         = "Downloaded"
+	TargetInstalling         = "Installing"
+	TargetInstalled          = "Installed"
+	TargetVerified           = "Verified"
+	TargetVersionMismatch    = "VersionMismatch"
+	TargetDownloadFailed     = "DownloadFailed"
+	TargetInstallationFailed = "InstallationFailed"
+	TargetRejected           = "Rejected"
+	TargetTimedOut           = "TimedOut"
+	TargetCancelled          = "Cancelled"
+)
+
+type FirmwareCampaign struct {
+	Id            int               `json:"campaign_id" bson:"campaign_id"`
+	Location      string            `json:"location" bson:"location"`
+	Version       string            `json:"version,omitempty" bson:"version,omitempty"` // firmware version expected after the update; empty accepts any change
+	RetrieveDate  time.Time         `json:"retrieve_date" bson:"retrieve_date"`
+	Retries       *int              `json:"retries,omitempty" bson:"retries,omitempty"`
+	RetryInterval *int              `json:"retry_interval,omitempty" bson:"retry_interval,omitempty"`
+	Model         string            `json:"model,omitempty" bson:"model,omitempty"`
+	Vendor        string            `json:"vendor,omitempty" bson:"vendor,omitempty"`
+	LocationId    string            `json:"location_id,omitempty" bson:"location_id,omitempty"`
+	MaxConcurrent int               `json:"max_concurrent" bson:"max_concurrent"`
+	Status        string            `json:"status" bson:"status"`
+	TimeCreated   time.Time         `json:"time_created" bson:"time_created"`
+	TimeFinished  time.Time         `json:"time_finished,omitempty" bson:"time_finished,omitempty"`
+	Targets       []*FirmwareTarget `json:"targets" bson:"targets"`
+}
+
+// FirmwareTarget is the progress of one charge point within a campaign.
+type FirmwareTarget struct {
+	ChargePointId   string    `json:"charge_point_id" bson:"charge_point_id"`
+	Status          string    `json:"status" bson:"status"`
+	PreviousVersion string    `json:"previous_version" bson:"previous_version"`
+	ReportedVersion string    `json:"reported_version,omitempty" bson:"reported_version,omitempty"`
+	Info            string    `json:"info,omitempty" bson:"info,omitempty"`
+	TimeUpdated     time.Time `json:"time_updated" bson:"time_updated"`
+}
+
+// IsActive reports whether the target holds one of the campaign's concurrency slots: the request
+// is out and the charge point has not reached an outcome yet.
+func (t *FirmwareTarget) IsActive() bool {
+	switch t.Status {
+	case TargetSending, TargetSent, TargetDownloading, TargetDownloaded, TargetInstalling, TargetInstalled:
+		return true
+	}
+	return false
+}
+
+// IsDone reports whether the target has reached an outcome.
+func (t *FirmwareTarget) IsDone() bool {
+	return !t.IsActive() && t.Status != TargetPending && t.Status != TargetOffline
+}
+
+// Progress counts targets per status, for a quick look at a running campaign.
+func (c *FirmwareCampaign) Progress() map[string]int {
+	progress := make(map[string]int)
+	for _, t := range c.Targets {
+		progress[t.Status]++
+	}
+	return progress
+}
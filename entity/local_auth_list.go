@@ -0,0 +1,30 @@
+package entity
+
+import "time"
+
+// LocalAuthList is what a charge point holds in its local authorization list, as of the last
+// SendLocalList it accepted. Differential updates are computed against it.
+type LocalAuthList struct {
+	ChargePointId string            `json:"charge_point_id" bson:"charge_point_id"`
+	ListVersion   int               `json:"list_version" bson:"list_version"`
+	Entries       []*LocalAuthEntry `json:"entries" bson:"entries"`
+	TimeUpdated   time.Time         `json:"time_updated" bson:"time_updated"`
+}
+
+type LocalAuthEntry struct {
+	IdTag       string     `json:"id_tag" bson:"id_tag"`
+	Status      string     `json:"status" bson:"status"`
+	ExpiryDate  *time.Time `json:"expiry_date,omitempty" bson:"expiry_date,omitempty"`
+	ParentIdTag string     `json:"parent_id_tag,omitempty" bson:"parent_id_tag,omitempty"`
+}
+
+// Equal reports whether the charge point would treat both entries the same.
+func (e *LocalAuthEntry) Equal(other *LocalAuthEntry) bool {
+	if e.Status != other.Status || e.ParentIdTag != other.ParentIdTag {
+		return false
+	}
+	if e.ExpiryDate == nil || other.ExpiryDate == nil {
+		return e.ExpiryDate == nil && other.ExpiryDate == nil
+	}
+	return e.ExpiryDate.Equal(*other.ExpiryDate)
+}
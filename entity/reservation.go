@@ -0,0 +1,74 @@
+package entity
+
+import (
+	"strings"
+	"time"
+)
+
+// Reservation lifecycle. A reservation is Active from the moment it is sent to the charge point
+// until it is either consumed by a transaction (Used), cancelled through the API, refused by the
+// charge point, or runs past its expiry date.
+const (
+	ReservationActive    = "Active"
+	ReservationUsed      = "Used"
+	ReservationCancelled = "Cancelled"
+	ReservationRejected  = "Rejected"
+	ReservationExpired   = "Expired"
+)
+
+type Reservation struct {
+	Id            int    `json:"reservation_id" bson:"reservation_id"`
+	ChargePointId string `json:"charge_point_id" bson:"charge_point_id"`
+	ConnectorId   int    `json:"connector_id" bson:"connector_id"` // 0 reserves any connector of the charge point
+	// EvseId and ConnectorType target a reservation on an OCPP 2.0.1 charge point: a specific EVSE,
+	// or, without one, any EVSE with a connector of the type (of any type when empty)
+	EvseId        *int      `json:"evse_id,omitempty" bson:"evse_id,omitempty"`
+	ConnectorType string    `json:"connector_type,omitempty" bson:"connector_type,omitempty"`
+	IdTag         string    `json:"id_tag" bson:"id_tag"`
+	ParentIdTag   string    `json:"parent_id_tag,omitempty" bson:"parent_id_tag,omitempty"`
+	ExpiryDate    time.Time `json:"expiry_date" bson:"expiry_date"`
+	Status        string    `json:"status" bson:"status"`
+	Info          string    `json:"info,omitempty" bson:"info,omitempty"`
+	TimeCreated   time.Time `json:"time_created" bson:"time_created"`
+	TimeUpdated   time.Time `json:"time_updated" bson:"time_updated"`
+	TransactionId int       `json:"transaction_id,omitempty" bson:"transaction_id,omitempty"`
+}
+
+// IsActiveAt reports whether the reservation still holds its connector at the given time.
+func (r *Reservation) IsActiveAt(t time.Time) bool {
+	return r.Status == ReservationActive && t.Before(r.ExpiryDate)
+}
+
+// Covers reports whether the reservation applies to the connector; a reservation for
+// connector 0 holds the whole charge point.
+func (r *Reservation) Covers(connectorId int) bool {
+	return r.ConnectorId == 0 || r.ConnectorId == connectorId
+}
+
+// CoversEvse reports whether a 2.0.1 reservation applies to a session on the EVSE, whose connector
+// is of the given type; an unknown type matches any reservation without an EVSE.
+func (r *Reservation) CoversEvse(evseId int, connectorType string) bool {
+	if r.EvseId != nil {
+		return *r.EvseId == evseId
+	}
+	return r.ConnectorType == "" || connectorType == "" || r.ConnectorType == connectorType
+}
+
+// IsExclusive reports whether the reservation holds a known connector or EVSE, which refuses every
+// other id tag. Otherwise the charge point itself keeps one of its connectors free.
+func (r *Reservation) IsExclusive() bool {
+	return r.ConnectorId != 0 || r.EvseId != nil
+}
+
+// IsHeldBy reports whether an id tag, as the charge point sent it, belongs to the reservation.
+// The charge point may prefix the tag with its source, so only the bare id is compared.
+func (r *Reservation) IsHeldBy(idTag string) bool {
+	_, id := SplitIdTag(idTag)
+	return strings.EqualFold(id, r.IdTag)
+}
+
+// IsHeldByGroup reports whether a tag of the parent id tag may use the reservation. ReserveNow passes
+// the parent on, and the charge point then lets any tag of that group start on the reservation.
+func (r *Reservation) IsHeldByGroup(parentIdTag string) bool {
+	return r.ParentIdTag != "" && strings.EqualFold(parentIdTag, r.ParentIdTag)
+}
@@ -0,0 +1,12 @@
+package entity
+
+import "time"
+
+// SecurityEvent is a SecurityEventNotification as received from a charge point.
+type SecurityEvent struct {
+	ChargePointId string    `json:"charge_point_id" bson:"charge_point_id"`
+	Type          string    `json:"type" bson:"type"`
+	Timestamp     time.Time `json:"timestamp" bson:"timestamp"` // when the event happened, by the charge point's clock
+	TechInfo      string    `json:"tech_info,omitempty" bson:"tech_info,omitempty"`
+	TimeReceived  time.Time `json:"time_received" bson:"time_received"`
+}
@@ -33,6 +33,10 @@ type Transaction struct {
 	ProtocolVersion string                 `json:"protocol_version,omitempty" bson:"protocol_version,omitempty"` // OCPP protocol version: "ocpp1.6", "ocpp2.0.1", "ocpp2.1"
 	EvseId          *int                   `json:"evse_id,omitempty" bson:"evse_id,omitempty"`                   // OCPP 2.0.1+ EVSE identifier
 	PowerLimit      int                    `json:"power_limit" bson:"power_limit"`                               // load balancer amperage assigned to this session (0 = none recorded)
+	ChargingNeeds   *ChargingNeeds         `json:"charging_needs,omitempty" bson:"charging_needs,omitempty"`     // ISO 15118 energy demand and departure time
+	Priority        bool                   `json:"priority,omitempty" bson:"priority,omitempty"`                 // OCPP 2.1 priority charging switched on at the station
+	SeqNo           int                    `json:"seq_no,omitempty" bson:"seq_no,omitempty"`                     // OCPP 2.0.1 highest TransactionEvent seqNo handled
+	MissingSeqNos   []int                  `json:"missing_seq_nos,omitempty" bson:"missing_seq_nos,omitempty"`   // OCPP 2.0.1 seqNos skipped and not received since
 	Metadata        map[string]interface{} `json:"metadata,omitempty" bson:"metadata,omitempty"`                 // Flexible storage for version-specific data
 	mutex           sync.Mutex
 }
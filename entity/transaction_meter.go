@@ -11,6 +11,9 @@ import "time"
 // advertising to the vehicle, so it can be compared directly against the
 // amperage the balancer asked for. All three are zero on readings from charge
 // points that do not report them.
+//
+// A sample reading taken outside a transaction has the id -1 and names its
+// charge point instead; its ConnectorId is the EVSE, 0 for the main meter.
 type TransactionMeter struct {
 	Id              int       `json:"transaction_id" bson:"transaction_id"`
 	Value           int       `json:"value" bson:"value"`
@@ -29,6 +32,7 @@ type TransactionMeter struct {
 	Measurand       string    `json:"measurand" bson:"measurand"`
 	ConnectorId     int       `json:"connector_id" bson:"connector_id"`
 	ConnectorStatus string    `json:"connector_status" bson:"connector_status"`
+	ChargePointId   string    `json:"charge_point_id,omitempty" bson:"charge_point_id,omitempty"`
 }
 
 func NewMeter(id, connectorId int, status string, timestamp time.Time) *TransactionMeter {
@@ -1,6 +1,7 @@
 package entity
 
 import (
+	"regexp"
 	"strings"
 	"time"
 )
@@ -15,6 +16,10 @@ type UserTag struct {
 	Note           string    `json:"note" bson:"note"`
 	DateRegistered time.Time `json:"date_registered" bson:"date_registered"`
 	LastSeen       time.Time `json:"last_seen" bson:"last_seen"`
+	// ExpiryDate and ParentIdTag are set by operations; both are passed to charge points in the
+	// local authorization list
+	ExpiryDate  *time.Time `json:"expiry_date,omitempty" bson:"expiry_date,omitempty"`
+	ParentIdTag string     `json:"parent_id_tag,omitempty" bson:"parent_id_tag,omitempty"`
 }
 
 func NewUserTag(idTag string) *UserTag {
@@ -27,6 +32,16 @@ func NewUserTag(idTag string) *UserTag {
 	}
 }
 
+func (t *UserTag) IsExpiredAt(at time.Time) bool {
+	return t.ExpiryDate != nil && !t.ExpiryDate.After(at)
+}
+
+// IdTagPattern matches an id tag as a charge point sent it, with or without the source prefix it may
+// add, see SplitIdTag
+func IdTagPattern(idTag string) string {
+	return "^([^:]*:)?" + regexp.QuoteMeta(idTag) + "$"
+}
+
 func SplitIdTag(idTag string) (string, string) {
 	if strings.Contains(idTag, ":") {
 		s := strings.Split(idTag, ":")
@@ -30,6 +30,9 @@ type Config struct {
 		TLS      bool   `yaml:"tls_enabled" env-default:"false"`
 		CertFile string `yaml:"cert_file" env-default:""`
 		KeyFile  string `yaml:"key_file" env-default:""`
+		// ClientCAFile, a PEM bundle, makes TLS connections require a client certificate issued by
+		// one of its CAs, with the charge point id as CN (security profile 3)
+		ClientCAFile string `yaml:"client_ca_file" env-default:""`
 	}
 	Api struct {
 		BindIP   string `yaml:"bind_ip" env-default:"0.0.0.0"`
@@ -56,6 +59,41 @@ type Config struct {
 		Url     string `yaml:"url" env-default:""`
 		Token   string `yaml:"token" env-default:""`
 	}
+	// Security configures the certificate authority that signs charge point certificates; with no
+	// ca_cert_file, SignCertificate requests are rejected. Missing CA files are generated, so the
+	// whole chain works offline. BasicAuth requires every charge point to present its id and stored
+	// password when it connects (security profiles 1 and 2, the latter with listen.tls_enabled);
+	// with AllowUnsetPassword, charge points that have no password yet still connect without one,
+	// so that existing ones can be given passwords after BasicAuth is switched on.
+	// ContractDir holds the files of the local ISO 15118 contract provider, see
+	// pki.FileContractProvider; without it OCPP 2.0.1 Plug & Charge requests fail.
+	Security struct {
+		BasicAuth          bool   `yaml:"basic_auth" env-default:"false"`
+		AllowUnsetPassword bool   `yaml:"allow_unset_password" env-default:"false"`
+		CACertFile         string `yaml:"ca_cert_file" env-default:""`
+		CAKeyFile          string `yaml:"ca_key_file" env-default:""`
+		Organization       string `yaml:"organization" env-default:""`
+		CertValidityDays   int    `yaml:"cert_validity_days" env-default:"365"`
+		ContractDir        string `yaml:"contract_dir" env-default:""`
+	}
+	// Monitoring grades the events OCPP 2.0.1 charge points report with NotifyEvent, by the severity
+	// of the monitor behind them, 0 (danger) to 9 (debug): an alerting event at or below
+	// alert_severity is sent to the alert listeners, at or below error_severity it is stored as an
+	// error. default_severity applies to events of monitors not seen in a monitoring report or a
+	// SetVariableMonitoring answer, hard-wired notifications among them.
+	Monitoring struct {
+		AlertSeverity   int `yaml:"alert_severity" env-default:"2"`
+		ErrorSeverity   int `yaml:"error_severity" env-default:"4"`
+		DefaultSeverity int `yaml:"default_severity" env-default:"4"`
+	}
+	// Tariff is how prices reach the drivers at OCPP 2.0.1 charge points: every TransactionEvent is
+	// answered with the running cost, cost_update_interval (seconds, 0 disables) pushes it with
+	// CostUpdated between events, and Authorize shows the driver's tariff. The billing service
+	// prices in cents of currency.
+	Tariff struct {
+		Currency           string `yaml:"currency" env-default:"EUR"`
+		CostUpdateInterval int    `yaml:"cost_update_interval" env-default:"0"`
+	}
 	Telegram struct {
 		Enabled bool   `yaml:"enabled" env-default:"false"`
 		ApiKey  string `yaml:"telegram_api_key" env-default:""`
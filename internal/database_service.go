@@ -20,18 +20,26 @@ type Database interface {
 	ResetOnlineStatus() error
 	AddChargePoint(chargePoint *entity.ChargePoint) error
 	GetChargePoint(id string) (*entity.ChargePoint, error)
+	UpdateChargePointAvailability(chargePointId string, isEnabled bool) error
+	UpdateChargePointPassword(chargePointId string, passwordHash string) error
 
 	GetConnectors() ([]*entity.Connector, error)
 	UpdateConnector(connector *entity.Connector) error
 	AddConnector(connector *entity.Connector) error
 	GetConnector(id int, chargePointId string) (*entity.Connector, error)
 	UpdateConnectorProfileVerdict(chargePointId string, connectorId int, verdict *entity.ProfileVerdict) error
+	UpdateConnectorAvailability(chargePointId string, connectorId int, isEnabled bool) error
 
 	GetUserTag(idTag string) (*entity.UserTag, error)
 	AddUserTag(userTag *entity.UserTag) error
 	UpdateTag(userTag *entity.UserTag) error
 	UpdateTagLastSeen(userTag *entity.UserTag) error
-	GetActiveUserTags(chargePointId string, listVersion int) ([]entity.UserTag, error)
+	GetLocalUserTags() ([]entity.UserTag, error)
+	DeleteUserTag(idTag string) error
+
+	GetLocalAuthList(chargePointId string) (*entity.LocalAuthList, error)
+	SaveLocalAuthList(list *entity.LocalAuthList) error
+	GetLocalAuthListsWithTag(idTag string) ([]*entity.LocalAuthList, error)
 
 	GetPaymentMethod(userId string) (*entity.PaymentMethod, error)
 	GetUserPaymentPlan(username string) (*entity.PaymentPlan, error)
@@ -44,6 +52,10 @@ type Database interface {
 	GetTransaction(id int) (*entity.Transaction, error)
 	AddTransaction(transaction *entity.Transaction) error
 	UpdateTransaction(transaction *entity.Transaction) error
+	UpdateTransactionChargingNeeds(transactionId int, needs *entity.ChargingNeeds) error
+	UpdateTransactionPriorityCharging(transactionId int, active bool) error
+	GetTransactionBySessionId(chargePointId, sessionId string) (*entity.Transaction, error)
+	UpdateTransactionSequence(transactionId, seqNo int, missing []int) error
 	GetUnfinishedTransactions(staleBefore, releasedBefore time.Time) ([]*entity.SweptTransaction, error)
 	GetUnfinishedTransactionsForChargePoint(chargePointId string) ([]*entity.Transaction, error)
 	GetTodayConsumedEnergy() ([]*entity.ConsumedEnergy, error)
@@ -56,6 +68,48 @@ type Database interface {
 	DeleteTransactionMeterValues(transactionId int) error
 	ReadLastMeterValues() ([]*entity.TransactionMeter, error)
 
+	GetLastReservation() (*entity.Reservation, error)
+	GetReservation(id int) (*entity.Reservation, error)
+	AddReservation(reservation *entity.Reservation) error
+	UpdateReservation(reservation *entity.Reservation) error
+	GetActiveReservations(chargePointId string, now time.Time) ([]*entity.Reservation, error)
+	GetExpiredReservations(now time.Time) ([]*entity.Reservation, error)
+
+	GetLastFirmwareCampaign() (*entity.FirmwareCampaign, error)
+	GetFirmwareCampaign(id int) (*entity.FirmwareCampaign, error)
+	GetRunningFirmwareCampaigns() ([]*entity.FirmwareCampaign, error)
+	AddFirmwareCampaign(campaign *entity.FirmwareCampaign) error
+	UpdateFirmwareCampaign(campaign *entity.FirmwareCampaign) error
+
+	GetLastDisplayMessage() (*entity.DisplayMessage, error)
+	GetDisplayMessage(id int) (*entity.DisplayMessage, error)
+	GetCurrentDisplayMessages() ([]*entity.DisplayMessage, error)
+	AddDisplayMessage(message *entity.DisplayMessage) error
+	UpdateDisplayMessage(message *entity.DisplayMessage) error
+
+	GetDeviceVariables(chargePointId string) ([]*entity.DeviceVariable, error)
+	FindDeviceVariables(query *entity.DeviceVariableQuery) ([]*entity.DeviceVariable, error)
+	SaveDeviceVariables(variables []*entity.DeviceVariable) error
+	DeleteDeviceVariables(variables []*entity.DeviceVariable) error
+	AddDeviceVariableChanges(changes []*entity.DeviceVariableChange) error
+	GetDeviceVariableChanges(chargePointId string, since time.Time) ([]*entity.DeviceVariableChange, error)
+
+	AddSecurityEvent(event *entity.SecurityEvent) error
+
+	GetCustomerTransactions(idTag string) ([]*entity.Transaction, error)
+	AnonymizeCustomerTransactions(idTag, placeholder string) (int, error)
+	GetCustomerReservations(idTag string) ([]*entity.Reservation, error)
+	AnonymizeCustomerReservations(idTag, placeholder string) (int, error)
+	FindLogMessages(pattern string) ([]*FeatureLogMessage, error)
+	AnonymizeLogMessages(pattern, text, placeholder string) (int, error)
+	AddCustomerInformation(information *entity.CustomerInformation) error
+	GetCustomerInformation(idTag string) ([]*entity.CustomerInformation, error)
+	DeleteCustomerInformation(idTag string) (int, error)
+
+	SaveExternalLimit(limit *entity.ExternalLimit) error
+	GetExternalLimits(chargePointId string) ([]*entity.ExternalLimit, error)
+	DeleteExternalLimits(chargePointId, source string, evseId *int) (int, error)
+
 	GetSubscriptions() ([]entity.UserSubscription, error)
 	AddSubscription(subscription *entity.UserSubscription) error
 	UpdateSubscription(subscription *entity.UserSubscription) error
@@ -1,6 +1,10 @@
 package internal
 
-import "time"
+import (
+	"regexp"
+	"strings"
+	"time"
+)
 
 const FeatureLogMessageType = "featureLogMessage"
 
@@ -20,3 +24,20 @@ func (fm *FeatureLogMessage) MessageType() string {
 func (fm *FeatureLogMessage) DataType() string {
 	return FeatureLogMessageType
 }
+
+// replaceMatched replaces text with the placeholder inside the matches of expression only. An
+// expression that takes in the characters around the text cannot match two occurrences sharing
+// one separator at once, so the next is left to another pass; there are never more passes than
+// occurrences.
+func replaceMatched(input string, expression *regexp.Regexp, text, placeholder string) string {
+	for range strings.Count(input, text) {
+		output := expression.ReplaceAllStringFunc(input, func(match string) string {
+			return strings.Replace(match, text, placeholder, 1)
+		})
+		if output == input {
+			break
+		}
+		input = output
+	}
+	return input
+}
@@ -0,0 +1,24 @@
+package internal
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestReplaceMatched(t *testing.T) {
+	// the pattern the customer data erasure selects an id tag's log lines with
+	expression := regexp.MustCompile(`(^|[^0-9A-Za-z])` + regexp.QuoteMeta("TAG1") + `([^0-9A-Za-z]|$)`)
+	tests := []struct {
+		input, want string
+	}{
+		{"authorized TAG1 on connector 1", "authorized *** on connector 1"},
+		{"TAG1,TAG1 TAG1", "***,*** ***"},
+		{"TAG1 and TAG12 and XTAG1", "*** and TAG12 and XTAG1"},
+		{"meter TAG100 of TAG1", "meter TAG100 of ***"},
+	}
+	for _, tt := range tests {
+		if got := replaceMatched(tt.input, expression, "TAG1", "***"); got != tt.want {
+			t.Errorf("replaceMatched(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
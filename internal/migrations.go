@@ -26,6 +26,10 @@ const (
 	MigrationOCPPMultiVersion  = 1 // OCPP multi-version support (Phase 2, Task 2.7)
 	MigrationTriggerMessage    = 2 // Enable meter value triggering on existing charge points
 	MigrationStuckTransactions = 3 // Close transactions abandoned before the sweeper was fixed
+	MigrationConnectorEnabled  = 4 // Backfill is_enabled on connectors before availability is re-asserted
+	MigrationDeviceModel       = 5 // Indexes of the device model store and its history
+	MigrationCustomerData      = 6 // Indexes finding the data of a customer by id tag
+	MigrationSessionIndex      = 7 // Index finding a 2.0.1 transaction by the id its charge point assigned
 
 	// stuckTransactionCutoff is how far back a transaction must have been idle to count as
 	// backlog. The runtime sweeper handles anything more recent, so this only has to be long
@@ -65,6 +69,30 @@ func GetMigrations() []Migration {
 			Up:          migrationStuckTransactionsUp,
 			Down:        migrationStuckTransactionsDown,
 		},
+		{
+			Version:     MigrationConnectorEnabled,
+			Description: "Enable is_enabled on connectors that predate the flag",
+			Up:          migrationConnectorEnabledUp,
+			Down:        migrationConnectorEnabledDown,
+		},
+		{
+			Version:     MigrationDeviceModel,
+			Description: "Index device variables by attribute and their changes by charge point",
+			Up:          migrationDeviceModelUp,
+			Down:        migrationDeviceModelDown,
+		},
+		{
+			Version:     MigrationCustomerData,
+			Description: "Index transactions, reservations and customer information by id tag",
+			Up:          migrationCustomerDataUp,
+			Down:        migrationCustomerDataDown,
+		},
+		{
+			Version:     MigrationSessionIndex,
+			Description: "Index transactions by charge point and session id",
+			Up:          migrationSessionIndexUp,
+			Down:        migrationSessionIndexDown,
+		},
 	}
 }
 
@@ -481,3 +509,125 @@ func migrationTriggerMessageDown(ctx context.Context, db *mongo.Database) error
 
 	return nil
 }
+
+// migrationConnectorEnabledUp backfills is_enabled on connectors stored before availability was
+// tracked. Those decode as false, and the boot-time re-assert would then take every one of them
+// out of service the next time its charge point reboots.
+func migrationConnectorEnabledUp(ctx context.Context, db *mongo.Database) error {
+	log.Println("Running migration: Enable is_enabled on existing connectors")
+
+	result, err := db.Collection("connectors").UpdateMany(
+		ctx,
+		bson.M{"is_enabled": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"is_enabled": true}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update connectors: %w", err)
+	}
+	log.Printf("Enabled is_enabled on %d connectors", result.ModifiedCount)
+
+	return nil
+}
+
+// migrationConnectorEnabledDown is a no-op: the backfilled value is indistinguishable from one
+// written by a ChangeAvailability, and removing it would disable those connectors on next boot.
+func migrationConnectorEnabledDown(_ context.Context, _ *mongo.Database) error {
+	log.Println("Rolling back migration: is_enabled on connectors is left in place")
+	return nil
+}
+
+// migrationDeviceModelUp indexes the device model store. The unique index is the identity
+// SaveDeviceVariables replaces by; fleet-wide queries mostly name a variable.
+func migrationDeviceModelUp(ctx context.Context, db *mongo.Database) error {
+	log.Println("Running migration: Index the device model")
+
+	_, err := db.Collection("device_variables").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "charge_point_id", Value: 1},
+				{Key: "component", Value: 1},
+				{Key: "component_instance", Value: 1},
+				{Key: "evse_id", Value: 1},
+				{Key: "connector_id", Value: 1},
+				{Key: "variable", Value: 1},
+				{Key: "variable_instance", Value: 1},
+				{Key: "attribute_type", Value: 1},
+			},
+			Options: options.Index().SetName("device_variable_1").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "variable", Value: 1}, {Key: "component", Value: 1}},
+			Options: options.Index().SetName("variable_component_1").SetBackground(true),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to index device_variables: %w", err)
+	}
+
+	_, err = db.Collection("device_variable_changes").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "charge_point_id", Value: 1}, {Key: "time", Value: 1}},
+		Options: options.Index().SetName("charge_point_time_1").SetBackground(true),
+	})
+	if err != nil {
+		log.Printf("Warning: failed to index device_variable_changes: %v", err)
+	}
+	return nil
+}
+
+// migrationDeviceModelDown drops the indexes; the stored model is left, the next reports keep it.
+func migrationDeviceModelDown(ctx context.Context, db *mongo.Database) error {
+	log.Println("Rolling back migration: Remove the device model indexes")
+
+	_, _ = db.Collection("device_variables").Indexes().DropOne(ctx, "device_variable_1")
+	_, _ = db.Collection("device_variables").Indexes().DropOne(ctx, "variable_component_1")
+	_, _ = db.Collection("device_variable_changes").Indexes().DropOne(ctx, "charge_point_time_1")
+	return nil
+}
+
+// migrationCustomerDataUp indexes the collections a customer data report or erasure looks up by
+// id tag; without them each request scans the whole transaction history.
+func migrationCustomerDataUp(ctx context.Context, db *mongo.Database) error {
+	log.Println("Running migration: Index customer data by id tag")
+
+	for _, name := range []string{"transactions", "reservations", "customer_information"} {
+		_, err := db.Collection(name).Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: "id_tag", Value: 1}},
+			Options: options.Index().SetName("id_tag_1").SetBackground(true),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to index %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func migrationCustomerDataDown(ctx context.Context, db *mongo.Database) error {
+	log.Println("Rolling back migration: Remove the id tag indexes")
+
+	for _, name := range []string{"transactions", "reservations", "customer_information"} {
+		_, _ = db.Collection(name).Indexes().DropOne(ctx, "id_tag_1")
+	}
+	return nil
+}
+
+// migrationSessionIndexUp indexes the transaction id a 2.0.1 charge point assigned, which every
+// TransactionEvent is looked up by
+func migrationSessionIndexUp(ctx context.Context, db *mongo.Database) error {
+	log.Println("Running migration: Index transactions by session id")
+
+	_, err := db.Collection("transactions").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "charge_point_id", Value: 1}, {Key: "session_id", Value: 1}},
+		Options: options.Index().SetName("charge_point_id_1_session_id_1").SetBackground(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to index transactions: %w", err)
+	}
+	return nil
+}
+
+func migrationSessionIndexDown(ctx context.Context, db *mongo.Database) error {
+	log.Println("Rolling back migration: Remove the session id index")
+
+	_, _ = db.Collection("transactions").Indexes().DropOne(ctx, "charge_point_id_1_session_id_1")
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"evsys/ocpp/v16/core"
 	"fmt"
 	"log"
+	"regexp"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -30,6 +31,15 @@ const (
 	collectionPaymentPlans    = "payment_plans"
 	collectionStopTransaction = "ocpp_stop_transaction"
 	collectionErrors          = "errors_log"
+	collectionReservations    = "reservations"
+	collectionFirmware        = "firmware_campaigns"
+	collectionLocalAuthLists  = "local_auth_lists"
+	collectionSecurityEvents  = "security_events"
+	collectionDisplayMessages = "display_messages"
+	collectionDeviceVariables = "device_variables"
+	collectionDeviceChanges   = "device_variable_changes"
+	collectionCustomerInfo    = "customer_information"
+	collectionExternalLimits  = "external_limits"
 )
 
 type MongoDB struct {
@@ -324,7 +334,15 @@ func (m *MongoDB) UpdateChargePoint(chargePoint *entity.ChargePoint) error {
 	defer m.disconnect(connection)
 
 	filter := bson.D{{"charge_point_id", chargePoint.Id}}
-	update := bson.M{"$set": bson.M{"serial_number": chargePoint.SerialNumber, "firmware_version": chargePoint.FirmwareVersion, "model": chargePoint.Model, "vendor": chargePoint.Vendor}}
+	set := bson.M{"serial_number": chargePoint.SerialNumber, "firmware_version": chargePoint.FirmwareVersion, "model": chargePoint.Model, "vendor": chargePoint.Vendor}
+	// the load balancer picks the smart charging protocol from the stored version
+	if chargePoint.ProtocolVersion != "" {
+		set["protocol_version"] = chargePoint.ProtocolVersion
+	}
+	if chargePoint.DeviceModel != nil {
+		set["device_model"] = chargePoint.DeviceModel
+	}
+	update := bson.M{"$set": set}
 	collection := connection.Database(m.database).Collection(collectionChargePoints)
 	_, err = collection.UpdateOne(m.ctx, filter, update)
 	if err != nil {
@@ -497,6 +515,49 @@ func (m *MongoDB) UpdateTransactionPowerLimit(transactionId, limit int) error {
 	return err
 }
 
+func (m *MongoDB) UpdateTransactionChargingNeeds(transactionId int, needs *entity.ChargingNeeds) error {
+	connection, err := m.connect()
+	if err != nil {
+		return err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{{"transaction_id", transactionId}}
+	update := bson.M{"$set": bson.M{"charging_needs": needs}}
+	collection := connection.Database(m.database).Collection(collectionTransactions)
+	_, err = collection.UpdateOne(m.ctx, filter, update)
+	return err
+}
+
+func (m *MongoDB) UpdateTransactionPriorityCharging(transactionId int, active bool) error {
+	connection, err := m.connect()
+	if err != nil {
+		return err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{{"transaction_id", transactionId}}
+	update := bson.M{"$set": bson.M{"priority": active}}
+	collection := connection.Database(m.database).Collection(collectionTransactions)
+	_, err = collection.UpdateOne(m.ctx, filter, update)
+	return err
+}
+
+// UpdateTransactionSequence stores how far the TransactionEvent messages of a 2.0.1 transaction got
+func (m *MongoDB) UpdateTransactionSequence(transactionId, seqNo int, missing []int) error {
+	connection, err := m.connect()
+	if err != nil {
+		return err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{{"transaction_id", transactionId}}
+	update := bson.M{"$set": bson.M{"seq_no": seqNo, "missing_seq_nos": missing}}
+	collection := connection.Database(m.database).Collection(collectionTransactions)
+	_, err = collection.UpdateOne(m.ctx, filter, update)
+	return err
+}
+
 func (m *MongoDB) AddConnector(connector *entity.Connector) error {
 	existedConnector, _ := m.GetConnector(connector.Id, connector.ChargePointId)
 	if existedConnector != nil {
@@ -640,6 +701,19 @@ func (m *MongoDB) UpdateTagLastSeen(userTag *entity.UserTag) error {
 	return err
 }
 
+func (m *MongoDB) DeleteUserTag(idTag string) error {
+	connection, err := m.connect()
+	if err != nil {
+		return err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{{"id_tag", idTag}}
+	collection := connection.Database(m.database).Collection(collectionUserTags)
+	_, err = collection.DeleteOne(m.ctx, filter)
+	return err
+}
+
 // UpdateTag updates an existing user tag in the MongoDB collection based on the provided ID.
 // It returns an error if the operation fails.
 func (m *MongoDB) UpdateTag(userTag *entity.UserTag) error {
@@ -663,23 +737,16 @@ func (m *MongoDB) UpdateTag(userTag *entity.UserTag) error {
 	return err
 }
 
-func (m *MongoDB) GetActiveUserTags(chargePointId string, listVersion int) ([]entity.UserTag, error) {
-	chargePoint, err := m.GetChargePoint(chargePointId)
-	if err != nil {
-		return nil, fmt.Errorf("charge point with id %s not found: %v", chargePointId, err)
-	}
+// GetLocalUserTags returns every tag meant for local authorization lists, disabled ones included:
+// they go to charge points as Blocked.
+func (m *MongoDB) GetLocalUserTags() ([]entity.UserTag, error) {
 	connection, err := m.connect()
 	if err != nil {
 		return nil, err
 	}
 	defer m.disconnect(connection)
 
-	filter := bson.D{
-		{"$and", bson.A{
-			bson.D{{"is_active", true}},
-			bson.D{{"local", true}},
-		}},
-	}
+	filter := bson.D{{"local", true}}
 	collection := connection.Database(m.database).Collection(collectionUserTags)
 	cursor, err := collection.Find(m.ctx, filter)
 	if err != nil {
@@ -689,13 +756,65 @@ func (m *MongoDB) GetActiveUserTags(chargePointId string, listVersion int) ([]en
 	if err = cursor.All(m.ctx, &userTags); err != nil {
 		return nil, err
 	}
-	// current list version has to be saved in charge point
-	chargePoint.LocalAuthVersion = listVersion
-	err = m.UpdateChargePoint(chargePoint)
+	return userTags, nil
+}
+
+func (m *MongoDB) GetLocalAuthList(chargePointId string) (*entity.LocalAuthList, error) {
+	connection, err := m.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{{"charge_point_id", chargePointId}}
+	collection := connection.Database(m.database).Collection(collectionLocalAuthLists)
+	var list entity.LocalAuthList
+	err = collection.FindOne(m.ctx, filter).Decode(&list)
 	if err != nil {
 		return nil, err
 	}
-	return userTags, nil
+	return &list, nil
+}
+
+// GetLocalAuthListsWithTag returns the stored lists that hold an id tag.
+func (m *MongoDB) GetLocalAuthListsWithTag(idTag string) ([]*entity.LocalAuthList, error) {
+	connection, err := m.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{{"entries.id_tag", idTag}}
+	collection := connection.Database(m.database).Collection(collectionLocalAuthLists)
+	cursor, err := collection.Find(m.ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var lists []*entity.LocalAuthList
+	if err = cursor.All(m.ctx, &lists); err != nil {
+		return nil, err
+	}
+	return lists, nil
+}
+
+// SaveLocalAuthList stores the list a charge point has accepted, and its version on the charge point.
+func (m *MongoDB) SaveLocalAuthList(list *entity.LocalAuthList) error {
+	connection, err := m.connect()
+	if err != nil {
+		return err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{{"charge_point_id", list.ChargePointId}}
+	collection := connection.Database(m.database).Collection(collectionLocalAuthLists)
+	_, err = collection.ReplaceOne(m.ctx, filter, list, options.Replace().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+	update := bson.M{"$set": bson.M{"local_auth_version": list.ListVersion}}
+	collection = connection.Database(m.database).Collection(collectionChargePoints)
+	_, err = collection.UpdateOne(m.ctx, filter, update)
+	return err
 }
 
 func (m *MongoDB) GetLastTransaction() (*entity.Transaction, error) {
@@ -740,6 +859,32 @@ func (m *MongoDB) GetTransaction(id int) (*entity.Transaction, error) {
 	return &transaction, nil
 }
 
+// GetTransactionBySessionId finds the latest transaction, finished or not, a 2.0.1 charge point
+// knows by the transactionId it assigned; nil when there is none.
+func (m *MongoDB) GetTransactionBySessionId(chargePointId, sessionId string) (*entity.Transaction, error) {
+	connection, err := m.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{
+		{"charge_point_id", chargePointId},
+		{"session_id", sessionId},
+	}
+	opts := options.FindOne().SetSort(bson.D{{"transaction_id", -1}})
+	collection := connection.Database(m.database).Collection(collectionTransactions)
+	var transaction entity.Transaction
+	err = collection.FindOne(m.ctx, filter, opts).Decode(&transaction)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
 // GetUnfinishedTransactionsForChargePoint retrieves every unfinished transaction of a single charge
 // point, regardless of age. Used after a reboot, where every open transaction of that charge point
 // is known to be dead.
@@ -952,6 +1097,8 @@ func (m *MongoDB) AddTransactionMeterValue(meterValue *entity.TransactionMeter)
 	return err
 }
 
+// AddSampleMeterValue keeps the latest reading of a measurand; readings outside a transaction are
+// kept per charge point and EVSE.
 func (m *MongoDB) AddSampleMeterValue(meterValue *entity.TransactionMeter) error {
 	connection, err := m.connect()
 	if err != nil {
@@ -964,6 +1111,9 @@ func (m *MongoDB) AddSampleMeterValue(meterValue *entity.TransactionMeter) error
 		{"transaction_id", meterValue.Id},
 		{"measurand", meterValue.Measurand},
 	}
+	if meterValue.ChargePointId != "" {
+		filter = append(filter, bson.E{"charge_point_id", meterValue.ChargePointId}, bson.E{"connector_id", meterValue.ConnectorId})
+	}
 	set := bson.M{"$set": meterValue}
 	_, err = collection.UpdateOne(m.ctx, filter, set, options.Update().SetUpsert(true))
 	return err
@@ -1534,3 +1684,742 @@ func (m *MongoDB) UpdateConnectorProfileVerdict(chargePointId string, connectorI
 	_, err = collection.UpdateOne(m.ctx, filter, update)
 	return err
 }
+
+// GetLastReservation returns the reservation with the highest id; reservation ids are assigned by
+// the central system and continue from there after a restart. An empty collection returns nil.
+func (m *MongoDB) GetLastReservation() (*entity.Reservation, error) {
+	connection, err := m.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.disconnect(connection)
+
+	collection := connection.Database(m.database).Collection(collectionReservations)
+	opts := options.FindOne().SetSort(bson.D{{"reservation_id", -1}})
+	var reservation entity.Reservation
+	err = collection.FindOne(m.ctx, bson.D{}, opts).Decode(&reservation)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &reservation, nil
+}
+
+func (m *MongoDB) GetReservation(id int) (*entity.Reservation, error) {
+	connection, err := m.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{{"reservation_id", id}}
+	collection := connection.Database(m.database).Collection(collectionReservations)
+	var reservation entity.Reservation
+	err = collection.FindOne(m.ctx, filter).Decode(&reservation)
+	if err != nil {
+		return nil, err
+	}
+	return &reservation, nil
+}
+
+func (m *MongoDB) AddReservation(reservation *entity.Reservation) error {
+	connection, err := m.connect()
+	if err != nil {
+		return err
+	}
+	defer m.disconnect(connection)
+
+	collection := connection.Database(m.database).Collection(collectionReservations)
+	_, err = collection.InsertOne(m.ctx, reservation)
+	return err
+}
+
+func (m *MongoDB) AddSecurityEvent(event *entity.SecurityEvent) error {
+	connection, err := m.connect()
+	if err != nil {
+		return err
+	}
+	defer m.disconnect(connection)
+
+	collection := connection.Database(m.database).Collection(collectionSecurityEvents)
+	_, err = collection.InsertOne(m.ctx, event)
+	return err
+}
+
+func (m *MongoDB) UpdateReservation(reservation *entity.Reservation) error {
+	connection, err := m.connect()
+	if err != nil {
+		return err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{{"reservation_id", reservation.Id}}
+	update := bson.M{"$set": reservation}
+	collection := connection.Database(m.database).Collection(collectionReservations)
+	_, err = collection.UpdateOne(m.ctx, filter, update)
+	return err
+}
+
+// GetActiveReservations returns the reservations of a charge point that are still active and not
+// yet expired at the given time, whichever connector they hold.
+func (m *MongoDB) GetActiveReservations(chargePointId string, now time.Time) ([]*entity.Reservation, error) {
+	connection, err := m.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{
+		{"charge_point_id", chargePointId},
+		{"status", entity.ReservationActive},
+		{"expiry_date", bson.D{{"$gt", now}}},
+	}
+	collection := connection.Database(m.database).Collection(collectionReservations)
+	cursor, err := collection.Find(m.ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var reservations []*entity.Reservation
+	if err = cursor.All(m.ctx, &reservations); err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
+
+// GetExpiredReservations returns reservations still marked active whose expiry date has passed.
+// The charge point drops them on its own; this is what the central system has to catch up on.
+func (m *MongoDB) GetExpiredReservations(now time.Time) ([]*entity.Reservation, error) {
+	connection, err := m.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{
+		{"status", entity.ReservationActive},
+		{"expiry_date", bson.D{{"$lte", now}}},
+	}
+	collection := connection.Database(m.database).Collection(collectionReservations)
+	cursor, err := collection.Find(m.ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var reservations []*entity.Reservation
+	if err = cursor.All(m.ctx, &reservations); err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
+
+// UpdateChargePointAvailability stores the availability operations chose for a whole charge point.
+func (m *MongoDB) UpdateChargePointAvailability(chargePointId string, isEnabled bool) error {
+	connection, err := m.connect()
+	if err != nil {
+		return err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{{"charge_point_id", chargePointId}}
+	update := bson.M{"$set": bson.M{"is_enabled": isEnabled}}
+	collection := connection.Database(m.database).Collection(collectionChargePoints)
+	_, err = collection.UpdateOne(m.ctx, filter, update)
+	return err
+}
+
+// UpdateChargePointPassword stores the hash of the password a charge point authenticates with.
+func (m *MongoDB) UpdateChargePointPassword(chargePointId string, passwordHash string) error {
+	connection, err := m.connect()
+	if err != nil {
+		return err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{{"charge_point_id", chargePointId}}
+	update := bson.M{"$set": bson.M{"password_hash": passwordHash}}
+	collection := connection.Database(m.database).Collection(collectionChargePoints)
+	_, err = collection.UpdateOne(m.ctx, filter, update)
+	return err
+}
+
+// UpdateConnectorAvailability stores the availability operations chose for a single connector.
+func (m *MongoDB) UpdateConnectorAvailability(chargePointId string, connectorId int, isEnabled bool) error {
+	connection, err := m.connect()
+	if err != nil {
+		return err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{{"connector_id", connectorId}, {"charge_point_id", chargePointId}}
+	update := bson.M{"$set": bson.M{"is_enabled": isEnabled}}
+	collection := connection.Database(m.database).Collection(collectionConnectors)
+	_, err = collection.UpdateOne(m.ctx, filter, update)
+	return err
+}
+
+// GetLastFirmwareCampaign returns the campaign with the highest id; an empty collection returns nil.
+func (m *MongoDB) GetLastFirmwareCampaign() (*entity.FirmwareCampaign, error) {
+	connection, err := m.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.disconnect(connection)
+
+	collection := connection.Database(m.database).Collection(collectionFirmware)
+	opts := options.FindOne().SetSort(bson.D{{"campaign_id", -1}})
+	var campaign entity.FirmwareCampaign
+	err = collection.FindOne(m.ctx, bson.D{}, opts).Decode(&campaign)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+func (m *MongoDB) GetFirmwareCampaign(id int) (*entity.FirmwareCampaign, error) {
+	connection, err := m.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{{"campaign_id", id}}
+	collection := connection.Database(m.database).Collection(collectionFirmware)
+	var campaign entity.FirmwareCampaign
+	err = collection.FindOne(m.ctx, filter).Decode(&campaign)
+	if err != nil {
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+// GetRunningFirmwareCampaigns returns campaigns that have not finished; they are picked up again
+// after a restart.
+func (m *MongoDB) GetRunningFirmwareCampaigns() ([]*entity.FirmwareCampaign, error) {
+	connection, err := m.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{{"status", entity.CampaignRunning}}
+	collection := connection.Database(m.database).Collection(collectionFirmware)
+	cursor, err := collection.Find(m.ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var campaigns []*entity.FirmwareCampaign
+	if err = cursor.All(m.ctx, &campaigns); err != nil {
+		return nil, err
+	}
+	return campaigns, nil
+}
+
+func (m *MongoDB) AddFirmwareCampaign(campaign *entity.FirmwareCampaign) error {
+	connection, err := m.connect()
+	if err != nil {
+		return err
+	}
+	defer m.disconnect(connection)
+
+	collection := connection.Database(m.database).Collection(collectionFirmware)
+	_, err = collection.InsertOne(m.ctx, campaign)
+	return err
+}
+
+func (m *MongoDB) UpdateFirmwareCampaign(campaign *entity.FirmwareCampaign) error {
+	connection, err := m.connect()
+	if err != nil {
+		return err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{{"campaign_id", campaign.Id}}
+	update := bson.M{"$set": campaign}
+	collection := connection.Database(m.database).Collection(collectionFirmware)
+	_, err = collection.UpdateOne(m.ctx, filter, update)
+	return err
+}
+
+// GetLastDisplayMessage returns the message with the highest id; an empty collection returns nil.
+func (m *MongoDB) GetLastDisplayMessage() (*entity.DisplayMessage, error) {
+	connection, err := m.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.disconnect(connection)
+
+	collection := connection.Database(m.database).Collection(collectionDisplayMessages)
+	opts := options.FindOne().SetSort(bson.D{{"message_id", -1}})
+	var message entity.DisplayMessage
+	err = collection.FindOne(m.ctx, bson.D{}, opts).Decode(&message)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+func (m *MongoDB) GetDisplayMessage(id int) (*entity.DisplayMessage, error) {
+	connection, err := m.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{{"message_id", id}}
+	collection := connection.Database(m.database).Collection(collectionDisplayMessages)
+	var message entity.DisplayMessage
+	err = collection.FindOne(m.ctx, filter).Decode(&message)
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// GetCurrentDisplayMessages returns messages that are scheduled or being shown; they are picked up
+// again after a restart.
+func (m *MongoDB) GetCurrentDisplayMessages() ([]*entity.DisplayMessage, error) {
+	connection, err := m.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{{"status", bson.D{{"$in", bson.A{entity.DisplayMessageScheduled, entity.DisplayMessageActive}}}}}
+	collection := connection.Database(m.database).Collection(collectionDisplayMessages)
+	cursor, err := collection.Find(m.ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var messages []*entity.DisplayMessage
+	if err = cursor.All(m.ctx, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func (m *MongoDB) AddDisplayMessage(message *entity.DisplayMessage) error {
+	connection, err := m.connect()
+	if err != nil {
+		return err
+	}
+	defer m.disconnect(connection)
+
+	collection := connection.Database(m.database).Collection(collectionDisplayMessages)
+	_, err = collection.InsertOne(m.ctx, message)
+	return err
+}
+
+func (m *MongoDB) UpdateDisplayMessage(message *entity.DisplayMessage) error {
+	connection, err := m.connect()
+	if err != nil {
+		return err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{{"message_id", message.Id}}
+	update := bson.M{"$set": message}
+	collection := connection.Database(m.database).Collection(collectionDisplayMessages)
+	_, err = collection.UpdateOne(m.ctx, filter, update)
+	return err
+}
+
+func (m *MongoDB) GetDeviceVariables(chargePointId string) ([]*entity.DeviceVariable, error) {
+	return m.FindDeviceVariables(&entity.DeviceVariableQuery{ChargePointId: chargePointId})
+}
+
+// FindDeviceVariables returns the attributes matching every field given in the query.
+func (m *MongoDB) FindDeviceVariables(query *entity.DeviceVariableQuery) ([]*entity.DeviceVariable, error) {
+	connection, err := m.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.disconnect(connection)
+
+	fields := bson.D{
+		{"charge_point_id", query.ChargePointId},
+		{"component", query.Component},
+		{"variable", query.Variable},
+		{"attribute_type", query.AttributeType},
+		{"value", query.Value},
+	}
+	filter := bson.D{}
+	for _, field := range fields {
+		if field.Value != "" {
+			filter = append(filter, field)
+		}
+	}
+	opts := options.Find().SetSort(bson.D{{"charge_point_id", 1}, {"component", 1}, {"evse_id", 1}, {"variable", 1}})
+	collection := connection.Database(m.database).Collection(collectionDeviceVariables)
+	cursor, err := collection.Find(m.ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var variables []*entity.DeviceVariable
+	if err = cursor.All(m.ctx, &variables); err != nil {
+		return nil, err
+	}
+	return variables, nil
+}
+
+// deviceVariableFilter matches the one record of an attribute; the empty instances and EVSE are
+// stored too, so they match exactly.
+func deviceVariableFilter(variable *entity.DeviceVariable) bson.D {
+	return bson.D{
+		{"charge_point_id", variable.ChargePointId},
+		{"component", variable.Component},
+		{"component_instance", variable.ComponentInstance},
+		{"evse_id", variable.EvseId},
+		{"connector_id", variable.ConnectorId},
+		{"variable", variable.Variable},
+		{"variable_instance", variable.VariableInstance},
+		{"attribute_type", variable.AttributeType},
+	}
+}
+
+func (m *MongoDB) SaveDeviceVariables(variables []*entity.DeviceVariable) error {
+	connection, err := m.connect()
+	if err != nil {
+		return err
+	}
+	defer m.disconnect(connection)
+
+	models := make([]mongo.WriteModel, 0, len(variables))
+	for _, variable := range variables {
+		models = append(models, mongo.NewReplaceOneModel().
+			SetFilter(deviceVariableFilter(variable)).
+			SetReplacement(variable).
+			SetUpsert(true))
+	}
+	collection := connection.Database(m.database).Collection(collectionDeviceVariables)
+	_, err = collection.BulkWrite(m.ctx, models)
+	return err
+}
+
+func (m *MongoDB) DeleteDeviceVariables(variables []*entity.DeviceVariable) error {
+	connection, err := m.connect()
+	if err != nil {
+		return err
+	}
+	defer m.disconnect(connection)
+
+	models := make([]mongo.WriteModel, 0, len(variables))
+	for _, variable := range variables {
+		models = append(models, mongo.NewDeleteOneModel().SetFilter(deviceVariableFilter(variable)))
+	}
+	collection := connection.Database(m.database).Collection(collectionDeviceVariables)
+	_, err = collection.BulkWrite(m.ctx, models)
+	return err
+}
+
+func (m *MongoDB) AddDeviceVariableChanges(changes []*entity.DeviceVariableChange) error {
+	connection, err := m.connect()
+	if err != nil {
+		return err
+	}
+	defer m.disconnect(connection)
+
+	documents := make([]interface{}, 0, len(changes))
+	for _, change := range changes {
+		documents = append(documents, change)
+	}
+	collection := connection.Database(m.database).Collection(collectionDeviceChanges)
+	_, err = collection.InsertMany(m.ctx, documents)
+	return err
+}
+
+func (m *MongoDB) GetDeviceVariableChanges(chargePointId string, since time.Time) ([]*entity.DeviceVariableChange, error) {
+	connection, err := m.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{{"charge_point_id", chargePointId}, {"time", bson.D{{"$gte", since}}}}
+	opts := options.Find().SetSort(bson.D{{"time", 1}})
+	collection := connection.Database(m.database).Collection(collectionDeviceChanges)
+	cursor, err := collection.Find(m.ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var changes []*entity.DeviceVariableChange
+	if err = cursor.All(m.ctx, &changes); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// GetCustomerTransactions returns the transactions of an id tag, the oldest first. A transaction
+// keeps the tag as the charge point sent it, so it may carry the prefix of the charge point.
+func (m *MongoDB) GetCustomerTransactions(idTag string) ([]*entity.Transaction, error) {
+	connection, err := m.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{{"id_tag", bson.D{{"$regex", entity.IdTagPattern(idTag)}}}}
+	opts := options.Find().SetSort(bson.D{{"time_start", 1}})
+	collection := connection.Database(m.database).Collection(collectionTransactions)
+	cursor, err := collection.Find(m.ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var transactions []*entity.Transaction
+	if err = cursor.All(m.ctx, &transactions); err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// AnonymizeCustomerTransactions replaces the id tag of a customer and what identifies them in their
+// transactions, and in the StopTransaction requests kept as received; in both the tag may carry the
+// prefix of the charge point. It returns the number of transactions changed.
+func (m *MongoDB) AnonymizeCustomerTransactions(idTag, placeholder string) (int, error) {
+	connection, err := m.connect()
+	if err != nil {
+		return 0, err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{{"id_tag", bson.D{{"$regex", entity.IdTagPattern(idTag)}}}}
+	update := bson.M{"$set": bson.D{
+		{"id_tag", placeholder},
+		{"id_tag_note", ""},
+		{"username", ""},
+		{"user_tag", nil},
+	}}
+	collection := connection.Database(m.database).Collection(collectionTransactions)
+	result, err := collection.UpdateMany(m.ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+
+	filter = bson.D{{"id_tag", bson.D{{"$regex", entity.IdTagPattern(idTag)}}}}
+	update = bson.M{"$set": bson.M{"id_tag": placeholder}}
+	collection = connection.Database(m.database).Collection(collectionStopTransaction)
+	if _, err = collection.UpdateMany(m.ctx, filter, update); err != nil {
+		return 0, err
+	}
+	return int(result.ModifiedCount), nil
+}
+
+func (m *MongoDB) GetCustomerReservations(idTag string) ([]*entity.Reservation, error) {
+	connection, err := m.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{{"id_tag", bson.D{{"$regex", entity.IdTagPattern(idTag)}}}}
+	opts := options.Find().SetSort(bson.D{{"reservation_id", 1}})
+	collection := connection.Database(m.database).Collection(collectionReservations)
+	cursor, err := collection.Find(m.ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var reservations []*entity.Reservation
+	if err = cursor.All(m.ctx, &reservations); err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
+
+func (m *MongoDB) AnonymizeCustomerReservations(idTag, placeholder string) (int, error) {
+	connection, err := m.connect()
+	if err != nil {
+		return 0, err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{{"id_tag", bson.D{{"$regex", entity.IdTagPattern(idTag)}}}}
+	update := bson.M{"$set": bson.M{"id_tag": placeholder}}
+	collection := connection.Database(m.database).Collection(collectionReservations)
+	result, err := collection.UpdateMany(m.ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return int(result.ModifiedCount), nil
+}
+
+// FindLogMessages returns the log lines whose text matches a regular expression, the oldest first.
+func (m *MongoDB) FindLogMessages(pattern string) ([]*FeatureLogMessage, error) {
+	connection, err := m.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{{"text", bson.D{{"$regex", pattern}}}}
+	opts := options.Find().SetSort(bson.D{{"timestamp", 1}})
+	collection := connection.Database(m.database).Collection(collectionLog)
+	cursor, err := collection.Find(m.ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var messages []*FeatureLogMessage
+	if err = cursor.All(m.ctx, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// AnonymizeLogMessages replaces text with the placeholder where a regular expression matches it in
+// the log lines, and returns the number of lines changed. The lines are rewritten here rather than
+// in the update, so that text inside a longer word the expression does not match is left alone.
+func (m *MongoDB) AnonymizeLogMessages(pattern, text, placeholder string) (int, error) {
+	expression, err := regexp.Compile(pattern)
+	if err != nil {
+		return 0, err
+	}
+	connection, err := m.connect()
+	if err != nil {
+		return 0, err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{{"text", bson.D{{"$regex", pattern}}}}
+	opts := options.Find().SetProjection(bson.D{{"text", 1}})
+	collection := connection.Database(m.database).Collection(collectionLog)
+	cursor, err := collection.Find(m.ctx, filter, opts)
+	if err != nil {
+		return 0, err
+	}
+	var lines []struct {
+		Id   interface{} `bson:"_id"`
+		Text string      `bson:"text"`
+	}
+	if err = cursor.All(m.ctx, &lines); err != nil {
+		return 0, err
+	}
+	changed := 0
+	for _, line := range lines {
+		anonymized := replaceMatched(line.Text, expression, text, placeholder)
+		if anonymized == line.Text {
+			continue
+		}
+		result, err := collection.UpdateByID(m.ctx, line.Id, bson.D{{"$set", bson.D{{"text", anonymized}}}})
+		if err != nil {
+			return changed, err
+		}
+		changed += int(result.ModifiedCount)
+	}
+	return changed, nil
+}
+
+func (m *MongoDB) AddCustomerInformation(information *entity.CustomerInformation) error {
+	connection, err := m.connect()
+	if err != nil {
+		return err
+	}
+	defer m.disconnect(connection)
+
+	collection := connection.Database(m.database).Collection(collectionCustomerInfo)
+	_, err = collection.InsertOne(m.ctx, information)
+	return err
+}
+
+func (m *MongoDB) GetCustomerInformation(idTag string) ([]*entity.CustomerInformation, error) {
+	connection, err := m.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{{"id_tag", idTag}}
+	opts := options.Find().SetSort(bson.D{{"time_received", 1}})
+	collection := connection.Database(m.database).Collection(collectionCustomerInfo)
+	cursor, err := collection.Find(m.ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var reports []*entity.CustomerInformation
+	if err = cursor.All(m.ctx, &reports); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+func (m *MongoDB) DeleteCustomerInformation(idTag string) (int, error) {
+	connection, err := m.connect()
+	if err != nil {
+		return 0, err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{{"id_tag", idTag}}
+	collection := connection.Database(m.database).Collection(collectionCustomerInfo)
+	result, err := collection.DeleteMany(m.ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return int(result.DeletedCount), nil
+}
+
+// SaveExternalLimit stores a limit in place of the one its source set earlier on the same EVSE
+func (m *MongoDB) SaveExternalLimit(limit *entity.ExternalLimit) error {
+	connection, err := m.connect()
+	if err != nil {
+		return err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{
+		{"charge_point_id", limit.ChargePointId},
+		{"evse_id", limit.EvseId},
+		{"source", limit.Source},
+	}
+	collection := connection.Database(m.database).Collection(collectionExternalLimits)
+	_, err = collection.ReplaceOne(m.ctx, filter, limit, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (m *MongoDB) GetExternalLimits(chargePointId string) ([]*entity.ExternalLimit, error) {
+	connection, err := m.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{{"charge_point_id", chargePointId}}
+	collection := connection.Database(m.database).Collection(collectionExternalLimits)
+	cursor, err := collection.Find(m.ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var limits []*entity.ExternalLimit
+	if err = cursor.All(m.ctx, &limits); err != nil {
+		return nil, err
+	}
+	return limits, nil
+}
+
+// DeleteExternalLimits removes the limits a source set on a charge point, on one EVSE or, without
+// one, on all of them
+func (m *MongoDB) DeleteExternalLimits(chargePointId, source string, evseId *int) (int, error) {
+	connection, err := m.connect()
+	if err != nil {
+		return 0, err
+	}
+	defer m.disconnect(connection)
+
+	filter := bson.D{
+		{"charge_point_id", chargePointId},
+		{"source", source},
+	}
+	if evseId != nil {
+		filter = append(filter, bson.E{Key: "evse_id", Value: *evseId})
+	}
+	collection := connection.Database(m.database).Collection(collectionExternalLimits)
+	result, err := collection.DeleteMany(m.ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return int(result.DeletedCount), nil
+}
@@ -1,5 +1,17 @@
 package internal
 
+import "crypto/x509"
+
 type StatusHandler interface {
 	OnOnlineStatusChanged(id string, isOnline bool)
 }
+
+// Authenticator checks the HTTP Basic credentials a charge point presents when it opens its websocket.
+type Authenticator interface {
+	AuthenticateChargePoint(chargePointId, username, password string) bool
+}
+
+// CertificateAuthenticator checks the verified client certificate a charge point connects with.
+type CertificateAuthenticator interface {
+	AuthenticateCertificate(chargePointId string, certificate *x509.Certificate) bool
+}
@@ -1,6 +1,8 @@
 package counters
 
 import (
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -127,3 +129,41 @@ func ObservePowerRate(location, chargePointId, connectorId string, power float64
 			"connector_id":    connectorId,
 		}).Set(power)
 }
+
+var authFailureCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "server",
+	Name:      "auth_failures",
+	Help:      "Rejected websocket connections by charge point and reason.",
+}, []string{"charge_point_id", "reason"})
+
+// CountAuthFailure takes an empty id for connections claiming an unknown charge point, so that
+// anyone probing ids cannot blow up the label set.
+func CountAuthFailure(chargePointId, reason string) {
+	if len(chargePointId) == 0 {
+		chargePointId = "unknown"
+	}
+	authFailureCounter.With(prometheus.Labels{"charge_point_id": chargePointId, "reason": reason}).Inc()
+}
+
+var unsetPasswordCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "server",
+	Name:      "unset_password_connections",
+	Help:      "Websocket connections let through without a password, as the charge point has none yet.",
+}, []string{"charge_point_id"})
+
+func CountUnsetPassword(chargePointId string) {
+	unsetPasswordCounter.With(prometheus.Labels{"charge_point_id": chargePointId}).Inc()
+}
+
+var certificateExpiryGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "server",
+	Name:      "client_certificate_expiry_timestamp_seconds",
+	Help:      "Expiry of the client certificate a charge point last connected with.",
+}, []string{"charge_point_id"})
+
+func ObserveCertificateExpiry(chargePointId string, notAfter time.Time) {
+	if len(chargePointId) == 0 {
+		return
+	}
+	certificateExpiryGauge.With(prometheus.Labels{"charge_point_id": chargePointId}).Set(float64(notAfter.Unix()))
+}
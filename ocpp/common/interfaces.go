@@ -1,6 +1,7 @@
 package common
 
 import (
+	"errors"
 	"net"
 	"time"
 )
@@ -111,10 +112,16 @@ type Feature interface {
 	GetResponseExample() Response
 }
 
+// ErrRequired is wrapped by validation errors of a missing field, or of a list with fewer elements
+// than it takes; such a request is answered with OccurenceConstraintViolation rather than
+// PropertyConstraintViolation
+var ErrRequired = errors.New("required")
+
 // ValidationError represents a validation error with details
 type ValidationError struct {
 	Field   string // The field that failed validation
 	Message string // The error message
+	Err     error  // ErrRequired for a missing field, nil otherwise
 }
 
 func (e *ValidationError) Error() string {
@@ -124,6 +131,10 @@ func (e *ValidationError) Error() string {
 	return e.Message
 }
 
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
 // NewValidationError creates a new validation error
 func NewValidationError(field, message string) *ValidationError {
 	return &ValidationError{
@@ -48,6 +48,12 @@ func (p ProtocolVersion) MajorVersion() string {
 	}
 }
 
+// IsOCPP2 reports whether the version belongs to the 2.x family. OCPP 2.1 keeps the 2.0.1
+// messages it does not extend, so most handling is shared between the two.
+func (p ProtocolVersion) IsOCPP2() bool {
+	return p == OCPP201 || p == OCPP21
+}
+
 // ParseProtocolVersion converts a string to a ProtocolVersion
 func ParseProtocolVersion(version string) ProtocolVersion {
 	switch version {
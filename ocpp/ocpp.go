@@ -1,6 +1,7 @@
 package ocpp
 
 import (
+	"fmt"
 	"reflect"
 )
 
@@ -21,3 +22,51 @@ type Feature interface {
 	GetRequestType() reflect.Type
 	GetResponseType() reflect.Type
 }
+
+// ErrorCode is the errorCode of an OCPP-J CallError
+type ErrorCode string
+
+// CallError codes as OCPP 1.6 spells them; OCPP 2.0.1 renamed two and added the last two
+const (
+	NotImplemented                ErrorCode = "NotImplemented"
+	NotSupported                  ErrorCode = "NotSupported"
+	InternalError                 ErrorCode = "InternalError"
+	ProtocolError                 ErrorCode = "ProtocolError"
+	SecurityError                 ErrorCode = "SecurityError"
+	FormationViolation            ErrorCode = "FormationViolation"
+	PropertyConstraintViolation   ErrorCode = "PropertyConstraintViolation"
+	OccurenceConstraintViolation  ErrorCode = "OccurenceConstraintViolation"
+	TypeConstraintViolation       ErrorCode = "TypeConstraintViolation"
+	GenericError                  ErrorCode = "GenericError"
+	FormatViolation               ErrorCode = "FormatViolation"               // 2.0.1 FormationViolation
+	OccurrenceConstraintViolation ErrorCode = "OccurrenceConstraintViolation" // 2.0.1 OccurenceConstraintViolation
+	MessageTypeNotSupported       ErrorCode = "MessageTypeNotSupported"
+	RpcFrameworkError             ErrorCode = "RpcFrameworkError"
+)
+
+// CallError is a request that could not be handled: the CallError a charge point answered one of
+// ours with, or the one we answer a charge point's request with.
+type CallError struct {
+	ErrorCode        ErrorCode   `json:"errorCode"`
+	ErrorDescription string      `json:"errorDescription"`
+	ErrorDetails     interface{} `json:"errorDetails,omitempty"`
+}
+
+func (e *CallError) Error() string {
+	if e.ErrorDescription == "" {
+		return string(e.ErrorCode)
+	}
+	return fmt.Sprintf("%s: %s", e.ErrorCode, e.ErrorDescription)
+}
+
+// NewCallError creates a CallError described by a formatted message
+func NewCallError(code ErrorCode, format string, args ...interface{}) *CallError {
+	return &CallError{ErrorCode: code, ErrorDescription: fmt.Sprintf(format, args...)}
+}
+
+// Answer is what a charge point answered a request with: the raw CallResult payload, or the
+// CallError in Err
+type Answer struct {
+	Payload string
+	Err     *CallError
+}
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"evsys/ocpp/common"
 	"evsys/types"
 	"fmt"
 )
@@ -18,7 +19,7 @@ func (f *AuthorizeRequest) GetFeatureName() string {
 // Validate checks required fields and length limits.
 func (f *AuthorizeRequest) Validate() error {
 	if f.IdTag == "" {
-		return fmt.Errorf("idTag is required")
+		return fmt.Errorf("idTag is %w", common.ErrRequired)
 	}
 	if len(f.IdTag) > 20 {
 		return fmt.Errorf("idTag exceeds 20 characters")
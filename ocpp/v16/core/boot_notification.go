@@ -1,6 +1,7 @@
 package core
 
 import (
+	"evsys/ocpp/common"
 	"evsys/types"
 	"fmt"
 	"reflect"
@@ -47,13 +48,13 @@ func (r *BootNotificationRequest) GetFeatureName() string {
 // Validate checks the fields mandated by the OCPP 1.6 spec.
 func (r *BootNotificationRequest) Validate() error {
 	if r.ChargePointVendor == "" {
-		return fmt.Errorf("chargePointVendor is required")
+		return fmt.Errorf("chargePointVendor is %w", common.ErrRequired)
 	}
 	if len(r.ChargePointVendor) > 20 {
 		return fmt.Errorf("chargePointVendor exceeds 20 characters")
 	}
 	if r.ChargePointModel == "" {
-		return fmt.Errorf("chargePointModel is required")
+		return fmt.Errorf("chargePointModel is %w", common.ErrRequired)
 	}
 	if len(r.ChargePointModel) > 20 {
 		return fmt.Errorf("chargePointModel exceeds 20 characters")
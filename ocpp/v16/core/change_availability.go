@@ -0,0 +1,56 @@
+package core
+
+const ChangeAvailabilityFeatureName = "ChangeAvailability"
+
+type AvailabilityType string
+
+type AvailabilityStatus string
+
+const (
+	AvailabilityTypeOperative   AvailabilityType   = "Operative"
+	AvailabilityTypeInoperative AvailabilityType   = "Inoperative"
+	AvailabilityStatusAccepted  AvailabilityStatus = "Accepted"
+	AvailabilityStatusRejected  AvailabilityStatus = "Rejected"
+	AvailabilityStatusScheduled AvailabilityStatus = "Scheduled"
+)
+
+func (t AvailabilityType) IsValid() bool {
+	switch t {
+	case AvailabilityTypeOperative, AvailabilityTypeInoperative:
+		return true
+	}
+	return false
+}
+
+func (s AvailabilityStatus) IsValid() bool {
+	switch s {
+	case AvailabilityStatusAccepted, AvailabilityStatusRejected, AvailabilityStatusScheduled:
+		return true
+	}
+	return false
+}
+
+type ChangeAvailabilityRequest struct {
+	ConnectorId int              `json:"connectorId" validate:"gte=0"`
+	Type        AvailabilityType `json:"type" validate:"required,availabilityType"`
+}
+
+type ChangeAvailabilityResponse struct {
+	Status AvailabilityStatus `json:"status" validate:"required,availabilityStatus"`
+}
+
+func (r ChangeAvailabilityRequest) GetFeatureName() string {
+	return ChangeAvailabilityFeatureName
+}
+
+func (c ChangeAvailabilityResponse) GetFeatureName() string {
+	return ChangeAvailabilityFeatureName
+}
+
+func NewChangeAvailabilityRequest(connectorId int, availabilityType AvailabilityType) *ChangeAvailabilityRequest {
+	return &ChangeAvailabilityRequest{ConnectorId: connectorId, Type: availabilityType}
+}
+
+func NewChangeAvailabilityResponse(status AvailabilityStatus) *ChangeAvailabilityResponse {
+	return &ChangeAvailabilityResponse{Status: status}
+}
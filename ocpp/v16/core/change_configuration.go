@@ -4,12 +4,12 @@ const ChangeConfigurationFeatureName = "ChangeConfiguration"
 
 type ConfigurationStatus string
 
-//const (
-//	ConfigurationStatusAccepted       ConfigurationStatus = "Accepted"
-//	ConfigurationStatusRejected       ConfigurationStatus = "Rejected"
-//	ConfigurationStatusRebootRequired ConfigurationStatus = "RebootRequired"
-//	ConfigurationStatusNotSupported   ConfigurationStatus = "NotSupported"
-//)
+const (
+	ConfigurationStatusAccepted       ConfigurationStatus = "Accepted"
+	ConfigurationStatusRejected       ConfigurationStatus = "Rejected"
+	ConfigurationStatusRebootRequired ConfigurationStatus = "RebootRequired"
+	ConfigurationStatusNotSupported   ConfigurationStatus = "NotSupported"
+)
 
 type ChangeConfigurationRequest struct {
 	Key   string `json:"key" validate:"required,max=50"`
@@ -1,6 +1,9 @@
 package core
 
-import "fmt"
+import (
+	"evsys/ocpp/common"
+	"fmt"
+)
 
 const DataTransferFeatureName = "DataTransfer"
 
@@ -31,7 +34,7 @@ func (r DataTransferRequest) GetFeatureName() string {
 // Validate checks required fields and length limits.
 func (r DataTransferRequest) Validate() error {
 	if r.VendorId == "" {
-		return fmt.Errorf("vendorId is required")
+		return fmt.Errorf("vendorId is %w", common.ErrRequired)
 	}
 	if len(r.VendorId) > 255 {
 		return fmt.Errorf("vendorId exceeds 255 characters")
@@ -46,6 +49,10 @@ func (c DataTransferResponse) GetFeatureName() string {
 	return DataTransferFeatureName
 }
 
+func NewDataTransferRequest(vendorId string) *DataTransferRequest {
+	return &DataTransferRequest{VendorId: vendorId}
+}
+
 func NewDataTransferResponse(status DataTransferStatus) *DataTransferResponse {
 	return &DataTransferResponse{Status: status}
 }
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"evsys/ocpp/common"
 	"evsys/types"
 	"fmt"
 )
@@ -26,7 +27,7 @@ func (r MeterValuesRequest) Validate() error {
 		return fmt.Errorf("connectorId must be >= 0")
 	}
 	if len(r.MeterValue) == 0 {
-		return fmt.Errorf("meterValue is required")
+		return fmt.Errorf("meterValue is %w", common.ErrRequired)
 	}
 	for i := range r.MeterValue {
 		if err := r.MeterValue[i].Validate(); err != nil {
@@ -18,6 +18,10 @@ func (r RemoteStartTransactionRequest) GetFeatureName() string {
 	return RemoteStartTransactionFeatureName
 }
 
+func (c RemoteStartTransactionResponse) GetFeatureName() string {
+	return RemoteStartTransactionFeatureName
+}
+
 func NewRemoteStartTransactionRequest(idTag string) *RemoteStartTransactionRequest {
 	return &RemoteStartTransactionRequest{IdTag: idTag}
 }
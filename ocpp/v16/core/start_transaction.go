@@ -1,6 +1,7 @@
 package core
 
 import (
+	"evsys/ocpp/common"
 	"evsys/types"
 	"fmt"
 	"time"
@@ -40,7 +41,7 @@ func (req StartTransactionRequest) Validate() error {
 		return fmt.Errorf("connectorId must be > 0")
 	}
 	if req.IdTag == "" {
-		return fmt.Errorf("idTag is required")
+		return fmt.Errorf("idTag is %w", common.ErrRequired)
 	}
 	if len(req.IdTag) > 20 {
 		return fmt.Errorf("idTag exceeds 20 characters")
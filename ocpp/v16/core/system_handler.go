@@ -10,3 +10,17 @@ type SystemHandler interface {
 	OnStatusNotification(chargePointId string, request *StatusNotificationRequest) (confirmation *StatusNotificationResponse, err error)
 	OnDataTransfer(chargePointId string, request *DataTransferRequest) (confirmation *DataTransferResponse, err error)
 }
+
+// CommandHandler builds the Core Profile requests the central system sends on API commands.
+type CommandHandler interface {
+	OnRemoteStartTransaction(chargePointId string, connectorId int, idTag string) (*RemoteStartTransactionRequest, error)
+	OnRemoteStopTransaction(chargePointId string, transactionId string) (*RemoteStopTransactionRequest, error)
+	OnGetConfiguration(chargePointId string, key string) (*GetConfigurationRequest, error)
+	OnChangeConfiguration(chargePointId string, payload string) (*ChangeConfigurationRequest, error)
+	OnReset(chargePointId string, payload string) (*ResetRequest, error)
+	OnChangeAvailability(chargePointId string, connectorId int, payload string) (*ChangeAvailabilityRequest, error)
+	OnUnlockConnector(chargePointId string, connectorId int) (*UnlockConnectorRequest, error)
+	OnSendDataTransfer(chargePointId string, payload string) (*DataTransferRequest, error)
+	// OnChangeAvailabilityResponse is called with the charge point's answer to a ChangeAvailability
+	OnChangeAvailabilityResponse(chargePointId string, request *ChangeAvailabilityRequest, response *ChangeAvailabilityResponse)
+}
@@ -3,4 +3,12 @@ package firmware
 type SystemHandler interface {
 	OnDiagnosticsStatusNotification(chargePointId string, request *DiagnosticsStatusNotificationRequest) (confirmation *DiagnosticsStatusNotificationResponse, err error)
 	OnFirmwareStatusNotification(chargePointId string, request *StatusNotificationRequest) (confirmation *StatusNotificationResponse, err error)
+	OnSignedFirmwareStatusNotification(chargePointId string, request *SignedStatusNotificationRequest) (confirmation *SignedStatusNotificationResponse, err error)
+}
+
+type CommandHandler interface {
+	OnGetDiagnostics(chargePointId string, payload string) (*GetDiagnosticsRequest, error)
+	OnUpdateFirmware(chargePointId string, payload string) (*UpdateFirmwareRequest, error)
+	OnSignedUpdateFirmware(chargePointId string, payload string) (*SignedUpdateFirmwareRequest, error)
+	OnSignedUpdateFirmwareResponse(chargePointId string, request *SignedUpdateFirmwareRequest, response *SignedUpdateFirmwareResponse)
 }
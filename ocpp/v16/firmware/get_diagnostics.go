@@ -12,10 +12,24 @@ type GetDiagnosticsRequest struct {
 	StopTime      *types.DateTime `json:"stopTime,omitempty"`
 }
 
+// GetDiagnosticsResponse names the file the charge point is going to upload; an empty file name
+// means there are no diagnostics to send.
+type GetDiagnosticsResponse struct {
+	FileName string `json:"fileName,omitempty" validate:"max=255"`
+}
+
 func (r GetDiagnosticsRequest) GetFeatureName() string {
 	return GetDiagnosticsFeatureName
 }
 
+func (c GetDiagnosticsResponse) GetFeatureName() string {
+	return GetDiagnosticsFeatureName
+}
+
 func NewGetDiagnosticsRequest(location string) *GetDiagnosticsRequest {
 	return &GetDiagnosticsRequest{Location: location}
 }
+
+func NewGetDiagnosticsResponse() *GetDiagnosticsResponse {
+	return &GetDiagnosticsResponse{}
+}
@@ -0,0 +1,72 @@
+package firmware
+
+import "fmt"
+
+const SignedStatusNotificationFeatureName = "SignedFirmwareStatusNotification"
+
+// SignedStatus extends Status with the steps of a signed update.
+type SignedStatus string
+
+const (
+	SignedStatusDownloaded                SignedStatus = "Downloaded"
+	SignedStatusDownloadFailed            SignedStatus = "DownloadFailed"
+	SignedStatusDownloading               SignedStatus = "Downloading"
+	SignedStatusDownloadScheduled         SignedStatus = "DownloadScheduled"
+	SignedStatusDownloadPaused            SignedStatus = "DownloadPaused"
+	SignedStatusIdle                      SignedStatus = "Idle"
+	SignedStatusInstallationFailed        SignedStatus = "InstallationFailed"
+	SignedStatusInstalling                SignedStatus = "Installing"
+	SignedStatusInstalled                 SignedStatus = "Installed"
+	SignedStatusInstallRebooting          SignedStatus = "InstallRebooting"
+	SignedStatusInstallScheduled          SignedStatus = "InstallScheduled"
+	SignedStatusInstallVerificationFailed SignedStatus = "InstallVerificationFailed"
+	SignedStatusInvalidSignature          SignedStatus = "InvalidSignature"
+	SignedStatusSignatureVerified         SignedStatus = "SignatureVerified"
+)
+
+func (s SignedStatus) IsValid() bool {
+	switch s {
+	case SignedStatusDownloaded, SignedStatusDownloadFailed, SignedStatusDownloading,
+		SignedStatusDownloadScheduled, SignedStatusDownloadPaused, SignedStatusIdle,
+		SignedStatusInstallationFailed, SignedStatusInstalling, SignedStatusInstalled,
+		SignedStatusInstallRebooting, SignedStatusInstallScheduled, SignedStatusInstallVerificationFailed,
+		SignedStatusInvalidSignature, SignedStatusSignatureVerified:
+		return true
+	}
+	return false
+}
+
+// Status maps a signed update status onto the plain firmware status of the same step; ok is false
+// for the steps plain updates do not have.
+func (s SignedStatus) Status() (status Status, ok bool) {
+	status = Status(s)
+	return status, status.IsValid()
+}
+
+type SignedStatusNotificationRequest struct {
+	Status    SignedStatus `json:"status" validate:"required,signedFirmwareStatus"`
+	RequestId *int         `json:"requestId,omitempty"`
+}
+
+type SignedStatusNotificationResponse struct {
+}
+
+func (r SignedStatusNotificationRequest) GetFeatureName() string {
+	return SignedStatusNotificationFeatureName
+}
+
+// Validate checks that the status is a known value.
+func (r SignedStatusNotificationRequest) Validate() error {
+	if !r.Status.IsValid() {
+		return fmt.Errorf("invalid status: %q", r.Status)
+	}
+	return nil
+}
+
+func (c SignedStatusNotificationResponse) GetFeatureName() string {
+	return SignedStatusNotificationFeatureName
+}
+
+func NewSignedStatusNotificationResponse() *SignedStatusNotificationResponse {
+	return &SignedStatusNotificationResponse{}
+}
@@ -0,0 +1,52 @@
+package firmware
+
+import "evsys/types"
+
+const SignedUpdateFirmwareFeatureName = "SignedUpdateFirmware"
+
+type UpdateFirmwareStatus string
+
+const (
+	UpdateFirmwareStatusAccepted           UpdateFirmwareStatus = "Accepted"
+	UpdateFirmwareStatusRejected           UpdateFirmwareStatus = "Rejected"
+	UpdateFirmwareStatusAcceptedCanceled   UpdateFirmwareStatus = "AcceptedCanceled"
+	UpdateFirmwareStatusInvalidCertificate UpdateFirmwareStatus = "InvalidCertificate"
+	UpdateFirmwareStatusRevokedCertificate UpdateFirmwareStatus = "RevokedCertificate"
+)
+
+// Firmware describes a signed firmware image; the charge point checks the signature against the
+// signing certificate, and the certificate against its manufacturer root certificates.
+type Firmware struct {
+	Location           string          `json:"location" validate:"required,max=512"`
+	RetrieveDateTime   *types.DateTime `json:"retrieveDateTime" validate:"required"`
+	InstallDateTime    *types.DateTime `json:"installDateTime,omitempty"`
+	SigningCertificate string          `json:"signingCertificate" validate:"required,max=5500"`
+	Signature          string          `json:"signature" validate:"required,max=800"`
+}
+
+type SignedUpdateFirmwareRequest struct {
+	Retries       *int     `json:"retries,omitempty" validate:"omitempty,gte=0"`
+	RetryInterval *int     `json:"retryInterval,omitempty" validate:"omitempty,gte=0"`
+	RequestId     int      `json:"requestId"`
+	Firmware      Firmware `json:"firmware" validate:"required"`
+}
+
+type SignedUpdateFirmwareResponse struct {
+	Status UpdateFirmwareStatus `json:"status" validate:"required,updateFirmwareStatus"`
+}
+
+func (r SignedUpdateFirmwareRequest) GetFeatureName() string {
+	return SignedUpdateFirmwareFeatureName
+}
+
+func (c SignedUpdateFirmwareResponse) GetFeatureName() string {
+	return SignedUpdateFirmwareFeatureName
+}
+
+func NewSignedUpdateFirmwareRequest(requestId int, firmware Firmware) *SignedUpdateFirmwareRequest {
+	return &SignedUpdateFirmwareRequest{RequestId: requestId, Firmware: firmware}
+}
+
+func NewSignedUpdateFirmwareResponse(status UpdateFirmwareStatus) *SignedUpdateFirmwareResponse {
+	return &SignedUpdateFirmwareResponse{Status: status}
+}
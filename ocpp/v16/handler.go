@@ -1,130 +1,383 @@
 package v16
 
 import (
+	"encoding/json"
+	"evsys/ocpp"
 	"evsys/ocpp/common"
 	"evsys/ocpp/v16/core"
 	"evsys/ocpp/v16/firmware"
 	"evsys/ocpp/v16/localauth"
 	"evsys/ocpp/v16/remotetrigger"
-	// "evsys/ocpp/v16/smartcharging" // TODO: uncomment when response types are added
+	"evsys/ocpp/v16/reservation"
+	"evsys/ocpp/v16/security"
+	"evsys/ocpp/v16/smartcharging"
 	"fmt"
 	"reflect"
 )
 
-// Handler16 implements the MessageHandler interface for OCPP 1.6J protocol
+// Handler16 implements the MessageHandler interface for OCPP 1.6J protocol.
+// Every feature is registered once, with its types and with whatever the handler does with it:
+// answering a request from a charge point, building a request from an API command, or recording
+// the charge point's answer to such a request.
 type Handler16 struct {
 	featureRegistry common.FeatureRegistry
+	features        map[string]*feature
+}
+
+// Handler16Config holds the handlers a Handler16 dispatches to; features of a missing handler are
+// registered, but refused.
+type Handler16Config struct {
+	CoreHandler             core.SystemHandler
+	CoreCmdHandler          core.CommandHandler
+	FirmwareHandler         firmware.SystemHandler
+	FirmwareCmdHandler      firmware.CommandHandler
+	SmartChargingCmdHandler smartcharging.CommandHandler
+	LocalAuthHandler        localauth.SystemHandler
+	RemoteTriggerHandler    remotetrigger.SystemHandler
+	ReservationHandler      reservation.SystemHandler
+	SecurityHandler         security.SystemHandler
+	SecurityCmdHandler      security.CommandHandler
+}
+
+// Command is an API command addressed to a charge point.
+type Command struct {
+	ChargePointId string
+	ConnectorId   int
+	Payload       string
+}
+
+// feature is one registered action
+type feature struct {
+	requestType  reflect.Type
+	responseType reflect.Type
+	// handle answers a request sent by a charge point
+	handle func(chargePointId string, request ocpp.Request) (ocpp.Response, error)
+	// build creates a request to a charge point from an API command
+	build func(command Command) (ocpp.Request, error)
+	// answered receives the charge point's response to a built request
+	answered func(chargePointId string, request ocpp.Request, response ocpp.Response)
 }
 
 // NewHandler16 creates a new OCPP 1.6J message handler
-func NewHandler16() *Handler16 {
+func NewHandler16(config Handler16Config) *Handler16 {
 	h := &Handler16{
 		featureRegistry: common.GetGlobalRegistry(),
+		features:        make(map[string]*feature),
 	}
-	h.registerFeatures()
+	h.registerFeatures(config)
 	return h
 }
 
 // registerFeatures registers all OCPP 1.6J features with the global registry
-func (h *Handler16) registerFeatures() {
-	version := common.OCPP16
+func (h *Handler16) registerFeatures(c Handler16Config) {
+	// Core Profile (Charge Point → Central System)
+	incoming(h, core.BootNotificationFeatureName, c.CoreHandler, core.SystemHandler.OnBootNotification)
+	incoming(h, core.AuthorizeFeatureName, c.CoreHandler, core.SystemHandler.OnAuthorize)
+	incoming(h, core.HeartbeatFeatureName, c.CoreHandler, core.SystemHandler.OnHeartbeat)
+	incoming(h, core.StartTransactionFeatureName, c.CoreHandler, core.SystemHandler.OnStartTransaction)
+	incoming(h, core.StopTransactionFeatureName, c.CoreHandler, core.SystemHandler.OnStopTransaction)
+	incoming(h, core.MeterValuesFeatureName, c.CoreHandler, core.SystemHandler.OnMeterValues)
+	incoming(h, core.StatusNotificationFeatureName, c.CoreHandler, core.SystemHandler.OnStatusNotification)
+	incoming(h, core.DataTransferFeatureName, c.CoreHandler, core.SystemHandler.OnDataTransfer)
 
-	// Core Profile
-	common.RegisterFeature(version, core.BootNotificationFeatureName,
-		reflect.TypeOf(core.BootNotificationRequest{}),
-		reflect.TypeOf(core.BootNotificationResponse{}))
+	// Core Profile (Central System → Charge Point); DataTransfer goes both ways
+	outgoing[core.DataTransferResponse](h, core.DataTransferFeatureName,
+		payloadBuilder(c.CoreCmdHandler, core.CommandHandler.OnSendDataTransfer), nil)
+	outgoing[core.RemoteStartTransactionResponse](h, core.RemoteStartTransactionFeatureName,
+		connectorPayloadBuilder(c.CoreCmdHandler, core.CommandHandler.OnRemoteStartTransaction), nil)
+	outgoing[core.RemoteStopTransactionResponse](h, core.RemoteStopTransactionFeatureName,
+		payloadBuilder(c.CoreCmdHandler, core.CommandHandler.OnRemoteStopTransaction), nil)
+	outgoing[core.GetConfigurationResponse](h, core.GetConfigurationFeatureName,
+		payloadBuilder(c.CoreCmdHandler, core.CommandHandler.OnGetConfiguration), nil)
+	outgoing[core.ChangeConfigurationResponse](h, core.ChangeConfigurationFeatureName,
+		payloadBuilder(c.CoreCmdHandler, core.CommandHandler.OnChangeConfiguration), nil)
+	outgoing[core.ResetResponse](h, core.ResetFeatureName,
+		payloadBuilder(c.CoreCmdHandler, core.CommandHandler.OnReset), nil)
+	outgoing[core.ChangeAvailabilityResponse](h, core.ChangeAvailabilityFeatureName,
+		connectorPayloadBuilder(c.CoreCmdHandler, core.CommandHandler.OnChangeAvailability),
+		responseHandler(c.CoreCmdHandler, core.CommandHandler.OnChangeAvailabilityResponse))
+	outgoing[core.UnlockConnectorResponse](h, core.UnlockConnectorFeatureName,
+		connectorBuilder(c.CoreCmdHandler, core.CommandHandler.OnUnlockConnector), nil)
 
-	common.RegisterFeature(version, core.AuthorizeFeatureName,
-		reflect.TypeOf(core.AuthorizeRequest{}),
-		reflect.TypeOf(core.AuthorizeResponse{}))
+	// Firmware Management Profile
+	incoming(h, firmware.DiagnosticsStatusNotificationFeatureName, c.FirmwareHandler, firmware.SystemHandler.OnDiagnosticsStatusNotification)
+	incoming(h, firmware.StatusNotificationFeatureName, c.FirmwareHandler, firmware.SystemHandler.OnFirmwareStatusNotification)
+	outgoing[firmware.GetDiagnosticsResponse](h, firmware.GetDiagnosticsFeatureName,
+		payloadBuilder(c.FirmwareCmdHandler, firmware.CommandHandler.OnGetDiagnostics), nil)
+	outgoing[firmware.UpdateFirmwareResponse](h, firmware.UpdateFirmwareFeatureName,
+		payloadBuilder(c.FirmwareCmdHandler, firmware.CommandHandler.OnUpdateFirmware), nil)
+	incoming(h, firmware.SignedStatusNotificationFeatureName, c.FirmwareHandler, firmware.SystemHandler.OnSignedFirmwareStatusNotification)
+	outgoing[firmware.SignedUpdateFirmwareResponse](h, firmware.SignedUpdateFirmwareFeatureName,
+		payloadBuilder(c.FirmwareCmdHandler, firmware.CommandHandler.OnSignedUpdateFirmware),
+		responseHandler(c.FirmwareCmdHandler, firmware.CommandHandler.OnSignedUpdateFirmwareResponse))
 
-	common.RegisterFeature(version, core.HeartbeatFeatureName,
-		reflect.TypeOf(core.HeartbeatRequest{}),
-		reflect.TypeOf(core.HeartbeatResponse{}))
+	// Smart Charging Profile
+	outgoing[smartcharging.SetChargingProfileResponse](h, smartcharging.SetChargingProfileFeatureName,
+		connectorPayloadBuilder(c.SmartChargingCmdHandler, smartcharging.CommandHandler.OnSetChargingProfile), nil)
+	outgoing[smartcharging.GetCompositeScheduleResponse](h, smartcharging.GetCompositeScheduleFeatureName,
+		connectorPayloadBuilder(c.SmartChargingCmdHandler, smartcharging.CommandHandler.OnGetCompositeSchedule), nil)
+	outgoing[smartcharging.ClearChargingProfileResponse](h, smartcharging.ClearChargingProfileFeatureName,
+		payloadBuilder(c.SmartChargingCmdHandler, smartcharging.CommandHandler.OnClearChargingProfile), nil)
 
-	common.RegisterFeature(version, core.StartTransactionFeatureName,
-		reflect.TypeOf(core.StartTransactionRequest{}),
-		reflect.TypeOf(core.StartTransactionResponse{}))
+	// Local Auth List Management Profile; a SendLocalList is only ever sent as part of a
+	// localauth.SystemHandler.SyncLocalList, never built from a single command
+	outgoing[localauth.SendLocalListResponse, localauth.SendLocalListRequest](h, localauth.SendLocalListFeatureName, nil, nil)
+	outgoing[localauth.GetLocalListVersionResponse](h, localauth.GetLocalListVersionFeatureName,
+		chargePointBuilder(c.LocalAuthHandler, localauth.SystemHandler.OnGetLocalListVersion), nil)
 
-	common.RegisterFeature(version, core.StopTransactionFeatureName,
-		reflect.TypeOf(core.StopTransactionRequest{}),
-		reflect.TypeOf(core.StopTransactionResponse{}))
+	// Remote Trigger Profile
+	outgoing[remotetrigger.TriggerMessageResponse](h, remotetrigger.TriggerMessageFeatureName,
+		connectorPayloadBuilder(c.RemoteTriggerHandler, remotetrigger.SystemHandler.OnTriggerMessage), nil)
 
-	common.RegisterFeature(version, core.MeterValuesFeatureName,
-		reflect.TypeOf(core.MeterValuesRequest{}),
-		reflect.TypeOf(core.MeterValuesResponse{}))
+	// Reservation Profile
+	outgoing[reservation.ReserveNowResponse](h, reservation.ReserveNowFeatureName,
+		connectorPayloadBuilder(c.ReservationHandler, reservation.SystemHandler.OnReserveNow),
+		responseHandler(c.ReservationHandler, reservation.SystemHandler.OnReserveNowResponse))
+	outgoing[reservation.CancelReservationResponse](h, reservation.CancelReservationFeatureName,
+		payloadBuilder(c.ReservationHandler, reservation.SystemHandler.OnCancelReservation),
+		responseHandler(c.ReservationHandler, reservation.SystemHandler.OnCancelReservationResponse))
 
-	common.RegisterFeature(version, core.StatusNotificationFeatureName,
-		reflect.TypeOf(core.StatusNotificationRequest{}),
-		reflect.TypeOf(core.StatusNotificationResponse{}))
+	// Security Whitepaper; a CertificateSigned only ever answers a SignCertificate
+	incoming(h, security.SecurityEventNotificationFeatureName, c.SecurityHandler, security.SystemHandler.OnSecurityEventNotification)
+	incoming(h, security.SignCertificateFeatureName, c.SecurityHandler, security.SystemHandler.OnSignCertificate)
+	incoming(h, security.LogStatusNotificationFeatureName, c.SecurityHandler, security.SystemHandler.OnLogStatusNotification)
+	outgoing[security.CertificateSignedResponse, security.CertificateSignedRequest](h, security.CertificateSignedFeatureName, nil, nil)
+	outgoing[security.InstallCertificateResponse](h, security.InstallCertificateFeatureName,
+		payloadBuilder(c.SecurityCmdHandler, security.CommandHandler.OnInstallCertificate), nil)
+	outgoing[security.GetInstalledCertificateIdsResponse](h, security.GetInstalledCertificateIdsFeatureName,
+		payloadBuilder(c.SecurityCmdHandler, security.CommandHandler.OnGetInstalledCertificateIds), nil)
+	outgoing[security.DeleteCertificateResponse](h, security.DeleteCertificateFeatureName,
+		payloadBuilder(c.SecurityCmdHandler, security.CommandHandler.OnDeleteCertificate), nil)
+	outgoing[security.GetLogResponse](h, security.GetLogFeatureName,
+		payloadBuilder(c.SecurityCmdHandler, security.CommandHandler.OnGetLog),
+		responseHandler(c.SecurityCmdHandler, security.CommandHandler.OnGetLogResponse))
+}
 
-	common.RegisterFeature(version, core.DataTransferFeatureName,
-		reflect.TypeOf(core.DataTransferRequest{}),
-		reflect.TypeOf(core.DataTransferResponse{}))
+// register records the types of an action, in the global registry and for the handler's own dispatch
+func (h *Handler16) register(action string, requestType, responseType reflect.Type) *feature {
+	common.RegisterFeature(common.OCPP16, action, requestType, responseType)
+	f, ok := h.features[action]
+	if !ok {
+		f = &feature{}
+		h.features[action] = f
+	}
+	f.requestType = requestType
+	f.responseType = responseType
+	return f
+}
 
-	common.RegisterFeature(version, core.RemoteStartTransactionFeatureName,
-		reflect.TypeOf(core.RemoteStartTransactionRequest{}),
-		reflect.TypeOf(core.RemoteStartTransactionResponse{}))
+// incoming registers a request sent by charge points, answered by a method of handler
+func incoming[H any, Req any, Resp any](h *Handler16, action string, handler H, method func(H, string, *Req) (*Resp, error)) {
+	f := h.register(action, typeOf[Req](), typeOf[Resp]())
+	if any(handler) == nil {
+		return
+	}
+	f.handle = func(chargePointId string, request ocpp.Request) (ocpp.Response, error) {
+		req, ok := any(request).(*Req)
+		if !ok {
+			return nil, fmt.Errorf("unexpected %s request type %T", action, request)
+		}
+		response, err := method(handler, chargePointId, req)
+		if err != nil {
+			return nil, err
+		}
+		if response == nil {
+			return nil, fmt.Errorf("no %s response", action)
+		}
+		confirmation, ok := any(response).(ocpp.Response)
+		if !ok {
+			return nil, fmt.Errorf("%s response type %T is not a response", action, response)
+		}
+		return confirmation, nil
+	}
+}
 
-	common.RegisterFeature(version, core.RemoteStopTransactionFeatureName,
-		reflect.TypeOf(core.RemoteStopTransactionRequest{}),
-		reflect.TypeOf(core.RemoteStopTransactionResponse{}))
+// outgoing registers a request the central system sends; build creates it from an API command and
+// answered, when set, gets the charge point's response
+func outgoing[Resp any, Req any](h *Handler16, action string, build func(Command) (*Req, error), answered func(string, *Req, *Resp)) {
+	f := h.register(action, typeOf[Req](), typeOf[Resp]())
+	if build != nil {
+		f.build = func(command Command) (ocpp.Request, error) {
+			request, err := build(command)
+			if err != nil {
+				return nil, err
+			}
+			if request == nil {
+				return nil, fmt.Errorf("no %s request", action)
+			}
+			built, ok := any(request).(ocpp.Request)
+			if !ok {
+				return nil, fmt.Errorf("%s request type %T is not a request", action, request)
+			}
+			return built, nil
+		}
+	}
+	if answered != nil {
+		f.answered = func(chargePointId string, request ocpp.Request, response ocpp.Response) {
+			req, ok := any(request).(*Req)
+			if !ok {
+				return
+			}
+			if resp, ok := any(response).(*Resp); ok {
+				answered(chargePointId, req, resp)
+			}
+		}
+	}
+}
 
-	common.RegisterFeature(version, core.GetConfigurationFeatureName,
-		reflect.TypeOf(core.GetConfigurationRequest{}),
-		reflect.TypeOf(core.GetConfigurationResponse{}))
+// Builders adapt the API command handlers, which take only the command fields they need.
 
-	common.RegisterFeature(version, core.ChangeConfigurationFeatureName,
-		reflect.TypeOf(core.ChangeConfigurationRequest{}),
-		reflect.TypeOf(core.ChangeConfigurationResponse{}))
+func payloadBuilder[H any, Req any](handler H, method func(H, string, string) (*Req, error)) func(Command) (*Req, error) {
+	if any(handler) == nil {
+		return nil
+	}
+	return func(command Command) (*Req, error) {
+		return method(handler, command.ChargePointId, command.Payload)
+	}
+}
 
-	// Note: Reset feature has request but no response type defined in current code
-	// common.RegisterFeature(version, core.ResetFeatureName, ...)
+func connectorPayloadBuilder[H any, Req any](handler H, method func(H, string, int, string) (*Req, error)) func(Command) (*Req, error) {
+	if any(handler) == nil {
+		return nil
+	}
+	return func(command Command) (*Req, error) {
+		return method(handler, command.ChargePointId, command.ConnectorId, command.Payload)
+	}
+}
 
-	// Firmware Management Profile
-	common.RegisterFeature(version, firmware.DiagnosticsStatusNotificationFeatureName,
-		reflect.TypeOf(firmware.DiagnosticsStatusNotificationRequest{}),
-		reflect.TypeOf(firmware.DiagnosticsStatusNotificationResponse{}))
+func connectorBuilder[H any, Req any](handler H, method func(H, string, int) (*Req, error)) func(Command) (*Req, error) {
+	if any(handler) == nil {
+		return nil
+	}
+	return func(command Command) (*Req, error) {
+		return method(handler, command.ChargePointId, command.ConnectorId)
+	}
+}
 
-	common.RegisterFeature(version, firmware.StatusNotificationFeatureName,
-		reflect.TypeOf(firmware.StatusNotificationRequest{}),
-		reflect.TypeOf(firmware.StatusNotificationResponse{}))
+func chargePointBuilder[H any, Req any](handler H, method func(H, string) (*Req, error)) func(Command) (*Req, error) {
+	if any(handler) == nil {
+		return nil
+	}
+	return func(command Command) (*Req, error) {
+		return method(handler, command.ChargePointId)
+	}
+}
 
-	// Note: GetDiagnostics has request but no response type defined
-	// common.RegisterFeature(version, firmware.GetDiagnosticsFeatureName, ...)
+func responseHandler[H any, Req any, Resp any](handler H, method func(H, string, *Req, *Resp)) func(string, *Req, *Resp) {
+	if any(handler) == nil {
+		return nil
+	}
+	return func(chargePointId string, request *Req, response *Resp) {
+		method(handler, chargePointId, request, response)
+	}
+}
 
-	// Smart Charging Profile
-	// Note: Smart charging features have requests but no response types defined in current code
-	// These will be registered when response types are added:
-	// common.RegisterFeature(version, smartcharging.SetChargingProfileFeatureName, ...)
-	// common.RegisterFeature(version, smartcharging.GetCompositeScheduleFeatureName, ...)
-	// common.RegisterFeature(version, smartcharging.ClearChargingProfileFeatureName, ...)
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// Handle passes a request from a charge point to the handler registered for its action
+func (h *Handler16) Handle(chargePointId string, request ocpp.Request) (ocpp.Response, error) {
+	action := request.GetFeatureName()
+	f, ok := h.features[action]
+	if !ok {
+		return nil, ocpp.NewCallError(ocpp.NotSupported, "feature not supported for OCPP 1.6: %s", action)
+	}
+	if f.handle == nil {
+		return nil, ocpp.NewCallError(ocpp.NotSupported, "no handler configured for action: %s", action)
+	}
+	return f.handle(chargePointId, request)
+}
 
-	// Local Auth List Management Profile
-	common.RegisterFeature(version, localauth.SendLocalListFeatureName,
-		reflect.TypeOf(localauth.SendLocalListRequest{}),
-		reflect.TypeOf(localauth.SendLocalListResponse{}))
+// BuildRequest creates the request an API command asks to send to a charge point
+func (h *Handler16) BuildRequest(action string, command Command) (ocpp.Request, error) {
+	f, ok := h.features[action]
+	if !ok || f.build == nil {
+		return nil, fmt.Errorf("feature not supported for OCPP 1.6: %s", action)
+	}
+	return f.build(command)
+}
 
-	// Remote Trigger Profile
-	common.RegisterFeature(version, remotetrigger.TriggerMessageFeatureName,
-		reflect.TypeOf(remotetrigger.TriggerMessageRequest{}),
-		reflect.TypeOf(remotetrigger.TriggerMessageResponse{}))
+// HandleResponse passes the charge point's answer to a built request on to the handler recording
+// its outcome; responses nobody records are only checked to be known.
+func (h *Handler16) HandleResponse(chargePointId string, request ocpp.Request, payload []byte) error {
+	action := request.GetFeatureName()
+	f, ok := h.features[action]
+	if !ok {
+		return fmt.Errorf("feature not supported for OCPP 1.6: %s", action)
+	}
+	if f.answered == nil {
+		return nil
+	}
+	response, ok := reflect.New(f.responseType).Interface().(ocpp.Response)
+	if !ok {
+		return fmt.Errorf("%s response type is not a response", action)
+	}
+	if err := json.Unmarshal(payload, response); err != nil {
+		return fmt.Errorf("invalid %s response: %w", action, err)
+	}
+	f.answered(chargePointId, request, response)
+	return nil
 }
 
-// HandleRequest processes incoming requests from charge points (not fully implemented - placeholder)
-// This will be implemented in a later phase when we have the full handler infrastructure
+// HandleRequest processes incoming requests from charge points
+// This implements the common.MessageHandler interface
 func (h *Handler16) HandleRequest(ws common.VersionedWebSocket, action string, payload []byte) (common.Response, error) {
-	// This is a placeholder implementation
-	// The actual implementation will delegate to the SystemHandler in server package
-	return nil, fmt.Errorf("HandleRequest not yet implemented for OCPP 1.6J handler")
+	f, ok := h.features[action]
+	if !ok {
+		return nil, fmt.Errorf("unsupported action: %s", action)
+	}
+
+	request := reflect.New(f.requestType).Interface()
+	if err := json.Unmarshal(payload, request); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal request for %s: %w", action, err)
+	}
+	if validator, ok := request.(interface{ Validate() error }); ok {
+		if err := validator.Validate(); err != nil {
+			return nil, fmt.Errorf("validation failed for %s: %w", action, err)
+		}
+	}
+
+	req, ok := request.(ocpp.Request)
+	if !ok {
+		return nil, fmt.Errorf("%s request type is not a request", action)
+	}
+	response, err := h.Handle(ws.ID(), req)
+	if err != nil {
+		return nil, err
+	}
+	return Response{Response: response}, nil
 }
 
-// CreateRequest creates outgoing requests to charge points (not fully implemented - placeholder)
-// This will be implemented in a later phase when we have the full handler infrastructure
+// CreateRequest creates outgoing requests to charge points, either from an API Command or from a
+// request already built
+// This implements the common.MessageHandler interface
 func (h *Handler16) CreateRequest(action string, payload interface{}) (common.Request, error) {
-	// This is a placeholder implementation
-	// The actual implementation will create proper request objects
-	return nil, fmt.Errorf("CreateRequest not yet implemented for OCPP 1.6J handler")
+	var request ocpp.Request
+	switch p := payload.(type) {
+	case Command:
+		built, err := h.BuildRequest(action, p)
+		if err != nil {
+			return nil, err
+		}
+		request = built
+	case ocpp.Request:
+		if !h.featureRegistry.IsSupported(common.OCPP16, action) {
+			return nil, fmt.Errorf("unsupported action: %s", action)
+		}
+		if p.GetFeatureName() != action {
+			return nil, fmt.Errorf("%s request given for action %s", p.GetFeatureName(), action)
+		}
+		request = p
+	default:
+		return nil, fmt.Errorf("payload is neither a command nor a request")
+	}
+
+	wrapped := Request{Request: request}
+	if err := wrapped.Validate(); err != nil {
+		return nil, fmt.Errorf("validation failed for %s: %w", action, err)
+	}
+	return wrapped, nil
 }
 
 // GetVersion returns the protocol version this handler supports
@@ -141,3 +394,39 @@ func (h *Handler16) SupportsFeature(action string) bool {
 func (h *Handler16) GetSupportedFeatures() []string {
 	return h.featureRegistry.GetFeatures(common.OCPP16)
 }
+
+// Request carries a 1.6 request through the version-agnostic interfaces; it marshals as the
+// request itself
+type Request struct {
+	ocpp.Request
+}
+
+func (r Request) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP16
+}
+
+// Validate runs the request's own validation, for the types that have one
+func (r Request) Validate() error {
+	if validator, ok := r.Request.(interface{ Validate() error }); ok {
+		return validator.Validate()
+	}
+	return nil
+}
+
+func (r Request) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.Request)
+}
+
+// Response carries a 1.6 response through the version-agnostic interfaces; it marshals as the
+// response itself
+type Response struct {
+	ocpp.Response
+}
+
+func (r Response) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP16
+}
+
+func (r Response) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.Response)
+}
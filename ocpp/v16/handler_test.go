@@ -0,0 +1,124 @@
+package v16
+
+import (
+	"evsys/ocpp"
+	"evsys/ocpp/common"
+	"evsys/ocpp/v16/core"
+	"evsys/types"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// stubCore answers Heartbeat only; the other methods are left to the embedded nil interface.
+type stubCore struct {
+	core.SystemHandler
+	heartbeats []string
+}
+
+func (s *stubCore) OnHeartbeat(chargePointId string, _ *core.HeartbeatRequest) (*core.HeartbeatResponse, error) {
+	s.heartbeats = append(s.heartbeats, chargePointId)
+	return core.NewHeartbeatResponse(types.NewDateTime(time.Now())), nil
+}
+
+// stubCommands builds ChangeAvailability and records the answers to it.
+type stubCommands struct {
+	core.CommandHandler
+	answered []core.AvailabilityStatus
+}
+
+func (s *stubCommands) OnChangeAvailability(_ string, connectorId int, payload string) (*core.ChangeAvailabilityRequest, error) {
+	return core.NewChangeAvailabilityRequest(connectorId, core.AvailabilityType(payload)), nil
+}
+
+func (s *stubCommands) OnChangeAvailabilityResponse(_ string, _ *core.ChangeAvailabilityRequest, response *core.ChangeAvailabilityResponse) {
+	s.answered = append(s.answered, response.Status)
+}
+
+type stubSocket struct {
+	common.VersionedWebSocket
+	id string
+}
+
+func (s stubSocket) ID() string           { return s.id }
+func (s stubSocket) RemoteAddr() net.Addr { return nil }
+
+func TestEveryFeatureHasBothTypes(t *testing.T) {
+	h := NewHandler16(Handler16Config{})
+	requestType := reflect.TypeOf((*ocpp.Request)(nil)).Elem()
+	responseType := reflect.TypeOf((*ocpp.Response)(nil)).Elem()
+	for action, f := range h.features {
+		if !reflect.PointerTo(f.requestType).Implements(requestType) {
+			t.Errorf("%s: %s is not a request", action, f.requestType)
+		}
+		if !reflect.PointerTo(f.responseType).Implements(responseType) {
+			t.Errorf("%s: %s is not a response", action, f.responseType)
+		}
+		if !h.SupportsFeature(action) {
+			t.Errorf("%s is missing from the registry", action)
+		}
+	}
+	for _, action := range []string{core.ResetFeatureName, "GetDiagnostics", "SetChargingProfile", "GetCompositeSchedule", "ClearChargingProfile"} {
+		if _, ok := h.features[action]; !ok {
+			t.Errorf("%s is not registered", action)
+		}
+	}
+}
+
+func TestHandleRequestDispatchesToHandler(t *testing.T) {
+	handler := &stubCore{}
+	h := NewHandler16(Handler16Config{CoreHandler: handler})
+
+	response, err := h.HandleRequest(stubSocket{id: "CP1"}, core.HeartbeatFeatureName, []byte(`{}`))
+	if err != nil {
+		t.Fatalf("HandleRequest: %v", err)
+	}
+	if response.GetFeatureName() != core.HeartbeatFeatureName || response.GetProtocolVersion() != common.OCPP16 {
+		t.Errorf("response = %s %s, want a 1.6 Heartbeat", response.GetProtocolVersion(), response.GetFeatureName())
+	}
+	if len(handler.heartbeats) != 1 || handler.heartbeats[0] != "CP1" {
+		t.Errorf("heartbeats = %v, want one from CP1", handler.heartbeats)
+	}
+
+	if _, err = h.HandleRequest(stubSocket{id: "CP1"}, "NoSuchAction", []byte(`{}`)); err == nil {
+		t.Error("an unknown action was handled")
+	}
+}
+
+func TestMissingHandlerIsRefused(t *testing.T) {
+	h := NewHandler16(Handler16Config{})
+	if _, err := h.Handle("CP1", &core.HeartbeatRequest{}); err == nil {
+		t.Error("Heartbeat handled without a core handler")
+	}
+	if _, err := h.BuildRequest(core.ResetFeatureName, Command{ChargePointId: "CP1", Payload: "Soft"}); err == nil {
+		t.Error("Reset built without a command handler")
+	}
+}
+
+func TestCommandRequestAndResponse(t *testing.T) {
+	commands := &stubCommands{}
+	h := NewHandler16(Handler16Config{CoreCmdHandler: commands})
+
+	request, err := h.CreateRequest(core.ChangeAvailabilityFeatureName,
+		Command{ChargePointId: "CP1", ConnectorId: 2, Payload: string(core.AvailabilityTypeInoperative)})
+	if err != nil {
+		t.Fatalf("CreateRequest: %v", err)
+	}
+	built, ok := request.(Request).Request.(*core.ChangeAvailabilityRequest)
+	if !ok || built.ConnectorId != 2 || built.Type != core.AvailabilityTypeInoperative {
+		t.Fatalf("built %+v, want connector 2 made inoperative", request)
+	}
+
+	if err = h.HandleResponse("CP1", built, []byte(`{"status":"Scheduled"}`)); err != nil {
+		t.Fatalf("HandleResponse: %v", err)
+	}
+	if len(commands.answered) != 1 || commands.answered[0] != core.AvailabilityStatusScheduled {
+		t.Errorf("answers = %v, want Scheduled", commands.answered)
+	}
+
+	// responses nobody records are accepted as they are
+	if err = h.HandleResponse("CP1", core.NewResetRequest(core.ResetTypeSoft), []byte(`{"status":"Accepted"}`)); err != nil {
+		t.Errorf("HandleResponse for Reset: %v", err)
+	}
+}
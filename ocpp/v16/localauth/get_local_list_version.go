@@ -0,0 +1,32 @@
+package localauth
+
+const GetLocalListVersionFeatureName = "GetLocalListVersion"
+
+// Special list versions a charge point reports
+const (
+	ListVersionEmpty    = 0  // no list installed
+	ListVersionDisabled = -1 // local authorization list is disabled
+)
+
+type GetLocalListVersionRequest struct {
+}
+
+type GetLocalListVersionResponse struct {
+	ListVersion int `json:"listVersion" validate:"gte=-1"`
+}
+
+func (r GetLocalListVersionRequest) GetFeatureName() string {
+	return GetLocalListVersionFeatureName
+}
+
+func (c GetLocalListVersionResponse) GetFeatureName() string {
+	return GetLocalListVersionFeatureName
+}
+
+func NewGetLocalListVersionRequest() *GetLocalListVersionRequest {
+	return &GetLocalListVersionRequest{}
+}
+
+func NewGetLocalListVersionResponse(version int) *GetLocalListVersionResponse {
+	return &GetLocalListVersionResponse{ListVersion: version}
+}
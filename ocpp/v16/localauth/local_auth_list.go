@@ -1,5 +1,17 @@
 package localauth
 
 type SystemHandler interface {
-	OnSendLocalList(chargePointId string) (*SendLocalListRequest, error)
+	// SyncLocalList brings the charge point's local authorization list in line with the stored
+	// user tags, over as many SendLocalList messages as it takes; full forces a Full update.
+	SyncLocalList(chargePointId string, full bool) (*SyncResult, error)
+	OnGetLocalListVersion(chargePointId string) (*GetLocalListVersionRequest, error)
+}
+
+// SyncResult sums up a local list synchronization.
+type SyncResult struct {
+	ListVersion int          `json:"listVersion"`
+	UpdateType  UpdateType   `json:"updateType,omitempty"`
+	Entries     int          `json:"entries"`  // entries sent, deletions included
+	Messages    int          `json:"messages"` // SendLocalList messages the charge point accepted
+	Status      UpdateStatus `json:"status"`
 }
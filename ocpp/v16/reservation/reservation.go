@@ -0,0 +1,9 @@
+package reservation
+
+type SystemHandler interface {
+	OnReserveNow(chargePointId string, connectorId int, payload string) (*ReserveNowRequest, error)
+	OnCancelReservation(chargePointId string, payload string) (*CancelReservationRequest, error)
+	// response handlers are called with the charge point's answer to a request built above
+	OnReserveNowResponse(chargePointId string, request *ReserveNowRequest, response *ReserveNowResponse)
+	OnCancelReservationResponse(chargePointId string, request *CancelReservationRequest, response *CancelReservationResponse)
+}
@@ -0,0 +1,52 @@
+package reservation
+
+import "evsys/types"
+
+const ReserveNowFeatureName = "ReserveNow"
+
+type Status string
+
+const (
+	StatusAccepted    Status = "Accepted"
+	StatusFaulted     Status = "Faulted"
+	StatusOccupied    Status = "Occupied"
+	StatusRejected    Status = "Rejected"
+	StatusUnavailable Status = "Unavailable"
+)
+
+func (s Status) IsValid() bool {
+	switch s {
+	case StatusAccepted, StatusFaulted, StatusOccupied, StatusRejected, StatusUnavailable:
+		return true
+	}
+	return false
+}
+
+type ReserveNowRequest struct {
+	ConnectorId   int             `json:"connectorId" validate:"gte=0"`
+	ExpiryDate    *types.DateTime `json:"expiryDate" validate:"required"`
+	IdTag         string          `json:"idTag" validate:"required,max=20"`
+	ParentIdTag   string          `json:"parentIdTag,omitempty" validate:"omitempty,max=20"`
+	ReservationId int             `json:"reservationId"`
+}
+
+type ReserveNowResponse struct {
+	Status Status `json:"status" validate:"required,reservationStatus"`
+}
+
+func (r ReserveNowRequest) GetFeatureName() string {
+	return ReserveNowFeatureName
+}
+
+func (c ReserveNowResponse) GetFeatureName() string {
+	return ReserveNowFeatureName
+}
+
+// NewReserveNowRequest creates a ReserveNowRequest containing all required fields. ParentIdTag is optional and may be set afterward.
+func NewReserveNowRequest(connectorId int, expiryDate *types.DateTime, idTag string, reservationId int) *ReserveNowRequest {
+	return &ReserveNowRequest{ConnectorId: connectorId, ExpiryDate: expiryDate, IdTag: idTag, ReservationId: reservationId}
+}
+
+func NewReserveNowResponse(status Status) *ReserveNowResponse {
+	return &ReserveNowResponse{Status: status}
+}
@@ -0,0 +1,36 @@
+package security
+
+const CertificateSignedFeatureName = "CertificateSigned"
+
+type CertificateSignedStatus string
+
+const (
+	CertificateSignedStatusAccepted CertificateSignedStatus = "Accepted"
+	CertificateSignedStatusRejected CertificateSignedStatus = "Rejected"
+)
+
+// CertificateSignedRequest delivers the signed certificate, followed by any intermediate CA
+// certificates, PEM encoded.
+type CertificateSignedRequest struct {
+	CertificateChain string `json:"certificateChain" validate:"required,max=10000"`
+}
+
+type CertificateSignedResponse struct {
+	Status CertificateSignedStatus `json:"status" validate:"required,certificateSignedStatus"`
+}
+
+func (r CertificateSignedRequest) GetFeatureName() string {
+	return CertificateSignedFeatureName
+}
+
+func (c CertificateSignedResponse) GetFeatureName() string {
+	return CertificateSignedFeatureName
+}
+
+func NewCertificateSignedRequest(certificateChain string) *CertificateSignedRequest {
+	return &CertificateSignedRequest{CertificateChain: certificateChain}
+}
+
+func NewCertificateSignedResponse(status CertificateSignedStatus) *CertificateSignedResponse {
+	return &CertificateSignedResponse{Status: status}
+}
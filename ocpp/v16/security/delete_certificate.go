@@ -0,0 +1,35 @@
+package security
+
+const DeleteCertificateFeatureName = "DeleteCertificate"
+
+type DeleteCertificateStatus string
+
+const (
+	DeleteCertificateStatusAccepted DeleteCertificateStatus = "Accepted"
+	DeleteCertificateStatusFailed   DeleteCertificateStatus = "Failed"
+	DeleteCertificateStatusNotFound DeleteCertificateStatus = "NotFound"
+)
+
+type DeleteCertificateRequest struct {
+	CertificateHashData CertificateHashData `json:"certificateHashData" validate:"required"`
+}
+
+type DeleteCertificateResponse struct {
+	Status DeleteCertificateStatus `json:"status" validate:"required,deleteCertificateStatus"`
+}
+
+func (r DeleteCertificateRequest) GetFeatureName() string {
+	return DeleteCertificateFeatureName
+}
+
+func (c DeleteCertificateResponse) GetFeatureName() string {
+	return DeleteCertificateFeatureName
+}
+
+func NewDeleteCertificateRequest(hashData CertificateHashData) *DeleteCertificateRequest {
+	return &DeleteCertificateRequest{CertificateHashData: hashData}
+}
+
+func NewDeleteCertificateResponse(status DeleteCertificateStatus) *DeleteCertificateResponse {
+	return &DeleteCertificateResponse{Status: status}
+}
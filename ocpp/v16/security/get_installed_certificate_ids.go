@@ -0,0 +1,35 @@
+package security
+
+const GetInstalledCertificateIdsFeatureName = "GetInstalledCertificateIds"
+
+type GetInstalledCertificateStatus string
+
+const (
+	GetInstalledCertificateStatusAccepted GetInstalledCertificateStatus = "Accepted"
+	GetInstalledCertificateStatusNotFound GetInstalledCertificateStatus = "NotFound"
+)
+
+type GetInstalledCertificateIdsRequest struct {
+	CertificateType CertificateUse `json:"certificateType" validate:"required,certificateUse"`
+}
+
+type GetInstalledCertificateIdsResponse struct {
+	Status              GetInstalledCertificateStatus `json:"status" validate:"required,getInstalledCertificateStatus"`
+	CertificateHashData []CertificateHashData         `json:"certificateHashData,omitempty" validate:"omitempty,dive"`
+}
+
+func (r GetInstalledCertificateIdsRequest) GetFeatureName() string {
+	return GetInstalledCertificateIdsFeatureName
+}
+
+func (c GetInstalledCertificateIdsResponse) GetFeatureName() string {
+	return GetInstalledCertificateIdsFeatureName
+}
+
+func NewGetInstalledCertificateIdsRequest(certificateType CertificateUse) *GetInstalledCertificateIdsRequest {
+	return &GetInstalledCertificateIdsRequest{CertificateType: certificateType}
+}
+
+func NewGetInstalledCertificateIdsResponse(status GetInstalledCertificateStatus) *GetInstalledCertificateIdsResponse {
+	return &GetInstalledCertificateIdsResponse{Status: status}
+}
@@ -0,0 +1,36 @@
+package security
+
+const InstallCertificateFeatureName = "InstallCertificate"
+
+type InstallCertificateStatus string
+
+const (
+	InstallCertificateStatusAccepted InstallCertificateStatus = "Accepted"
+	InstallCertificateStatusFailed   InstallCertificateStatus = "Failed"
+	InstallCertificateStatusRejected InstallCertificateStatus = "Rejected"
+)
+
+type InstallCertificateRequest struct {
+	CertificateType CertificateUse `json:"certificateType" validate:"required,certificateUse"`
+	Certificate     string         `json:"certificate" validate:"required,max=5500"`
+}
+
+type InstallCertificateResponse struct {
+	Status InstallCertificateStatus `json:"status" validate:"required,installCertificateStatus"`
+}
+
+func (r InstallCertificateRequest) GetFeatureName() string {
+	return InstallCertificateFeatureName
+}
+
+func (c InstallCertificateResponse) GetFeatureName() string {
+	return InstallCertificateFeatureName
+}
+
+func NewInstallCertificateRequest(certificateType CertificateUse, certificate string) *InstallCertificateRequest {
+	return &InstallCertificateRequest{CertificateType: certificateType, Certificate: certificate}
+}
+
+func NewInstallCertificateResponse(status InstallCertificateStatus) *InstallCertificateResponse {
+	return &InstallCertificateResponse{Status: status}
+}
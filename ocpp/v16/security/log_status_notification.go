@@ -0,0 +1,55 @@
+package security
+
+import "fmt"
+
+const LogStatusNotificationFeatureName = "LogStatusNotification"
+
+type UploadLogStatus string
+
+const (
+	UploadLogStatusBadMessage            UploadLogStatus = "BadMessage"
+	UploadLogStatusIdle                  UploadLogStatus = "Idle"
+	UploadLogStatusNotSupportedOperation UploadLogStatus = "NotSupportedOperation"
+	UploadLogStatusPermissionDenied      UploadLogStatus = "PermissionDenied"
+	UploadLogStatusUploaded              UploadLogStatus = "Uploaded"
+	UploadLogStatusUploadFailure         UploadLogStatus = "UploadFailure"
+	UploadLogStatusUploading             UploadLogStatus = "Uploading"
+)
+
+func (s UploadLogStatus) IsValid() bool {
+	switch s {
+	case UploadLogStatusBadMessage, UploadLogStatusIdle, UploadLogStatusNotSupportedOperation,
+		UploadLogStatusPermissionDenied, UploadLogStatusUploaded, UploadLogStatusUploadFailure,
+		UploadLogStatusUploading:
+		return true
+	}
+	return false
+}
+
+type LogStatusNotificationRequest struct {
+	Status    UploadLogStatus `json:"status" validate:"required,uploadLogStatus"`
+	RequestId *int            `json:"requestId,omitempty"`
+}
+
+type LogStatusNotificationResponse struct {
+}
+
+func (r LogStatusNotificationRequest) GetFeatureName() string {
+	return LogStatusNotificationFeatureName
+}
+
+// Validate checks that the status is a known value.
+func (r LogStatusNotificationRequest) Validate() error {
+	if !r.Status.IsValid() {
+		return fmt.Errorf("invalid status: %q", r.Status)
+	}
+	return nil
+}
+
+func (c LogStatusNotificationResponse) GetFeatureName() string {
+	return LogStatusNotificationFeatureName
+}
+
+func NewLogStatusNotificationResponse() *LogStatusNotificationResponse {
+	return &LogStatusNotificationResponse{}
+}
@@ -0,0 +1,57 @@
+// Package security holds the messages of the OCPP 1.6 security extensions (the "Improving security
+// for OCPP 1.6-J" whitepaper): security events, charge point certificates, installed CA
+// certificates and log uploads. Signed firmware updates live in the firmware package.
+package security
+
+type SystemHandler interface {
+	OnSecurityEventNotification(chargePointId string, request *SecurityEventNotificationRequest) (confirmation *SecurityEventNotificationResponse, err error)
+	OnSignCertificate(chargePointId string, request *SignCertificateRequest) (confirmation *SignCertificateResponse, err error)
+	OnLogStatusNotification(chargePointId string, request *LogStatusNotificationRequest) (confirmation *LogStatusNotificationResponse, err error)
+}
+
+type CommandHandler interface {
+	OnInstallCertificate(chargePointId string, payload string) (*InstallCertificateRequest, error)
+	OnGetInstalledCertificateIds(chargePointId string, payload string) (*GetInstalledCertificateIdsRequest, error)
+	OnDeleteCertificate(chargePointId string, payload string) (*DeleteCertificateRequest, error)
+	OnGetLog(chargePointId string, payload string) (*GetLogRequest, error)
+	OnGetLogResponse(chargePointId string, request *GetLogRequest, response *GetLogResponse)
+}
+
+type CertificateUse string
+
+const (
+	CentralSystemRootCertificate CertificateUse = "CentralSystemRootCertificate"
+	ManufacturerRootCertificate  CertificateUse = "ManufacturerRootCertificate"
+)
+
+func (u CertificateUse) IsValid() bool {
+	switch u {
+	case CentralSystemRootCertificate, ManufacturerRootCertificate:
+		return true
+	}
+	return false
+}
+
+type HashAlgorithm string
+
+const (
+	SHA256 HashAlgorithm = "SHA256"
+	SHA384 HashAlgorithm = "SHA384"
+	SHA512 HashAlgorithm = "SHA512"
+)
+
+func (a HashAlgorithm) IsValid() bool {
+	switch a {
+	case SHA256, SHA384, SHA512:
+		return true
+	}
+	return false
+}
+
+// CertificateHashData identifies an installed certificate by its issuer and serial number.
+type CertificateHashData struct {
+	HashAlgorithm  HashAlgorithm `json:"hashAlgorithm" validate:"required,hashAlgorithm"`
+	IssuerNameHash string        `json:"issuerNameHash" validate:"required,max=128"`
+	IssuerKeyHash  string        `json:"issuerKeyHash" validate:"required,max=128"`
+	SerialNumber   string        `json:"serialNumber" validate:"required,max=40"`
+}
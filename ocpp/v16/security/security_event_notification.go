@@ -0,0 +1,62 @@
+package security
+
+import (
+	"evsys/ocpp/common"
+	"evsys/types"
+	"fmt"
+)
+
+const SecurityEventNotificationFeatureName = "SecurityEventNotification"
+
+// Security event types the whitepaper defines; charge points may send types of their own.
+const (
+	EventFirmwareUpdated                     = "FirmwareUpdated"
+	EventFailedToAuthenticateAtCentralSystem = "FailedToAuthenticateAtCentralSystem"
+	EventCentralSystemFailedToAuthenticate   = "CentralSystemFailedToAuthenticate"
+	EventSettingSystemTime                   = "SettingSystemTime"
+	EventStartupOfTheDevice                  = "StartupOfTheDevice"
+	EventResetOrReboot                       = "ResetOrReboot"
+	EventSecurityLogWasCleared               = "SecurityLogWasCleared"
+	EventReconfigurationOfSecurityParameters = "ReconfigurationOfSecurityParameters"
+	EventMemoryExhaustion                    = "MemoryExhaustion"
+	EventInvalidMessages                     = "InvalidMessages"
+	EventAttemptedReplayAttacks              = "AttemptedReplayAttacks"
+	EventTamperDetectionActivated            = "TamperDetectionActivated"
+	EventInvalidFirmwareSignature            = "InvalidFirmwareSignature"
+	EventInvalidFirmwareSigningCertificate   = "InvalidFirmwareSigningCertificate"
+	EventInvalidCentralSystemCertificate     = "InvalidCentralSystemCertificate"
+	EventInvalidChargePointCertificate       = "InvalidChargePointCertificate"
+	EventInvalidTLSVersion                   = "InvalidTLSVersion"
+	EventInvalidTLSCipherSuite               = "InvalidTLSCipherSuite"
+)
+
+type SecurityEventNotificationRequest struct {
+	Type      string          `json:"type" validate:"required,max=50"`
+	Timestamp *types.DateTime `json:"timestamp" validate:"required"`
+	TechInfo  string          `json:"techInfo,omitempty" validate:"max=255"`
+}
+
+type SecurityEventNotificationResponse struct {
+}
+
+func (r SecurityEventNotificationRequest) GetFeatureName() string {
+	return SecurityEventNotificationFeatureName
+}
+
+func (r SecurityEventNotificationRequest) Validate() error {
+	if r.Type == "" || len(r.Type) > 50 {
+		return fmt.Errorf("invalid type: %q", r.Type)
+	}
+	if r.Timestamp == nil {
+		return fmt.Errorf("timestamp is %w", common.ErrRequired)
+	}
+	return nil
+}
+
+func (c SecurityEventNotificationResponse) GetFeatureName() string {
+	return SecurityEventNotificationFeatureName
+}
+
+func NewSecurityEventNotificationResponse() *SecurityEventNotificationResponse {
+	return &SecurityEventNotificationResponse{}
+}
@@ -0,0 +1,47 @@
+package security
+
+import (
+	"evsys/ocpp/common"
+	"fmt"
+)
+
+const SignCertificateFeatureName = "SignCertificate"
+
+type GenericStatus string
+
+const (
+	GenericStatusAccepted GenericStatus = "Accepted"
+	GenericStatusRejected GenericStatus = "Rejected"
+)
+
+// SignCertificateRequest carries a PEM encoded certificate signing request for the charge
+// point's client certificate.
+type SignCertificateRequest struct {
+	Csr string `json:"csr" validate:"required,max=5500"`
+}
+
+type SignCertificateResponse struct {
+	Status GenericStatus `json:"status" validate:"required,genericStatus"`
+}
+
+func (r SignCertificateRequest) GetFeatureName() string {
+	return SignCertificateFeatureName
+}
+
+func (r SignCertificateRequest) Validate() error {
+	if r.Csr == "" {
+		return fmt.Errorf("csr is %w", common.ErrRequired)
+	}
+	if len(r.Csr) > 5500 {
+		return fmt.Errorf("csr is longer than 5500 characters")
+	}
+	return nil
+}
+
+func (c SignCertificateResponse) GetFeatureName() string {
+	return SignCertificateFeatureName
+}
+
+func NewSignCertificateResponse(status GenericStatus) *SignCertificateResponse {
+	return &SignCertificateResponse{Status: status}
+}
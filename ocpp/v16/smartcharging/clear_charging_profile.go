@@ -4,6 +4,13 @@ import "evsys/types"
 
 const ClearChargingProfileFeatureName = "ClearChargingProfile"
 
+type ClearChargingProfileStatus string
+
+const (
+	ClearChargingProfileStatusAccepted ClearChargingProfileStatus = "Accepted"
+	ClearChargingProfileStatusUnknown  ClearChargingProfileStatus = "Unknown"
+)
+
 type ClearChargingProfileRequest struct {
 	Id                     *int                             `json:"id,omitempty" validate:"omitempty"`
 	ConnectorId            *int                             `json:"connectorId,omitempty" validate:"omitempty,gte=0"`
@@ -11,10 +18,18 @@ type ClearChargingProfileRequest struct {
 	StackLevel             *int                             `json:"stackLevel,omitempty" validate:"omitempty,gte=0"`
 }
 
+type ClearChargingProfileResponse struct {
+	Status ClearChargingProfileStatus `json:"status" validate:"required,clearChargingProfileStatus"`
+}
+
 func (r ClearChargingProfileRequest) GetFeatureName() string {
 	return ClearChargingProfileFeatureName
 }
 
+func (c ClearChargingProfileResponse) GetFeatureName() string {
+	return ClearChargingProfileFeatureName
+}
+
 func NewClearChargingProfileRequest() *ClearChargingProfileRequest {
 	return &ClearChargingProfileRequest{}
 }
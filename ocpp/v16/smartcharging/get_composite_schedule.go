@@ -4,16 +4,35 @@ import "evsys/types"
 
 const GetCompositeScheduleFeatureName = "GetCompositeSchedule"
 
+type GetCompositeScheduleStatus string
+
+const (
+	GetCompositeScheduleStatusAccepted GetCompositeScheduleStatus = "Accepted"
+	GetCompositeScheduleStatusRejected GetCompositeScheduleStatus = "Rejected"
+)
+
 type GetCompositeScheduleRequest struct {
 	ConnectorId      int                        `json:"connectorId" validate:"gte=0"`
 	Duration         int                        `json:"duration" validate:"gte=0"`
 	ChargingRateUnit types.ChargingRateUnitType `json:"chargingRateUnit,omitempty" validate:"omitempty,chargingRateUnit"`
 }
 
+// GetCompositeScheduleResponse carries the schedule only when the request was accepted.
+type GetCompositeScheduleResponse struct {
+	Status           GetCompositeScheduleStatus `json:"status" validate:"required,compositeScheduleStatus"`
+	ConnectorId      *int                       `json:"connectorId,omitempty" validate:"omitempty,gte=0"`
+	ScheduleStart    *types.DateTime            `json:"scheduleStart,omitempty"`
+	ChargingSchedule *types.ChargingSchedule    `json:"chargingSchedule,omitempty" validate:"omitempty"`
+}
+
 func (r GetCompositeScheduleRequest) GetFeatureName() string {
 	return GetCompositeScheduleFeatureName
 }
 
+func (c GetCompositeScheduleResponse) GetFeatureName() string {
+	return GetCompositeScheduleFeatureName
+}
+
 func NewGetCompositeScheduleRequest(connectorId int, duration int) *GetCompositeScheduleRequest {
 	return &GetCompositeScheduleRequest{ConnectorId: connectorId, Duration: duration}
 }
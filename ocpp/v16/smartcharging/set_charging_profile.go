@@ -7,6 +7,14 @@ import (
 
 const SetChargingProfileFeatureName = "SetChargingProfile"
 
+type ChargingProfileStatus string
+
+const (
+	ChargingProfileStatusAccepted     ChargingProfileStatus = "Accepted"
+	ChargingProfileStatusRejected     ChargingProfileStatus = "Rejected"
+	ChargingProfileStatusNotSupported ChargingProfileStatus = "NotSupported"
+)
+
 type SetChargingProfileRequest struct {
 	ConnectorId     int                    `json:"connectorId" validate:"gte=0"`
 	ChargingProfile *types.ChargingProfile `json:"csChargingProfiles" validate:"required"`
@@ -16,10 +24,18 @@ func NewSetChargingProfileRequest(connectorId int, chargingProfile *types.Chargi
 	return &SetChargingProfileRequest{ConnectorId: connectorId, ChargingProfile: chargingProfile}
 }
 
+type SetChargingProfileResponse struct {
+	Status ChargingProfileStatus `json:"status" validate:"required,chargingProfileStatus"`
+}
+
 func (r SetChargingProfileRequest) GetFeatureName() string {
 	return SetChargingProfileFeatureName
 }
 
+func (c SetChargingProfileResponse) GetFeatureName() string {
+	return SetChargingProfileFeatureName
+}
+
 func NewDefaultChargingProfile(limit int) *types.ChargingProfile {
 	duration := 86400
 	period := types.ChargingSchedulePeriod{
@@ -0,0 +1,7 @@
+package smartcharging
+
+type CommandHandler interface {
+	OnSetChargingProfile(chargePointId string, connectorId int, payload string) (*SetChargingProfileRequest, error)
+	OnGetCompositeSchedule(chargePointId string, connectorId int, payload string) (*GetCompositeScheduleRequest, error)
+	OnClearChargingProfile(chargePointId string, payload string) (*ClearChargingProfileRequest, error)
+}
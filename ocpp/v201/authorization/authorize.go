@@ -75,8 +75,13 @@ func (r AuthorizeResponse) GetProtocolVersion() common.ProtocolVersion {
 type ValidationError struct {
 	Field   string
 	Message string
+	Err     error // common.ErrRequired for a missing field
 }
 
 func (e *ValidationError) Error() string {
 	return e.Field + ": " + e.Message
 }
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
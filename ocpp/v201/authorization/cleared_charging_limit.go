@@ -52,7 +52,7 @@ func (r ClearedChargingLimitRequest) GetProtocolVersion() common.ProtocolVersion
 // Validate implements common.Request interface
 func (r ClearedChargingLimitRequest) Validate() error {
 	if r.ChargingLimitSource == "" {
-		return &ValidationError{Field: "chargingLimitSource", Message: "required"}
+		return &ValidationError{Field: "chargingLimitSource", Message: "required", Err: common.ErrRequired}
 	}
 	if r.EvseId != nil && *r.EvseId < 1 {
 		return &ValidationError{Field: "evseId", Message: "must be >= 1"}
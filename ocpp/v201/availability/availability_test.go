@@ -10,7 +10,7 @@ import (
 // ============================================================================
 // OCPP 2.0.1 Availability Messages Tests
 // ============================================================================
-// Tests for StatusNotification
+// Tests for StatusNotification and ChangeAvailability
 // ============================================================================
 
 func TestStatusNotificationRequest_Serialization(t *testing.T) {
@@ -196,3 +196,32 @@ func TestStatusNotificationRequest_MultipleConnectors(t *testing.T) {
 		}
 	}
 }
+
+func TestChangeAvailabilityRequest_Validate(t *testing.T) {
+	connectorId := 2
+	zero := 0
+	tests := []struct {
+		name    string
+		request ChangeAvailabilityRequest
+		wantErr bool
+	}{
+		{"whole station", ChangeAvailabilityRequest{OperationalStatus: OperationalStatusInoperative}, false},
+		{"connector of an EVSE", ChangeAvailabilityRequest{OperationalStatus: OperationalStatusOperative, Evse: &v201.EVSE{Id: 1, ConnectorId: &connectorId}}, false},
+		{"unknown status", ChangeAvailabilityRequest{OperationalStatus: "Broken"}, true},
+		{"EVSE zero", ChangeAvailabilityRequest{OperationalStatus: OperationalStatusOperative, Evse: &v201.EVSE{Id: 0}}, true},
+		{"connector zero", ChangeAvailabilityRequest{OperationalStatus: OperationalStatusOperative, Evse: &v201.EVSE{Id: 1, ConnectorId: &zero}}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.request.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	data, err := json.Marshal(ChangeAvailabilityRequest{OperationalStatus: OperationalStatusInoperative})
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if string(data) != `{"operationalStatus":"Inoperative"}` {
+		t.Errorf("serialized %s, want no EVSE for the whole station", data)
+	}
+}
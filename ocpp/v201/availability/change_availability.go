@@ -0,0 +1,88 @@
+package availability
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+)
+
+// ============================================================================
+// ChangeAvailability - OCPP 2.0.1
+// ============================================================================
+// Sent by: CSMS → Charging Station
+// Purpose: Take the charging station, an EVSE or a single connector in or
+//          out of service. Without an EVSE the whole station changes; an
+//          EVSE without a connector id changes all its connectors.
+// ============================================================================
+
+const ChangeAvailabilityFeatureName = "ChangeAvailability"
+
+// OperationalStatusType defines the availability to change to
+type OperationalStatusType string
+
+const (
+	OperationalStatusInoperative OperationalStatusType = "Inoperative" // Out of service
+	OperationalStatusOperative   OperationalStatusType = "Operative"   // In service
+)
+
+// ChangeAvailabilityStatusType defines the response to a ChangeAvailability request
+type ChangeAvailabilityStatusType string
+
+const (
+	ChangeAvailabilityStatusAccepted  ChangeAvailabilityStatusType = "Accepted"  // Availability changed
+	ChangeAvailabilityStatusRejected  ChangeAvailabilityStatusType = "Rejected"  // Availability not changed
+	ChangeAvailabilityStatusScheduled ChangeAvailabilityStatusType = "Scheduled" // Changed once the running transactions end
+)
+
+// ChangeAvailabilityRequest represents the request for ChangeAvailability
+type ChangeAvailabilityRequest struct {
+	// OperationalStatus is the availability to change to
+	OperationalStatus OperationalStatusType `json:"operationalStatus" validate:"required"`
+
+	// Evse is the EVSE, and optionally connector, to change; the whole station when omitted
+	Evse *v201.EVSE `json:"evse,omitempty"`
+}
+
+// ChangeAvailabilityResponse represents the response to ChangeAvailability
+type ChangeAvailabilityResponse struct {
+	// Status indicates whether the availability was changed
+	Status ChangeAvailabilityStatusType `json:"status" validate:"required"`
+
+	// StatusInfo provides additional status information
+	StatusInfo *v201.StatusInfo `json:"statusInfo,omitempty"`
+}
+
+// GetFeatureName implements common.Request interface
+func (r ChangeAvailabilityRequest) GetFeatureName() string {
+	return ChangeAvailabilityFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r ChangeAvailabilityRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r ChangeAvailabilityRequest) Validate() error {
+	if r.OperationalStatus != OperationalStatusInoperative && r.OperationalStatus != OperationalStatusOperative {
+		return &ValidationError{Field: "operationalStatus", Message: "must be Inoperative or Operative"}
+	}
+	if r.Evse != nil {
+		if r.Evse.Id < 1 {
+			return &ValidationError{Field: "evse.id", Message: "must be >= 1"}
+		}
+		if r.Evse.ConnectorId != nil && *r.Evse.ConnectorId < 1 {
+			return &ValidationError{Field: "evse.connectorId", Message: "must be >= 1"}
+		}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r ChangeAvailabilityResponse) GetFeatureName() string {
+	return ChangeAvailabilityFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r ChangeAvailabilityResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
@@ -50,7 +50,7 @@ func (r StatusNotificationRequest) GetProtocolVersion() common.ProtocolVersion {
 // Validate implements common.Request interface
 func (r StatusNotificationRequest) Validate() error {
 	if r.ConnectorStatus == "" {
-		return &ValidationError{Field: "connectorStatus", Message: "required"}
+		return &ValidationError{Field: "connectorStatus", Message: "required", Err: common.ErrRequired}
 	}
 	if r.EvseId < 0 {
 		return &ValidationError{Field: "evseId", Message: "must be >= 0"}
@@ -75,8 +75,13 @@ func (r StatusNotificationResponse) GetProtocolVersion() common.ProtocolVersion
 type ValidationError struct {
 	Field   string
 	Message string
+	Err     error // common.ErrRequired for a missing field
 }
 
 func (e *ValidationError) Error() string {
 	return e.Field + ": " + e.Message
 }
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
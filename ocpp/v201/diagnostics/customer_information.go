@@ -0,0 +1,102 @@
+package diagnostics
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+)
+
+// ============================================================================
+// CustomerInformation - OCPP 2.0.1
+// ============================================================================
+// Sent by: CSMS → Charging Station
+// Purpose: Have the charging station report and/or clear what it holds
+//          about a customer, identified by an id token, a customer
+//          identifier or a certificate. The report follows in
+//          NotifyCustomerInformation messages under the same requestId.
+// ============================================================================
+
+const CustomerInformationFeatureName = "CustomerInformation"
+
+// CustomerInformationStatusType defines the response to a CustomerInformation request
+type CustomerInformationStatusType string
+
+const (
+	CustomerInformationStatusAccepted CustomerInformationStatusType = "Accepted" // Request will be carried out
+	CustomerInformationStatusRejected CustomerInformationStatusType = "Rejected" // Request refused
+	CustomerInformationStatusInvalid  CustomerInformationStatusType = "Invalid"  // The customer was not identified correctly
+)
+
+// CustomerInformationRequest represents the request for CustomerInformation
+type CustomerInformationRequest struct {
+	// RequestId identifies the report in NotifyCustomerInformation
+	RequestId int `json:"requestId"`
+
+	// Report asks for the customer information held by the station
+	Report bool `json:"report"`
+
+	// Clear asks the station to delete the customer information it holds
+	Clear bool `json:"clear"`
+
+	// CustomerCertificate identifies the customer by an ISO 15118 contract certificate
+	CustomerCertificate *v201.CertificateHashData `json:"customerCertificate,omitempty"`
+
+	// IdToken identifies the customer by a token
+	IdToken *v201.IdToken `json:"idToken,omitempty"`
+
+	// CustomerIdentifier identifies the customer by a vendor specific identifier
+	CustomerIdentifier string `json:"customerIdentifier,omitempty" validate:"omitempty,max=64"`
+}
+
+// CustomerInformationResponse represents the response to CustomerInformation
+type CustomerInformationResponse struct {
+	// Status indicates whether the request will be carried out
+	Status CustomerInformationStatusType `json:"status" validate:"required"`
+
+	// StatusInfo provides additional status information
+	StatusInfo *v201.StatusInfo `json:"statusInfo,omitempty"`
+}
+
+// GetFeatureName implements common.Request interface
+func (r CustomerInformationRequest) GetFeatureName() string {
+	return CustomerInformationFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r CustomerInformationRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r CustomerInformationRequest) Validate() error {
+	if !r.Report && !r.Clear {
+		return &ValidationError{Field: "report", Message: "report or clear required", Err: common.ErrRequired}
+	}
+	if r.IdToken == nil && r.CustomerIdentifier == "" && r.CustomerCertificate == nil {
+		return &ValidationError{Field: "idToken", Message: "one of idToken, customerIdentifier and customerCertificate required", Err: common.ErrRequired}
+	}
+	if r.IdToken != nil {
+		if r.IdToken.IdToken == "" || len(r.IdToken.IdToken) > 36 {
+			return &ValidationError{Field: "idToken.idToken", Message: "1 to 36 characters"}
+		}
+		if r.IdToken.Type == "" {
+			return &ValidationError{Field: "idToken.type", Message: "required", Err: common.ErrRequired}
+		}
+	}
+	if len(r.CustomerIdentifier) > 64 {
+		return &ValidationError{Field: "customerIdentifier", Message: "max length is 64"}
+	}
+	if c := r.CustomerCertificate; c != nil && (c.HashAlgorithm == "" || c.IssuerNameHash == "" || c.IssuerKeyHash == "" || c.SerialNumber == "") {
+		return &ValidationError{Field: "customerCertificate", Message: "hashAlgorithm, issuerNameHash, issuerKeyHash and serialNumber required", Err: common.ErrRequired}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r CustomerInformationResponse) GetFeatureName() string {
+	return CustomerInformationFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r CustomerInformationResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
@@ -0,0 +1,114 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"evsys/ocpp/v201"
+	"testing"
+	"time"
+)
+
+// ============================================================================
+// OCPP 2.0.1 Diagnostics Messages Tests
+// ============================================================================
+// Tests for GetLog, LogStatusNotification, CustomerInformation and
+// NotifyCustomerInformation
+// ============================================================================
+
+func TestGetLogRequest_Serialization(t *testing.T) {
+	oldest := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	req := GetLogRequest{
+		Log:       LogParametersType{RemoteLocation: "https://logs.example.com/upload", OldestTimestamp: &oldest},
+		LogType:   LogTypeSecurity,
+		RequestId: 7,
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var decoded GetLogRequest
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if decoded.LogType != LogTypeSecurity || decoded.RequestId != 7 || !decoded.Log.OldestTimestamp.Equal(oldest) {
+		t.Errorf("decoded %+v, want the security log of request 7 since May 1st", decoded)
+	}
+	if err = decoded.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
+
+func TestGetLogRequest_Validate(t *testing.T) {
+	oldest := time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)
+	latest := oldest.Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		req     GetLogRequest
+		wantErr bool
+	}{
+		{"valid", GetLogRequest{LogType: LogTypeDiagnostics, Log: LogParametersType{RemoteLocation: "ftp://host"}}, false},
+		{"unknown type", GetLogRequest{LogType: "AuditLog", Log: LogParametersType{RemoteLocation: "ftp://host"}}, true},
+		{"no location", GetLogRequest{LogType: LogTypeDiagnostics}, true},
+		{"reversed period", GetLogRequest{LogType: LogTypeDiagnostics, Log: LogParametersType{
+			RemoteLocation: "ftp://host", OldestTimestamp: &oldest, LatestTimestamp: &latest}}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.req.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLogStatusNotificationRequest_Validate(t *testing.T) {
+	var req LogStatusNotificationRequest
+	if err := json.Unmarshal([]byte(`{"status":"Uploading","requestId":7}`), &req); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if req.Status != UploadLogStatusUploading || *req.RequestId != 7 {
+		t.Errorf("decoded %+v, want Uploading for request 7", req)
+	}
+	if err := req.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	if err := (LogStatusNotificationRequest{}).Validate(); err == nil {
+		t.Error("Validate() accepted an empty status")
+	}
+}
+
+func TestCustomerInformationRequest_Validate(t *testing.T) {
+	token := &v201.IdToken{IdToken: "04A2B3C4", Type: v201.IdTokenTypeISO14443}
+	certificate := &v201.CertificateHashData{HashAlgorithm: v201.HashAlgorithmSHA256, IssuerNameHash: "a1", IssuerKeyHash: "b2"}
+
+	tests := []struct {
+		name    string
+		req     CustomerInformationRequest
+		wantErr bool
+	}{
+		{"report by token", CustomerInformationRequest{RequestId: 1, Report: true, IdToken: token}, false},
+		{"clear by identifier", CustomerInformationRequest{RequestId: 2, Clear: true, CustomerIdentifier: "customer-17"}, false},
+		{"neither report nor clear", CustomerInformationRequest{IdToken: token}, true},
+		{"no customer", CustomerInformationRequest{Report: true, Clear: true}, true},
+		{"token without type", CustomerInformationRequest{Report: true, IdToken: &v201.IdToken{IdToken: "04A2B3C4"}}, true},
+		{"certificate without serial", CustomerInformationRequest{Clear: true, CustomerCertificate: certificate}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.req.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNotifyCustomerInformationRequest_Serialization(t *testing.T) {
+	payload := `{"data":"sessions: 3","tbc":true,"seqNo":0,"generatedAt":"2024-05-01T10:00:00Z","requestId":12}`
+	var req NotifyCustomerInformationRequest
+	if err := json.Unmarshal([]byte(payload), &req); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if req.Data != "sessions: 3" || !req.Tbc || req.RequestId != 12 {
+		t.Errorf("decoded %+v, want the first of more parts of request 12", req)
+	}
+	if err := req.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
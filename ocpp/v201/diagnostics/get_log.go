@@ -0,0 +1,132 @@
+package diagnostics
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+	"time"
+)
+
+// ============================================================================
+// GetLog - OCPP 2.0.1
+// ============================================================================
+// Sent by: CSMS → Charging Station
+// Purpose: Have the charging station upload a diagnostics or security log
+//          to a remote location. Progress is reported with
+//          LogStatusNotification under the same requestId.
+// ============================================================================
+
+const GetLogFeatureName = "GetLog"
+
+// LogType defines the kind of log to upload
+type LogType string
+
+const (
+	LogTypeDiagnostics LogType = "DiagnosticsLog" // Diagnostics information
+	LogTypeSecurity    LogType = "SecurityLog"    // Security events
+)
+
+// LogStatusType defines the response to a GetLog request
+type LogStatusType string
+
+const (
+	LogStatusAccepted         LogStatusType = "Accepted"         // Upload will be started
+	LogStatusRejected         LogStatusType = "Rejected"         // Upload refused
+	LogStatusAcceptedCanceled LogStatusType = "AcceptedCanceled" // Accepted, an ongoing upload was canceled
+)
+
+// LogParametersType describes the log to upload
+type LogParametersType struct {
+	// RemoteLocation is the URL the log is uploaded to
+	RemoteLocation string `json:"remoteLocation" validate:"required,max=512"`
+
+	// OldestTimestamp limits the log to entries after this time
+	OldestTimestamp *time.Time `json:"oldestTimestamp,omitempty"`
+
+	// LatestTimestamp limits the log to entries before this time
+	LatestTimestamp *time.Time `json:"latestTimestamp,omitempty"`
+}
+
+// GetLogRequest represents the request for GetLog
+type GetLogRequest struct {
+	// Log describes the log to upload
+	Log LogParametersType `json:"log" validate:"required"`
+
+	// LogType is the kind of log
+	LogType LogType `json:"logType" validate:"required"`
+
+	// RequestId identifies the upload in LogStatusNotification
+	RequestId int `json:"requestId"`
+
+	// Retries is how often the upload may be retried
+	Retries *int `json:"retries,omitempty" validate:"omitempty,min=0"`
+
+	// RetryInterval is the time between retries in seconds
+	RetryInterval *int `json:"retryInterval,omitempty" validate:"omitempty,min=0"`
+}
+
+// GetLogResponse represents the response to GetLog
+type GetLogResponse struct {
+	// Status indicates whether the upload will be started
+	Status LogStatusType `json:"status" validate:"required"`
+
+	// StatusInfo provides additional status information
+	StatusInfo *v201.StatusInfo `json:"statusInfo,omitempty"`
+
+	// Filename is the name of the uploaded file
+	Filename string `json:"filename,omitempty" validate:"omitempty,max=255"`
+}
+
+// GetFeatureName implements common.Request interface
+func (r GetLogRequest) GetFeatureName() string {
+	return GetLogFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r GetLogRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r GetLogRequest) Validate() error {
+	if r.LogType != LogTypeDiagnostics && r.LogType != LogTypeSecurity {
+		return &ValidationError{Field: "logType", Message: "must be DiagnosticsLog or SecurityLog"}
+	}
+	if r.Log.RemoteLocation == "" {
+		return &ValidationError{Field: "log.remoteLocation", Message: "required", Err: common.ErrRequired}
+	}
+	if len(r.Log.RemoteLocation) > 512 {
+		return &ValidationError{Field: "log.remoteLocation", Message: "max length is 512"}
+	}
+	if r.Log.OldestTimestamp != nil && r.Log.LatestTimestamp != nil && r.Log.LatestTimestamp.Before(*r.Log.OldestTimestamp) {
+		return &ValidationError{Field: "log.latestTimestamp", Message: "before oldestTimestamp"}
+	}
+	if (r.Retries != nil && *r.Retries < 0) || (r.RetryInterval != nil && *r.RetryInterval < 0) {
+		return &ValidationError{Field: "retries", Message: "must be >= 0"}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r GetLogResponse) GetFeatureName() string {
+	return GetLogFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r GetLogResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// ValidationError represents a validation error
+type ValidationError struct {
+	Field   string
+	Message string
+	Err     error // common.ErrRequired for a missing field
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,19 @@
+package diagnostics
+
+// ============================================================================
+// Diagnostics Handler Interface - OCPP 2.0.1
+// ============================================================================
+// This interface defines the methods that must be implemented to follow
+// log uploads and customer information reports from charging stations.
+// ============================================================================
+
+// Handler defines the interface for handling diagnostics messages
+type Handler interface {
+	// OnLogStatusNotification handles incoming LogStatusNotification requests
+	// Called at every step of a log upload
+	OnLogStatusNotification(chargePointId string, request *LogStatusNotificationRequest) (*LogStatusNotificationResponse, error)
+
+	// OnNotifyCustomerInformation handles incoming NotifyCustomerInformation requests
+	// Called for each part of the report asked for with CustomerInformation
+	OnNotifyCustomerInformation(chargePointId string, request *NotifyCustomerInformationRequest) (*NotifyCustomerInformationResponse, error)
+}
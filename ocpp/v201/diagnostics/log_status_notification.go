@@ -0,0 +1,70 @@
+package diagnostics
+
+import (
+	"evsys/ocpp/common"
+)
+
+// ============================================================================
+// LogStatusNotification - OCPP 2.0.1
+// ============================================================================
+// Sent by: Charging Station → CSMS
+// Purpose: Report each step of a log upload started by GetLog.
+// ============================================================================
+
+const LogStatusNotificationFeatureName = "LogStatusNotification"
+
+// UploadLogStatusType defines the steps of a log upload
+type UploadLogStatusType string
+
+const (
+	UploadLogStatusBadMessage            UploadLogStatusType = "BadMessage"            // Remote server refused the upload
+	UploadLogStatusIdle                  UploadLogStatusType = "Idle"                  // No upload in progress
+	UploadLogStatusNotSupportedOperation UploadLogStatusType = "NotSupportedOperation" // Remote server does not support the operation
+	UploadLogStatusPermissionDenied      UploadLogStatusType = "PermissionDenied"      // Remote server denied access
+	UploadLogStatusUploaded              UploadLogStatusType = "Uploaded"              // Log uploaded
+	UploadLogStatusUploadFailure         UploadLogStatusType = "UploadFailure"         // Upload failed
+	UploadLogStatusUploading             UploadLogStatusType = "Uploading"             // Upload in progress
+	UploadLogStatusAcceptedCanceled      UploadLogStatusType = "AcceptedCanceled"      // Upload canceled by a new GetLog
+)
+
+// LogStatusNotificationRequest represents the request for LogStatusNotification
+type LogStatusNotificationRequest struct {
+	// Status is the current step of the upload
+	Status UploadLogStatusType `json:"status" validate:"required"`
+
+	// RequestId is the id of the GetLog request; absent for Idle
+	RequestId *int `json:"requestId,omitempty"`
+}
+
+// LogStatusNotificationResponse represents the response to LogStatusNotification
+type LogStatusNotificationResponse struct {
+	// No fields required - empty response indicates acknowledgment
+}
+
+// GetFeatureName implements common.Request interface
+func (r LogStatusNotificationRequest) GetFeatureName() string {
+	return LogStatusNotificationFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r LogStatusNotificationRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r LogStatusNotificationRequest) Validate() error {
+	if r.Status == "" {
+		return &ValidationError{Field: "status", Message: "required", Err: common.ErrRequired}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r LogStatusNotificationResponse) GetFeatureName() string {
+	return LogStatusNotificationFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r LogStatusNotificationResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
@@ -0,0 +1,71 @@
+package diagnostics
+
+import (
+	"evsys/ocpp/common"
+	"time"
+)
+
+// ============================================================================
+// NotifyCustomerInformation - OCPP 2.0.1
+// ============================================================================
+// Sent by: Charging Station → CSMS
+// Purpose: Carry the customer information asked for with CustomerInformation.
+//          A long report comes in several parts, numbered by seqNo, all but
+//          the last of them with tbc set.
+// ============================================================================
+
+const NotifyCustomerInformationFeatureName = "NotifyCustomerInformation"
+
+// NotifyCustomerInformationRequest represents the request for NotifyCustomerInformation
+type NotifyCustomerInformationRequest struct {
+	// Data is a part of the customer information, in a format of the station's choosing
+	Data string `json:"data" validate:"required,max=512"`
+
+	// Tbc is true when more parts follow
+	Tbc bool `json:"tbc,omitempty"`
+
+	// SeqNo is the number of the part, from 0
+	SeqNo int `json:"seqNo" validate:"min=0"`
+
+	// GeneratedAt is when the report was generated
+	GeneratedAt time.Time `json:"generatedAt" validate:"required"`
+
+	// RequestId is the id of the CustomerInformation request
+	RequestId int `json:"requestId"`
+}
+
+// NotifyCustomerInformationResponse represents the response to NotifyCustomerInformation
+type NotifyCustomerInformationResponse struct {
+	// No fields required - empty response indicates acknowledgment
+}
+
+// GetFeatureName implements common.Request interface
+func (r NotifyCustomerInformationRequest) GetFeatureName() string {
+	return NotifyCustomerInformationFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r NotifyCustomerInformationRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r NotifyCustomerInformationRequest) Validate() error {
+	if len(r.Data) > 512 {
+		return &ValidationError{Field: "data", Message: "max length is 512"}
+	}
+	if r.SeqNo < 0 {
+		return &ValidationError{Field: "seqNo", Message: "must be >= 0"}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r NotifyCustomerInformationResponse) GetFeatureName() string {
+	return NotifyCustomerInformationFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r NotifyCustomerInformationResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
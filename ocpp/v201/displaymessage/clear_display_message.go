@@ -0,0 +1,63 @@
+package displaymessage
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+)
+
+// ============================================================================
+// ClearDisplayMessage - OCPP 2.0.1
+// ============================================================================
+// Sent by: CSMS → Charging Station
+// Purpose: Remove an installed message from the display.
+// ============================================================================
+
+const ClearDisplayMessageFeatureName = "ClearDisplayMessage"
+
+// ClearMessageStatusType defines the response to a ClearDisplayMessage request
+type ClearMessageStatusType string
+
+const (
+	ClearMessageStatusAccepted ClearMessageStatusType = "Accepted" // Message removed
+	ClearMessageStatusUnknown  ClearMessageStatusType = "Unknown"  // No message with the id
+)
+
+// ClearDisplayMessageRequest represents the request for ClearDisplayMessage
+type ClearDisplayMessageRequest struct {
+	// Id is the message to remove
+	Id int `json:"id"`
+}
+
+// ClearDisplayMessageResponse represents the response to ClearDisplayMessage
+type ClearDisplayMessageResponse struct {
+	// Status indicates whether the message was removed
+	Status ClearMessageStatusType `json:"status" validate:"required"`
+
+	// StatusInfo provides additional status information
+	StatusInfo *v201.StatusInfo `json:"statusInfo,omitempty"`
+}
+
+// GetFeatureName implements common.Request interface
+func (r ClearDisplayMessageRequest) GetFeatureName() string {
+	return ClearDisplayMessageFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r ClearDisplayMessageRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r ClearDisplayMessageRequest) Validate() error {
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r ClearDisplayMessageResponse) GetFeatureName() string {
+	return ClearDisplayMessageFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r ClearDisplayMessageResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
@@ -0,0 +1,96 @@
+package displaymessage
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"evsys/ocpp/v201"
+)
+
+// ============================================================================
+// OCPP 2.0.1 Display Message Messages Tests
+// ============================================================================
+// Tests for SetDisplayMessage, GetDisplayMessages and NotifyDisplayMessages
+// ============================================================================
+
+func TestSetDisplayMessageRequest_Serialization(t *testing.T) {
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	req := SetDisplayMessageRequest{Message: MessageInfoType{
+		Id:            10,
+		Priority:      v201.MessagePriorityInFront,
+		StartDateTime: &start,
+		Message:       v201.MessageContent{Content: "0.35 EUR/kWh", Format: MessageFormatUTF8, Language: "en"},
+	}}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(data), `"priority":"InFront"`) || strings.Contains(string(data), "endDateTime") {
+		t.Errorf("serialized %s, want the priority and no end time", data)
+	}
+
+	var decoded SetDisplayMessageRequest
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if decoded.Message.Id != 10 || !decoded.Message.StartDateTime.Equal(start) || decoded.Message.Message.Language != "en" {
+		t.Errorf("decoded %+v, want message 10 in English from %s", decoded.Message, start)
+	}
+	if err = decoded.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
+
+func TestMessageInfoType_Validate(t *testing.T) {
+	valid := func() MessageInfoType {
+		return MessageInfoType{Id: 1, Priority: v201.MessagePriorityNormalCycle, Message: v201.MessageContent{Content: "Welcome", Format: MessageFormatASCII}}
+	}
+	start := time.Now()
+	end := start.Add(-time.Hour)
+
+	noPriority := valid()
+	noPriority.Priority = ""
+	badState := valid()
+	badState.State = "Sleeping"
+	badFormat := valid()
+	badFormat.Message.Format = "Markdown"
+	longContent := valid()
+	longContent.Message.Content = strings.Repeat("a", 513)
+	endBeforeStart := valid()
+	endBeforeStart.StartDateTime, endBeforeStart.EndDateTime = &start, &end
+
+	tests := []struct {
+		name    string
+		message MessageInfoType
+		wantErr bool
+	}{
+		{"valid", valid(), false},
+		{"no priority", noPriority, true},
+		{"unknown state", badState, true},
+		{"unknown format", badFormat, true},
+		{"content too long", longContent, true},
+		{"end before start", endBeforeStart, true},
+	}
+	for _, tt := range tests {
+		if err := tt.message.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNotifyDisplayMessagesRequest_Serialization(t *testing.T) {
+	var req NotifyDisplayMessagesRequest
+	payload := `{"requestId":7,"tbc":true,"messageInfo":[{"id":3,"priority":"AlwaysFront","state":"Faulted","message":{"content":"Out of order","format":"UTF8"}}]}`
+	if err := json.Unmarshal([]byte(payload), &req); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if req.RequestId != 7 || !req.Tbc || len(req.MessageInfo) != 1 || req.MessageInfo[0].State != v201.MessageStateFaulted {
+		t.Errorf("decoded %+v, want one faulted message of request 7, more to come", req)
+	}
+	if err := (GetDisplayMessagesRequest{RequestId: 7, Priority: "Urgent"}).Validate(); err == nil {
+		t.Error("Validate() accepted an unknown priority")
+	}
+}
@@ -0,0 +1,84 @@
+package displaymessage
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+)
+
+// ============================================================================
+// GetDisplayMessages - OCPP 2.0.1
+// ============================================================================
+// Sent by: CSMS → Charging Station
+// Purpose: Ask for the messages installed on the charging station, all of
+//          them or those matching the ids, priority and state given. The
+//          messages arrive in NotifyDisplayMessages under the same requestId.
+// ============================================================================
+
+const GetDisplayMessagesFeatureName = "GetDisplayMessages"
+
+// GetDisplayMessagesStatusType defines the response to a GetDisplayMessages request
+type GetDisplayMessagesStatusType string
+
+const (
+	GetDisplayMessagesStatusAccepted GetDisplayMessagesStatusType = "Accepted" // Messages will be reported
+	GetDisplayMessagesStatusUnknown  GetDisplayMessagesStatusType = "Unknown"  // No message matches
+)
+
+// GetDisplayMessagesRequest represents the request for GetDisplayMessages
+type GetDisplayMessagesRequest struct {
+	// Id limits the report to these messages
+	Id []int `json:"id,omitempty"`
+
+	// RequestId identifies the report in NotifyDisplayMessages
+	RequestId int `json:"requestId"`
+
+	// Priority limits the report to messages of this priority
+	Priority v201.MessagePriorityType `json:"priority,omitempty"`
+
+	// State limits the report to messages of this state
+	State v201.MessageStateType `json:"state,omitempty"`
+}
+
+// GetDisplayMessagesResponse represents the response to GetDisplayMessages
+type GetDisplayMessagesResponse struct {
+	// Status indicates whether any message will be reported
+	Status GetDisplayMessagesStatusType `json:"status" validate:"required"`
+
+	// StatusInfo provides additional status information
+	StatusInfo *v201.StatusInfo `json:"statusInfo,omitempty"`
+}
+
+// GetFeatureName implements common.Request interface
+func (r GetDisplayMessagesRequest) GetFeatureName() string {
+	return GetDisplayMessagesFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r GetDisplayMessagesRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r GetDisplayMessagesRequest) Validate() error {
+	switch r.Priority {
+	case "", v201.MessagePriorityAlwaysFront, v201.MessagePriorityInFront, v201.MessagePriorityNormalCycle:
+	default:
+		return &ValidationError{Field: "priority", Message: "must be AlwaysFront, InFront or NormalCycle"}
+	}
+	switch r.State {
+	case "", v201.MessageStateCharging, v201.MessageStateFaulted, v201.MessageStateIdle, v201.MessageStateUnavailable:
+	default:
+		return &ValidationError{Field: "state", Message: "must be Charging, Faulted, Idle or Unavailable"}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r GetDisplayMessagesResponse) GetFeatureName() string {
+	return GetDisplayMessagesFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r GetDisplayMessagesResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
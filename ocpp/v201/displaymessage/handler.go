@@ -0,0 +1,15 @@
+package displaymessage
+
+// ============================================================================
+// Display Message Handler Interface - OCPP 2.0.1
+// ============================================================================
+// This interface defines the methods that must be implemented to receive
+// the messages installed on charging station displays.
+// ============================================================================
+
+// Handler defines the interface for handling display message messages
+type Handler interface {
+	// OnNotifyDisplayMessages handles incoming NotifyDisplayMessages requests
+	// Called with the messages asked for by GetDisplayMessages, possibly in several parts
+	OnNotifyDisplayMessages(chargePointId string, request *NotifyDisplayMessagesRequest) (*NotifyDisplayMessagesResponse, error)
+}
@@ -0,0 +1,57 @@
+package displaymessage
+
+import (
+	"evsys/ocpp/common"
+)
+
+// ============================================================================
+// NotifyDisplayMessages - OCPP 2.0.1
+// ============================================================================
+// Sent by: Charging Station → CSMS
+// Purpose: Report the messages asked for by GetDisplayMessages. A long list
+//          comes in several parts; tbc is set on all but the last.
+// ============================================================================
+
+const NotifyDisplayMessagesFeatureName = "NotifyDisplayMessages"
+
+// NotifyDisplayMessagesRequest represents the request for NotifyDisplayMessages
+type NotifyDisplayMessagesRequest struct {
+	// RequestId is the id of the GetDisplayMessages request
+	RequestId int `json:"requestId"`
+
+	// Tbc is set when more parts follow
+	Tbc bool `json:"tbc,omitempty"`
+
+	// MessageInfo are the installed messages
+	MessageInfo []MessageInfoType `json:"messageInfo,omitempty"`
+}
+
+// NotifyDisplayMessagesResponse represents the response to NotifyDisplayMessages
+type NotifyDisplayMessagesResponse struct {
+	// Empty response
+}
+
+// GetFeatureName implements common.Request interface
+func (r NotifyDisplayMessagesRequest) GetFeatureName() string {
+	return NotifyDisplayMessagesFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r NotifyDisplayMessagesRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r NotifyDisplayMessagesRequest) Validate() error {
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r NotifyDisplayMessagesResponse) GetFeatureName() string {
+	return NotifyDisplayMessagesFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r NotifyDisplayMessagesResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
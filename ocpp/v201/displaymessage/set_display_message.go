@@ -0,0 +1,155 @@
+package displaymessage
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+	"time"
+)
+
+// ============================================================================
+// SetDisplayMessage - OCPP 2.0.1
+// ============================================================================
+// Sent by: CSMS → Charging Station
+// Purpose: Install a message on the display of the charging station. A message
+//          with the id of an installed one replaces it; start and end times
+//          bound when it is shown, state and transactionId when it applies.
+// ============================================================================
+
+const SetDisplayMessageFeatureName = "SetDisplayMessage"
+
+// Formats of a message content
+const (
+	MessageFormatASCII = "ASCII" // Plain ASCII text
+	MessageFormatHTML  = "HTML"  // HTML markup
+	MessageFormatURI   = "URI"   // Link to the content
+	MessageFormatUTF8  = "UTF8"  // Plain UTF-8 text
+)
+
+// DisplayMessageStatusType defines the response to a SetDisplayMessage request
+type DisplayMessageStatusType string
+
+const (
+	DisplayMessageStatusAccepted                  DisplayMessageStatusType = "Accepted"                  // Message installed
+	DisplayMessageStatusNotSupportedMessageFormat DisplayMessageStatusType = "NotSupportedMessageFormat" // Format not supported
+	DisplayMessageStatusRejected                  DisplayMessageStatusType = "Rejected"                  // Message refused
+	DisplayMessageStatusNotSupportedPriority      DisplayMessageStatusType = "NotSupportedPriority"      // Priority not supported
+	DisplayMessageStatusNotSupportedState         DisplayMessageStatusType = "NotSupportedState"         // State not supported
+	DisplayMessageStatusUnknownTransaction        DisplayMessageStatusType = "UnknownTransaction"        // Transaction not known to the station
+)
+
+// MessageInfoType is a message as installed on a display
+type MessageInfoType struct {
+	// Display is the display to show the message on; all displays when omitted
+	Display *v201.Component `json:"display,omitempty"`
+
+	// Id identifies the message on the charging station
+	Id int `json:"id"`
+
+	// Priority decides how the message is shown among the others
+	Priority v201.MessagePriorityType `json:"priority" validate:"required"`
+
+	// State limits the message to a state of the charging station
+	State v201.MessageStateType `json:"state,omitempty"`
+
+	// StartDateTime is when the message is first shown
+	StartDateTime *time.Time `json:"startDateTime,omitempty"`
+
+	// EndDateTime is when the message is removed
+	EndDateTime *time.Time `json:"endDateTime,omitempty"`
+
+	// TransactionId limits the message to a transaction
+	TransactionId string `json:"transactionId,omitempty" validate:"omitempty,max=36"`
+
+	// Message is the content
+	Message v201.MessageContent `json:"message" validate:"required"`
+}
+
+// SetDisplayMessageRequest represents the request for SetDisplayMessage
+type SetDisplayMessageRequest struct {
+	// Message is the message to install
+	Message MessageInfoType `json:"message" validate:"required"`
+}
+
+// SetDisplayMessageResponse represents the response to SetDisplayMessage
+type SetDisplayMessageResponse struct {
+	// Status indicates whether the message was installed
+	Status DisplayMessageStatusType `json:"status" validate:"required"`
+
+	// StatusInfo provides additional status information
+	StatusInfo *v201.StatusInfo `json:"statusInfo,omitempty"`
+}
+
+// Validate checks a message as the charging station would
+func (m MessageInfoType) Validate() error {
+	switch m.Priority {
+	case v201.MessagePriorityAlwaysFront, v201.MessagePriorityInFront, v201.MessagePriorityNormalCycle:
+	default:
+		return &ValidationError{Field: "message.priority", Message: "must be AlwaysFront, InFront or NormalCycle"}
+	}
+	switch m.State {
+	case "", v201.MessageStateCharging, v201.MessageStateFaulted, v201.MessageStateIdle, v201.MessageStateUnavailable:
+	default:
+		return &ValidationError{Field: "message.state", Message: "must be Charging, Faulted, Idle or Unavailable"}
+	}
+	switch m.Message.Format {
+	case MessageFormatASCII, MessageFormatHTML, MessageFormatURI, MessageFormatUTF8:
+	default:
+		return &ValidationError{Field: "message.message.format", Message: "must be ASCII, HTML, URI or UTF8"}
+	}
+	if m.Message.Content == "" {
+		return &ValidationError{Field: "message.message.content", Message: "required", Err: common.ErrRequired}
+	}
+	if len(m.Message.Content) > 512 {
+		return &ValidationError{Field: "message.message.content", Message: "max length is 512"}
+	}
+	if len(m.Message.Language) > 8 {
+		return &ValidationError{Field: "message.message.language", Message: "max length is 8"}
+	}
+	if len(m.TransactionId) > 36 {
+		return &ValidationError{Field: "message.transactionId", Message: "max length is 36"}
+	}
+	if m.StartDateTime != nil && m.EndDateTime != nil && !m.EndDateTime.After(*m.StartDateTime) {
+		return &ValidationError{Field: "message.endDateTime", Message: "must be after startDateTime"}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Request interface
+func (r SetDisplayMessageRequest) GetFeatureName() string {
+	return SetDisplayMessageFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r SetDisplayMessageRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r SetDisplayMessageRequest) Validate() error {
+	return r.Message.Validate()
+}
+
+// GetFeatureName implements common.Response interface
+func (r SetDisplayMessageResponse) GetFeatureName() string {
+	return SetDisplayMessageFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r SetDisplayMessageResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// ValidationError represents a validation error
+type ValidationError struct {
+	Field   string
+	Message string
+	Err     error // common.ErrRequired for a missing field
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
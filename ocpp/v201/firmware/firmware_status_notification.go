@@ -0,0 +1,77 @@
+package firmware
+
+import (
+	"evsys/ocpp/common"
+)
+
+// ============================================================================
+// FirmwareStatusNotification - OCPP 2.0.1
+// ============================================================================
+// Sent by: Charging Station → CSMS
+// Purpose: Report each step of a firmware update, from the download through
+//          signature verification to the installation.
+// ============================================================================
+
+const FirmwareStatusNotificationFeatureName = "FirmwareStatusNotification"
+
+// FirmwareStatusType defines the steps of a firmware update
+type FirmwareStatusType string
+
+const (
+	FirmwareStatusDownloaded                FirmwareStatusType = "Downloaded"                // Image downloaded
+	FirmwareStatusDownloadFailed            FirmwareStatusType = "DownloadFailed"            // Download failed
+	FirmwareStatusDownloading               FirmwareStatusType = "Downloading"               // Download in progress
+	FirmwareStatusDownloadScheduled         FirmwareStatusType = "DownloadScheduled"         // Waiting for the retrieve time
+	FirmwareStatusDownloadPaused            FirmwareStatusType = "DownloadPaused"            // Download paused
+	FirmwareStatusIdle                      FirmwareStatusType = "Idle"                      // No update in progress
+	FirmwareStatusInstallationFailed        FirmwareStatusType = "InstallationFailed"        // Installation failed
+	FirmwareStatusInstalling                FirmwareStatusType = "Installing"                // Installation in progress
+	FirmwareStatusInstalled                 FirmwareStatusType = "Installed"                 // New firmware installed
+	FirmwareStatusInstallRebooting          FirmwareStatusType = "InstallRebooting"          // Rebooting to install
+	FirmwareStatusInstallScheduled          FirmwareStatusType = "InstallScheduled"          // Waiting for the install time
+	FirmwareStatusInstallVerificationFailed FirmwareStatusType = "InstallVerificationFailed" // Installed image failed verification
+	FirmwareStatusInvalidSignature          FirmwareStatusType = "InvalidSignature"          // Image signature is invalid
+	FirmwareStatusSignatureVerified         FirmwareStatusType = "SignatureVerified"         // Image signature is valid
+)
+
+// FirmwareStatusNotificationRequest represents the request for FirmwareStatusNotification
+type FirmwareStatusNotificationRequest struct {
+	// Status is the current step of the update
+	Status FirmwareStatusType `json:"status" validate:"required"`
+
+	// RequestId is the id of the UpdateFirmware request; absent for Idle
+	RequestId *int `json:"requestId,omitempty"`
+}
+
+// FirmwareStatusNotificationResponse represents the response to FirmwareStatusNotification
+type FirmwareStatusNotificationResponse struct {
+	// No fields required - empty response indicates acknowledgment
+}
+
+// GetFeatureName implements common.Request interface
+func (r FirmwareStatusNotificationRequest) GetFeatureName() string {
+	return FirmwareStatusNotificationFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r FirmwareStatusNotificationRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r FirmwareStatusNotificationRequest) Validate() error {
+	if r.Status == "" {
+		return &ValidationError{Field: "status", Message: "required", Err: common.ErrRequired}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r FirmwareStatusNotificationResponse) GetFeatureName() string {
+	return FirmwareStatusNotificationFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r FirmwareStatusNotificationResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
@@ -0,0 +1,96 @@
+package firmware
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ============================================================================
+// OCPP 2.0.1 Firmware Messages Tests
+// ============================================================================
+// Tests for UpdateFirmware and FirmwareStatusNotification
+// ============================================================================
+
+func TestUpdateFirmwareRequest_Serialization(t *testing.T) {
+	retries := 3
+	req := UpdateFirmwareRequest{
+		Retries:   &retries,
+		RequestId: 42,
+		Firmware: FirmwareType{
+			Location:           "https://firmware.example.com/cs-2.1.bin",
+			RetrieveDateTime:   time.Date(2024, 5, 1, 2, 0, 0, 0, time.UTC),
+			SigningCertificate: "-----BEGIN CERTIFICATE-----",
+			Signature:          "c2lnbmF0dXJl",
+		},
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(data), `"signature":"c2lnbmF0dXJl"`) || strings.Contains(string(data), "installDateTime") {
+		t.Errorf("serialized %s, want the signature and no install time", data)
+	}
+
+	var decoded UpdateFirmwareRequest
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if decoded.RequestId != 42 || *decoded.Retries != 3 || decoded.Firmware.SigningCertificate != req.Firmware.SigningCertificate {
+		t.Errorf("decoded %+v, want request 42 with 3 retries and the certificate", decoded)
+	}
+	if err = decoded.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
+
+func TestUpdateFirmwareRequest_Validate(t *testing.T) {
+	valid := func() UpdateFirmwareRequest {
+		return UpdateFirmwareRequest{RequestId: 1, Firmware: FirmwareType{Location: "ftp://host/fw.bin", RetrieveDateTime: time.Now()}}
+	}
+	negative := -1
+
+	noLocation := valid()
+	noLocation.Firmware.Location = ""
+	noRetrieve := valid()
+	noRetrieve.Firmware.RetrieveDateTime = time.Time{}
+	longSignature := valid()
+	longSignature.Firmware.Signature = strings.Repeat("a", 801)
+	negativeRetries := valid()
+	negativeRetries.Retries = &negative
+
+	tests := []struct {
+		name    string
+		req     UpdateFirmwareRequest
+		wantErr bool
+	}{
+		{"valid", valid(), false},
+		{"no location", noLocation, true},
+		{"no retrieve time", noRetrieve, true},
+		{"signature too long", longSignature, true},
+		{"negative retries", negativeRetries, true},
+	}
+	for _, tt := range tests {
+		if err := tt.req.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestFirmwareStatusNotificationRequest_Serialization(t *testing.T) {
+	var req FirmwareStatusNotificationRequest
+	if err := json.Unmarshal([]byte(`{"status":"SignatureVerified","requestId":42}`), &req); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if req.Status != FirmwareStatusSignatureVerified || req.RequestId == nil || *req.RequestId != 42 {
+		t.Errorf("decoded %+v, want SignatureVerified for request 42", req)
+	}
+	if err := req.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	if err := (FirmwareStatusNotificationRequest{}).Validate(); err == nil {
+		t.Error("Validate() accepted an empty status")
+	}
+}
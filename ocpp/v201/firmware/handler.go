@@ -0,0 +1,15 @@
+package firmware
+
+// ============================================================================
+// Firmware Handler Interface - OCPP 2.0.1
+// ============================================================================
+// This interface defines the methods that must be implemented to follow
+// firmware updates on charging stations.
+// ============================================================================
+
+// Handler defines the interface for handling firmware messages
+type Handler interface {
+	// OnFirmwareStatusNotification handles incoming FirmwareStatusNotification requests
+	// Called at every step of a firmware update
+	OnFirmwareStatusNotification(chargePointId string, request *FirmwareStatusNotificationRequest) (*FirmwareStatusNotificationResponse, error)
+}
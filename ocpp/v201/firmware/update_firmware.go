@@ -0,0 +1,130 @@
+package firmware
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+	"time"
+)
+
+// ============================================================================
+// UpdateFirmware - OCPP 2.0.1
+// ============================================================================
+// Sent by: CSMS → Charging Station
+// Purpose: Have the charging station download and install a firmware image.
+//          The signing certificate and signature let the station verify the
+//          image before installing it. Progress is reported with
+//          FirmwareStatusNotification under the same requestId.
+// ============================================================================
+
+const UpdateFirmwareFeatureName = "UpdateFirmware"
+
+// UpdateFirmwareStatusType defines the response to an UpdateFirmware request
+type UpdateFirmwareStatusType string
+
+const (
+	UpdateFirmwareStatusAccepted           UpdateFirmwareStatusType = "Accepted"           // Update will be started
+	UpdateFirmwareStatusRejected           UpdateFirmwareStatusType = "Rejected"           // Update refused
+	UpdateFirmwareStatusAcceptedCanceled   UpdateFirmwareStatusType = "AcceptedCanceled"   // Accepted, an ongoing update was canceled
+	UpdateFirmwareStatusInvalidCertificate UpdateFirmwareStatusType = "InvalidCertificate" // Signing certificate is invalid
+	UpdateFirmwareStatusRevokedCertificate UpdateFirmwareStatusType = "RevokedCertificate" // Signing certificate was revoked
+)
+
+// FirmwareType describes the firmware image to install
+type FirmwareType struct {
+	// Location is the URI the image is downloaded from
+	Location string `json:"location" validate:"required,max=512"`
+
+	// RetrieveDateTime is when the download should start
+	RetrieveDateTime time.Time `json:"retrieveDateTime" validate:"required"`
+
+	// InstallDateTime is when the image should be installed
+	InstallDateTime *time.Time `json:"installDateTime,omitempty"`
+
+	// SigningCertificate is the PEM certificate the image was signed with
+	SigningCertificate string `json:"signingCertificate,omitempty" validate:"omitempty,max=5500"`
+
+	// Signature is the base64 signature of the image
+	Signature string `json:"signature,omitempty" validate:"omitempty,max=800"`
+}
+
+// UpdateFirmwareRequest represents the request for UpdateFirmware
+type UpdateFirmwareRequest struct {
+	// Retries is how often the download may be retried
+	Retries *int `json:"retries,omitempty" validate:"omitempty,min=0"`
+
+	// RetryInterval is the time between retries in seconds
+	RetryInterval *int `json:"retryInterval,omitempty" validate:"omitempty,min=0"`
+
+	// RequestId identifies the update in FirmwareStatusNotification
+	RequestId int `json:"requestId"`
+
+	// Firmware describes the image
+	Firmware FirmwareType `json:"firmware" validate:"required"`
+}
+
+// UpdateFirmwareResponse represents the response to UpdateFirmware
+type UpdateFirmwareResponse struct {
+	// Status indicates whether the update will be started
+	Status UpdateFirmwareStatusType `json:"status" validate:"required"`
+
+	// StatusInfo provides additional status information
+	StatusInfo *v201.StatusInfo `json:"statusInfo,omitempty"`
+}
+
+// GetFeatureName implements common.Request interface
+func (r UpdateFirmwareRequest) GetFeatureName() string {
+	return UpdateFirmwareFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r UpdateFirmwareRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r UpdateFirmwareRequest) Validate() error {
+	if r.Firmware.Location == "" {
+		return &ValidationError{Field: "firmware.location", Message: "required", Err: common.ErrRequired}
+	}
+	if len(r.Firmware.Location) > 512 {
+		return &ValidationError{Field: "firmware.location", Message: "max length is 512"}
+	}
+	if r.Firmware.RetrieveDateTime.IsZero() {
+		return &ValidationError{Field: "firmware.retrieveDateTime", Message: "required", Err: common.ErrRequired}
+	}
+	if len(r.Firmware.SigningCertificate) > 5500 {
+		return &ValidationError{Field: "firmware.signingCertificate", Message: "max length is 5500"}
+	}
+	if len(r.Firmware.Signature) > 800 {
+		return &ValidationError{Field: "firmware.signature", Message: "max length is 800"}
+	}
+	if (r.Retries != nil && *r.Retries < 0) || (r.RetryInterval != nil && *r.RetryInterval < 0) {
+		return &ValidationError{Field: "retries", Message: "must be >= 0"}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r UpdateFirmwareResponse) GetFeatureName() string {
+	return UpdateFirmwareFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r UpdateFirmwareResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// ValidationError represents a validation error
+type ValidationError struct {
+	Field   string
+	Message string
+	Err     error // common.ErrRequired for a missing field
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
@@ -5,8 +5,18 @@ import (
 	"evsys/ocpp/common"
 	"evsys/ocpp/v201/authorization"
 	"evsys/ocpp/v201/availability"
+	"evsys/ocpp/v201/diagnostics"
+	"evsys/ocpp/v201/displaymessage"
+	"evsys/ocpp/v201/firmware"
+	"evsys/ocpp/v201/iso15118"
+	"evsys/ocpp/v201/localauth"
+	"evsys/ocpp/v201/metervalues"
+	"evsys/ocpp/v201/monitoring"
 	"evsys/ocpp/v201/provisioning"
 	"evsys/ocpp/v201/remotecontrol"
+	"evsys/ocpp/v201/reservation"
+	"evsys/ocpp/v201/security"
+	"evsys/ocpp/v201/smartcharging"
 	"evsys/ocpp/v201/transactions"
 	"fmt"
 	"reflect"
@@ -27,6 +37,15 @@ type Handler201 struct {
 	authorizationHandler   authorization.Handler
 	transactionsHandler    transactions.Handler
 	availabilityHandler    availability.Handler
+	meterValuesHandler     metervalues.Handler
+	smartChargingHandler   smartcharging.Handler
+	monitoringHandler      monitoring.Handler
+	firmwareHandler        firmware.Handler
+	diagnosticsHandler     diagnostics.Handler
+	displayMessageHandler  displaymessage.Handler
+	iso15118Handler        iso15118.Handler
+	securityHandler        security.Handler
+	reservationHandler     reservation.Handler
 	remoteControlHandler   remotecontrol.Handler
 	provisioningCmdHandler provisioning.CommandHandler
 }
@@ -37,6 +56,15 @@ type Handler201Config struct {
 	AuthorizationHandler   authorization.Handler
 	TransactionsHandler    transactions.Handler
 	AvailabilityHandler    availability.Handler
+	MeterValuesHandler     metervalues.Handler
+	SmartChargingHandler   smartcharging.Handler
+	MonitoringHandler      monitoring.Handler
+	FirmwareHandler        firmware.Handler
+	DiagnosticsHandler     diagnostics.Handler
+	DisplayMessageHandler  displaymessage.Handler
+	Iso15118Handler        iso15118.Handler
+	SecurityHandler        security.Handler
+	ReservationHandler     reservation.Handler
 	RemoteControlHandler   remotecontrol.Handler
 	ProvisioningCmdHandler provisioning.CommandHandler
 }
@@ -49,6 +77,15 @@ func NewHandler201(config Handler201Config) *Handler201 {
 		authorizationHandler:   config.AuthorizationHandler,
 		transactionsHandler:    config.TransactionsHandler,
 		availabilityHandler:    config.AvailabilityHandler,
+		meterValuesHandler:     config.MeterValuesHandler,
+		smartChargingHandler:   config.SmartChargingHandler,
+		monitoringHandler:      config.MonitoringHandler,
+		firmwareHandler:        config.FirmwareHandler,
+		diagnosticsHandler:     config.DiagnosticsHandler,
+		displayMessageHandler:  config.DisplayMessageHandler,
+		iso15118Handler:        config.Iso15118Handler,
+		securityHandler:        config.SecurityHandler,
+		reservationHandler:     config.ReservationHandler,
 		remoteControlHandler:   config.RemoteControlHandler,
 		provisioningCmdHandler: config.ProvisioningCmdHandler,
 	}
@@ -81,6 +118,10 @@ func (h *Handler201) registerFeatures() {
 		reflect.TypeOf(provisioning.GetBaseReportRequest{}),
 		reflect.TypeOf(provisioning.GetBaseReportResponse{}))
 
+	common.RegisterFeature(version, provisioning.GetReportFeatureName,
+		reflect.TypeOf(provisioning.GetReportRequest{}),
+		reflect.TypeOf(provisioning.GetReportResponse{}))
+
 	common.RegisterFeature(version, provisioning.GetVariablesFeatureName,
 		reflect.TypeOf(provisioning.GetVariablesRequest{}),
 		reflect.TypeOf(provisioning.GetVariablesResponse{}))
@@ -105,6 +146,15 @@ func (h *Handler201) registerFeatures() {
 		reflect.TypeOf(authorization.ClearedChargingLimitRequest{}),
 		reflect.TypeOf(authorization.ClearedChargingLimitResponse{}))
 
+	// Local Authorization List Commands (CSMS → Charging Station)
+	common.RegisterFeature(version, localauth.SendLocalListFeatureName,
+		reflect.TypeOf(localauth.SendLocalListRequest{}),
+		reflect.TypeOf(localauth.SendLocalListResponse{}))
+
+	common.RegisterFeature(version, localauth.GetLocalListVersionFeatureName,
+		reflect.TypeOf(localauth.GetLocalListVersionRequest{}),
+		reflect.TypeOf(localauth.GetLocalListVersionResponse{}))
+
 	// ========================================================================
 	// REMOTE CONTROL FEATURES (CSMS → Charging Station)
 	// ========================================================================
@@ -117,6 +167,10 @@ func (h *Handler201) registerFeatures() {
 		reflect.TypeOf(remotecontrol.RequestStopTransactionRequest{}),
 		reflect.TypeOf(remotecontrol.RequestStopTransactionResponse{}))
 
+	common.RegisterFeature(version, remotecontrol.UnlockConnectorFeatureName,
+		reflect.TypeOf(remotecontrol.UnlockConnectorRequest{}),
+		reflect.TypeOf(remotecontrol.UnlockConnectorResponse{}))
+
 	// ========================================================================
 	// TRANSACTION FEATURES
 	// ========================================================================
@@ -125,6 +179,11 @@ func (h *Handler201) registerFeatures() {
 		reflect.TypeOf(transactions.TransactionEventRequest{}),
 		reflect.TypeOf(transactions.TransactionEventResponse{}))
 
+	// Transaction Commands (CSMS → Charging Station)
+	common.RegisterFeature(version, transactions.CostUpdatedFeatureName,
+		reflect.TypeOf(transactions.CostUpdatedRequest{}),
+		reflect.TypeOf(transactions.CostUpdatedResponse{}))
+
 	// ========================================================================
 	// AVAILABILITY FEATURES
 	// ========================================================================
@@ -132,6 +191,173 @@ func (h *Handler201) registerFeatures() {
 	common.RegisterFeature(version, availability.StatusNotificationFeatureName,
 		reflect.TypeOf(availability.StatusNotificationRequest{}),
 		reflect.TypeOf(availability.StatusNotificationResponse{}))
+
+	// Availability Commands (CSMS → Charging Station)
+	common.RegisterFeature(version, availability.ChangeAvailabilityFeatureName,
+		reflect.TypeOf(availability.ChangeAvailabilityRequest{}),
+		reflect.TypeOf(availability.ChangeAvailabilityResponse{}))
+
+	// ========================================================================
+	// METER VALUES FEATURES
+	// ========================================================================
+
+	common.RegisterFeature(version, metervalues.MeterValuesFeatureName,
+		reflect.TypeOf(metervalues.MeterValuesRequest{}),
+		reflect.TypeOf(metervalues.MeterValuesResponse{}))
+
+	// ========================================================================
+	// SMART CHARGING FEATURES
+	// ========================================================================
+
+	common.RegisterFeature(version, smartcharging.ReportChargingProfilesFeatureName,
+		reflect.TypeOf(smartcharging.ReportChargingProfilesRequest{}),
+		reflect.TypeOf(smartcharging.ReportChargingProfilesResponse{}))
+
+	common.RegisterFeature(version, smartcharging.NotifyEVChargingNeedsFeatureName,
+		reflect.TypeOf(smartcharging.NotifyEVChargingNeedsRequest{}),
+		reflect.TypeOf(smartcharging.NotifyEVChargingNeedsResponse{}))
+
+	common.RegisterFeature(version, smartcharging.NotifyEVChargingScheduleFeatureName,
+		reflect.TypeOf(smartcharging.NotifyEVChargingScheduleRequest{}),
+		reflect.TypeOf(smartcharging.NotifyEVChargingScheduleResponse{}))
+
+	common.RegisterFeature(version, smartcharging.NotifyChargingLimitFeatureName,
+		reflect.TypeOf(smartcharging.NotifyChargingLimitRequest{}),
+		reflect.TypeOf(smartcharging.NotifyChargingLimitResponse{}))
+
+	// Smart Charging Commands (CSMS → Charging Station)
+	common.RegisterFeature(version, smartcharging.SetChargingProfileFeatureName,
+		reflect.TypeOf(smartcharging.SetChargingProfileRequest{}),
+		reflect.TypeOf(smartcharging.SetChargingProfileResponse{}))
+
+	common.RegisterFeature(version, smartcharging.GetChargingProfilesFeatureName,
+		reflect.TypeOf(smartcharging.GetChargingProfilesRequest{}),
+		reflect.TypeOf(smartcharging.GetChargingProfilesResponse{}))
+
+	common.RegisterFeature(version, smartcharging.ClearChargingProfileFeatureName,
+		reflect.TypeOf(smartcharging.ClearChargingProfileRequest{}),
+		reflect.TypeOf(smartcharging.ClearChargingProfileResponse{}))
+
+	common.RegisterFeature(version, smartcharging.GetCompositeScheduleFeatureName,
+		reflect.TypeOf(smartcharging.GetCompositeScheduleRequest{}),
+		reflect.TypeOf(smartcharging.GetCompositeScheduleResponse{}))
+
+	// ========================================================================
+	// MONITORING FEATURES
+	// ========================================================================
+
+	common.RegisterFeature(version, monitoring.NotifyEventFeatureName,
+		reflect.TypeOf(monitoring.NotifyEventRequest{}),
+		reflect.TypeOf(monitoring.NotifyEventResponse{}))
+
+	common.RegisterFeature(version, monitoring.NotifyMonitoringReportFeatureName,
+		reflect.TypeOf(monitoring.NotifyMonitoringReportRequest{}),
+		reflect.TypeOf(monitoring.NotifyMonitoringReportResponse{}))
+
+	// Monitoring Commands (CSMS → Charging Station)
+	common.RegisterFeature(version, monitoring.SetVariableMonitoringFeatureName,
+		reflect.TypeOf(monitoring.SetVariableMonitoringRequest{}),
+		reflect.TypeOf(monitoring.SetVariableMonitoringResponse{}))
+
+	common.RegisterFeature(version, monitoring.ClearVariableMonitoringFeatureName,
+		reflect.TypeOf(monitoring.ClearVariableMonitoringRequest{}),
+		reflect.TypeOf(monitoring.ClearVariableMonitoringResponse{}))
+
+	common.RegisterFeature(version, monitoring.SetMonitoringBaseFeatureName,
+		reflect.TypeOf(monitoring.SetMonitoringBaseRequest{}),
+		reflect.TypeOf(monitoring.SetMonitoringBaseResponse{}))
+
+	common.RegisterFeature(version, monitoring.GetMonitoringReportFeatureName,
+		reflect.TypeOf(monitoring.GetMonitoringReportRequest{}),
+		reflect.TypeOf(monitoring.GetMonitoringReportResponse{}))
+
+	// ========================================================================
+	// FIRMWARE AND DIAGNOSTICS FEATURES
+	// ========================================================================
+
+	common.RegisterFeature(version, firmware.FirmwareStatusNotificationFeatureName,
+		reflect.TypeOf(firmware.FirmwareStatusNotificationRequest{}),
+		reflect.TypeOf(firmware.FirmwareStatusNotificationResponse{}))
+
+	common.RegisterFeature(version, diagnostics.LogStatusNotificationFeatureName,
+		reflect.TypeOf(diagnostics.LogStatusNotificationRequest{}),
+		reflect.TypeOf(diagnostics.LogStatusNotificationResponse{}))
+
+	common.RegisterFeature(version, diagnostics.NotifyCustomerInformationFeatureName,
+		reflect.TypeOf(diagnostics.NotifyCustomerInformationRequest{}),
+		reflect.TypeOf(diagnostics.NotifyCustomerInformationResponse{}))
+
+	// Firmware and Diagnostics Commands (CSMS → Charging Station)
+	common.RegisterFeature(version, firmware.UpdateFirmwareFeatureName,
+		reflect.TypeOf(firmware.UpdateFirmwareRequest{}),
+		reflect.TypeOf(firmware.UpdateFirmwareResponse{}))
+
+	common.RegisterFeature(version, diagnostics.GetLogFeatureName,
+		reflect.TypeOf(diagnostics.GetLogRequest{}),
+		reflect.TypeOf(diagnostics.GetLogResponse{}))
+
+	common.RegisterFeature(version, diagnostics.CustomerInformationFeatureName,
+		reflect.TypeOf(diagnostics.CustomerInformationRequest{}),
+		reflect.TypeOf(diagnostics.CustomerInformationResponse{}))
+
+	// ========================================================================
+	// DISPLAY MESSAGE FEATURES
+	// ========================================================================
+
+	common.RegisterFeature(version, displaymessage.NotifyDisplayMessagesFeatureName,
+		reflect.TypeOf(displaymessage.NotifyDisplayMessagesRequest{}),
+		reflect.TypeOf(displaymessage.NotifyDisplayMessagesResponse{}))
+
+	// Display Message Commands (CSMS → Charging Station)
+	common.RegisterFeature(version, displaymessage.SetDisplayMessageFeatureName,
+		reflect.TypeOf(displaymessage.SetDisplayMessageRequest{}),
+		reflect.TypeOf(displaymessage.SetDisplayMessageResponse{}))
+
+	common.RegisterFeature(version, displaymessage.GetDisplayMessagesFeatureName,
+		reflect.TypeOf(displaymessage.GetDisplayMessagesRequest{}),
+		reflect.TypeOf(displaymessage.GetDisplayMessagesResponse{}))
+
+	common.RegisterFeature(version, displaymessage.ClearDisplayMessageFeatureName,
+		reflect.TypeOf(displaymessage.ClearDisplayMessageRequest{}),
+		reflect.TypeOf(displaymessage.ClearDisplayMessageResponse{}))
+
+	// ========================================================================
+	// ISO 15118 AND SECURITY FEATURES
+	// ========================================================================
+
+	common.RegisterFeature(version, iso15118.Get15118EVCertificateFeatureName,
+		reflect.TypeOf(iso15118.Get15118EVCertificateRequest{}),
+		reflect.TypeOf(iso15118.Get15118EVCertificateResponse{}))
+
+	common.RegisterFeature(version, iso15118.GetCertificateStatusFeatureName,
+		reflect.TypeOf(iso15118.GetCertificateStatusRequest{}),
+		reflect.TypeOf(iso15118.GetCertificateStatusResponse{}))
+
+	common.RegisterFeature(version, security.SignCertificateFeatureName,
+		reflect.TypeOf(security.SignCertificateRequest{}),
+		reflect.TypeOf(security.SignCertificateResponse{}))
+
+	// Security Commands (CSMS → Charging Station)
+	common.RegisterFeature(version, security.CertificateSignedFeatureName,
+		reflect.TypeOf(security.CertificateSignedRequest{}),
+		reflect.TypeOf(security.CertificateSignedResponse{}))
+
+	// ========================================================================
+	// RESERVATION FEATURES
+	// ========================================================================
+
+	common.RegisterFeature(version, reservation.ReservationStatusUpdateFeatureName,
+		reflect.TypeOf(reservation.ReservationStatusUpdateRequest{}),
+		reflect.TypeOf(reservation.ReservationStatusUpdateResponse{}))
+
+	// Reservation Commands (CSMS → Charging Station)
+	common.RegisterFeature(version, reservation.ReserveNowFeatureName,
+		reflect.TypeOf(reservation.ReserveNowRequest{}),
+		reflect.TypeOf(reservation.ReserveNowResponse{}))
+
+	common.RegisterFeature(version, reservation.CancelReservationFeatureName,
+		reflect.TypeOf(reservation.CancelReservationRequest{}),
+		reflect.TypeOf(reservation.CancelReservationResponse{}))
 }
 
 // HandleRequest processes incoming requests from charge points
@@ -223,6 +449,132 @@ func (h *Handler201) HandleRequest(ws common.VersionedWebSocket, action string,
 		req := request.(*availability.StatusNotificationRequest)
 		return h.availabilityHandler.OnStatusNotification(chargePointId, req)
 
+	// ========================================================================
+	// METER VALUES FEATURES
+	// ========================================================================
+	case metervalues.MeterValuesFeatureName:
+		if h.meterValuesHandler == nil {
+			return nil, fmt.Errorf("meter values handler not configured")
+		}
+		req := request.(*metervalues.MeterValuesRequest)
+		return h.meterValuesHandler.OnMeterValues(chargePointId, req)
+
+	// ========================================================================
+	// SMART CHARGING FEATURES
+	// ========================================================================
+	case smartcharging.ReportChargingProfilesFeatureName:
+		if h.smartChargingHandler == nil {
+			return nil, fmt.Errorf("smart charging handler not configured")
+		}
+		req := request.(*smartcharging.ReportChargingProfilesRequest)
+		return h.smartChargingHandler.OnReportChargingProfiles(chargePointId, req)
+
+	case smartcharging.NotifyEVChargingNeedsFeatureName:
+		if h.smartChargingHandler == nil {
+			return nil, fmt.Errorf("smart charging handler not configured")
+		}
+		req := request.(*smartcharging.NotifyEVChargingNeedsRequest)
+		return h.smartChargingHandler.OnNotifyEVChargingNeeds(chargePointId, req)
+
+	case smartcharging.NotifyEVChargingScheduleFeatureName:
+		if h.smartChargingHandler == nil {
+			return nil, fmt.Errorf("smart charging handler not configured")
+		}
+		req := request.(*smartcharging.NotifyEVChargingScheduleRequest)
+		return h.smartChargingHandler.OnNotifyEVChargingSchedule(chargePointId, req)
+
+	case smartcharging.NotifyChargingLimitFeatureName:
+		if h.smartChargingHandler == nil {
+			return nil, fmt.Errorf("smart charging handler not configured")
+		}
+		req := request.(*smartcharging.NotifyChargingLimitRequest)
+		return h.smartChargingHandler.OnNotifyChargingLimit(chargePointId, req)
+
+	// ========================================================================
+	// MONITORING FEATURES
+	// ========================================================================
+	case monitoring.NotifyEventFeatureName:
+		if h.monitoringHandler == nil {
+			return nil, fmt.Errorf("monitoring handler not configured")
+		}
+		req := request.(*monitoring.NotifyEventRequest)
+		return h.monitoringHandler.OnNotifyEvent(chargePointId, req)
+
+	case monitoring.NotifyMonitoringReportFeatureName:
+		if h.monitoringHandler == nil {
+			return nil, fmt.Errorf("monitoring handler not configured")
+		}
+		req := request.(*monitoring.NotifyMonitoringReportRequest)
+		return h.monitoringHandler.OnNotifyMonitoringReport(chargePointId, req)
+
+	// ========================================================================
+	// FIRMWARE AND DIAGNOSTICS FEATURES
+	// ========================================================================
+	case firmware.FirmwareStatusNotificationFeatureName:
+		if h.firmwareHandler == nil {
+			return nil, fmt.Errorf("firmware handler not configured")
+		}
+		req := request.(*firmware.FirmwareStatusNotificationRequest)
+		return h.firmwareHandler.OnFirmwareStatusNotification(chargePointId, req)
+
+	case diagnostics.LogStatusNotificationFeatureName:
+		if h.diagnosticsHandler == nil {
+			return nil, fmt.Errorf("diagnostics handler not configured")
+		}
+		req := request.(*diagnostics.LogStatusNotificationRequest)
+		return h.diagnosticsHandler.OnLogStatusNotification(chargePointId, req)
+
+	case diagnostics.NotifyCustomerInformationFeatureName:
+		if h.diagnosticsHandler == nil {
+			return nil, fmt.Errorf("diagnostics handler not configured")
+		}
+		req := request.(*diagnostics.NotifyCustomerInformationRequest)
+		return h.diagnosticsHandler.OnNotifyCustomerInformation(chargePointId, req)
+
+	// ========================================================================
+	// DISPLAY MESSAGE FEATURES
+	// ========================================================================
+	case displaymessage.NotifyDisplayMessagesFeatureName:
+		if h.displayMessageHandler == nil {
+			return nil, fmt.Errorf("display message handler not configured")
+		}
+		req := request.(*displaymessage.NotifyDisplayMessagesRequest)
+		return h.displayMessageHandler.OnNotifyDisplayMessages(chargePointId, req)
+
+	// ========================================================================
+	// ISO 15118 AND SECURITY FEATURES
+	// ========================================================================
+	case iso15118.Get15118EVCertificateFeatureName:
+		if h.iso15118Handler == nil {
+			return nil, fmt.Errorf("iso15118 handler not configured")
+		}
+		req := request.(*iso15118.Get15118EVCertificateRequest)
+		return h.iso15118Handler.OnGet15118EVCertificate(chargePointId, req)
+
+	case iso15118.GetCertificateStatusFeatureName:
+		if h.iso15118Handler == nil {
+			return nil, fmt.Errorf("iso15118 handler not configured")
+		}
+		req := request.(*iso15118.GetCertificateStatusRequest)
+		return h.iso15118Handler.OnGetCertificateStatus(chargePointId, req)
+
+	case security.SignCertificateFeatureName:
+		if h.securityHandler == nil {
+			return nil, fmt.Errorf("security handler not configured")
+		}
+		req := request.(*security.SignCertificateRequest)
+		return h.securityHandler.OnSignCertificate(chargePointId, req)
+
+	// ========================================================================
+	// RESERVATION FEATURES
+	// ========================================================================
+	case reservation.ReservationStatusUpdateFeatureName:
+		if h.reservationHandler == nil {
+			return nil, fmt.Errorf("reservation handler not configured")
+		}
+		req := request.(*reservation.ReservationStatusUpdateRequest)
+		return h.reservationHandler.OnReservationStatusUpdate(chargePointId, req)
+
 	default:
 		return nil, fmt.Errorf("no handler configured for action: %s", action)
 	}
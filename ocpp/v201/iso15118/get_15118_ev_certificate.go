@@ -0,0 +1,113 @@
+package iso15118
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+)
+
+// ============================================================================
+// Get15118EVCertificate - OCPP 2.0.1
+// ============================================================================
+// Sent by: Charging Station → CSMS
+// Purpose: Relay the CertificateInstallationReq or CertificateUpdateReq of an
+//          EV to the certificate provisioning service. The request and the
+//          response are EXI encoded ISO 15118 messages, base64 in OCPP; the
+//          charging station does not decode them.
+// ============================================================================
+
+const Get15118EVCertificateFeatureName = "Get15118EVCertificate"
+
+// CertificateActionType defines what the EV asks for
+type CertificateActionType string
+
+const (
+	CertificateActionInstall CertificateActionType = "Install" // Install a new contract certificate
+	CertificateActionUpdate  CertificateActionType = "Update"  // Update an installed one
+)
+
+// Iso15118EVCertificateStatusType defines the response to a Get15118EVCertificate request
+type Iso15118EVCertificateStatusType string
+
+const (
+	Iso15118EVCertificateStatusAccepted Iso15118EVCertificateStatusType = "Accepted" // exiResponse holds the answer
+	Iso15118EVCertificateStatusFailed   Iso15118EVCertificateStatusType = "Failed"   // No answer could be obtained
+)
+
+// Get15118EVCertificateRequest represents the request for Get15118EVCertificate
+type Get15118EVCertificateRequest struct {
+	// Iso15118SchemaVersion is the schema the EV uses
+	Iso15118SchemaVersion string `json:"iso15118SchemaVersion" validate:"required,max=50"`
+
+	// Action is Install or Update
+	Action CertificateActionType `json:"action" validate:"required"`
+
+	// ExiRequest is the base64 EXI request of the EV
+	ExiRequest string `json:"exiRequest" validate:"required,max=5600"`
+}
+
+// Get15118EVCertificateResponse represents the response to Get15118EVCertificate
+type Get15118EVCertificateResponse struct {
+	// Status indicates whether an answer for the EV was obtained
+	Status Iso15118EVCertificateStatusType `json:"status" validate:"required"`
+
+	// StatusInfo provides additional status information
+	StatusInfo *v201.StatusInfo `json:"statusInfo,omitempty"`
+
+	// ExiResponse is the base64 EXI response for the EV
+	ExiResponse string `json:"exiResponse" validate:"max=5600"`
+}
+
+// GetFeatureName implements common.Request interface
+func (r Get15118EVCertificateRequest) GetFeatureName() string {
+	return Get15118EVCertificateFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r Get15118EVCertificateRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r Get15118EVCertificateRequest) Validate() error {
+	if r.Iso15118SchemaVersion == "" {
+		return &ValidationError{Field: "iso15118SchemaVersion", Message: "required", Err: common.ErrRequired}
+	}
+	if len(r.Iso15118SchemaVersion) > 50 {
+		return &ValidationError{Field: "iso15118SchemaVersion", Message: "max length is 50"}
+	}
+	if r.Action != CertificateActionInstall && r.Action != CertificateActionUpdate {
+		return &ValidationError{Field: "action", Message: "must be Install or Update"}
+	}
+	if r.ExiRequest == "" {
+		return &ValidationError{Field: "exiRequest", Message: "required", Err: common.ErrRequired}
+	}
+	if len(r.ExiRequest) > 5600 {
+		return &ValidationError{Field: "exiRequest", Message: "max length is 5600"}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r Get15118EVCertificateResponse) GetFeatureName() string {
+	return Get15118EVCertificateFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r Get15118EVCertificateResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// ValidationError represents a validation error
+type ValidationError struct {
+	Field   string
+	Message string
+	Err     error // common.ErrRequired for a missing field
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,80 @@
+package iso15118
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+)
+
+// ============================================================================
+// GetCertificateStatus - OCPP 2.0.1
+// ============================================================================
+// Sent by: Charging Station → CSMS
+// Purpose: Obtain the OCSP response for a certificate of the V2G or contract
+//          chain, for a charging station without access to the responder. The
+//          station passes the response on to the EV.
+// ============================================================================
+
+const GetCertificateStatusFeatureName = "GetCertificateStatus"
+
+// GetCertificateStatusType defines the response to a GetCertificateStatus request
+type GetCertificateStatusType string
+
+const (
+	GetCertificateStatusAccepted GetCertificateStatusType = "Accepted" // ocspResult holds the response
+	GetCertificateStatusFailed   GetCertificateStatusType = "Failed"   // No response could be obtained
+)
+
+// GetCertificateStatusRequest represents the request for GetCertificateStatus
+type GetCertificateStatusRequest struct {
+	// OcspRequestData identifies the certificate
+	OcspRequestData v201.OCSPRequestDataType `json:"ocspRequestData" validate:"required"`
+}
+
+// GetCertificateStatusResponse represents the response to GetCertificateStatus
+type GetCertificateStatusResponse struct {
+	// Status indicates whether an OCSP response was obtained
+	Status GetCertificateStatusType `json:"status" validate:"required"`
+
+	// StatusInfo provides additional status information
+	StatusInfo *v201.StatusInfo `json:"statusInfo,omitempty"`
+
+	// OcspResult is the base64 DER OCSP response
+	OcspResult string `json:"ocspResult,omitempty" validate:"omitempty,max=5500"`
+}
+
+// GetFeatureName implements common.Request interface
+func (r GetCertificateStatusRequest) GetFeatureName() string {
+	return GetCertificateStatusFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r GetCertificateStatusRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r GetCertificateStatusRequest) Validate() error {
+	data := r.OcspRequestData
+	switch data.HashAlgorithm {
+	case v201.HashAlgorithmSHA256, v201.HashAlgorithmSHA384, v201.HashAlgorithmSHA512:
+	default:
+		return &ValidationError{Field: "ocspRequestData.hashAlgorithm", Message: "must be SHA256, SHA384 or SHA512"}
+	}
+	if data.IssuerNameHash == "" || data.IssuerKeyHash == "" || data.SerialNumber == "" {
+		return &ValidationError{Field: "ocspRequestData", Message: "issuerNameHash, issuerKeyHash and serialNumber are required", Err: common.ErrRequired}
+	}
+	if len(data.SerialNumber) > 40 {
+		return &ValidationError{Field: "ocspRequestData.serialNumber", Message: "max length is 40"}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r GetCertificateStatusResponse) GetFeatureName() string {
+	return GetCertificateStatusFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r GetCertificateStatusResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
@@ -0,0 +1,19 @@
+package iso15118
+
+// ============================================================================
+// ISO 15118 Handler Interface - OCPP 2.0.1
+// ============================================================================
+// This interface defines the methods that must be implemented to serve the
+// certificate requests charging stations relay for ISO 15118 Plug & Charge.
+// ============================================================================
+
+// Handler defines the interface for handling ISO 15118 certificate messages
+type Handler interface {
+	// OnGet15118EVCertificate handles incoming Get15118EVCertificate requests
+	// Called when an EV asks for the installation or update of its contract certificate
+	OnGet15118EVCertificate(chargePointId string, request *Get15118EVCertificateRequest) (*Get15118EVCertificateResponse, error)
+
+	// OnGetCertificateStatus handles incoming GetCertificateStatus requests
+	// Called when a charging station needs the OCSP status of a certificate
+	OnGetCertificateStatus(chargePointId string, request *GetCertificateStatusRequest) (*GetCertificateStatusResponse, error)
+}
@@ -0,0 +1,84 @@
+package iso15118
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"evsys/ocpp/v201"
+)
+
+// ============================================================================
+// OCPP 2.0.1 ISO 15118 Messages Tests
+// ============================================================================
+// Tests for Get15118EVCertificate and GetCertificateStatus
+// ============================================================================
+
+func TestGet15118EVCertificateRequest_Serialization(t *testing.T) {
+	var req Get15118EVCertificateRequest
+	payload := `{"iso15118SchemaVersion":"urn:iso:15118:2:2013:MsgDef","action":"Install","exiRequest":"gAEC"}`
+	if err := json.Unmarshal([]byte(payload), &req); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if req.Action != CertificateActionInstall || req.ExiRequest != "gAEC" {
+		t.Errorf("decoded %+v, want an install request", req)
+	}
+	if err := req.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	data, err := json.Marshal(Get15118EVCertificateResponse{Status: Iso15118EVCertificateStatusFailed})
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if string(data) != `{"status":"Failed","exiResponse":""}` {
+		t.Errorf("serialized %s, want the required exiResponse even when empty", data)
+	}
+}
+
+func TestGet15118EVCertificateRequest_Validate(t *testing.T) {
+	valid := Get15118EVCertificateRequest{Iso15118SchemaVersion: "urn:iso:15118:2:2013:MsgDef", Action: CertificateActionUpdate, ExiRequest: "gAEC"}
+	badAction, noExi, longExi := valid, valid, valid
+	badAction.Action = "Delete"
+	noExi.ExiRequest = ""
+	longExi.ExiRequest = strings.Repeat("a", 5601)
+
+	tests := []struct {
+		name    string
+		request Get15118EVCertificateRequest
+		wantErr bool
+	}{
+		{"valid", valid, false},
+		{"unknown action", badAction, true},
+		{"no request", noExi, true},
+		{"request too long", longExi, true},
+	}
+	for _, tt := range tests {
+		if err := tt.request.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestGetCertificateStatusRequest_Validate(t *testing.T) {
+	valid := GetCertificateStatusRequest{OcspRequestData: v201.OCSPRequestDataType{
+		HashAlgorithm:  v201.HashAlgorithmSHA256,
+		IssuerNameHash: "a1b2",
+		IssuerKeyHash:  "c3d4",
+		SerialNumber:   "0f",
+		ResponderURL:   "http://ocsp.example.com",
+	}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	badHash := valid
+	badHash.OcspRequestData.HashAlgorithm = "MD5"
+	if err := badHash.Validate(); err == nil {
+		t.Error("Validate() accepted an unknown hash algorithm")
+	}
+	noSerial := valid
+	noSerial.OcspRequestData.SerialNumber = ""
+	if err := noSerial.Validate(); err == nil {
+		t.Error("Validate() accepted hash data without a serial number")
+	}
+}
@@ -0,0 +1,49 @@
+package localauth
+
+import (
+	"evsys/ocpp/common"
+)
+
+// ============================================================================
+// GetLocalListVersion - OCPP 2.0.1
+// ============================================================================
+// Sent by: CSMS → Charging Station
+// Purpose: Read the version of the installed local authorization list; 0
+//          means no list is installed.
+// ============================================================================
+
+const GetLocalListVersionFeatureName = "GetLocalListVersion"
+
+// GetLocalListVersionRequest represents the request for GetLocalListVersion
+type GetLocalListVersionRequest struct{}
+
+// GetLocalListVersionResponse represents the response to GetLocalListVersion
+type GetLocalListVersionResponse struct {
+	// VersionNumber is the version of the installed list
+	VersionNumber int `json:"versionNumber"`
+}
+
+// GetFeatureName implements common.Request interface
+func (r GetLocalListVersionRequest) GetFeatureName() string {
+	return GetLocalListVersionFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r GetLocalListVersionRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r GetLocalListVersionRequest) Validate() error {
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r GetLocalListVersionResponse) GetFeatureName() string {
+	return GetLocalListVersionFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r GetLocalListVersionResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
@@ -0,0 +1,75 @@
+package localauth
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"evsys/ocpp/v201"
+)
+
+// ============================================================================
+// OCPP 2.0.1 Local Authorization List Messages Tests
+// ============================================================================
+// Tests for SendLocalList and GetLocalListVersion
+// ============================================================================
+
+func TestSendLocalListRequest_Serialization(t *testing.T) {
+	req := SendLocalListRequest{
+		VersionNumber: 4,
+		UpdateType:    UpdateTypeDifferential,
+		LocalAuthorizationList: []AuthorizationData{
+			{IdToken: v201.IdToken{IdToken: "ALICE", Type: v201.IdTokenTypeISO14443}, IdTokenInfo: &v201.IdTokenInfo{Status: v201.AuthorizationStatusAccepted}},
+			{IdToken: v201.IdToken{IdToken: "BOB", Type: v201.IdTokenTypeISO14443}},
+		},
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if strings.Count(string(data), "idTokenInfo") != 1 {
+		t.Errorf("serialized %s, want idTokenInfo only for the entry that stays", data)
+	}
+
+	var decoded SendLocalListRequest
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if decoded.VersionNumber != 4 || len(decoded.LocalAuthorizationList) != 2 || decoded.LocalAuthorizationList[1].IdTokenInfo != nil {
+		t.Errorf("decoded %+v, want version 4 with one removal", decoded)
+	}
+	if err = decoded.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
+
+func TestSendLocalListRequest_Validate(t *testing.T) {
+	removal := []AuthorizationData{{IdToken: v201.IdToken{IdToken: "BOB", Type: v201.IdTokenTypeISO14443}}}
+	tests := []struct {
+		name    string
+		request SendLocalListRequest
+		wantErr bool
+	}{
+		{"empty full list", SendLocalListRequest{VersionNumber: 1, UpdateType: UpdateTypeFull}, false},
+		{"version zero", SendLocalListRequest{VersionNumber: 0, UpdateType: UpdateTypeFull}, true},
+		{"unknown update type", SendLocalListRequest{VersionNumber: 1, UpdateType: "Partial"}, true},
+		{"removal in a full list", SendLocalListRequest{VersionNumber: 1, UpdateType: UpdateTypeFull, LocalAuthorizationList: removal}, true},
+		{"removal in a differential list", SendLocalListRequest{VersionNumber: 2, UpdateType: UpdateTypeDifferential, LocalAuthorizationList: removal}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.request.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestGetLocalListVersionResponse_Serialization(t *testing.T) {
+	var response GetLocalListVersionResponse
+	if err := json.Unmarshal([]byte(`{"versionNumber":0}`), &response); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if response.VersionNumber != 0 {
+		t.Errorf("VersionNumber = %d, want 0 for no list", response.VersionNumber)
+	}
+}
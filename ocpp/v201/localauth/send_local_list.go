@@ -0,0 +1,120 @@
+package localauth
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+	"fmt"
+)
+
+// ============================================================================
+// SendLocalList - OCPP 2.0.1
+// ============================================================================
+// Sent by: CSMS → Charging Station
+// Purpose: Install or update the local authorization list, which the
+//          station uses to authorize id tokens while it is offline. A Full
+//          update replaces the list, a Differential one adds, changes and
+//          removes entries; an entry without idTokenInfo is removed.
+// ============================================================================
+
+const SendLocalListFeatureName = "SendLocalList"
+
+// UpdateType defines how a SendLocalList applies to the installed list
+type UpdateType string
+
+const (
+	UpdateTypeDifferential UpdateType = "Differential" // Update the installed list
+	UpdateTypeFull         UpdateType = "Full"         // Replace the installed list
+)
+
+// SendLocalListStatusType defines the response to a SendLocalList request
+type SendLocalListStatusType string
+
+const (
+	SendLocalListStatusAccepted        SendLocalListStatusType = "Accepted"        // List updated
+	SendLocalListStatusFailed          SendLocalListStatusType = "Failed"          // List not updated
+	SendLocalListStatusVersionMismatch SendLocalListStatusType = "VersionMismatch" // Differential update on the wrong version
+)
+
+// AuthorizationData is an entry of the local authorization list
+type AuthorizationData struct {
+	// IdToken is the token the entry is for
+	IdToken v201.IdToken `json:"idToken" validate:"required"`
+
+	// IdTokenInfo is how the token is authorized; omitted to remove the entry
+	IdTokenInfo *v201.IdTokenInfo `json:"idTokenInfo,omitempty"`
+}
+
+// SendLocalListRequest represents the request for SendLocalList
+type SendLocalListRequest struct {
+	// VersionNumber is the version of the list once the update is applied
+	VersionNumber int `json:"versionNumber" validate:"required"`
+
+	// UpdateType is how the update applies
+	UpdateType UpdateType `json:"updateType" validate:"required"`
+
+	// LocalAuthorizationList holds the entries of the update; a Full update without entries clears the list
+	LocalAuthorizationList []AuthorizationData `json:"localAuthorizationList,omitempty"`
+}
+
+// SendLocalListResponse represents the response to SendLocalList
+type SendLocalListResponse struct {
+	// Status indicates whether the list was updated
+	Status SendLocalListStatusType `json:"status" validate:"required"`
+
+	// StatusInfo provides additional status information
+	StatusInfo *v201.StatusInfo `json:"statusInfo,omitempty"`
+}
+
+// GetFeatureName implements common.Request interface
+func (r SendLocalListRequest) GetFeatureName() string {
+	return SendLocalListFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r SendLocalListRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r SendLocalListRequest) Validate() error {
+	if r.VersionNumber < 1 {
+		return &ValidationError{Field: "versionNumber", Message: "must be >= 1"}
+	}
+	if r.UpdateType != UpdateTypeDifferential && r.UpdateType != UpdateTypeFull {
+		return &ValidationError{Field: "updateType", Message: "must be Differential or Full"}
+	}
+	for i, data := range r.LocalAuthorizationList {
+		if err := data.IdToken.Validate(); err != nil {
+			return &ValidationError{Field: fmt.Sprintf("localAuthorizationList[%d].idToken", i), Message: err.Error()}
+		}
+		if r.UpdateType == UpdateTypeFull && data.IdTokenInfo == nil {
+			return &ValidationError{Field: fmt.Sprintf("localAuthorizationList[%d].idTokenInfo", i), Message: "required in a Full update", Err: common.ErrRequired}
+		}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r SendLocalListResponse) GetFeatureName() string {
+	return SendLocalListFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r SendLocalListResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// ValidationError represents a validation error
+type ValidationError struct {
+	Field   string
+	Message string
+	Err     error // common.ErrRequired for a missing field
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,15 @@
+package metervalues
+
+// ============================================================================
+// Meter Values Handler Interface - OCPP 2.0.1
+// ============================================================================
+// This interface defines the methods that must be implemented to handle
+// meter readings sent outside of TransactionEvent.
+// ============================================================================
+
+// Handler defines the interface for handling meter values messages
+type Handler interface {
+	// OnMeterValues handles incoming MeterValues requests
+	// Called for EVSE readings outside a transaction and main meter readings on EVSE 0
+	OnMeterValues(chargePointId string, request *MeterValuesRequest) (*MeterValuesResponse, error)
+}
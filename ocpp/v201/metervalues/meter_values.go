@@ -0,0 +1,83 @@
+package metervalues
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+)
+
+// ============================================================================
+// MeterValues - OCPP 2.0.1
+// ============================================================================
+// Sent by: Charging Station → CSMS
+// Purpose: Report meter readings that are not part of a transaction, such as
+//          the EVSE readings configured by AlignedDataCtrlr or the readings of
+//          the main meter on EVSE 0. Readings taken during a transaction are
+//          sent in TransactionEvent instead.
+// ============================================================================
+
+const MeterValuesFeatureName = "MeterValues"
+
+// MeterValuesRequest represents the request for MeterValues
+type MeterValuesRequest struct {
+	// EvseId is the EVSE the readings were taken on; 0 is the main meter of the charging station
+	EvseId int `json:"evseId" validate:"min=0"`
+
+	// MeterValue contains the sampled readings
+	MeterValue []v201.MeterValue `json:"meterValue" validate:"required,min=1,dive"`
+}
+
+// MeterValuesResponse represents the response to MeterValues
+type MeterValuesResponse struct {
+	// No fields required - empty response indicates acknowledgment
+}
+
+// GetFeatureName implements common.Request interface
+func (r MeterValuesRequest) GetFeatureName() string {
+	return MeterValuesFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r MeterValuesRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r MeterValuesRequest) Validate() error {
+	if r.EvseId < 0 {
+		return &ValidationError{Field: "evseId", Message: "must be >= 0"}
+	}
+	if len(r.MeterValue) == 0 {
+		return &ValidationError{Field: "meterValue", Message: "at least one meter value required", Err: common.ErrRequired}
+	}
+	for _, meterValue := range r.MeterValue {
+		if len(meterValue.SampledValue) == 0 {
+			return &ValidationError{Field: "meterValue.sampledValue", Message: "at least one sampled value required", Err: common.ErrRequired}
+		}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r MeterValuesResponse) GetFeatureName() string {
+	return MeterValuesFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r MeterValuesResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// ValidationError represents a validation error
+type ValidationError struct {
+	Field   string
+	Message string
+	Err     error // common.ErrRequired for a missing field
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
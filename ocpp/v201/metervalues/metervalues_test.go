@@ -0,0 +1,84 @@
+package metervalues
+
+import (
+	"encoding/json"
+	"evsys/ocpp/v201"
+	"testing"
+	"time"
+)
+
+// ============================================================================
+// OCPP 2.0.1 Meter Values Messages Tests
+// ============================================================================
+// Tests for MeterValues
+// ============================================================================
+
+func TestMeterValuesRequest_Serialization(t *testing.T) {
+	req := MeterValuesRequest{
+		EvseId: 0,
+		MeterValue: []v201.MeterValue{{
+			Timestamp: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+			SampledValue: []v201.SampledValue{
+				{Value: 125000, Measurand: v201.MeasurandEnergyActiveImportRegister},
+				{Value: 230.5, Measurand: v201.MeasurandVoltage, Phase: v201.PhaseL1},
+			},
+		}},
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	var decoded MeterValuesRequest
+	err = json.Unmarshal(data, &decoded)
+	if err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	if decoded.EvseId != 0 {
+		t.Errorf("EvseId = %v, want 0", decoded.EvseId)
+	}
+	if len(decoded.MeterValue) != 1 || len(decoded.MeterValue[0].SampledValue) != 2 {
+		t.Fatalf("MeterValue = %+v, want one value with two samples", decoded.MeterValue)
+	}
+	if decoded.MeterValue[0].SampledValue[1].Value != 230.5 {
+		t.Errorf("Voltage = %v, want 230.5", decoded.MeterValue[0].SampledValue[1].Value)
+	}
+}
+
+func TestMeterValuesRequest_Validate(t *testing.T) {
+	valid := MeterValuesRequest{
+		EvseId: 1,
+		MeterValue: []v201.MeterValue{{
+			Timestamp:    time.Now(),
+			SampledValue: []v201.SampledValue{{Value: 1}},
+		}},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		req  MeterValuesRequest
+	}{
+		{"negative evse", MeterValuesRequest{EvseId: -1, MeterValue: valid.MeterValue}},
+		{"no meter values", MeterValuesRequest{EvseId: 1}},
+		{"no sampled values", MeterValuesRequest{EvseId: 1, MeterValue: []v201.MeterValue{{Timestamp: time.Now()}}}},
+	}
+	for _, tt := range tests {
+		if err := tt.req.Validate(); err == nil {
+			t.Errorf("%s: Validate() error = nil, want an error", tt.name)
+		}
+	}
+}
+
+func TestMeterValues_GetFeatureName(t *testing.T) {
+	if (MeterValuesRequest{}).GetFeatureName() != MeterValuesFeatureName {
+		t.Errorf("request feature name = %v, want %v", (MeterValuesRequest{}).GetFeatureName(), MeterValuesFeatureName)
+	}
+	if (MeterValuesResponse{}).GetFeatureName() != MeterValuesFeatureName {
+		t.Errorf("response feature name = %v, want %v", (MeterValuesResponse{}).GetFeatureName(), MeterValuesFeatureName)
+	}
+}
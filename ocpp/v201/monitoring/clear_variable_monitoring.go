@@ -0,0 +1,77 @@
+package monitoring
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+)
+
+// ============================================================================
+// ClearVariableMonitoring - OCPP 2.0.1
+// ============================================================================
+// Sent by: CSMS → Charging Station
+// Purpose: Remove monitors by id. Hard-wired and preconfigured monitors
+//          cannot be removed, only overridden.
+// ============================================================================
+
+const ClearVariableMonitoringFeatureName = "ClearVariableMonitoring"
+
+// ClearMonitoringStatusType defines the result of removing a monitor
+type ClearMonitoringStatusType string
+
+const (
+	ClearMonitoringStatusAccepted ClearMonitoringStatusType = "Accepted" // Monitor removed
+	ClearMonitoringStatusRejected ClearMonitoringStatusType = "Rejected" // Monitor cannot be removed
+	ClearMonitoringStatusNotFound ClearMonitoringStatusType = "NotFound" // No monitor with this id
+)
+
+// ClearVariableMonitoringRequest represents the request for ClearVariableMonitoring
+type ClearVariableMonitoringRequest struct {
+	// Id lists the monitors to remove
+	Id []int `json:"id" validate:"required,min=1"`
+}
+
+// ClearMonitoringResultType is the result for one monitor
+type ClearMonitoringResultType struct {
+	// Status indicates whether the monitor was removed
+	Status ClearMonitoringStatusType `json:"status" validate:"required"`
+
+	// Id is the monitor
+	Id int `json:"id"`
+
+	// StatusInfo provides additional status information
+	StatusInfo *v201.StatusInfo `json:"statusInfo,omitempty"`
+}
+
+// ClearVariableMonitoringResponse represents the response to ClearVariableMonitoring
+type ClearVariableMonitoringResponse struct {
+	// ClearMonitoringResult contains one result per monitor
+	ClearMonitoringResult []ClearMonitoringResultType `json:"clearMonitoringResult" validate:"required,min=1,dive"`
+}
+
+// GetFeatureName implements common.Request interface
+func (r ClearVariableMonitoringRequest) GetFeatureName() string {
+	return ClearVariableMonitoringFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r ClearVariableMonitoringRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r ClearVariableMonitoringRequest) Validate() error {
+	if len(r.Id) == 0 {
+		return &ValidationError{Field: "id", Message: "at least one monitor id required", Err: common.ErrRequired}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r ClearVariableMonitoringResponse) GetFeatureName() string {
+	return ClearVariableMonitoringFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r ClearVariableMonitoringResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
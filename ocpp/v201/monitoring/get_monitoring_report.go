@@ -0,0 +1,85 @@
+package monitoring
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+	"evsys/ocpp/v201/provisioning"
+)
+
+// ============================================================================
+// GetMonitoringReport - OCPP 2.0.1
+// ============================================================================
+// Sent by: CSMS → Charging Station
+// Purpose: Ask for the installed monitors, optionally filtered by kind and
+//          by component and variable. The monitors follow in one or more
+//          NotifyMonitoringReport messages carrying the same requestId.
+// ============================================================================
+
+const GetMonitoringReportFeatureName = "GetMonitoringReport"
+
+// MonitoringCriterionType selects monitors by kind
+type MonitoringCriterionType string
+
+const (
+	MonitoringCriterionThreshold MonitoringCriterionType = "ThresholdMonitoring" // Upper and lower thresholds
+	MonitoringCriterionDelta     MonitoringCriterionType = "DeltaMonitoring"     // Delta monitors
+	MonitoringCriterionPeriodic  MonitoringCriterionType = "PeriodicMonitoring"  // Periodic monitors
+)
+
+// ComponentVariableType names a component and, optionally, one of its variables; GetReport
+// selects variables the same way
+type ComponentVariableType = provisioning.ComponentVariableType
+
+// GetMonitoringReportRequest represents the request for GetMonitoringReport
+type GetMonitoringReportRequest struct {
+	// RequestId links the NotifyMonitoringReport messages to this request
+	RequestId int `json:"requestId"`
+
+	// MonitoringCriteria selects monitors by kind
+	MonitoringCriteria []MonitoringCriterionType `json:"monitoringCriteria,omitempty" validate:"omitempty,max=3"`
+
+	// ComponentVariable selects monitors by component and variable
+	ComponentVariable []ComponentVariableType `json:"componentVariable,omitempty" validate:"omitempty,dive"`
+}
+
+// GetMonitoringReportResponse represents the response to GetMonitoringReport
+type GetMonitoringReportResponse struct {
+	// Status indicates whether monitors will be reported
+	Status provisioning.GenericDeviceModelStatusType `json:"status" validate:"required"`
+
+	// StatusInfo provides additional status information
+	StatusInfo *v201.StatusInfo `json:"statusInfo,omitempty"`
+}
+
+// GetFeatureName implements common.Request interface
+func (r GetMonitoringReportRequest) GetFeatureName() string {
+	return GetMonitoringReportFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r GetMonitoringReportRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r GetMonitoringReportRequest) Validate() error {
+	if len(r.MonitoringCriteria) > 3 {
+		return &ValidationError{Field: "monitoringCriteria", Message: "max 3 criteria"}
+	}
+	for _, componentVariable := range r.ComponentVariable {
+		if componentVariable.Component.Name == "" {
+			return &ValidationError{Field: "componentVariable.component.name", Message: "required", Err: common.ErrRequired}
+		}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r GetMonitoringReportResponse) GetFeatureName() string {
+	return GetMonitoringReportFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r GetMonitoringReportResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
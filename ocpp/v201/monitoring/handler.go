@@ -0,0 +1,19 @@
+package monitoring
+
+// ============================================================================
+// Monitoring Handler Interface - OCPP 2.0.1
+// ============================================================================
+// This interface defines the methods that must be implemented to handle
+// the events and monitor reports sent by charging stations.
+// ============================================================================
+
+// Handler defines the interface for handling monitoring messages
+type Handler interface {
+	// OnNotifyEvent handles incoming NotifyEvent requests
+	// Called when a monitor triggers or a hard-wired notification is raised
+	OnNotifyEvent(chargePointId string, request *NotifyEventRequest) (*NotifyEventResponse, error)
+
+	// OnNotifyMonitoringReport handles incoming NotifyMonitoringReport requests
+	// Called with the monitors asked for by GetMonitoringReport
+	OnNotifyMonitoringReport(chargePointId string, request *NotifyMonitoringReportRequest) (*NotifyMonitoringReportResponse, error)
+}
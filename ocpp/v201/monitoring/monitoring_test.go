@@ -0,0 +1,180 @@
+package monitoring
+
+import (
+	"encoding/json"
+	"evsys/ocpp/v201"
+	"testing"
+	"time"
+)
+
+// ============================================================================
+// OCPP 2.0.1 Monitoring Messages Tests
+// ============================================================================
+// Tests for NotifyEvent, NotifyMonitoringReport, SetVariableMonitoring,
+// ClearVariableMonitoring, SetMonitoringBase and GetMonitoringReport
+// ============================================================================
+
+func TestNotifyEventRequest_Serialization(t *testing.T) {
+	data := []byte(`{"generatedAt":"2024-05-01T10:00:00Z","seqNo":0,"eventData":[{
+		"eventId":7,"timestamp":"2024-05-01T09:59:58Z","trigger":"Alerting","actualValue":"82.5",
+		"techCode":"OT1","variableMonitoringId":3,"eventNotificationType":"CustomMonitor",
+		"component":{"name":"ChargingStation","evse":{"id":1}},"variable":{"name":"Temperature"}}]}`)
+
+	var req NotifyEventRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	event := req.EventData[0]
+	if event.EventId != 7 || event.Trigger != EventTriggerAlerting || event.ActualValue != "82.5" {
+		t.Errorf("decoded %+v, want an alerting event 7 at 82.5", event)
+	}
+	if event.VariableMonitoringId == nil || *event.VariableMonitoringId != 3 || event.Component.Evse.Id != 1 {
+		t.Errorf("decoded %+v, want monitor 3 on EVSE 1", event)
+	}
+	if err := req.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
+
+func TestNotifyEventRequest_Validate(t *testing.T) {
+	valid := EventDataType{
+		EventId:               1,
+		Timestamp:             time.Now(),
+		Trigger:               EventTriggerAlerting,
+		ActualValue:           "true",
+		EventNotificationType: EventNotificationHardWiredNotification,
+		Component:             v201.Component{Name: "RCD"},
+		Variable:              v201.Variable{Name: "Tripped"},
+	}
+	noTrigger := valid
+	noTrigger.Trigger = ""
+	noVariable := valid
+	noVariable.Variable.Name = ""
+
+	tests := []struct {
+		name    string
+		req     NotifyEventRequest
+		wantErr bool
+	}{
+		{"valid", NotifyEventRequest{GeneratedAt: time.Now(), EventData: []EventDataType{valid}}, false},
+		{"no events", NotifyEventRequest{GeneratedAt: time.Now()}, true},
+		{"negative seqNo", NotifyEventRequest{SeqNo: -1, EventData: []EventDataType{valid}}, true},
+		{"no trigger", NotifyEventRequest{EventData: []EventDataType{noTrigger}}, true},
+		{"no variable", NotifyEventRequest{EventData: []EventDataType{noVariable}}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.req.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSetVariableMonitoringRequest_Serialization(t *testing.T) {
+	req := SetVariableMonitoringRequest{SetMonitoringData: []SetMonitoringDataType{{
+		Value:     80,
+		Type:      MonitorUpperThreshold,
+		Severity:  SeverityHardwareFault,
+		Component: v201.Component{Name: "ChargingStation"},
+		Variable:  v201.Variable{Name: "Temperature"},
+	}}}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var decoded SetVariableMonitoringRequest
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	monitor := decoded.SetMonitoringData[0]
+	if monitor.Id != nil || monitor.Value != 80 || monitor.Type != MonitorUpperThreshold || monitor.Severity != SeverityHardwareFault {
+		t.Errorf("decoded %+v, want a new upper threshold at 80 with severity 1", monitor)
+	}
+}
+
+func TestSetVariableMonitoringRequest_Validate(t *testing.T) {
+	valid := SetMonitoringDataType{
+		Type:      MonitorDelta,
+		Severity:  SeverityWarning,
+		Component: v201.Component{Name: "EVSE"},
+		Variable:  v201.Variable{Name: "Power"},
+	}
+	badType := valid
+	badType.Type = "Sometimes"
+	badSeverity := valid
+	badSeverity.Severity = 10
+	noComponent := valid
+	noComponent.Component.Name = ""
+
+	tests := []struct {
+		name    string
+		data    []SetMonitoringDataType
+		wantErr bool
+	}{
+		{"valid", []SetMonitoringDataType{valid}, false},
+		{"empty", nil, true},
+		{"unknown type", []SetMonitoringDataType{badType}, true},
+		{"severity above 9", []SetMonitoringDataType{badSeverity}, true},
+		{"no component", []SetMonitoringDataType{noComponent}, true},
+	}
+	for _, tt := range tests {
+		err := SetVariableMonitoringRequest{SetMonitoringData: tt.data}.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestClearVariableMonitoringRequest_Validate(t *testing.T) {
+	if err := (ClearVariableMonitoringRequest{Id: []int{1, 2}}).Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	if err := (ClearVariableMonitoringRequest{}).Validate(); err == nil {
+		t.Error("Validate() accepted no ids")
+	}
+}
+
+func TestSetMonitoringBaseRequest_Validate(t *testing.T) {
+	for _, base := range []MonitoringBaseType{MonitoringBaseAll, MonitoringBaseFactoryDefault, MonitoringBaseHardWiredOnly} {
+		if err := (SetMonitoringBaseRequest{MonitoringBase: base}).Validate(); err != nil {
+			t.Errorf("%s: Validate() error = %v", base, err)
+		}
+	}
+	if err := (SetMonitoringBaseRequest{MonitoringBase: "Custom"}).Validate(); err == nil {
+		t.Error("Validate() accepted an unknown base")
+	}
+}
+
+func TestGetMonitoringReportRequest_Validate(t *testing.T) {
+	req := GetMonitoringReportRequest{
+		RequestId:          1,
+		MonitoringCriteria: []MonitoringCriterionType{MonitoringCriterionThreshold},
+		ComponentVariable:  []ComponentVariableType{{Component: v201.Component{Name: "ChargingStation"}}},
+	}
+	if err := req.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	req.ComponentVariable[0].Component.Name = ""
+	if err := req.Validate(); err == nil {
+		t.Error("Validate() accepted a nameless component")
+	}
+}
+
+func TestNotifyMonitoringReportRequest_Validate(t *testing.T) {
+	req := NotifyMonitoringReportRequest{
+		RequestId:   1,
+		GeneratedAt: time.Now(),
+		Monitor: []MonitoringDataType{{
+			Component:          v201.Component{Name: "ChargingStation"},
+			Variable:           v201.Variable{Name: "Temperature"},
+			VariableMonitoring: []VariableMonitoringType{{Id: 3, Value: 80, Type: MonitorUpperThreshold, Severity: 1}},
+		}},
+	}
+	if err := req.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	req.Monitor[0].VariableMonitoring = nil
+	if err := req.Validate(); err == nil {
+		t.Error("Validate() accepted a variable without monitors")
+	}
+}
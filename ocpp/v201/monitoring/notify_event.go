@@ -0,0 +1,142 @@
+package monitoring
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+	"time"
+)
+
+// ============================================================================
+// NotifyEvent - OCPP 2.0.1
+// ============================================================================
+// Sent by: Charging Station → CSMS
+// Purpose: Report that a monitor triggered, or that the charging station
+//          raised a hard-wired notification such as a fault. The actual
+//          value of the variable comes with the event; a later event with
+//          cleared set reports that the condition has gone.
+// ============================================================================
+
+const NotifyEventFeatureName = "NotifyEvent"
+
+// EventTriggerType defines what caused an event
+type EventTriggerType string
+
+const (
+	EventTriggerAlerting EventTriggerType = "Alerting" // A threshold was crossed or a problem was raised
+	EventTriggerDelta    EventTriggerType = "Delta"    // The value changed by more than the delta
+	EventTriggerPeriodic EventTriggerType = "Periodic" // A periodic monitor fired
+)
+
+// EventNotificationType defines the source of an event
+type EventNotificationType string
+
+const (
+	EventNotificationHardWiredNotification EventNotificationType = "HardWiredNotification" // Raised by the firmware itself
+	EventNotificationHardWiredMonitor      EventNotificationType = "HardWiredMonitor"      // A monitor built into the firmware
+	EventNotificationPreconfiguredMonitor  EventNotificationType = "PreconfiguredMonitor"  // A monitor installed by the manufacturer
+	EventNotificationCustomMonitor         EventNotificationType = "CustomMonitor"         // A monitor installed by SetVariableMonitoring
+)
+
+// EventDataType is one reported event
+type EventDataType struct {
+	// EventId identifies the event
+	EventId int `json:"eventId"`
+
+	// Timestamp is when the event occurred
+	Timestamp time.Time `json:"timestamp" validate:"required"`
+
+	// Trigger is what caused the event
+	Trigger EventTriggerType `json:"trigger" validate:"required"`
+
+	// Cause is the eventId of the event that caused this one
+	Cause *int `json:"cause,omitempty"`
+
+	// ActualValue is the value of the variable when the event occurred
+	ActualValue string `json:"actualValue" validate:"required,max=2500"`
+
+	// TechCode is a technical code of the charging station
+	TechCode string `json:"techCode,omitempty" validate:"omitempty,max=50"`
+
+	// TechInfo is technical detail of the charging station
+	TechInfo string `json:"techInfo,omitempty" validate:"omitempty,max=500"`
+
+	// Cleared reports that the condition of an earlier event has gone
+	Cleared bool `json:"cleared,omitempty"`
+
+	// TransactionId is the transaction running when the event occurred
+	TransactionId string `json:"transactionId,omitempty" validate:"omitempty,max=36"`
+
+	// VariableMonitoringId is the monitor that triggered, if any
+	VariableMonitoringId *int `json:"variableMonitoringId,omitempty"`
+
+	// EventNotificationType is the source of the event
+	EventNotificationType EventNotificationType `json:"eventNotificationType" validate:"required"`
+
+	// Component is the component of the variable
+	Component v201.Component `json:"component" validate:"required"`
+
+	// Variable is the variable the event is about
+	Variable v201.Variable `json:"variable" validate:"required"`
+}
+
+// NotifyEventRequest represents the request for NotifyEvent
+type NotifyEventRequest struct {
+	// GeneratedAt is when the message was generated
+	GeneratedAt time.Time `json:"generatedAt" validate:"required"`
+
+	// Tbc indicates more parts follow
+	Tbc bool `json:"tbc,omitempty"`
+
+	// SeqNo is the number of this part, starting at 0
+	SeqNo int `json:"seqNo" validate:"min=0"`
+
+	// EventData contains the events
+	EventData []EventDataType `json:"eventData" validate:"required,min=1,dive"`
+}
+
+// NotifyEventResponse represents the response to NotifyEvent
+type NotifyEventResponse struct {
+	// No fields required - empty response indicates acknowledgment
+}
+
+// GetFeatureName implements common.Request interface
+func (r NotifyEventRequest) GetFeatureName() string {
+	return NotifyEventFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r NotifyEventRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r NotifyEventRequest) Validate() error {
+	if r.SeqNo < 0 {
+		return &ValidationError{Field: "seqNo", Message: "must be >= 0"}
+	}
+	if len(r.EventData) == 0 {
+		return &ValidationError{Field: "eventData", Message: "at least one event required", Err: common.ErrRequired}
+	}
+	for _, event := range r.EventData {
+		if event.Trigger == "" {
+			return &ValidationError{Field: "eventData.trigger", Message: "required", Err: common.ErrRequired}
+		}
+		if event.EventNotificationType == "" {
+			return &ValidationError{Field: "eventData.eventNotificationType", Message: "required", Err: common.ErrRequired}
+		}
+		if event.Component.Name == "" || event.Variable.Name == "" {
+			return &ValidationError{Field: "eventData.component", Message: "component and variable required", Err: common.ErrRequired}
+		}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r NotifyEventResponse) GetFeatureName() string {
+	return NotifyEventFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r NotifyEventResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
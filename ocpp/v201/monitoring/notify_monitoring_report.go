@@ -0,0 +1,107 @@
+package monitoring
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+	"time"
+)
+
+// ============================================================================
+// NotifyMonitoringReport - OCPP 2.0.1
+// ============================================================================
+// Sent by: Charging Station → CSMS
+// Purpose: Report the monitors asked for by GetMonitoringReport, grouped
+//          by component and variable. tbc is set while more parts of the
+//          report are to follow.
+// ============================================================================
+
+const NotifyMonitoringReportFeatureName = "NotifyMonitoringReport"
+
+// VariableMonitoringType is an installed monitor
+type VariableMonitoringType struct {
+	// Id identifies the monitor
+	Id int `json:"id"`
+
+	// Transaction limits the monitor to running transactions
+	Transaction bool `json:"transaction"`
+
+	// Value is the threshold, delta or interval, depending on the type
+	Value float64 `json:"value"`
+
+	// Type is the kind of monitor
+	Type MonitorType `json:"type" validate:"required"`
+
+	// Severity is reported with the events of this monitor (0-9)
+	Severity int `json:"severity" validate:"min=0,max=9"`
+}
+
+// MonitoringDataType holds the monitors of one variable
+type MonitoringDataType struct {
+	// Component is the monitored component
+	Component v201.Component `json:"component" validate:"required"`
+
+	// Variable is the monitored variable
+	Variable v201.Variable `json:"variable" validate:"required"`
+
+	// VariableMonitoring contains the monitors
+	VariableMonitoring []VariableMonitoringType `json:"variableMonitoring" validate:"required,min=1,dive"`
+}
+
+// NotifyMonitoringReportRequest represents the request for NotifyMonitoringReport
+type NotifyMonitoringReportRequest struct {
+	// RequestId is the id of the GetMonitoringReport request being answered
+	RequestId int `json:"requestId"`
+
+	// Tbc indicates more parts follow
+	Tbc bool `json:"tbc,omitempty"`
+
+	// SeqNo is the number of this part, starting at 0
+	SeqNo int `json:"seqNo" validate:"min=0"`
+
+	// GeneratedAt is when the report was generated
+	GeneratedAt time.Time `json:"generatedAt" validate:"required"`
+
+	// Monitor contains the reported monitors
+	Monitor []MonitoringDataType `json:"monitor,omitempty" validate:"omitempty,dive"`
+}
+
+// NotifyMonitoringReportResponse represents the response to NotifyMonitoringReport
+type NotifyMonitoringReportResponse struct {
+	// No fields required - empty response indicates acknowledgment
+}
+
+// GetFeatureName implements common.Request interface
+func (r NotifyMonitoringReportRequest) GetFeatureName() string {
+	return NotifyMonitoringReportFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r NotifyMonitoringReportRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r NotifyMonitoringReportRequest) Validate() error {
+	if r.SeqNo < 0 {
+		return &ValidationError{Field: "seqNo", Message: "must be >= 0"}
+	}
+	if r.GeneratedAt.IsZero() {
+		return &ValidationError{Field: "generatedAt", Message: "required", Err: common.ErrRequired}
+	}
+	for _, monitor := range r.Monitor {
+		if len(monitor.VariableMonitoring) == 0 {
+			return &ValidationError{Field: "monitor.variableMonitoring", Message: "at least one monitor required", Err: common.ErrRequired}
+		}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r NotifyMonitoringReportResponse) GetFeatureName() string {
+	return NotifyMonitoringReportFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r NotifyMonitoringReportResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
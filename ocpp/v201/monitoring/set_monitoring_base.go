@@ -0,0 +1,71 @@
+package monitoring
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+	"evsys/ocpp/v201/provisioning"
+)
+
+// ============================================================================
+// SetMonitoringBase - OCPP 2.0.1
+// ============================================================================
+// Sent by: CSMS → Charging Station
+// Purpose: Choose which set of monitors is active: all of them, the
+//          factory defaults, or only the hard-wired ones. Custom monitors
+//          are removed by anything but All.
+// ============================================================================
+
+const SetMonitoringBaseFeatureName = "SetMonitoringBase"
+
+// MonitoringBaseType defines the set of active monitors
+type MonitoringBaseType string
+
+const (
+	MonitoringBaseAll            MonitoringBaseType = "All"            // Every monitor
+	MonitoringBaseFactoryDefault MonitoringBaseType = "FactoryDefault" // Preconfigured monitors only
+	MonitoringBaseHardWiredOnly  MonitoringBaseType = "HardWiredOnly"  // Hard-wired monitors only
+)
+
+// SetMonitoringBaseRequest represents the request for SetMonitoringBase
+type SetMonitoringBaseRequest struct {
+	// MonitoringBase is the set of monitors to activate
+	MonitoringBase MonitoringBaseType `json:"monitoringBase" validate:"required"`
+}
+
+// SetMonitoringBaseResponse represents the response to SetMonitoringBase
+type SetMonitoringBaseResponse struct {
+	// Status indicates whether the base was activated
+	Status provisioning.GenericDeviceModelStatusType `json:"status" validate:"required"`
+
+	// StatusInfo provides additional status information
+	StatusInfo *v201.StatusInfo `json:"statusInfo,omitempty"`
+}
+
+// GetFeatureName implements common.Request interface
+func (r SetMonitoringBaseRequest) GetFeatureName() string {
+	return SetMonitoringBaseFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r SetMonitoringBaseRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r SetMonitoringBaseRequest) Validate() error {
+	switch r.MonitoringBase {
+	case MonitoringBaseAll, MonitoringBaseFactoryDefault, MonitoringBaseHardWiredOnly:
+		return nil
+	}
+	return &ValidationError{Field: "monitoringBase", Message: "must be All, FactoryDefault or HardWiredOnly"}
+}
+
+// GetFeatureName implements common.Response interface
+func (r SetMonitoringBaseResponse) GetFeatureName() string {
+	return SetMonitoringBaseFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r SetMonitoringBaseResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
@@ -0,0 +1,173 @@
+package monitoring
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+)
+
+// ============================================================================
+// SetVariableMonitoring - OCPP 2.0.1
+// ============================================================================
+// Sent by: CSMS → Charging Station
+// Purpose: Install monitors on device model variables. A triggered monitor
+//          is reported in NotifyEvent with its id, so the severity set here
+//          tells how serious the event is.
+// ============================================================================
+
+const SetVariableMonitoringFeatureName = "SetVariableMonitoring"
+
+// MonitorType defines what makes a monitor trigger
+type MonitorType string
+
+const (
+	MonitorUpperThreshold       MonitorType = "UpperThreshold"       // Value rises above the threshold
+	MonitorLowerThreshold       MonitorType = "LowerThreshold"       // Value drops below the threshold
+	MonitorDelta                MonitorType = "Delta"                // Value changes by more than the delta
+	MonitorPeriodic             MonitorType = "Periodic"             // Every value seconds
+	MonitorPeriodicClockAligned MonitorType = "PeriodicClockAligned" // Every value seconds, aligned to the clock
+)
+
+// Severity levels of a monitor; lower is more severe
+const (
+	SeverityDanger        = 0 // Danger to people or equipment
+	SeverityHardwareFault = 1 // Hardware failure
+	SeveritySystemFailure = 2 // System failure
+	SeverityCritical      = 3 // Critical error
+	SeverityError         = 4 // Non-urgent error
+	SeverityAlert         = 5 // Alert that needs attention
+	SeverityWarning       = 6 // Warning
+	SeverityNotice        = 7 // Unusual event
+	SeverityInformational = 8 // Regular operation
+	SeverityDebug         = 9 // Debugging information
+)
+
+// SetMonitoringStatusType defines the result of installing a monitor
+type SetMonitoringStatusType string
+
+const (
+	SetMonitoringStatusAccepted               SetMonitoringStatusType = "Accepted"               // Monitor installed
+	SetMonitoringStatusUnknownComponent       SetMonitoringStatusType = "UnknownComponent"       // Component unknown
+	SetMonitoringStatusUnknownVariable        SetMonitoringStatusType = "UnknownVariable"        // Variable unknown
+	SetMonitoringStatusUnsupportedMonitorType SetMonitoringStatusType = "UnsupportedMonitorType" // Monitor type not supported
+	SetMonitoringStatusRejected               SetMonitoringStatusType = "Rejected"               // Monitor refused
+	SetMonitoringStatusDuplicate              SetMonitoringStatusType = "Duplicate"              // Same monitor already installed
+)
+
+// SetMonitoringDataType describes a monitor to install
+type SetMonitoringDataType struct {
+	// Id replaces an existing monitor; empty installs a new one
+	Id *int `json:"id,omitempty"`
+
+	// Transaction limits the monitor to running transactions
+	Transaction bool `json:"transaction,omitempty"`
+
+	// Value is the threshold, delta or interval, depending on the type
+	Value float64 `json:"value"`
+
+	// Type is the kind of monitor
+	Type MonitorType `json:"type" validate:"required"`
+
+	// Severity is reported with the events of this monitor (0-9)
+	Severity int `json:"severity" validate:"min=0,max=9"`
+
+	// Component is the monitored component
+	Component v201.Component `json:"component" validate:"required"`
+
+	// Variable is the monitored variable
+	Variable v201.Variable `json:"variable" validate:"required"`
+}
+
+// SetVariableMonitoringRequest represents the request for SetVariableMonitoring
+type SetVariableMonitoringRequest struct {
+	// SetMonitoringData contains the monitors to install
+	SetMonitoringData []SetMonitoringDataType `json:"setMonitoringData" validate:"required,min=1,dive"`
+}
+
+// SetMonitoringResultType is the result for one monitor
+type SetMonitoringResultType struct {
+	// Id is the id the charging station gave the monitor
+	Id *int `json:"id,omitempty"`
+
+	// Status indicates whether the monitor was installed
+	Status SetMonitoringStatusType `json:"status" validate:"required"`
+
+	// Type is the kind of monitor
+	Type MonitorType `json:"type" validate:"required"`
+
+	// Severity is the severity of the monitor
+	Severity int `json:"severity"`
+
+	// Component is the monitored component
+	Component v201.Component `json:"component" validate:"required"`
+
+	// Variable is the monitored variable
+	Variable v201.Variable `json:"variable" validate:"required"`
+
+	// StatusInfo provides additional status information
+	StatusInfo *v201.StatusInfo `json:"statusInfo,omitempty"`
+}
+
+// SetVariableMonitoringResponse represents the response to SetVariableMonitoring
+type SetVariableMonitoringResponse struct {
+	// SetMonitoringResult contains one result per requested monitor
+	SetMonitoringResult []SetMonitoringResultType `json:"setMonitoringResult" validate:"required,min=1,dive"`
+}
+
+// GetFeatureName implements common.Request interface
+func (r SetVariableMonitoringRequest) GetFeatureName() string {
+	return SetVariableMonitoringFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r SetVariableMonitoringRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r SetVariableMonitoringRequest) Validate() error {
+	if len(r.SetMonitoringData) == 0 {
+		return &ValidationError{Field: "setMonitoringData", Message: "at least one monitor required", Err: common.ErrRequired}
+	}
+	for _, data := range r.SetMonitoringData {
+		switch data.Type {
+		case MonitorUpperThreshold, MonitorLowerThreshold, MonitorDelta, MonitorPeriodic, MonitorPeriodicClockAligned:
+		default:
+			return &ValidationError{Field: "setMonitoringData.type", Message: "unknown monitor type"}
+		}
+		if data.Severity < SeverityDanger || data.Severity > SeverityDebug {
+			return &ValidationError{Field: "setMonitoringData.severity", Message: "must be 0 to 9"}
+		}
+		if data.Component.Name == "" {
+			return &ValidationError{Field: "setMonitoringData.component.name", Message: "required", Err: common.ErrRequired}
+		}
+		if data.Variable.Name == "" {
+			return &ValidationError{Field: "setMonitoringData.variable.name", Message: "required", Err: common.ErrRequired}
+		}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r SetVariableMonitoringResponse) GetFeatureName() string {
+	return SetVariableMonitoringFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r SetVariableMonitoringResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// ValidationError represents a validation error
+type ValidationError struct {
+	Field   string
+	Message string
+	Err     error // common.ErrRequired for a missing field
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
@@ -53,14 +53,20 @@ func (r BootNotificationRequest) GetProtocolVersion() common.ProtocolVersion {
 
 // Validate implements common.Request interface
 func (r BootNotificationRequest) Validate() error {
-	if r.ChargingStation.VendorName == "" || len(r.ChargingStation.VendorName) > 50 {
-		return &ValidationError{Field: "chargingStation.vendorName", Message: "required, max 50 characters"}
+	if r.ChargingStation.VendorName == "" {
+		return &ValidationError{Field: "chargingStation.vendorName", Message: "required", Err: common.ErrRequired}
 	}
-	if r.ChargingStation.Model == "" || len(r.ChargingStation.Model) > 20 {
-		return &ValidationError{Field: "chargingStation.model", Message: "required, max 20 characters"}
+	if len(r.ChargingStation.VendorName) > 50 {
+		return &ValidationError{Field: "chargingStation.vendorName", Message: "max 50 characters"}
+	}
+	if r.ChargingStation.Model == "" {
+		return &ValidationError{Field: "chargingStation.model", Message: "required", Err: common.ErrRequired}
+	}
+	if len(r.ChargingStation.Model) > 20 {
+		return &ValidationError{Field: "chargingStation.model", Message: "max 20 characters"}
 	}
 	if r.Reason == "" {
-		return &ValidationError{Field: "reason", Message: "required"}
+		return &ValidationError{Field: "reason", Message: "required", Err: common.ErrRequired}
 	}
 	return nil
 }
@@ -79,8 +85,13 @@ func (r BootNotificationResponse) GetProtocolVersion() common.ProtocolVersion {
 type ValidationError struct {
 	Field   string
 	Message string
+	Err     error // common.ErrRequired for a missing field
 }
 
 func (e *ValidationError) Error() string {
 	return e.Field + ": " + e.Message
 }
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
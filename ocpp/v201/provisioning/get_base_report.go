@@ -56,7 +56,7 @@ func (r GetBaseReportRequest) GetProtocolVersion() common.ProtocolVersion {
 // Validate implements common.Request interface
 func (r GetBaseReportRequest) Validate() error {
 	if r.ReportBase == "" {
-		return &ValidationError{Field: "reportBase", Message: "required"}
+		return &ValidationError{Field: "reportBase", Message: "required", Err: common.ErrRequired}
 	}
 	return nil
 }
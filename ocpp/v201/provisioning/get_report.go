@@ -0,0 +1,104 @@
+package provisioning
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+)
+
+// ============================================================================
+// GetReport - OCPP 2.0.1
+// ============================================================================
+// Sent by: CSMS → Charging Station
+// Purpose: Ask for a part of the device model, selected by component
+//          criteria and by component and variable. The variables follow in
+//          one or more NotifyReport messages carrying the same requestId.
+// ============================================================================
+
+const GetReportFeatureName = "GetReport"
+
+// ComponentCriterionType selects components by their state
+type ComponentCriterionType string
+
+const (
+	ComponentCriterionActive    ComponentCriterionType = "Active"    // Components that are active
+	ComponentCriterionAvailable ComponentCriterionType = "Available" // Components that are available
+	ComponentCriterionEnabled   ComponentCriterionType = "Enabled"   // Components that are enabled
+	ComponentCriterionProblem   ComponentCriterionType = "Problem"   // Components that report a problem
+)
+
+// IsValid checks if the component criterion is valid
+func (c ComponentCriterionType) IsValid() bool {
+	switch c {
+	case ComponentCriterionActive, ComponentCriterionAvailable, ComponentCriterionEnabled, ComponentCriterionProblem:
+		return true
+	}
+	return false
+}
+
+// ComponentVariableType names a component and, optionally, one of its variables
+type ComponentVariableType struct {
+	// Component is the component
+	Component v201.Component `json:"component" validate:"required"`
+
+	// Variable is the variable; empty means every variable of the component
+	Variable *v201.Variable `json:"variable,omitempty"`
+}
+
+// GetReportRequest represents the request for GetReport
+type GetReportRequest struct {
+	// RequestId links the NotifyReport messages to this request
+	RequestId int `json:"requestId"`
+
+	// ComponentCriteria selects components by their state
+	ComponentCriteria []ComponentCriterionType `json:"componentCriteria,omitempty" validate:"omitempty,max=4"`
+
+	// ComponentVariable selects variables by component and variable
+	ComponentVariable []ComponentVariableType `json:"componentVariable,omitempty" validate:"omitempty,dive"`
+}
+
+// GetReportResponse represents the response to GetReport
+type GetReportResponse struct {
+	// Status indicates whether the report will be sent
+	Status GenericDeviceModelStatusType `json:"status" validate:"required"`
+
+	// StatusInfo provides additional status information
+	StatusInfo *v201.StatusInfo `json:"statusInfo,omitempty"`
+}
+
+// GetFeatureName implements common.Request interface
+func (r GetReportRequest) GetFeatureName() string {
+	return GetReportFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r GetReportRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r GetReportRequest) Validate() error {
+	if len(r.ComponentCriteria) > 4 {
+		return &ValidationError{Field: "componentCriteria", Message: "max 4 criteria"}
+	}
+	for _, criterion := range r.ComponentCriteria {
+		if !criterion.IsValid() {
+			return &ValidationError{Field: "componentCriteria", Message: "invalid criterion " + string(criterion)}
+		}
+	}
+	for _, componentVariable := range r.ComponentVariable {
+		if componentVariable.Component.Name == "" {
+			return &ValidationError{Field: "componentVariable.component.name", Message: "required", Err: common.ErrRequired}
+		}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r GetReportResponse) GetFeatureName() string {
+	return GetReportFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r GetReportResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
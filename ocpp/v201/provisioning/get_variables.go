@@ -85,7 +85,7 @@ func (r GetVariablesRequest) GetProtocolVersion() common.ProtocolVersion {
 // Validate implements common.Request interface
 func (r GetVariablesRequest) Validate() error {
 	if len(r.GetVariableData) == 0 {
-		return &ValidationError{Field: "getVariableData", Message: "at least one variable required"}
+		return &ValidationError{Field: "getVariableData", Message: "at least one variable required", Err: common.ErrRequired}
 	}
 	return nil
 }
@@ -10,7 +10,7 @@ import (
 // ============================================================================
 // OCPP 2.0.1 Provisioning Messages Tests
 // ============================================================================
-// Tests for BootNotification, Heartbeat, NotifyReport, etc.
+// Tests for BootNotification, Heartbeat, NotifyReport, GetReport, etc.
 // ============================================================================
 
 func TestBootNotificationRequest_Serialization(t *testing.T) {
@@ -251,6 +251,54 @@ func TestGetBaseReportRequest_GetFeatureName(t *testing.T) {
 	}
 }
 
+func TestGetReportRequest_Serialization(t *testing.T) {
+	req := GetReportRequest{
+		RequestId:         457,
+		ComponentCriteria: []ComponentCriterionType{ComponentCriterionProblem},
+		ComponentVariable: []ComponentVariableType{{
+			Component: v201.Component{Name: "EVSE", Evse: &v201.EVSE{Id: 1}},
+			Variable:  &v201.Variable{Name: "AvailabilityState"},
+		}},
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	var decoded GetReportRequest
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if decoded.RequestId != req.RequestId || len(decoded.ComponentVariable) != 1 {
+		t.Fatalf("decoded %+v, want %+v", decoded, req)
+	}
+	if decoded.ComponentVariable[0].Variable == nil || decoded.ComponentVariable[0].Variable.Name != "AvailabilityState" {
+		t.Errorf("Variable = %+v, want AvailabilityState", decoded.ComponentVariable[0].Variable)
+	}
+}
+
+func TestGetReportRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     GetReportRequest
+		wantErr bool
+	}{
+		{"everything", GetReportRequest{RequestId: 1}, false},
+		{"criteria", GetReportRequest{RequestId: 1, ComponentCriteria: []ComponentCriterionType{ComponentCriterionActive, ComponentCriterionEnabled}}, false},
+		{"unknown criterion", GetReportRequest{RequestId: 1, ComponentCriteria: []ComponentCriterionType{"Broken"}}, true},
+		{"component without name", GetReportRequest{RequestId: 1, ComponentVariable: []ComponentVariableType{{}}}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.req.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+	if (GetReportRequest{}).GetFeatureName() != GetReportFeatureName {
+		t.Errorf("GetFeatureName() = %v, want %v", GetReportRequest{}.GetFeatureName(), GetReportFeatureName)
+	}
+}
+
 func TestResetRequest_Serialization(t *testing.T) {
 	evseId := 1
 	req := ResetRequest{
@@ -63,7 +63,7 @@ func (r ResetRequest) GetProtocolVersion() common.ProtocolVersion {
 // Validate implements common.Request interface
 func (r ResetRequest) Validate() error {
 	if r.Type == "" {
-		return &ValidationError{Field: "type", Message: "required"}
+		return &ValidationError{Field: "type", Message: "required", Err: common.ErrRequired}
 	}
 	if r.EvseId != nil && *r.EvseId < 1 {
 		return &ValidationError{Field: "evseId", Message: "must be >= 1"}
@@ -86,11 +86,11 @@ func (r SetVariablesRequest) GetProtocolVersion() common.ProtocolVersion {
 // Validate implements common.Request interface
 func (r SetVariablesRequest) Validate() error {
 	if len(r.SetVariableData) == 0 {
-		return &ValidationError{Field: "setVariableData", Message: "at least one variable required"}
+		return &ValidationError{Field: "setVariableData", Message: "at least one variable required", Err: common.ErrRequired}
 	}
 	for i, data := range r.SetVariableData {
 		if data.AttributeValue == "" {
-			return &ValidationError{Field: "setVariableData[" + string(rune(i)) + "].attributeValue", Message: "required"}
+			return &ValidationError{Field: "setVariableData[" + string(rune(i)) + "].attributeValue", Message: "required", Err: common.ErrRequired}
 		}
 	}
 	return nil
@@ -70,7 +70,7 @@ func (r RequestStartTransactionRequest) Validate() error {
 		return err
 	}
 	if r.RemoteStartId == 0 {
-		return &ValidationError{Field: "remoteStartId", Message: "required"}
+		return &ValidationError{Field: "remoteStartId", Message: "required", Err: common.ErrRequired}
 	}
 	if r.EvseId != nil && *r.EvseId < 1 {
 		return &ValidationError{Field: "evseId", Message: "must be >= 1"}
@@ -97,8 +97,13 @@ func (r RequestStartTransactionResponse) GetProtocolVersion() common.ProtocolVer
 type ValidationError struct {
 	Field   string
 	Message string
+	Err     error // common.ErrRequired for a missing field
 }
 
 func (e *ValidationError) Error() string {
 	return e.Field + ": " + e.Message
 }
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
@@ -44,7 +44,7 @@ func (r RequestStopTransactionRequest) GetProtocolVersion() common.ProtocolVersi
 // Validate implements common.Request interface
 func (r RequestStopTransactionRequest) Validate() error {
 	if r.TransactionId == "" {
-		return &ValidationError{Field: "transactionId", Message: "required"}
+		return &ValidationError{Field: "transactionId", Message: "required", Err: common.ErrRequired}
 	}
 	if len(r.TransactionId) > 36 {
 		return &ValidationError{Field: "transactionId", Message: "max 36 characters"}
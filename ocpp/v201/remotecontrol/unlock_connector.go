@@ -0,0 +1,76 @@
+package remotecontrol
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+)
+
+// ============================================================================
+// UnlockConnector - OCPP 2.0.1
+// ============================================================================
+// Sent by: CSMS → Charging Station
+// Purpose: Release the cable of a connector, e.g. when a driver cannot
+//          unplug. A station refuses while an authorized transaction runs on
+//          the connector; that transaction has to be stopped first.
+// ============================================================================
+
+const UnlockConnectorFeatureName = "UnlockConnector"
+
+// UnlockStatusType defines the response to an UnlockConnector request
+type UnlockStatusType string
+
+const (
+	UnlockStatusUnlocked                     UnlockStatusType = "Unlocked"                     // Connector unlocked
+	UnlockStatusUnlockFailed                 UnlockStatusType = "UnlockFailed"                 // Unlocking failed
+	UnlockStatusOngoingAuthorizedTransaction UnlockStatusType = "OngoingAuthorizedTransaction" // Transaction running
+	UnlockStatusUnknownConnector             UnlockStatusType = "UnknownConnector"             // No such connector
+)
+
+// UnlockConnectorRequest represents the request for UnlockConnector
+type UnlockConnectorRequest struct {
+	// EvseId is the EVSE of the connector
+	EvseId int `json:"evseId" validate:"required"`
+
+	// ConnectorId is the connector to unlock within the EVSE
+	ConnectorId int `json:"connectorId" validate:"required"`
+}
+
+// UnlockConnectorResponse represents the response to UnlockConnector
+type UnlockConnectorResponse struct {
+	// Status indicates whether the connector was unlocked
+	Status UnlockStatusType `json:"status" validate:"required"`
+
+	// StatusInfo provides additional status information
+	StatusInfo *v201.StatusInfo `json:"statusInfo,omitempty"`
+}
+
+// GetFeatureName implements common.Request interface
+func (r UnlockConnectorRequest) GetFeatureName() string {
+	return UnlockConnectorFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r UnlockConnectorRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r UnlockConnectorRequest) Validate() error {
+	if r.EvseId < 1 {
+		return &ValidationError{Field: "evseId", Message: "must be >= 1"}
+	}
+	if r.ConnectorId < 1 {
+		return &ValidationError{Field: "connectorId", Message: "must be >= 1"}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r UnlockConnectorResponse) GetFeatureName() string {
+	return UnlockConnectorFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r UnlockConnectorResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
@@ -0,0 +1,63 @@
+package reservation
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+)
+
+// ============================================================================
+// CancelReservation - OCPP 2.0.1
+// ============================================================================
+// Sent by: CSMS → Charging Station
+// Purpose: Cancel a reservation made with ReserveNow.
+// ============================================================================
+
+const CancelReservationFeatureName = "CancelReservation"
+
+// CancelReservationStatusType defines the response to a CancelReservation request
+type CancelReservationStatusType string
+
+const (
+	CancelReservationStatusAccepted CancelReservationStatusType = "Accepted" // Reservation cancelled
+	CancelReservationStatusRejected CancelReservationStatusType = "Rejected" // No such reservation
+)
+
+// CancelReservationRequest represents the request for CancelReservation
+type CancelReservationRequest struct {
+	// ReservationId is the reservation to cancel
+	ReservationId int `json:"reservationId" validate:"required"`
+}
+
+// CancelReservationResponse represents the response to CancelReservation
+type CancelReservationResponse struct {
+	// Status indicates whether the reservation was cancelled
+	Status CancelReservationStatusType `json:"status" validate:"required"`
+
+	// StatusInfo provides additional status information
+	StatusInfo *v201.StatusInfo `json:"statusInfo,omitempty"`
+}
+
+// GetFeatureName implements common.Request interface
+func (r CancelReservationRequest) GetFeatureName() string {
+	return CancelReservationFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r CancelReservationRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r CancelReservationRequest) Validate() error {
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r CancelReservationResponse) GetFeatureName() string {
+	return CancelReservationFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r CancelReservationResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
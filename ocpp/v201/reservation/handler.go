@@ -0,0 +1,15 @@
+package reservation
+
+// ============================================================================
+// Reservation Handler Interface - OCPP 2.0.1
+// ============================================================================
+// This interface defines the methods that must be implemented to handle
+// reservation messages from charging stations.
+// ============================================================================
+
+// Handler defines the interface for handling reservation messages
+type Handler interface {
+	// OnReservationStatusUpdate handles incoming ReservationStatusUpdate requests
+	// Called when a reservation expired or was removed on the charging station
+	OnReservationStatusUpdate(chargePointId string, request *ReservationStatusUpdateRequest) (*ReservationStatusUpdateResponse, error)
+}
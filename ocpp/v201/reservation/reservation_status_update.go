@@ -0,0 +1,65 @@
+package reservation
+
+import (
+	"evsys/ocpp/common"
+)
+
+// ============================================================================
+// ReservationStatusUpdate - OCPP 2.0.1
+// ============================================================================
+// Sent by: Charging Station → CSMS
+// Purpose: Report that a reservation ended without being used: it expired,
+//          or the station removed it, e.g. because its EVSE became
+//          unavailable.
+// ============================================================================
+
+const ReservationStatusUpdateFeatureName = "ReservationStatusUpdate"
+
+// ReservationUpdateStatusType defines how a reservation ended
+type ReservationUpdateStatusType string
+
+const (
+	ReservationUpdateStatusExpired ReservationUpdateStatusType = "Expired" // Reservation ran past its expiry date
+	ReservationUpdateStatusRemoved ReservationUpdateStatusType = "Removed" // Reservation removed by the station
+)
+
+// ReservationStatusUpdateRequest represents the request for ReservationStatusUpdate
+type ReservationStatusUpdateRequest struct {
+	// ReservationId is the reservation that ended
+	ReservationId int `json:"reservationId" validate:"required"`
+
+	// ReservationUpdateStatus is how it ended
+	ReservationUpdateStatus ReservationUpdateStatusType `json:"reservationUpdateStatus" validate:"required"`
+}
+
+// ReservationStatusUpdateResponse represents the response to ReservationStatusUpdate
+type ReservationStatusUpdateResponse struct{}
+
+// GetFeatureName implements common.Request interface
+func (r ReservationStatusUpdateRequest) GetFeatureName() string {
+	return ReservationStatusUpdateFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r ReservationStatusUpdateRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r ReservationStatusUpdateRequest) Validate() error {
+	switch r.ReservationUpdateStatus {
+	case ReservationUpdateStatusExpired, ReservationUpdateStatusRemoved:
+		return nil
+	}
+	return &ValidationError{Field: "reservationUpdateStatus", Message: "must be Expired or Removed"}
+}
+
+// GetFeatureName implements common.Response interface
+func (r ReservationStatusUpdateResponse) GetFeatureName() string {
+	return ReservationStatusUpdateFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r ReservationStatusUpdateResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
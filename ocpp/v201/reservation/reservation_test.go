@@ -0,0 +1,98 @@
+package reservation
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"evsys/ocpp/v201"
+)
+
+// ============================================================================
+// OCPP 2.0.1 Reservation Messages Tests
+// ============================================================================
+// Tests for ReserveNow, CancelReservation and ReservationStatusUpdate
+// ============================================================================
+
+func TestReserveNowRequest_Serialization(t *testing.T) {
+	expiry := time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC)
+	req := ReserveNowRequest{
+		Id:             12,
+		ExpiryDateTime: expiry,
+		ConnectorType:  ConnectorTypeCCS2,
+		IdToken:        v201.IdToken{IdToken: "TAG-1", Type: v201.IdTokenTypeISO14443},
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(data), `"connectorType":"cCCS2"`) || strings.Contains(string(data), "evseId") {
+		t.Errorf("serialized %s, want the connector type and no EVSE", data)
+	}
+
+	var decoded ReserveNowRequest
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if decoded.Id != 12 || !decoded.ExpiryDateTime.Equal(expiry) || decoded.IdToken.IdToken != "TAG-1" {
+		t.Errorf("decoded %+v, want reservation 12 for TAG-1 until %s", decoded, expiry)
+	}
+	if err = decoded.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
+
+func TestReserveNowRequest_Validate(t *testing.T) {
+	valid := func() ReserveNowRequest {
+		return ReserveNowRequest{
+			Id:             1,
+			ExpiryDateTime: time.Now().Add(time.Hour),
+			IdToken:        v201.IdToken{IdToken: "TAG-1", Type: v201.IdTokenTypeISO14443},
+		}
+	}
+	zero := 0
+
+	noExpiry := valid()
+	noExpiry.ExpiryDateTime = time.Time{}
+	badType := valid()
+	badType.ConnectorType = "Type9"
+	badEvse := valid()
+	badEvse.EvseId = &zero
+	noToken := valid()
+	noToken.IdToken = v201.IdToken{}
+
+	tests := []struct {
+		name    string
+		request ReserveNowRequest
+		wantErr bool
+	}{
+		{"valid", valid(), false},
+		{"no expiry", noExpiry, true},
+		{"unknown connector type", badType, true},
+		{"evse zero", badEvse, true},
+		{"no id token", noToken, true},
+	}
+	for _, tt := range tests {
+		if err := tt.request.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestReservationStatusUpdateRequest_Validate(t *testing.T) {
+	var req ReservationStatusUpdateRequest
+	if err := json.Unmarshal([]byte(`{"reservationId":5,"reservationUpdateStatus":"Removed"}`), &req); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if req.ReservationId != 5 || req.ReservationUpdateStatus != ReservationUpdateStatusRemoved {
+		t.Errorf("decoded %+v, want reservation 5 removed", req)
+	}
+	if err := req.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	if err := (ReservationStatusUpdateRequest{ReservationId: 5, ReservationUpdateStatus: "Used"}).Validate(); err == nil {
+		t.Error("Validate() accepted an unknown status")
+	}
+}
@@ -0,0 +1,157 @@
+package reservation
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+	"time"
+)
+
+// ============================================================================
+// ReserveNow - OCPP 2.0.1
+// ============================================================================
+// Sent by: CSMS → Charging Station
+// Purpose: Reserve an EVSE for an id token until an expiry date. Without an
+//          evseId the station keeps any EVSE free, of the connector type when
+//          one is given.
+// ============================================================================
+
+const ReserveNowFeatureName = "ReserveNow"
+
+// ConnectorType defines the connector types a reservation can ask for
+type ConnectorType string
+
+const (
+	ConnectorTypeCCS1         ConnectorType = "cCCS1"       // Combined Charging System 1
+	ConnectorTypeCCS2         ConnectorType = "cCCS2"       // Combined Charging System 2
+	ConnectorTypeG105         ConnectorType = "cG105"       // CHAdeMO
+	ConnectorTypeTesla        ConnectorType = "cTesla"      // Tesla connector
+	ConnectorTypeType1        ConnectorType = "cType1"      // IEC 62196-2 Type 1 cable
+	ConnectorTypeType2        ConnectorType = "cType2"      // IEC 62196-2 Type 2 cable
+	ConnectorTypeS309P116A    ConnectorType = "s309-1P-16A" // IEC 60309 single phase 16 A
+	ConnectorTypeS309P132A    ConnectorType = "s309-1P-32A" // IEC 60309 single phase 32 A
+	ConnectorTypeS309P316A    ConnectorType = "s309-3P-16A" // IEC 60309 three phase 16 A
+	ConnectorTypeS309P332A    ConnectorType = "s309-3P-32A" // IEC 60309 three phase 32 A
+	ConnectorTypeBS1361       ConnectorType = "sBS1361"     // UK domestic socket
+	ConnectorTypeCEE77        ConnectorType = "sCEE-7-7"    // Schuko
+	ConnectorTypeSType2       ConnectorType = "sType2"      // IEC 62196-2 Type 2 socket
+	ConnectorTypeSType3       ConnectorType = "sType3"      // IEC 62196-2 Type 3 socket
+	ConnectorTypeOther1PhMax  ConnectorType = "Other1PhMax16A"
+	ConnectorTypeOther1PhOve  ConnectorType = "Other1PhOver16A"
+	ConnectorTypeOther3Ph     ConnectorType = "Other3Ph"
+	ConnectorTypePan          ConnectorType = "Pan"          // Pantograph
+	ConnectorTypeInductive    ConnectorType = "wInductive"   // Wireless, inductive
+	ConnectorTypeResonant     ConnectorType = "wResonant"    // Wireless, resonant
+	ConnectorTypeUndetermined ConnectorType = "Undetermined" // Not yet known
+	ConnectorTypeUnknown      ConnectorType = "Unknown"      // Unknown
+)
+
+// IsValid reports whether the connector type is one of OCPP 2.0.1
+func (t ConnectorType) IsValid() bool {
+	switch t {
+	case ConnectorTypeCCS1, ConnectorTypeCCS2, ConnectorTypeG105, ConnectorTypeTesla, ConnectorTypeType1,
+		ConnectorTypeType2, ConnectorTypeS309P116A, ConnectorTypeS309P132A, ConnectorTypeS309P316A,
+		ConnectorTypeS309P332A, ConnectorTypeBS1361, ConnectorTypeCEE77, ConnectorTypeSType2, ConnectorTypeSType3,
+		ConnectorTypeOther1PhMax, ConnectorTypeOther1PhOve, ConnectorTypeOther3Ph, ConnectorTypePan,
+		ConnectorTypeInductive, ConnectorTypeResonant, ConnectorTypeUndetermined, ConnectorTypeUnknown:
+		return true
+	}
+	return false
+}
+
+// ReserveNowStatusType defines the response to a ReserveNow request
+type ReserveNowStatusType string
+
+const (
+	ReserveNowStatusAccepted    ReserveNowStatusType = "Accepted"    // Reservation made
+	ReserveNowStatusFaulted     ReserveNowStatusType = "Faulted"     // EVSE faulted
+	ReserveNowStatusOccupied    ReserveNowStatusType = "Occupied"    // EVSE occupied or reserved
+	ReserveNowStatusRejected    ReserveNowStatusType = "Rejected"    // Reservations not accepted
+	ReserveNowStatusUnavailable ReserveNowStatusType = "Unavailable" // EVSE unavailable
+)
+
+// ReserveNowRequest represents the request for ReserveNow
+type ReserveNowRequest struct {
+	// Id of the reservation
+	Id int `json:"id" validate:"required"`
+
+	// ExpiryDateTime is when the reservation ends
+	ExpiryDateTime time.Time `json:"expiryDateTime" validate:"required"`
+
+	// ConnectorType is the connector type the EVSE must have (optional)
+	ConnectorType ConnectorType `json:"connectorType,omitempty"`
+
+	// IdToken is who the reservation is for
+	IdToken v201.IdToken `json:"idToken" validate:"required"`
+
+	// EvseId is the reserved EVSE; any EVSE when omitted
+	EvseId *int `json:"evseId,omitempty"`
+
+	// GroupIdToken is a group whose members may use the reservation too (optional)
+	GroupIdToken *v201.IdToken `json:"groupIdToken,omitempty"`
+}
+
+// ReserveNowResponse represents the response to ReserveNow
+type ReserveNowResponse struct {
+	// Status indicates whether the reservation was made
+	Status ReserveNowStatusType `json:"status" validate:"required"`
+
+	// StatusInfo provides additional status information
+	StatusInfo *v201.StatusInfo `json:"statusInfo,omitempty"`
+}
+
+// GetFeatureName implements common.Request interface
+func (r ReserveNowRequest) GetFeatureName() string {
+	return ReserveNowFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r ReserveNowRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r ReserveNowRequest) Validate() error {
+	if r.ExpiryDateTime.IsZero() {
+		return &ValidationError{Field: "expiryDateTime", Message: "required", Err: common.ErrRequired}
+	}
+	if r.ConnectorType != "" && !r.ConnectorType.IsValid() {
+		return &ValidationError{Field: "connectorType", Message: "unknown connector type"}
+	}
+	if err := r.IdToken.Validate(); err != nil {
+		return &ValidationError{Field: "idToken", Message: err.Error()}
+	}
+	if r.EvseId != nil && *r.EvseId < 1 {
+		return &ValidationError{Field: "evseId", Message: "must be >= 1"}
+	}
+	if r.GroupIdToken != nil {
+		if err := r.GroupIdToken.Validate(); err != nil {
+			return &ValidationError{Field: "groupIdToken", Message: err.Error()}
+		}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r ReserveNowResponse) GetFeatureName() string {
+	return ReserveNowFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r ReserveNowResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// ValidationError represents a validation error
+type ValidationError struct {
+	Field   string
+	Message string
+	Err     error // common.ErrRequired for a missing field
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
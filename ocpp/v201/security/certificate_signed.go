@@ -0,0 +1,73 @@
+package security
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+)
+
+// ============================================================================
+// CertificateSigned - OCPP 2.0.1
+// ============================================================================
+// Sent by: CSMS → Charging Station
+// Purpose: Deliver the certificate chain signed for a SignCertificate
+//          request; the charging station installs it for the use it asked for.
+// ============================================================================
+
+const CertificateSignedFeatureName = "CertificateSigned"
+
+// CertificateSignedStatusType defines the response to a CertificateSigned request
+type CertificateSignedStatusType string
+
+const (
+	CertificateSignedStatusAccepted CertificateSignedStatusType = "Accepted" // Certificate installed
+	CertificateSignedStatusRejected CertificateSignedStatusType = "Rejected" // Certificate refused
+)
+
+// CertificateSignedRequest represents the request for CertificateSigned
+type CertificateSignedRequest struct {
+	// CertificateChain is the PEM chain, the certificate first
+	CertificateChain string `json:"certificateChain" validate:"required,max=10000"`
+
+	// CertificateType is what the certificate is for
+	CertificateType CertificateSigningUseType `json:"certificateType,omitempty"`
+}
+
+// CertificateSignedResponse represents the response to CertificateSigned
+type CertificateSignedResponse struct {
+	// Status indicates whether the certificate was installed
+	Status CertificateSignedStatusType `json:"status" validate:"required"`
+
+	// StatusInfo provides additional status information
+	StatusInfo *v201.StatusInfo `json:"statusInfo,omitempty"`
+}
+
+// GetFeatureName implements common.Request interface
+func (r CertificateSignedRequest) GetFeatureName() string {
+	return CertificateSignedFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r CertificateSignedRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r CertificateSignedRequest) Validate() error {
+	if r.CertificateChain == "" {
+		return &ValidationError{Field: "certificateChain", Message: "required", Err: common.ErrRequired}
+	}
+	if len(r.CertificateChain) > 10000 {
+		return &ValidationError{Field: "certificateChain", Message: "max length is 10000"}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r CertificateSignedResponse) GetFeatureName() string {
+	return CertificateSignedFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r CertificateSignedResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
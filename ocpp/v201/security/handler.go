@@ -0,0 +1,15 @@
+package security
+
+// ============================================================================
+// Security Handler Interface - OCPP 2.0.1
+// ============================================================================
+// This interface defines the methods that must be implemented to issue
+// certificates to charging stations.
+// ============================================================================
+
+// Handler defines the interface for handling security messages
+type Handler interface {
+	// OnSignCertificate handles incoming SignCertificate requests
+	// Called when a charging station needs a new certificate; it is sent with CertificateSigned
+	OnSignCertificate(chargePointId string, request *SignCertificateRequest) (*SignCertificateResponse, error)
+}
@@ -0,0 +1,67 @@
+package security
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// ============================================================================
+// OCPP 2.0.1 Security Messages Tests
+// ============================================================================
+// Tests for SignCertificate and CertificateSigned
+// ============================================================================
+
+func TestSignCertificateRequest_Serialization(t *testing.T) {
+	var req SignCertificateRequest
+	payload := `{"csr":"-----BEGIN CERTIFICATE REQUEST-----","certificateType":"V2GCertificate"}`
+	if err := json.Unmarshal([]byte(payload), &req); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if req.CertificateType != CertificateSigningUseV2G {
+		t.Errorf("CertificateType = %v, want %v", req.CertificateType, CertificateSigningUseV2G)
+	}
+	if err := req.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
+
+func TestSignCertificateRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		request SignCertificateRequest
+		wantErr bool
+	}{
+		{"station certificate by default", SignCertificateRequest{Csr: "csr"}, false},
+		{"no csr", SignCertificateRequest{}, true},
+		{"csr too long", SignCertificateRequest{Csr: strings.Repeat("a", 5501)}, true},
+		{"unknown type", SignCertificateRequest{Csr: "csr", CertificateType: "ManufacturerRootCertificate"}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.request.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCertificateSignedRequest_Serialization(t *testing.T) {
+	req := CertificateSignedRequest{CertificateChain: "-----BEGIN CERTIFICATE-----", CertificateType: CertificateSigningUseChargingStation}
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(data), `"certificateType":"ChargingStationCertificate"`) {
+		t.Errorf("serialized %s, want the certificate type", data)
+	}
+	if err = (CertificateSignedRequest{}).Validate(); err == nil {
+		t.Error("Validate() accepted a request without a chain")
+	}
+
+	var resp CertificateSignedResponse
+	if err = json.Unmarshal([]byte(`{"status":"Rejected","statusInfo":{"reasonCode":"InvalidCertificate"}}`), &resp); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if resp.Status != CertificateSignedStatusRejected || resp.StatusInfo == nil {
+		t.Errorf("decoded %+v, want a rejection with its reason", resp)
+	}
+}
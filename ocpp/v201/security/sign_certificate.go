@@ -0,0 +1,102 @@
+package security
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+)
+
+// ============================================================================
+// SignCertificate - OCPP 2.0.1
+// ============================================================================
+// Sent by: Charging Station → CSMS
+// Purpose: Have a certificate signing request signed: either for the
+//          connection to the CSMS, or for the ISO 15118 TLS connection to the
+//          EV. The certificate follows with CertificateSigned.
+// ============================================================================
+
+const SignCertificateFeatureName = "SignCertificate"
+
+// CertificateSigningUseType defines what a certificate is for
+type CertificateSigningUseType string
+
+const (
+	CertificateSigningUseChargingStation CertificateSigningUseType = "ChargingStationCertificate" // Connection to the CSMS
+	CertificateSigningUseV2G             CertificateSigningUseType = "V2GCertificate"             // ISO 15118 connection to the EV
+)
+
+// GenericStatusType defines a plain accepted or rejected answer
+type GenericStatusType string
+
+const (
+	GenericStatusAccepted GenericStatusType = "Accepted" // Request accepted
+	GenericStatusRejected GenericStatusType = "Rejected" // Request rejected
+)
+
+// SignCertificateRequest represents the request for SignCertificate
+type SignCertificateRequest struct {
+	// Csr is the PEM certificate signing request
+	Csr string `json:"csr" validate:"required,max=5500"`
+
+	// CertificateType is what the certificate is for; ChargingStationCertificate when omitted
+	CertificateType CertificateSigningUseType `json:"certificateType,omitempty"`
+}
+
+// SignCertificateResponse represents the response to SignCertificate
+type SignCertificateResponse struct {
+	// Status indicates whether the request will be signed
+	Status GenericStatusType `json:"status" validate:"required"`
+
+	// StatusInfo provides additional status information
+	StatusInfo *v201.StatusInfo `json:"statusInfo,omitempty"`
+}
+
+// GetFeatureName implements common.Request interface
+func (r SignCertificateRequest) GetFeatureName() string {
+	return SignCertificateFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r SignCertificateRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r SignCertificateRequest) Validate() error {
+	if r.Csr == "" {
+		return &ValidationError{Field: "csr", Message: "required", Err: common.ErrRequired}
+	}
+	if len(r.Csr) > 5500 {
+		return &ValidationError{Field: "csr", Message: "max length is 5500"}
+	}
+	switch r.CertificateType {
+	case "", CertificateSigningUseChargingStation, CertificateSigningUseV2G:
+	default:
+		return &ValidationError{Field: "certificateType", Message: "must be ChargingStationCertificate or V2GCertificate"}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r SignCertificateResponse) GetFeatureName() string {
+	return SignCertificateFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r SignCertificateResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// ValidationError represents a validation error
+type ValidationError struct {
+	Field   string
+	Message string
+	Err     error // common.ErrRequired for a missing field
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,93 @@
+package smartcharging
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+)
+
+// ============================================================================
+// ClearChargingProfile - OCPP 2.0.1
+// ============================================================================
+// Sent by: CSMS → Charging Station
+// Purpose: Remove charging profiles, either the one with the given id or
+//          every profile matching the criteria.
+// ============================================================================
+
+const ClearChargingProfileFeatureName = "ClearChargingProfile"
+
+// ClearChargingProfileStatusType defines the result of clearing profiles
+type ClearChargingProfileStatusType string
+
+const (
+	ClearChargingProfileStatusAccepted ClearChargingProfileStatusType = "Accepted" // Profiles removed
+	ClearChargingProfileStatusUnknown  ClearChargingProfileStatusType = "Unknown"  // Nothing matched
+)
+
+// ClearChargingProfileType selects the profiles to clear; empty fields match everything
+type ClearChargingProfileType struct {
+	// EvseId selects profiles of one EVSE; 0 selects the station-wide profiles
+	EvseId *int `json:"evseId,omitempty" validate:"omitempty,min=0"`
+
+	// ChargingProfilePurpose selects profiles of one purpose
+	ChargingProfilePurpose v201.ChargingProfilePurposeType `json:"chargingProfilePurpose,omitempty"`
+
+	// StackLevel selects profiles of one stack level
+	StackLevel *int `json:"stackLevel,omitempty" validate:"omitempty,min=0"`
+}
+
+// ClearChargingProfileRequest represents the request for ClearChargingProfile
+type ClearChargingProfileRequest struct {
+	// ChargingProfileId is the id of the profile to clear
+	ChargingProfileId *int `json:"chargingProfileId,omitempty"`
+
+	// ChargingProfileCriteria selects the profiles to clear when no id is given
+	ChargingProfileCriteria *ClearChargingProfileType `json:"chargingProfileCriteria,omitempty"`
+}
+
+// ClearChargingProfileResponse represents the response to ClearChargingProfile
+type ClearChargingProfileResponse struct {
+	// Status indicates whether any profile was cleared
+	Status ClearChargingProfileStatusType `json:"status" validate:"required"`
+
+	// StatusInfo provides additional status information
+	StatusInfo *v201.StatusInfo `json:"statusInfo,omitempty"`
+}
+
+// NewClearDefaultChargingProfileRequest clears the profile installed by NewDefaultChargingProfile
+func NewClearDefaultChargingProfileRequest() *ClearChargingProfileRequest {
+	id := DefaultProfileId
+	return &ClearChargingProfileRequest{ChargingProfileId: &id}
+}
+
+// GetFeatureName implements common.Request interface
+func (r ClearChargingProfileRequest) GetFeatureName() string {
+	return ClearChargingProfileFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r ClearChargingProfileRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r ClearChargingProfileRequest) Validate() error {
+	if criteria := r.ChargingProfileCriteria; criteria != nil {
+		if criteria.EvseId != nil && *criteria.EvseId < 0 {
+			return &ValidationError{Field: "chargingProfileCriteria.evseId", Message: "must be >= 0"}
+		}
+		if criteria.StackLevel != nil && *criteria.StackLevel < 0 {
+			return &ValidationError{Field: "chargingProfileCriteria.stackLevel", Message: "must be >= 0"}
+		}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r ClearChargingProfileResponse) GetFeatureName() string {
+	return ClearChargingProfileFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r ClearChargingProfileResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
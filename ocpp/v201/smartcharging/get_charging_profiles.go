@@ -0,0 +1,97 @@
+package smartcharging
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+	"evsys/ocpp/v201/authorization"
+)
+
+// ============================================================================
+// GetChargingProfiles - OCPP 2.0.1
+// ============================================================================
+// Sent by: CSMS → Charging Station
+// Purpose: Ask the Charging Station for its installed charging profiles.
+//          The response only says whether any match; the profiles follow in
+//          one or more ReportChargingProfiles messages carrying the same
+//          requestId.
+// ============================================================================
+
+const GetChargingProfilesFeatureName = "GetChargingProfiles"
+
+// GetChargingProfileStatusType defines whether any profile matched the request
+type GetChargingProfileStatusType string
+
+const (
+	GetChargingProfileStatusAccepted   GetChargingProfileStatusType = "Accepted"   // Profiles will be reported
+	GetChargingProfileStatusNoProfiles GetChargingProfileStatusType = "NoProfiles" // Nothing matched
+)
+
+// ChargingProfileCriterionType selects the profiles to report; empty fields match everything
+type ChargingProfileCriterionType struct {
+	// ChargingProfilePurpose selects profiles of one purpose
+	ChargingProfilePurpose v201.ChargingProfilePurposeType `json:"chargingProfilePurpose,omitempty"`
+
+	// StackLevel selects profiles of one stack level
+	StackLevel *int `json:"stackLevel,omitempty" validate:"omitempty,min=0"`
+
+	// ChargingProfileId selects profiles by id
+	ChargingProfileId []int `json:"chargingProfileId,omitempty"`
+
+	// ChargingLimitSource selects profiles by the source that set them
+	ChargingLimitSource []authorization.ChargingLimitSourceType `json:"chargingLimitSource,omitempty" validate:"omitempty,max=4"`
+}
+
+// GetChargingProfilesRequest represents the request for GetChargingProfiles
+type GetChargingProfilesRequest struct {
+	// RequestId links the ReportChargingProfiles messages to this request
+	RequestId int `json:"requestId"`
+
+	// EvseId limits the report to one EVSE; 0 reports the station-wide profiles
+	EvseId *int `json:"evseId,omitempty" validate:"omitempty,min=0"`
+
+	// ChargingProfile selects the profiles to report
+	ChargingProfile ChargingProfileCriterionType `json:"chargingProfile" validate:"required"`
+}
+
+// GetChargingProfilesResponse represents the response to GetChargingProfiles
+type GetChargingProfilesResponse struct {
+	// Status indicates whether any profiles will be reported
+	Status GetChargingProfileStatusType `json:"status" validate:"required"`
+
+	// StatusInfo provides additional status information
+	StatusInfo *v201.StatusInfo `json:"statusInfo,omitempty"`
+}
+
+// GetFeatureName implements common.Request interface
+func (r GetChargingProfilesRequest) GetFeatureName() string {
+	return GetChargingProfilesFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r GetChargingProfilesRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r GetChargingProfilesRequest) Validate() error {
+	if r.EvseId != nil && *r.EvseId < 0 {
+		return &ValidationError{Field: "evseId", Message: "must be >= 0"}
+	}
+	if r.ChargingProfile.StackLevel != nil && *r.ChargingProfile.StackLevel < 0 {
+		return &ValidationError{Field: "chargingProfile.stackLevel", Message: "must be >= 0"}
+	}
+	if len(r.ChargingProfile.ChargingLimitSource) > 4 {
+		return &ValidationError{Field: "chargingProfile.chargingLimitSource", Message: "max 4 sources"}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r GetChargingProfilesResponse) GetFeatureName() string {
+	return GetChargingProfilesFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r GetChargingProfilesResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
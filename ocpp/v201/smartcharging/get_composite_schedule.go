@@ -0,0 +1,109 @@
+package smartcharging
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+	"time"
+)
+
+// ============================================================================
+// GetCompositeSchedule - OCPP 2.0.1
+// ============================================================================
+// Sent by: CSMS → Charging Station
+// Purpose: Ask for the schedule an EVSE will actually follow, combining all
+//          installed profiles and any external limits, for the given number
+//          of seconds from now.
+// ============================================================================
+
+const GetCompositeScheduleFeatureName = "GetCompositeSchedule"
+
+// GenericStatusType defines an accepted or rejected request
+type GenericStatusType string
+
+const (
+	GenericStatusAccepted GenericStatusType = "Accepted" // Request accepted
+	GenericStatusRejected GenericStatusType = "Rejected" // Request rejected
+)
+
+// GetCompositeScheduleRequest represents the request for GetCompositeSchedule
+type GetCompositeScheduleRequest struct {
+	// Duration is the length of the requested schedule in seconds
+	Duration int `json:"duration" validate:"min=0"`
+
+	// ChargingRateUnit forces the unit of the schedule (optional)
+	ChargingRateUnit v201.ChargingRateUnitType `json:"chargingRateUnit,omitempty"`
+
+	// EvseId is the EVSE to report; 0 reports the grid connection of the charging station
+	EvseId int `json:"evseId" validate:"min=0"`
+}
+
+// CompositeScheduleType is the schedule an EVSE will follow
+type CompositeScheduleType struct {
+	// EvseId is the EVSE the schedule applies to
+	EvseId int `json:"evseId" validate:"min=0"`
+
+	// Duration is the length of the schedule in seconds
+	Duration int `json:"duration"`
+
+	// ScheduleStart is when the schedule starts
+	ScheduleStart time.Time `json:"scheduleStart" validate:"required"`
+
+	// ChargingRateUnit is the unit of the schedule periods
+	ChargingRateUnit v201.ChargingRateUnitType `json:"chargingRateUnit" validate:"required"`
+
+	// ChargingSchedulePeriod contains the schedule periods
+	ChargingSchedulePeriod []v201.ChargingSchedulePeriod `json:"chargingSchedulePeriod" validate:"required,min=1,dive"`
+}
+
+// GetCompositeScheduleResponse represents the response to GetCompositeSchedule
+type GetCompositeScheduleResponse struct {
+	// Status indicates whether a schedule could be computed
+	Status GenericStatusType `json:"status" validate:"required"`
+
+	// StatusInfo provides additional status information
+	StatusInfo *v201.StatusInfo `json:"statusInfo,omitempty"`
+
+	// Schedule is the composite schedule, present when accepted
+	Schedule *CompositeScheduleType `json:"schedule,omitempty"`
+}
+
+// NewGetCompositeScheduleRequest asks for the schedule of an EVSE over duration seconds
+func NewGetCompositeScheduleRequest(evseId, duration int) *GetCompositeScheduleRequest {
+	return &GetCompositeScheduleRequest{EvseId: evseId, Duration: duration}
+}
+
+// GetFeatureName implements common.Request interface
+func (r GetCompositeScheduleRequest) GetFeatureName() string {
+	return GetCompositeScheduleFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r GetCompositeScheduleRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r GetCompositeScheduleRequest) Validate() error {
+	if r.Duration < 0 {
+		return &ValidationError{Field: "duration", Message: "must be >= 0"}
+	}
+	if r.EvseId < 0 {
+		return &ValidationError{Field: "evseId", Message: "must be >= 0"}
+	}
+	switch r.ChargingRateUnit {
+	case "", v201.ChargingRateUnitA, v201.ChargingRateUnitW:
+	default:
+		return &ValidationError{Field: "chargingRateUnit", Message: "must be A or W"}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r GetCompositeScheduleResponse) GetFeatureName() string {
+	return GetCompositeScheduleFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r GetCompositeScheduleResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
@@ -0,0 +1,30 @@
+package smartcharging
+
+// ============================================================================
+// Smart Charging Handler Interface - OCPP 2.0.1
+// ============================================================================
+// This interface defines the methods that must be implemented to handle
+// smart charging messages from charging stations. The requests sent to
+// the charging station (SetChargingProfile, GetChargingProfiles,
+// ClearChargingProfile and GetCompositeSchedule) are built by the load
+// balancer and the API.
+// ============================================================================
+
+// Handler defines the interface for handling smart charging messages
+type Handler interface {
+	// OnReportChargingProfiles handles incoming ReportChargingProfiles requests
+	// Called with the profiles matching an earlier GetChargingProfiles request
+	OnReportChargingProfiles(chargePointId string, request *ReportChargingProfilesRequest) (*ReportChargingProfilesResponse, error)
+
+	// OnNotifyEVChargingNeeds handles incoming NotifyEVChargingNeeds requests
+	// Called when an ISO 15118 EV reports its energy demand and departure time
+	OnNotifyEVChargingNeeds(chargePointId string, request *NotifyEVChargingNeedsRequest) (*NotifyEVChargingNeedsResponse, error)
+
+	// OnNotifyEVChargingSchedule handles incoming NotifyEVChargingSchedule requests
+	// Called with the schedule an ISO 15118 EV proposes for its session
+	OnNotifyEVChargingSchedule(chargePointId string, request *NotifyEVChargingScheduleRequest) (*NotifyEVChargingScheduleResponse, error)
+
+	// OnNotifyChargingLimit handles incoming NotifyChargingLimit requests
+	// Called when an external system, such as an EMS, sets a limit on the station
+	OnNotifyChargingLimit(chargePointId string, request *NotifyChargingLimitRequest) (*NotifyChargingLimitResponse, error)
+}
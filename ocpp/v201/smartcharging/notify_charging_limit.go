@@ -0,0 +1,83 @@
+package smartcharging
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+	"evsys/ocpp/v201/authorization"
+)
+
+// ============================================================================
+// NotifyChargingLimit - OCPP 2.0.1
+// ============================================================================
+// Sent by: Charging Station → CSMS
+// Purpose: Report a charging limit set on the station by an external
+//          system, such as a local EMS or the grid operator, together with
+//          the schedule it imposes. ClearedChargingLimit reports its end.
+// ============================================================================
+
+const NotifyChargingLimitFeatureName = "NotifyChargingLimit"
+
+// ChargingLimitType names where an external limit comes from
+type ChargingLimitType struct {
+	// ChargingLimitSource is the system that set the limit
+	ChargingLimitSource authorization.ChargingLimitSourceType `json:"chargingLimitSource" validate:"required"`
+
+	// IsGridCritical is set when the limit protects the grid
+	IsGridCritical *bool `json:"isGridCritical,omitempty"`
+}
+
+// NotifyChargingLimitRequest represents the request for NotifyChargingLimit
+type NotifyChargingLimitRequest struct {
+	// ChargingSchedule holds the limits the external system imposes
+	ChargingSchedule []v201.ChargingSchedule `json:"chargingSchedule,omitempty" validate:"omitempty,dive"`
+
+	// EvseId is the EVSE the limit applies to; the whole station when absent or 0
+	EvseId *int `json:"evseId,omitempty" validate:"omitempty,min=0"`
+
+	// ChargingLimit names the source of the limit
+	ChargingLimit ChargingLimitType `json:"chargingLimit" validate:"required"`
+}
+
+// NotifyChargingLimitResponse represents the response to NotifyChargingLimit
+type NotifyChargingLimitResponse struct {
+	// No fields required - empty response indicates acknowledgment
+}
+
+// GetFeatureName implements common.Request interface
+func (r NotifyChargingLimitRequest) GetFeatureName() string {
+	return NotifyChargingLimitFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r NotifyChargingLimitRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r NotifyChargingLimitRequest) Validate() error {
+	if r.ChargingLimit.ChargingLimitSource == "" {
+		return &ValidationError{Field: "chargingLimit.chargingLimitSource", Message: "required", Err: common.ErrRequired}
+	}
+	if r.EvseId != nil && *r.EvseId < 0 {
+		return &ValidationError{Field: "evseId", Message: "must be >= 0"}
+	}
+	for _, schedule := range r.ChargingSchedule {
+		if schedule.ChargingRateUnit == "" {
+			return &ValidationError{Field: "chargingSchedule.chargingRateUnit", Message: "required", Err: common.ErrRequired}
+		}
+		if len(schedule.ChargingSchedulePeriod) == 0 {
+			return &ValidationError{Field: "chargingSchedule.chargingSchedulePeriod", Message: "at least one period required", Err: common.ErrRequired}
+		}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r NotifyChargingLimitResponse) GetFeatureName() string {
+	return NotifyChargingLimitFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r NotifyChargingLimitResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
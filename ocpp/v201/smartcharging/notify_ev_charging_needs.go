@@ -0,0 +1,177 @@
+package smartcharging
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+	"time"
+)
+
+// ============================================================================
+// NotifyEVChargingNeeds - OCPP 2.0.1
+// ============================================================================
+// Sent by: Charging Station → CSMS
+// Purpose: Pass on the charging needs an ISO 15118 EV reported at the start
+//          of its session: the energy it wants, by when, and the electrical
+//          limits of the car. The CSMS may answer with a charging profile.
+// ============================================================================
+
+const NotifyEVChargingNeedsFeatureName = "NotifyEVChargingNeeds"
+
+// EnergyTransferModeType defines how the EV wants to be charged
+type EnergyTransferModeType string
+
+const (
+	EnergyTransferModeDC            EnergyTransferModeType = "DC"              // DC charging
+	EnergyTransferModeACSinglePhase EnergyTransferModeType = "AC_single_phase" // AC, one phase
+	EnergyTransferModeACTwoPhase    EnergyTransferModeType = "AC_two_phase"    // AC, two phases
+	EnergyTransferModeACThreePhase  EnergyTransferModeType = "AC_three_phase"  // AC, three phases
+)
+
+// IsValid reports whether the mode is one defined by OCPP 2.0.1
+func (m EnergyTransferModeType) IsValid() bool {
+	switch m {
+	case EnergyTransferModeDC, EnergyTransferModeACSinglePhase, EnergyTransferModeACTwoPhase, EnergyTransferModeACThreePhase:
+		return true
+	}
+	return false
+}
+
+// NotifyEVChargingNeedsStatusType defines the answer to the charging needs
+type NotifyEVChargingNeedsStatusType string
+
+const (
+	NotifyEVChargingNeedsStatusAccepted   NotifyEVChargingNeedsStatusType = "Accepted"   // A schedule will be provided
+	NotifyEVChargingNeedsStatusRejected   NotifyEVChargingNeedsStatusType = "Rejected"   // No schedule will be provided
+	NotifyEVChargingNeedsStatusProcessing NotifyEVChargingNeedsStatusType = "Processing" // A schedule is being worked out
+)
+
+// ACChargingParametersType holds the limits of an EV charging on AC
+type ACChargingParametersType struct {
+	// EnergyAmount is the energy requested, in Wh
+	EnergyAmount int `json:"energyAmount"`
+
+	// EVMinCurrent is the minimum current per phase the EV supports, in A
+	EVMinCurrent int `json:"evMinCurrent"`
+
+	// EVMaxCurrent is the maximum current per phase the EV supports, in A
+	EVMaxCurrent int `json:"evMaxCurrent"`
+
+	// EVMaxVoltage is the maximum voltage the EV supports, in V
+	EVMaxVoltage int `json:"evMaxVoltage"`
+}
+
+// DCChargingParametersType holds the limits and battery state of an EV charging on DC
+type DCChargingParametersType struct {
+	// EVMaxCurrent is the maximum current the EV supports, in A
+	EVMaxCurrent int `json:"evMaxCurrent"`
+
+	// EVMaxVoltage is the maximum voltage the EV supports, in V
+	EVMaxVoltage int `json:"evMaxVoltage"`
+
+	// EnergyAmount is the energy requested, in Wh
+	EnergyAmount *int `json:"energyAmount,omitempty"`
+
+	// EVMaxPower is the maximum power the EV supports, in W
+	EVMaxPower *int `json:"evMaxPower,omitempty"`
+
+	// StateOfCharge is the battery level, in percent
+	StateOfCharge *int `json:"stateOfCharge,omitempty" validate:"omitempty,min=0,max=100"`
+
+	// EVEnergyCapacity is the capacity of the battery, in Wh
+	EVEnergyCapacity *int `json:"evEnergyCapacity,omitempty"`
+
+	// FullSoC is the level at which the EV considers the battery full, in percent
+	FullSoC *int `json:"fullSoC,omitempty" validate:"omitempty,min=0,max=100"`
+
+	// BulkSoC is the level at which fast charging ends, in percent
+	BulkSoC *int `json:"bulkSoC,omitempty" validate:"omitempty,min=0,max=100"`
+}
+
+// ChargingNeedsType describes what the EV needs from its session
+type ChargingNeedsType struct {
+	// RequestedEnergyTransfer is the mode the EV charges in
+	RequestedEnergyTransfer EnergyTransferModeType `json:"requestedEnergyTransfer" validate:"required"`
+
+	// DepartureTime is when the EV driver plans to leave
+	DepartureTime *time.Time `json:"departureTime,omitempty"`
+
+	// ACChargingParameters is set for AC charging
+	ACChargingParameters *ACChargingParametersType `json:"acChargingParameters,omitempty"`
+
+	// DCChargingParameters is set for DC charging
+	DCChargingParameters *DCChargingParametersType `json:"dcChargingParameters,omitempty"`
+}
+
+// EnergyAmount is the energy requested, in Wh, whichever parameters the EV sent; 0 when it did not say
+func (n ChargingNeedsType) EnergyAmount() int {
+	switch {
+	case n.ACChargingParameters != nil:
+		return n.ACChargingParameters.EnergyAmount
+	case n.DCChargingParameters != nil && n.DCChargingParameters.EnergyAmount != nil:
+		return *n.DCChargingParameters.EnergyAmount
+	}
+	return 0
+}
+
+// NotifyEVChargingNeedsRequest represents the request for NotifyEVChargingNeeds
+type NotifyEVChargingNeedsRequest struct {
+	// MaxScheduleTuples is the number of schedule periods the EV accepts
+	MaxScheduleTuples *int `json:"maxScheduleTuples,omitempty"`
+
+	// EvseId is the EVSE the EV is connected to
+	EvseId int `json:"evseId" validate:"min=1"`
+
+	// ChargingNeeds describes what the EV needs
+	ChargingNeeds ChargingNeedsType `json:"chargingNeeds" validate:"required"`
+}
+
+// NotifyEVChargingNeedsResponse represents the response to NotifyEVChargingNeeds
+type NotifyEVChargingNeedsResponse struct {
+	// Status tells whether the CSMS will provide a charging schedule
+	Status NotifyEVChargingNeedsStatusType `json:"status" validate:"required"`
+
+	// StatusInfo provides additional status information
+	StatusInfo *v201.StatusInfo `json:"statusInfo,omitempty"`
+}
+
+// GetFeatureName implements common.Request interface
+func (r NotifyEVChargingNeedsRequest) GetFeatureName() string {
+	return NotifyEVChargingNeedsFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r NotifyEVChargingNeedsRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r NotifyEVChargingNeedsRequest) Validate() error {
+	if r.EvseId < 1 {
+		return &ValidationError{Field: "evseId", Message: "must be >= 1"}
+	}
+	if !r.ChargingNeeds.RequestedEnergyTransfer.IsValid() {
+		return &ValidationError{Field: "chargingNeeds.requestedEnergyTransfer", Message: "unknown energy transfer mode"}
+	}
+	if dc := r.ChargingNeeds.DCChargingParameters; dc != nil {
+		levels := []struct {
+			field string
+			value *int
+		}{{"stateOfCharge", dc.StateOfCharge}, {"fullSoC", dc.FullSoC}, {"bulkSoC", dc.BulkSoC}}
+		for _, level := range levels {
+			if level.value != nil && (*level.value < 0 || *level.value > 100) {
+				return &ValidationError{Field: "dcChargingParameters." + level.field, Message: "must be 0 to 100"}
+			}
+		}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r NotifyEVChargingNeedsResponse) GetFeatureName() string {
+	return NotifyEVChargingNeedsFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r NotifyEVChargingNeedsResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
@@ -0,0 +1,76 @@
+package smartcharging
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+	"time"
+)
+
+// ============================================================================
+// NotifyEVChargingSchedule - OCPP 2.0.1
+// ============================================================================
+// Sent by: Charging Station → CSMS
+// Purpose: Pass on the charging schedule an ISO 15118 EV has worked out
+//          within the limits it was given. A Rejected answer asks for a
+//          renegotiation.
+// ============================================================================
+
+const NotifyEVChargingScheduleFeatureName = "NotifyEVChargingSchedule"
+
+// NotifyEVChargingScheduleRequest represents the request for NotifyEVChargingSchedule
+type NotifyEVChargingScheduleRequest struct {
+	// TimeBase is the moment the periods of the schedule count from
+	TimeBase time.Time `json:"timeBase" validate:"required"`
+
+	// ChargingSchedule is the schedule the EV proposes
+	ChargingSchedule v201.ChargingSchedule `json:"chargingSchedule" validate:"required"`
+
+	// EvseId is the EVSE the EV is connected to
+	EvseId int `json:"evseId" validate:"min=1"`
+}
+
+// NotifyEVChargingScheduleResponse represents the response to NotifyEVChargingSchedule
+type NotifyEVChargingScheduleResponse struct {
+	// Status tells whether the CSMS accepts the schedule
+	Status GenericStatusType `json:"status" validate:"required"`
+
+	// StatusInfo provides additional status information
+	StatusInfo *v201.StatusInfo `json:"statusInfo,omitempty"`
+}
+
+// GetFeatureName implements common.Request interface
+func (r NotifyEVChargingScheduleRequest) GetFeatureName() string {
+	return NotifyEVChargingScheduleFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r NotifyEVChargingScheduleRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r NotifyEVChargingScheduleRequest) Validate() error {
+	if r.EvseId < 1 {
+		return &ValidationError{Field: "evseId", Message: "must be >= 1"}
+	}
+	if r.TimeBase.IsZero() {
+		return &ValidationError{Field: "timeBase", Message: "required", Err: common.ErrRequired}
+	}
+	if r.ChargingSchedule.ChargingRateUnit == "" {
+		return &ValidationError{Field: "chargingSchedule.chargingRateUnit", Message: "required", Err: common.ErrRequired}
+	}
+	if len(r.ChargingSchedule.ChargingSchedulePeriod) == 0 {
+		return &ValidationError{Field: "chargingSchedule.chargingSchedulePeriod", Message: "at least one period required", Err: common.ErrRequired}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r NotifyEVChargingScheduleResponse) GetFeatureName() string {
+	return NotifyEVChargingScheduleFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r NotifyEVChargingScheduleResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
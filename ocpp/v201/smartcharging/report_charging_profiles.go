@@ -0,0 +1,75 @@
+package smartcharging
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+	"evsys/ocpp/v201/authorization"
+)
+
+// ============================================================================
+// ReportChargingProfiles - OCPP 2.0.1
+// ============================================================================
+// Sent by: Charging Station → CSMS
+// Purpose: Report the charging profiles asked for by GetChargingProfiles.
+//          Profiles are grouped per EVSE and limit source; tbc is set while
+//          more reports for the same requestId are to follow.
+// ============================================================================
+
+const ReportChargingProfilesFeatureName = "ReportChargingProfiles"
+
+// ReportChargingProfilesRequest represents the request for ReportChargingProfiles
+type ReportChargingProfilesRequest struct {
+	// RequestId is the id of the GetChargingProfiles request being answered
+	RequestId int `json:"requestId"`
+
+	// ChargingLimitSource is the source that installed the reported profiles
+	ChargingLimitSource authorization.ChargingLimitSourceType `json:"chargingLimitSource" validate:"required"`
+
+	// Tbc indicates more reports follow for this request
+	Tbc bool `json:"tbc,omitempty"`
+
+	// EvseId is the EVSE the profiles are installed on; 0 is the charging station
+	EvseId int `json:"evseId" validate:"min=0"`
+
+	// ChargingProfile contains the reported profiles
+	ChargingProfile []v201.ChargingProfile `json:"chargingProfile" validate:"required,min=1,dive"`
+}
+
+// ReportChargingProfilesResponse represents the response to ReportChargingProfiles
+type ReportChargingProfilesResponse struct {
+	// No fields required - empty response indicates acknowledgment
+}
+
+// GetFeatureName implements common.Request interface
+func (r ReportChargingProfilesRequest) GetFeatureName() string {
+	return ReportChargingProfilesFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r ReportChargingProfilesRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r ReportChargingProfilesRequest) Validate() error {
+	if r.ChargingLimitSource == "" {
+		return &ValidationError{Field: "chargingLimitSource", Message: "required", Err: common.ErrRequired}
+	}
+	if r.EvseId < 0 {
+		return &ValidationError{Field: "evseId", Message: "must be >= 0"}
+	}
+	if len(r.ChargingProfile) == 0 {
+		return &ValidationError{Field: "chargingProfile", Message: "at least one profile required", Err: common.ErrRequired}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r ReportChargingProfilesResponse) GetFeatureName() string {
+	return ReportChargingProfilesFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r ReportChargingProfilesResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
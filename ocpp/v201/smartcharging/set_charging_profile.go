@@ -0,0 +1,169 @@
+package smartcharging
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+	"time"
+)
+
+// ============================================================================
+// SetChargingProfile - OCPP 2.0.1
+// ============================================================================
+// Sent by: CSMS → Charging Station
+// Purpose: Install a charging profile on an EVSE, or on the whole charging
+//          station with EVSE 0. Unlike OCPP 1.6, a TxProfile names the
+//          transaction by its string id and the profile id is unique across
+//          the charging station.
+// ============================================================================
+
+const SetChargingProfileFeatureName = "SetChargingProfile"
+
+// ChargingProfileStatusType defines the result of installing a charging profile
+type ChargingProfileStatusType string
+
+const (
+	ChargingProfileStatusAccepted ChargingProfileStatusType = "Accepted" // Profile installed
+	ChargingProfileStatusRejected ChargingProfileStatusType = "Rejected" // Profile refused
+)
+
+// DefaultProfileId is the id of the TxDefaultProfile installed on EVSE 0
+const DefaultProfileId = 1
+
+// txProfileIdBase keeps transaction profile ids clear of the default profile, one id per EVSE
+const txProfileIdBase = 10
+
+// TxProfileStackLevel is the level used until the charging station reports
+// SmartChargingCtrlr.ProfileStackLevel; callers clamp it to the reported maximum.
+const TxProfileStackLevel = 10
+
+// SetChargingProfileRequest represents the request for SetChargingProfile
+type SetChargingProfileRequest struct {
+	// EvseId is the EVSE the profile applies to; 0 applies it to the charging station
+	EvseId int `json:"evseId" validate:"min=0"`
+
+	// ChargingProfile is the profile to install
+	ChargingProfile v201.ChargingProfile `json:"chargingProfile" validate:"required"`
+}
+
+// SetChargingProfileResponse represents the response to SetChargingProfile
+type SetChargingProfileResponse struct {
+	// Status indicates whether the profile was installed
+	Status ChargingProfileStatusType `json:"status" validate:"required"`
+
+	// StatusInfo provides additional status information
+	StatusInfo *v201.StatusInfo `json:"statusInfo,omitempty"`
+}
+
+// NewSetChargingProfileRequest creates a SetChargingProfile request for an EVSE
+func NewSetChargingProfileRequest(evseId int, profile v201.ChargingProfile) *SetChargingProfileRequest {
+	return &SetChargingProfileRequest{EvseId: evseId, ChargingProfile: profile}
+}
+
+// NewDefaultChargingProfile limits every new transaction to limit amperes, repeating daily
+func NewDefaultChargingProfile(limit int) v201.ChargingProfile {
+	duration := 86400
+	start := time.Now()
+	return v201.ChargingProfile{
+		Id:                     DefaultProfileId,
+		StackLevel:             1,
+		ChargingProfilePurpose: v201.ChargingProfilePurposeTxDefaultProfile,
+		ChargingProfileKind:    v201.ChargingProfileKindRecurring,
+		RecurrencyKind:         v201.RecurrencyKindDaily,
+		ChargingSchedule: []v201.ChargingSchedule{{
+			Id:                     DefaultProfileId,
+			StartSchedule:          &start,
+			Duration:               &duration,
+			ChargingRateUnit:       v201.ChargingRateUnitA,
+			ChargingSchedulePeriod: []v201.ChargingSchedulePeriod{{StartPeriod: 0, Limit: float64(limit)}},
+		}},
+	}
+}
+
+// NewTransactionChargingProfile limits the running transaction on an EVSE to limit amperes
+func NewTransactionChargingProfile(evseId int, transactionId string, limit, stackLevel int) v201.ChargingProfile {
+	return v201.ChargingProfile{
+		Id:                     txProfileIdBase + evseId,
+		StackLevel:             stackLevel,
+		ChargingProfilePurpose: v201.ChargingProfilePurposeTxProfile,
+		ChargingProfileKind:    v201.ChargingProfileKindRelative,
+		TransactionId:          transactionId,
+		ChargingSchedule: []v201.ChargingSchedule{{
+			Id:                     txProfileIdBase + evseId,
+			ChargingRateUnit:       v201.ChargingRateUnitA,
+			ChargingSchedulePeriod: []v201.ChargingSchedulePeriod{{StartPeriod: 0, Limit: float64(limit)}},
+		}},
+	}
+}
+
+// GetFeatureName implements common.Request interface
+func (r SetChargingProfileRequest) GetFeatureName() string {
+	return SetChargingProfileFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r SetChargingProfileRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r SetChargingProfileRequest) Validate() error {
+	if r.EvseId < 0 {
+		return &ValidationError{Field: "evseId", Message: "must be >= 0"}
+	}
+	return validateProfile(r.ChargingProfile)
+}
+
+// GetFeatureName implements common.Response interface
+func (r SetChargingProfileResponse) GetFeatureName() string {
+	return SetChargingProfileFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r SetChargingProfileResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+func validateProfile(profile v201.ChargingProfile) error {
+	if profile.StackLevel < 0 {
+		return &ValidationError{Field: "chargingProfile.stackLevel", Message: "must be >= 0"}
+	}
+	if profile.ChargingProfilePurpose == "" {
+		return &ValidationError{Field: "chargingProfile.chargingProfilePurpose", Message: "required", Err: common.ErrRequired}
+	}
+	if profile.ChargingProfileKind == "" {
+		return &ValidationError{Field: "chargingProfile.chargingProfileKind", Message: "required", Err: common.ErrRequired}
+	}
+	if profile.ChargingProfileKind == v201.ChargingProfileKindRecurring && profile.RecurrencyKind == "" {
+		return &ValidationError{Field: "chargingProfile.recurrencyKind", Message: "required for a recurring profile", Err: common.ErrRequired}
+	}
+	if profile.ChargingProfilePurpose == v201.ChargingProfilePurposeTxProfile && profile.TransactionId == "" {
+		return &ValidationError{Field: "chargingProfile.transactionId", Message: "required for a TxProfile", Err: common.ErrRequired}
+	}
+	if len(profile.ChargingSchedule) == 0 || len(profile.ChargingSchedule) > 3 {
+		return &ValidationError{Field: "chargingProfile.chargingSchedule", Message: "1 to 3 schedules required", Err: common.ErrRequired}
+	}
+	for _, schedule := range profile.ChargingSchedule {
+		if schedule.ChargingRateUnit == "" {
+			return &ValidationError{Field: "chargingSchedule.chargingRateUnit", Message: "required", Err: common.ErrRequired}
+		}
+		if len(schedule.ChargingSchedulePeriod) == 0 {
+			return &ValidationError{Field: "chargingSchedule.chargingSchedulePeriod", Message: "at least one period required", Err: common.ErrRequired}
+		}
+	}
+	return nil
+}
+
+// ValidationError represents a validation error
+type ValidationError struct {
+	Field   string
+	Message string
+	Err     error // common.ErrRequired for a missing field
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,236 @@
+package smartcharging
+
+import (
+	"encoding/json"
+	"evsys/ocpp/v201"
+	"strings"
+	"testing"
+)
+
+// ============================================================================
+// OCPP 2.0.1 Smart Charging Messages Tests
+// ============================================================================
+// Tests for SetChargingProfile, GetChargingProfiles, ReportChargingProfiles,
+// ClearChargingProfile, GetCompositeSchedule, NotifyEVChargingNeeds,
+// NotifyEVChargingSchedule and NotifyChargingLimit
+// ============================================================================
+
+func TestSetChargingProfileRequest_Serialization(t *testing.T) {
+	req := NewSetChargingProfileRequest(2, NewTransactionChargingProfile(2, "tx-42", 16, 5))
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(data), `"transactionId":"tx-42"`) {
+		t.Errorf("serialized %s, want the transaction id in the profile", data)
+	}
+
+	var decoded SetChargingProfileRequest
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	profile := decoded.ChargingProfile
+	if decoded.EvseId != 2 || profile.StackLevel != 5 || profile.ChargingProfilePurpose != v201.ChargingProfilePurposeTxProfile {
+		t.Errorf("decoded %+v, want a TxProfile at stack level 5 on EVSE 2", decoded)
+	}
+	if limit := profile.ChargingSchedule[0].ChargingSchedulePeriod[0].Limit; limit != 16 {
+		t.Errorf("Limit = %v, want 16", limit)
+	}
+	if err = decoded.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
+
+func TestSetChargingProfileRequest_Validate(t *testing.T) {
+	if err := NewSetChargingProfileRequest(0, NewDefaultChargingProfile(32)).Validate(); err != nil {
+		t.Errorf("default profile: Validate() error = %v", err)
+	}
+
+	noTransaction := NewTransactionChargingProfile(1, "", 16, 5)
+	noRecurrency := NewDefaultChargingProfile(32)
+	noRecurrency.RecurrencyKind = ""
+	noSchedule := NewDefaultChargingProfile(32)
+	noSchedule.ChargingSchedule = nil
+
+	tests := []struct {
+		name string
+		req  *SetChargingProfileRequest
+	}{
+		{"negative evse", NewSetChargingProfileRequest(-1, NewDefaultChargingProfile(32))},
+		{"TxProfile without transaction", NewSetChargingProfileRequest(1, noTransaction)},
+		{"recurring without recurrency", NewSetChargingProfileRequest(0, noRecurrency)},
+		{"no schedule", NewSetChargingProfileRequest(0, noSchedule)},
+	}
+	for _, tt := range tests {
+		if err := tt.req.Validate(); err == nil {
+			t.Errorf("%s: Validate() error = nil, want an error", tt.name)
+		}
+	}
+}
+
+// Profile ids are unique across the charging station, so each EVSE needs its own.
+func TestTransactionProfileIdsAreDistinct(t *testing.T) {
+	first := NewTransactionChargingProfile(1, "a", 16, 5)
+	second := NewTransactionChargingProfile(2, "b", 16, 5)
+	if first.Id == second.Id || first.Id == DefaultProfileId || second.Id == DefaultProfileId {
+		t.Errorf("ids %d and %d, want distinct ids clear of the default profile", first.Id, second.Id)
+	}
+}
+
+func TestReportChargingProfilesRequest_Serialization(t *testing.T) {
+	payload := `{"requestId":7,"chargingLimitSource":"CSO","tbc":true,"evseId":1,"chargingProfile":[{"id":11,"stackLevel":3,"chargingProfilePurpose":"TxProfile","chargingProfileKind":"Relative","transactionId":"tx-1","chargingSchedule":[{"id":11,"chargingRateUnit":"A","chargingSchedulePeriod":[{"startPeriod":0,"limit":10}]}]}]}`
+
+	var decoded ReportChargingProfilesRequest
+	if err := json.Unmarshal([]byte(payload), &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if decoded.RequestId != 7 || !decoded.Tbc || decoded.EvseId != 1 || len(decoded.ChargingProfile) != 1 {
+		t.Errorf("decoded %+v, want one profile of request 7 on EVSE 1", decoded)
+	}
+	if err := decoded.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	if err := (ReportChargingProfilesRequest{ChargingLimitSource: "CSO"}).Validate(); err == nil {
+		t.Error("Validate() accepted a report without profiles")
+	}
+}
+
+func TestClearChargingProfileRequest_Serialization(t *testing.T) {
+	data, err := json.Marshal(NewClearDefaultChargingProfileRequest())
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if string(data) != `{"chargingProfileId":1}` {
+		t.Errorf("serialized %s, want only the default profile id", data)
+	}
+}
+
+func TestGetCompositeScheduleResponse_Serialization(t *testing.T) {
+	payload := `{"status":"Accepted","schedule":{"evseId":1,"duration":3600,"scheduleStart":"2024-05-01T10:00:00Z","chargingRateUnit":"A","chargingSchedulePeriod":[{"startPeriod":0,"limit":16}]}}`
+
+	var decoded GetCompositeScheduleResponse
+	if err := json.Unmarshal([]byte(payload), &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if decoded.Status != GenericStatusAccepted || decoded.Schedule == nil || decoded.Schedule.Duration != 3600 {
+		t.Errorf("decoded %+v, want an accepted one-hour schedule", decoded)
+	}
+	if err := (GetCompositeScheduleRequest{Duration: 60, ChargingRateUnit: "kW"}).Validate(); err == nil {
+		t.Error("Validate() accepted an unknown rate unit")
+	}
+}
+
+func TestNotifyEVChargingNeedsRequest_Serialization(t *testing.T) {
+	payload := `{"evseId":1,"chargingNeeds":{"requestedEnergyTransfer":"DC","departureTime":"2025-03-01T18:00:00Z",` +
+		`"dcChargingParameters":{"evMaxCurrent":200,"evMaxVoltage":450,"energyAmount":30000,"stateOfCharge":40}}}`
+
+	var req NotifyEVChargingNeedsRequest
+	if err := json.Unmarshal([]byte(payload), &req); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if err := req.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	needs := req.ChargingNeeds
+	if needs.DepartureTime == nil || needs.DepartureTime.Hour() != 18 {
+		t.Errorf("DepartureTime = %v, want 18:00", needs.DepartureTime)
+	}
+	if needs.EnergyAmount() != 30000 {
+		t.Errorf("EnergyAmount() = %d, want 30000", needs.EnergyAmount())
+	}
+
+	ac := ChargingNeedsType{
+		RequestedEnergyTransfer: EnergyTransferModeACThreePhase,
+		ACChargingParameters:    &ACChargingParametersType{EnergyAmount: 11000, EVMinCurrent: 6, EVMaxCurrent: 16, EVMaxVoltage: 400},
+	}
+	if ac.EnergyAmount() != 11000 {
+		t.Errorf("AC EnergyAmount() = %d, want 11000", ac.EnergyAmount())
+	}
+}
+
+func TestNotifyEVChargingNeedsRequest_Validate(t *testing.T) {
+	soc := 120
+	tests := []struct {
+		name    string
+		req     NotifyEVChargingNeedsRequest
+		wantErr bool
+	}{
+		{"valid", NotifyEVChargingNeedsRequest{EvseId: 1, ChargingNeeds: ChargingNeedsType{RequestedEnergyTransfer: EnergyTransferModeDC}}, false},
+		{"no EVSE", NotifyEVChargingNeedsRequest{ChargingNeeds: ChargingNeedsType{RequestedEnergyTransfer: EnergyTransferModeDC}}, true},
+		{"unknown mode", NotifyEVChargingNeedsRequest{EvseId: 1, ChargingNeeds: ChargingNeedsType{RequestedEnergyTransfer: "AC"}}, true},
+		{"state of charge above 100", NotifyEVChargingNeedsRequest{EvseId: 1, ChargingNeeds: ChargingNeedsType{
+			RequestedEnergyTransfer: EnergyTransferModeDC,
+			DCChargingParameters:    &DCChargingParametersType{StateOfCharge: &soc},
+		}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.req.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNotifyEVChargingScheduleRequest_Serialization(t *testing.T) {
+	payload := `{"timeBase":"2025-03-01T12:00:00Z","evseId":1,"chargingSchedule":{"id":3,"chargingRateUnit":"A",` +
+		`"chargingSchedulePeriod":[{"startPeriod":0,"limit":32},{"startPeriod":1800,"limit":16}]}}`
+
+	var req NotifyEVChargingScheduleRequest
+	if err := json.Unmarshal([]byte(payload), &req); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if err := req.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if periods := req.ChargingSchedule.ChargingSchedulePeriod; len(periods) != 2 || periods[1].Limit != 16 {
+		t.Errorf("periods = %+v, want two with 16A last", periods)
+	}
+	if err := (NotifyEVChargingScheduleRequest{EvseId: 1, ChargingSchedule: req.ChargingSchedule}).Validate(); err == nil {
+		t.Error("Validate() accepted a schedule without a time base")
+	}
+}
+
+func TestNotifyChargingLimitRequest_Serialization(t *testing.T) {
+	payload := `{"evseId":2,"chargingLimit":{"chargingLimitSource":"SO","isGridCritical":true},"chargingSchedule":[` +
+		`{"id":1,"chargingRateUnit":"W","chargingSchedulePeriod":[{"startPeriod":0,"limit":11000}]}]}`
+
+	var req NotifyChargingLimitRequest
+	if err := json.Unmarshal([]byte(payload), &req); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if err := req.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if req.ChargingLimit.IsGridCritical == nil || !*req.ChargingLimit.IsGridCritical || *req.EvseId != 2 {
+		t.Errorf("request = %+v, want a grid critical limit on EVSE 2", req)
+	}
+	if err := (NotifyChargingLimitRequest{}).Validate(); err == nil {
+		t.Error("Validate() accepted a limit without a source")
+	}
+	req.ChargingSchedule[0].ChargingSchedulePeriod = nil
+	if err := req.Validate(); err == nil {
+		t.Error("Validate() accepted a schedule without periods")
+	}
+}
+
+func TestSmartCharging_GetFeatureName(t *testing.T) {
+	tests := []struct {
+		got, want string
+	}{
+		{SetChargingProfileRequest{}.GetFeatureName(), SetChargingProfileFeatureName},
+		{GetChargingProfilesRequest{}.GetFeatureName(), GetChargingProfilesFeatureName},
+		{ReportChargingProfilesRequest{}.GetFeatureName(), ReportChargingProfilesFeatureName},
+		{ClearChargingProfileRequest{}.GetFeatureName(), ClearChargingProfileFeatureName},
+		{GetCompositeScheduleRequest{}.GetFeatureName(), GetCompositeScheduleFeatureName},
+		{NotifyEVChargingNeedsRequest{}.GetFeatureName(), NotifyEVChargingNeedsFeatureName},
+		{NotifyEVChargingScheduleRequest{}.GetFeatureName(), NotifyEVChargingScheduleFeatureName},
+		{NotifyChargingLimitRequest{}.GetFeatureName(), NotifyChargingLimitFeatureName},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("feature name = %v, want %v", tt.got, tt.want)
+		}
+	}
+}
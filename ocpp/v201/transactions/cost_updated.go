@@ -0,0 +1,62 @@
+package transactions
+
+import (
+	"evsys/ocpp/common"
+)
+
+// ============================================================================
+// CostUpdated - OCPP 2.0.1
+// ============================================================================
+// Sent by: CSMS → Charging Station
+// Purpose: Push the running cost of a transaction to the charging station,
+//          which shows it to the driver. Sent between TransactionEvent
+//          messages, whose answers carry the cost as well.
+// ============================================================================
+
+const CostUpdatedFeatureName = "CostUpdated"
+
+// CostUpdatedRequest represents the request for CostUpdated
+type CostUpdatedRequest struct {
+	// TotalCost is the running cost of the transaction, in the currency of the tariff
+	TotalCost float64 `json:"totalCost"`
+
+	// TransactionId is the id the charging station gave the transaction
+	TransactionId string `json:"transactionId" validate:"required,max=36"`
+}
+
+// CostUpdatedResponse represents the response to CostUpdated
+type CostUpdatedResponse struct{}
+
+// GetFeatureName implements common.Request interface
+func (r CostUpdatedRequest) GetFeatureName() string {
+	return CostUpdatedFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r CostUpdatedRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
+
+// Validate implements common.Request interface
+func (r CostUpdatedRequest) Validate() error {
+	if r.TransactionId == "" {
+		return &ValidationError{Field: "transactionId", Message: "required", Err: common.ErrRequired}
+	}
+	if len(r.TransactionId) > 36 {
+		return &ValidationError{Field: "transactionId", Message: "max length is 36"}
+	}
+	if r.TotalCost < 0 {
+		return &ValidationError{Field: "totalCost", Message: "must be >= 0"}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r CostUpdatedResponse) GetFeatureName() string {
+	return CostUpdatedFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r CostUpdatedResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP201
+}
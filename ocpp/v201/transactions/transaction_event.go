@@ -98,16 +98,16 @@ func (r TransactionEventRequest) GetProtocolVersion() common.ProtocolVersion {
 // Validate implements common.Request interface
 func (r TransactionEventRequest) Validate() error {
 	if r.EventType == "" {
-		return &ValidationError{Field: "eventType", Message: "required"}
+		return &ValidationError{Field: "eventType", Message: "required", Err: common.ErrRequired}
 	}
 	if r.TriggerReason == "" {
-		return &ValidationError{Field: "triggerReason", Message: "required"}
+		return &ValidationError{Field: "triggerReason", Message: "required", Err: common.ErrRequired}
 	}
 	if r.SeqNo < 0 {
 		return &ValidationError{Field: "seqNo", Message: "must be >= 0"}
 	}
 	if r.TransactionInfo.TransactionId == "" {
-		return &ValidationError{Field: "transactionInfo.transactionId", Message: "required"}
+		return &ValidationError{Field: "transactionInfo.transactionId", Message: "required", Err: common.ErrRequired}
 	}
 
 	// Validate IdToken if present
@@ -144,8 +144,13 @@ func (r TransactionEventResponse) GetProtocolVersion() common.ProtocolVersion {
 type ValidationError struct {
 	Field   string
 	Message string
+	Err     error // common.ErrRequired for a missing field
 }
 
 func (e *ValidationError) Error() string {
 	return e.Field + ": " + e.Message
 }
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
@@ -10,7 +10,7 @@ import (
 // ============================================================================
 // OCPP 2.0.1 Transaction Messages Tests
 // ============================================================================
-// Tests for TransactionEvent
+// Tests for TransactionEvent and CostUpdated
 // ============================================================================
 
 func TestTransactionEventRequest_Serialization_Started(t *testing.T) {
@@ -292,3 +292,23 @@ func floatPtr(f float64) *float64 {
 func intPtr(i int) *int {
 	return &i
 }
+
+func TestCostUpdatedRequest_Serialization(t *testing.T) {
+	req := CostUpdatedRequest{TotalCost: 4.37, TransactionId: "TX-0001"}
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if string(data) != `{"totalCost":4.37,"transactionId":"TX-0001"}` {
+		t.Errorf("serialized %s", data)
+	}
+	if err = req.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	if err = (CostUpdatedRequest{TotalCost: 1}).Validate(); err == nil {
+		t.Error("Validate() accepted a request without a transaction id")
+	}
+	if err = (CostUpdatedRequest{TotalCost: -1, TransactionId: "TX-0001"}).Validate(); err == nil {
+		t.Error("Validate() accepted a negative cost")
+	}
+}
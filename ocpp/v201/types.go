@@ -665,6 +665,21 @@ const (
 	AuthorizeCertificateStatusContractCancelled      AuthorizeCertificateStatusType = "ContractCancelled"      // Contract cancelled
 )
 
+// CertificateHashData identifies a certificate by hashes of its issuer and its serial number
+type CertificateHashData struct {
+	// HashAlgorithm is the algorithm used for hashing
+	HashAlgorithm HashAlgorithmType `json:"hashAlgorithm" validate:"required"`
+
+	// IssuerNameHash is the hash of the issuer's distinguished name
+	IssuerNameHash string `json:"issuerNameHash" validate:"required,max=128"`
+
+	// IssuerKeyHash is the hash of the issuer's public key
+	IssuerKeyHash string `json:"issuerKeyHash" validate:"required,max=128"`
+
+	// SerialNumber is the serial number of the certificate
+	SerialNumber string `json:"serialNumber" validate:"required,max=40"`
+}
+
 // OCSPRequestDataType contains data for OCSP request
 type OCSPRequestDataType struct {
 	// HashAlgorithm is the algorithm used for hashing
@@ -722,8 +737,13 @@ var (
 type ValidationError struct {
 	Field   string
 	Message string
+	Err     error // common.ErrRequired for a missing field
 }
 
 func (e *ValidationError) Error() string {
 	return e.Field + ": " + e.Message
 }
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v21/smartcharging"
+	"evsys/ocpp/v21/tariff"
+	"evsys/ocpp/v21/transactions"
+	"reflect"
+)
+
+// ============================================================================
+// Handler21 - OCPP 2.1 Feature Registration
+// ============================================================================
+// OCPP 2.1 keeps most 2.0.1 messages as they are on the wire, so a 2.1
+// charging station is served by the 2.0.1 types and handlers wherever 2.1
+// did not change a message. Handler21 registers the 2.0.1 features for 2.1,
+// then puts the 2.1 types in place of the messages 2.1 extended and adds
+// the messages that are new in 2.1.
+// ============================================================================
+
+// Handler21 registers the OCPP 2.1 features
+type Handler21 struct {
+	featureRegistry      common.FeatureRegistry
+	smartChargingHandler smartcharging.Handler
+	transactionsHandler  transactions.Handler
+}
+
+// Handler21Config holds the configuration for Handler21
+type Handler21Config struct {
+	SmartChargingHandler smartcharging.Handler
+	TransactionsHandler  transactions.Handler
+}
+
+// NewHandler21 creates the OCPP 2.1 handler; the 2.0.1 features must be registered already, see
+// NewHandler201
+func NewHandler21(config Handler21Config) *Handler21 {
+	h := &Handler21{
+		featureRegistry:      common.GetGlobalRegistry(),
+		smartChargingHandler: config.SmartChargingHandler,
+		transactionsHandler:  config.TransactionsHandler,
+	}
+	h.registerFeatures()
+	return h
+}
+
+// registerFeatures registers all OCPP 2.1 features with the global registry
+func (h *Handler21) registerFeatures() {
+	version := common.OCPP21
+
+	// the messages 2.1 left alone, with their 2.0.1 types
+	for _, action := range h.featureRegistry.GetFeatures(common.OCPP201) {
+		requestType, responseType, err := h.featureRegistry.GetTypes(common.OCPP201, action)
+		if err != nil {
+			continue
+		}
+		h.featureRegistry.RegisterFeature(version, action, requestType, responseType)
+	}
+
+	// ========================================================================
+	// TRANSACTION FEATURES (extended in 2.1)
+	// ========================================================================
+
+	h.featureRegistry.RegisterFeature(version, transactions.TransactionEventFeatureName,
+		reflect.TypeOf(transactions.TransactionEventRequest{}),
+		reflect.TypeOf(transactions.TransactionEventResponse{}))
+
+	// ========================================================================
+	// SMART CHARGING FEATURES
+	// ========================================================================
+
+	h.featureRegistry.RegisterFeature(version, smartcharging.NotifyPriorityChargingFeatureName,
+		reflect.TypeOf(smartcharging.NotifyPriorityChargingRequest{}),
+		reflect.TypeOf(smartcharging.NotifyPriorityChargingResponse{}))
+
+	// Smart Charging Commands (CSMS → Charging Station)
+	h.featureRegistry.RegisterFeature(version, smartcharging.SetChargingProfileFeatureName,
+		reflect.TypeOf(smartcharging.SetChargingProfileRequest{}),
+		reflect.TypeOf(smartcharging.SetChargingProfileResponse{}))
+
+	// ========================================================================
+	// TARIFF AND COST FEATURES (CSMS → Charging Station)
+	// ========================================================================
+
+	h.featureRegistry.RegisterFeature(version, tariff.SetDefaultTariffFeatureName,
+		reflect.TypeOf(tariff.SetDefaultTariffRequest{}),
+		reflect.TypeOf(tariff.SetDefaultTariffResponse{}))
+
+	h.featureRegistry.RegisterFeature(version, tariff.GetTariffsFeatureName,
+		reflect.TypeOf(tariff.GetTariffsRequest{}),
+		reflect.TypeOf(tariff.GetTariffsResponse{}))
+
+	h.featureRegistry.RegisterFeature(version, tariff.ClearTariffsFeatureName,
+		reflect.TypeOf(tariff.ClearTariffsRequest{}),
+		reflect.TypeOf(tariff.ClearTariffsResponse{}))
+}
+
+// GetVersion returns the protocol version this handler supports
+func (h *Handler21) GetVersion() common.ProtocolVersion {
+	return common.OCPP21
+}
+
+// SupportsFeature checks if a specific feature is supported by this handler
+func (h *Handler21) SupportsFeature(action string) bool {
+	return h.featureRegistry.IsSupported(common.OCPP21, action)
+}
+
+// GetSupportedFeatures returns all features supported by this handler
+func (h *Handler21) GetSupportedFeatures() []string {
+	return h.featureRegistry.GetFeatures(common.OCPP21)
+}
+
+// GetFeatureCount returns the number of features registered for this handler
+func (h *Handler21) GetFeatureCount() int {
+	return h.featureRegistry.GetFeatureCount(common.OCPP21)
+}
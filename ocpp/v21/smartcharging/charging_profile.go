@@ -0,0 +1,188 @@
+package smartcharging
+
+import (
+	"evsys/ocpp/v201"
+	"time"
+)
+
+// ============================================================================
+// Charging profiles - OCPP 2.1
+// ============================================================================
+// OCPP 2.1 extends the 2.0.1 charging profile for bidirectional charging:
+// a schedule period may carry a discharge limit and a setpoint, and names
+// the V2X operation mode it runs in. A period without these fields is the
+// 2.0.1 period, so a 2.0.1 profile is also a valid 2.1 profile.
+// ============================================================================
+
+// OperationModeType defines how the EVSE controls the energy flow during a schedule period
+type OperationModeType string
+
+const (
+	OperationModeIdle               OperationModeType = "Idle"               // No energy flow
+	OperationModeChargingOnly       OperationModeType = "ChargingOnly"       // Charging only, as in 2.0.1
+	OperationModeCentralSetpoint    OperationModeType = "CentralSetpoint"    // Power follows the setpoint of the CSMS
+	OperationModeExternalSetpoint   OperationModeType = "ExternalSetpoint"   // Power follows the setpoint of an external actor
+	OperationModeExternalLimits     OperationModeType = "ExternalLimits"     // Charge and discharge limits come from an external actor
+	OperationModeCentralFrequency   OperationModeType = "CentralFrequency"   // Frequency support controlled by the CSMS
+	OperationModeLocalFrequency     OperationModeType = "LocalFrequency"     // Frequency support controlled by the charging station
+	OperationModeLocalLoadBalancing OperationModeType = "LocalLoadBalancing" // Load balancing done by the charging station
+)
+
+// IsValid checks if the operation mode is valid
+func (m OperationModeType) IsValid() bool {
+	switch m {
+	case OperationModeIdle, OperationModeChargingOnly, OperationModeCentralSetpoint, OperationModeExternalSetpoint,
+		OperationModeExternalLimits, OperationModeCentralFrequency, OperationModeLocalFrequency, OperationModeLocalLoadBalancing:
+		return true
+	}
+	return false
+}
+
+// Discharges reports whether the mode lets energy flow from the EV
+func (m OperationModeType) Discharges() bool {
+	return m != "" && m != OperationModeIdle && m != OperationModeChargingOnly
+}
+
+// Charging profile purposes and kinds added in OCPP 2.1
+const (
+	ChargingProfilePurposePriorityCharging v201.ChargingProfilePurposeType = "PriorityCharging" // Applied while priority charging is active
+	ChargingProfilePurposeLocalGeneration  v201.ChargingProfilePurposeType = "LocalGeneration"  // Follows the local generation of the site
+	ChargingProfileKindDynamic             v201.ChargingProfileKindType    = "Dynamic"          // The limit of the current period is updated by the CSMS
+)
+
+// ChargingProfile defines an OCPP 2.1 charging profile
+type ChargingProfile struct {
+	// Id is the unique identifier for this profile
+	Id int `json:"id" validate:"required"`
+
+	// StackLevel defines the level of this profile (0-based)
+	StackLevel int `json:"stackLevel" validate:"min=0"`
+
+	// ChargingProfilePurpose defines the purpose
+	ChargingProfilePurpose v201.ChargingProfilePurposeType `json:"chargingProfilePurpose" validate:"required"`
+
+	// ChargingProfileKind defines the kind
+	ChargingProfileKind v201.ChargingProfileKindType `json:"chargingProfileKind" validate:"required"`
+
+	// RecurrencyKind defines the recurrency (required if kind is Recurring)
+	RecurrencyKind v201.RecurrencyKindType `json:"recurrencyKind,omitempty"`
+
+	// ValidFrom is when the profile becomes valid
+	ValidFrom *time.Time `json:"validFrom,omitempty"`
+
+	// ValidTo is when the profile becomes invalid
+	ValidTo *time.Time `json:"validTo,omitempty"`
+
+	// TransactionId associates this profile with a transaction
+	TransactionId string `json:"transactionId,omitempty" validate:"omitempty,max=36"`
+
+	// MaxOfflineDuration is how long, in seconds, the profile stays in force while the station is offline
+	MaxOfflineDuration *int `json:"maxOfflineDuration,omitempty"`
+
+	// InvalidAfterOfflineDuration drops the profile for good once MaxOfflineDuration has passed
+	InvalidAfterOfflineDuration *bool `json:"invalidAfterOfflineDuration,omitempty"`
+
+	// DynUpdateInterval is how often, in seconds, a Dynamic profile is updated
+	DynUpdateInterval *int `json:"dynUpdateInterval,omitempty"`
+
+	// DynUpdateTime is when a Dynamic profile was last updated
+	DynUpdateTime *time.Time `json:"dynUpdateTime,omitempty"`
+
+	// ChargingSchedule defines the charging schedule
+	ChargingSchedule []ChargingSchedule `json:"chargingSchedule" validate:"required,min=1,max=3,dive"`
+}
+
+// ChargingSchedule defines an OCPP 2.1 charging schedule
+type ChargingSchedule struct {
+	// Id is the unique identifier for this schedule
+	Id int `json:"id" validate:"required"`
+
+	// LimitAtSoC limits the power once the EV reaches a state of charge
+	LimitAtSoC *LimitAtSoC `json:"limitAtSoC,omitempty"`
+
+	// StartSchedule is when the schedule starts (optional for relative schedules)
+	StartSchedule *time.Time `json:"startSchedule,omitempty"`
+
+	// Duration is the duration of the schedule in seconds
+	Duration *int `json:"duration,omitempty" validate:"omitempty,min=0"`
+
+	// ChargingRateUnit is the unit for the charging rate
+	ChargingRateUnit v201.ChargingRateUnitType `json:"chargingRateUnit" validate:"required"`
+
+	// MinChargingRate is the minimum charging rate
+	MinChargingRate *float64 `json:"minChargingRate,omitempty" validate:"omitempty,min=0"`
+
+	// PowerTolerance is how far, in kW, the power may stray from the setpoint
+	PowerTolerance *float64 `json:"powerTolerance,omitempty"`
+
+	// UseLocalTime reads the times of a recurring schedule in the local time of the station
+	UseLocalTime *bool `json:"useLocalTime,omitempty"`
+
+	// RandomizedDelay is the upper bound, in seconds, of a random delay at each period start
+	RandomizedDelay *int `json:"randomizedDelay,omitempty" validate:"omitempty,min=0"`
+
+	// ChargingSchedulePeriod defines the schedule periods
+	ChargingSchedulePeriod []ChargingSchedulePeriod `json:"chargingSchedulePeriod" validate:"required,min=1,dive"`
+
+	// SalesTariff is the sales tariff associated with this schedule
+	SalesTariff *v201.SalesTariff `json:"salesTariff,omitempty"`
+}
+
+// LimitAtSoC caps the charging rate from a state of charge on
+type LimitAtSoC struct {
+	// Soc is the state of charge, in percent, from which the limit applies
+	Soc int `json:"soc" validate:"min=0,max=100"`
+
+	// Limit is the charging rate limit, in the unit of the schedule
+	Limit float64 `json:"limit"`
+}
+
+// ChargingSchedulePeriod defines a time period in an OCPP 2.1 charging schedule. Positive values
+// charge the EV and negative values discharge it; the _L2 and _L3 values apply to the other phases
+// when they differ from the first.
+type ChargingSchedulePeriod struct {
+	// StartPeriod is the start of the period in seconds from schedule start
+	StartPeriod int `json:"startPeriod" validate:"min=0"`
+
+	// Limit is the charging rate limit; optional in 2.1, where a setpoint may take its place
+	Limit *float64 `json:"limit,omitempty"`
+	// Limit_L2 is the charging rate limit on phase 2
+	Limit_L2 *float64 `json:"limit_L2,omitempty"`
+	// Limit_L3 is the charging rate limit on phase 3
+	Limit_L3 *float64 `json:"limit_L3,omitempty"`
+
+	// NumberPhases is the number of phases to use
+	NumberPhases *int `json:"numberPhases,omitempty" validate:"omitempty,min=1,max=3"`
+
+	// PhaseToUse is which phase to use for single-phase charging
+	PhaseToUse *int `json:"phaseToUse,omitempty" validate:"omitempty,min=1,max=3"`
+
+	// DischargeLimit is the discharging rate limit, 0 or negative
+	DischargeLimit *float64 `json:"dischargeLimit,omitempty" validate:"omitempty,max=0"`
+	// DischargeLimit_L2 is the discharging rate limit on phase 2
+	DischargeLimit_L2 *float64 `json:"dischargeLimit_L2,omitempty" validate:"omitempty,max=0"`
+	// DischargeLimit_L3 is the discharging rate limit on phase 3
+	DischargeLimit_L3 *float64 `json:"dischargeLimit_L3,omitempty" validate:"omitempty,max=0"`
+
+	// Setpoint is the rate the EVSE aims for in the setpoint modes; negative discharges
+	Setpoint *float64 `json:"setpoint,omitempty"`
+	// Setpoint_L2 is the setpoint on phase 2
+	Setpoint_L2 *float64 `json:"setpoint_L2,omitempty"`
+	// Setpoint_L3 is the setpoint on phase 3
+	Setpoint_L3 *float64 `json:"setpoint_L3,omitempty"`
+
+	// SetpointReactive is the reactive power setpoint
+	SetpointReactive *float64 `json:"setpointReactive,omitempty"`
+
+	// PreconditioningRequest asks the EV to precondition its battery during the period
+	PreconditioningRequest *bool `json:"preconditioningRequest,omitempty"`
+
+	// EvseSleep lets the EVSE go to sleep during the period
+	EvseSleep *bool `json:"evseSleep,omitempty"`
+
+	// V2xBaseline is the power, in W, the station counts as its baseline in frequency support
+	V2xBaseline *float64 `json:"v2xBaseline,omitempty"`
+
+	// OperationMode is the V2X mode of the period; ChargingOnly when absent
+	OperationMode OperationModeType `json:"operationMode,omitempty"`
+}
@@ -0,0 +1,15 @@
+package smartcharging
+
+// ============================================================================
+// Smart Charging Handler Interface - OCPP 2.1
+// ============================================================================
+// The 2.0.1 smart charging messages keep their 2.0.1 handler; this one adds
+// the messages only a 2.1 charging station sends.
+// ============================================================================
+
+// Handler defines the interface for handling OCPP 2.1 smart charging messages
+type Handler interface {
+	// OnNotifyPriorityCharging handles incoming NotifyPriorityCharging requests
+	// Called when priority charging is switched on or off for a transaction
+	OnNotifyPriorityCharging(chargePointId string, request *NotifyPriorityChargingRequest) (*NotifyPriorityChargingResponse, error)
+}
@@ -0,0 +1,60 @@
+package smartcharging
+
+import (
+	"evsys/ocpp/common"
+)
+
+// ============================================================================
+// NotifyPriorityCharging - OCPP 2.1
+// ============================================================================
+// Sent by: Charging Station → CSMS
+// Purpose: Tell that priority charging was switched on or off for a
+//          transaction, for instance by the driver at the station. While it
+//          is active the station charges by its PriorityCharging profile and
+//          ignores the limits of the CSMS.
+// ============================================================================
+
+const NotifyPriorityChargingFeatureName = "NotifyPriorityCharging"
+
+// NotifyPriorityChargingRequest represents the request for NotifyPriorityCharging
+type NotifyPriorityChargingRequest struct {
+	// TransactionId is the transaction priority charging applies to
+	TransactionId string `json:"transactionId" validate:"required,max=36"`
+
+	// Activated is true when priority charging was switched on, false when it ended
+	Activated bool `json:"activated"`
+}
+
+// NotifyPriorityChargingResponse represents the response to NotifyPriorityCharging; it has no fields
+type NotifyPriorityChargingResponse struct{}
+
+// GetFeatureName implements common.Request interface
+func (r NotifyPriorityChargingRequest) GetFeatureName() string {
+	return NotifyPriorityChargingFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r NotifyPriorityChargingRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP21
+}
+
+// Validate implements common.Request interface
+func (r NotifyPriorityChargingRequest) Validate() error {
+	if r.TransactionId == "" {
+		return &ValidationError{Field: "transactionId", Message: "required", Err: common.ErrRequired}
+	}
+	if len(r.TransactionId) > 36 {
+		return &ValidationError{Field: "transactionId", Message: "max 36 characters"}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r NotifyPriorityChargingResponse) GetFeatureName() string {
+	return NotifyPriorityChargingFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r NotifyPriorityChargingResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP21
+}
@@ -0,0 +1,130 @@
+package smartcharging
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+	smartcharging201 "evsys/ocpp/v201/smartcharging"
+	"fmt"
+)
+
+// ============================================================================
+// SetChargingProfile - OCPP 2.1
+// ============================================================================
+// Sent by: CSMS → Charging Station
+// Purpose: Install a charging profile, as in 2.0.1, with the 2.1 schedule
+//          periods that may discharge the EV. The answer is the 2.0.1 one.
+// ============================================================================
+
+const SetChargingProfileFeatureName = smartcharging201.SetChargingProfileFeatureName
+
+// SetChargingProfileRequest represents the request for SetChargingProfile
+type SetChargingProfileRequest struct {
+	// EvseId is the EVSE the profile applies to; 0 applies it to the charging station
+	EvseId int `json:"evseId" validate:"min=0"`
+
+	// ChargingProfile is the profile to install
+	ChargingProfile ChargingProfile `json:"chargingProfile" validate:"required"`
+}
+
+// SetChargingProfileResponse represents the response to SetChargingProfile
+type SetChargingProfileResponse = smartcharging201.SetChargingProfileResponse
+
+// GetFeatureName implements common.Request interface
+func (r SetChargingProfileRequest) GetFeatureName() string {
+	return SetChargingProfileFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r SetChargingProfileRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP21
+}
+
+// Validate implements common.Request interface
+func (r SetChargingProfileRequest) Validate() error {
+	if r.EvseId < 0 {
+		return &ValidationError{Field: "evseId", Message: "must be >= 0"}
+	}
+	profile := r.ChargingProfile
+	if profile.StackLevel < 0 {
+		return &ValidationError{Field: "chargingProfile.stackLevel", Message: "must be >= 0"}
+	}
+	if profile.ChargingProfilePurpose == "" {
+		return &ValidationError{Field: "chargingProfile.chargingProfilePurpose", Message: "required", Err: common.ErrRequired}
+	}
+	if profile.ChargingProfileKind == "" {
+		return &ValidationError{Field: "chargingProfile.chargingProfileKind", Message: "required", Err: common.ErrRequired}
+	}
+	if profile.ChargingProfileKind == v201.ChargingProfileKindRecurring && profile.RecurrencyKind == "" {
+		return &ValidationError{Field: "chargingProfile.recurrencyKind", Message: "required for a recurring profile", Err: common.ErrRequired}
+	}
+	if profile.ChargingProfilePurpose == v201.ChargingProfilePurposeTxProfile && profile.TransactionId == "" {
+		return &ValidationError{Field: "chargingProfile.transactionId", Message: "required for a TxProfile", Err: common.ErrRequired}
+	}
+	if profile.ChargingProfileKind == ChargingProfileKindDynamic && profile.DynUpdateInterval == nil {
+		return &ValidationError{Field: "chargingProfile.dynUpdateInterval", Message: "required for a dynamic profile", Err: common.ErrRequired}
+	}
+	if len(profile.ChargingSchedule) == 0 || len(profile.ChargingSchedule) > 3 {
+		return &ValidationError{Field: "chargingProfile.chargingSchedule", Message: "1 to 3 schedules required", Err: common.ErrRequired}
+	}
+	for _, schedule := range profile.ChargingSchedule {
+		if schedule.ChargingRateUnit == "" {
+			return &ValidationError{Field: "chargingSchedule.chargingRateUnit", Message: "required", Err: common.ErrRequired}
+		}
+		if len(schedule.ChargingSchedulePeriod) == 0 {
+			return &ValidationError{Field: "chargingSchedule.chargingSchedulePeriod", Message: "at least one period required", Err: common.ErrRequired}
+		}
+		for i, period := range schedule.ChargingSchedulePeriod {
+			if err := validatePeriod(period); err != nil {
+				err.Field = fmt.Sprintf("chargingSchedulePeriod[%d].%s", i, err.Field)
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validatePeriod checks that a period keeps to its operation mode: discharging needs a mode that
+// allows it, and the setpoint modes need a setpoint to follow.
+func validatePeriod(period ChargingSchedulePeriod) *ValidationError {
+	if period.StartPeriod < 0 {
+		return &ValidationError{Field: "startPeriod", Message: "must be >= 0"}
+	}
+	if period.OperationMode != "" && !period.OperationMode.IsValid() {
+		return &ValidationError{Field: "operationMode", Message: "invalid mode " + string(period.OperationMode)}
+	}
+	for _, limit := range []*float64{period.DischargeLimit, period.DischargeLimit_L2, period.DischargeLimit_L3} {
+		if limit != nil && *limit > 0 {
+			return &ValidationError{Field: "dischargeLimit", Message: "must be <= 0"}
+		}
+	}
+	discharging := period.DischargeLimit != nil || (period.Setpoint != nil && *period.Setpoint < 0)
+	if discharging && !period.OperationMode.Discharges() {
+		return &ValidationError{Field: "operationMode", Message: "discharging needs a V2X operation mode"}
+	}
+	switch period.OperationMode {
+	case OperationModeCentralSetpoint, OperationModeExternalSetpoint:
+		if period.Setpoint == nil {
+			return &ValidationError{Field: "setpoint", Message: "required in " + string(period.OperationMode), Err: common.ErrRequired}
+		}
+	case "", OperationModeChargingOnly:
+		if period.Limit == nil {
+			return &ValidationError{Field: "limit", Message: "required when charging only", Err: common.ErrRequired}
+		}
+	}
+	return nil
+}
+
+// ValidationError represents a validation error
+type ValidationError struct {
+	Field   string
+	Message string
+	Err     error // common.ErrRequired for a missing field
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,133 @@
+package smartcharging
+
+import (
+	"encoding/json"
+	"evsys/ocpp/v201"
+	smartcharging201 "evsys/ocpp/v201/smartcharging"
+	"strings"
+	"testing"
+)
+
+// ============================================================================
+// OCPP 2.1 Smart Charging Messages Tests
+// ============================================================================
+// Tests for SetChargingProfile with V2X schedule periods and
+// NotifyPriorityCharging
+// ============================================================================
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+// v2xProfile discharges the EV at the setpoint of the CSMS on EVSE 1
+func v2xProfile(period ChargingSchedulePeriod) ChargingProfile {
+	return ChargingProfile{
+		Id:                     20,
+		StackLevel:             2,
+		ChargingProfilePurpose: v201.ChargingProfilePurposeTxDefaultProfile,
+		ChargingProfileKind:    v201.ChargingProfileKindAbsolute,
+		ChargingSchedule: []ChargingSchedule{{
+			Id:                     20,
+			ChargingRateUnit:       v201.ChargingRateUnitW,
+			ChargingSchedulePeriod: []ChargingSchedulePeriod{period},
+		}},
+	}
+}
+
+func TestSetChargingProfileRequest_Serialization(t *testing.T) {
+	req := SetChargingProfileRequest{EvseId: 1, ChargingProfile: v2xProfile(ChargingSchedulePeriod{
+		Setpoint:       floatPtr(-7000),
+		DischargeLimit: floatPtr(-11000),
+		OperationMode:  OperationModeCentralSetpoint,
+	})}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	for _, field := range []string{`"setpoint":-7000`, `"dischargeLimit":-11000`, `"operationMode":"CentralSetpoint"`} {
+		if !strings.Contains(string(data), field) {
+			t.Errorf("serialized %s, want %s", data, field)
+		}
+	}
+	if strings.Contains(string(data), `"limit"`) {
+		t.Errorf("serialized %s, want no limit in a setpoint period", data)
+	}
+
+	var decoded SetChargingProfileRequest
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if err = decoded.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
+
+// A 2.0.1 profile on the wire is a valid 2.1 profile.
+func TestSetChargingProfileRequest_AcceptsV201Profile(t *testing.T) {
+	data, err := json.Marshal(smartcharging201.NewSetChargingProfileRequest(0, smartcharging201.NewDefaultChargingProfile(32)))
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var decoded SetChargingProfileRequest
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if err = decoded.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	if limit := decoded.ChargingProfile.ChargingSchedule[0].ChargingSchedulePeriod[0].Limit; limit == nil || *limit != 32 {
+		t.Errorf("Limit = %v, want 32", limit)
+	}
+}
+
+func TestSetChargingProfileRequest_Validate(t *testing.T) {
+	dynamic := v2xProfile(ChargingSchedulePeriod{Limit: floatPtr(11000)})
+	dynamic.ChargingProfileKind = ChargingProfileKindDynamic
+
+	tests := []struct {
+		name   string
+		period ChargingSchedulePeriod
+	}{
+		{"positive discharge limit", ChargingSchedulePeriod{DischargeLimit: floatPtr(5000), OperationMode: OperationModeExternalLimits}},
+		{"discharging while charging only", ChargingSchedulePeriod{Limit: floatPtr(11000), DischargeLimit: floatPtr(-5000)}},
+		{"negative setpoint without a V2X mode", ChargingSchedulePeriod{Limit: floatPtr(11000), Setpoint: floatPtr(-5000)}},
+		{"central setpoint without a setpoint", ChargingSchedulePeriod{OperationMode: OperationModeCentralSetpoint}},
+		{"charging without a limit", ChargingSchedulePeriod{OperationMode: OperationModeChargingOnly}},
+		{"unknown mode", ChargingSchedulePeriod{Limit: floatPtr(11000), OperationMode: "Turbo"}},
+	}
+	for _, tt := range tests {
+		req := SetChargingProfileRequest{EvseId: 1, ChargingProfile: v2xProfile(tt.period)}
+		if err := req.Validate(); err == nil {
+			t.Errorf("%s: Validate() error = nil, want an error", tt.name)
+		}
+	}
+	if err := (SetChargingProfileRequest{ChargingProfile: dynamic}).Validate(); err == nil {
+		t.Error("dynamic profile without update interval: Validate() error = nil, want an error")
+	}
+	idle := SetChargingProfileRequest{EvseId: 1, ChargingProfile: v2xProfile(ChargingSchedulePeriod{OperationMode: OperationModeIdle})}
+	if err := idle.Validate(); err != nil {
+		t.Errorf("idle period: Validate() error = %v", err)
+	}
+}
+
+func TestNotifyPriorityChargingRequest_Serialization(t *testing.T) {
+	var req NotifyPriorityChargingRequest
+	if err := json.Unmarshal([]byte(`{"transactionId":"tx-7","activated":true}`), &req); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if req.TransactionId != "tx-7" || !req.Activated {
+		t.Errorf("decoded %+v, want tx-7 activated", req)
+	}
+	if err := req.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	if err := (NotifyPriorityChargingRequest{Activated: true}).Validate(); err == nil {
+		t.Error("no transaction id: Validate() error = nil, want an error")
+	}
+
+	data, err := json.Marshal(NotifyPriorityChargingResponse{})
+	if err != nil || string(data) != "{}" {
+		t.Errorf("response serialized %s, %v; want {}", data, err)
+	}
+}
@@ -0,0 +1,85 @@
+package tariff
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+)
+
+// ============================================================================
+// ClearTariffs - OCPP 2.1
+// ============================================================================
+// Sent by: CSMS → Charging Station
+// Purpose: Remove installed tariffs, selected by id and EVSE. Without ids
+//          every tariff goes; without an EVSE they go from every EVSE.
+// ============================================================================
+
+const ClearTariffsFeatureName = "ClearTariffs"
+
+// TariffClearStatusType defines the result of removing a tariff
+type TariffClearStatusType string
+
+const (
+	TariffClearStatusAccepted TariffClearStatusType = "Accepted" // Tariff removed
+	TariffClearStatusRejected TariffClearStatusType = "Rejected" // Tariff kept
+	TariffClearStatusNoTariff TariffClearStatusType = "NoTariff" // No such tariff
+)
+
+// ClearTariffsResultType is the outcome for one tariff
+type ClearTariffsResultType struct {
+	// TariffId identifies the tariff; absent when the request named none
+	TariffId string `json:"tariffId,omitempty" validate:"omitempty,max=60"`
+
+	// Status indicates whether the tariff was removed
+	Status TariffClearStatusType `json:"status" validate:"required"`
+
+	// StatusInfo provides additional status information
+	StatusInfo *v201.StatusInfo `json:"statusInfo,omitempty"`
+}
+
+// ClearTariffsRequest represents the request for ClearTariffs
+type ClearTariffsRequest struct {
+	// TariffIds are the tariffs to remove; empty removes every tariff
+	TariffIds []string `json:"tariffIds,omitempty"`
+
+	// EvseId limits the removal to an EVSE
+	EvseId *int `json:"evseId,omitempty" validate:"omitempty,min=0"`
+}
+
+// ClearTariffsResponse represents the response to ClearTariffs
+type ClearTariffsResponse struct {
+	// ClearTariffsResult has the outcome for each tariff
+	ClearTariffsResult []ClearTariffsResultType `json:"clearTariffsResult" validate:"required,min=1,dive"`
+}
+
+// GetFeatureName implements common.Request interface
+func (r ClearTariffsRequest) GetFeatureName() string {
+	return ClearTariffsFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r ClearTariffsRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP21
+}
+
+// Validate implements common.Request interface
+func (r ClearTariffsRequest) Validate() error {
+	if r.EvseId != nil && *r.EvseId < 0 {
+		return &ValidationError{Field: "evseId", Message: "must be >= 0"}
+	}
+	for _, id := range r.TariffIds {
+		if id == "" || len(id) > 60 {
+			return &ValidationError{Field: "tariffIds", Message: "ids of 1 to 60 characters required"}
+		}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r ClearTariffsResponse) GetFeatureName() string {
+	return ClearTariffsFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r ClearTariffsResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP21
+}
@@ -0,0 +1,90 @@
+package tariff
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+	"time"
+)
+
+// ============================================================================
+// GetTariffs - OCPP 2.1
+// ============================================================================
+// Sent by: CSMS → Charging Station
+// Purpose: Ask which tariffs are installed on an EVSE, or on every EVSE
+//          with EVSE 0, and to which EVSEs and drivers they are assigned.
+// ============================================================================
+
+const GetTariffsFeatureName = "GetTariffs"
+
+// TariffGetStatusType defines the result of GetTariffs
+type TariffGetStatusType string
+
+const (
+	TariffGetStatusAccepted TariffGetStatusType = "Accepted" // Tariffs listed
+	TariffGetStatusRejected TariffGetStatusType = "Rejected" // Request refused
+	TariffGetStatusNoTariff TariffGetStatusType = "NoTariff" // No tariff installed
+)
+
+// TariffAssignmentType tells where a tariff is in use
+type TariffAssignmentType struct {
+	// TariffId identifies the tariff
+	TariffId string `json:"tariffId" validate:"required,max=60"`
+
+	// TariffKind tells whether it is a default or a driver tariff
+	TariffKind TariffKindType `json:"tariffKind" validate:"required"`
+
+	// ValidFrom is when the tariff starts to apply
+	ValidFrom *time.Time `json:"validFrom,omitempty"`
+
+	// EvseIds are the EVSEs the tariff is installed on
+	EvseIds []int `json:"evseIds,omitempty"`
+
+	// IdTokens are the drivers a driver tariff belongs to
+	IdTokens []string `json:"idTokens,omitempty"`
+}
+
+// GetTariffsRequest represents the request for GetTariffs
+type GetTariffsRequest struct {
+	// EvseId is the EVSE to list the tariffs of; 0 lists them for every EVSE
+	EvseId int `json:"evseId" validate:"min=0"`
+}
+
+// GetTariffsResponse represents the response to GetTariffs
+type GetTariffsResponse struct {
+	// Status indicates whether the tariffs are listed
+	Status TariffGetStatusType `json:"status" validate:"required"`
+
+	// StatusInfo provides additional status information
+	StatusInfo *v201.StatusInfo `json:"statusInfo,omitempty"`
+
+	// TariffAssignments lists the installed tariffs
+	TariffAssignments []TariffAssignmentType `json:"tariffAssignments,omitempty" validate:"omitempty,dive"`
+}
+
+// GetFeatureName implements common.Request interface
+func (r GetTariffsRequest) GetFeatureName() string {
+	return GetTariffsFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r GetTariffsRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP21
+}
+
+// Validate implements common.Request interface
+func (r GetTariffsRequest) Validate() error {
+	if r.EvseId < 0 {
+		return &ValidationError{Field: "evseId", Message: "must be >= 0"}
+	}
+	return nil
+}
+
+// GetFeatureName implements common.Response interface
+func (r GetTariffsResponse) GetFeatureName() string {
+	return GetTariffsFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r GetTariffsResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP21
+}
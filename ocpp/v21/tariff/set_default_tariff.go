@@ -0,0 +1,74 @@
+package tariff
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+)
+
+// ============================================================================
+// SetDefaultTariff - OCPP 2.1
+// ============================================================================
+// Sent by: CSMS → Charging Station
+// Purpose: Install the tariff the charging station calculates costs with
+//          when the authorization of a driver brings no tariff of its own.
+//          EVSE 0 installs it on every EVSE.
+// ============================================================================
+
+const SetDefaultTariffFeatureName = "SetDefaultTariff"
+
+// TariffSetStatusType defines the result of installing a tariff
+type TariffSetStatusType string
+
+const (
+	TariffSetStatusAccepted              TariffSetStatusType = "Accepted"              // Tariff installed
+	TariffSetStatusRejected              TariffSetStatusType = "Rejected"              // Tariff refused
+	TariffSetStatusTooManyElements       TariffSetStatusType = "TooManyElements"       // More prices or conditions than the station holds
+	TariffSetStatusConditionNotSupported TariffSetStatusType = "ConditionNotSupported" // A condition the station cannot evaluate
+	TariffSetStatusDuplicateTariffId     TariffSetStatusType = "DuplicateTariffId"     // The tariff id is in use with other prices
+)
+
+// SetDefaultTariffRequest represents the request for SetDefaultTariff
+type SetDefaultTariffRequest struct {
+	// EvseId is the EVSE the tariff applies to; 0 applies it to every EVSE
+	EvseId int `json:"evseId" validate:"min=0"`
+
+	// Tariff is the tariff to install
+	Tariff TariffType `json:"tariff" validate:"required"`
+}
+
+// SetDefaultTariffResponse represents the response to SetDefaultTariff
+type SetDefaultTariffResponse struct {
+	// Status indicates whether the tariff was installed
+	Status TariffSetStatusType `json:"status" validate:"required"`
+
+	// StatusInfo provides additional status information
+	StatusInfo *v201.StatusInfo `json:"statusInfo,omitempty"`
+}
+
+// GetFeatureName implements common.Request interface
+func (r SetDefaultTariffRequest) GetFeatureName() string {
+	return SetDefaultTariffFeatureName
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r SetDefaultTariffRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP21
+}
+
+// Validate implements common.Request interface
+func (r SetDefaultTariffRequest) Validate() error {
+	if r.EvseId < 0 {
+		return &ValidationError{Field: "evseId", Message: "must be >= 0"}
+	}
+	return r.Tariff.Validate()
+}
+
+// GetFeatureName implements common.Response interface
+func (r SetDefaultTariffResponse) GetFeatureName() string {
+	return SetDefaultTariffFeatureName
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r SetDefaultTariffResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP21
+}
@@ -0,0 +1,274 @@
+package tariff
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+	"time"
+)
+
+// ============================================================================
+// Tariffs and cost details - OCPP 2.1
+// ============================================================================
+// OCPP 2.1 lets the charging station calculate the cost of a transaction
+// itself: the CSMS installs a tariff, and the station reports the cost it
+// arrived at in the costDetails of TransactionEvent. The prices are in the
+// currency of the tariff, without and with tax.
+// ============================================================================
+
+// TariffKindType tells how a tariff was assigned
+type TariffKindType string
+
+const (
+	TariffKindDefault TariffKindType = "DefaultTariff" // Set with SetDefaultTariff, for every driver
+	TariffKindDriver  TariffKindType = "DriverTariff"  // Sent with the authorization of a driver
+)
+
+// TaxRateType is a tax applied to a price
+type TaxRateType struct {
+	// Type names the tax, like "VAT"
+	Type string `json:"type" validate:"required,max=20"`
+
+	// Tax is the rate in percent
+	Tax float64 `json:"tax"`
+
+	// Stack orders taxes applied on top of each other; 0 applies to the price without tax
+	Stack *int `json:"stack,omitempty" validate:"omitempty,min=0"`
+}
+
+// PriceType is an amount without and, optionally, with tax
+type PriceType struct {
+	// ExclTax is the price without tax
+	ExclTax *float64 `json:"exclTax,omitempty"`
+
+	// InclTax is the price with tax
+	InclTax *float64 `json:"inclTax,omitempty"`
+
+	// TaxRates are the taxes the difference is made of
+	TaxRates []TaxRateType `json:"taxRates,omitempty" validate:"omitempty,max=5,dive"`
+}
+
+// TariffConditionsType restricts when a price applies; empty fields do not restrict
+type TariffConditionsType struct {
+	// StartTimeOfDay is the local time, as hh:mm, the price starts to apply
+	StartTimeOfDay string `json:"startTimeOfDay,omitempty"`
+
+	// EndTimeOfDay is the local time, as hh:mm, the price stops to apply
+	EndTimeOfDay string `json:"endTimeOfDay,omitempty"`
+
+	// DayOfWeek lists the days the price applies, like "Monday"
+	DayOfWeek []string `json:"dayOfWeek,omitempty" validate:"omitempty,max=7"`
+
+	// MinEnergy is the energy, in Wh, charged before the price applies
+	MinEnergy *float64 `json:"minEnergy,omitempty"`
+
+	// MaxEnergy is the energy, in Wh, charged after which the price no longer applies
+	MaxEnergy *float64 `json:"maxEnergy,omitempty"`
+
+	// MinTime is the duration of the transaction, in seconds, before the price applies
+	MinTime *int `json:"minTime,omitempty"`
+
+	// MaxTime is the duration of the transaction, in seconds, after which the price no longer applies
+	MaxTime *int `json:"maxTime,omitempty"`
+
+	// MinPower is the power, in W, from which the price applies
+	MinPower *float64 `json:"minPower,omitempty"`
+
+	// MaxPower is the power, in W, up to which the price applies
+	MaxPower *float64 `json:"maxPower,omitempty"`
+}
+
+// TariffEnergyPriceType is a price per kWh
+type TariffEnergyPriceType struct {
+	// PriceKwh is the price of one kWh without tax
+	PriceKwh float64 `json:"priceKwh"`
+
+	// Conditions restrict when the price applies
+	Conditions *TariffConditionsType `json:"conditions,omitempty"`
+}
+
+// TariffEnergyType prices the energy charged
+type TariffEnergyType struct {
+	// Prices are tried in order; the first whose conditions hold applies
+	Prices []TariffEnergyPriceType `json:"prices" validate:"required,min=1,dive"`
+
+	// TaxRates apply to the prices
+	TaxRates []TaxRateType `json:"taxRates,omitempty" validate:"omitempty,max=5,dive"`
+}
+
+// TariffTimePriceType is a price per minute
+type TariffTimePriceType struct {
+	// PriceMinute is the price of one minute without tax
+	PriceMinute float64 `json:"priceMinute"`
+
+	// Conditions restrict when the price applies
+	Conditions *TariffConditionsType `json:"conditions,omitempty"`
+}
+
+// TariffTimeType prices the time spent charging or idle
+type TariffTimeType struct {
+	// Prices are tried in order; the first whose conditions hold applies
+	Prices []TariffTimePriceType `json:"prices" validate:"required,min=1,dive"`
+
+	// TaxRates apply to the prices
+	TaxRates []TaxRateType `json:"taxRates,omitempty" validate:"omitempty,max=5,dive"`
+}
+
+// TariffFixedPriceType is a price per transaction
+type TariffFixedPriceType struct {
+	// PriceFixed is the fixed price without tax
+	PriceFixed float64 `json:"priceFixed"`
+
+	// Conditions restrict when the price applies
+	Conditions *TariffConditionsType `json:"conditions,omitempty"`
+}
+
+// TariffFixedType prices a transaction as a whole
+type TariffFixedType struct {
+	// Prices are tried in order; the first whose conditions hold applies
+	Prices []TariffFixedPriceType `json:"prices" validate:"required,min=1,dive"`
+
+	// TaxRates apply to the prices
+	TaxRates []TaxRateType `json:"taxRates,omitempty" validate:"omitempty,max=5,dive"`
+}
+
+// TariffType is a tariff the charging station calculates costs with
+type TariffType struct {
+	// TariffId identifies the tariff
+	TariffId string `json:"tariffId" validate:"required,max=60"`
+
+	// Description is shown to the driver
+	Description []v201.MessageContent `json:"description,omitempty" validate:"omitempty,max=10"`
+
+	// Currency is the ISO 4217 code of the currency of the prices
+	Currency string `json:"currency" validate:"required,len=3"`
+
+	// ValidFrom is when the tariff starts to apply; now when absent
+	ValidFrom *time.Time `json:"validFrom,omitempty"`
+
+	// Energy prices the energy charged
+	Energy *TariffEnergyType `json:"energy,omitempty"`
+
+	// ChargingTime prices the time spent charging
+	ChargingTime *TariffTimeType `json:"chargingTime,omitempty"`
+
+	// IdleTime prices the time connected without charging
+	IdleTime *TariffTimeType `json:"idleTime,omitempty"`
+
+	// FixedFee prices the transaction as a whole
+	FixedFee *TariffFixedType `json:"fixedFee,omitempty"`
+
+	// MinCost is the least a transaction costs
+	MinCost *PriceType `json:"minCost,omitempty"`
+
+	// MaxCost is the most a transaction costs
+	MaxCost *PriceType `json:"maxCost,omitempty"`
+}
+
+// Validate checks the parts of a tariff the charging station relies on
+func (t TariffType) Validate() error {
+	if t.TariffId == "" {
+		return &ValidationError{Field: "tariff.tariffId", Message: "required", Err: common.ErrRequired}
+	}
+	if len(t.TariffId) > 60 {
+		return &ValidationError{Field: "tariff.tariffId", Message: "max 60 characters"}
+	}
+	if len(t.Currency) != 3 {
+		return &ValidationError{Field: "tariff.currency", Message: "must be an ISO 4217 code"}
+	}
+	if t.Energy == nil && t.ChargingTime == nil && t.IdleTime == nil && t.FixedFee == nil {
+		return &ValidationError{Field: "tariff", Message: "at least one of energy, chargingTime, idleTime and fixedFee required", Err: common.ErrRequired}
+	}
+	if t.Energy != nil && len(t.Energy.Prices) == 0 {
+		return &ValidationError{Field: "tariff.energy.prices", Message: "at least one price required", Err: common.ErrRequired}
+	}
+	if t.ChargingTime != nil && len(t.ChargingTime.Prices) == 0 {
+		return &ValidationError{Field: "tariff.chargingTime.prices", Message: "at least one price required", Err: common.ErrRequired}
+	}
+	if t.IdleTime != nil && len(t.IdleTime.Prices) == 0 {
+		return &ValidationError{Field: "tariff.idleTime.prices", Message: "at least one price required", Err: common.ErrRequired}
+	}
+	if t.FixedFee != nil && len(t.FixedFee.Prices) == 0 {
+		return &ValidationError{Field: "tariff.fixedFee.prices", Message: "at least one price required", Err: common.ErrRequired}
+	}
+	return nil
+}
+
+// CostKindType tells which bound, if any, the total cost was held to
+type CostKindType string
+
+const (
+	CostKindNormal  CostKindType = "Normal"  // Calculated from the tariff
+	CostKindMinCost CostKindType = "MinCost" // Raised to the minimum cost of the tariff
+	CostKindMaxCost CostKindType = "MaxCost" // Cut to the maximum cost of the tariff
+)
+
+// TotalCostType is the cost of a transaction, part by part
+type TotalCostType struct {
+	// Currency is the ISO 4217 code of the currency
+	Currency string `json:"currency" validate:"required,len=3"`
+
+	// TypeOfCost tells whether a bound of the tariff applied
+	TypeOfCost CostKindType `json:"typeOfCost" validate:"required"`
+
+	// Fixed is the fixed fee
+	Fixed *PriceType `json:"fixed,omitempty"`
+
+	// Energy is the cost of the energy
+	Energy *PriceType `json:"energy,omitempty"`
+
+	// ChargingTime is the cost of the time spent charging
+	ChargingTime *PriceType `json:"chargingTime,omitempty"`
+
+	// IdleTime is the cost of the idle time
+	IdleTime *PriceType `json:"idleTime,omitempty"`
+
+	// Total is the cost of the transaction
+	Total PriceType `json:"total" validate:"required"`
+}
+
+// TotalUsageType is what a transaction used
+type TotalUsageType struct {
+	// Energy is the energy charged, in Wh
+	Energy float64 `json:"energy"`
+
+	// ChargingTime is the time spent charging, in seconds
+	ChargingTime int `json:"chargingTime"`
+
+	// IdleTime is the time connected without charging, in seconds
+	IdleTime int `json:"idleTime"`
+}
+
+// CostDetailsType is the cost a charging station calculated for a transaction
+type CostDetailsType struct {
+	// FailureToCalculate is true when the station could not calculate the cost
+	FailureToCalculate *bool `json:"failureToCalculate,omitempty"`
+
+	// FailureReason says why the calculation failed
+	FailureReason string `json:"failureReason,omitempty" validate:"omitempty,max=500"`
+
+	// TotalCost is the calculated cost
+	TotalCost TotalCostType `json:"totalCost" validate:"required"`
+
+	// TotalUsage is what the cost was calculated from
+	TotalUsage TotalUsageType `json:"totalUsage" validate:"required"`
+}
+
+// Failed reports whether the charging station could not calculate the cost
+func (c *CostDetailsType) Failed() bool {
+	return c.FailureToCalculate != nil && *c.FailureToCalculate
+}
+
+// ValidationError represents a validation error
+type ValidationError struct {
+	Field   string
+	Message string
+	Err     error // common.ErrRequired for a missing field
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,111 @@
+package tariff
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// ============================================================================
+// OCPP 2.1 Tariff Messages Tests
+// ============================================================================
+// Tests for SetDefaultTariff, GetTariffs, ClearTariffs and the cost details
+// of TransactionEvent
+// ============================================================================
+
+func energyTariff(id string, priceKwh float64) TariffType {
+	return TariffType{
+		TariffId: id,
+		Currency: "EUR",
+		Energy:   &TariffEnergyType{Prices: []TariffEnergyPriceType{{PriceKwh: priceKwh}}},
+	}
+}
+
+func TestSetDefaultTariffRequest_Serialization(t *testing.T) {
+	minTime := 3600
+	tariff := energyTariff("basic", 0.35)
+	tariff.ChargingTime = &TariffTimeType{Prices: []TariffTimePriceType{{
+		PriceMinute: 0.05,
+		Conditions:  &TariffConditionsType{MinTime: &minTime},
+	}}}
+	req := SetDefaultTariffRequest{EvseId: 0, Tariff: tariff}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	for _, field := range []string{`"priceKwh":0.35`, `"priceMinute":0.05`, `"minTime":3600`, `"evseId":0`} {
+		if !strings.Contains(string(data), field) {
+			t.Errorf("serialized %s, want %s", data, field)
+		}
+	}
+
+	var decoded SetDefaultTariffRequest
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if err = decoded.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
+
+func TestSetDefaultTariffRequest_Validate(t *testing.T) {
+	noPrices := energyTariff("basic", 0.35)
+	noPrices.Energy.Prices = nil
+	nothingPriced := energyTariff("basic", 0.35)
+	nothingPriced.Energy = nil
+
+	tests := []struct {
+		name string
+		req  SetDefaultTariffRequest
+	}{
+		{"negative evse", SetDefaultTariffRequest{EvseId: -1, Tariff: energyTariff("basic", 0.35)}},
+		{"no tariff id", SetDefaultTariffRequest{Tariff: energyTariff("", 0.35)}},
+		{"currency symbol", SetDefaultTariffRequest{Tariff: TariffType{TariffId: "basic", Currency: "€", Energy: noPrices.Energy}}},
+		{"energy without prices", SetDefaultTariffRequest{Tariff: noPrices}},
+		{"nothing priced", SetDefaultTariffRequest{Tariff: nothingPriced}},
+	}
+	for _, tt := range tests {
+		if err := tt.req.Validate(); err == nil {
+			t.Errorf("%s: Validate() error = nil, want an error", tt.name)
+		}
+	}
+}
+
+func TestGetTariffsResponse_Serialization(t *testing.T) {
+	payload := `{"status":"Accepted","tariffAssignments":[{"tariffId":"basic","tariffKind":"DefaultTariff","evseIds":[1,2]}]}`
+	var response GetTariffsResponse
+	if err := json.Unmarshal([]byte(payload), &response); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if response.Status != TariffGetStatusAccepted || len(response.TariffAssignments) != 1 {
+		t.Fatalf("decoded %+v, want one accepted assignment", response)
+	}
+	if assignment := response.TariffAssignments[0]; assignment.TariffKind != TariffKindDefault || len(assignment.EvseIds) != 2 {
+		t.Errorf("assignment %+v, want the default tariff on 2 EVSEs", assignment)
+	}
+}
+
+func TestClearTariffsRequest_Validate(t *testing.T) {
+	evseId := 1
+	if err := (ClearTariffsRequest{}).Validate(); err != nil {
+		t.Errorf("clear all: Validate() error = %v", err)
+	}
+	if err := (ClearTariffsRequest{TariffIds: []string{"basic"}, EvseId: &evseId}).Validate(); err != nil {
+		t.Errorf("clear one: Validate() error = %v", err)
+	}
+	if err := (ClearTariffsRequest{TariffIds: []string{""}}).Validate(); err == nil {
+		t.Error("empty tariff id: Validate() error = nil, want an error")
+	}
+}
+
+func TestCostDetails_Failed(t *testing.T) {
+	var details CostDetailsType
+	payload := `{"failureToCalculate":true,"failureReason":"no tariff","totalCost":{"currency":"EUR","typeOfCost":"Normal","total":{}},"totalUsage":{"energy":0,"chargingTime":0,"idleTime":0}}`
+	if err := json.Unmarshal([]byte(payload), &details); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if !details.Failed() || details.FailureReason != "no tariff" {
+		t.Errorf("decoded %+v, want a failed calculation", details)
+	}
+}
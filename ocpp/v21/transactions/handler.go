@@ -0,0 +1,15 @@
+package transactions
+
+// ============================================================================
+// Transactions Handler Interface - OCPP 2.1
+// ============================================================================
+// The 2.1 TransactionEvent replaces the 2.0.1 one for 2.1 charging stations;
+// CostUpdated is sent unchanged.
+// ============================================================================
+
+// Handler defines the interface for handling OCPP 2.1 transaction messages
+type Handler interface {
+	// OnTransactionEvent handles incoming TransactionEvent requests
+	// Called for the start, updates and end of a transaction
+	OnTransactionEvent(chargePointId string, request *TransactionEventRequest) (*TransactionEventResponse, error)
+}
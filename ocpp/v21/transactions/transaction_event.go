@@ -0,0 +1,122 @@
+package transactions
+
+import (
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+	transactions201 "evsys/ocpp/v201/transactions"
+	"evsys/ocpp/v21/smartcharging"
+	"evsys/ocpp/v21/tariff"
+)
+
+// ============================================================================
+// TransactionEvent - OCPP 2.1
+// ============================================================================
+// Sent by: Charging Station → CSMS
+// Purpose: The 2.0.1 TransactionEvent with the fields 2.1 adds: the V2X
+//          operation mode, tariff and limits of the transaction, and the
+//          cost the charging station calculated with a local tariff. The
+//          CSMS may set new limits in its answer.
+// ============================================================================
+
+const TransactionEventFeatureName = transactions201.TransactionEventFeatureName
+
+// TransactionLimitType caps a transaction; the charging station stops it at the first limit reached
+type TransactionLimitType struct {
+	// MaxCost is the most the transaction may cost, in the currency of its tariff
+	MaxCost *float64 `json:"maxCost,omitempty"`
+
+	// MaxEnergy is the most energy, in Wh, the transaction may charge
+	MaxEnergy *float64 `json:"maxEnergy,omitempty"`
+
+	// MaxTime is the longest, in seconds, the transaction may last
+	MaxTime *int `json:"maxTime,omitempty"`
+
+	// MaxSoC is the state of charge, in percent, at which the transaction stops
+	MaxSoC *int `json:"maxSoC,omitempty" validate:"omitempty,min=0,max=100"`
+}
+
+// Transaction is the 2.0.1 transaction info with the fields added in 2.1
+type Transaction struct {
+	v201.Transaction
+
+	// OperationMode is the V2X mode the transaction runs in
+	OperationMode smartcharging.OperationModeType `json:"operationMode,omitempty"`
+
+	// TariffId is the tariff the charging station calculates the cost with
+	TariffId string `json:"tariffId,omitempty" validate:"omitempty,max=60"`
+
+	// TransactionLimit are the limits in force for the transaction
+	TransactionLimit *TransactionLimitType `json:"transactionLimit,omitempty"`
+}
+
+// TransactionEventRequest represents the request for TransactionEvent. The 2.1 transaction info
+// shadows the 2.0.1 one, which stays empty; Base copies it over for the 2.0.1 handling.
+type TransactionEventRequest struct {
+	transactions201.TransactionEventRequest
+
+	// TransactionInfo contains transaction-related information
+	TransactionInfo Transaction `json:"transactionInfo" validate:"required"`
+
+	// CostDetails is the cost calculated by the charging station with a local tariff
+	CostDetails *tariff.CostDetailsType `json:"costDetails,omitempty"`
+
+	// EvseSleep tells that the EVSE sleeps while the transaction is suspended
+	EvseSleep *bool `json:"evseSleep,omitempty"`
+}
+
+// TransactionEventResponse represents the response to TransactionEvent
+type TransactionEventResponse struct {
+	transactions201.TransactionEventResponse
+
+	// TransactionLimit sets new limits for the transaction
+	TransactionLimit *TransactionLimitType `json:"transactionLimit,omitempty"`
+}
+
+// Base returns the 2.0.1 request carried in the 2.1 one
+func (r *TransactionEventRequest) Base() *transactions201.TransactionEventRequest {
+	base := r.TransactionEventRequest
+	base.TransactionInfo = r.TransactionInfo.Transaction
+	return &base
+}
+
+// GetProtocolVersion implements common.Request interface
+func (r TransactionEventRequest) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP21
+}
+
+// Validate implements common.Request interface
+func (r TransactionEventRequest) Validate() error {
+	if err := r.Base().Validate(); err != nil {
+		return err
+	}
+	if mode := r.TransactionInfo.OperationMode; mode != "" && !mode.IsValid() {
+		return &ValidationError{Field: "transactionInfo.operationMode", Message: "invalid mode " + string(mode)}
+	}
+	if len(r.TransactionInfo.TariffId) > 60 {
+		return &ValidationError{Field: "transactionInfo.tariffId", Message: "max 60 characters"}
+	}
+	if r.CostDetails != nil && !r.CostDetails.Failed() && len(r.CostDetails.TotalCost.Currency) != 3 {
+		return &ValidationError{Field: "costDetails.totalCost.currency", Message: "must be an ISO 4217 code"}
+	}
+	return nil
+}
+
+// GetProtocolVersion implements common.Response interface
+func (r TransactionEventResponse) GetProtocolVersion() common.ProtocolVersion {
+	return common.OCPP21
+}
+
+// ValidationError represents a validation error
+type ValidationError struct {
+	Field   string
+	Message string
+	Err     error // common.ErrRequired for a missing field
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,105 @@
+package transactions
+
+import (
+	"encoding/json"
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+	"evsys/ocpp/v21/smartcharging"
+	"testing"
+)
+
+// ============================================================================
+// OCPP 2.1 Transaction Messages Tests
+// ============================================================================
+// Tests for TransactionEvent with the fields added in 2.1
+// ============================================================================
+
+const endedEvent = `{
+	"eventType": "Ended",
+	"timestamp": "2026-03-01T10:00:00Z",
+	"triggerReason": "EVCommunicationLost",
+	"seqNo": 4,
+	"transactionInfo": {
+		"transactionId": "tx-21",
+		"stoppedReason": "EVDisconnected",
+		"operationMode": "CentralSetpoint",
+		"tariffId": "basic",
+		"transactionLimit": {"maxCost": 20.5, "maxSoC": 80}
+	},
+	"costDetails": {
+		"totalCost": {"currency": "EUR", "typeOfCost": "Normal", "total": {"exclTax": 10, "inclTax": 12.1}},
+		"totalUsage": {"energy": 25000, "chargingTime": 3600, "idleTime": 0}
+	},
+	"evse": {"id": 1}
+}`
+
+func TestTransactionEventRequest_Serialization(t *testing.T) {
+	var req TransactionEventRequest
+	if err := json.Unmarshal([]byte(endedEvent), &req); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	info := req.TransactionInfo
+	if info.TransactionId != "tx-21" || info.OperationMode != smartcharging.OperationModeCentralSetpoint || info.TariffId != "basic" {
+		t.Errorf("transaction info %+v, want tx-21 in CentralSetpoint with tariff basic", info)
+	}
+	if info.TransactionLimit == nil || *info.TransactionLimit.MaxSoC != 80 {
+		t.Errorf("transaction limit %+v, want maxSoC 80", info.TransactionLimit)
+	}
+	if req.CostDetails == nil || *req.CostDetails.TotalCost.Total.InclTax != 12.1 {
+		t.Errorf("cost details %+v, want 12.1 including tax", req.CostDetails)
+	}
+	if err := req.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	if req.GetProtocolVersion() != common.OCPP21 || req.GetFeatureName() != TransactionEventFeatureName {
+		t.Errorf("request is %s %s, want a 2.1 TransactionEvent", req.GetProtocolVersion(), req.GetFeatureName())
+	}
+}
+
+// The 2.0.1 handling sees the transaction info of the 2.1 request.
+func TestTransactionEventRequest_Base(t *testing.T) {
+	var req TransactionEventRequest
+	if err := json.Unmarshal([]byte(endedEvent), &req); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	base := req.Base()
+	if base.TransactionInfo.TransactionId != "tx-21" || base.TransactionInfo.StoppedReason != "EVDisconnected" {
+		t.Errorf("base transaction info %+v, want tx-21 stopped by EVDisconnected", base.TransactionInfo)
+	}
+	if base.EventType != v201.TransactionEventEnded || base.SeqNo != 4 || base.Evse == nil || base.Evse.Id != 1 {
+		t.Errorf("base %+v, want the ended event 4 on EVSE 1", base)
+	}
+}
+
+func TestTransactionEventRequest_Validate(t *testing.T) {
+	var req TransactionEventRequest
+	if err := json.Unmarshal([]byte(endedEvent), &req); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	noTransaction := req
+	noTransaction.TransactionInfo.TransactionId = ""
+	badMode := req
+	badMode.TransactionInfo.OperationMode = "Turbo"
+
+	if err := noTransaction.Validate(); err == nil {
+		t.Error("no transaction id: Validate() error = nil, want an error")
+	}
+	if err := badMode.Validate(); err == nil {
+		t.Error("unknown operation mode: Validate() error = nil, want an error")
+	}
+}
+
+func TestTransactionEventResponse_Serialization(t *testing.T) {
+	total := 12.1
+	maxCost := 30.0
+	response := TransactionEventResponse{TransactionLimit: &TransactionLimitType{MaxCost: &maxCost}}
+	response.TotalCost = &total
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if got := string(data); got != `{"totalCost":12.1,"transactionLimit":{"maxCost":30}}` {
+		t.Errorf("serialized %s, want the 2.0.1 fields beside the transaction limit", got)
+	}
+}
@@ -0,0 +1,48 @@
+package pki
+
+// ContractStatus is the verdict on the contract certificate of an EV, as OCPP 2.0.1 reports it in
+// the certificateStatus of an Authorize answer.
+type ContractStatus string
+
+const (
+	ContractAccepted          ContractStatus = "Accepted"
+	ContractSignatureError    ContractStatus = "SignatureError"
+	ContractExpired           ContractStatus = "CertificateExpired"
+	ContractRevoked           ContractStatus = "CertificateRevoked"
+	ContractNoCertificate     ContractStatus = "NoCertificateAvailable"
+	ContractCertChainError    ContractStatus = "CertChainError"
+	ContractContractCancelled ContractStatus = "ContractCancelled"
+)
+
+// CertificateHashData identifies a certificate the way an OCSP request does; the hashes and the
+// serial number are hex encoded.
+type CertificateHashData struct {
+	HashAlgorithm  string
+	IssuerNameHash string
+	IssuerKeyHash  string
+	SerialNumber   string
+	ResponderURL   string
+}
+
+/*
+ContractProvider gives the central system access to the ISO 15118 Plug & Charge PKI: the certificate
+provisioning service that installs contract certificates in EVs, the OCSP responders of the V2G and
+mobility operator CAs, and the CPO sub-CA that signs charging station V2G certificates.
+FileContractProvider does all of it from local files, for testing without the real services.
+*/
+type ContractProvider interface {
+	// GetEVCertificate forwards the EXI encoded, base64 CertificateInstallationReq or
+	// CertificateUpdateReq of an EV and returns the EXI response; action is Install or Update.
+	GetEVCertificate(schemaVersion, action, exiRequest string) (string, error)
+
+	// GetCertificateStatus returns the base64 DER OCSP response for a certificate.
+	GetCertificateStatus(hashData CertificateHashData) (string, error)
+
+	// VerifyContract decides on the contract of an eMAID, from its PEM certificate chain when the
+	// charging station sends one, otherwise from the hash data of the chain it has verified itself.
+	VerifyContract(emaid, certificateChain string, hashData []CertificateHashData) (ContractStatus, error)
+
+	// SignV2GCertificate signs the PEM certificate signing request of a charging station for the
+	// TLS connection to the EV and returns the PEM chain.
+	SignV2GCertificate(csrPEM string) (string, error)
+}
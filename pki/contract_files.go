@@ -0,0 +1,332 @@
+package pki
+
+import (
+	"bufio"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// Files of the directory FileContractProvider works from. Only the authorities are required; each of
+// the others enables what it is for. The revocation and cancellation lists are read on every use, so
+// they can be edited while the central system runs.
+const (
+	// authoritiesFile holds the trusted V2G and mobility operator roots, with any intermediate CAs
+	authoritiesFile = "authorities.pem"
+	// revokedFile lists revoked certificates by hex serial number, one per line
+	revokedFile = "revoked.txt"
+	// cancelledFile lists the eMAIDs of cancelled contracts, one per line
+	cancelledFile = "cancelled.txt"
+	// responderFile and responderKeyFile sign OCSP responses for certificates of the authorities
+	responderFile    = "ocsp.pem"
+	responderKeyFile = "ocsp-key.pem"
+	// cpoCAFile and cpoCAKeyFile sign charging station V2G certificates
+	cpoCAFile    = "cpo-ca.pem"
+	cpoCAKeyFile = "cpo-ca-key.pem"
+	// evCertificatesDir holds EXI responses to Get15118EVCertificate, each in a file named by the hex
+	// SHA-256 of the request it answers; a file named default answers any other request
+	evCertificatesDir = "ev-certificates"
+
+	// ocspValidity is how long an OCSP response of the provider may be cached
+	ocspValidity = 24 * time.Hour
+	// v2gValidity is how long a V2G certificate signed by the provider is valid
+	v2gValidity = 365 * 24 * time.Hour
+)
+
+// FileContractProvider is a ContractProvider working from files in a directory, without the network:
+// a stand-in for the Plug & Charge services while they are not contracted, and for tests.
+type FileContractProvider struct {
+	dir           string
+	authorities   []*x509.Certificate
+	roots         *x509.CertPool
+	intermediates *x509.CertPool
+	responder     *x509.Certificate
+	responderKey  crypto.Signer
+	cpoCA         *LocalCA
+	now           func() time.Time
+}
+
+// NewFileContractProvider loads the authorities, and the OCSP responder and CPO sub-CA where their
+// files exist, from dir.
+func NewFileContractProvider(dir string) (*FileContractProvider, error) {
+	p := &FileContractProvider{
+		dir:           dir,
+		roots:         x509.NewCertPool(),
+		intermediates: x509.NewCertPool(),
+		now:           time.Now,
+	}
+	data, err := os.ReadFile(filepath.Join(dir, authoritiesFile))
+	if err != nil {
+		return nil, fmt.Errorf("read authorities: %w", err)
+	}
+	p.authorities, err = parseCertificates(data)
+	if err != nil {
+		return nil, fmt.Errorf("authorities: %w", err)
+	}
+	for _, authority := range p.authorities {
+		if authority.CheckSignatureFrom(authority) == nil {
+			p.roots.AddCert(authority)
+		} else {
+			p.intermediates.AddCert(authority)
+		}
+	}
+
+	certificatePEM, certErr := os.ReadFile(filepath.Join(dir, responderFile))
+	keyPEM, keyErr := os.ReadFile(filepath.Join(dir, responderKeyFile))
+	if certErr == nil && keyErr == nil {
+		responders, err := parseCertificates(certificatePEM)
+		if err != nil {
+			return nil, fmt.Errorf("OCSP responder: %w", err)
+		}
+		p.responder = responders[0]
+		if p.responderKey, err = parsePrivateKey(keyPEM); err != nil {
+			return nil, fmt.Errorf("OCSP responder: %w", err)
+		}
+	} else if !errors.Is(certErr, os.ErrNotExist) || !errors.Is(keyErr, os.ErrNotExist) {
+		return nil, fmt.Errorf("OCSP responder needs both %s and %s", responderFile, responderKeyFile)
+	}
+
+	certificatePEM, certErr = os.ReadFile(filepath.Join(dir, cpoCAFile))
+	keyPEM, keyErr = os.ReadFile(filepath.Join(dir, cpoCAKeyFile))
+	if certErr == nil && keyErr == nil {
+		if p.cpoCA, err = NewLocalCA(certificatePEM, keyPEM, v2gValidity); err != nil {
+			return nil, fmt.Errorf("CPO sub-CA: %w", err)
+		}
+	} else if !errors.Is(certErr, os.ErrNotExist) || !errors.Is(keyErr, os.ErrNotExist) {
+		return nil, fmt.Errorf("CPO sub-CA needs both %s and %s", cpoCAFile, cpoCAKeyFile)
+	}
+	return p, nil
+}
+
+func (p *FileContractProvider) GetEVCertificate(_, _, exiRequest string) (string, error) {
+	sum := sha256.Sum256([]byte(exiRequest))
+	for _, name := range []string{hex.EncodeToString(sum[:]), "default"} {
+		data, err := os.ReadFile(filepath.Join(p.dir, evCertificatesDir, name))
+		if err == nil {
+			return strings.TrimSpace(string(data)), nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("no EV certificate for the request")
+}
+
+func (p *FileContractProvider) GetCertificateStatus(hashData CertificateHashData) (string, error) {
+	if p.responder == nil {
+		return "", fmt.Errorf("no OCSP responder configured")
+	}
+	hash, err := hashAlgorithm(hashData.HashAlgorithm)
+	if err != nil {
+		return "", err
+	}
+	var issuer *x509.Certificate
+	for _, authority := range p.authorities {
+		if matchesIssuer(authority, hash, hashData) {
+			issuer = authority
+			break
+		}
+	}
+	if issuer == nil {
+		return "", fmt.Errorf("issuer of certificate %s is not a known authority", hashData.SerialNumber)
+	}
+	serial, ok := new(big.Int).SetString(normalSerial(hashData.SerialNumber), 16)
+	if !ok {
+		return "", fmt.Errorf("invalid serial number %q", hashData.SerialNumber)
+	}
+	revoked, err := p.readList(revokedFile, normalSerial)
+	if err != nil {
+		return "", err
+	}
+
+	now := p.now()
+	template := ocsp.Response{
+		Status:       ocsp.Good,
+		SerialNumber: serial,
+		ThisUpdate:   now,
+		NextUpdate:   now.Add(ocspValidity),
+		IssuerHash:   hash,
+	}
+	if revoked[normalSerial(hashData.SerialNumber)] {
+		template.Status = ocsp.Revoked
+		template.RevokedAt = now
+	}
+	der, err := ocsp.CreateResponse(issuer, p.responder, template, p.responderKey)
+	if err != nil {
+		return "", fmt.Errorf("create OCSP response: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(der), nil
+}
+
+/*
+VerifyContract checks a contract the way a mobility operator would. A certificate chain has to lead to
+one of the authorities, be valid now, and belong to the eMAID, the common name of a contract
+certificate; without a chain the charging station has verified it and only revocation is left to
+check. Either way the contract itself must not be cancelled.
+*/
+func (p *FileContractProvider) VerifyContract(emaid, certificateChain string, hashData []CertificateHashData) (ContractStatus, error) {
+	revoked, err := p.readList(revokedFile, normalSerial)
+	if err != nil {
+		return "", err
+	}
+	cancelled, err := p.readList(cancelledFile, normalEmaid)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case certificateChain != "":
+		chain, err := parseCertificates([]byte(certificateChain))
+		if err != nil {
+			return ContractCertChainError, nil
+		}
+		intermediates := p.intermediates.Clone()
+		for _, certificate := range chain[1:] {
+			intermediates.AddCert(certificate)
+		}
+		_, err = chain[0].Verify(x509.VerifyOptions{
+			Roots:         p.roots,
+			Intermediates: intermediates,
+			CurrentTime:   p.now(),
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		})
+		var invalid x509.CertificateInvalidError
+		var unknown x509.UnknownAuthorityError
+		switch {
+		case errors.As(err, &invalid) && invalid.Reason == x509.Expired:
+			return ContractExpired, nil
+		case errors.As(err, &unknown):
+			return ContractCertChainError, nil
+		case err != nil:
+			return ContractSignatureError, nil
+		}
+		if normalEmaid(chain[0].Subject.CommonName) != normalEmaid(emaid) {
+			return ContractCertChainError, nil
+		}
+		for _, certificate := range chain {
+			if revoked[normalSerial(certificate.SerialNumber.Text(16))] {
+				return ContractRevoked, nil
+			}
+		}
+	case len(hashData) > 0:
+		for _, data := range hashData {
+			if revoked[normalSerial(data.SerialNumber)] {
+				return ContractRevoked, nil
+			}
+		}
+	default:
+		return ContractNoCertificate, nil
+	}
+	if cancelled[normalEmaid(emaid)] {
+		return ContractContractCancelled, nil
+	}
+	return ContractAccepted, nil
+}
+
+func (p *FileContractProvider) SignV2GCertificate(csrPEM string) (string, error) {
+	if p.cpoCA == nil {
+		return "", fmt.Errorf("no CPO sub-CA configured")
+	}
+	return p.cpoCA.sign(csrPEM, x509.ExtKeyUsageServerAuth)
+}
+
+// readList reads a list file into a set of normalized entries; a missing file is an empty list.
+func (p *FileContractProvider) readList(name string, normal func(string) string) (map[string]bool, error) {
+	list := make(map[string]bool)
+	file, err := os.Open(filepath.Join(p.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return list, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			list[normal(line)] = true
+		}
+	}
+	return list, scanner.Err()
+}
+
+// matchesIssuer tells whether authority issued the certificate of the hash data: OCSP names the
+// issuer by the hashes of its subject and of its public key.
+func matchesIssuer(authority *x509.Certificate, hash crypto.Hash, hashData CertificateHashData) bool {
+	var publicKeyInfo struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(authority.RawSubjectPublicKeyInfo, &publicKeyInfo); err != nil {
+		return false
+	}
+	nameHash := hash.New()
+	nameHash.Write(authority.RawSubject)
+	keyHash := hash.New()
+	keyHash.Write(publicKeyInfo.PublicKey.RightAlign())
+	return strings.EqualFold(hex.EncodeToString(nameHash.Sum(nil)), hashData.IssuerNameHash) &&
+		strings.EqualFold(hex.EncodeToString(keyHash.Sum(nil)), hashData.IssuerKeyHash)
+}
+
+func hashAlgorithm(name string) (crypto.Hash, error) {
+	switch name {
+	case "SHA256":
+		return crypto.SHA256, nil
+	case "SHA384":
+		return crypto.SHA384, nil
+	case "SHA512":
+		return crypto.SHA512, nil
+	}
+	return 0, fmt.Errorf("unsupported hash algorithm %q", name)
+}
+
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certificates []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certificates = append(certificates, certificate)
+	}
+	if len(certificates) == 0 {
+		return nil, fmt.Errorf("no certificate in PEM")
+	}
+	return certificates, nil
+}
+
+// normalSerial writes a hex serial number the same way whatever its case and leading zeros
+func normalSerial(serial string) string {
+	serial = strings.TrimLeft(strings.ToLower(strings.TrimSpace(serial)), "0")
+	if serial == "" {
+		return "0"
+	}
+	return serial
+}
+
+// normalEmaid ignores the case and the optional separators of an eMAID
+func normalEmaid(emaid string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(emaid), "-", ""))
+}
@@ -0,0 +1,170 @@
+package pki
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// newContractDir sets up a provider directory whose authority is also the OCSP responder and the CPO
+// sub-CA, and returns it with a contract certificate chain issued for emaid.
+func newContractDir(t *testing.T, emaid string) (string, *x509.Certificate, string) {
+	t.Helper()
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, authoritiesFile), filepath.Join(dir, responderKeyFile)
+	ca, err := LoadOrCreateLocalCA(certFile, keyFile, "Mobility Operator", 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("create CA: %v", err)
+	}
+	certificatePEM, _ := os.ReadFile(certFile)
+	keyPEM, _ := os.ReadFile(keyFile)
+	for name, data := range map[string][]byte{responderFile: certificatePEM, cpoCAFile: certificatePEM, cpoCAKeyFile: keyPEM} {
+		if err = os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	chain, err := ca.Sign(newCSR(t, emaid))
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return dir, ca.Certificate(), chain
+}
+
+func writeList(t *testing.T, dir, name, entry string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("# test list\n"+entry+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileContractProviderVerifiesContracts(t *testing.T) {
+	dir, _, chain := newContractDir(t, "DE8ACC12E46L89")
+	p, err := NewFileContractProvider(dir)
+	if err != nil {
+		t.Fatalf("NewFileContractProvider: %v", err)
+	}
+	block, _ := pem.Decode([]byte(chain))
+	contract, _ := x509.ParseCertificate(block.Bytes)
+	_, other, _ := newContractDir(t, "DE8ACC12E46L89")
+	foreign := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: other.Raw}))
+
+	check := func(name, emaid, certificateChain string, hashData []CertificateHashData, want ContractStatus) {
+		t.Helper()
+		status, err := p.VerifyContract(emaid, certificateChain, hashData)
+		if err != nil || status != want {
+			t.Errorf("%s: status %s (%v), want %s", name, status, err, want)
+		}
+	}
+	check("valid chain", "DE-8AC-C12E46L-89", chain, nil, ContractAccepted)
+	check("other eMAID", "DE8ACC99999999", chain, nil, ContractCertChainError)
+	check("unknown authority", "DE8ACC12E46L89", foreign, nil, ContractCertChainError)
+	check("nothing to verify", "DE8ACC12E46L89", "", nil, ContractNoCertificate)
+
+	p.now = func() time.Time { return time.Now().Add(60 * 24 * time.Hour) }
+	check("expired", "DE8ACC12E46L89", chain, nil, ContractExpired)
+	p.now = time.Now
+
+	serial := "00" + contract.SerialNumber.Text(16)
+	writeList(t, dir, revokedFile, serial)
+	check("revoked", "DE8ACC12E46L89", chain, nil, ContractRevoked)
+	check("revoked by hash data", "DE8ACC12E46L89", "", []CertificateHashData{{SerialNumber: serial}}, ContractRevoked)
+
+	writeList(t, dir, revokedFile, "")
+	writeList(t, dir, cancelledFile, "de8acc12e46l89")
+	check("cancelled", "DE8ACC12E46L89", "", []CertificateHashData{{SerialNumber: serial}}, ContractContractCancelled)
+}
+
+func TestFileContractProviderAnswersOCSP(t *testing.T) {
+	dir, authority, chain := newContractDir(t, "DE8ACC12E46L89")
+	p, err := NewFileContractProvider(dir)
+	if err != nil {
+		t.Fatalf("NewFileContractProvider: %v", err)
+	}
+	block, _ := pem.Decode([]byte(chain))
+	contract, _ := x509.ParseCertificate(block.Bytes)
+
+	// the hash data a charging station sends for the contract certificate
+	request, err := ocsp.ParseRequest(mustRequest(t, contract, authority))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashData := CertificateHashData{
+		HashAlgorithm:  "SHA256",
+		IssuerNameHash: hex.EncodeToString(request.IssuerNameHash),
+		IssuerKeyHash:  hex.EncodeToString(request.IssuerKeyHash),
+		SerialNumber:   contract.SerialNumber.Text(16),
+	}
+
+	status := func() int {
+		t.Helper()
+		result, err := p.GetCertificateStatus(hashData)
+		if err != nil {
+			t.Fatalf("GetCertificateStatus: %v", err)
+		}
+		der, _ := base64.StdEncoding.DecodeString(result)
+		response, err := ocsp.ParseResponseForCert(der, contract, authority)
+		if err != nil {
+			t.Fatalf("ParseResponseForCert: %v", err)
+		}
+		return response.Status
+	}
+	if got := status(); got != ocsp.Good {
+		t.Errorf("status %d, want good", got)
+	}
+	writeList(t, dir, revokedFile, hashData.SerialNumber)
+	if got := status(); got != ocsp.Revoked {
+		t.Errorf("status %d, want revoked", got)
+	}
+
+	hashData.IssuerKeyHash = "00"
+	if _, err = p.GetCertificateStatus(hashData); err == nil {
+		t.Error("answered for an unknown issuer")
+	}
+}
+
+func TestFileContractProviderSignsAndReplays(t *testing.T) {
+	dir, _, _ := newContractDir(t, "DE8ACC12E46L89")
+	if err := os.MkdirAll(filepath.Join(dir, evCertificatesDir), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, evCertificatesDir, "default"), []byte("gAIB\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewFileContractProvider(dir)
+	if err != nil {
+		t.Fatalf("NewFileContractProvider: %v", err)
+	}
+	if response, err := p.GetEVCertificate("urn:iso:15118:2:2013:MsgDef", "Install", "gAEC"); err != nil || response != "gAIB" {
+		t.Errorf("GetEVCertificate = %q (%v), want the default response", response, err)
+	}
+
+	chain, err := p.SignV2GCertificate(newCSR(t, "CS001"))
+	if err != nil {
+		t.Fatalf("SignV2GCertificate: %v", err)
+	}
+	block, _ := pem.Decode([]byte(chain))
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(certificate.ExtKeyUsage) != 1 || certificate.ExtKeyUsage[0] != x509.ExtKeyUsageServerAuth {
+		t.Errorf("key usage %v, want a TLS server certificate", certificate.ExtKeyUsage)
+	}
+}
+
+func mustRequest(t *testing.T, certificate, issuer *x509.Certificate) []byte {
+	t.Helper()
+	request, err := ocsp.CreateRequest(certificate, issuer, &ocsp.RequestOptions{Hash: crypto.SHA256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return request
+}
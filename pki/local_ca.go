@@ -0,0 +1,214 @@
+// Package pki issues the client certificates charge points use under the OCPP 1.6 security
+// profiles, and stands between the central system and the ISO 15118 Plug & Charge PKI.
+package pki
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+)
+
+const (
+	// caValidity applies to a root generated on first start
+	caValidity = 10 * 365 * 24 * time.Hour
+	// backdate covers charge points whose clock runs a little behind
+	backdate = 5 * time.Minute
+)
+
+// CertificateAuthority signs charge point certificates. LocalCA does so with a key on disk; an
+// implementation forwarding to an external CA can take its place.
+type CertificateAuthority interface {
+	// Sign issues a client certificate for a PEM encoded certificate signing request and returns
+	// the PEM encoded chain: the certificate followed by any intermediate CA certificates.
+	Sign(csrPEM string) (string, error)
+}
+
+// LocalCA signs with a CA certificate and key kept by the central system; it needs no network.
+type LocalCA struct {
+	certificate *x509.Certificate
+	key         crypto.Signer
+	validity    time.Duration
+	now         func() time.Time
+}
+
+// NewLocalCA creates a CA from a PEM encoded certificate and private key; validity is how long the
+// certificates it issues are valid.
+func NewLocalCA(certificatePEM, keyPEM []byte, validity time.Duration) (*LocalCA, error) {
+	block, _ := pem.Decode(certificatePEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no certificate in CA certificate PEM")
+	}
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse CA certificate: %w", err)
+	}
+	if !certificate.IsCA {
+		return nil, fmt.Errorf("certificate %s is not a CA", certificate.Subject)
+	}
+	key, err := parsePrivateKey(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	if validity <= 0 {
+		return nil, fmt.Errorf("certificate validity must be positive")
+	}
+	return &LocalCA{certificate: certificate, key: key, validity: validity, now: time.Now}, nil
+}
+
+// LoadOrCreateLocalCA reads the CA certificate and key from their files, generating a self-signed
+// root for organization and saving it there when neither file exists yet.
+func LoadOrCreateLocalCA(certFile, keyFile, organization string, validity time.Duration) (*LocalCA, error) {
+	certificatePEM, certErr := os.ReadFile(certFile)
+	keyPEM, keyErr := os.ReadFile(keyFile)
+	if errors.Is(certErr, os.ErrNotExist) && errors.Is(keyErr, os.ErrNotExist) {
+		var err error
+		certificatePEM, keyPEM, err = generateRoot(organization)
+		if err != nil {
+			return nil, err
+		}
+		if err = os.WriteFile(keyFile, keyPEM, 0600); err != nil {
+			return nil, fmt.Errorf("save CA key: %w", err)
+		}
+		if err = os.WriteFile(certFile, certificatePEM, 0644); err != nil {
+			return nil, fmt.Errorf("save CA certificate: %w", err)
+		}
+	} else if certErr != nil {
+		return nil, fmt.Errorf("read CA certificate: %w", certErr)
+	} else if keyErr != nil {
+		return nil, fmt.Errorf("read CA key: %w", keyErr)
+	}
+	return NewLocalCA(certificatePEM, keyPEM, validity)
+}
+
+// Certificate returns the CA certificate.
+func (ca *LocalCA) Certificate() *x509.Certificate {
+	return ca.certificate
+}
+
+func (ca *LocalCA) Sign(csrPEM string) (string, error) {
+	return ca.sign(csrPEM, x509.ExtKeyUsageClientAuth)
+}
+
+// sign issues a certificate for the given use: charge points authenticate to the central system as
+// TLS clients, while a V2G certificate makes the charging station the TLS server of the EV.
+func (ca *LocalCA) sign(csrPEM string, usage x509.ExtKeyUsage) (string, error) {
+	block, _ := pem.Decode([]byte(csrPEM))
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return "", fmt.Errorf("no certificate request in PEM")
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return "", fmt.Errorf("parse certificate request: %w", err)
+	}
+	if err = csr.CheckSignature(); err != nil {
+		return "", fmt.Errorf("certificate request signature: %w", err)
+	}
+
+	serial, err := serialNumber()
+	if err != nil {
+		return "", err
+	}
+	now := ca.now()
+	notAfter := now.Add(ca.validity)
+	if notAfter.After(ca.certificate.NotAfter) {
+		notAfter = ca.certificate.NotAfter
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      csr.Subject,
+		NotBefore:    now.Add(-backdate),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.certificate, csr.PublicKey, ca.key)
+	if err != nil {
+		return "", fmt.Errorf("create certificate: %w", err)
+	}
+
+	chain := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	// a root is already installed on the charge point; an intermediate has to come with the chain
+	if ca.certificate.CheckSignatureFrom(ca.certificate) != nil {
+		chain = append(chain, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.certificate.Raw})...)
+	}
+	return string(chain), nil
+}
+
+func parsePrivateKey(keyPEM []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("no private key in CA key PEM")
+	}
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse CA key: %w", err)
+	}
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		return k, nil
+	case *rsa.PrivateKey:
+		return k, nil
+	}
+	return nil, fmt.Errorf("unsupported CA key type %T", key)
+}
+
+func generateRoot(organization string) (certificatePEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generate CA key: %w", err)
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	subject := pkix.Name{CommonName: "evsys charge point CA"}
+	if organization != "" {
+		subject.Organization = []string{organization}
+		subject.CommonName = organization + " charge point CA"
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               subject,
+		NotBefore:             now.Add(-backdate),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create CA certificate: %w", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encode CA key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), nil
+}
+
+// serialNumber draws a random 128 bit serial, as RFC 5280 allows at most 20 octets
+func serialNumber() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("generate serial number: %w", err)
+	}
+	return serial, nil
+}
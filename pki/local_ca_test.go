@@ -0,0 +1,86 @@
+package pki
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newCSR(t *testing.T, commonName string) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: commonName, Organization: []string{"Operator"}},
+	}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}))
+}
+
+func TestLocalCAIssuesClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "ca.pem"), filepath.Join(dir, "ca.key")
+	ca, err := LoadOrCreateLocalCA(certFile, keyFile, "Operator", 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("create CA: %v", err)
+	}
+
+	chain, err := ca.Sign(newCSR(t, "SN-0001"))
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	block, rest := pem.Decode([]byte(chain))
+	if block == nil || len(rest) != 0 {
+		t.Fatalf("chain of a root CA should hold the certificate only, got %q", chain)
+	}
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if certificate.Subject.CommonName != "SN-0001" {
+		t.Errorf("subject = %s, want the CSR's", certificate.Subject)
+	}
+
+	// the CA saved on first start is the one loaded on the next
+	reloaded, err := LoadOrCreateLocalCA(certFile, keyFile, "Operator", 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("reload CA: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(reloaded.Certificate())
+	_, err = certificate.Verify(x509.VerifyOptions{
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		t.Errorf("issued certificate does not verify for client auth: %v", err)
+	}
+}
+
+func TestLocalCARefusesBadRequest(t *testing.T) {
+	dir := t.TempDir()
+	ca, err := LoadOrCreateLocalCA(filepath.Join(dir, "ca.pem"), filepath.Join(dir, "ca.key"), "", time.Hour)
+	if err != nil {
+		t.Fatalf("create CA: %v", err)
+	}
+
+	csr := newCSR(t, "SN-0001")
+	block, _ := pem.Decode([]byte(csr))
+	block.Bytes[len(block.Bytes)-1] ^= 0xff
+	if _, err = ca.Sign(string(pem.EncodeToMemory(block))); err == nil {
+		t.Error("signed a request with a broken signature")
+	}
+	if _, err = ca.Sign("not a csr"); err == nil {
+		t.Error("signed something that is not PEM")
+	}
+}
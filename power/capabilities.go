@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"evsys/ocpp/v16/core"
 	"evsys/ocpp/v16/smartcharging"
+	"evsys/ocpp/v201"
+	"evsys/ocpp/v201/provisioning"
+	smartcharging201 "evsys/ocpp/v201/smartcharging"
 	"fmt"
 	"strconv"
 	"strings"
@@ -24,6 +27,13 @@ const (
 
 	rateUnitCurrent = "Current"
 
+	// On 2.0.1 the same limits are device model variables of SmartChargingCtrlr.
+	// ProfileStackLevel is unambiguously the highest level allowed, and RateUnit
+	// lists "A" and/or "W".
+	smartChargingCtrlr        = "SmartChargingCtrlr"
+	variableProfileStackLevel = "ProfileStackLevel"
+	variableRateUnit          = "RateUnit"
+
 	// capabilityTimeout bounds a configuration read. At boot this runs on its own
 	// goroutine; on the lazy path it runs before the balancer lock is taken, so
 	// in neither case does it hold up another session.
@@ -182,3 +192,52 @@ func parseCapabilities(payload string) (capabilities, error) {
 	}
 	return result, nil
 }
+
+// newCapabilityVariablesRequest asks a 2.0.1 charge point for the variables
+// parseCapabilityVariables reads.
+func newCapabilityVariablesRequest() *provisioning.GetVariablesRequest {
+	component := v201.Component{Name: smartChargingCtrlr}
+	return &provisioning.GetVariablesRequest{GetVariableData: []provisioning.GetVariableDataType{
+		{Component: component, Variable: v201.Variable{Name: variableProfileStackLevel}},
+		{Component: component, Variable: v201.Variable{Name: variableRateUnit}},
+	}}
+}
+
+// parseCapabilityVariables reads a GetVariables response. As with the 1.6
+// configuration, a variable the charge point does not report leaves its field
+// at the fallback.
+func parseCapabilityVariables(payload string) (capabilities, error) {
+	var response provisioning.GetVariablesResponse
+	if err := json.Unmarshal([]byte(payload), &response); err != nil {
+		return capabilities{}, fmt.Errorf("unreadable variables response %q: %w", payload, err)
+	}
+	result := capabilities{
+		known:           true,
+		stackLevelKnown: true,
+		maxStackLevel:   smartcharging201.TxProfileStackLevel,
+		allowsCurrent:   true,
+		allowedUnits:    string(v201.ChargingRateUnitA),
+	}
+	for _, variable := range response.GetVariableResult {
+		if variable.AttributeStatus != provisioning.GetVariableStatusAccepted || variable.Component.Name != smartChargingCtrlr {
+			continue
+		}
+		switch variable.Variable.Name {
+		case variableProfileStackLevel:
+			level, err := strconv.Atoi(strings.TrimSpace(variable.AttributeValue))
+			if err != nil {
+				return capabilities{}, fmt.Errorf("%s is not a number: %q", variableProfileStackLevel, variable.AttributeValue)
+			}
+			result.maxStackLevel = level
+		case variableRateUnit:
+			result.allowedUnits = variable.AttributeValue
+			result.allowsCurrent = false
+			for _, unit := range strings.Split(variable.AttributeValue, ",") {
+				if strings.TrimSpace(unit) == string(v201.ChargingRateUnitA) {
+					result.allowsCurrent = true
+				}
+			}
+		}
+	}
+	return result, nil
+}
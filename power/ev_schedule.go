@@ -0,0 +1,58 @@
+package power
+
+import (
+	"evsys/entity"
+	"evsys/ocpp/v201"
+	"fmt"
+	"time"
+)
+
+// nominalVoltage turns a schedule in watts into amperes per phase, the unit of the location limit
+const nominalVoltage = 230
+
+// ScheduleFits reports whether the charging schedule an ISO 15118 EV proposes stays within the
+// power limit of the charge point's location and the external limits on its EVSE, the ones the
+// balancer caps sessions by, and why not when it does not. A charge point outside a balanced
+// location has no limit to keep to.
+func (lb *LoadBalancer) ScheduleFits(chargePointId string, evseId int, schedule v201.ChargingSchedule) (bool, string) {
+	location, chp := lb.getLocation(chargePointId)
+	if chp == nil {
+		return true, ""
+	}
+	limit, source := 0, ""
+	if location != nil && location.PowerLimit > 0 {
+		limit, source = location.PowerLimit, "location limit"
+	}
+	s := &session{connector: &entity.Connector{Id: evseId, ChargePointId: chargePointId, EvseId: &evseId}, protocol: protocolOf(chp)}
+	lb.readExternalLimits([]*session{s}, time.Now())
+	if s.capped && (source == "" || s.cap < limit) {
+		limit, source = s.cap, "external limit on the EVSE"
+	}
+	if source == "" {
+		return true, ""
+	}
+	for _, period := range schedule.ChargingSchedulePeriod {
+		phases := 0
+		if period.NumberPhases != nil {
+			phases = *period.NumberPhases
+		}
+		current := periodCurrent(period.Limit, string(schedule.ChargingRateUnit), phases)
+		if current > float64(limit) {
+			return false, fmt.Sprintf("%.0fA from %ds exceeds the %s of %dA",
+				current, period.StartPeriod, source, limit)
+		}
+	}
+	return true, ""
+}
+
+// periodCurrent is a limit in amperes per phase. A limit in watts is taken to be spread over its
+// phases, three when the number is not known.
+func periodCurrent(limit float64, unit string, phases int) float64 {
+	if unit != string(v201.ChargingRateUnitW) {
+		return limit
+	}
+	if phases <= 0 {
+		phases = 3
+	}
+	return limit / float64(nominalVoltage*phases)
+}
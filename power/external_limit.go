@@ -0,0 +1,69 @@
+package power
+
+import (
+	"evsys/entity"
+	"evsys/ocpp/common"
+	"fmt"
+	"math"
+	"time"
+)
+
+// readExternalLimits caps each session of a 2.x charge point by the limits external systems set on
+// its EVSE or on the whole charge point. The limits are read once per charge point; a read error
+// leaves its sessions uncapped, balanced as before.
+func (lb *LoadBalancer) readExternalLimits(sessions []*session, now time.Time) {
+	if lb.database == nil {
+		return
+	}
+	limits := make(map[string][]*entity.ExternalLimit)
+	for _, s := range sessions {
+		if s.protocol == common.OCPP16 {
+			continue
+		}
+		chargePointId := s.connector.ChargePointId
+		chpLimits, ok := limits[chargePointId]
+		if !ok {
+			var err error
+			chpLimits, err = lb.database.GetExternalLimits(chargePointId)
+			if err != nil {
+				lb.log.FeatureEvent(featureName, chargePointId, fmt.Sprintf("error reading external limits: %s", err))
+			}
+			limits[chargePointId] = chpLimits
+		}
+		evseId := s.connector.Id
+		if s.connector.EvseId != nil {
+			evseId = *s.connector.EvseId
+		}
+		s.cap, s.capped = hardCap(chpLimits, evseId, now)
+	}
+}
+
+// hardCap is the lowest limit in force on an EVSE, in whole amperes; false when nothing limits it
+func hardCap(limits []*entity.ExternalLimit, evseId int, now time.Time) (int, bool) {
+	lowest, capped := 0, false
+	for _, limit := range limits {
+		if !limit.AppliesTo(evseId) {
+			continue
+		}
+		for _, period := range limit.InForce(now) {
+			current := int(math.Floor(periodCurrent(period.Limit, period.Unit, period.Phases)))
+			if !capped || current < lowest {
+				lowest, capped = current, true
+			}
+		}
+	}
+	return max(lowest, 0), capped
+}
+
+// isSlot tells whether a limit is one the balancer hands out, rather than a cap it was lowered to
+func isSlot(limit int) bool {
+	if limit == baseLimit {
+		return true
+	}
+	for _, slot := range powerSlots {
+		if slot == limit {
+			return true
+		}
+	}
+	return false
+}
@@ -5,9 +5,12 @@ import (
 	"evsys/entity"
 	"evsys/internal"
 	"evsys/ocpp"
+	"evsys/ocpp/common"
 	"evsys/ocpp/v16/core"
 	"evsys/ocpp/v16/smartcharging"
+	smartcharging201 "evsys/ocpp/v201/smartcharging"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 )
@@ -73,11 +76,12 @@ func (lb *LoadBalancer) onChargePointBoot(chargePointId string) {
 	// getLocation only reads fields fixed at construction, so it is safe to call
 	// before taking the balancer lock - and discovery must not hold that lock
 	// while it waits on the network.
-	location, _ := lb.getLocation(chargePointId)
+	location, chp := lb.getLocation(chargePointId)
 	if location == nil {
 		return
 	}
-	lb.discoverCapabilities(chargePointId)
+	protocol := protocolOf(chp)
+	lb.discoverCapabilities(chargePointId, protocol)
 
 	lb.mutex.Lock()
 	defer lb.mutex.Unlock()
@@ -86,9 +90,15 @@ func (lb *LoadBalancer) onChargePointBoot(chargePointId string) {
 	if location.DefaultPowerLimit == 0 {
 		description = "clearing default charging profile"
 		request = smartcharging.NewClearDefaultChargingProfileRequest()
+		if protocol.IsOCPP2() {
+			request = smartcharging201.NewClearDefaultChargingProfileRequest()
+		}
 	} else {
 		description = fmt.Sprintf("setting default charging profile to %dA", location.DefaultPowerLimit)
 		request = smartcharging.NewSetChargingProfileRequest(0, smartcharging.NewDefaultChargingProfile(location.DefaultPowerLimit))
+		if protocol.IsOCPP2() {
+			request = smartcharging201.NewSetChargingProfileRequest(0, smartcharging201.NewDefaultChargingProfile(location.DefaultPowerLimit))
+		}
 	}
 	lb.log.FeatureEvent(featureName, chargePointId, description)
 	if err := lb.sendProfile(chargePointId, description, request, nil); err != nil {
@@ -109,20 +119,35 @@ func (lb *LoadBalancer) ensureCapabilities(chargePointId string) {
 	if !lb.capabilities.beginDiscovery(chargePointId, time.Now(), discoveryRetryInterval) {
 		return
 	}
-	lb.discoverCapabilities(chargePointId)
+	protocol := common.OCPP16
+	if lb.database != nil {
+		if chp, err := lb.database.GetChargePoint(chargePointId); err == nil {
+			protocol = protocolOf(chp)
+		}
+	}
+	lb.discoverCapabilities(chargePointId, protocol)
 }
 
 // discoverCapabilities asks the charge point what charging profiles it will
-// accept, so we stop guessing at the stack level and the rate unit.
-func (lb *LoadBalancer) discoverCapabilities(chargePointId string) {
-	request := core.NewGetConfigurationRequest([]string{keyMaxStackLevel, keyAllowedRateUnit})
+// accept, so we stop guessing at the stack level and the rate unit. A 1.6
+// charge point is asked for its configuration keys, a 2.0.1 one for the
+// SmartChargingCtrlr variables of its device model.
+func (lb *LoadBalancer) discoverCapabilities(chargePointId string, protocol common.ProtocolVersion) {
+	var request ocpp.Request = core.NewGetConfigurationRequest([]string{keyMaxStackLevel, keyAllowedRateUnit})
+	parse := parseCapabilities
+	stackLevelKey, rateUnitKey := keyMaxStackLevel, keyAllowedRateUnit
+	if protocol.IsOCPP2() {
+		request = newCapabilityVariablesRequest()
+		parse = parseCapabilityVariables
+		stackLevelKey, rateUnitKey = smartChargingCtrlr+"."+variableProfileStackLevel, smartChargingCtrlr+"."+variableRateUnit
+	}
 	payload, err := lb.server.SendRequestSync(chargePointId, request, capabilityTimeout)
 	if err != nil {
 		lb.log.FeatureEvent(featureName, chargePointId,
 			fmt.Sprintf("smart charging configuration unavailable, using defaults: %s", err))
 		return
 	}
-	reported, err := parseCapabilities(payload)
+	reported, err := parse(payload)
 	if err != nil {
 		lb.log.FeatureEvent(featureName, chargePointId, fmt.Sprintf("smart charging configuration: %s", err))
 		return
@@ -130,8 +155,8 @@ func (lb *LoadBalancer) discoverCapabilities(chargePointId string) {
 	stored := lb.capabilities.record(chargePointId, reported)
 	lb.log.FeatureEvent(featureName, chargePointId, fmt.Sprintf(
 		"smart charging configuration: %s=%d (using %d), %s=%s",
-		keyMaxStackLevel, reported.maxStackLevel, stored.maxStackLevel,
-		keyAllowedRateUnit, reported.allowedUnits))
+		stackLevelKey, reported.maxStackLevel, stored.maxStackLevel,
+		rateUnitKey, reported.allowedUnits))
 	if !reported.allowsCurrent {
 		lb.log.FeatureEvent(featureName, chargePointId, fmt.Sprintf(
 			"CANNOT ENFORCE POWER LIMITS: charge point accepts %s schedules only, and the balancer works in amperes",
@@ -156,12 +181,18 @@ func (lb *LoadBalancer) sendProfile(chargePointId, description string, request o
 		defer release()
 		verdict := entity.ProfileStatusNoResponse
 		select {
-		case payload := <-response:
+		case answer := <-response:
+			if answer.Err != nil {
+				verdict = entity.ProfileStatusCallError
+				lb.log.FeatureEvent(featureName, chargePointId,
+					fmt.Sprintf("%s: REFUSED by charge point with %s", description, answer.Err))
+				break
+			}
 			var status profileStatus
-			if err := json.Unmarshal([]byte(payload), &status); err != nil {
+			if err := json.Unmarshal([]byte(answer.Payload), &status); err != nil {
 				verdict = entity.ProfileStatusUnreadable
 				lb.log.FeatureEvent(featureName, chargePointId,
-					fmt.Sprintf("%s: unreadable response: %s", description, payload))
+					fmt.Sprintf("%s: unreadable response: %s", description, answer.Payload))
 				break
 			}
 			verdict = status.Status
@@ -209,22 +240,25 @@ func (lb *LoadBalancer) recordVerdict(connector *entity.Connector, status string
 // of levels available, so a charge point reporting 10 may accept only 0..9. The
 // retry settles that against the hardware instead of guessing, and the working
 // level is remembered for later profiles.
-func (lb *LoadBalancer) sendTransactionProfile(connector *entity.Connector, powerLimit int, connectorInfo string, retriesLeft int) error {
+func (lb *LoadBalancer) sendTransactionProfile(connector *entity.Connector, protocol common.ProtocolVersion, powerLimit int, connectorInfo string, retriesLeft int) error {
 	chargePointId := connector.ChargePointId
 	limits := lb.capabilities.get(chargePointId)
 	if limits.known && !limits.allowsCurrent {
 		return fmt.Errorf("charge point accepts %s schedules only", limits.allowedUnits)
 	}
 	stackLevel := limits.stackLevelFor(smartcharging.TxProfileStackLevel)
+	if protocol.IsOCPP2() {
+		stackLevel = limits.stackLevelFor(smartcharging201.TxProfileStackLevel)
+	}
 	transactionId := connector.CurrentTransactionId
 
+	request, err := lb.transactionProfileRequest(connector, protocol, powerLimit, stackLevel)
+	if err != nil {
+		return err
+	}
 	description := fmt.Sprintf("power limit %dA for %s at stack level %d", powerLimit, connectorInfo, stackLevel)
 	lb.log.FeatureEvent(featureName, chargePointId, "setting "+description)
 
-	request := smartcharging.NewSetChargingProfileRequest(
-		connector.Id, smartcharging.NewTransactionChargingProfile(
-			connector.Id, transactionId, powerLimit, stackLevel))
-
 	return lb.sendProfile(chargePointId, description, request, func(status string) {
 		lb.recordVerdict(connector, status, powerLimit, stackLevel)
 		if status == entity.ProfileStatusAccepted {
@@ -248,13 +282,52 @@ func (lb *LoadBalancer) sendTransactionProfile(connector *entity.Connector, powe
 		if connector.CurrentTransactionId != transactionId {
 			return
 		}
-		if err := lb.sendTransactionProfile(connector, powerLimit, connectorInfo, retriesLeft-1); err != nil {
+		if err := lb.sendTransactionProfile(connector, protocol, powerLimit, connectorInfo, retriesLeft-1); err != nil {
 			lb.log.FeatureEvent(featureName, chargePointId,
 				fmt.Sprintf("retry at stack level %d failed: %s", stackLevel-1, err))
 		}
 	})
 }
 
+// transactionProfileRequest builds the SetChargingProfile limiting a connector's
+// session in the charge point's own protocol. A 2.0.1 TxProfile is bound to the
+// EVSE and names the transaction by the id the charge point gave it, which is
+// kept as the session id of our transaction record.
+func (lb *LoadBalancer) transactionProfileRequest(connector *entity.Connector, protocol common.ProtocolVersion, powerLimit, stackLevel int) (ocpp.Request, error) {
+	if !protocol.IsOCPP2() {
+		return smartcharging.NewSetChargingProfileRequest(
+			connector.Id, smartcharging.NewTransactionChargingProfile(
+				connector.Id, connector.CurrentTransactionId, powerLimit, stackLevel)), nil
+	}
+	if lb.database == nil {
+		return nil, fmt.Errorf("transaction %d: no database to read its OCPP 2.0.1 id from", connector.CurrentTransactionId)
+	}
+	transaction, err := lb.database.GetTransaction(connector.CurrentTransactionId)
+	if err != nil {
+		return nil, fmt.Errorf("transaction %d: %s", connector.CurrentTransactionId, err)
+	}
+	if transaction == nil || transaction.SessionId == "" {
+		return nil, fmt.Errorf("transaction %d has no OCPP 2.0.1 transaction id", connector.CurrentTransactionId)
+	}
+	evseId := connector.Id
+	if connector.EvseId != nil {
+		evseId = *connector.EvseId
+	}
+	return smartcharging201.NewSetChargingProfileRequest(
+		evseId, smartcharging201.NewTransactionChargingProfile(
+			evseId, transaction.SessionId, powerLimit, stackLevel)), nil
+}
+
+// protocolOf reads the protocol a charge point last booted with; one that never
+// said is taken to speak 1.6, which is all the balancer used to support. A 2.1
+// charge point takes the 2.0.1 profiles, which 2.1 left unchanged on the wire.
+func protocolOf(chp *entity.ChargePoint) common.ProtocolVersion {
+	if chp != nil && common.ProtocolVersion(chp.ProtocolVersion).IsOCPP2() {
+		return common.ProtocolVersion(chp.ProtocolVersion)
+	}
+	return common.OCPP16
+}
+
 func (lb *LoadBalancer) OnSystemStart() {
 	if lb.database == nil {
 		return
@@ -304,18 +377,16 @@ func (lb *LoadBalancer) CheckPowerLimit(chargePointId string) {
 	if location.PowerLimit == 0 {
 		return
 	}
-	usedSlots := make(map[int]bool)
 	// all active connectors on smart charging points
-	activeConnectors := 0
+	sessions := make([]*session, 0)
 	for _, chp := range location.Evses {
 		if chp.SmartCharging {
 			for _, connector := range chp.Connectors {
 				if connector.CurrentTransactionId >= 0 {
-					activeConnectors++
-					usedSlots[connector.CurrentPowerLimit] = true
+					sessions = append(sessions, &session{connector: connector, protocol: protocolOf(chp)})
 				} else if connector.CurrentPowerLimit > 0 {
 					// clear power limit for connector with no active transaction
-					err := lb.updateConnectorPower(0, connector)
+					err := lb.updateConnectorPower(0, connector, protocolOf(chp))
 					if err != nil {
 						lb.log.FeatureEvent(featureName, chargePointId, fmt.Sprintf("error updating connector: %s", err))
 					}
@@ -323,31 +394,153 @@ func (lb *LoadBalancer) CheckPowerLimit(chargePointId string) {
 			}
 		}
 	}
-	if activeConnectors == 0 {
+	if len(sessions) == 0 {
 		return
 	}
-	powerLimit := baseLimit
-	for _, slot := range powerSlots {
-		if !usedSlots[slot] {
-			powerLimit = slot
-			break
+	now := time.Now()
+	lb.readUrgency(sessions, now)
+	lb.readExternalLimits(sessions, now)
+	// the most urgent session is served first; without charging needs, in the order of the location
+	sort.SliceStable(sessions, func(i, j int) bool { return sessions[i].outranks(sessions[j]) })
+
+	// an external limit below a session's slot lowers the session and frees the slot
+	lowered := make(map[*session]bool)
+	for _, s := range sessions {
+		if s.capped && s.connector.CurrentPowerLimit > s.cap {
+			lb.log.FeatureEvent(featureName, chargePointId, fmt.Sprintf("external limit lowers a session from %dA to %dA",
+				s.connector.CurrentPowerLimit, s.cap))
+			lb.setSessionPower(chargePointId, s, s.cap)
+			lowered[s] = true
+		}
+	}
+	usedSlots := make(map[int]bool)
+	for _, s := range sessions {
+		if isSlot(s.connector.CurrentPowerLimit) {
+			usedSlots[s.connector.CurrentPowerLimit] = true
 		}
 	}
-	lb.log.FeatureEvent(featureName, chargePointId, fmt.Sprintf("active connectors: %d; assigning %dA to new sessions", activeConnectors, powerLimit))
 
-	// send set charging profile request to each active connector
-	for _, chp := range location.Evses {
-		if chp.SmartCharging {
-			for _, connector := range chp.Connectors {
-				if connector.CurrentTransactionId >= 0 && connector.CurrentPowerLimit > 0 {
-					continue
-				}
-				err := lb.updateConnectorPower(powerLimit, connector)
-				if err != nil {
-					lb.log.FeatureEvent(featureName, chargePointId, fmt.Sprintf("error updating connector: %s", err))
-				}
+	// new sessions take the highest free slot, one session per slot; so does a session whose
+	// external limit was raised or cleared, and a capped one keeps the slot free if it cannot use it
+	for _, s := range sessions {
+		if !s.needsLimit() || lowered[s] {
+			continue
+		}
+		powerLimit := baseLimit
+		for _, slot := range powerSlots {
+			if !usedSlots[slot] {
+				powerLimit = slot
+				break
 			}
 		}
+		if s.capped && s.cap < powerLimit {
+			lb.log.FeatureEvent(featureName, chargePointId, fmt.Sprintf("active connectors: %d; assigning %dA to a session under an external limit",
+				len(sessions), s.cap))
+			lb.setSessionPower(chargePointId, s, s.cap)
+			continue
+		}
+		usedSlots[powerLimit] = true
+		lb.log.FeatureEvent(featureName, chargePointId, fmt.Sprintf("active connectors: %d; assigning %dA to a new session", len(sessions), powerLimit))
+		lb.setSessionPower(chargePointId, s, powerLimit)
+	}
+	lb.promoteUrgent(chargePointId, sessions)
+}
+
+// session is an active connector of a balanced location, with the urgency of its EV
+type session struct {
+	connector *entity.Connector
+	protocol  common.ProtocolVersion
+	// urgency is the average power, in W, the EV needs to get its energy by its departure time;
+	// 0 when it did not report charging needs
+	urgency int
+	// priority is set while the driver has priority charging switched on at an OCPP 2.1 station
+	priority bool
+	// cap is the lowest external limit in force on the EVSE, in A, when capped is set
+	cap    int
+	capped bool
+}
+
+// needsLimit tells whether the session is to be given a slot: it has none yet, or holds a limit
+// the balancer did not hand out and that is no longer its cap. A 0A cap is sent again each time,
+// as it cannot be told from no limit at all.
+func (s *session) needsLimit() bool {
+	limit := s.connector.CurrentPowerLimit
+	if limit == 0 || isSlot(limit) {
+		return limit == 0
+	}
+	return !s.capped || limit != s.cap
+}
+
+// outranks tells whether s is served before other: a priority session before any other, then
+// the higher urgency
+func (s *session) outranks(other *session) bool {
+	if s.priority != other.priority {
+		return s.priority
+	}
+	return s.urgency > other.urgency
+}
+
+// readUrgency fills in the urgency of each session from the charging needs stored on its
+// transaction. Only ISO 15118 EVs report them; a read error leaves a session at 0, balanced as
+// before.
+func (lb *LoadBalancer) readUrgency(sessions []*session, now time.Time) {
+	if lb.database == nil {
+		return
+	}
+	for _, s := range sessions {
+		transaction, err := lb.database.GetTransaction(s.connector.CurrentTransactionId)
+		if err != nil || transaction == nil {
+			continue
+		}
+		s.urgency = transaction.ChargingNeeds.RequiredPower(now)
+		s.priority = transaction.Priority
+	}
+}
+
+// promoteUrgent swaps limits so that a priority session or one with a deadline is not left on a
+// low slot while a less urgent one holds a higher slot. sessions are sorted most urgent first;
+// sessions with neither priority nor charging needs never displace anyone. A session under an
+// external limit could not use a higher slot, and its own limit is not one to hand over.
+func (lb *LoadBalancer) promoteUrgent(chargePointId string, sessions []*session) {
+	for i, urgent := range sessions {
+		if urgent.urgency == 0 && !urgent.priority {
+			return
+		}
+		if urgent.capped {
+			continue
+		}
+		var holder *session
+		for _, other := range sessions[i+1:] {
+			if other.capped || !urgent.outranks(other) || other.connector.CurrentPowerLimit <= urgent.connector.CurrentPowerLimit {
+				continue
+			}
+			if holder == nil || other.connector.CurrentPowerLimit > holder.connector.CurrentPowerLimit {
+				holder = other
+			}
+		}
+		if holder == nil {
+			continue
+		}
+		higher, lower := holder.connector.CurrentPowerLimit, urgent.connector.CurrentPowerLimit
+		if urgent.priority {
+			lb.log.FeatureEvent(featureName, chargePointId, fmt.Sprintf("priority session takes %dA from one needing %dW",
+				higher, holder.urgency))
+		} else {
+			lb.log.FeatureEvent(featureName, chargePointId, fmt.Sprintf("session needing %dW takes %dA from one needing %dW",
+				urgent.urgency, higher, holder.urgency))
+		}
+		lb.setSessionPower(chargePointId, holder, lower)
+		lb.setSessionPower(chargePointId, urgent, higher)
+	}
+}
+
+// setSessionPower sends a session its limit, never above its external cap
+func (lb *LoadBalancer) setSessionPower(chargePointId string, s *session, powerLimit int) {
+	if s.capped && powerLimit > s.cap {
+		powerLimit = s.cap
+	}
+	if err := lb.updateConnectorPower(powerLimit, s.connector, s.protocol); err != nil {
+		lb.log.FeatureEvent(featureName, chargePointId, fmt.Sprintf("error updating connector: %s", err))
 	}
 }
 
@@ -375,8 +568,9 @@ func (lb *LoadBalancer) getLocation(chargePointId string) (*entity.Location, *en
 }
 
 // updateConnectorPower sets or clears the power limit for a connector
-// Handles both OCPP 1.6J connectors and OCPP 2.0.1 EVSEs
-func (lb *LoadBalancer) updateConnectorPower(powerLimit int, connector *entity.Connector) error {
+// Handles both OCPP 1.6J connectors and OCPP 2.0.1 EVSEs, so charge points of
+// either protocol share the budget of their location
+func (lb *LoadBalancer) updateConnectorPower(powerLimit int, connector *entity.Connector, protocol common.ProtocolVersion) error {
 	if connector.CurrentTransactionId < 0 && connector.CurrentPowerLimit == 0 {
 		// no need to update - connector is not active and has no limit set
 		return nil
@@ -388,7 +582,7 @@ func (lb *LoadBalancer) updateConnectorPower(powerLimit int, connector *entity.C
 		if connector.EvseId != nil {
 			connectorInfo = fmt.Sprintf("EVSE %d / connector %d", *connector.EvseId, connector.Id)
 		}
-		if err := lb.sendTransactionProfile(connector, powerLimit, connectorInfo, stackLevelRetries); err != nil {
+		if err := lb.sendTransactionProfile(connector, protocol, powerLimit, connectorInfo, stackLevelRetries); err != nil {
 			return fmt.Errorf("sending profile update request: %s", err)
 		}
 		connector.CurrentPowerLimit = powerLimit
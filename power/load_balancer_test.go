@@ -4,8 +4,12 @@ import (
 	"errors"
 	"evsys/entity"
 	"evsys/ocpp"
+	"evsys/ocpp/common"
 	"evsys/ocpp/v16/core"
 	"evsys/ocpp/v16/smartcharging"
+	"evsys/ocpp/v201"
+	"evsys/ocpp/v201/provisioning"
+	smartcharging201 "evsys/ocpp/v201/smartcharging"
 	"evsys/types"
 	"fmt"
 	"strings"
@@ -20,12 +24,35 @@ type stubRepo struct {
 	location    *entity.Location
 	txLimits    map[int]int                         // transactionId -> last recorded power limit
 	verdicts    map[string][]*entity.ProfileVerdict // "chargePointId/connectorId" -> verdicts, in order
+	sessionIds  map[int]string                      // transactionId -> the charge point's 2.0.1 transaction id
+	needs       map[int]*entity.ChargingNeeds       // transactionId -> what its ISO 15118 EV reported
+	priority    map[int]bool                        // transactionId -> priority charging switched on
+	limits      []*entity.ExternalLimit             // set by external systems, on any charge point
 }
 
-func (s *stubRepo) GetChargePoint(_ string) (*entity.ChargePoint, error) {
+// GetChargePoint finds the charge point among the location's, so a test can
+// mix charge points; chargePoint answers for any other id.
+func (s *stubRepo) GetChargePoint(id string) (*entity.ChargePoint, error) {
+	if s.location != nil {
+		for _, chp := range s.location.Evses {
+			if chp.Id == id {
+				return chp, nil
+			}
+		}
+	}
 	return s.chargePoint, nil
 }
 
+func (s *stubRepo) GetTransaction(id int) (*entity.Transaction, error) {
+	sessionId, ok := s.sessionIds[id]
+	needs, hasNeeds := s.needs[id]
+	priority := s.priority[id]
+	if !ok && !hasNeeds && !priority {
+		return nil, fmt.Errorf("transaction %d not found", id)
+	}
+	return &entity.Transaction{Id: id, SessionId: sessionId, ChargingNeeds: needs, Priority: priority}, nil
+}
+
 func (s *stubRepo) GetLocation(_ string) (*entity.Location, error) {
 	return s.location, nil
 }
@@ -46,6 +73,16 @@ func (s *stubRepo) UpdateTransactionPowerLimit(transactionId, limit int) error {
 	return nil
 }
 
+func (s *stubRepo) GetExternalLimits(chargePointId string) ([]*entity.ExternalLimit, error) {
+	var limits []*entity.ExternalLimit
+	for _, limit := range s.limits {
+		if limit.ChargePointId == chargePointId {
+			limits = append(limits, limit)
+		}
+	}
+	return limits, nil
+}
+
 // Verdicts are written from the goroutine that waits on the charge point, so
 // the test's own reads have to be guarded.
 func (s *stubRepo) UpdateConnectorProfileVerdict(chargePointId string, connectorId int, verdict *entity.ProfileVerdict) error {
@@ -93,10 +130,17 @@ type stubServer struct {
 	// silent makes the stub accept profile requests and never answer them, so
 	// the balancer falls through to its response timeout.
 	silent bool
+	// callError, when set, is the CallError the stub answers profiles with.
+	callError *ocpp.CallError
 	// configCalls counts how many times its configuration was read.
 	configCalls int
 	// profiles records the charging profiles the balancer installed, in order.
 	profiles []*types.ChargingProfile
+	// variablesPayload is the CallResult a 2.0.1 charge point answers
+	// GetVariables with; empty means it does not answer.
+	variablesPayload string
+	// profiles201 records the 2.0.1 profiles installed, by charge point.
+	profiles201 map[string][]*smartcharging201.SetChargingProfileRequest
 }
 
 func (s *stubServer) SendRequest(_ string, _ ocpp.Request) (string, error) {
@@ -113,6 +157,15 @@ func (s *stubServer) SendRequestSync(_ string, request ocpp.Request, _ time.Dura
 		}
 		return s.configPayload, nil
 	}
+	if _, ok := request.(*provisioning.GetVariablesRequest); ok {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		s.configCalls++
+		if s.variablesPayload == "" {
+			return "", errors.New("no response")
+		}
+		return s.variablesPayload, nil
+	}
 	return `{"status":"Accepted"}`, nil
 }
 
@@ -124,24 +177,35 @@ func (s *stubServer) configurationReads() int {
 
 // SendRequestWithResponse answers immediately so the verdict goroutine the load
 // balancer spawns finishes within the test rather than sitting on its timeout.
-func (s *stubServer) SendRequestWithResponse(_ string, request ocpp.Request) (<-chan string, func(), error) {
+func (s *stubServer) SendRequestWithResponse(clientId string, request ocpp.Request) (<-chan ocpp.Answer, func(), error) {
 	s.mutex.Lock()
 	payload := s.payload
 	silent := s.silent
-	if profile, ok := request.(*smartcharging.SetChargingProfileRequest); ok {
+	callError := s.callError
+	switch profile := request.(type) {
+	case *smartcharging.SetChargingProfileRequest:
 		s.profiles = append(s.profiles, profile.ChargingProfile)
+	case *smartcharging201.SetChargingProfileRequest:
+		if s.profiles201 == nil {
+			s.profiles201 = make(map[string][]*smartcharging201.SetChargingProfileRequest)
+		}
+		s.profiles201[clientId] = append(s.profiles201[clientId], profile)
 	}
 	s.mutex.Unlock()
 
-	response := make(chan string, 1)
+	response := make(chan ocpp.Answer, 1)
 	if silent {
 		// queued but never answered
 		return response, func() {}, nil
 	}
+	if callError != nil {
+		response <- ocpp.Answer{Err: callError}
+		return response, func() {}, nil
+	}
 	if payload == "" {
 		payload = `{"status":"Accepted"}`
 	}
-	response <- payload
+	response <- ocpp.Answer{Payload: payload}
 	return response, func() {}, nil
 }
 
@@ -153,6 +217,12 @@ func (s *stubServer) installedProfiles() []*types.ChargingProfile {
 	return append([]*types.ChargingProfile{}, s.profiles...)
 }
 
+func (s *stubServer) installedProfiles201(chargePointId string) []*smartcharging201.SetChargingProfileRequest {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]*smartcharging201.SetChargingProfileRequest{}, s.profiles201[chargePointId]...)
+}
+
 // stubLog records feature events so a test can assert on what the load balancer
 // reported. The verdict goroutine writes concurrently with the test, hence the
 // mutex.
@@ -256,6 +326,22 @@ func TestUnreadableProfileResponseIsReported(t *testing.T) {
 	log.waitForEvent(t, "unreadable response")
 }
 
+// A profile refused with a CallError is recorded as such rather than waited out.
+func TestProfileCallErrorIsRecorded(t *testing.T) {
+	lb, connectors := newTestBalancer(1)
+	lb.server.(*stubServer).callError = ocpp.NewCallError(ocpp.PropertyConstraintViolation, "stackLevel out of range")
+	repo := lb.database.(*stubRepo)
+	log := lb.log.(*stubLog)
+
+	connectors[0].CurrentTransactionId = 7
+	lb.CheckPowerLimit("chp1")
+
+	log.waitForEvent(t, "PropertyConstraintViolation")
+	if verdict := repo.waitForVerdicts(t, "chp1", 1, 1)[0]; verdict.Status != entity.ProfileStatusCallError {
+		t.Errorf("status = %q, want %q", verdict.Status, entity.ProfileStatusCallError)
+	}
+}
+
 // current_power_limit records what was asked for and is written before the
 // charge point answers, so on its own it cannot tell a limit in force from one
 // that was refused. The verdict has to be stored for that question to be
@@ -642,6 +728,161 @@ func TestFreedSlotGoesToNextSession(t *testing.T) {
 	}
 }
 
+// needsBy is an EV asking for energy Wh by the given time from now
+func needsBy(energy int, left time.Duration) *entity.ChargingNeeds {
+	departure := time.Now().Add(left)
+	return &entity.ChargingNeeds{EnergyAmount: energy, DepartureTime: &departure}
+}
+
+// Sessions waiting for a limit are served most urgent first; one without
+// charging needs comes after those with a deadline.
+func TestUrgentSessionsGetHigherSlots(t *testing.T) {
+	lb, connectors := newTestBalancer(3)
+	repo := lb.database.(*stubRepo)
+	repo.needs = map[int]*entity.ChargingNeeds{
+		1: needsBy(10000, 5*time.Hour),
+		2: needsBy(30000, time.Hour),
+	}
+
+	for i := range connectors {
+		connectors[i].CurrentTransactionId = i
+	}
+	lb.CheckPowerLimit("chp1")
+
+	want := []int{powerSlots[2], powerSlots[1], powerSlots[0]}
+	for i, connector := range connectors {
+		if connector.CurrentPowerLimit != want[i] {
+			t.Errorf("connector %d: got %dA, want %dA", i+1, connector.CurrentPowerLimit, want[i])
+		}
+	}
+}
+
+// An EV that reports a close departure after its session was balanced takes the
+// highest slot held by a less urgent session, which moves down to its slot.
+func TestUrgentSessionTakesHigherSlot(t *testing.T) {
+	lb, connectors := newTestBalancer(2)
+	repo := lb.database.(*stubRepo)
+
+	connectors[0].CurrentTransactionId = 0
+	lb.CheckPowerLimit("chp1")
+	connectors[1].CurrentTransactionId = 1
+	lb.CheckPowerLimit("chp1")
+	if connectors[0].CurrentPowerLimit != powerSlots[0] || connectors[1].CurrentPowerLimit != powerSlots[1] {
+		t.Fatalf("limits %dA and %dA before the needs arrived, want %dA and %dA",
+			connectors[0].CurrentPowerLimit, connectors[1].CurrentPowerLimit, powerSlots[0], powerSlots[1])
+	}
+
+	repo.needs = map[int]*entity.ChargingNeeds{1: needsBy(20000, 2*time.Hour)}
+	lb.CheckPowerLimit("chp1")
+	if connectors[0].CurrentPowerLimit != powerSlots[1] || connectors[1].CurrentPowerLimit != powerSlots[0] {
+		t.Fatalf("limits %dA and %dA after the needs arrived, want %dA and %dA",
+			connectors[0].CurrentPowerLimit, connectors[1].CurrentPowerLimit, powerSlots[1], powerSlots[0])
+	}
+	if repo.txLimits[1] != powerSlots[0] || repo.txLimits[0] != powerSlots[1] {
+		t.Errorf("recorded limits %v, want the swapped ones", repo.txLimits)
+	}
+
+	// checking again changes nothing: the urgent session already holds the top slot
+	lb.CheckPowerLimit("chp1")
+	if connectors[1].CurrentPowerLimit != powerSlots[0] {
+		t.Errorf("urgent session moved to %dA", connectors[1].CurrentPowerLimit)
+	}
+}
+
+// A session switched to priority charging takes the top slot even from an EV with a deadline.
+func TestPrioritySessionOutranksDeadline(t *testing.T) {
+	lb, connectors := newTestBalancer(3)
+	repo := lb.database.(*stubRepo)
+	for i := range connectors {
+		connectors[i].CurrentTransactionId = i
+	}
+	lb.CheckPowerLimit("chp1")
+
+	repo.needs = map[int]*entity.ChargingNeeds{1: needsBy(30000, time.Hour)}
+	repo.priority = map[int]bool{2: true}
+	lb.CheckPowerLimit("chp1")
+
+	want := []int{powerSlots[2], powerSlots[1], powerSlots[0]}
+	for i, connector := range connectors {
+		if connector.CurrentPowerLimit != want[i] {
+			t.Errorf("connector %d: got %dA, want %dA", i+1, connector.CurrentPowerLimit, want[i])
+		}
+	}
+
+	// once priority charging ends, the EV with a deadline is the most urgent again
+	repo.priority = nil
+	lb.CheckPowerLimit("chp1")
+	if connectors[1].CurrentPowerLimit != powerSlots[0] {
+		t.Errorf("session with a deadline got %dA after priority ended, want %dA", connectors[1].CurrentPowerLimit, powerSlots[0])
+	}
+}
+
+func TestScheduleFitsLocationLimit(t *testing.T) {
+	lb, _ := newTestBalancer(1)
+	repo := lb.database.(*stubRepo)
+	repo.location.PowerLimit = 32
+
+	amps := v201.ChargingSchedule{
+		ChargingRateUnit:       v201.ChargingRateUnitA,
+		ChargingSchedulePeriod: []v201.ChargingSchedulePeriod{{StartPeriod: 0, Limit: 32}, {StartPeriod: 600, Limit: 40}},
+	}
+	if fits, reason := lb.ScheduleFits("chp1", 1, amps); fits || !strings.Contains(reason, "600s") {
+		t.Errorf("ScheduleFits = %v, %q; want the 40A period refused", fits, reason)
+	}
+	amps.ChargingSchedulePeriod = amps.ChargingSchedulePeriod[:1]
+	if fits, reason := lb.ScheduleFits("chp1", 1, amps); !fits {
+		t.Errorf("32A refused: %s", reason)
+	}
+
+	// 22kW on three phases is 32A per phase; on one phase it is far above
+	watts := v201.ChargingSchedule{
+		ChargingRateUnit:       v201.ChargingRateUnitW,
+		ChargingSchedulePeriod: []v201.ChargingSchedulePeriod{{StartPeriod: 0, Limit: 22000}},
+	}
+	if fits, reason := lb.ScheduleFits("chp1", 1, watts); !fits {
+		t.Errorf("22kW on three phases refused: %s", reason)
+	}
+	phases := 1
+	watts.ChargingSchedulePeriod[0].NumberPhases = &phases
+	if fits, _ := lb.ScheduleFits("chp1", 1, watts); fits {
+		t.Error("22kW on one phase accepted")
+	}
+
+	repo.location.PowerLimit = 0
+	if fits, _ := lb.ScheduleFits("chp1", 1, watts); !fits {
+		t.Error("a location without a limit refused a schedule")
+	}
+}
+
+// An external limit on the EVSE below the location limit is the one a schedule has to keep to.
+func TestScheduleFitsExternalLimit(t *testing.T) {
+	lb, _, _ := newMixedBalancer()
+	repo := lb.database.(*stubRepo)
+	repo.location.PowerLimit = 32
+	repo.limits = []*entity.ExternalLimit{{
+		ChargePointId: "chp2",
+		EvseId:        1,
+		Source:        "EMS",
+		Periods:       []entity.LimitPeriod{{Start: time.Now().Add(-time.Minute), Limit: 16, Unit: "A"}},
+	}}
+
+	schedule := v201.ChargingSchedule{
+		ChargingRateUnit:       v201.ChargingRateUnitA,
+		ChargingSchedulePeriod: []v201.ChargingSchedulePeriod{{StartPeriod: 0, Limit: 20}},
+	}
+	if fits, reason := lb.ScheduleFits("chp2", 1, schedule); fits || !strings.Contains(reason, "16A") {
+		t.Errorf("ScheduleFits = %v, %q; want 20A refused under the 16A EVSE limit", fits, reason)
+	}
+	if fits, reason := lb.ScheduleFits("chp2", 2, schedule); !fits {
+		t.Errorf("20A refused on an EVSE without an external limit: %s", reason)
+	}
+
+	repo.location.PowerLimit = 0
+	if fits, _ := lb.ScheduleFits("chp2", 1, schedule); fits {
+		t.Error("20A accepted under the 16A EVSE limit in a location without a limit")
+	}
+}
+
 func TestBalancingDisabledWithoutLocationLimit(t *testing.T) {
 	lb, connectors := newTestBalancer(1)
 	repo := lb.database.(*stubRepo)
@@ -653,3 +894,188 @@ func TestBalancingDisabledWithoutLocationLimit(t *testing.T) {
 		t.Fatalf("got %dA, want no limit when location power limit is 0", connectors[0].CurrentPowerLimit)
 	}
 }
+
+// newMixedBalancer puts a 1.6 charge point and a 2.0.1 one with one EVSE in the
+// same location.
+func newMixedBalancer() (*LoadBalancer, *entity.Connector, *entity.Connector) {
+	lb, connectors := newTestBalancer(1)
+	repo := lb.database.(*stubRepo)
+	evseId := 1
+	evse := entity.NewConnector(1, "chp2")
+	evse.EvseId = &evseId
+	repo.location.Evses = append(repo.location.Evses, &entity.ChargePoint{
+		Id:              "chp2",
+		LocationId:      "loc1",
+		SmartCharging:   true,
+		ProtocolVersion: string(common.OCPP201),
+		Connectors:      []*entity.Connector{evse},
+	})
+	repo.sessionIds = map[int]string{8: "f3a1-tx"}
+	return lb, connectors[0], evse
+}
+
+// A 2.0.1 charge point shares the location budget with 1.6 ones, and is sent a
+// 2.0.1 TxProfile naming its own transaction id.
+func TestMixedProtocolsShareLocationBudget(t *testing.T) {
+	lb, connector, evse := newMixedBalancer()
+	server := lb.server.(*stubServer)
+	repo := lb.database.(*stubRepo)
+
+	connector.CurrentTransactionId = 7
+	lb.CheckPowerLimit("chp1")
+	evse.CurrentTransactionId = 8
+	lb.CheckPowerLimit("chp2")
+
+	if connector.CurrentPowerLimit != powerSlots[0] || evse.CurrentPowerLimit != powerSlots[1] {
+		t.Fatalf("limits %dA and %dA, want %dA and %dA",
+			connector.CurrentPowerLimit, evse.CurrentPowerLimit, powerSlots[0], powerSlots[1])
+	}
+	installed := server.installedProfiles201("chp2")
+	if len(installed) != 1 {
+		t.Fatalf("installed %d 2.0.1 profiles, want 1", len(installed))
+	}
+	request := installed[0]
+	if request.EvseId != 1 || request.ChargingProfile.TransactionId != "f3a1-tx" {
+		t.Errorf("profile for EVSE %d, transaction %q; want EVSE 1, transaction f3a1-tx",
+			request.EvseId, request.ChargingProfile.TransactionId)
+	}
+	if err := request.Validate(); err != nil {
+		t.Errorf("invalid 2.0.1 profile: %v", err)
+	}
+	if verdict := repo.waitForVerdicts(t, "chp2", 1, 1)[0]; verdict.Limit != powerSlots[1] {
+		t.Errorf("verdict for %dA, want %dA", verdict.Limit, powerSlots[1])
+	}
+	if len(server.installedProfiles()) != 1 {
+		t.Errorf("the 1.6 charge point was sent %d profiles, want 1", len(server.installedProfiles()))
+	}
+}
+
+// An external limit below its slot lowers a 2.0.1 session and hands the slot to the
+// next session; once it is cleared, the session is back in the slots.
+func TestExternalLimitCapsSession(t *testing.T) {
+	lb, connector, evse := newMixedBalancer()
+	repo := lb.database.(*stubRepo)
+
+	evse.CurrentTransactionId = 8
+	lb.CheckPowerLimit("chp2")
+	if evse.CurrentPowerLimit != powerSlots[0] {
+		t.Fatalf("EVSE got %dA, want %dA", evse.CurrentPowerLimit, powerSlots[0])
+	}
+
+	repo.limits = []*entity.ExternalLimit{{
+		ChargePointId: "chp2",
+		EvseId:        1,
+		Source:        "EMS",
+		Periods:       []entity.LimitPeriod{{Start: time.Now().Add(-time.Minute), Limit: 32, Unit: "A"}},
+	}}
+	lb.CheckPowerLimit("chp2")
+	if evse.CurrentPowerLimit != 32 || repo.txLimits[8] != 32 {
+		t.Fatalf("capped EVSE at %dA, recorded %dA; want 32A", evse.CurrentPowerLimit, repo.txLimits[8])
+	}
+
+	connector.CurrentTransactionId = 7
+	lb.CheckPowerLimit("chp1")
+	if connector.CurrentPowerLimit != powerSlots[0] {
+		t.Fatalf("next session got %dA, want the freed %dA", connector.CurrentPowerLimit, powerSlots[0])
+	}
+	if evse.CurrentPowerLimit != 32 {
+		t.Fatalf("capped EVSE moved to %dA", evse.CurrentPowerLimit)
+	}
+
+	repo.limits = nil
+	lb.CheckPowerLimit("chp2")
+	if evse.CurrentPowerLimit != powerSlots[1] {
+		t.Errorf("EVSE got %dA after the limit was cleared, want %dA", evse.CurrentPowerLimit, powerSlots[1])
+	}
+}
+
+// A new session under an external limit leaves the slot it cannot use to the next one.
+func TestCappedSessionLeavesSlotFree(t *testing.T) {
+	lb, connector, evse := newMixedBalancer()
+	repo := lb.database.(*stubRepo)
+	repo.limits = []*entity.ExternalLimit{{
+		ChargePointId: "chp2",
+		Source:        "SO",
+		Periods:       []entity.LimitPeriod{{Start: time.Now().Add(-time.Minute), Limit: 11040, Unit: "W"}},
+	}}
+
+	evse.CurrentTransactionId = 8
+	lb.CheckPowerLimit("chp2")
+	connector.CurrentTransactionId = 7
+	lb.CheckPowerLimit("chp1")
+
+	if evse.CurrentPowerLimit != 16 || connector.CurrentPowerLimit != powerSlots[0] {
+		t.Errorf("limits %dA and %dA, want 16A and %dA", evse.CurrentPowerLimit, connector.CurrentPowerLimit, powerSlots[0])
+	}
+}
+
+func TestHardCap(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+	limits := []*entity.ExternalLimit{
+		{EvseId: 0, Periods: []entity.LimitPeriod{{Start: now.Add(-time.Hour), End: &later, Limit: 63, Unit: "A"}}},
+		{EvseId: 1, Periods: []entity.LimitPeriod{
+			{Start: now.Add(-time.Hour), End: &now, Limit: 6, Unit: "A"},
+			{Start: now, End: &later, Limit: 7360, Unit: "W", Phases: 1},
+			{Start: later, Limit: 8, Unit: "A"},
+		}},
+	}
+	tests := []struct {
+		evseId int
+		cap    int
+		capped bool
+	}{
+		{1, 32, true},
+		{2, 63, true},
+	}
+	for _, tt := range tests {
+		if got, capped := hardCap(limits, tt.evseId, now); got != tt.cap || capped != tt.capped {
+			t.Errorf("EVSE %d: cap %dA (%v), want %dA (%v)", tt.evseId, got, capped, tt.cap, tt.capped)
+		}
+	}
+	if _, capped := hardCap(nil, 1, now); capped {
+		t.Error("capped without a limit")
+	}
+}
+
+// Without its transaction id a 2.0.1 TxProfile would be refused, so none is sent.
+func TestUnknownTransactionIdIsReported(t *testing.T) {
+	lb, _, evse := newMixedBalancer()
+	log := lb.log.(*stubLog)
+
+	evse.CurrentTransactionId = 9
+	lb.CheckPowerLimit("chp2")
+
+	log.waitForEvent(t, "transaction 9 not found")
+	if installed := lb.server.(*stubServer).installedProfiles201("chp2"); len(installed) != 0 {
+		t.Fatalf("installed %d profiles for an unknown transaction", len(installed))
+	}
+}
+
+// A 2.0.1 charge point reports its limits as SmartChargingCtrlr variables.
+func TestCapabilitiesDiscoveredFromDeviceModel(t *testing.T) {
+	lb, _, evse := newMixedBalancer()
+	server := lb.server.(*stubServer)
+	server.variablesPayload = `{"getVariableResult":[` +
+		`{"attributeStatus":"Accepted","attributeValue":"4","component":{"name":"SmartChargingCtrlr"},"variable":{"name":"ProfileStackLevel"}},` +
+		`{"attributeStatus":"Accepted","attributeValue":"A,W","component":{"name":"SmartChargingCtrlr"},"variable":{"name":"RateUnit"}}]}`
+	log := lb.log.(*stubLog)
+
+	evse.CurrentTransactionId = 8
+	lb.CheckPowerLimit("chp2")
+
+	log.waitForEvent(t, "SmartChargingCtrlr.ProfileStackLevel=4")
+	installed := server.installedProfiles201("chp2")
+	if len(installed) != 1 || installed[0].ChargingProfile.StackLevel != 4 {
+		t.Fatalf("installed %+v, want one profile at stack level 4", installed)
+	}
+
+	reported, err := parseCapabilityVariables(`{"getVariableResult":[` +
+		`{"attributeStatus":"Accepted","attributeValue":"W","component":{"name":"SmartChargingCtrlr"},"variable":{"name":"RateUnit"}}]}`)
+	if err != nil {
+		t.Fatalf("parseCapabilityVariables: %v", err)
+	}
+	if reported.allowsCurrent {
+		t.Error("a charge point taking W schedules only reads as taking amperes")
+	}
+}
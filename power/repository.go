@@ -8,8 +8,14 @@ type Repository interface {
 	GetLocations() ([]*entity.Location, error)
 	UpdateConnectorCurrentPower(connector *entity.Connector) error
 	UpdateTransactionPowerLimit(transactionId, limit int) error
+	// GetTransaction supplies the charge point's own id of a 2.0.1 transaction,
+	// which a TxProfile has to name.
+	GetTransaction(id int) (*entity.Transaction, error)
 	// UpdateConnectorProfileVerdict is keyed by identity rather than taking a
 	// connector: the charge point's answer arrives on a goroutine that no longer
 	// owns the connector the profile was built from.
 	UpdateConnectorProfileVerdict(chargePointId string, connectorId int, verdict *entity.ProfileVerdict) error
+	// GetExternalLimits is the limits external systems set on a 2.x charge point,
+	// which cap its sessions whatever slot they would otherwise get.
+	GetExternalLimits(chargePointId string) ([]*entity.ExternalLimit, error)
 }
@@ -0,0 +1,259 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"evsys/entity"
+	"evsys/internal"
+	"evsys/metrics/counters"
+	"evsys/ocpp"
+	"evsys/ocpp/common"
+	"evsys/ocpp/v16/core"
+	"evsys/ocpp/v201"
+	"evsys/ocpp/v201/provisioning"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	featureNameAuthentication = "Authentication"
+	// ChangePasswordFeatureName is the API command that rotates a charge point's password
+	ChangePasswordFeatureName = "ChangeChargePointPassword"
+	// authorizationKey is the 1.6 configuration key holding the Basic authentication password
+	authorizationKey = "AuthorizationKey"
+	// passwordChangeTimeout bounds the wait for a charge point to accept its new password
+	passwordChangeTimeout = 30 * time.Second
+	// the security whitepaper and 2.0.1 both allow passwords of 16 to 40 characters
+	minPasswordLength = 16
+	maxPasswordLength = 40
+	// certificateExpiryWarning is how early an expiring client certificate is alerted, once a day
+	certificateExpiryWarning = 30 * 24 * time.Hour
+)
+
+// PasswordChange is the outcome of a password rotation; the password itself is never reported.
+type PasswordChange struct {
+	ChargePointId string `json:"charge_point_id"`
+	Status        string `json:"status"`
+	Stored        bool   `json:"stored"`
+}
+
+// AuthenticateChargePoint checks HTTP Basic credentials against the stored password hash; the
+// user name has to be the charge point id. A charge point without a password cannot connect,
+// unless allowUnsetPassword lets it through, whatever it presents, until it is given one.
+func (h *SystemHandler) AuthenticateChargePoint(chargePointId, username, password string) bool {
+	chargePoint, ok := h.storedChargePoint(chargePointId)
+	if !ok {
+		h.rejectAuthentication("", chargePointId, "unknown charge point")
+		return false
+	}
+	passwordHash := chargePoint.PasswordHash
+	if passwordHash == "" && h.allowUnsetPassword {
+		h.logger.FeatureEvent(featureNameAuthentication, chargePointId, "connected without a password, none is set")
+		counters.CountUnsetPassword(chargePointId)
+		return true
+	}
+	switch {
+	case username == "" && password == "":
+		h.rejectAuthentication(chargePointId, chargePointId, "no credentials")
+		return false
+	case subtle.ConstantTimeCompare([]byte(username), []byte(chargePointId)) != 1:
+		h.rejectAuthentication(chargePointId, chargePointId, "user name mismatch")
+		return false
+	case passwordHash == "":
+		h.rejectAuthentication(chargePointId, chargePointId, "no password set")
+		return false
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password)); err != nil {
+		h.rejectAuthentication(chargePointId, chargePointId, "wrong password")
+		return false
+	}
+	return true
+}
+
+// AuthenticateCertificate accepts a client certificate, already verified against the configured CA
+// bundle, when its CN is the charge point id or its stored serial number, the CNs checkCsrSubject
+// lets the local CA sign, and keeps track of its expiry.
+func (h *SystemHandler) AuthenticateCertificate(chargePointId string, certificate *x509.Certificate) bool {
+	commonName := certificate.Subject.CommonName
+	chargePoint, known := h.storedChargePoint(chargePointId)
+	if commonName != chargePointId && (!known || commonName == "" || commonName != chargePoint.SerialNumber) {
+		knownId := ""
+		if known {
+			knownId = chargePointId
+		}
+		h.rejectAuthentication(knownId, chargePointId, fmt.Sprintf("certificate issued to %q", commonName))
+		return false
+	}
+	counters.ObserveCertificateExpiry(chargePointId, certificate.NotAfter)
+
+	now := h.getTime()
+	if certificate.NotAfter.Sub(now) > certificateExpiryWarning || !h.shouldAlertCertificate(chargePointId, now) {
+		return true
+	}
+	info := fmt.Sprintf("client certificate expires %s", certificate.NotAfter.Format(time.RFC3339))
+	h.logger.FeatureEvent(featureNameAuthentication, chargePointId, info)
+	eventMessage := &internal.EventMessage{
+		ChargePointId: chargePointId,
+		Time:          now,
+		Status:        "CertificateExpiring",
+		Info:          info,
+	}
+	go h.notifyEventListeners(internal.Alert, eventMessage)
+	return true
+}
+
+// storedChargePoint looks up a charge point for authentication, which runs on the HTTP goroutine
+// for any client before the upgrade: the map under h.mux first, then the database. Unlike
+// getChargePoint it never registers an id it does not know, so probing ids creates nothing. The
+// result is a copy, safe to read without the lock.
+func (h *SystemHandler) storedChargePoint(chargePointId string) (entity.ChargePoint, bool) {
+	h.mux.Lock()
+	state, ok := h.chargePoints[chargePointId]
+	var chargePoint entity.ChargePoint
+	if ok {
+		chargePoint = *state.model
+	}
+	h.mux.Unlock()
+	if ok {
+		return chargePoint, true
+	}
+	if h.database == nil {
+		return chargePoint, false
+	}
+	stored, err := h.database.GetChargePoint(chargePointId)
+	if err != nil || stored == nil {
+		return chargePoint, false
+	}
+	return *stored, true
+}
+
+// shouldAlertCertificate lets through one expiry alert a day for each charge point
+func (h *SystemHandler) shouldAlertCertificate(chargePointId string, now time.Time) bool {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if h.certificateAlerts == nil {
+		h.certificateAlerts = make(map[string]time.Time)
+	}
+	if last, ok := h.certificateAlerts[chargePointId]; ok && now.Sub(last) < 24*time.Hour {
+		return false
+	}
+	h.certificateAlerts[chargePointId] = now
+	return true
+}
+
+// rejectAuthentication counts a failure under knownId, left empty for unknown charge points
+func (h *SystemHandler) rejectAuthentication(knownId, chargePointId, reason string) {
+	h.logger.FeatureEvent(featureNameAuthentication, chargePointId, fmt.Sprintf("connection rejected: %s", reason))
+	counters.CountAuthFailure(knownId, reason)
+}
+
+/*
+ChangeChargePointPassword sets a new Basic authentication password on the charge point, through
+AuthorizationKey on 1.6 or SecurityCtrlr.BasicAuthPassword on 2.0.1, and stores its hash once the
+charge point has taken it. An empty password is replaced by a random one. Until the charge point
+accepts, the old password stays valid, so a refused change does not lock it out.
+*/
+func (h *SystemHandler) ChangeChargePointPassword(chargePointId string, protocol common.ProtocolVersion, password string) (*PasswordChange, error) {
+	h.mux.Lock()
+	state, ok := h.getChargePoint(chargePointId)
+	h.mux.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("charge point not found")
+	}
+	if h.server == nil || h.database == nil {
+		return nil, fmt.Errorf("password change is not available")
+	}
+	if password == "" {
+		var err error
+		if password, err = newPassword(); err != nil {
+			return nil, err
+		}
+	}
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return nil, fmt.Errorf("password must be %d to %d characters long", minPasswordLength, maxPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("hash password: %v", err)
+	}
+
+	var status string
+	var accepted bool
+	if protocol.IsOCPP2() {
+		status, accepted, err = h.setBasicAuthPassword(chargePointId, password)
+	} else {
+		status, accepted, err = h.changeAuthorizationKey(chargePointId, password)
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := &PasswordChange{ChargePointId: chargePointId, Status: status}
+	if accepted {
+		if err = h.database.UpdateChargePointPassword(chargePointId, string(hash)); err != nil {
+			// the charge point already uses the new password and will fail to reconnect; say so loudly
+			h.logger.Error(fmt.Sprintf("store password of %s", chargePointId), err)
+			return nil, fmt.Errorf("password accepted by the charge point but not stored: %v", err)
+		}
+		h.mux.Lock()
+		state.model.PasswordHash = string(hash)
+		h.mux.Unlock()
+		result.Stored = true
+	}
+	h.logger.FeatureEvent(ChangePasswordFeatureName, chargePointId, fmt.Sprintf("password change %s", status))
+	return result, nil
+}
+
+func (h *SystemHandler) changeAuthorizationKey(chargePointId, password string) (string, bool, error) {
+	request := &core.ChangeConfigurationRequest{Key: authorizationKey, Value: password}
+	var response core.ChangeConfigurationResponse
+	if err := h.sendPasswordRequest(chargePointId, request, &response); err != nil {
+		return "", false, err
+	}
+	accepted := response.Status == core.ConfigurationStatusAccepted || response.Status == core.ConfigurationStatusRebootRequired
+	return string(response.Status), accepted, nil
+}
+
+func (h *SystemHandler) setBasicAuthPassword(chargePointId, password string) (string, bool, error) {
+	request := &provisioning.SetVariablesRequest{
+		SetVariableData: []provisioning.SetVariableDataType{{
+			AttributeValue: password,
+			Component:      v201.Component{Name: "SecurityCtrlr"},
+			Variable:       v201.Variable{Name: "BasicAuthPassword"},
+		}},
+	}
+	var response provisioning.SetVariablesResponse
+	if err := h.sendPasswordRequest(chargePointId, request, &response); err != nil {
+		return "", false, err
+	}
+	if len(response.SetVariableResult) == 0 {
+		return "", false, fmt.Errorf("empty SetVariables result")
+	}
+	status := response.SetVariableResult[0].AttributeStatus
+	accepted := status == provisioning.SetVariableStatusAccepted || status == provisioning.SetVariableStatusRebootRequired
+	return string(status), accepted, nil
+}
+
+func (h *SystemHandler) sendPasswordRequest(chargePointId string, request ocpp.Request, response interface{}) error {
+	payload, err := h.server.SendRequestSync(chargePointId, request, passwordChangeTimeout)
+	if err != nil {
+		return fmt.Errorf("send %s: %v", request.GetFeatureName(), err)
+	}
+	if err = json.Unmarshal([]byte(payload), response); err != nil {
+		return fmt.Errorf("invalid %s response: %s", request.GetFeatureName(), payload)
+	}
+	return nil
+}
+
+// newPassword makes a password of the maximal length from 20 random bytes
+func newPassword() (string, error) {
+	b := make([]byte, maxPasswordLength/2)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate password: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,335 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"evsys/entity"
+	"evsys/internal"
+	"evsys/internal/config"
+	"evsys/ocpp"
+	"evsys/ocpp/common"
+	"evsys/ocpp/v16/core"
+	"evsys/ocpp/v16/security"
+	"evsys/ocpp/v201/provisioning"
+	"evsys/pki"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testPassword = "0123456789abcdef0123"
+
+type passwordDB struct {
+	internal.Database
+	stored map[string]string
+}
+
+func (s *passwordDB) GetChargePoint(_ string) (*entity.ChargePoint, error) {
+	return nil, fmt.Errorf("not found")
+}
+
+func (s *passwordDB) UpdateChargePointPassword(chargePointId string, passwordHash string) error {
+	s.stored[chargePointId] = passwordHash
+	return nil
+}
+
+// passwordCharger answers a password change with status, and keeps the password it was sent.
+type passwordCharger struct {
+	status   string
+	password string
+}
+
+func (c *passwordCharger) SendRequest(_ string, _ ocpp.Request) (string, error) {
+	return "", nil
+}
+
+func (c *passwordCharger) SendRequestSync(_ string, request ocpp.Request, _ time.Duration) (string, error) {
+	var response interface{}
+	switch r := request.(type) {
+	case *core.ChangeConfigurationRequest:
+		if r.Key != authorizationKey {
+			return "", fmt.Errorf("unexpected key %s", r.Key)
+		}
+		c.password = r.Value
+		response = core.ChangeConfigurationResponse{Status: core.ConfigurationStatus(c.status)}
+	case *provisioning.SetVariablesRequest:
+		data := r.SetVariableData[0]
+		c.password = data.AttributeValue
+		response = provisioning.SetVariablesResponse{SetVariableResult: []provisioning.SetVariableResultType{{
+			AttributeStatus: provisioning.SetVariableStatusType(c.status),
+			Component:       data.Component,
+			Variable:        data.Variable,
+		}}}
+	default:
+		return "", fmt.Errorf("unexpected request %T", request)
+	}
+	data, err := json.Marshal(response)
+	return string(data), err
+}
+
+func newPasswordHandler(t *testing.T, charger *passwordCharger) (*SystemHandler, *passwordDB) {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := &passwordDB{stored: map[string]string{}}
+	h := &SystemHandler{
+		chargePoints: map[string]*ChargePointState{},
+		database:     db,
+		server:       charger,
+		logger:       stopStubLogger{},
+		location:     time.UTC,
+	}
+	h.chargePoints["CP1"] = newChargePointState(&entity.ChargePoint{Id: "CP1", PasswordHash: string(hash)})
+	h.chargePoints["CP2"] = newChargePointState(&entity.ChargePoint{Id: "CP2"})
+	return h, db
+}
+
+func TestAuthenticateChargePoint(t *testing.T) {
+	h, _ := newPasswordHandler(t, nil)
+	tests := []struct {
+		name                   string
+		id, username, password string
+		want                   bool
+	}{
+		{"valid", "CP1", "CP1", testPassword, true},
+		{"wrong password", "CP1", "CP1", "fedcba9876543210fedc", false},
+		{"other user name", "CP1", "CP2", testPassword, false},
+		{"no credentials", "CP1", "", "", false},
+		{"no password set", "CP2", "CP2", testPassword, false},
+		{"unknown charge point", "CP9", "CP9", testPassword, false},
+	}
+	for _, tt := range tests {
+		if got := h.AuthenticateChargePoint(tt.id, tt.username, tt.password); got != tt.want {
+			t.Errorf("%s: authenticated = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// While charge points are being given passwords, the ones without still connect, and only those.
+func TestAuthenticateChargePointWithoutPassword(t *testing.T) {
+	h, _ := newPasswordHandler(t, nil)
+	h.allowUnsetPassword = true
+	tests := []struct {
+		name                   string
+		id, username, password string
+		want                   bool
+	}{
+		{"no password set", "CP2", "", "", true},
+		{"password set, no credentials", "CP1", "", "", false},
+		{"password set, wrong password", "CP1", "CP1", "fedcba9876543210fedc", false},
+		{"password set, valid", "CP1", "CP1", testPassword, true},
+		{"unknown charge point", "CP9", "", "", false},
+	}
+	for _, tt := range tests {
+		if got := h.AuthenticateChargePoint(tt.id, tt.username, tt.password); got != tt.want {
+			t.Errorf("%s: authenticated = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// Authentication runs for any client, so an id it does not know is refused even where unknown
+// charge points are accepted on boot, and is never registered.
+func TestAuthenticationDoesNotRegisterUnknownIds(t *testing.T) {
+	h, _ := newPasswordHandler(t, nil)
+	h.acceptPoints = true
+
+	if h.AuthenticateChargePoint("CP9", "CP9", testPassword) {
+		t.Error("unknown charge point authenticated")
+	}
+	if _, ok := h.chargePoints["CP9"]; ok {
+		t.Error("unknown charge point was registered")
+	}
+}
+
+func TestUnauthenticatedConnectionIsRefusedBeforeUpgrade(t *testing.T) {
+	h, _ := newPasswordHandler(t, nil)
+	s := NewServer(&config.Config{}, stopStubLogger{})
+	defer s.pool.Stop()
+	s.SetAuthenticator(h)
+
+	request := httptest.NewRequest(http.MethodGet, "/ws/CP1", nil)
+	request.SetBasicAuth("CP1", "fedcba9876543210fedc")
+	recorder := httptest.NewRecorder()
+	s.httpServer.Handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusUnauthorized || recorder.Header().Get("WWW-Authenticate") == "" {
+		t.Errorf("code = %d, want 401 with a Basic challenge", recorder.Code)
+	}
+
+	// good credentials get as far as the upgrade, which a plain request fails
+	request = httptest.NewRequest(http.MethodGet, "/ws/CP1", nil)
+	request.SetBasicAuth("CP1", testPassword)
+	recorder = httptest.NewRecorder()
+	s.httpServer.Handler.ServeHTTP(recorder, request)
+	if recorder.Code == http.StatusUnauthorized {
+		t.Error("valid credentials refused")
+	}
+}
+
+func TestChangeChargePointPassword(t *testing.T) {
+	charger := &passwordCharger{status: "Accepted"}
+	h, db := newPasswordHandler(t, charger)
+
+	result, err := h.ChangeChargePointPassword("CP1", common.OCPP16, "")
+	if err != nil {
+		t.Fatalf("ChangeChargePointPassword: %v", err)
+	}
+	if !result.Stored || len(charger.password) != maxPasswordLength {
+		t.Fatalf("result %+v with password %q, want a generated password stored", result, charger.password)
+	}
+	if !h.AuthenticateChargePoint("CP1", "CP1", charger.password) || h.AuthenticateChargePoint("CP1", "CP1", testPassword) {
+		t.Error("the new password did not replace the old one")
+	}
+	if db.stored["CP1"] == "" {
+		t.Error("hash not stored in the database")
+	}
+
+	// a refused change leaves the old password in place
+	charger.status = string(provisioning.SetVariableStatusRejected)
+	accepted := charger.password
+	result, err = h.ChangeChargePointPassword("CP1", common.OCPP201, "a-password-of-twenty")
+	if err != nil {
+		t.Fatalf("ChangeChargePointPassword: %v", err)
+	}
+	if result.Stored || result.Status != "Rejected" || !h.AuthenticateChargePoint("CP1", "CP1", accepted) {
+		t.Errorf("result %+v, want the rejected password ignored", result)
+	}
+
+	if _, err = h.ChangeChargePointPassword("CP1", common.OCPP16, "short"); err == nil {
+		t.Error("a short password was sent")
+	}
+}
+
+// newClientCertificate has ca issue a certificate for commonName, ready for a TLS client.
+func newClientCertificate(t *testing.T, ca *pki.LocalCA, commonName string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: commonName}}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain, err := ca.Sign(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})))
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	block, _ := pem.Decode([]byte(chain))
+	return tls.Certificate{Certificate: [][]byte{block.Bytes}, PrivateKey: key}
+}
+
+func TestClientCertificateMustNameChargePoint(t *testing.T) {
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	ca, err := pki.LoadOrCreateLocalCA(caFile, filepath.Join(dir, "ca-key.pem"), "Operator", 24*time.Hour)
+	if err != nil {
+		t.Fatalf("LoadOrCreateLocalCA: %v", err)
+	}
+	tlsConfig, err := clientCertificateConfig(caFile)
+	if err != nil {
+		t.Fatalf("clientCertificateConfig: %v", err)
+	}
+
+	h, _ := newPasswordHandler(t, nil)
+	listener := &alertListener{alerts: make(chan *internal.EventMessage, 1)}
+	h.eventListeners = append(h.eventListeners, listener)
+	s := NewServer(&config.Config{}, stopStubLogger{})
+	defer s.pool.Stop()
+	s.SetCertificateAuthenticator(h)
+
+	ts := httptest.NewUnstartedServer(s.httpServer.Handler)
+	ts.TLS = tlsConfig
+	ts.StartTLS()
+	defer ts.Close()
+	serverCAs := x509.NewCertPool()
+	serverCAs.AddCert(ts.Certificate())
+
+	get := func(commonName string) int {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      serverCAs,
+			Certificates: []tls.Certificate{newClientCertificate(t, ca, commonName)},
+		}}}
+		response, err := client.Get(ts.URL + "/ws/CP1")
+		if err != nil {
+			t.Fatalf("GET as %s: %v", commonName, err)
+		}
+		_ = response.Body.Close()
+		return response.StatusCode
+	}
+	if code := get("CP2"); code != http.StatusForbidden {
+		t.Errorf("certificate of CP2: code = %d, want 403", code)
+	}
+	// the right certificate gets as far as the upgrade, which a plain request fails
+	if code := get("CP1"); code == http.StatusForbidden {
+		t.Error("certificate of CP1 refused")
+	}
+	// the CA signs for a day, well within the expiry warning
+	select {
+	case alert := <-listener.alerts:
+		if alert.ChargePointId != "CP1" {
+			t.Errorf("alert for %s, want CP1", alert.ChargePointId)
+		}
+	case <-time.After(time.Second):
+		t.Error("expiring certificate not alerted")
+	}
+	get("CP1")
+	select {
+	case <-listener.alerts:
+		t.Error("expiring certificate alerted twice in a day")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// A charge point following the whitepaper names its serial number in the CSR; the certificate the
+// local CA signs for it has to get it through mutual TLS.
+func TestCertificateSignedForSerialNumberAuthenticates(t *testing.T) {
+	dir := t.TempDir()
+	ca, err := pki.LoadOrCreateLocalCA(filepath.Join(dir, "ca.pem"), filepath.Join(dir, "ca-key.pem"), "Operator", 24*time.Hour)
+	if err != nil {
+		t.Fatalf("LoadOrCreateLocalCA: %v", err)
+	}
+	charger := &certificateCharger{signed: make(chan string, 1)}
+	h := newSecurityHandler()
+	h.chargePoints["CP2"] = newChargePointState(&entity.ChargePoint{Id: "CP2", SerialNumber: "SN-0002", IsEnabled: true})
+	h.server = charger
+	h.SetCertificateAuthority(ca, "Operator")
+
+	response, _ := h.OnSignCertificate("CP1", &security.SignCertificateRequest{Csr: newCsr(t, "SN-0001", "Operator")})
+	if response.Status != security.GenericStatusAccepted {
+		t.Fatalf("status = %s, want Accepted", response.Status)
+	}
+	var certificate *x509.Certificate
+	select {
+	case chain := <-charger.signed:
+		block, _ := pem.Decode([]byte(chain))
+		if block == nil {
+			t.Fatalf("chain %q holds no certificate", chain)
+		}
+		if certificate, err = x509.ParseCertificate(block.Bytes); err != nil {
+			t.Fatalf("parse signed certificate: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no CertificateSigned sent")
+	}
+
+	if !h.AuthenticateCertificate("CP1", certificate) {
+		t.Error("certificate signed for the serial number of CP1 refused")
+	}
+	if h.AuthenticateCertificate("CP2", certificate) {
+		t.Error("certificate of CP1 accepted for CP2")
+	}
+}
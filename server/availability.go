@@ -0,0 +1,336 @@
+package server
+
+import (
+	"encoding/json"
+	"evsys/entity"
+	"evsys/ocpp"
+	"evsys/ocpp/v16/core"
+	"evsys/ocpp/v201"
+	"evsys/ocpp/v201/availability"
+	"evsys/ocpp/v201/remotecontrol"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// availabilityTimeout bounds each boot-time ChangeAvailability. They are sent one after another on
+// their own goroutine, so a slow charge point delays nothing else.
+const availabilityTimeout = 15 * time.Second
+
+func (h *SystemHandler) OnChangeAvailability(chargePointId string, connectorId int, payload string) (*core.ChangeAvailabilityRequest, error) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	state, ok := h.getChargePoint(chargePointId)
+	if !ok {
+		return nil, fmt.Errorf("charge point not found")
+	}
+	if connectorId < 0 {
+		return nil, fmt.Errorf("invalid connector id")
+	}
+	if connectorId > 0 {
+		if _, ok = state.connectors[connectorId]; !ok {
+			return nil, fmt.Errorf("connector %d not found", connectorId)
+		}
+	}
+	availability := core.AvailabilityType(strings.TrimSpace(payload))
+	if !availability.IsValid() {
+		return nil, fmt.Errorf("availability must be %s or %s", core.AvailabilityTypeOperative, core.AvailabilityTypeInoperative)
+	}
+	request := core.NewChangeAvailabilityRequest(connectorId, availability)
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("connector %d: %s", connectorId, availability))
+	return request, nil
+}
+
+// OnChangeAvailabilityResponse stores the availability once the charge point has taken it. A
+// Scheduled answer is stored too: the charge point switches when the running session ends, and the
+// stored value is what the next boot re-asserts.
+func (h *SystemHandler) OnChangeAvailabilityResponse(chargePointId string, request *core.ChangeAvailabilityRequest, response *core.ChangeAvailabilityResponse) {
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("connector %d: %s %s", request.ConnectorId, request.Type, response.Status))
+	if response.Status != core.AvailabilityStatusAccepted && response.Status != core.AvailabilityStatusScheduled {
+		return
+	}
+	h.setAvailability(chargePointId, request.ConnectorId, request.Type == core.AvailabilityTypeOperative)
+}
+
+func (h *SystemHandler) setAvailability(chargePointId string, connectorId int, isEnabled bool) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	state, ok := h.getChargePoint(chargePointId)
+	if !ok {
+		return
+	}
+	var err error
+	if connectorId == 0 {
+		state.model.IsEnabled = isEnabled
+		if h.database != nil {
+			err = h.database.UpdateChargePointAvailability(chargePointId, isEnabled)
+		}
+	} else {
+		connector := h.getConnector(state, connectorId)
+		connector.IsEnabled = isEnabled
+		if h.database != nil {
+			err = h.database.UpdateConnectorAvailability(chargePointId, connectorId, isEnabled)
+		}
+	}
+	if err != nil {
+		h.logger.Error("update availability", err)
+	}
+}
+
+// availabilityRequests lists what has to be re-asserted on a charge point after a boot. Operative
+// is what a charger comes back as by default, so only the charge point or connectors operations
+// took out of service are sent; a disabled charge point is covered by connector 0 alone.
+// Called with h.mux held.
+func availabilityRequests(state *ChargePointState) []*core.ChangeAvailabilityRequest {
+	requests := make([]*core.ChangeAvailabilityRequest, 0)
+	if !state.model.IsEnabled {
+		return append(requests, core.NewChangeAvailabilityRequest(0, core.AvailabilityTypeInoperative))
+	}
+	ids := make([]int, 0, len(state.connectors))
+	for id, connector := range state.connectors {
+		if id > 0 && !connector.IsEnabled {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		requests = append(requests, core.NewChangeAvailabilityRequest(id, core.AvailabilityTypeInoperative))
+	}
+	return requests
+}
+
+// enforceAvailability sends the availability operations chose to a charge point that has just
+// booted. A charger replaced, reflashed or reset to factory settings forgets it, and would come
+// back offering connectors that were taken out of service.
+func (h *SystemHandler) enforceAvailability(chargePointId string, requests []*core.ChangeAvailabilityRequest) {
+	if h.server == nil {
+		return
+	}
+	for _, request := range requests {
+		payload, err := h.server.SendRequestSync(chargePointId, request, availabilityTimeout)
+		if err != nil {
+			h.logger.Error(fmt.Sprintf("change availability of %s@%d", chargePointId, request.ConnectorId), err)
+			continue
+		}
+		var response core.ChangeAvailabilityResponse
+		if err = json.Unmarshal([]byte(payload), &response); err != nil {
+			h.logger.Error(fmt.Sprintf("parse change availability of %s@%d", chargePointId, request.ConnectorId), err)
+			continue
+		}
+		h.logger.FeatureEvent(request.GetFeatureName(), chargePointId,
+			fmt.Sprintf("re-asserted connector %d: %s %s", request.ConnectorId, request.Type, response.Status))
+	}
+}
+
+func (h *SystemHandler) OnUnlockConnector(chargePointId string, connectorId int) (*core.UnlockConnectorRequest, error) {
+	_, ok := h.getChargePoint(chargePointId)
+	if !ok {
+		return nil, fmt.Errorf("charge point not found")
+	}
+	if connectorId <= 0 {
+		return nil, fmt.Errorf("connector id must be greater than 0")
+	}
+	request := core.NewUnlockConnectorRequest(connectorId)
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("unlock connector %d", connectorId))
+	return request, nil
+}
+
+// ============================================================================
+// OCPP 2.0.1
+// ============================================================================
+
+// changeAvailabilityQuery201 is the object form of a 2.0.1 ChangeAvailability payload, for a single
+// connector of a multi-connector EVSE
+type changeAvailabilityQuery201 struct {
+	OperationalStatus string `json:"operationalStatus"`
+	ConnectorId       *int   `json:"connectorId,omitempty"`
+}
+
+// OnChangeAvailability creates a ChangeAvailability request for OCPP 2.0.1; evseId 0 changes the
+// whole charging station. The payload is Operative or Inoperative, as with 1.6, or an object that
+// also names the connector within the EVSE.
+func (h *V201Handlers) OnChangeAvailability(chargePointId string, evseId int, payload string) (ocpp.Request, error) {
+	query := changeAvailabilityQuery201{OperationalStatus: strings.TrimSpace(payload)}
+	if strings.HasPrefix(query.OperationalStatus, "{") {
+		query = changeAvailabilityQuery201{}
+		if err := json.Unmarshal([]byte(payload), &query); err != nil {
+			return nil, fmt.Errorf("invalid payload")
+		}
+	}
+	if evseId < 0 {
+		return nil, fmt.Errorf("invalid EVSE id")
+	}
+	request := &availability.ChangeAvailabilityRequest{
+		OperationalStatus: availability.OperationalStatusType(query.OperationalStatus),
+	}
+	if evseId > 0 {
+		request.Evse = &v201.EVSE{Id: evseId, ConnectorId: query.ConnectorId}
+	} else if query.ConnectorId != nil {
+		return nil, fmt.Errorf("connector id needs an EVSE id")
+	}
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	if err := h.systemHandler.checkEvse(chargePointId, request.Evse); err != nil {
+		return nil, err
+	}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: %s: %s", evseTarget(request.Evse), request.OperationalStatus))
+	return request, nil
+}
+
+// onChangeAvailabilityResponse stores the availability the charging station has taken, or will take
+// once its running transactions end
+func (h *V201Handlers) onChangeAvailabilityResponse(chargePointId string, request *availability.ChangeAvailabilityRequest, payload []byte) error {
+	var response availability.ChangeAvailabilityResponse
+	if err := json.Unmarshal(payload, &response); err != nil {
+		return fmt.Errorf("invalid %s response", request.GetFeatureName())
+	}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: %s: %s %s",
+		evseTarget(request.Evse), request.OperationalStatus, response.Status))
+	if response.Status != availability.ChangeAvailabilityStatusAccepted && response.Status != availability.ChangeAvailabilityStatusScheduled {
+		return nil
+	}
+	isEnabled := request.OperationalStatus == availability.OperationalStatusOperative
+	for _, connectorId := range h.systemHandler.availabilityTargets(chargePointId, request.Evse) {
+		h.systemHandler.setAvailability(chargePointId, connectorId, isEnabled)
+	}
+	return nil
+}
+
+// OnUnlockConnector creates an UnlockConnector request for OCPP 2.0.1; the payload is the connector
+// id within the EVSE, 1 when empty
+func (h *V201Handlers) OnUnlockConnector(chargePointId string, evseId int, payload string) (ocpp.Request, error) {
+	connectorId := 1
+	if payload = strings.TrimSpace(payload); payload != "" {
+		id, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("invalid connector id")
+		}
+		connectorId = id
+	}
+	request := &remotecontrol.UnlockConnectorRequest{EvseId: evseId, ConnectorId: connectorId}
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	if err := h.systemHandler.checkEvse(chargePointId, &v201.EVSE{Id: evseId, ConnectorId: &connectorId}); err != nil {
+		return nil, err
+	}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: unlock EVSE %d connector %d", evseId, connectorId))
+	return request, nil
+}
+
+// checkEvse makes sure the charge point is known and has the EVSE and connector a request targets;
+// a nil EVSE targets the whole charge point
+func (h *SystemHandler) checkEvse(chargePointId string, evse *v201.EVSE) error {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	state, ok := h.getChargePoint(chargePointId)
+	if !ok {
+		return fmt.Errorf("charge point not found")
+	}
+	if evse == nil {
+		return nil
+	}
+	connectors := evseConnectors(state, evse.Id)
+	if len(connectors) == 0 {
+		return fmt.Errorf("EVSE %d not found", evse.Id)
+	}
+	if evse.ConnectorId == nil {
+		return nil
+	}
+	for _, connector := range connectors {
+		if connector.Id == *evse.ConnectorId {
+			return nil
+		}
+	}
+	return fmt.Errorf("connector %d not found on EVSE %d", *evse.ConnectorId, evse.Id)
+}
+
+// availabilityTargets lists the stored connectors a ChangeAvailability applies to; connector 0
+// stands for the whole charge point
+func (h *SystemHandler) availabilityTargets(chargePointId string, evse *v201.EVSE) []int {
+	if evse == nil {
+		return []int{0}
+	}
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	state, ok := h.getChargePoint(chargePointId)
+	if !ok {
+		return nil
+	}
+	ids := make([]int, 0)
+	for _, connector := range evseConnectors(state, evse.Id) {
+		if evse.ConnectorId == nil || *evse.ConnectorId == connector.Id {
+			ids = append(ids, connector.Id)
+		}
+	}
+	return ids
+}
+
+// availabilityRequests201 is availabilityRequests for a 2.0.1 charging station; a connector whose
+// EVSE is not known yet is taken out of service with its whole EVSE.
+// Called with h.mux held.
+func availabilityRequests201(state *ChargePointState) []*availability.ChangeAvailabilityRequest {
+	requests := make([]*availability.ChangeAvailabilityRequest, 0)
+	if !state.model.IsEnabled {
+		return append(requests, &availability.ChangeAvailabilityRequest{OperationalStatus: availability.OperationalStatusInoperative})
+	}
+	connectors := make([]*entity.Connector, 0, len(state.connectors))
+	for id, connector := range state.connectors {
+		if id > 0 && !connector.IsEnabled {
+			connectors = append(connectors, connector)
+		}
+	}
+	sort.Slice(connectors, func(i, j int) bool { return connectors[i].Id < connectors[j].Id })
+	for _, connector := range connectors {
+		evse := &v201.EVSE{Id: connector.Id}
+		if connector.EvseId != nil {
+			connectorId := connector.Id
+			evse = &v201.EVSE{Id: *connector.EvseId, ConnectorId: &connectorId}
+		}
+		requests = append(requests, &availability.ChangeAvailabilityRequest{
+			OperationalStatus: availability.OperationalStatusInoperative,
+			Evse:              evse,
+		})
+	}
+	return requests
+}
+
+// enforceAvailability201 sends the availability operations chose to a 2.0.1 charging station that
+// has just booted
+func (h *SystemHandler) enforceAvailability201(chargePointId string, requests []*availability.ChangeAvailabilityRequest) {
+	if h.server == nil {
+		return
+	}
+	for _, request := range requests {
+		payload, err := h.server.SendRequestSync(chargePointId, request, availabilityTimeout)
+		if err != nil {
+			h.logger.Error(fmt.Sprintf("change availability of %s, %s", chargePointId, evseTarget(request.Evse)), err)
+			continue
+		}
+		var response availability.ChangeAvailabilityResponse
+		if err = json.Unmarshal([]byte(payload), &response); err != nil {
+			h.logger.Error(fmt.Sprintf("parse change availability of %s, %s", chargePointId, evseTarget(request.Evse)), err)
+			continue
+		}
+		h.logger.FeatureEvent(request.GetFeatureName(), chargePointId,
+			fmt.Sprintf("v2.0.1: re-asserted %s: %s %s", evseTarget(request.Evse), request.OperationalStatus, response.Status))
+	}
+}
+
+// evseTarget describes what a 2.0.1 request targets, for the log
+func evseTarget(evse *v201.EVSE) string {
+	switch {
+	case evse == nil:
+		return "charging station"
+	case evse.ConnectorId != nil:
+		return fmt.Sprintf("EVSE %d connector %d", evse.Id, *evse.ConnectorId)
+	}
+	return fmt.Sprintf("EVSE %d", evse.Id)
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"evsys/entity"
+	"evsys/internal"
+	"evsys/ocpp/v16/core"
+	"evsys/ocpp/v201/availability"
+)
+
+type availabilityStubDB struct {
+	internal.Database
+	chargePoint map[string]bool
+	connector   map[int]bool
+}
+
+func (s *availabilityStubDB) UpdateChargePointAvailability(_ string, isEnabled bool) error {
+	s.chargePoint["CP1"] = isEnabled
+	return nil
+}
+
+func (s *availabilityStubDB) UpdateConnectorAvailability(_ string, connectorId int, isEnabled bool) error {
+	s.connector[connectorId] = isEnabled
+	return nil
+}
+
+func newAvailabilityHandler(db internal.Database) *SystemHandler {
+	h := &SystemHandler{
+		chargePoints: map[string]*ChargePointState{},
+		database:     db,
+		logger:       stopStubLogger{},
+		location:     time.UTC,
+	}
+	state := newChargePointState(&entity.ChargePoint{Id: "CP1", IsEnabled: true})
+	state.connectors[1] = entity.NewConnector(1, "CP1")
+	state.connectors[2] = entity.NewConnector(2, "CP1")
+	h.chargePoints["CP1"] = state
+	return h
+}
+
+func TestChangeAvailabilityStoredOnlyWhenTaken(t *testing.T) {
+	tests := []struct {
+		status core.AvailabilityStatus
+		stored bool
+	}{
+		{core.AvailabilityStatusAccepted, true},
+		{core.AvailabilityStatusScheduled, true},
+		{core.AvailabilityStatusRejected, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			db := &availabilityStubDB{chargePoint: map[string]bool{}, connector: map[int]bool{}}
+			h := newAvailabilityHandler(db)
+
+			request, err := h.OnChangeAvailability("CP1", 2, "Inoperative")
+			if err != nil {
+				t.Fatalf("OnChangeAvailability: %v", err)
+			}
+			h.OnChangeAvailabilityResponse("CP1", request, core.NewChangeAvailabilityResponse(tt.status))
+
+			enabled, written := db.connector[2]
+			if written != tt.stored {
+				t.Fatalf("stored = %v, want %v", written, tt.stored)
+			}
+			if tt.stored && enabled {
+				t.Errorf("connector stored as enabled after Inoperative")
+			}
+			if h.chargePoints["CP1"].connectors[2].IsEnabled == tt.stored {
+				t.Errorf("in-memory connector enabled = %v after %s", !tt.stored, tt.status)
+			}
+		})
+	}
+}
+
+func TestChangeAvailabilityRejectsUnknownType(t *testing.T) {
+	h := newAvailabilityHandler(nil)
+	if _, err := h.OnChangeAvailability("CP1", 0, "Sleeping"); err == nil {
+		t.Error("expected an error for an unknown availability type")
+	}
+}
+
+func TestAvailabilityRequestsAfterBoot(t *testing.T) {
+	h := newAvailabilityHandler(nil)
+	state := h.chargePoints["CP1"]
+
+	if requests := availabilityRequests(state); len(requests) != 0 {
+		t.Fatalf("nothing to re-assert on an operative charge point, got %d requests", len(requests))
+	}
+
+	state.connectors[2].IsEnabled = false
+	requests := availabilityRequests(state)
+	if len(requests) != 1 || requests[0].ConnectorId != 2 || requests[0].Type != core.AvailabilityTypeInoperative {
+		t.Fatalf("expected connector 2 Inoperative, got %+v", requests)
+	}
+
+	// a disabled charge point is covered by connector 0 alone
+	state.model.IsEnabled = false
+	requests = availabilityRequests(state)
+	if len(requests) != 1 || requests[0].ConnectorId != 0 {
+		t.Fatalf("expected a single connector 0 request, got %+v", requests)
+	}
+}
+
+func TestChangeAvailability201TargetsEvse(t *testing.T) {
+	db := &availabilityStubDB{chargePoint: map[string]bool{}, connector: map[int]bool{}}
+	h := newAvailabilityHandler(db)
+	evseId := 2
+	h.chargePoints["CP1"].connectors[2].EvseId = &evseId
+	handlers := NewV201Handlers(h, stopStubLogger{})
+
+	if _, err := handlers.OnChangeAvailability("CP1", 3, "Inoperative"); err == nil {
+		t.Error("expected an error for an unknown EVSE")
+	}
+	if _, err := handlers.OnChangeAvailability("CP1", 2, `{"operationalStatus":"Inoperative","connectorId":5}`); err == nil {
+		t.Error("expected an error for an unknown connector")
+	}
+	if _, err := handlers.OnChangeAvailability("CP1", 0, `{"operationalStatus":"Inoperative","connectorId":1}`); err == nil {
+		t.Error("expected an error for a connector without its EVSE")
+	}
+
+	request, err := handlers.OnChangeAvailability("CP1", 2, "Inoperative")
+	if err != nil {
+		t.Fatalf("OnChangeAvailability: %v", err)
+	}
+	if err = handlers.HandleResponse("CP1", request, []byte(`{"status":"Rejected"}`)); err != nil {
+		t.Fatalf("HandleResponse: %v", err)
+	}
+	if len(db.connector) != 0 {
+		t.Fatalf("a rejected change was stored: %v", db.connector)
+	}
+	if err = handlers.HandleResponse("CP1", request, []byte(`{"status":"Scheduled"}`)); err != nil {
+		t.Fatalf("HandleResponse: %v", err)
+	}
+	if enabled, ok := db.connector[2]; !ok || enabled {
+		t.Errorf("connector 2 stored = %v, %v; want disabled", enabled, ok)
+	}
+	if _, ok := db.connector[1]; ok {
+		t.Error("connector 1 was changed by a request for EVSE 2")
+	}
+
+	// no EVSE changes the whole charging station
+	request, _ = handlers.OnChangeAvailability("CP1", 0, "Inoperative")
+	_ = handlers.HandleResponse("CP1", request, []byte(`{"status":"Accepted"}`))
+	if enabled, ok := db.chargePoint["CP1"]; !ok || enabled {
+		t.Errorf("charging station stored = %v, %v; want disabled", enabled, ok)
+	}
+}
+
+func TestAvailabilityRequestsAfterBoot201(t *testing.T) {
+	h := newAvailabilityHandler(nil)
+	state := h.chargePoints["CP1"]
+	evseId := 2
+	state.connectors[2].EvseId = &evseId
+	state.connectors[2].IsEnabled = false
+
+	requests := availabilityRequests201(state)
+	if len(requests) != 1 || requests[0].Evse == nil || requests[0].Evse.Id != 2 ||
+		requests[0].OperationalStatus != availability.OperationalStatusInoperative {
+		t.Fatalf("expected EVSE 2 Inoperative, got %+v", requests)
+	}
+
+	state.model.IsEnabled = false
+	requests = availabilityRequests201(state)
+	if len(requests) != 1 || requests[0].Evse != nil {
+		t.Fatalf("expected a single charging station request, got %+v", requests)
+	}
+}
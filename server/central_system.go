@@ -2,8 +2,14 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"evsys/billing"
+	"evsys/campaign"
+	"evsys/customerdata"
+	"evsys/datatransfer"
+	"evsys/devicemodel"
+	"evsys/display"
 	"evsys/internal"
 	"evsys/internal/config"
 	"evsys/internal/errorlistener"
@@ -12,14 +18,29 @@ import (
 	"evsys/ocpp/common"
 	"evsys/ocpp/v16"
 	"evsys/ocpp/v16/core"
-	"evsys/ocpp/v16/firmware"
 	"evsys/ocpp/v16/localauth"
-	"evsys/ocpp/v16/remotetrigger"
-	"evsys/ocpp/v16/smartcharging"
+	"evsys/ocpp/v201"
 	"evsys/ocpp/v201/authorization"
 	"evsys/ocpp/v201/availability"
+	"evsys/ocpp/v201/diagnostics"
+	"evsys/ocpp/v201/displaymessage"
+	"evsys/ocpp/v201/firmware"
+	"evsys/ocpp/v201/handlers"
+	"evsys/ocpp/v201/iso15118"
+	localauth201 "evsys/ocpp/v201/localauth"
+	"evsys/ocpp/v201/metervalues"
+	"evsys/ocpp/v201/monitoring"
 	"evsys/ocpp/v201/provisioning"
+	"evsys/ocpp/v201/remotecontrol"
+	"evsys/ocpp/v201/reservation"
+	"evsys/ocpp/v201/security"
+	"evsys/ocpp/v201/smartcharging"
 	"evsys/ocpp/v201/transactions"
+	handlers21 "evsys/ocpp/v21/handlers"
+	smartcharging21 "evsys/ocpp/v21/smartcharging"
+	"evsys/ocpp/v21/tariff"
+	transactions21 "evsys/ocpp/v21/transactions"
+	"evsys/pki"
 	"evsys/power"
 	"evsys/telegram"
 	"evsys/types"
@@ -29,6 +50,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -43,11 +65,18 @@ type CentralSystem struct {
 	api               *Api
 	logger            internal.LogHandler
 	coreHandler       *SystemHandler
-	firmwareHandler   firmware.SystemHandler
-	remoteTrigger     remotetrigger.SystemHandler
 	localAuth         localauth.SystemHandler
-	v201Handlers      *V201Handlers // OCPP 2.0.1 business logic handlers
+	v16Handler        *v16.Handler16       // OCPP 1.6 feature registration and dispatch
+	v201Handlers      *V201Handlers        // OCPP 2.0.1 business logic handlers
+	v201Handler       *handlers.Handler201 // OCPP 2.0.1 feature registration
+	v21Handlers       *V21Handlers         // OCPP 2.1 business logic handlers, on top of the 2.0.1 ones
+	v21Handler        *handlers21.Handler21
 	powerManager      PowerManager
+	firmwareCampaigns *campaign.Manager
+	displayMessages   *display.Manager
+	deviceModel       *devicemodel.Manager
+	customerData      *customerdata.Manager
+	reservations      *ReservationService
 	location          *time.Location
 	supportedProtocol []string
 	connections       sync.Map               // chargePointId → common.ProtocolVersion
@@ -68,16 +97,21 @@ func (cs *CentralSystem) SetCoreHandler(handler *SystemHandler) {
 	cs.coreHandler = handler
 }
 
+// RegisterDataTransfer adds a handler for vendor DataTransfer messages from charge points.
+func (cs *CentralSystem) RegisterDataTransfer(vendorId, messageId string, handler datatransfer.HandlerFunc) {
+	cs.coreHandler.RegisterDataTransfer(vendorId, messageId, handler)
+}
+
 func (cs *CentralSystem) SetV201Handlers(handlers *V201Handlers) {
 	cs.v201Handlers = handlers
 }
 
-func (cs *CentralSystem) SetFirmwareHandler(handler firmware.SystemHandler) {
-	cs.firmwareHandler = handler
+func (cs *CentralSystem) SetV21Handlers(handlers *V21Handlers) {
+	cs.v21Handlers = handlers
 }
 
-func (cs *CentralSystem) SetRemoteTriggerHandler(handler remotetrigger.SystemHandler) {
-	cs.remoteTrigger = handler
+func (cs *CentralSystem) SetV16Handler(handler *v16.Handler16) {
+	cs.v16Handler = handler
 }
 
 func (cs *CentralSystem) SetLocalAuthHandler(handler localauth.SystemHandler) {
@@ -117,6 +151,12 @@ func (cs *CentralSystem) handleIncomingMessage(ws ocpp.WebSocket, data []byte) e
 	}
 	if callType == CallTypeError {
 		cs.logger.Warn(fmt.Sprintf("error message received from charge point %s: %s", chargePointId, string(data)))
+		callError, err := ParseCallError(message)
+		if err != nil {
+			cs.logger.Warn(fmt.Sprintf("invalid error message received from charge point %s: %s", chargePointId, err))
+			return nil
+		}
+		cs.server.ResolveError(callError.UniqueId, callError.Err())
 		return nil
 	}
 	if callType == CallTypeResult {
@@ -136,61 +176,8 @@ func (cs *CentralSystem) handleIncomingMessage(ws ocpp.WebSocket, data []byte) e
 		return cs.handleIncomingMessageVersionAware(ws, message, protocol, chargePointId)
 	}
 
-	// Legacy routing (backward compatibility)
-	callRequest, err := ParseRequest(message)
-	if err != nil {
-		return err
-	}
-	ws.SetUniqueId(callRequest.UniqueId)
-
-	request := callRequest.Payload
-	action := request.GetFeatureName()
-	var confirmation ocpp.Response
-	switch action {
-	case core.BootNotificationFeatureName:
-		confirmation, err = cs.coreHandler.OnBootNotification(chargePointId, request.(*core.BootNotificationRequest))
-	case core.AuthorizeFeatureName:
-		confirmation, err = cs.coreHandler.OnAuthorize(chargePointId, request.(*core.AuthorizeRequest))
-	case core.HeartbeatFeatureName:
-		confirmation, err = cs.coreHandler.OnHeartbeat(chargePointId, request.(*core.HeartbeatRequest))
-	case core.StartTransactionFeatureName:
-		confirmation, err = cs.coreHandler.OnStartTransaction(chargePointId, request.(*core.StartTransactionRequest))
-	case core.StopTransactionFeatureName:
-		confirmation, err = cs.coreHandler.OnStopTransaction(chargePointId, request.(*core.StopTransactionRequest))
-	case core.MeterValuesFeatureName:
-		confirmation, err = cs.coreHandler.OnMeterValues(chargePointId, request.(*core.MeterValuesRequest))
-	case core.StatusNotificationFeatureName:
-		confirmation, err = cs.coreHandler.OnStatusNotification(chargePointId, request.(*core.StatusNotificationRequest))
-	case core.DataTransferFeatureName:
-		confirmation, err = cs.coreHandler.OnDataTransfer(chargePointId, request.(*core.DataTransferRequest))
-	case firmware.DiagnosticsStatusNotificationFeatureName:
-		confirmation, err = cs.firmwareHandler.OnDiagnosticsStatusNotification(chargePointId, request.(*firmware.DiagnosticsStatusNotificationRequest))
-	case firmware.StatusNotificationFeatureName:
-		confirmation, err = cs.firmwareHandler.OnFirmwareStatusNotification(chargePointId, request.(*firmware.StatusNotificationRequest))
-	default:
-		err = fmt.Errorf("feature not supported: %s", action)
-	}
-	if err != nil {
-		return err
-	}
-
-	if ws.IsClosed() {
-		cs.logger.FeatureEvent(action, chargePointId, "websocket closed, response not sent")
-		return nil
-	}
-	err = cs.server.SendResponse(ws, confirmation)
-
-	// call the power manager to check the power limit
-	switch action {
-	case core.StartTransactionFeatureName:
-		go cs.powerManager.CheckPowerLimit(chargePointId)
-	case core.StopTransactionFeatureName:
-		go cs.powerManager.CheckPowerLimit(chargePointId)
-	case core.BootNotificationFeatureName:
-		cs.powerManager.OnChargePointBoot(chargePointId)
-	}
-
-	return err
+	// Legacy routing (backward compatibility): every charge point is taken for 1.6
+	return cs.handleIncomingMessageVersionAware(ws, message, common.OCPP16, chargePointId)
 }
 
 // handleIncomingMessageVersionAware handles incoming messages using the version-aware registry-based routing
@@ -198,6 +185,7 @@ func (cs *CentralSystem) handleIncomingMessageVersionAware(ws ocpp.WebSocket, me
 	// Parse the call request structure
 	callRequest, err := ParseRequestVersionAware(message, protocol, cs.featureRegistry)
 	if err != nil {
+		cs.sendCallError(ws, message, protocol, err)
 		return fmt.Errorf("failed to parse request: %w", err)
 	}
 	ws.SetUniqueId(callRequest.UniqueId)
@@ -211,17 +199,17 @@ func (cs *CentralSystem) handleIncomingMessageVersionAware(ws ocpp.WebSocket, me
 	var confirmation ocpp.Response
 	switch protocol {
 	case common.OCPP16:
-		confirmation, err = cs.routeOCPP16Request(chargePointId, action, request)
+		confirmation, err = cs.routeOCPP16Request(chargePointId, request)
 	case common.OCPP201:
 		confirmation, err = cs.routeOCPP201Request(chargePointId, action, request)
 	case common.OCPP21:
-		// TODO: Implement OCPP 2.1 routing in Phase 4
-		return fmt.Errorf("OCPP 2.1 not yet implemented")
+		confirmation, err = cs.routeOCPP21Request(chargePointId, action, request)
 	default:
-		return fmt.Errorf("unsupported protocol version: %s", protocol)
+		err = ocpp.NewCallError(ocpp.NotSupported, "unsupported protocol version: %s", protocol)
 	}
 
 	if err != nil {
+		cs.sendCallError(ws, message, protocol, err)
 		return err
 	}
 
@@ -238,39 +226,65 @@ func (cs *CentralSystem) handleIncomingMessageVersionAware(ws ocpp.WebSocket, me
 		go cs.powerManager.CheckPowerLimit(chargePointId)
 	case core.StopTransactionFeatureName:
 		go cs.powerManager.CheckPowerLimit(chargePointId)
+	case transactions.TransactionEventFeatureName:
+		// on 2.x only the start and end of a transaction change the location budget
+		if eventType, ok := transactionEventType(request); ok && eventType != v201.TransactionEventUpdated {
+			go cs.powerManager.CheckPowerLimit(chargePointId)
+		}
+	case smartcharging.NotifyEVChargingNeedsFeatureName, smartcharging21.NotifyPriorityChargingFeatureName:
+		// an EV with a close departure or a driver asking for priority may take a higher slot
+		go cs.powerManager.CheckPowerLimit(chargePointId)
+	case smartcharging.NotifyChargingLimitFeatureName, authorization.ClearedChargingLimitFeatureName:
+		// an external limit caps the sessions it covers, and its end frees them to take a slot again
+		go cs.powerManager.CheckPowerLimit(chargePointId)
 	case core.BootNotificationFeatureName:
 		cs.powerManager.OnChargePointBoot(chargePointId)
+		cs.customerData.OnChargePointBoot(chargePointId)
+		// a reboot may wipe the displays; the station is accepted now, so they can be set again
+		if protocol.IsOCPP2() {
+			go cs.displayMessages.OnChargePointBoot(chargePointId)
+			cs.deviceModel.OnChargePointBoot(chargePointId)
+		}
 	}
 
 	return err
 }
 
-// routeOCPP16Request routes OCPP 1.6J requests to the appropriate handler
-func (cs *CentralSystem) routeOCPP16Request(chargePointId string, action string, request ocpp.Request) (ocpp.Response, error) {
-	switch action {
-	case core.BootNotificationFeatureName:
-		return cs.coreHandler.OnBootNotification(chargePointId, request.(*core.BootNotificationRequest))
-	case core.AuthorizeFeatureName:
-		return cs.coreHandler.OnAuthorize(chargePointId, request.(*core.AuthorizeRequest))
-	case core.HeartbeatFeatureName:
-		return cs.coreHandler.OnHeartbeat(chargePointId, request.(*core.HeartbeatRequest))
-	case core.StartTransactionFeatureName:
-		return cs.coreHandler.OnStartTransaction(chargePointId, request.(*core.StartTransactionRequest))
-	case core.StopTransactionFeatureName:
-		return cs.coreHandler.OnStopTransaction(chargePointId, request.(*core.StopTransactionRequest))
-	case core.MeterValuesFeatureName:
-		return cs.coreHandler.OnMeterValues(chargePointId, request.(*core.MeterValuesRequest))
-	case core.StatusNotificationFeatureName:
-		return cs.coreHandler.OnStatusNotification(chargePointId, request.(*core.StatusNotificationRequest))
-	case core.DataTransferFeatureName:
-		return cs.coreHandler.OnDataTransfer(chargePointId, request.(*core.DataTransferRequest))
-	case firmware.DiagnosticsStatusNotificationFeatureName:
-		return cs.firmwareHandler.OnDiagnosticsStatusNotification(chargePointId, request.(*firmware.DiagnosticsStatusNotificationRequest))
-	case firmware.StatusNotificationFeatureName:
-		return cs.firmwareHandler.OnFirmwareStatusNotification(chargePointId, request.(*firmware.StatusNotificationRequest))
-	default:
-		return nil, fmt.Errorf("feature not supported for OCPP 1.6: %s", action)
+// sendCallError answers a request that could not be handled with the CallError its error carries;
+// any other error is the central system's own, an InternalError. A message without a unique id
+// cannot be answered.
+func (cs *CentralSystem) sendCallError(ws ocpp.WebSocket, message []interface{}, protocol common.ProtocolVersion, err error) {
+	if len(message) < 2 || ws.IsClosed() {
+		return
+	}
+	uniqueId, ok := message[1].(string)
+	if !ok {
+		return
+	}
+	var callError *ocpp.CallError
+	if !errors.As(err, &callError) {
+		callError = ocpp.NewCallError(ocpp.InternalError, "%s", err)
 	}
+	_ = cs.server.SendCallError(ws, uniqueId, CreateCallError(callError, uniqueId, protocol))
+}
+
+// transactionEventType reads the event type of a 2.0.1 or 2.1 TransactionEvent
+func transactionEventType(request ocpp.Request) (v201.TransactionEventType, bool) {
+	switch event := request.(type) {
+	case *transactions.TransactionEventRequest:
+		return event.EventType, true
+	case *transactions21.TransactionEventRequest:
+		return event.EventType, true
+	}
+	return "", false
+}
+
+// routeOCPP16Request routes OCPP 1.6J requests to the handler registered for the action
+func (cs *CentralSystem) routeOCPP16Request(chargePointId string, request ocpp.Request) (ocpp.Response, error) {
+	if cs.v16Handler == nil {
+		return nil, fmt.Errorf("OCPP 1.6 handler not initialized")
+	}
+	return cs.v16Handler.Handle(chargePointId, request)
 }
 
 // routeOCPP201Request routes OCPP 2.0.1 requests to the appropriate handler
@@ -295,8 +309,57 @@ func (cs *CentralSystem) routeOCPP201Request(chargePointId string, action string
 		return cs.v201Handlers.OnTransactionEvent(chargePointId, request.(*transactions.TransactionEventRequest))
 	case "StatusNotification":
 		return cs.v201Handlers.OnStatusNotification(chargePointId, request.(*availability.StatusNotificationRequest))
+	case metervalues.MeterValuesFeatureName:
+		return cs.v201Handlers.OnMeterValues(chargePointId, request.(*metervalues.MeterValuesRequest))
+	case smartcharging.ReportChargingProfilesFeatureName:
+		return cs.v201Handlers.OnReportChargingProfiles(chargePointId, request.(*smartcharging.ReportChargingProfilesRequest))
+	case monitoring.NotifyEventFeatureName:
+		return cs.v201Handlers.OnNotifyEvent(chargePointId, request.(*monitoring.NotifyEventRequest))
+	case monitoring.NotifyMonitoringReportFeatureName:
+		return cs.v201Handlers.OnNotifyMonitoringReport(chargePointId, request.(*monitoring.NotifyMonitoringReportRequest))
+	case firmware.FirmwareStatusNotificationFeatureName:
+		return cs.v201Handlers.OnFirmwareStatusNotification(chargePointId, request.(*firmware.FirmwareStatusNotificationRequest))
+	case diagnostics.LogStatusNotificationFeatureName:
+		return cs.v201Handlers.OnLogStatusNotification(chargePointId, request.(*diagnostics.LogStatusNotificationRequest))
+	case diagnostics.NotifyCustomerInformationFeatureName:
+		return cs.v201Handlers.OnNotifyCustomerInformation(chargePointId, request.(*diagnostics.NotifyCustomerInformationRequest))
+	case smartcharging.NotifyEVChargingNeedsFeatureName:
+		return cs.v201Handlers.OnNotifyEVChargingNeeds(chargePointId, request.(*smartcharging.NotifyEVChargingNeedsRequest))
+	case smartcharging.NotifyEVChargingScheduleFeatureName:
+		return cs.v201Handlers.OnNotifyEVChargingSchedule(chargePointId, request.(*smartcharging.NotifyEVChargingScheduleRequest))
+	case smartcharging.NotifyChargingLimitFeatureName:
+		return cs.v201Handlers.OnNotifyChargingLimit(chargePointId, request.(*smartcharging.NotifyChargingLimitRequest))
+	case displaymessage.NotifyDisplayMessagesFeatureName:
+		return cs.v201Handlers.OnNotifyDisplayMessages(chargePointId, request.(*displaymessage.NotifyDisplayMessagesRequest))
+	case iso15118.Get15118EVCertificateFeatureName:
+		return cs.v201Handlers.OnGet15118EVCertificate(chargePointId, request.(*iso15118.Get15118EVCertificateRequest))
+	case iso15118.GetCertificateStatusFeatureName:
+		return cs.v201Handlers.OnGetCertificateStatus(chargePointId, request.(*iso15118.GetCertificateStatusRequest))
+	case security.SignCertificateFeatureName:
+		return cs.v201Handlers.OnSignCertificate(chargePointId, request.(*security.SignCertificateRequest))
+	case reservation.ReservationStatusUpdateFeatureName:
+		return cs.v201Handlers.OnReservationStatusUpdate(chargePointId, request.(*reservation.ReservationStatusUpdateRequest))
 	default:
-		return nil, fmt.Errorf("feature not supported for OCPP 2.0.1: %s", action)
+		return nil, ocpp.NewCallError(ocpp.NotSupported, "feature not supported for OCPP 2.0.1: %s", action)
+	}
+}
+
+// routeOCPP21Request routes OCPP 2.1 requests: the messages 2.1 extended or added go to the 2.1
+// handlers, the ones it kept as they were to the 2.0.1 handlers
+func (cs *CentralSystem) routeOCPP21Request(chargePointId string, action string, request ocpp.Request) (ocpp.Response, error) {
+	if cs.v21Handlers == nil {
+		return nil, fmt.Errorf("OCPP 2.1 handlers not initialized")
+	}
+
+	switch action {
+	case provisioning.BootNotificationFeatureName:
+		return cs.v21Handlers.OnBootNotification(chargePointId, request.(*provisioning.BootNotificationRequest))
+	case transactions21.TransactionEventFeatureName:
+		return cs.v21Handlers.OnTransactionEvent(chargePointId, request.(*transactions21.TransactionEventRequest))
+	case smartcharging21.NotifyPriorityChargingFeatureName:
+		return cs.v21Handlers.OnNotifyPriorityCharging(chargePointId, request.(*smartcharging21.NotifyPriorityChargingRequest))
+	default:
+		return cs.routeOCPP201Request(chargePointId, action, request)
 	}
 }
 
@@ -316,11 +379,88 @@ func (cs *CentralSystem) handleApiRequest(w http.ResponseWriter, command Central
 		_, err = w.Write(cs.server.GetStatus())
 		return err
 	}
+	switch command.FeatureName {
+	case campaign.StartFeatureName, campaign.GetFeatureName, campaign.CancelFeatureName:
+		data, err := cs.firmwareCampaigns.HandleCommand(command.FeatureName, command.Payload)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	case display.ScheduleFeatureName, display.GetFeatureName, display.CancelFeatureName:
+		data, err := cs.displayMessages.HandleCommand(command.FeatureName, command.Payload)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	case devicemodel.QueryFeatureName, devicemodel.HistoryFeatureName, devicemodel.ReportFeatureName:
+		data, err := cs.deviceModel.HandleCommand(command.FeatureName, command.Payload)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	case customerdata.InformationFeatureName, customerdata.ExportFeatureName, customerdata.EraseFeatureName:
+		data, err := cs.customerData.HandleCommand(command.FeatureName, command.Payload)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+
+	if command.FeatureName == FirmwareProgressFeatureName {
+		progress, err := cs.coreHandler.GetFirmwareProgress(command.ChargePointId)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(progress)
+		if err != nil {
+			return err
+		}
+		w.Header().Add("Content-Type", "application/json; charset=utf-8")
+		_, err = w.Write(data)
+		return err
+	}
+
+	// a password change is only stored once the charge point accepted it
+	if command.FeatureName == ChangePasswordFeatureName {
+		result, err := cs.coreHandler.ChangeChargePointPassword(command.ChargePointId, protocol, command.Payload)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		w.Header().Add("Content-Type", "application/json; charset=utf-8")
+		_, err = w.Write(data)
+		return err
+	}
+
+	// a local list sync is a conversation of its own rather than a single forwarded request
+	if command.FeatureName == localauth.SendLocalListFeatureName {
+		full := strings.EqualFold(strings.TrimSpace(command.Payload), string(localauth.UpdateTypeFull))
+		result, err := cs.localAuth.SyncLocalList(command.ChargePointId, full)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		w.Header().Add("Content-Type", "application/json; charset=utf-8")
+		_, err = w.Write(data)
+		return err
+	}
 
 	// Route based on protocol version
 	switch protocol {
 	case common.OCPP201:
 		request, err = cs.handleApiRequestV201(command)
+	case common.OCPP21:
+		request, err = cs.handleApiRequestV21(command)
 	default:
 		// Default to OCPP 1.6 (backward compatibility)
 		request, err = cs.handleApiRequestV16(command)
@@ -336,6 +476,10 @@ func (cs *CentralSystem) handleApiRequest(w http.ResponseWriter, command Central
 		w.WriteHeader(http.StatusNoContent)
 		return nil
 	}
+	var callError *ocpp.CallError
+	if errors.As(err, &callError) {
+		cs.logger.Warn(fmt.Sprintf("%s refused %s: %s", command.ChargePointId, command.FeatureName, callError))
+	}
 	if err != nil {
 		return err
 	}
@@ -343,6 +487,7 @@ func (cs *CentralSystem) handleApiRequest(w http.ResponseWriter, command Central
 		w.WriteHeader(http.StatusNoContent)
 		return nil
 	}
+	cs.handleApiResponse(command.ChargePointId, protocol, request, payload)
 	w.Header().Add("Content-Type", "application/json; charset=utf-8")
 	if _, err = w.Write([]byte(payload)); err != nil {
 		cs.logger.Error("cs command send response", err)
@@ -351,6 +496,21 @@ func (cs *CentralSystem) handleApiRequest(w http.ResponseWriter, command Central
 	return nil
 }
 
+// handleApiResponse passes the charge point's answer to a forwarded command back to the handler
+// that built it, for commands whose outcome has to be recorded on the central system side.
+func (cs *CentralSystem) handleApiResponse(chargePointId string, protocol common.ProtocolVersion, request ocpp.Request, payload string) {
+	var err error
+	switch {
+	case protocol.IsOCPP2() && cs.v201Handlers != nil:
+		err = cs.v201Handlers.HandleResponse(chargePointId, request, []byte(payload))
+	case !protocol.IsOCPP2() && cs.v16Handler != nil:
+		err = cs.v16Handler.HandleResponse(chargePointId, request, []byte(payload))
+	}
+	if err != nil {
+		cs.logger.Warn(fmt.Sprintf("%s from %s: %s", err, chargePointId, payload))
+	}
+}
+
 // resolveProtocolVersion determines the protocol version for an API command
 // Priority: 1. Explicit version in command, 2. Auto-detect from connection, 3. Default to OCPP 1.6
 func (cs *CentralSystem) resolveProtocolVersion(command CentralSystemCommand) common.ProtocolVersion {
@@ -375,34 +535,16 @@ func (cs *CentralSystem) resolveProtocolVersion(command CentralSystemCommand) co
 	return common.OCPP16
 }
 
-// handleApiRequestV16 handles API requests for OCPP 1.6 charge points
+// handleApiRequestV16 builds the request for an API command with the handler registered for the feature
 func (cs *CentralSystem) handleApiRequestV16(command CentralSystemCommand) (ocpp.Request, error) {
-	switch command.FeatureName {
-	case remotetrigger.TriggerMessageFeatureName:
-		return cs.remoteTrigger.OnTriggerMessage(command.ChargePointId, command.ConnectorId, command.Payload)
-	case localauth.SendLocalListFeatureName:
-		return cs.localAuth.OnSendLocalList(command.ChargePointId)
-	case core.RemoteStartTransactionFeatureName:
-		return cs.coreHandler.OnRemoteStartTransaction(command.ChargePointId, command.ConnectorId, command.Payload)
-	case core.RemoteStopTransactionFeatureName:
-		return cs.coreHandler.OnRemoteStopTransaction(command.ChargePointId, command.Payload)
-	case core.GetConfigurationFeatureName:
-		return cs.coreHandler.OnGetConfiguration(command.ChargePointId, command.Payload)
-	case core.ChangeConfigurationFeatureName:
-		return cs.coreHandler.OnChangeConfiguration(command.ChargePointId, command.Payload)
-	case core.ResetFeatureName:
-		return cs.coreHandler.OnReset(command.ChargePointId, command.Payload)
-	case smartcharging.SetChargingProfileFeatureName:
-		return cs.coreHandler.OnSetChargingProfile(command.ChargePointId, command.ConnectorId, command.Payload)
-	case smartcharging.GetCompositeScheduleFeatureName:
-		return cs.coreHandler.OnGetCompositeSchedule(command.ChargePointId, command.ConnectorId, command.Payload)
-	case smartcharging.ClearChargingProfileFeatureName:
-		return cs.coreHandler.OnClearChargingProfile(command.ChargePointId, command.Payload)
-	case firmware.GetDiagnosticsFeatureName:
-		return cs.coreHandler.OnGetDiagnostics(command.ChargePointId, command.Payload)
-	default:
-		return nil, fmt.Errorf("feature not supported for OCPP 1.6: %s", command.FeatureName)
-	}
+	if cs.v16Handler == nil {
+		return nil, fmt.Errorf("OCPP 1.6 handler not initialized")
+	}
+	return cs.v16Handler.BuildRequest(command.FeatureName, v16.Command{
+		ChargePointId: command.ChargePointId,
+		ConnectorId:   command.ConnectorId,
+		Payload:       command.Payload,
+	})
 }
 
 // handleApiRequestV201 handles API requests for OCPP 2.0.1 charge points
@@ -424,17 +566,72 @@ func (cs *CentralSystem) handleApiRequestV201(command CentralSystemCommand) (ocp
 		return cs.v201Handlers.OnSetVariables(command.ChargePointId, command.Payload)
 	case "TriggerMessage":
 		return cs.v201Handlers.OnTriggerMessage(command.ChargePointId, command.Payload)
+	case smartcharging.SetChargingProfileFeatureName:
+		return cs.v201Handlers.OnSetChargingProfile(command.ChargePointId, command.ConnectorId, command.Payload)
+	case smartcharging.GetChargingProfilesFeatureName:
+		return cs.v201Handlers.OnGetChargingProfiles(command.ChargePointId, command.ConnectorId, command.Payload)
+	case smartcharging.ClearChargingProfileFeatureName:
+		return cs.v201Handlers.OnClearChargingProfile(command.ChargePointId, command.Payload)
+	case smartcharging.GetCompositeScheduleFeatureName:
+		return cs.v201Handlers.OnGetCompositeSchedule(command.ChargePointId, command.ConnectorId, command.Payload)
+	case monitoring.SetVariableMonitoringFeatureName:
+		return cs.v201Handlers.OnSetVariableMonitoring(command.ChargePointId, command.Payload)
+	case monitoring.ClearVariableMonitoringFeatureName:
+		return cs.v201Handlers.OnClearVariableMonitoring(command.ChargePointId, command.Payload)
+	case monitoring.SetMonitoringBaseFeatureName:
+		return cs.v201Handlers.OnSetMonitoringBase(command.ChargePointId, command.Payload)
+	case monitoring.GetMonitoringReportFeatureName:
+		return cs.v201Handlers.OnGetMonitoringReport(command.ChargePointId, command.Payload)
+	case firmware.UpdateFirmwareFeatureName:
+		return cs.v201Handlers.OnUpdateFirmware(command.ChargePointId, command.Payload)
+	case diagnostics.GetLogFeatureName:
+		return cs.v201Handlers.OnGetLog(command.ChargePointId, command.Payload)
+	case displaymessage.SetDisplayMessageFeatureName:
+		return cs.v201Handlers.OnSetDisplayMessage(command.ChargePointId, command.Payload)
+	case displaymessage.GetDisplayMessagesFeatureName:
+		return cs.v201Handlers.OnGetDisplayMessages(command.ChargePointId, command.Payload)
+	case displaymessage.ClearDisplayMessageFeatureName:
+		return cs.v201Handlers.OnClearDisplayMessage(command.ChargePointId, command.Payload)
+	case reservation.ReserveNowFeatureName:
+		return cs.v201Handlers.OnReserveNow(command.ChargePointId, command.ConnectorId, command.Payload)
+	case reservation.CancelReservationFeatureName:
+		return cs.v201Handlers.OnCancelReservation(command.ChargePointId, command.Payload)
+	case availability.ChangeAvailabilityFeatureName:
+		return cs.v201Handlers.OnChangeAvailability(command.ChargePointId, command.ConnectorId, command.Payload)
+	case remotecontrol.UnlockConnectorFeatureName:
+		return cs.v201Handlers.OnUnlockConnector(command.ChargePointId, command.ConnectorId, command.Payload)
+	case localauth201.GetLocalListVersionFeatureName:
+		return cs.v201Handlers.OnGetLocalListVersion(command.ChargePointId)
 	default:
 		return nil, fmt.Errorf("feature not supported for OCPP 2.0.1: %s", command.FeatureName)
 	}
 }
 
+// handleApiRequestV21 handles API requests for OCPP 2.1 charge points; the commands 2.1 did not
+// change are built as for 2.0.1
+func (cs *CentralSystem) handleApiRequestV21(command CentralSystemCommand) (ocpp.Request, error) {
+	if cs.v21Handlers == nil {
+		return nil, fmt.Errorf("OCPP 2.1 handlers not initialized")
+	}
+
+	switch command.FeatureName {
+	case smartcharging21.SetChargingProfileFeatureName:
+		return cs.v21Handlers.OnSetChargingProfile(command.ChargePointId, command.ConnectorId, command.Payload)
+	case tariff.SetDefaultTariffFeatureName:
+		return cs.v21Handlers.OnSetDefaultTariff(command.ChargePointId, command.ConnectorId, command.Payload)
+	case tariff.GetTariffsFeatureName:
+		return cs.v21Handlers.OnGetTariffs(command.ChargePointId, command.ConnectorId)
+	case tariff.ClearTariffsFeatureName:
+		return cs.v21Handlers.OnClearTariffs(command.ChargePointId, command.Payload)
+	default:
+		return cs.handleApiRequestV201(command)
+	}
+}
+
 // EnableVersionAwareRouting enables the new registry-based routing system
 // This should be called after initialization but before Start() to use the new routing
 func (cs *CentralSystem) EnableVersionAwareRouting() {
 	cs.routingEnabled = true
-	// Initialize OCPP 1.6 handler to register all features
-	_ = v16.NewHandler16()
 	log.Println("version-aware routing enabled - using feature registry")
 }
 
@@ -456,6 +653,11 @@ func (cs *CentralSystem) Start() {
 	}()
 
 	go cs.powerManager.OnSystemStart()
+	go cs.firmwareCampaigns.OnSystemStart()
+	go cs.displayMessages.OnSystemStart()
+	go cs.deviceModel.OnSystemStart()
+	go cs.customerData.OnSystemStart()
+	go cs.reservations.OnSystemStart()
 
 	// Wait for shutdown signal
 	quit := make(chan os.Signal, 1)
@@ -576,12 +778,47 @@ func NewCentralSystem(conf *config.Config) (*CentralSystem, error) {
 		systemHandler.SetErrorListener(errorListener)
 	}
 
+	// certificate authority for charge point certificates
+	if conf.Security.CACertFile != "" {
+		validity := time.Duration(conf.Security.CertValidityDays) * 24 * time.Hour
+		ca, e := pki.LoadOrCreateLocalCA(conf.Security.CACertFile, conf.Security.CAKeyFile, conf.Security.Organization, validity)
+		if e != nil {
+			return cs, fmt.Errorf("certificate authority setup failed: %s", e)
+		}
+		systemHandler.SetCertificateAuthority(ca, conf.Security.Organization)
+		log.Println("certificate authority is configured and enabled")
+	}
+
+	// contract provider for ISO 15118 Plug & Charge
+	if conf.Security.ContractDir != "" {
+		provider, e := pki.NewFileContractProvider(conf.Security.ContractDir)
+		if e != nil {
+			return cs, fmt.Errorf("contract provider setup failed: %s", e)
+		}
+		systemHandler.SetContractProvider(provider)
+		log.Println("contract provider is configured and enabled")
+	}
+
 	// websocket listener
 	wsServer := NewServer(conf, logService)
+	// a charge point offering several versions gets the first of its list that is supported here
 	wsServer.AddSupportedSupProtocol(types.SubProtocol16)
-	wsServer.AddSupportedSupProtocol("ocpp2.0.1") // Add OCPP 2.0.1 support
+	wsServer.AddSupportedSupProtocol(types.SubProtocol201)
+	wsServer.AddSupportedSupProtocol(types.SubProtocol21)
 	wsServer.SetMessageHandler(cs.handleIncomingMessage)
 	wsServer.SetWatchdog(systemHandler)
+	if conf.Listen.ClientCAFile != "" {
+		wsServer.SetCertificateAuthenticator(systemHandler)
+		log.Println("charge point client certificates are required")
+	}
+	if conf.Security.BasicAuth {
+		wsServer.SetAuthenticator(systemHandler)
+		systemHandler.SetAllowUnsetPassword(conf.Security.AllowUnsetPassword)
+		log.Println("charge point basic authentication is enabled")
+		if conf.Security.AllowUnsetPassword {
+			log.Println("charge points without a password are let through")
+		}
+	}
 
 	cs.server = wsServer
 
@@ -592,11 +829,51 @@ func NewCentralSystem(conf *config.Config) (*CentralSystem, error) {
 	}
 	cs.powerManager = power.NewLoadBalancer(powerRepo, wsServer, logService)
 
+	// firmware campaigns
+	var campaignRepo campaign.Repository
+	if database != nil {
+		campaignRepo = database
+	}
+	cs.firmwareCampaigns = campaign.NewManager(campaignRepo, wsServer, logService)
+	if err = cs.firmwareCampaigns.Load(); err != nil {
+		return cs, err
+	}
+	systemHandler.SetFirmwareListener(cs.firmwareCampaigns)
+
+	// display messages of 2.0.1 stations
+	var displayRepo display.Repository
+	if database != nil {
+		displayRepo = database
+	}
+	cs.displayMessages = display.NewManager(displayRepo, wsServer, logService)
+	if err = cs.displayMessages.Load(); err != nil {
+		return cs, err
+	}
+
+	// device model of 2.0.1 stations
+	var deviceModelRepo devicemodel.Repository
+	if database != nil {
+		deviceModelRepo = database
+	}
+	cs.deviceModel = devicemodel.NewManager(deviceModelRepo, wsServer, logService)
+
+	// reservations of both protocol versions, expired server-side
+	var reservationRepo ReservationRepository
+	if database != nil {
+		reservationRepo = database
+	}
+	cs.reservations = NewReservationService(reservationRepo, logService, systemHandler.getTime)
+	if err = cs.reservations.Load(); err != nil {
+		return cs, err
+	}
+	systemHandler.SetReservationService(cs.reservations)
+
 	trigger := NewTrigger(wsServer, logService)
 	systemHandler.SetTrigger(trigger)
 	systemHandler.SetServer(wsServer)
 	systemHandler.SetMeterSampleInterval(conf.MeterValueSampleInterval)
 	systemHandler.SetMeterMeasurands(conf.MeterValuesMeasurands)
+	systemHandler.SetTariff(conf.Tariff.Currency, conf.Tariff.CostUpdateInterval)
 
 	err = systemHandler.OnStart()
 	if err != nil {
@@ -604,10 +881,29 @@ func NewCentralSystem(conf *config.Config) (*CentralSystem, error) {
 	}
 
 	cs.SetCoreHandler(systemHandler)
-	cs.SetFirmwareHandler(systemHandler)
-	cs.SetRemoteTriggerHandler(systemHandler)
 	cs.SetLocalAuthHandler(systemHandler)
 
+	// customer data requests, erased from the database, the local lists and the 2.0.1 stations
+	var customerDataRepo customerdata.Repository
+	if database != nil {
+		customerDataRepo = database
+	}
+	cs.customerData = customerdata.NewManager(customerDataRepo, wsServer, systemHandler, logService)
+
+	// OCPP 1.6 features are registered once, with the handlers they dispatch to
+	cs.SetV16Handler(v16.NewHandler16(v16.Handler16Config{
+		CoreHandler:             systemHandler,
+		CoreCmdHandler:          systemHandler,
+		FirmwareHandler:         systemHandler,
+		FirmwareCmdHandler:      systemHandler,
+		SmartChargingCmdHandler: systemHandler,
+		LocalAuthHandler:        systemHandler,
+		RemoteTriggerHandler:    systemHandler,
+		ReservationHandler:      systemHandler,
+		SecurityHandler:         systemHandler,
+		SecurityCmdHandler:      systemHandler,
+	}))
+
 	// ========================================================================
 	// OCPP 2.0.1 Handler Setup
 	// ========================================================================
@@ -615,11 +911,45 @@ func NewCentralSystem(conf *config.Config) (*CentralSystem, error) {
 
 	// Create v201 business logic handlers
 	v201Handlers := NewV201Handlers(systemHandler, logService)
+	v201Handlers.SetMonitoringSeverities(conf.Monitoring.AlertSeverity, conf.Monitoring.ErrorSeverity, conf.Monitoring.DefaultSeverity)
+	v201Handlers.SetPowerManager(cs.powerManager)
+	v201Handlers.SetDeviceModel(cs.deviceModel)
+	v201Handlers.SetCustomerData(cs.customerData)
 
 	// Register v201 handlers in the central system
 	cs.SetV201Handlers(v201Handlers)
+
+	// registering the OCPP 2.0.1 features is what lets incoming 2.0.1 messages be parsed
+	cs.v201Handler = handlers.NewHandler201(handlers.Handler201Config{
+		ProvisioningHandler:   v201Handlers,
+		AuthorizationHandler:  v201Handlers,
+		TransactionsHandler:   v201Handlers,
+		AvailabilityHandler:   v201Handlers,
+		MeterValuesHandler:    v201Handlers,
+		SmartChargingHandler:  v201Handlers,
+		MonitoringHandler:     v201Handlers,
+		FirmwareHandler:       v201Handlers,
+		DiagnosticsHandler:    v201Handlers,
+		DisplayMessageHandler: v201Handlers,
+		Iso15118Handler:       v201Handlers,
+		SecurityHandler:       v201Handlers,
+		ReservationHandler:    v201Handlers,
+	})
 	log.Println("OCPP 2.0.1 handlers registered successfully")
 
+	// ========================================================================
+	// OCPP 2.1 Handler Setup
+	// ========================================================================
+
+	// the 2.1 features start as a copy of the 2.0.1 ones, so they are registered after them
+	v21Handlers := NewV21Handlers(v201Handlers)
+	cs.SetV21Handlers(v21Handlers)
+	cs.v21Handler = handlers21.NewHandler21(handlers21.Handler21Config{
+		SmartChargingHandler: v21Handlers,
+		TransactionsHandler:  v21Handlers,
+	})
+	log.Printf("OCPP 2.1 handlers registered: %d features", cs.v21Handler.GetFeatureCount())
+
 	// api server
 	apiServer := NewServerApi(conf, logService)
 	apiServer.SetRequestHandler(cs.handleApiRequest)
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"evsys/entity"
+	"evsys/ocpp/v201/authorization"
+	"evsys/ocpp/v201/smartcharging"
+	"fmt"
+	"time"
+)
+
+// OnNotifyChargingLimit handles OCPP 2.0.1 NotifyChargingLimit requests. The limit an external
+// system set is stored per EVSE and source, where the load balancer reads it as a hard cap on the
+// sessions of the EVSE; it is rebalanced once the answer is sent.
+func (h *V201Handlers) OnNotifyChargingLimit(chargePointId string, request *smartcharging.NotifyChargingLimitRequest) (*smartcharging.NotifyChargingLimitResponse, error) {
+	limit := externalLimit(chargePointId, request, h.systemHandler.getTime())
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: EVSE %d: %s limit of %d periods, grid critical %v",
+		limit.EvseId, limit.Source, len(limit.Periods), limit.GridCritical))
+
+	if h.systemHandler.database != nil {
+		if err := h.systemHandler.database.SaveExternalLimit(limit); err != nil {
+			h.logger.Error("save external limit", err)
+		}
+	}
+	return &smartcharging.NotifyChargingLimitResponse{}, nil
+}
+
+// OnClearedChargingLimit handles OCPP 2.0.1 ClearedChargingLimit requests: the limits the source
+// set on the EVSE, or on every EVSE without one, are released to the load balancer
+func (h *V201Handlers) OnClearedChargingLimit(chargePointId string, request *authorization.ClearedChargingLimitRequest) (*authorization.ClearedChargingLimitResponse, error) {
+	evse := "all EVSEs"
+	if request.EvseId != nil {
+		evse = fmt.Sprintf("EVSE %d", *request.EvseId)
+	}
+	h.logger.FeatureEvent("ClearedChargingLimit", chargePointId, fmt.Sprintf("v2.0.1: source=%s, %s", request.ChargingLimitSource, evse))
+
+	if h.systemHandler.database != nil {
+		if _, err := h.systemHandler.database.DeleteExternalLimits(chargePointId, string(request.ChargingLimitSource), request.EvseId); err != nil {
+			h.logger.Error("delete external limits", err)
+		}
+	}
+	return &authorization.ClearedChargingLimitResponse{}, nil
+}
+
+// externalLimit turns the schedules of a NotifyChargingLimit into periods of absolute time. A
+// schedule without a start counts from when it was received; a period lasts until the next one,
+// or until the end of its schedule.
+func externalLimit(chargePointId string, request *smartcharging.NotifyChargingLimitRequest, now time.Time) *entity.ExternalLimit {
+	limit := &entity.ExternalLimit{
+		ChargePointId: chargePointId,
+		Source:        string(request.ChargingLimit.ChargingLimitSource),
+		GridCritical:  request.ChargingLimit.IsGridCritical != nil && *request.ChargingLimit.IsGridCritical,
+		Periods:       make([]entity.LimitPeriod, 0),
+		TimeReceived:  now,
+	}
+	if request.EvseId != nil {
+		limit.EvseId = *request.EvseId
+	}
+	for _, schedule := range request.ChargingSchedule {
+		start := now
+		if schedule.StartSchedule != nil {
+			start = *schedule.StartSchedule
+		}
+		var scheduleEnd *time.Time
+		if schedule.Duration != nil {
+			end := start.Add(time.Duration(*schedule.Duration) * time.Second)
+			scheduleEnd = &end
+		}
+		periods := schedule.ChargingSchedulePeriod
+		for i, period := range periods {
+			limitPeriod := entity.LimitPeriod{
+				Start: start.Add(time.Duration(period.StartPeriod) * time.Second),
+				End:   scheduleEnd,
+				Limit: period.Limit,
+				Unit:  string(schedule.ChargingRateUnit),
+			}
+			if i+1 < len(periods) {
+				end := start.Add(time.Duration(periods[i+1].StartPeriod) * time.Second)
+				limitPeriod.End = &end
+			}
+			if period.NumberPhases != nil {
+				limitPeriod.Phases = *period.NumberPhases
+			}
+			limit.Periods = append(limit.Periods, limitPeriod)
+		}
+	}
+	return limit
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"evsys/ocpp/v201"
+	"evsys/ocpp/v201/authorization"
+	"evsys/ocpp/v201/smartcharging"
+)
+
+// Periods are stored in absolute time: each lasts until the next, the last until the schedule ends.
+func TestExternalLimitPeriods(t *testing.T) {
+	received := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	evseId, duration, phases := 2, 3600, 1
+	request := &smartcharging.NotifyChargingLimitRequest{
+		EvseId:        &evseId,
+		ChargingLimit: smartcharging.ChargingLimitType{ChargingLimitSource: authorization.ChargingLimitSourceEMS},
+		ChargingSchedule: []v201.ChargingSchedule{{
+			Duration:         &duration,
+			ChargingRateUnit: v201.ChargingRateUnitW,
+			ChargingSchedulePeriod: []v201.ChargingSchedulePeriod{
+				{StartPeriod: 0, Limit: 7400, NumberPhases: &phases},
+				{StartPeriod: 900, Limit: 3700},
+			},
+		}},
+	}
+
+	limit := externalLimit("CP1", request, received)
+	if limit.EvseId != 2 || limit.Source != "EMS" || len(limit.Periods) != 2 {
+		t.Fatalf("limit on EVSE %d from %s with %d periods, want EVSE 2 from EMS with 2", limit.EvseId, limit.Source, len(limit.Periods))
+	}
+	first, second := limit.Periods[0], limit.Periods[1]
+	if !first.Start.Equal(received) || first.End == nil || !first.End.Equal(received.Add(15*time.Minute)) || first.Phases != 1 {
+		t.Errorf("first period %+v, want from receipt for 15 minutes on 1 phase", first)
+	}
+	if !second.Start.Equal(received.Add(15*time.Minute)) || second.End == nil || !second.End.Equal(received.Add(time.Hour)) {
+		t.Errorf("second period %+v, want until the schedule ends an hour after receipt", second)
+	}
+	if second.Unit != "W" || second.Limit != 3700 {
+		t.Errorf("second period of %v%s, want 3700W", second.Limit, second.Unit)
+	}
+}
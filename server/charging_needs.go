@@ -0,0 +1,97 @@
+package server
+
+import (
+	"evsys/entity"
+	"evsys/ocpp/v201"
+	"evsys/ocpp/v201/smartcharging"
+	"fmt"
+	"time"
+)
+
+// SetPowerManager lets the load balancer rule on the charging schedules EVs propose; without one,
+// every schedule is accepted
+func (h *V201Handlers) SetPowerManager(powerManager PowerManager) {
+	h.powerManager = powerManager
+}
+
+// OnNotifyEVChargingNeeds handles OCPP 2.0.1 NotifyEVChargingNeeds requests. The needs are stored on
+// the transaction running on the EVSE, where the load balancer reads them to serve the sessions with
+// the closest deadlines first; the limit the session gets still comes from the balancer's slots.
+func (h *V201Handlers) OnNotifyEVChargingNeeds(chargePointId string, request *smartcharging.NotifyEVChargingNeedsRequest) (*smartcharging.NotifyEVChargingNeedsResponse, error) {
+	needs := chargingNeeds(request.ChargingNeeds, h.systemHandler.getTime())
+	departure := "no departure time"
+	if needs.DepartureTime != nil {
+		departure = "by " + needs.DepartureTime.Format(time.RFC3339)
+	}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: EVSE %d: %s, %d Wh %s",
+		request.EvseId, needs.EnergyTransfer, needs.EnergyAmount, departure))
+
+	rejected := &smartcharging.NotifyEVChargingNeedsResponse{Status: smartcharging.NotifyEVChargingNeedsStatusRejected}
+	transactionId, ok := h.systemHandler.evseTransaction(chargePointId, request.EvseId)
+	if !ok {
+		h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: no transaction on EVSE %d", request.EvseId))
+		return rejected, nil
+	}
+	if h.systemHandler.database == nil {
+		return rejected, nil
+	}
+	if err := h.systemHandler.database.UpdateTransactionChargingNeeds(transactionId, needs); err != nil {
+		h.logger.Error("update charging needs", err)
+		return rejected, nil
+	}
+	return &smartcharging.NotifyEVChargingNeedsResponse{Status: smartcharging.NotifyEVChargingNeedsStatusAccepted}, nil
+}
+
+// OnNotifyEVChargingSchedule handles OCPP 2.0.1 NotifyEVChargingSchedule requests: the schedule is
+// accepted when it keeps to the limit of the charge point's location and of its EVSE, and rejected
+// otherwise, which makes the EV renegotiate
+func (h *V201Handlers) OnNotifyEVChargingSchedule(chargePointId string, request *smartcharging.NotifyEVChargingScheduleRequest) (*smartcharging.NotifyEVChargingScheduleResponse, error) {
+	response := &smartcharging.NotifyEVChargingScheduleResponse{Status: smartcharging.GenericStatusAccepted}
+	if h.powerManager != nil {
+		if fits, reason := h.powerManager.ScheduleFits(chargePointId, request.EvseId, request.ChargingSchedule); !fits {
+			response.Status = smartcharging.GenericStatusRejected
+			response.StatusInfo = &v201.StatusInfo{ReasonCode: "LimitExceeded", AdditionalInfo: reason}
+		}
+	}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: EVSE %d: %d periods in %s: %s",
+		request.EvseId, len(request.ChargingSchedule.ChargingSchedulePeriod), request.ChargingSchedule.ChargingRateUnit, response.Status))
+	return response, nil
+}
+
+// evseTransaction finds the transaction running on an EVSE
+func (h *SystemHandler) evseTransaction(chargePointId string, evseId int) (int, bool) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	state, ok := h.getChargePoint(chargePointId)
+	if !ok {
+		return 0, false
+	}
+	for _, connector := range evseConnectors(state, evseId) {
+		if connector.CurrentTransactionId >= 0 {
+			return connector.CurrentTransactionId, true
+		}
+	}
+	return 0, false
+}
+
+// chargingNeeds keeps what the balancer can use of the needs an EV reported
+func chargingNeeds(reported smartcharging.ChargingNeedsType, now time.Time) *entity.ChargingNeeds {
+	needs := &entity.ChargingNeeds{
+		EnergyTransfer: string(reported.RequestedEnergyTransfer),
+		EnergyAmount:   reported.EnergyAmount(),
+		DepartureTime:  reported.DepartureTime,
+		TimeReceived:   now,
+	}
+	if ac := reported.ACChargingParameters; ac != nil {
+		needs.MaxCurrent = ac.EVMaxCurrent
+	}
+	if dc := reported.DCChargingParameters; dc != nil {
+		needs.MaxCurrent = dc.EVMaxCurrent
+		needs.StateOfCharge = dc.StateOfCharge
+		if dc.EVMaxPower != nil {
+			needs.MaxPower = *dc.EVMaxPower
+		}
+	}
+	return needs
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"evsys/entity"
+	"evsys/internal"
+	"evsys/ocpp/v201"
+	"evsys/ocpp/v201/smartcharging"
+)
+
+type chargingNeedsDB struct {
+	internal.Database
+	needs map[int]*entity.ChargingNeeds
+}
+
+func (s *chargingNeedsDB) UpdateTransactionChargingNeeds(transactionId int, needs *entity.ChargingNeeds) error {
+	s.needs[transactionId] = needs
+	return nil
+}
+
+// schedulePowerManager refuses every schedule above limit amperes
+type schedulePowerManager struct {
+	PowerManager
+	limit float64
+}
+
+func (m schedulePowerManager) ScheduleFits(_ string, _ int, schedule v201.ChargingSchedule) (bool, string) {
+	for _, period := range schedule.ChargingSchedulePeriod {
+		if period.Limit > m.limit {
+			return false, "above the limit"
+		}
+	}
+	return true, ""
+}
+
+func newChargingNeedsHandlers(db *chargingNeedsDB) *V201Handlers {
+	h := &SystemHandler{
+		chargePoints: map[string]*ChargePointState{},
+		database:     db,
+		logger:       stopStubLogger{},
+		location:     time.UTC,
+	}
+	state := newChargePointState(&entity.ChargePoint{Id: "CP1", IsEnabled: true})
+	evseId := 1
+	connector := entity.NewConnector(1, "CP1")
+	connector.EvseId = &evseId
+	connector.CurrentTransactionId = 42
+	state.connectors[1] = connector
+	h.chargePoints["CP1"] = state
+	return NewV201Handlers(h, stopStubLogger{})
+}
+
+func TestChargingNeedsStoredOnTransaction(t *testing.T) {
+	db := &chargingNeedsDB{needs: map[int]*entity.ChargingNeeds{}}
+	handlers := newChargingNeedsHandlers(db)
+	departure := time.Now().Add(3 * time.Hour)
+	energy, power := 40000, 50000
+
+	response, err := handlers.OnNotifyEVChargingNeeds("CP1", &smartcharging.NotifyEVChargingNeedsRequest{
+		EvseId: 1,
+		ChargingNeeds: smartcharging.ChargingNeedsType{
+			RequestedEnergyTransfer: smartcharging.EnergyTransferModeDC,
+			DepartureTime:           &departure,
+			DCChargingParameters:    &smartcharging.DCChargingParametersType{EVMaxCurrent: 125, EVMaxVoltage: 400, EnergyAmount: &energy, EVMaxPower: &power},
+		},
+	})
+	if err != nil {
+		t.Fatalf("OnNotifyEVChargingNeeds: %v", err)
+	}
+	if response.Status != smartcharging.NotifyEVChargingNeedsStatusAccepted {
+		t.Fatalf("status %s, want Accepted", response.Status)
+	}
+	needs := db.needs[42]
+	if needs == nil || needs.EnergyAmount != 40000 || needs.MaxPower != 50000 || needs.MaxCurrent != 125 {
+		t.Fatalf("stored needs %+v, want 40000 Wh at up to 50000 W and 125 A", needs)
+	}
+	if got := needs.RequiredPower(time.Now()); got < 13000 || got > 13400 {
+		t.Errorf("RequiredPower = %d W, want about 13333 W for 40 kWh in three hours", got)
+	}
+
+	// nothing runs on EVSE 2, so there is no session to attach the needs to
+	response, _ = handlers.OnNotifyEVChargingNeeds("CP1", &smartcharging.NotifyEVChargingNeedsRequest{
+		EvseId:        2,
+		ChargingNeeds: smartcharging.ChargingNeedsType{RequestedEnergyTransfer: smartcharging.EnergyTransferModeACThreePhase},
+	})
+	if response.Status != smartcharging.NotifyEVChargingNeedsStatusRejected {
+		t.Errorf("status %s for an idle EVSE, want Rejected", response.Status)
+	}
+}
+
+func TestEVChargingScheduleCheckedAgainstLocation(t *testing.T) {
+	handlers := newChargingNeedsHandlers(&chargingNeedsDB{needs: map[int]*entity.ChargingNeeds{}})
+	request := &smartcharging.NotifyEVChargingScheduleRequest{
+		TimeBase: time.Now(),
+		EvseId:   1,
+		ChargingSchedule: v201.ChargingSchedule{
+			Id:                     1,
+			ChargingRateUnit:       v201.ChargingRateUnitA,
+			ChargingSchedulePeriod: []v201.ChargingSchedulePeriod{{StartPeriod: 0, Limit: 32}},
+		},
+	}
+
+	// without a load balancer there is no limit to keep to
+	if response, _ := handlers.OnNotifyEVChargingSchedule("CP1", request); response.Status != smartcharging.GenericStatusAccepted {
+		t.Errorf("status %s without a power manager, want Accepted", response.Status)
+	}
+
+	handlers.SetPowerManager(schedulePowerManager{limit: 16})
+	response, _ := handlers.OnNotifyEVChargingSchedule("CP1", request)
+	if response.Status != smartcharging.GenericStatusRejected || response.StatusInfo == nil {
+		t.Fatalf("response %+v, want Rejected with a reason", response)
+	}
+	handlers.SetPowerManager(schedulePowerManager{limit: 32})
+	if response, _ = handlers.OnNotifyEVChargingSchedule("CP1", request); response.Status != smartcharging.GenericStatusAccepted {
+		t.Errorf("status %s within the limit, want Accepted", response.Status)
+	}
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"evsys/entity"
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+	"evsys/ocpp/v201/transactions"
+	"evsys/ocpp/v21/tariff"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SetTariff sets the currency prices are shown in, and how often, in seconds, the running cost of
+// 2.0.1 transactions is pushed with CostUpdated; 0 leaves it to the TransactionEvent answers.
+func (h *SystemHandler) SetTariff(currency string, costUpdateInterval int) {
+	h.currency = currency
+	h.costUpdateInterval = costUpdateInterval
+}
+
+// cost converts an amount of the billing service, in cents, to the currency units OCPP carries
+func cost(cents int) float64 {
+	return float64(cents) / 100
+}
+
+// tariffMessage describes the plan a driver would charge by, for the display of the charge point;
+// nil when there is no plan to show.
+func (h *SystemHandler) tariffMessage(username string) *v201.MessageContent {
+	if h.billing == nil {
+		return nil
+	}
+	plan := h.billing.Tariff(username)
+	if plan == nil || (plan.PricePerKwh == 0 && plan.PricePerHour == 0) {
+		return nil
+	}
+	var parts []string
+	if plan.PricePerKwh > 0 {
+		parts = append(parts, fmt.Sprintf("%.2f %s/kWh", cost(plan.PricePerKwh), h.currency))
+	}
+	if plan.PricePerHour > 0 {
+		// the billing service charges time only after the first hour
+		parts = append(parts, fmt.Sprintf("%.2f %s/h after the first hour", cost(plan.PricePerHour), h.currency))
+	}
+	return &v201.MessageContent{Content: strings.Join(parts, ", "), Format: "UTF8"}
+}
+
+// defaultTariff turns the default payment plan of the billing service into a 2.1 tariff, for the
+// charging stations that calculate the cost themselves
+func (h *SystemHandler) defaultTariff() (*tariff.TariffType, error) {
+	if h.billing == nil {
+		return nil, fmt.Errorf("no billing service to take the tariff from")
+	}
+	plan := h.billing.Tariff("")
+	if plan == nil {
+		return nil, fmt.Errorf("no default payment plan")
+	}
+	defaultTariff := &tariff.TariffType{TariffId: plan.PlanId, Currency: h.currency}
+	if defaultTariff.TariffId == "" {
+		defaultTariff.TariffId = "default"
+	}
+	if plan.Description != "" {
+		defaultTariff.Description = []v201.MessageContent{{Content: plan.Description, Format: "UTF8"}}
+	}
+	// a plan without an energy price still needs one, or the station has nothing to calculate with
+	if plan.PricePerKwh > 0 || plan.PricePerHour == 0 {
+		defaultTariff.Energy = &tariff.TariffEnergyType{Prices: []tariff.TariffEnergyPriceType{{PriceKwh: cost(plan.PricePerKwh)}}}
+	}
+	if plan.PricePerHour > 0 {
+		// the billing service charges time only after the first hour
+		firstHour := 3600
+		defaultTariff.ChargingTime = &tariff.TariffTimeType{Prices: []tariff.TariffTimePriceType{{
+			PriceMinute: cost(plan.PricePerHour) / 60,
+			Conditions:  &tariff.TariffConditionsType{MinTime: &firstHour},
+		}}}
+	}
+	return defaultTariff, nil
+}
+
+// sessionTransaction finds the unfinished transaction a 2.0.1 charge point knows by sessionId
+func (h *SystemHandler) sessionTransaction(chargePointId, sessionId string) *entity.Transaction {
+	transaction := h.stationTransaction(chargePointId, sessionId)
+	if transaction == nil || transaction.IsFinished {
+		return nil
+	}
+	return transaction
+}
+
+// runningCost is the price of the last meter value of a transaction, in cents
+func (h *SystemHandler) runningCost(transaction *entity.Transaction) (int, bool) {
+	if h.database == nil {
+		return 0, false
+	}
+	meter, err := h.database.ReadTransactionMeterValue(transaction.Id)
+	if err != nil || meter == nil {
+		return 0, false
+	}
+	return meter.Price, true
+}
+
+// sendCostUpdates pushes the running cost of every 2.0.1 transaction for the lifetime of the process.
+func (h *SystemHandler) sendCostUpdates() {
+	sent := make(map[int]int)
+	ticker := time.NewTicker(time.Duration(h.costUpdateInterval) * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		sent = h.updateCosts(sent)
+	}
+}
+
+// updateCosts sends CostUpdated for every running transaction of an online 2.0.1 charge point
+// whose cost changed since the last update; sent maps transaction ids to the costs sent last, and
+// the map of this round is returned.
+func (h *SystemHandler) updateCosts(sent map[int]int) map[int]int {
+	if h.database == nil || h.server == nil {
+		return sent
+	}
+	running := make(map[int]string)
+	h.mux.Lock()
+	for _, state := range h.chargePoints {
+		if !common.ProtocolVersion(state.model.ProtocolVersion).IsOCPP2() || !state.model.IsOnline {
+			continue
+		}
+		for _, connector := range state.connectors {
+			if connector.CurrentTransactionId >= 0 {
+				running[connector.CurrentTransactionId] = state.model.Id
+			}
+		}
+	}
+	h.mux.Unlock()
+
+	current := make(map[int]int, len(running))
+	for transactionId, chargePointId := range running {
+		transaction, err := h.database.GetTransaction(transactionId)
+		if err != nil || transaction == nil || transaction.IsFinished || transaction.SessionId == "" {
+			continue
+		}
+		price, ok := h.runningCost(transaction)
+		if !ok {
+			continue
+		}
+		if last, found := sent[transactionId]; found && last == price {
+			current[transactionId] = price
+			continue
+		}
+		request := &transactions.CostUpdatedRequest{TotalCost: cost(price), TransactionId: transaction.SessionId}
+		if _, err = h.server.SendRequest(chargePointId, request); err != nil {
+			h.logger.Warn(fmt.Sprintf("send cost update to %s: %v", chargePointId, err))
+			continue
+		}
+		h.logger.FeatureEvent(request.GetFeatureName(), chargePointId,
+			fmt.Sprintf("v2.0.1: transaction %s costs %.2f %s", transaction.SessionId, request.TotalCost, h.currency))
+		current[transactionId] = price
+	}
+	return current
+}
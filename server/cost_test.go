@@ -0,0 +1,152 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"evsys/entity"
+	"evsys/internal"
+	"evsys/ocpp/v201"
+	"evsys/ocpp/v201/authorization"
+	"evsys/ocpp/v201/transactions"
+)
+
+// costStubDB holds one running transaction and the meter values stored for it.
+type costStubDB struct {
+	internal.Database
+	transaction *entity.Transaction
+	meters      []*entity.TransactionMeter
+}
+
+func (s *costStubDB) GetTransaction(int) (*entity.Transaction, error) {
+	return s.transaction, nil
+}
+
+func (s *costStubDB) GetTransactionBySessionId(_, sessionId string) (*entity.Transaction, error) {
+	if s.transaction.SessionId != sessionId {
+		return nil, nil
+	}
+	return s.transaction, nil
+}
+
+func (s *costStubDB) UpdateTransactionSequence(int, int, []int) error { return nil }
+
+func (s *costStubDB) AddTransactionMeterValue(meter *entity.TransactionMeter) error {
+	s.meters = append(s.meters, meter)
+	return nil
+}
+
+func (s *costStubDB) ReadTransactionMeterValue(int) (*entity.TransactionMeter, error) {
+	if len(s.meters) == 0 {
+		return nil, nil
+	}
+	return s.meters[len(s.meters)-1], nil
+}
+
+func (s *costStubDB) UpdateConnector(*entity.Connector) error { return nil }
+
+// costStubBilling charges 35 cents per kWh by a plan of 35 cents per kWh and 1.20 per hour.
+type costStubBilling struct{}
+
+func (costStubBilling) OnTransactionStart(*entity.Transaction) error    { return nil }
+func (costStubBilling) OnTransactionFinished(*entity.Transaction) error { return nil }
+func (costStubBilling) OnMeterValue(transaction *entity.Transaction, meter *entity.TransactionMeter) error {
+	meter.Price = (meter.Value - transaction.MeterStart) * 35 / 1000
+	return nil
+}
+func (costStubBilling) Tariff(string) *entity.PaymentPlan {
+	return &entity.PaymentPlan{PricePerKwh: 35, PricePerHour: 120}
+}
+
+func newCostHandler() (*SystemHandler, *costStubDB) {
+	db := &costStubDB{transaction: &entity.Transaction{Id: 7, SessionId: "TX-0001", ChargePointId: "CP1", MeterStart: 1000}}
+	h := NewSystemHandler(time.UTC)
+	h.database = db
+	h.billing = costStubBilling{}
+	h.logger = stopStubLogger{}
+	h.SetTariff("EUR", 60)
+	evseId := 1
+	connector := entity.NewConnector(1, "CP1")
+	connector.EvseId = &evseId
+	connector.CurrentTransactionId = 7
+	state := newChargePointState(&entity.ChargePoint{Id: "CP1", ProtocolVersion: "ocpp2.0.1", IsOnline: true})
+	state.connectors[1] = connector
+	h.chargePoints["CP1"] = state
+	return h, db
+}
+
+func TestTransactionEventIsAnsweredWithRunningCost(t *testing.T) {
+	h, _ := newCostHandler()
+	handlers := NewV201Handlers(h, stopStubLogger{})
+	connectorId := 1
+	request := &transactions.TransactionEventRequest{
+		EventType:       v201.TransactionEventUpdated,
+		Timestamp:       time.Now(),
+		TriggerReason:   v201.TriggerReasonMeterValuePeriodic,
+		SeqNo:           1,
+		TransactionInfo: v201.Transaction{TransactionId: "TX-0001"},
+		Evse:            &v201.EVSE{Id: 1, ConnectorId: &connectorId},
+		MeterValue: []v201.MeterValue{{
+			Timestamp:    time.Now(),
+			SampledValue: []v201.SampledValue{{Value: 11000, Measurand: v201.MeasurandEnergyActiveImportRegister}},
+		}},
+	}
+	response, err := handlers.OnTransactionEvent("CP1", request)
+	if err != nil {
+		t.Fatalf("OnTransactionEvent: %v", err)
+	}
+	if response.TotalCost == nil || *response.TotalCost != 3.5 {
+		t.Errorf("total cost %v, want 3.50 for 10 kWh", response.TotalCost)
+	}
+}
+
+func TestCostUpdatedIsSentWhenTheCostChanges(t *testing.T) {
+	h, db := newCostHandler()
+	sender := &recordingSender{}
+	h.server = sender
+	db.meters = append(db.meters, &entity.TransactionMeter{Price: 120})
+
+	sent := h.updateCosts(map[int]int{})
+	request, ok := sender.request.(*transactions.CostUpdatedRequest)
+	if sender.calls != 1 || !ok || sender.clientId != "CP1" || request.TransactionId != "TX-0001" || request.TotalCost != 1.2 {
+		t.Fatalf("sent %+v to %s in %d calls, want 1.20 for TX-0001 to CP1", sender.request, sender.clientId, sender.calls)
+	}
+	sent = h.updateCosts(sent)
+	if sender.calls != 1 {
+		t.Error("sent an unchanged cost again")
+	}
+	db.meters = append(db.meters, &entity.TransactionMeter{Price: 150})
+	h.updateCosts(sent)
+	if sender.calls != 2 {
+		t.Error("no update for a changed cost")
+	}
+}
+
+func TestAuthorizeShowsTheTariff(t *testing.T) {
+	h, _ := newCostHandler()
+	h.database = nil
+	h.acceptTags = true
+	handlers := NewV201Handlers(h, stopStubLogger{})
+	response, err := handlers.OnAuthorize("CP1", &authorization.AuthorizeRequest{IdToken: v201.IdToken{IdToken: "TAG1", Type: v201.IdTokenTypeISO14443}})
+	if err != nil {
+		t.Fatalf("OnAuthorize: %v", err)
+	}
+	message := response.IdTokenInfo.PersonalMessage
+	if message == nil || message.Content != "0.35 EUR/kWh, 1.20 EUR/h after the first hour" {
+		t.Errorf("personal message %+v, want the tariff", message)
+	}
+}
+
+func TestSessionTransactionSkipsFinished(t *testing.T) {
+	h, db := newCostHandler()
+	if transaction := h.sessionTransaction("CP1", "TX-0001"); transaction == nil || transaction.Id != 7 {
+		t.Fatalf("running transaction = %v, want #7", transaction)
+	}
+	db.transaction.IsFinished = true
+	if transaction := h.sessionTransaction("CP1", "TX-0001"); transaction != nil {
+		t.Errorf("finished transaction #%d returned", transaction.Id)
+	}
+	if transaction := h.sessionTransaction("CP1", "TX-0002"); transaction != nil {
+		t.Errorf("transaction #%d returned for an unknown session", transaction.Id)
+	}
+}
@@ -0,0 +1,16 @@
+package server
+
+import (
+	"evsys/ocpp/v201/diagnostics"
+)
+
+// CustomerData keeps the reports charge points send about a customer.
+type CustomerData interface {
+	OnNotifyCustomerInformation(chargePointId string, request *diagnostics.NotifyCustomerInformationRequest)
+}
+
+// SetCustomerData passes the customer information reports of the charge points on to the customer
+// data manager
+func (h *V201Handlers) SetCustomerData(customerData CustomerData) {
+	h.customerData = customerData
+}
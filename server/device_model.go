@@ -0,0 +1,17 @@
+package server
+
+import (
+	"evsys/ocpp/v201/monitoring"
+	"evsys/ocpp/v201/provisioning"
+)
+
+// DeviceModel keeps the device model the OCPP 2.0.1 charge points report.
+type DeviceModel interface {
+	OnNotifyReport(chargePointId string, request *provisioning.NotifyReportRequest)
+	OnNotifyEvent(chargePointId string, events []monitoring.EventDataType)
+}
+
+// SetDeviceModel passes the reports and events of the charge points on to the device model store
+func (h *V201Handlers) SetDeviceModel(deviceModel DeviceModel) {
+	h.deviceModel = deviceModel
+}
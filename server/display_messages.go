@@ -0,0 +1,83 @@
+package server
+
+import (
+	"encoding/json"
+	"evsys/ocpp"
+	"evsys/ocpp/v201/displaymessage"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ============================================================================
+// DISPLAY MESSAGE HANDLER
+// ============================================================================
+
+// OnNotifyDisplayMessages handles OCPP 2.0.1 NotifyDisplayMessages requests, the answer to a
+// GetDisplayMessages command; the messages are logged, the scheduled ones are kept by display.Manager
+func (h *V201Handlers) OnNotifyDisplayMessages(chargePointId string, request *displaymessage.NotifyDisplayMessagesRequest) (*displaymessage.NotifyDisplayMessagesResponse, error) {
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: requestId=%d, %d messages, tbc=%v",
+		request.RequestId, len(request.MessageInfo), request.Tbc))
+	for _, message := range request.MessageInfo {
+		info := fmt.Sprintf("v2.0.1: message %d, %s", message.Id, message.Priority)
+		if message.State != "" {
+			info += ", when " + string(message.State)
+		}
+		if message.Message.Language != "" {
+			info += ", " + message.Message.Language
+		}
+		h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("%s: %s", info, message.Message.Content))
+	}
+	return &displaymessage.NotifyDisplayMessagesResponse{}, nil
+}
+
+// ============================================================================
+// DISPLAY MESSAGE API COMMANDS (CSMS → Charging Station)
+// ============================================================================
+
+// OnSetDisplayMessage creates a SetDisplayMessage request for OCPP 2.0.1; the payload is a messageInfo
+// object. Scheduled messages are installed under ids from 10 up, so a message set here under such an
+// id may be replaced or cleared by the schedule.
+func (h *V201Handlers) OnSetDisplayMessage(chargePointId string, payload string) (ocpp.Request, error) {
+	var request displaymessage.SetDisplayMessageRequest
+	if err := json.Unmarshal([]byte(payload), &request.Message); err != nil {
+		return nil, fmt.Errorf("invalid payload")
+	}
+	if request.Message.Message.Format == "" {
+		request.Message.Message.Format = displaymessage.MessageFormatUTF8
+	}
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: message %d, %s", request.Message.Id, request.Message.Priority))
+	return &request, nil
+}
+
+// OnGetDisplayMessages creates a GetDisplayMessages request for OCPP 2.0.1; an empty payload asks for
+// every message, otherwise it is an object with id, priority and state. The messages arrive in
+// NotifyDisplayMessages.
+func (h *V201Handlers) OnGetDisplayMessages(chargePointId string, payload string) (ocpp.Request, error) {
+	request := &displaymessage.GetDisplayMessagesRequest{}
+	if payload != "" {
+		if err := json.Unmarshal([]byte(payload), request); err != nil {
+			return nil, fmt.Errorf("invalid payload")
+		}
+	}
+	request.RequestId = h.systemHandler.nextRequestId()
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: requestId=%d", request.RequestId))
+	return request, nil
+}
+
+// OnClearDisplayMessage creates a ClearDisplayMessage request for OCPP 2.0.1; the payload is the message id
+func (h *V201Handlers) OnClearDisplayMessage(chargePointId string, payload string) (ocpp.Request, error) {
+	id, err := strconv.Atoi(strings.TrimSpace(payload))
+	if err != nil {
+		return nil, fmt.Errorf("invalid message id")
+	}
+	request := &displaymessage.ClearDisplayMessageRequest{Id: id}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: message %d", id))
+	return request, nil
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"evsys/internal"
+	"fmt"
+	"time"
+)
+
+// FirmwareProgressFeatureName is the API command reporting the latest firmware update and log
+// upload of a charge point
+const FirmwareProgressFeatureName = "GetFirmwareProgress"
+
+type progressKind string
+
+const (
+	progressFirmware progressKind = "firmware update"
+	progressLog      progressKind = "log upload"
+	// stepRequested is recorded when the central system builds the command, before any answer
+	stepRequested = "Requested"
+)
+
+// failedSteps are the statuses, of 1.6 and 2.0.1 alike, that end an update or upload without success;
+// Rejected and the certificate statuses are answers to the command itself
+var failedSteps = map[string]bool{
+	"Rejected":                  true,
+	"InvalidCertificate":        true,
+	"RevokedCertificate":        true,
+	"DownloadFailed":            true,
+	"InstallationFailed":        true,
+	"InstallVerificationFailed": true,
+	"InvalidSignature":          true,
+	"UploadFailed":              true,
+	"UploadFailure":             true,
+	"BadMessage":                true,
+	"PermissionDenied":          true,
+	"NotSupportedOperation":     true,
+}
+
+// ProgressStep is one status of an update or upload, as reported by the charge point
+type ProgressStep struct {
+	Status string    `json:"status"`
+	Time   time.Time `json:"time"`
+}
+
+// Progress follows one firmware update or log upload, step by step
+type Progress struct {
+	RequestId *int           `json:"request_id,omitempty"`
+	Status    string         `json:"status"`
+	Failed    bool           `json:"failed"`
+	Steps     []ProgressStep `json:"steps"`
+}
+
+// FirmwareProgress is the latest firmware update and log upload of a charge point; diagnostics
+// uploads of 1.6 count as log uploads
+type FirmwareProgress struct {
+	ChargePointId string    `json:"charge_point_id"`
+	Firmware      *Progress `json:"firmware,omitempty"`
+	Log           *Progress `json:"log,omitempty"`
+}
+
+// GetFirmwareProgress returns a copy of the progress kept for a charge point
+func (h *SystemHandler) GetFirmwareProgress(chargePointId string) (*FirmwareProgress, error) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	state, ok := h.getChargePoint(chargePointId)
+	if !ok {
+		return nil, fmt.Errorf("charge point not found")
+	}
+	return &FirmwareProgress{
+		ChargePointId: chargePointId,
+		Firmware:      state.firmwareProgress.copy(),
+		Log:           state.logProgress.copy(),
+	}, nil
+}
+
+/*
+recordProgress adds a step to the firmware update or log upload of a charge point, and passes it on
+to the event listeners: as an alert when the step is a failure, as information otherwise. A command
+being sent, or a step naming another request id, starts a new record; steps without a request id, as
+1.6 sends them, belong to the current one.
+*/
+func (h *SystemHandler) recordProgress(chargePointId string, kind progressKind, requestId *int, status string) {
+	now := h.getTime()
+	h.mux.Lock()
+	state, ok := h.getChargePoint(chargePointId)
+	if !ok {
+		h.mux.Unlock()
+		return
+	}
+	current := &state.firmwareProgress
+	if kind == progressLog {
+		current = &state.logProgress
+	}
+	progress := *current
+	if progress == nil || status == stepRequested ||
+		(requestId != nil && (progress.RequestId == nil || *progress.RequestId != *requestId)) {
+		progress = &Progress{RequestId: requestId}
+		*current = progress
+	}
+	progress.Status = status
+	progress.Failed = failedSteps[status]
+	progress.Steps = append(progress.Steps, ProgressStep{Status: status, Time: now})
+	failed := progress.Failed
+	h.mux.Unlock()
+
+	info := fmt.Sprintf("%s: %s %s", chargePointId, kind, status)
+	if requestId != nil {
+		info = fmt.Sprintf("%s; request #%d", info, *requestId)
+	}
+	eventMessage := &internal.EventMessage{
+		ChargePointId: chargePointId,
+		Time:          now,
+		Status:        status,
+		Info:          info,
+	}
+	if failed {
+		go h.notifyEventListeners(internal.Alert, eventMessage)
+	} else {
+		go h.notifyEventListeners(internal.Information, eventMessage)
+	}
+}
+
+func (p *Progress) copy() *Progress {
+	if p == nil {
+		return nil
+	}
+	c := *p
+	c.Steps = append([]ProgressStep(nil), p.Steps...)
+	return &c
+}
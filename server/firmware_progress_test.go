@@ -0,0 +1,109 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"evsys/internal"
+	"evsys/ocpp/v16/firmware"
+	"evsys/ocpp/v16/security"
+	firmware201 "evsys/ocpp/v201/firmware"
+)
+
+// progressListener passes on the alerts and information it is notified of.
+type progressListener struct {
+	internal.EventHandler
+	events chan *internal.EventMessage
+	alerts chan *internal.EventMessage
+}
+
+func (l *progressListener) OnInfo(event *internal.EventMessage)  { l.events <- event }
+func (l *progressListener) OnAlert(event *internal.EventMessage) { l.alerts <- event }
+
+func TestFirmwareUpdate201IsFollowedStepByStep(t *testing.T) {
+	h := newSecurityHandler()
+	h.protocolAdapter = NewProtocolAdapter()
+	listener := &progressListener{events: make(chan *internal.EventMessage, 8), alerts: make(chan *internal.EventMessage, 8)}
+	h.eventListeners = append(h.eventListeners, listener)
+	handlers := NewV201Handlers(h, stopStubLogger{})
+
+	built, err := handlers.OnUpdateFirmware("CP1", `{"location":"https://firmware.example.com/fw.bin","signingCertificate":"-----BEGIN CERTIFICATE-----","signature":"c2ln"}`)
+	if err != nil {
+		t.Fatalf("OnUpdateFirmware: %v", err)
+	}
+	request := built.(*firmware201.UpdateFirmwareRequest)
+	if request.RequestId == 0 || request.Firmware.Signature != "c2ln" || request.Firmware.RetrieveDateTime.IsZero() {
+		t.Fatalf("built %+v, want a signed update retrieved now", request)
+	}
+	if err = handlers.HandleResponse("CP1", request, []byte(`{"status":"Accepted"}`)); err != nil {
+		t.Fatalf("HandleResponse: %v", err)
+	}
+	requestId := request.RequestId
+	for _, status := range []firmware201.FirmwareStatusType{firmware201.FirmwareStatusDownloading, firmware201.FirmwareStatusInvalidSignature} {
+		_, err = handlers.OnFirmwareStatusNotification("CP1", &firmware201.FirmwareStatusNotificationRequest{Status: status, RequestId: &requestId})
+		if err != nil {
+			t.Fatalf("OnFirmwareStatusNotification: %v", err)
+		}
+	}
+
+	progress, err := h.GetFirmwareProgress("CP1")
+	if err != nil {
+		t.Fatalf("GetFirmwareProgress: %v", err)
+	}
+	steps := progress.Firmware.Steps
+	if len(steps) != 4 || steps[0].Status != stepRequested || steps[1].Status != "Accepted" || steps[3].Status != "InvalidSignature" {
+		t.Errorf("steps %+v, want Requested, Accepted, Downloading, InvalidSignature", steps)
+	}
+	if !progress.Firmware.Failed || *progress.Firmware.RequestId != requestId || progress.Log != nil {
+		t.Errorf("progress %+v, want the failed update of request %d only", progress.Firmware, requestId)
+	}
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-listener.events:
+		case <-time.After(time.Second):
+			t.Fatalf("%d steps passed on, want 3", i)
+		}
+	}
+	select {
+	case alert := <-listener.alerts:
+		if alert.Status != "InvalidSignature" {
+			t.Errorf("alert %+v, want the invalid signature", alert)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("failure not alerted")
+	}
+}
+
+func TestProgress16WithoutRequestIdContinuesRecord(t *testing.T) {
+	h := newSecurityHandler()
+
+	if _, err := h.OnUpdateFirmware("CP1", `{"location":"ftp://host/fw.bin"}`); err != nil {
+		t.Fatalf("OnUpdateFirmware: %v", err)
+	}
+	for _, status := range []firmware.Status{firmware.StatusDownloading, firmware.StatusDownloaded, firmware.StatusInstalled} {
+		_, _ = h.OnFirmwareStatusNotification("CP1", &firmware.StatusNotificationRequest{Status: status})
+	}
+	log, err := h.OnGetLog("CP1", `{"logType":"SecurityLog","remoteLocation":"ftp://host/logs"}`)
+	if err != nil {
+		t.Fatalf("OnGetLog: %v", err)
+	}
+	h.OnGetLogResponse("CP1", log, &security.GetLogResponse{Status: security.LogStatusRejected})
+
+	progress, _ := h.GetFirmwareProgress("CP1")
+	if len(progress.Firmware.Steps) != 4 || progress.Firmware.Status != string(firmware.StatusInstalled) || progress.Firmware.Failed {
+		t.Errorf("firmware %+v, want four steps up to Installed", progress.Firmware)
+	}
+	if progress.Log.Status != "Rejected" || !progress.Log.Failed || *progress.Log.RequestId != log.RequestId {
+		t.Errorf("log %+v, want request %d rejected", progress.Log, log.RequestId)
+	}
+
+	// a new command starts over
+	if _, err = h.OnUpdateFirmware("CP1", `{"location":"ftp://host/fw2.bin"}`); err != nil {
+		t.Fatalf("OnUpdateFirmware: %v", err)
+	}
+	progress, _ = h.GetFirmwareProgress("CP1")
+	if len(progress.Firmware.Steps) != 1 {
+		t.Errorf("steps %+v, want a fresh record", progress.Firmware.Steps)
+	}
+}
@@ -0,0 +1,446 @@
+package server
+
+import (
+	"encoding/json"
+	"evsys/entity"
+	"evsys/ocpp"
+	"evsys/ocpp/common"
+	"evsys/ocpp/v16/core"
+	"evsys/ocpp/v16/localauth"
+	"evsys/ocpp/v201"
+	localauth201 "evsys/ocpp/v201/localauth"
+	"evsys/ocpp/v201/provisioning"
+	"evsys/types"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	// localListTimeout bounds each request of a sync; storing a long list takes some chargers a while
+	localListTimeout = 30 * time.Second
+	// sendLocalListMaxLengthKey limits the entries in a single SendLocalList
+	sendLocalListMaxLengthKey = "SendLocalListMaxLength"
+	// defaultSendLocalListMaxLength applies when a charge point does not report its limit; small
+	// enough for any charger seen so far
+	defaultSendLocalListMaxLength = 20
+	// maxIdTagLength and maxIdTokenLength bound the id tags that fit on a 1.6 and a 2.0.1 list
+	maxIdTagLength   = 20
+	maxIdTokenLength = 36
+)
+
+// localListProtocol carries the messages of a local list sync in the protocol of the charge point.
+// The sync itself, versions and differences, is the same for both.
+type localListProtocol interface {
+	getVersion(chargePointId string) (int, error)
+	maxLength(chargePointId string) int
+	maxIdLength() int
+	send(chargePointId string, version int, updateType localauth.UpdateType, changes []localListChange) (localauth.UpdateStatus, error)
+}
+
+// localListChange is one entry of a differential update; a nil entry removes the id tag.
+type localListChange struct {
+	idTag string
+	entry *entity.LocalAuthEntry
+}
+
+func (h *SystemHandler) OnGetLocalListVersion(chargePointId string) (*localauth.GetLocalListVersionRequest, error) {
+	_, ok := h.getChargePoint(chargePointId)
+	if !ok {
+		return nil, fmt.Errorf("charge point not found")
+	}
+	request := localauth.NewGetLocalListVersionRequest()
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, "")
+	return request, nil
+}
+
+/*
+SyncLocalList brings the charge point's local authorization list in line with the stored user tags.
+
+The charge point is asked for its list version first. When it matches the version of the list stored
+after the last accepted update, only the differences are sent: new and changed tags, and deletions for
+tags no longer meant to be there. Otherwise, or when full is set, the whole list is sent. The list is
+split to respect SendLocalListMaxLength, and every accepted message is stored with its version right
+away, so an interrupted sync leaves the stored list matching the charge point. A VersionMismatch on a
+differential update falls back to a full one.
+
+A 2.0.1 charge point gets the same sync with its own messages, split by its ItemsPerMessage.
+*/
+func (h *SystemHandler) SyncLocalList(chargePointId string, full bool) (*localauth.SyncResult, error) {
+	h.mux.Lock()
+	state, ok := h.getChargePoint(chargePointId)
+	var protocol localListProtocol = localList16{h}
+	if ok && common.ProtocolVersion(state.model.ProtocolVersion).IsOCPP2() {
+		protocol = localList201{h}
+	}
+	h.mux.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("charge point not found")
+	}
+	if h.server == nil || h.database == nil {
+		return nil, fmt.Errorf("local list sync is not available")
+	}
+	if !h.beginLocalListSync(chargePointId) {
+		return nil, fmt.Errorf("local list sync is already running")
+	}
+	defer h.endLocalListSync(chargePointId)
+
+	version, err := protocol.getVersion(chargePointId)
+	if err != nil {
+		return nil, err
+	}
+	if version == localauth.ListVersionDisabled {
+		return nil, fmt.Errorf("local authorization list is disabled on the charge point")
+	}
+	tags, err := h.database.GetLocalUserTags()
+	if err != nil {
+		return nil, fmt.Errorf("get local user tags: %v", err)
+	}
+	desired := localListEntries(tags, h.getTime(), protocol.maxIdLength())
+	maxLength := protocol.maxLength(chargePointId)
+
+	// a missing stored list is not an error: nothing was sent to the charge point yet
+	installed, _ := h.database.GetLocalAuthList(chargePointId)
+	if !full && (installed == nil || installed.ListVersion != version) {
+		h.logger.FeatureEvent(localauth.SendLocalListFeatureName, chargePointId,
+			fmt.Sprintf("charge point has list #%d, stored list does not match; sending full list", version))
+		full = true
+	}
+	result, err := h.sendLocalList(chargePointId, protocol, version, installed, desired, full, maxLength)
+	if err == nil && !full && result.Status == localauth.UpdateStatusVersionMismatch {
+		h.logger.FeatureEvent(localauth.SendLocalListFeatureName, chargePointId, "version mismatch; sending full list")
+		result, err = h.sendLocalList(chargePointId, protocol, result.ListVersion, nil, desired, true, maxLength)
+	}
+	return result, err
+}
+
+// sendLocalList sends a full list or the changes against installed, in messages of at most
+// maxLength entries, and stores the list after every accepted message.
+func (h *SystemHandler) sendLocalList(chargePointId string, protocol localListProtocol, version int, installed *entity.LocalAuthList, desired []*entity.LocalAuthEntry, full bool, maxLength int) (*localauth.SyncResult, error) {
+	current := make(map[string]*entity.LocalAuthEntry)
+	var changes []localListChange
+	updateType := localauth.UpdateTypeDifferential
+	if full {
+		updateType = localauth.UpdateTypeFull
+		changes = make([]localListChange, 0, len(desired))
+		for _, entry := range desired {
+			changes = append(changes, localListChange{idTag: entry.IdTag, entry: entry})
+		}
+	} else {
+		for _, entry := range installed.Entries {
+			current[entry.IdTag] = entry
+		}
+		changes = localListChanges(installed.Entries, desired)
+	}
+
+	result := &localauth.SyncResult{
+		ListVersion: version,
+		UpdateType:  updateType,
+		Status:      localauth.UpdateStatusAccepted,
+	}
+	if len(changes) == 0 && !full {
+		h.logger.FeatureEvent(localauth.SendLocalListFeatureName, chargePointId, fmt.Sprintf("list #%d is up to date", version))
+		return result, nil
+	}
+
+	// a full update of an empty list still goes out once, to clear the charge point's list
+	for i := 0; i == 0 || i < len(changes); i += maxLength {
+		chunk := changes[i:min(i+maxLength, len(changes))]
+		listVersion := result.ListVersion + 1
+
+		status, err := protocol.send(chargePointId, listVersion, updateType, chunk)
+		if err != nil {
+			return result, err
+		}
+		result.Status = status
+		h.logger.FeatureEvent(localauth.SendLocalListFeatureName, chargePointId,
+			fmt.Sprintf("%s list #%d with %d entries: %s", updateType, listVersion, len(chunk), status))
+		if status != localauth.UpdateStatusAccepted {
+			return result, nil
+		}
+		result.ListVersion = listVersion
+		result.Entries += len(chunk)
+		result.Messages++
+
+		if updateType == localauth.UpdateTypeFull {
+			current = make(map[string]*entity.LocalAuthEntry)
+		}
+		for _, change := range chunk {
+			if change.entry == nil {
+				delete(current, change.idTag)
+			} else {
+				current[change.idTag] = change.entry
+			}
+		}
+		h.saveLocalAuthList(chargePointId, result.ListVersion, current)
+
+		// the rest of a split full list is added on top of its first part
+		updateType = localauth.UpdateTypeDifferential
+	}
+	return result, nil
+}
+
+// localList16 syncs the list of an OCPP 1.6 charge point
+type localList16 struct {
+	h *SystemHandler
+}
+
+func (p localList16) maxIdLength() int {
+	return maxIdTagLength
+}
+
+func (p localList16) send(chargePointId string, version int, updateType localauth.UpdateType, changes []localListChange) (localauth.UpdateStatus, error) {
+	request := localauth.NewSendLocalListRequest(version, updateType)
+	request.LocalAuthorizationList = authorizationData(changes)
+	payload, err := p.h.server.SendRequestSync(chargePointId, request, localListTimeout)
+	if err != nil {
+		return "", fmt.Errorf("send local list #%d: %v", request.ListVersion, err)
+	}
+	var response localauth.SendLocalListResponse
+	if err = json.Unmarshal([]byte(payload), &response); err != nil {
+		return "", fmt.Errorf("parse send local list response: %v", err)
+	}
+	return response.Status, nil
+}
+
+func (p localList16) getVersion(chargePointId string) (int, error) {
+	payload, err := p.h.server.SendRequestSync(chargePointId, localauth.NewGetLocalListVersionRequest(), localListTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("get local list version: %v", err)
+	}
+	var response localauth.GetLocalListVersionResponse
+	if err = json.Unmarshal([]byte(payload), &response); err != nil {
+		return 0, fmt.Errorf("parse local list version: %v", err)
+	}
+	return response.ListVersion, nil
+}
+
+// maxLength reads the charge point's limit on entries per SendLocalList, falling back to a
+// conservative default when it cannot be read.
+func (p localList16) maxLength(chargePointId string) int {
+	read := core.NewGetConfigurationRequest([]string{sendLocalListMaxLengthKey})
+	payload, err := p.h.server.SendRequestSync(chargePointId, read, localListTimeout)
+	if err != nil {
+		return defaultSendLocalListMaxLength
+	}
+	var response core.GetConfigurationResponse
+	if err = json.Unmarshal([]byte(payload), &response); err != nil {
+		return defaultSendLocalListMaxLength
+	}
+	for _, key := range response.ConfigurationKey {
+		if key.Key != sendLocalListMaxLengthKey || key.Value == nil {
+			continue
+		}
+		if length, err := strconv.Atoi(*key.Value); err == nil && length > 0 {
+			return length
+		}
+	}
+	return defaultSendLocalListMaxLength
+}
+
+func (h *SystemHandler) saveLocalAuthList(chargePointId string, version int, entries map[string]*entity.LocalAuthEntry) {
+	list := &entity.LocalAuthList{
+		ChargePointId: chargePointId,
+		ListVersion:   version,
+		Entries:       make([]*entity.LocalAuthEntry, 0, len(entries)),
+		TimeUpdated:   h.getTime(),
+	}
+	for _, entry := range entries {
+		list.Entries = append(list.Entries, entry)
+	}
+	sort.Slice(list.Entries, func(i, j int) bool { return list.Entries[i].IdTag < list.Entries[j].IdTag })
+	if err := h.database.SaveLocalAuthList(list); err != nil {
+		h.logger.Error("save local auth list", err)
+	}
+
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if state, ok := h.getChargePoint(chargePointId); ok {
+		state.model.LocalAuthVersion = version
+	}
+}
+
+func (h *SystemHandler) beginLocalListSync(chargePointId string) bool {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if h.localListSyncs == nil {
+		h.localListSyncs = make(map[string]bool)
+	}
+	if h.localListSyncs[chargePointId] {
+		return false
+	}
+	h.localListSyncs[chargePointId] = true
+	return true
+}
+
+func (h *SystemHandler) endLocalListSync(chargePointId string) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	delete(h.localListSyncs, chargePointId)
+}
+
+// localListEntries turns the stored tags into list entries, sorted by id tag. Disabled and expired
+// tags stay on the list as Blocked and Expired, so a charge point working offline refuses them
+// instead of falling back to its own cache.
+func localListEntries(tags []entity.UserTag, now time.Time, maxIdLength int) []*entity.LocalAuthEntry {
+	byTag := make(map[string]*entity.LocalAuthEntry, len(tags))
+	for i := range tags {
+		tag := &tags[i]
+		if tag.IdTag == "" || len(tag.IdTag) > maxIdLength {
+			continue
+		}
+		status := types.AuthorizationStatusAccepted
+		if !tag.IsEnabled {
+			status = types.AuthorizationStatusBlocked
+		} else if tag.IsExpiredAt(now) {
+			status = types.AuthorizationStatusExpired
+		}
+		byTag[tag.IdTag] = &entity.LocalAuthEntry{
+			IdTag:       tag.IdTag,
+			Status:      string(status),
+			ExpiryDate:  tag.ExpiryDate,
+			ParentIdTag: tag.ParentIdTag,
+		}
+	}
+	entries := make([]*entity.LocalAuthEntry, 0, len(byTag))
+	for _, entry := range byTag {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].IdTag < entries[j].IdTag })
+	return entries
+}
+
+// localListChanges lists what a differential update has to carry to turn installed into desired.
+func localListChanges(installed, desired []*entity.LocalAuthEntry) []localListChange {
+	old := make(map[string]*entity.LocalAuthEntry, len(installed))
+	for _, entry := range installed {
+		old[entry.IdTag] = entry
+	}
+	changes := make([]localListChange, 0)
+	for _, entry := range desired {
+		if previous, ok := old[entry.IdTag]; !ok || !previous.Equal(entry) {
+			changes = append(changes, localListChange{idTag: entry.IdTag, entry: entry})
+		}
+		delete(old, entry.IdTag)
+	}
+	for idTag := range old {
+		changes = append(changes, localListChange{idTag: idTag})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].idTag < changes[j].idTag })
+	return changes
+}
+
+func authorizationData(changes []localListChange) []localauth.AuthorizationData {
+	data := make([]localauth.AuthorizationData, 0, len(changes))
+	for _, change := range changes {
+		item := localauth.AuthorizationData{IdTag: change.idTag}
+		if change.entry != nil {
+			item.IdTagInfo = types.NewIdTagInfo(types.AuthorizationStatus(change.entry.Status))
+			item.IdTagInfo.ParentIdTag = change.entry.ParentIdTag
+			if change.entry.ExpiryDate != nil {
+				item.IdTagInfo.ExpiryDate = types.NewDateTime(*change.entry.ExpiryDate)
+			}
+		}
+		data = append(data, item)
+	}
+	return data
+}
+
+// localList201 syncs the list of an OCPP 2.0.1 charge point. The stored user tags carry no token
+// type; they are listed as ISO14443 cards, which is what a station reads at its RFID reader.
+type localList201 struct {
+	h *SystemHandler
+}
+
+func (p localList201) maxIdLength() int {
+	return maxIdTokenLength
+}
+
+func (p localList201) send(chargePointId string, version int, updateType localauth.UpdateType, changes []localListChange) (localauth.UpdateStatus, error) {
+	request := &localauth201.SendLocalListRequest{
+		VersionNumber:          version,
+		UpdateType:             localauth201.UpdateType(updateType),
+		LocalAuthorizationList: authorizationData201(changes),
+	}
+	payload, err := p.h.server.SendRequestSync(chargePointId, request, localListTimeout)
+	if err != nil {
+		return "", fmt.Errorf("send local list #%d: %v", version, err)
+	}
+	var response localauth201.SendLocalListResponse
+	if err = json.Unmarshal([]byte(payload), &response); err != nil {
+		return "", fmt.Errorf("parse send local list response: %v", err)
+	}
+	return localauth.UpdateStatus(response.Status), nil
+}
+
+func (p localList201) getVersion(chargePointId string) (int, error) {
+	payload, err := p.h.server.SendRequestSync(chargePointId, &localauth201.GetLocalListVersionRequest{}, localListTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("get local list version: %v", err)
+	}
+	var response localauth201.GetLocalListVersionResponse
+	if err = json.Unmarshal([]byte(payload), &response); err != nil {
+		return 0, fmt.Errorf("parse local list version: %v", err)
+	}
+	return response.VersionNumber, nil
+}
+
+// maxLength reads LocalAuthListCtrlr.ItemsPerMessage, falling back to the 1.6 default
+func (p localList201) maxLength(chargePointId string) int {
+	read := &provisioning.GetVariablesRequest{GetVariableData: []provisioning.GetVariableDataType{{
+		Component: v201.Component{Name: "LocalAuthListCtrlr"},
+		Variable:  v201.Variable{Name: "ItemsPerMessage"},
+	}}}
+	payload, err := p.h.server.SendRequestSync(chargePointId, read, localListTimeout)
+	if err != nil {
+		return defaultSendLocalListMaxLength
+	}
+	var response provisioning.GetVariablesResponse
+	if err = json.Unmarshal([]byte(payload), &response); err != nil || len(response.GetVariableResult) == 0 {
+		return defaultSendLocalListMaxLength
+	}
+	result := response.GetVariableResult[0]
+	if result.AttributeStatus != provisioning.GetVariableStatusAccepted {
+		return defaultSendLocalListMaxLength
+	}
+	if length, err := strconv.Atoi(result.AttributeValue); err == nil && length > 0 {
+		return length
+	}
+	return defaultSendLocalListMaxLength
+}
+
+// authorizationData201 builds the 2.0.1 list entries: the IdTokenInfo of a user tag carries its
+// status, its expiry date as the cache expiry and its parent id tag as the group id token
+func authorizationData201(changes []localListChange) []localauth201.AuthorizationData {
+	data := make([]localauth201.AuthorizationData, 0, len(changes))
+	for _, change := range changes {
+		item := localauth201.AuthorizationData{
+			IdToken: v201.IdToken{IdToken: change.idTag, Type: v201.IdTokenTypeISO14443},
+		}
+		if change.entry != nil {
+			item.IdTokenInfo = &v201.IdTokenInfo{
+				Status:              v201.AuthorizationStatusType(change.entry.Status),
+				CacheExpiryDateTime: change.entry.ExpiryDate,
+			}
+			if change.entry.ParentIdTag != "" {
+				item.IdTokenInfo.GroupIdToken = &v201.IdToken{IdToken: change.entry.ParentIdTag, Type: v201.IdTokenTypeCentral}
+			}
+		}
+		data = append(data, item)
+	}
+	return data
+}
+
+// OnGetLocalListVersion creates a GetLocalListVersion request for OCPP 2.0.1
+func (h *V201Handlers) OnGetLocalListVersion(chargePointId string) (ocpp.Request, error) {
+	h.systemHandler.mux.Lock()
+	_, ok := h.systemHandler.getChargePoint(chargePointId)
+	h.systemHandler.mux.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("charge point not found")
+	}
+	request := &localauth201.GetLocalListVersionRequest{}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, "v2.0.1")
+	return request, nil
+}
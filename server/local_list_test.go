@@ -0,0 +1,324 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"evsys/entity"
+	"evsys/internal"
+	"evsys/ocpp"
+	"evsys/ocpp/common"
+	"evsys/ocpp/v16/core"
+	"evsys/ocpp/v16/localauth"
+	"evsys/ocpp/v201"
+	localauth201 "evsys/ocpp/v201/localauth"
+	"evsys/ocpp/v201/provisioning"
+	"evsys/types"
+)
+
+// localListDB serves the stored tags and the list stored after the last accepted update.
+type localListDB struct {
+	internal.Database
+	tags  []entity.UserTag
+	list  *entity.LocalAuthList
+	saved []*entity.LocalAuthList
+}
+
+func (s *localListDB) GetLocalUserTags() ([]entity.UserTag, error) {
+	return s.tags, nil
+}
+
+func (s *localListDB) GetLocalAuthList(_ string) (*entity.LocalAuthList, error) {
+	if s.list == nil {
+		return nil, fmt.Errorf("not found")
+	}
+	return s.list, nil
+}
+
+func (s *localListDB) SaveLocalAuthList(list *entity.LocalAuthList) error {
+	s.list = list
+	s.saved = append(s.saved, list)
+	return nil
+}
+
+// localListCharger answers the requests of a sync like a charge point holding a list of the given
+// version, in either protocol; answers overrides its answer to SendLocalList messages, in order.
+type localListCharger struct {
+	version   int
+	maxLength string
+	answers   []localauth.UpdateStatus
+	sent      []*localauth.SendLocalListRequest
+	sent201   []*localauth201.SendLocalListRequest
+}
+
+func (c *localListCharger) SendRequest(_ string, _ ocpp.Request) (string, error) {
+	return "", nil
+}
+
+func (c *localListCharger) SendRequestSync(_ string, request ocpp.Request, _ time.Duration) (string, error) {
+	var response interface{}
+	switch r := request.(type) {
+	case *localauth.GetLocalListVersionRequest:
+		response = localauth.NewGetLocalListVersionResponse(c.version)
+	case *core.GetConfigurationRequest:
+		value := c.maxLength
+		response = core.GetConfigurationResponse{
+			ConfigurationKey: []core.ConfigurationKey{{Key: sendLocalListMaxLengthKey, Value: &value}},
+		}
+	case *localauth.SendLocalListRequest:
+		c.sent = append(c.sent, r)
+		status := localauth.UpdateStatusAccepted
+		if len(c.answers) > 0 {
+			status, c.answers = c.answers[0], c.answers[1:]
+		}
+		if status == localauth.UpdateStatusAccepted {
+			c.version = r.ListVersion
+		}
+		response = localauth.NewSendLocalListResponse(status)
+	case *localauth201.GetLocalListVersionRequest:
+		response = localauth201.GetLocalListVersionResponse{VersionNumber: c.version}
+	case *provisioning.GetVariablesRequest:
+		response = provisioning.GetVariablesResponse{GetVariableResult: []provisioning.GetVariableResultType{{
+			AttributeStatus: provisioning.GetVariableStatusAccepted,
+			AttributeValue:  c.maxLength,
+			Component:       r.GetVariableData[0].Component,
+			Variable:        r.GetVariableData[0].Variable,
+		}}}
+	case *localauth201.SendLocalListRequest:
+		c.sent201 = append(c.sent201, r)
+		c.version = r.VersionNumber
+		response = localauth201.SendLocalListResponse{Status: localauth201.SendLocalListStatusAccepted}
+	default:
+		return "", fmt.Errorf("unexpected request %T", request)
+	}
+	data, err := json.Marshal(response)
+	return string(data), err
+}
+
+func newLocalListHandler(db *localListDB, charger *localListCharger) *SystemHandler {
+	h := &SystemHandler{
+		chargePoints: map[string]*ChargePointState{},
+		database:     db,
+		server:       charger,
+		logger:       stopStubLogger{},
+		location:     time.UTC,
+	}
+	h.chargePoints["CP1"] = newChargePointState(&entity.ChargePoint{Id: "CP1", IsEnabled: true})
+	return h
+}
+
+func localEntry(idTag, status string) *entity.LocalAuthEntry {
+	return &entity.LocalAuthEntry{IdTag: idTag, Status: status}
+}
+
+func TestSyncLocalListSendsDifferences(t *testing.T) {
+	expiry := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	db := &localListDB{
+		tags: []entity.UserTag{
+			{IdTag: "ALICE", IsEnabled: true, Local: true},
+			{IdTag: "BOB", IsEnabled: false, Local: true},
+			{IdTag: "DAVE", IsEnabled: true, Local: true, ExpiryDate: &expiry, ParentIdTag: "FLEET"},
+		},
+		list: &entity.LocalAuthList{
+			ChargePointId: "CP1",
+			ListVersion:   3,
+			Entries: []*entity.LocalAuthEntry{
+				localEntry("ALICE", "Accepted"),
+				localEntry("BOB", "Accepted"),
+				localEntry("CAROL", "Accepted"),
+			},
+		},
+	}
+	charger := &localListCharger{version: 3, maxLength: "10"}
+	h := newLocalListHandler(db, charger)
+
+	result, err := h.SyncLocalList("CP1", false)
+	if err != nil {
+		t.Fatalf("SyncLocalList: %v", err)
+	}
+	if len(charger.sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(charger.sent))
+	}
+	request := charger.sent[0]
+	if request.UpdateType != localauth.UpdateTypeDifferential || request.ListVersion != 4 {
+		t.Errorf("sent %s list #%d, want Differential #4", request.UpdateType, request.ListVersion)
+	}
+
+	got := make(map[string]*types.IdTagInfo)
+	for _, item := range request.LocalAuthorizationList {
+		got[item.IdTag] = item.IdTagInfo
+	}
+	if _, ok := got["ALICE"]; ok {
+		t.Error("unchanged ALICE was sent again")
+	}
+	if info := got["BOB"]; info == nil || info.Status != types.AuthorizationStatusBlocked {
+		t.Errorf("disabled BOB = %+v, want Blocked", info)
+	}
+	if info, ok := got["CAROL"]; !ok || info != nil {
+		t.Errorf("removed CAROL = %+v, want a deletion without id tag info", info)
+	}
+	info := got["DAVE"]
+	if info == nil || info.Status != types.AuthorizationStatusAccepted || info.ParentIdTag != "FLEET" ||
+		info.ExpiryDate == nil || !info.ExpiryDate.Equal(expiry) {
+		t.Errorf("new DAVE = %+v, want Accepted with parent and expiry date", info)
+	}
+
+	if result.ListVersion != 4 || result.Status != localauth.UpdateStatusAccepted || result.Entries != 3 {
+		t.Errorf("result = %+v, want version 4 accepted with 3 entries", result)
+	}
+	if db.list.ListVersion != 4 || len(db.list.Entries) != 3 {
+		t.Errorf("stored list #%d with %d entries, want #4 with ALICE, BOB and DAVE", db.list.ListVersion, len(db.list.Entries))
+	}
+	if h.chargePoints["CP1"].model.LocalAuthVersion != 4 {
+		t.Errorf("charge point list version = %d, want 4", h.chargePoints["CP1"].model.LocalAuthVersion)
+	}
+}
+
+func TestSyncLocalListSplitsFullUpdate(t *testing.T) {
+	db := &localListDB{
+		list: &entity.LocalAuthList{ChargePointId: "CP1", ListVersion: 3},
+	}
+	for _, idTag := range []string{"A", "B", "C", "D", "E"} {
+		db.tags = append(db.tags, entity.UserTag{IdTag: idTag, IsEnabled: true, Local: true})
+	}
+	// the charge point was reset or updated elsewhere: its version does not match the stored one
+	charger := &localListCharger{version: 7, maxLength: "2"}
+	h := newLocalListHandler(db, charger)
+
+	result, err := h.SyncLocalList("CP1", false)
+	if err != nil {
+		t.Fatalf("SyncLocalList: %v", err)
+	}
+
+	want := []struct {
+		updateType localauth.UpdateType
+		version    int
+		entries    int
+	}{
+		{localauth.UpdateTypeFull, 8, 2},
+		{localauth.UpdateTypeDifferential, 9, 2},
+		{localauth.UpdateTypeDifferential, 10, 1},
+	}
+	if len(charger.sent) != len(want) {
+		t.Fatalf("sent %d messages, want %d", len(charger.sent), len(want))
+	}
+	for i, w := range want {
+		got := charger.sent[i]
+		if got.UpdateType != w.updateType || got.ListVersion != w.version || len(got.LocalAuthorizationList) != w.entries {
+			t.Errorf("message %d = %s #%d with %d entries, want %s #%d with %d",
+				i, got.UpdateType, got.ListVersion, len(got.LocalAuthorizationList), w.updateType, w.version, w.entries)
+		}
+	}
+	if result.ListVersion != 10 || result.Messages != 3 {
+		t.Errorf("result = %+v, want version 10 after 3 messages", result)
+	}
+	if len(db.saved) != 3 || db.list.ListVersion != 10 || len(db.list.Entries) != 5 {
+		t.Errorf("stored list #%d with %d entries after %d saves, want #10 with 5 after 3",
+			db.list.ListVersion, len(db.list.Entries), len(db.saved))
+	}
+}
+
+func TestSyncLocalListFallsBackToFullOnVersionMismatch(t *testing.T) {
+	db := &localListDB{
+		tags: []entity.UserTag{{IdTag: "ALICE", IsEnabled: true, Local: true}},
+		list: &entity.LocalAuthList{ChargePointId: "CP1", ListVersion: 2},
+	}
+	charger := &localListCharger{
+		version:   2,
+		maxLength: "10",
+		answers:   []localauth.UpdateStatus{localauth.UpdateStatusVersionMismatch},
+	}
+	h := newLocalListHandler(db, charger)
+
+	result, err := h.SyncLocalList("CP1", false)
+	if err != nil {
+		t.Fatalf("SyncLocalList: %v", err)
+	}
+	if len(charger.sent) != 2 || charger.sent[1].UpdateType != localauth.UpdateTypeFull {
+		t.Fatalf("sent %d messages, want a differential followed by a full update", len(charger.sent))
+	}
+	if result.Status != localauth.UpdateStatusAccepted || result.UpdateType != localauth.UpdateTypeFull {
+		t.Errorf("result = %+v, want an accepted full update", result)
+	}
+}
+
+func TestSyncLocalListKeepsVersionWhenRefused(t *testing.T) {
+	db := &localListDB{
+		tags: []entity.UserTag{{IdTag: "ALICE", IsEnabled: true, Local: true}},
+	}
+	charger := &localListCharger{
+		version:   5,
+		maxLength: "10",
+		answers:   []localauth.UpdateStatus{localauth.UpdateStatusFailed},
+	}
+	h := newLocalListHandler(db, charger)
+
+	result, err := h.SyncLocalList("CP1", false)
+	if err != nil {
+		t.Fatalf("SyncLocalList: %v", err)
+	}
+	if result.Status != localauth.UpdateStatusFailed || result.ListVersion != 5 {
+		t.Errorf("result = %+v, want Failed at the charge point's version 5", result)
+	}
+	if len(db.saved) != 0 {
+		t.Errorf("a refused list was stored as version %d", db.saved[0].ListVersion)
+	}
+}
+
+func TestSyncLocalListOnOCPP201(t *testing.T) {
+	expiry := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	db := &localListDB{
+		tags: []entity.UserTag{
+			{IdTag: "ALICE", IsEnabled: true, Local: true},
+			{IdTag: "BOB", IsEnabled: false, Local: true},
+			{IdTag: "DAVE", IsEnabled: true, Local: true, ExpiryDate: &expiry, ParentIdTag: "FLEET"},
+			{IdTag: "04A2B3C4D5E6F708A2B3C4D5E6F7", IsEnabled: true, Local: true},
+		},
+	}
+	charger := &localListCharger{version: 0, maxLength: "3"}
+	h := newLocalListHandler(db, charger)
+	h.chargePoints["CP1"].model.ProtocolVersion = string(common.OCPP201)
+
+	result, err := h.SyncLocalList("CP1", false)
+	if err != nil {
+		t.Fatalf("SyncLocalList: %v", err)
+	}
+	if len(charger.sent) != 0 {
+		t.Fatalf("sent %d 1.6 messages to a 2.0.1 station", len(charger.sent))
+	}
+	// four entries in messages of at most three; the 28 character id fits a 2.0.1 list
+	if len(charger.sent201) != 2 {
+		t.Fatalf("sent %d messages, want 2", len(charger.sent201))
+	}
+	if first := charger.sent201[0]; first.UpdateType != localauth201.UpdateTypeFull || first.VersionNumber != 1 {
+		t.Errorf("first message is %s #%d, want Full #1", first.UpdateType, first.VersionNumber)
+	}
+	if result.Status != localauth.UpdateStatusAccepted || result.ListVersion != 2 {
+		t.Errorf("result = %+v, want accepted at version 2", result)
+	}
+
+	got := make(map[string]localauth201.AuthorizationData)
+	for _, request := range charger.sent201 {
+		for _, item := range request.LocalAuthorizationList {
+			got[item.IdToken.IdToken] = item
+		}
+	}
+	if item := got["BOB"]; item.IdTokenInfo == nil || item.IdTokenInfo.Status != v201.AuthorizationStatusBlocked {
+		t.Errorf("disabled BOB = %+v, want Blocked", item.IdTokenInfo)
+	}
+	dave := got["DAVE"]
+	if dave.IdToken.Type != v201.IdTokenTypeISO14443 {
+		t.Errorf("DAVE token type = %s, want ISO14443", dave.IdToken.Type)
+	}
+	if dave.IdTokenInfo == nil || dave.IdTokenInfo.CacheExpiryDateTime == nil || !dave.IdTokenInfo.CacheExpiryDateTime.Equal(expiry) {
+		t.Errorf("DAVE token info = %+v, want the tag's expiry as cache expiry", dave.IdTokenInfo)
+	}
+	if dave.IdTokenInfo == nil || dave.IdTokenInfo.GroupIdToken == nil || dave.IdTokenInfo.GroupIdToken.IdToken != "FLEET" {
+		t.Errorf("DAVE token info = %+v, want FLEET as group id token", dave.IdTokenInfo)
+	}
+	if _, ok := got["04A2B3C4D5E6F708A2B3C4D5E6F7"]; !ok {
+		t.Error("a 28 character id token was left off the 2.0.1 list")
+	}
+}
@@ -2,10 +2,9 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"evsys/ocpp"
 	"evsys/ocpp/common"
-	"evsys/ocpp/v16/core"
-	"evsys/ocpp/v16/firmware"
 	"evsys/utility"
 	"fmt"
 	"log"
@@ -54,6 +53,92 @@ func CreateCallResult(confirmation ocpp.Response, uniqueId string) (*CallResult,
 	return &callResult, nil
 }
 
+// CallError An OCPP-J CallError message, answering a request that could not be handled.
+type CallError struct {
+	TypeId           CallType
+	UniqueId         string
+	ErrorCode        ocpp.ErrorCode
+	ErrorDescription string
+	ErrorDetails     interface{}
+}
+
+func (callError *CallError) MarshalJSON() ([]byte, error) {
+	fields := make([]interface{}, 5)
+	fields[0] = int(callError.TypeId)
+	fields[1] = callError.UniqueId
+	fields[2] = callError.ErrorCode
+	fields[3] = callError.ErrorDescription
+	// errorDetails is an object, empty rather than null when there is nothing to add
+	fields[4] = callError.ErrorDetails
+	if fields[4] == nil {
+		fields[4] = struct{}{}
+	}
+	return json.Marshal(fields)
+}
+
+// CreateCallError answers a request with its error, in the error codes of the protocol: OCPP 2.x
+// corrected the spelling of two of them.
+func CreateCallError(err *ocpp.CallError, uniqueId string, protocol common.ProtocolVersion) *CallError {
+	code := err.ErrorCode
+	if protocol.IsOCPP2() {
+		switch code {
+		case ocpp.FormationViolation:
+			code = ocpp.FormatViolation
+		case ocpp.OccurenceConstraintViolation:
+			code = ocpp.OccurrenceConstraintViolation
+		}
+	}
+	return &CallError{
+		TypeId:           CallTypeError,
+		UniqueId:         uniqueId,
+		ErrorCode:        code,
+		ErrorDescription: err.ErrorDescription,
+		ErrorDetails:     err.ErrorDetails,
+	}
+}
+
+// ParseCallError reads a CallError a charge point answered one of our requests with
+func ParseCallError(data []interface{}) (*CallError, error) {
+	typeId, err := MessageType(data)
+	if err != nil {
+		return nil, err
+	}
+	if typeId != CallTypeError {
+		return nil, fmt.Errorf("invalid message type id: %v", typeId)
+	}
+	if len(data) < 4 {
+		return nil, fmt.Errorf("unsupported error format; expected at least 4 elements")
+	}
+	uniqueId, ok := data[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid message unique id in error")
+	}
+	code, ok := data[2].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid error code in error")
+	}
+	description, _ := data[3].(string)
+	callError := CallError{
+		TypeId:           typeId,
+		UniqueId:         uniqueId,
+		ErrorCode:        ocpp.ErrorCode(code),
+		ErrorDescription: description,
+	}
+	if len(data) > 4 {
+		callError.ErrorDetails = data[4]
+	}
+	return &callError, nil
+}
+
+// Err is the error the CallError reports to whoever sent the request
+func (callError *CallError) Err() *ocpp.CallError {
+	return &ocpp.CallError{
+		ErrorCode:        callError.ErrorCode,
+		ErrorDescription: callError.ErrorDescription,
+		ErrorDetails:     callError.ErrorDetails,
+	}
+}
+
 type CallRequest struct {
 	TypeId   CallType
 	UniqueId string
@@ -99,37 +184,9 @@ func MessageType(data []interface{}) (CallType, error) {
 	return typeId, nil
 }
 
+// ParseRequest parses an OCPP 1.6 request, with the types registered for the 1.6 features
 func ParseRequest(data []interface{}) (*CallRequest, error) {
-	typeId, err := MessageType(data)
-	if err != nil {
-		return nil, err
-	}
-	if typeId != CallTypeRequest {
-		return nil, fmt.Errorf("invalid request type id: %v", typeId)
-	}
-	if len(data) != 4 {
-		return nil, fmt.Errorf("unsupported request format; expected length: 4 elements")
-	}
-	uniqueId, ok := data[1].(string)
-	if !ok {
-		return nil, fmt.Errorf("invalid message unique id in request")
-	}
-	action := data[2].(string)
-	requestType, err := getMessageType(action)
-	if err != nil {
-		return nil, err
-	}
-	request, err := ParseRawJsonRequest(data[3], requestType)
-	if err != nil {
-		return nil, err
-	}
-	callRequest := CallRequest{
-		TypeId:   typeId,
-		UniqueId: uniqueId,
-		feature:  action,
-		Payload:  request,
-	}
-	return &callRequest, nil
+	return ParseRequestVersionAware(data, common.OCPP16, common.GetGlobalRegistry())
 }
 
 func ParseResultUnchecked(data []interface{}) (*CallResultUnchecked, error) {
@@ -159,72 +216,56 @@ func ParseResultUnchecked(data []interface{}) (*CallResultUnchecked, error) {
 	return &callResult, nil
 }
 
-func getMessageType(action string) (requestType reflect.Type, err error) {
-	switch action {
-	case core.BootNotificationFeatureName:
-		requestType = reflect.TypeOf(core.BootNotificationRequest{})
-	case core.AuthorizeFeatureName:
-		requestType = reflect.TypeOf(core.AuthorizeRequest{})
-	case core.HeartbeatFeatureName:
-		requestType = reflect.TypeOf(core.HeartbeatRequest{})
-	case core.StartTransactionFeatureName:
-		requestType = reflect.TypeOf(core.StartTransactionRequest{})
-	case core.StopTransactionFeatureName:
-		requestType = reflect.TypeOf(core.StopTransactionRequest{})
-	case core.MeterValuesFeatureName:
-		requestType = reflect.TypeOf(core.MeterValuesRequest{})
-	case core.StatusNotificationFeatureName:
-		requestType = reflect.TypeOf(core.StatusNotificationRequest{})
-	case core.DataTransferFeatureName:
-		requestType = reflect.TypeOf(core.DataTransferRequest{})
-	case firmware.DiagnosticsStatusNotificationFeatureName:
-		requestType = reflect.TypeOf(firmware.DiagnosticsStatusNotificationRequest{})
-	case firmware.StatusNotificationFeatureName:
-		requestType = reflect.TypeOf(firmware.StatusNotificationRequest{})
-	default:
-		return nil, fmt.Errorf("unsupported action requested: %s", action)
-	}
-	return requestType, nil
-}
-
 func ParseRawJsonRequest(raw interface{}, requestType reflect.Type) (ocpp.Request, error) {
 	if raw == nil {
 		raw = &struct{}{}
 	}
 	bytes, err := json.Marshal(raw)
 	if err != nil {
-		return nil, err
+		return nil, ocpp.NewCallError(ocpp.FormationViolation, "%s", err)
 	}
 	request := reflect.New(requestType).Interface()
 	err = json.Unmarshal(bytes, &request)
 	if err != nil {
 		log.Printf("bytes: %v", string(bytes))
-		return nil, err
+		var typeError *json.UnmarshalTypeError
+		if errors.As(err, &typeError) {
+			return nil, ocpp.NewCallError(ocpp.TypeConstraintViolation, "%s", err)
+		}
+		return nil, ocpp.NewCallError(ocpp.FormationViolation, "%s", err)
 	}
 	result := request.(ocpp.Request)
 
 	// Schema validation: reject malformed payloads before they reach the handlers
 	if v, ok := result.(interface{ Validate() error }); ok {
 		if err = v.Validate(); err != nil {
-			return nil, fmt.Errorf("validation failed: %w", err)
+			return nil, ocpp.NewCallError(validationErrorCode(err), "validation failed: %s", err)
 		}
 	}
 
 	return result, nil
 }
 
+// validationErrorCode tells a missing field, which Validate reports with common.ErrRequired, from a
+// value out of its bounds
+func validationErrorCode(err error) ocpp.ErrorCode {
+	if errors.Is(err, common.ErrRequired) {
+		return ocpp.OccurenceConstraintViolation
+	}
+	return ocpp.PropertyConstraintViolation
+}
+
 // ParseRequestVersionAware parses an OCPP request using the version-aware feature registry
-// This function replaces the hardcoded getMessageType() with dynamic type lookup
 func ParseRequestVersionAware(data []interface{}, protocol common.ProtocolVersion, registry common.FeatureRegistry) (*CallRequest, error) {
 	typeId, err := MessageType(data)
 	if err != nil {
-		return nil, err
+		return nil, ocpp.NewCallError(ocpp.FormationViolation, "%s", err)
 	}
 	if typeId != CallTypeRequest {
-		return nil, fmt.Errorf("invalid request type id: %v", typeId)
+		return nil, ocpp.NewCallError(ocpp.FormationViolation, "invalid request type id: %v", typeId)
 	}
 	if len(data) != 4 {
-		return nil, fmt.Errorf("unsupported request format; expected length: 4 elements")
+		return nil, ocpp.NewCallError(ocpp.FormationViolation, "unsupported request format; expected length: 4 elements")
 	}
 	uniqueId, ok := data[1].(string)
 	if !ok {
@@ -232,13 +273,13 @@ func ParseRequestVersionAware(data []interface{}, protocol common.ProtocolVersio
 	}
 	action, ok := data[2].(string)
 	if !ok {
-		return nil, fmt.Errorf("invalid action in request")
+		return nil, ocpp.NewCallError(ocpp.FormationViolation, "invalid action in request")
 	}
 
 	// Use the registry to get the request type for this protocol version and action
 	requestType, _, err := registry.GetTypes(protocol, action)
 	if err != nil {
-		return nil, fmt.Errorf("feature %s not supported for protocol %s: %w", action, protocol, err)
+		return nil, ocpp.NewCallError(ocpp.NotImplemented, "feature %s not supported for protocol %s: %s", action, protocol, err)
 	}
 
 	// Parse the payload using the dynamically retrieved type
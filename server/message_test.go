@@ -0,0 +1,99 @@
+package server
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"evsys/ocpp"
+	"evsys/ocpp/common"
+	"evsys/ocpp/v16/core"
+	"evsys/ocpp/v21/smartcharging"
+	"evsys/ocpp/v21/tariff"
+	"evsys/utility"
+)
+
+// A request that cannot be handled is answered with the CallError its failure calls for.
+func TestParseRequestErrorCodes(t *testing.T) {
+	registry := common.NewFeatureRegistry()
+	registry.RegisterFeature(common.OCPP16, core.StartTransactionFeatureName,
+		reflect.TypeOf(core.StartTransactionRequest{}), reflect.TypeOf(core.StartTransactionResponse{}))
+	registry.RegisterFeature(common.OCPP21, smartcharging.SetChargingProfileFeatureName,
+		reflect.TypeOf(smartcharging.SetChargingProfileRequest{}), reflect.TypeOf(smartcharging.SetChargingProfileResponse{}))
+	registry.RegisterFeature(common.OCPP21, tariff.ClearTariffsFeatureName,
+		reflect.TypeOf(tariff.ClearTariffsRequest{}), reflect.TypeOf(tariff.ClearTariffsResponse{}))
+
+	tests := []struct {
+		name     string
+		protocol common.ProtocolVersion
+		message  string
+		code     ocpp.ErrorCode
+	}{
+		{"unknown action", common.OCPP16, `[2,"1","Unknown",{}]`, ocpp.NotImplemented},
+		{"missing payload", common.OCPP16, `[2,"1","StartTransaction"]`, ocpp.FormationViolation},
+		{"field of the wrong type", common.OCPP16, `[2,"1","StartTransaction",{"connectorId":"one","idTag":"ABC","meterStart":0}]`, ocpp.TypeConstraintViolation},
+		{"missing required field", common.OCPP16, `[2,"1","StartTransaction",{"connectorId":1,"meterStart":0}]`, ocpp.OccurenceConstraintViolation},
+		{"value out of bounds", common.OCPP16, `[2,"1","StartTransaction",{"connectorId":0,"idTag":"ABC","meterStart":0}]`, ocpp.PropertyConstraintViolation},
+		{"field required by another", common.OCPP21, `[2,"1","SetChargingProfile",{"evseId":0,"chargingProfile":{"id":1,"stackLevel":0,"chargingProfilePurpose":"TxDefaultProfile","chargingProfileKind":"Recurring"}}]`, ocpp.OccurenceConstraintViolation},
+		{"value out of bounds worded as required", common.OCPP21, `[2,"1","ClearTariffs",{"tariffIds":[""]}]`, ocpp.PropertyConstraintViolation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, err := utility.ParseJson([]byte(tt.message))
+			if err != nil {
+				t.Fatalf("ParseJson: %v", err)
+			}
+			_, err = ParseRequestVersionAware(message, tt.protocol, registry)
+			var callError *ocpp.CallError
+			if !errors.As(err, &callError) {
+				t.Fatalf("error %v carries no CallError", err)
+			}
+			if callError.ErrorCode != tt.code {
+				t.Errorf("code %s, want %s", callError.ErrorCode, tt.code)
+			}
+		})
+	}
+}
+
+// A payload that cannot be read is the charge point's fault, not an internal error.
+func TestParseRawJsonRequestUnreadablePayload(t *testing.T) {
+	_, err := ParseRawJsonRequest(map[string]interface{}{"idTag": make(chan int)}, reflect.TypeOf(core.AuthorizeRequest{}))
+	var callError *ocpp.CallError
+	if !errors.As(err, &callError) {
+		t.Fatalf("error %v carries no CallError", err)
+	}
+	if callError.ErrorCode != ocpp.FormationViolation {
+		t.Errorf("code %s, want %s", callError.ErrorCode, ocpp.FormationViolation)
+	}
+}
+
+func TestCreateCallError(t *testing.T) {
+	err := ocpp.NewCallError(ocpp.FormationViolation, "invalid action in request")
+
+	data, _ := CreateCallError(err, "42", common.OCPP16).MarshalJSON()
+	if want := `[4,"42","FormationViolation","invalid action in request",{}]`; string(data) != want {
+		t.Errorf("1.6 CallError %s, want %s", data, want)
+	}
+	if code := CreateCallError(err, "42", common.OCPP201).ErrorCode; code != ocpp.FormatViolation {
+		t.Errorf("2.0.1 code %s, want %s", code, ocpp.FormatViolation)
+	}
+}
+
+// A CallError answering a request reaches the caller waiting on it instead of leaving it to time out.
+func TestCallErrorResolvesPendingRequest(t *testing.T) {
+	s := &Server{pending: make(map[string]chan ocpp.Answer), fireAndForget: make(map[string]struct{})}
+	response := s.registerPending("42")
+
+	message, _ := utility.ParseJson([]byte(`[4,"42","NotSupported","GetCompositeSchedule",{}]`))
+	callError, err := ParseCallError(message)
+	if err != nil {
+		t.Fatalf("ParseCallError: %v", err)
+	}
+	if !s.ResolveError(callError.UniqueId, callError.Err()) {
+		t.Fatal("nobody was waiting on the request")
+	}
+	answer := <-response
+	if answer.Err == nil || answer.Err.ErrorCode != ocpp.NotSupported || answer.Err.ErrorDescription != "GetCompositeSchedule" {
+		t.Errorf("answer %+v, want the NotSupported CallError", answer)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"evsys/entity"
 	"evsys/internal"
 	"evsys/ocpp/v16/core"
+	"evsys/ocpp/v201"
+	"evsys/ocpp/v201/metervalues"
 	"evsys/types"
 )
 
@@ -17,6 +19,7 @@ type meterStubDB struct {
 	internal.Database
 	transaction *entity.Transaction
 	stored      []*entity.TransactionMeter
+	samples     []*entity.TransactionMeter
 }
 
 func (s *meterStubDB) GetTransaction(_ int) (*entity.Transaction, error) {
@@ -28,6 +31,11 @@ func (s *meterStubDB) AddTransactionMeterValue(m *entity.TransactionMeter) error
 	return nil
 }
 
+func (s *meterStubDB) AddSampleMeterValue(m *entity.TransactionMeter) error {
+	s.samples = append(s.samples, m)
+	return nil
+}
+
 func (s *meterStubDB) GetConnector(_ int, _ string) (*entity.Connector, error) {
 	return nil, nil
 }
@@ -41,6 +49,7 @@ func (meterStubBilling) OnTransactionFinished(*entity.Transaction) error { retur
 func (meterStubBilling) OnMeterValue(*entity.Transaction, *entity.TransactionMeter) error {
 	return nil
 }
+func (meterStubBilling) Tariff(string) *entity.PaymentPlan { return nil }
 
 type meterStubLogger struct{}
 
@@ -332,3 +341,58 @@ func TestOnMeterValuesWithoutElectricalMeasurands(t *testing.T) {
 		t.Errorf("stored value = %d, want 1500", stored.Value)
 	}
 }
+
+// TestOnMeterValues201 checks that 2.0.1 readings of a charging EVSE are recorded with its
+// transaction, and that main meter readings on EVSE 0 are kept as samples of the charge point.
+func TestOnMeterValues201(t *testing.T) {
+	db := &meterStubDB{transaction: &entity.Transaction{Id: 7, MeterStart: 1000}}
+	h := &SystemHandler{
+		chargePoints:    map[string]*ChargePointState{},
+		lastMeter:       map[int]*entity.TransactionMeter{},
+		database:        db,
+		billing:         meterStubBilling{},
+		logger:          meterStubLogger{},
+		protocolAdapter: NewProtocolAdapter(),
+	}
+	state := newChargePointState(&entity.ChargePoint{Id: "CP1"})
+	evseId := 1
+	connector := entity.NewConnector(1, "CP1")
+	connector.EvseId = &evseId
+	connector.CurrentTransactionId = 7
+	state.connectors[1] = connector
+	h.chargePoints["CP1"] = state
+	v201Handlers := NewV201Handlers(h, meterStubLogger{})
+
+	reading := func(evse int, energy float64) *metervalues.MeterValuesRequest {
+		return &metervalues.MeterValuesRequest{
+			EvseId: evse,
+			MeterValue: []v201.MeterValue{{
+				Timestamp: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+				SampledValue: []v201.SampledValue{
+					{Value: energy, Measurand: v201.MeasurandEnergyActiveImportRegister},
+					{Value: 231, Measurand: v201.MeasurandVoltage, Location: v201.LocationOutlet},
+				},
+			}},
+		}
+	}
+
+	if _, err := v201Handlers.OnMeterValues("CP1", reading(1, 4000)); err != nil {
+		t.Fatalf("OnMeterValues: %v", err)
+	}
+	if len(db.stored) != 1 || len(db.samples) != 0 {
+		t.Fatalf("stored %d transaction and %d sample values, want one transaction value", len(db.stored), len(db.samples))
+	}
+	if meter := db.stored[0]; meter.Id != 7 || meter.ConsumedEnergy != 3000 || meter.ConnectorId != 1 || meter.Voltage != 231 {
+		t.Errorf("stored %+v, want 3000 Wh consumed in transaction 7 at 231 V", meter)
+	}
+
+	if _, err := v201Handlers.OnMeterValues("CP1", reading(0, 900000)); err != nil {
+		t.Fatalf("OnMeterValues: %v", err)
+	}
+	if len(db.samples) != 1 {
+		t.Fatalf("stored %d samples, want the main meter reading", len(db.samples))
+	}
+	if sample := db.samples[0]; sample.Id != -1 || sample.ChargePointId != "CP1" || sample.ConnectorId != 0 || sample.Value != 900000 {
+		t.Errorf("sample %+v, want the main meter of CP1", sample)
+	}
+}
@@ -0,0 +1,281 @@
+package server
+
+import (
+	"encoding/json"
+	"evsys/entity"
+	"evsys/internal"
+	"evsys/metrics/counters"
+	"evsys/ocpp"
+	"evsys/ocpp/v201"
+	"evsys/ocpp/v201/monitoring"
+	"evsys/ocpp/v201/provisioning"
+	"fmt"
+	"strings"
+)
+
+// SetMonitoringSeverities sets how events of OCPP 2.0.1 monitors are graded, by the severity of the
+// monitor that raised them, 0 being the most severe: at or below alertSeverity an event is alerted,
+// at or below errorSeverity it is recorded as an error. Events of monitors the central system has not
+// seen, such as hard-wired notifications, take fallback.
+func (h *V201Handlers) SetMonitoringSeverities(alertSeverity, errorSeverity, fallback int) {
+	h.alertSeverity = alertSeverity
+	h.errorSeverity = errorSeverity
+	h.defaultSeverity = fallback
+}
+
+// ============================================================================
+// MONITORING HANDLER
+// ============================================================================
+
+// OnNotifyEvent handles OCPP 2.0.1 NotifyEvent requests. The reported value of every event goes into
+// the device model of the charge point; an alerting event that is not a clearance is then graded by
+// the severity of its monitor.
+func (h *V201Handlers) OnNotifyEvent(chargePointId string, request *monitoring.NotifyEventRequest) (*monitoring.NotifyEventResponse, error) {
+	response := &monitoring.NotifyEventResponse{}
+
+	h.systemHandler.mux.Lock()
+	state, ok := h.systemHandler.getChargePoint(chargePointId)
+	if !ok {
+		h.systemHandler.mux.Unlock()
+		return nil, fmt.Errorf("charge point not found: %s", chargePointId)
+	}
+	if state.model.DeviceModel == nil {
+		state.model.DeviceModel = make(map[string]interface{})
+	}
+	var records []*entity.ErrorData
+	var alerts []*internal.EventMessage
+	for _, event := range request.EventData {
+		key := deviceModelKey(event.Component, event.Variable)
+		state.model.DeviceModel[key] = event.ActualValue
+
+		severity := h.defaultSeverity
+		if event.VariableMonitoringId != nil {
+			if known, found := state.monitors[*event.VariableMonitoringId]; found {
+				severity = known
+			}
+		}
+		info := fmt.Sprintf("v2.0.1: event %d, %s %s=%s, severity %d", event.EventId, event.Trigger, key, event.ActualValue, severity)
+		if event.Cleared {
+			info += ", cleared"
+		}
+		h.logger.FeatureEvent(monitoring.NotifyEventFeatureName, chargePointId, info)
+
+		// delta and periodic events report values, not problems
+		if event.Trigger != monitoring.EventTriggerAlerting || event.Cleared {
+			continue
+		}
+		connectorId := 0
+		if evse := event.Component.Evse; evse != nil {
+			if evse.ConnectorId != nil {
+				connectorId = *evse.ConnectorId
+			} else if connector := evseConnector(state, evse.Id); connector != nil {
+				connectorId = connector.Id
+			}
+		}
+		if severity <= h.errorSeverity {
+			records = append(records, &entity.ErrorData{
+				Location:        state.model.LocationId,
+				ChargePointID:   chargePointId,
+				ConnectorID:     connectorId,
+				ErrorCode:       key,
+				Info:            event.TechInfo,
+				Status:          event.ActualValue,
+				Timestamp:       event.Timestamp,
+				VendorErrorCode: event.TechCode,
+			})
+		}
+		if severity <= h.alertSeverity {
+			alerts = append(alerts, &internal.EventMessage{
+				ChargePointId: chargePointId,
+				ConnectorId:   connectorId,
+				LocationId:    state.model.LocationId,
+				Time:          event.Timestamp,
+				Status:        key,
+				Info:          fmt.Sprintf("%s, severity %d %s", event.ActualValue, severity, event.TechInfo),
+			})
+		}
+	}
+	chargePoint := state.model
+	h.systemHandler.mux.Unlock()
+
+	if h.systemHandler.database != nil {
+		if err := h.systemHandler.database.UpdateChargePoint(chargePoint); err != nil {
+			h.logger.Error("update device model", err)
+		}
+	}
+	if h.deviceModel != nil {
+		h.deviceModel.OnNotifyEvent(chargePointId, request.EventData)
+	}
+	for _, data := range records {
+		code := data.VendorErrorCode
+		if code == "" {
+			code = data.ErrorCode
+		}
+		counters.ObserveError(data.Location, chargePointId, code)
+		if h.systemHandler.errorListener != nil {
+			h.systemHandler.errorListener.OnError(data)
+		}
+	}
+	for _, alert := range alerts {
+		go h.systemHandler.notifyEventListeners(internal.Alert, alert)
+	}
+	return response, nil
+}
+
+// OnNotifyMonitoringReport handles OCPP 2.0.1 NotifyMonitoringReport requests, the answer to a
+// GetMonitoringReport command. The severities of the reported monitors are kept to grade their events.
+func (h *V201Handlers) OnNotifyMonitoringReport(chargePointId string, request *monitoring.NotifyMonitoringReportRequest) (*monitoring.NotifyMonitoringReportResponse, error) {
+	count := 0
+	h.systemHandler.mux.Lock()
+	state, ok := h.systemHandler.getChargePoint(chargePointId)
+	if ok {
+		for _, data := range request.Monitor {
+			for _, monitor := range data.VariableMonitoring {
+				state.setMonitorSeverity(monitor.Id, monitor.Severity)
+				count++
+			}
+		}
+	}
+	h.systemHandler.mux.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("charge point not found: %s", chargePointId)
+	}
+	h.logger.FeatureEvent(monitoring.NotifyMonitoringReportFeatureName, chargePointId, fmt.Sprintf("v2.0.1: requestId=%d, seqNo=%d, %d monitors",
+		request.RequestId, request.SeqNo, count))
+	return &monitoring.NotifyMonitoringReportResponse{}, nil
+}
+
+// onMonitoringResponse keeps track of the monitors the charge point installed or removed on command
+func (h *V201Handlers) onMonitoringResponse(chargePointId string, request ocpp.Request, payload []byte) error {
+	switch request.(type) {
+	case *monitoring.SetVariableMonitoringRequest:
+		var response monitoring.SetVariableMonitoringResponse
+		if err := json.Unmarshal(payload, &response); err != nil {
+			return fmt.Errorf("invalid %s response", request.GetFeatureName())
+		}
+		h.systemHandler.mux.Lock()
+		defer h.systemHandler.mux.Unlock()
+		if state, ok := h.systemHandler.getChargePoint(chargePointId); ok {
+			for _, result := range response.SetMonitoringResult {
+				if result.Status == monitoring.SetMonitoringStatusAccepted && result.Id != nil {
+					state.setMonitorSeverity(*result.Id, result.Severity)
+				}
+			}
+		}
+	case *monitoring.ClearVariableMonitoringRequest:
+		var response monitoring.ClearVariableMonitoringResponse
+		if err := json.Unmarshal(payload, &response); err != nil {
+			return fmt.Errorf("invalid %s response", request.GetFeatureName())
+		}
+		h.systemHandler.mux.Lock()
+		defer h.systemHandler.mux.Unlock()
+		if state, ok := h.systemHandler.getChargePoint(chargePointId); ok {
+			for _, result := range response.ClearMonitoringResult {
+				if result.Status != monitoring.ClearMonitoringStatusRejected {
+					delete(state.monitors, result.Id)
+				}
+			}
+		}
+	case *monitoring.SetMonitoringBaseRequest:
+		var response monitoring.SetMonitoringBaseResponse
+		if err := json.Unmarshal(payload, &response); err != nil {
+			return fmt.Errorf("invalid %s response", request.GetFeatureName())
+		}
+		// which monitors survive a new base is up to the charge point; a monitoring report tells again
+		if response.Status == provisioning.GenericDeviceModelStatusAccepted {
+			h.systemHandler.mux.Lock()
+			defer h.systemHandler.mux.Unlock()
+			if state, ok := h.systemHandler.getChargePoint(chargePointId); ok {
+				state.monitors = nil
+			}
+		}
+	}
+	return nil
+}
+
+func (st *ChargePointState) setMonitorSeverity(monitorId, severity int) {
+	if st.monitors == nil {
+		st.monitors = make(map[int]int)
+	}
+	st.monitors[monitorId] = severity
+}
+
+// deviceModelKey names a variable in the device model as Component[:instance][@evse[:connector]]/Variable[:instance]
+func deviceModelKey(component v201.Component, variable v201.Variable) string {
+	var key strings.Builder
+	key.WriteString(component.Name)
+	if component.Instance != "" {
+		key.WriteString(":" + component.Instance)
+	}
+	if component.Evse != nil {
+		key.WriteString(fmt.Sprintf("@%d", component.Evse.Id))
+		if component.Evse.ConnectorId != nil {
+			key.WriteString(fmt.Sprintf(":%d", *component.Evse.ConnectorId))
+		}
+	}
+	key.WriteString("/" + variable.Name)
+	if variable.Instance != "" {
+		key.WriteString(":" + variable.Instance)
+	}
+	return key.String()
+}
+
+// ============================================================================
+// MONITORING API COMMANDS (CSMS → Charging Station)
+// ============================================================================
+
+// OnSetVariableMonitoring creates a SetVariableMonitoring request for OCPP 2.0.1 from a JSON array of
+// setMonitoringData; the severities of the accepted monitors are kept from the response
+func (h *V201Handlers) OnSetVariableMonitoring(chargePointId string, payload string) (ocpp.Request, error) {
+	var request monitoring.SetVariableMonitoringRequest
+	if err := json.Unmarshal([]byte(payload), &request.SetMonitoringData); err != nil {
+		return nil, fmt.Errorf("invalid payload")
+	}
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: %d monitors", len(request.SetMonitoringData)))
+	return &request, nil
+}
+
+// OnClearVariableMonitoring creates a ClearVariableMonitoring request for OCPP 2.0.1 from a JSON array of
+// monitor ids
+func (h *V201Handlers) OnClearVariableMonitoring(chargePointId string, payload string) (ocpp.Request, error) {
+	var request monitoring.ClearVariableMonitoringRequest
+	if err := json.Unmarshal([]byte(payload), &request.Id); err != nil {
+		return nil, fmt.Errorf("invalid payload")
+	}
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: monitors %v", request.Id))
+	return &request, nil
+}
+
+// OnSetMonitoringBase creates a SetMonitoringBase request for OCPP 2.0.1; the payload is the base
+func (h *V201Handlers) OnSetMonitoringBase(chargePointId string, payload string) (ocpp.Request, error) {
+	request := &monitoring.SetMonitoringBaseRequest{MonitoringBase: monitoring.MonitoringBaseType(payload)}
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: base=%s", payload))
+	return request, nil
+}
+
+// OnGetMonitoringReport creates a GetMonitoringReport request for OCPP 2.0.1; an empty payload asks for
+// every monitor, otherwise it is an object with monitoringCriteria and componentVariable. The monitors
+// arrive in NotifyMonitoringReport.
+func (h *V201Handlers) OnGetMonitoringReport(chargePointId string, payload string) (ocpp.Request, error) {
+	request := &monitoring.GetMonitoringReportRequest{}
+	if payload != "" {
+		if err := json.Unmarshal([]byte(payload), request); err != nil {
+			return nil, fmt.Errorf("invalid payload")
+		}
+	}
+	request.RequestId = h.systemHandler.nextRequestId()
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: requestId=%d", request.RequestId))
+	return request, nil
+}
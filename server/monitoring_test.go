@@ -0,0 +1,156 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"evsys/entity"
+	"evsys/internal"
+	"evsys/ocpp/v201"
+	"evsys/ocpp/v201/monitoring"
+)
+
+type monitoringDB struct {
+	internal.Database
+	updated []*entity.ChargePoint
+}
+
+func (s *monitoringDB) UpdateChargePoint(chargePoint *entity.ChargePoint) error {
+	s.updated = append(s.updated, chargePoint)
+	return nil
+}
+
+type errorRecorder struct {
+	errors []*entity.ErrorData
+}
+
+func (r *errorRecorder) OnError(data *entity.ErrorData) {
+	r.errors = append(r.errors, data)
+}
+
+func newMonitoringHandlers() (*V201Handlers, *monitoringDB, *errorRecorder, *alertListener) {
+	db := &monitoringDB{}
+	recorder := &errorRecorder{}
+	listener := &alertListener{alerts: make(chan *internal.EventMessage, 4)}
+	h := &SystemHandler{
+		chargePoints:    map[string]*ChargePointState{},
+		database:        db,
+		errorListener:   recorder,
+		eventListeners:  []internal.EventHandler{listener},
+		logger:          stopStubLogger{},
+		protocolAdapter: NewProtocolAdapter(),
+		location:        time.UTC,
+	}
+	h.chargePoints["CP1"] = newChargePointState(&entity.ChargePoint{Id: "CP1", LocationId: "L1"})
+	return NewV201Handlers(h, stopStubLogger{}), db, recorder, listener
+}
+
+func monitorEvent(monitorId *int, trigger monitoring.EventTriggerType, variable, value string) monitoring.EventDataType {
+	connectorId := 2
+	return monitoring.EventDataType{
+		EventId:               1,
+		Timestamp:             time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Trigger:               trigger,
+		ActualValue:           value,
+		VariableMonitoringId:  monitorId,
+		EventNotificationType: monitoring.EventNotificationCustomMonitor,
+		Component:             v201.Component{Name: "ChargingStation", Evse: &v201.EVSE{Id: 1, ConnectorId: &connectorId}},
+		Variable:              v201.Variable{Name: variable},
+	}
+}
+
+func TestNotifyEventIsGradedBySeverity(t *testing.T) {
+	handlers, db, recorder, listener := newMonitoringHandlers()
+	handlers.SetMonitoringSeverities(2, 4, 6)
+
+	// the severities of monitors come from the answer to SetVariableMonitoring
+	temperature, power := 3, 4
+	request := &monitoring.SetVariableMonitoringRequest{}
+	err := handlers.HandleResponse("CP1", request, []byte(`{"setMonitoringResult":[
+		{"id":3,"status":"Accepted","type":"UpperThreshold","severity":1,"component":{"name":"ChargingStation"},"variable":{"name":"Temperature"}},
+		{"id":4,"status":"Accepted","type":"UpperThreshold","severity":4,"component":{"name":"ChargingStation"},"variable":{"name":"Power"}}]}`))
+	if err != nil {
+		t.Fatalf("HandleResponse: %v", err)
+	}
+
+	_, err = handlers.OnNotifyEvent("CP1", &monitoring.NotifyEventRequest{
+		GeneratedAt: time.Now(),
+		EventData: []monitoring.EventDataType{
+			monitorEvent(&temperature, monitoring.EventTriggerAlerting, "Temperature", "82.5"),
+			monitorEvent(&power, monitoring.EventTriggerAlerting, "Power", "23000"),
+			monitorEvent(nil, monitoring.EventTriggerAlerting, "Voltage", "250"),
+			monitorEvent(&temperature, monitoring.EventTriggerDelta, "Current", "31"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("OnNotifyEvent: %v", err)
+	}
+
+	key := "ChargingStation@1:2/Temperature"
+	model := handlers.systemHandler.chargePoints["CP1"].model
+	if model.DeviceModel[key] != "82.5" || model.DeviceModel["ChargingStation@1:2/Current"] != "31" {
+		t.Errorf("device model %v, want every reported value", model.DeviceModel)
+	}
+	if len(db.updated) != 1 {
+		t.Errorf("device model stored %d times, want once", len(db.updated))
+	}
+	// severity 1 and 4 are errors; the unknown monitor takes 6 and the delta event reports a value only
+	if len(recorder.errors) != 2 || recorder.errors[0].ErrorCode != key || recorder.errors[0].ConnectorID != 2 {
+		t.Errorf("errors %+v, want temperature and power on connector 2", recorder.errors)
+	}
+	select {
+	case alert := <-listener.alerts:
+		if alert.Status != key {
+			t.Errorf("alert %+v, want the temperature", alert)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no alert")
+	}
+	select {
+	case alert := <-listener.alerts:
+		t.Errorf("unexpected alert %+v", alert)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// a cleared condition is only logged
+	cleared := monitorEvent(&temperature, monitoring.EventTriggerAlerting, "Temperature", "60")
+	cleared.Cleared = true
+	if _, err = handlers.OnNotifyEvent("CP1", &monitoring.NotifyEventRequest{GeneratedAt: time.Now(), EventData: []monitoring.EventDataType{cleared}}); err != nil {
+		t.Fatalf("OnNotifyEvent: %v", err)
+	}
+	if len(recorder.errors) != 2 || model.DeviceModel[key] != "60" {
+		t.Errorf("cleared event: %d errors, temperature %v", len(recorder.errors), model.DeviceModel[key])
+	}
+}
+
+func TestMonitoringReportAndClearUpdateSeverities(t *testing.T) {
+	handlers, _, _, _ := newMonitoringHandlers()
+	_, err := handlers.OnNotifyMonitoringReport("CP1", &monitoring.NotifyMonitoringReportRequest{
+		RequestId:   1,
+		GeneratedAt: time.Now(),
+		Monitor: []monitoring.MonitoringDataType{{
+			Component: v201.Component{Name: "RCD"},
+			Variable:  v201.Variable{Name: "Tripped"},
+			VariableMonitoring: []monitoring.VariableMonitoringType{
+				{Id: 10, Type: monitoring.MonitorDelta, Severity: monitoring.SeverityDanger},
+				{Id: 11, Type: monitoring.MonitorPeriodic, Severity: monitoring.SeverityInformational},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("OnNotifyMonitoringReport: %v", err)
+	}
+	state := handlers.systemHandler.chargePoints["CP1"]
+	if state.monitors[10] != monitoring.SeverityDanger || state.monitors[11] != monitoring.SeverityInformational {
+		t.Errorf("monitors %v, want the reported severities", state.monitors)
+	}
+
+	err = handlers.HandleResponse("CP1", &monitoring.ClearVariableMonitoringRequest{Id: []int{10, 11}},
+		[]byte(`{"clearMonitoringResult":[{"id":10,"status":"Accepted"},{"id":11,"status":"Rejected"}]}`))
+	if err != nil {
+		t.Fatalf("HandleResponse: %v", err)
+	}
+	if _, ok := state.monitors[10]; ok || len(state.monitors) != 1 {
+		t.Errorf("monitors %v, want only the rejected one left", state.monitors)
+	}
+}
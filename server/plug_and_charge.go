@@ -0,0 +1,186 @@
+package server
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"evsys/internal"
+	"evsys/ocpp/v201"
+	"evsys/ocpp/v201/authorization"
+	"evsys/ocpp/v201/iso15118"
+	"evsys/ocpp/v201/security"
+	"evsys/pki"
+	"fmt"
+)
+
+// ============================================================================
+// ISO 15118 PLUG & CHARGE HANDLERS
+// ============================================================================
+// A 2.0.1 charging station relays the certificate requests of an EV, and
+// asks for its own V2G certificate, here; the contract provider answers them.
+// ============================================================================
+
+// SetContractProvider enables the ISO 15118 certificate requests of OCPP 2.0.1 and the contract
+// check of Authorize.
+func (h *SystemHandler) SetContractProvider(provider pki.ContractProvider) {
+	h.contractProvider = provider
+}
+
+// OnGet15118EVCertificate passes the EXI request of the EV on to the provider; the answer is opaque
+// to the central system as it is to the charging station.
+func (h *V201Handlers) OnGet15118EVCertificate(chargePointId string, request *iso15118.Get15118EVCertificateRequest) (*iso15118.Get15118EVCertificateResponse, error) {
+	provider := h.systemHandler.contractProvider
+	if provider == nil {
+		h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, "v2.0.1: no contract provider configured; failed")
+		return &iso15118.Get15118EVCertificateResponse{Status: iso15118.Iso15118EVCertificateStatusFailed}, nil
+	}
+	exiResponse, err := provider.GetEVCertificate(request.Iso15118SchemaVersion, string(request.Action), request.ExiRequest)
+	if err != nil {
+		h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: %s failed: %v", request.Action, err))
+		return &iso15118.Get15118EVCertificateResponse{
+			Status:     iso15118.Iso15118EVCertificateStatusFailed,
+			StatusInfo: &v201.StatusInfo{ReasonCode: "ProviderError"},
+		}, nil
+	}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: %s accepted", request.Action))
+	return &iso15118.Get15118EVCertificateResponse{Status: iso15118.Iso15118EVCertificateStatusAccepted, ExiResponse: exiResponse}, nil
+}
+
+// OnGetCertificateStatus returns the OCSP response of the provider for a certificate of the chain
+// the charging station checks.
+func (h *V201Handlers) OnGetCertificateStatus(chargePointId string, request *iso15118.GetCertificateStatusRequest) (*iso15118.GetCertificateStatusResponse, error) {
+	provider := h.systemHandler.contractProvider
+	if provider == nil {
+		h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, "v2.0.1: no contract provider configured; failed")
+		return &iso15118.GetCertificateStatusResponse{Status: iso15118.GetCertificateStatusFailed}, nil
+	}
+	serial := request.OcspRequestData.SerialNumber
+	result, err := provider.GetCertificateStatus(certificateHashData(request.OcspRequestData))
+	if err != nil {
+		h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: serial %s failed: %v", serial, err))
+		return &iso15118.GetCertificateStatusResponse{
+			Status:     iso15118.GetCertificateStatusFailed,
+			StatusInfo: &v201.StatusInfo{ReasonCode: "ProviderError"},
+		}, nil
+	}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: serial %s answered", serial))
+	return &iso15118.GetCertificateStatusResponse{Status: iso15118.GetCertificateStatusAccepted, OcspResult: result}, nil
+}
+
+// OnSignCertificate accepts a request the signer for its certificate type can take: the certificate
+// authority for the connection to the central system, with the subject rules of 1.6, or the CPO
+// sub-CA of the provider for the connection to the EV. CertificateSigned follows once it is signed.
+func (h *V201Handlers) OnSignCertificate(chargePointId string, request *security.SignCertificateRequest) (*security.SignCertificateResponse, error) {
+	rejected := func(info string) (*security.SignCertificateResponse, error) {
+		h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, "v2.0.1: "+info)
+		return &security.SignCertificateResponse{Status: security.GenericStatusRejected}, nil
+	}
+	h.systemHandler.mux.Lock()
+	state, ok := h.systemHandler.getChargePoint(chargePointId)
+	h.systemHandler.mux.Unlock()
+	if !ok {
+		return rejected("unknown charge point; rejected")
+	}
+	if h.systemHandler.server == nil {
+		return rejected("no connection to send the certificate; rejected")
+	}
+
+	certificateType := request.CertificateType
+	if certificateType == "" {
+		certificateType = security.CertificateSigningUseChargingStation
+	}
+	var sign func(csr string) (string, error)
+	switch certificateType {
+	case security.CertificateSigningUseV2G:
+		if h.systemHandler.contractProvider == nil {
+			return rejected("no contract provider configured; rejected")
+		}
+		if err := checkCsr(request.Csr); err != nil {
+			return rejected(fmt.Sprintf("rejected: %v", err))
+		}
+		sign = h.systemHandler.contractProvider.SignV2GCertificate
+	default:
+		if h.systemHandler.certificateAuthority == nil {
+			return rejected("no certificate authority configured; rejected")
+		}
+		if err := checkCsrSubject(request.Csr, state.model, h.systemHandler.certificateOrganization); err != nil {
+			return rejected(fmt.Sprintf("rejected: %v", err))
+		}
+		sign = h.systemHandler.certificateAuthority.Sign
+	}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: %s accepted", certificateType))
+	go h.sendSignedCertificate(chargePointId, certificateType, request.Csr, sign)
+	return &security.SignCertificateResponse{Status: security.GenericStatusAccepted}, nil
+}
+
+func (h *V201Handlers) sendSignedCertificate(chargePointId string, certificateType security.CertificateSigningUseType, csr string, sign func(string) (string, error)) {
+	chain, err := sign(csr)
+	if err != nil {
+		h.logger.Warn(fmt.Sprintf("sign %s for %s: %v", certificateType, chargePointId, err))
+		return
+	}
+	request := &security.CertificateSignedRequest{CertificateChain: chain, CertificateType: certificateType}
+	payload, err := h.systemHandler.server.SendRequestSync(chargePointId, request, certificateSignedTimeout)
+	if err != nil {
+		h.logger.Warn(fmt.Sprintf("send signed certificate to %s: %v", chargePointId, err))
+		return
+	}
+	var response security.CertificateSignedResponse
+	if err = json.Unmarshal([]byte(payload), &response); err != nil {
+		h.logger.Warn(fmt.Sprintf("invalid %s response from %s: %s", request.GetFeatureName(), chargePointId, payload))
+		return
+	}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: %s %s", certificateType, response.Status))
+	if response.Status != security.CertificateSignedStatusAccepted {
+		eventMessage := &internal.EventMessage{
+			ChargePointId: chargePointId,
+			Time:          h.systemHandler.getTime(),
+			Status:        string(response.Status),
+			Info:          fmt.Sprintf("charge point refused its signed %s", certificateType),
+		}
+		go h.systemHandler.notifyEventListeners(internal.Alert, eventMessage)
+	}
+}
+
+// verifyContract decides on the contract certificate an Authorize request carries. Without a
+// provider there is nobody to vouch for the contract, so it is refused as if no certificate came.
+func (h *V201Handlers) verifyContract(chargePointId string, request *authorization.AuthorizeRequest) v201.AuthorizeCertificateStatusType {
+	provider := h.systemHandler.contractProvider
+	if provider == nil {
+		return v201.AuthorizeCertificateStatusNoCertificateAvailable
+	}
+	hashData := make([]pki.CertificateHashData, 0, len(request.Iso15118CertificateHashData))
+	for _, data := range request.Iso15118CertificateHashData {
+		hashData = append(hashData, certificateHashData(data))
+	}
+	status, err := provider.VerifyContract(request.IdToken.IdToken, request.Certificate, hashData)
+	if err != nil {
+		h.logger.Warn(fmt.Sprintf("verify contract %s at %s: %v", request.IdToken.IdToken, chargePointId, err))
+		return v201.AuthorizeCertificateStatusNoCertificateAvailable
+	}
+	return v201.AuthorizeCertificateStatusType(status)
+}
+
+func certificateHashData(data v201.OCSPRequestDataType) pki.CertificateHashData {
+	return pki.CertificateHashData{
+		HashAlgorithm:  string(data.HashAlgorithm),
+		IssuerNameHash: data.IssuerNameHash,
+		IssuerKeyHash:  data.IssuerKeyHash,
+		SerialNumber:   data.SerialNumber,
+		ResponderURL:   data.ResponderURL,
+	}
+}
+
+// checkCsr only makes sure a V2G request is a signed CSR; the subject of a V2G certificate is the
+// business of the CPO sub-CA.
+func checkCsr(csrPEM string) error {
+	block, _ := pem.Decode([]byte(csrPEM))
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return fmt.Errorf("no certificate request in PEM")
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("parse certificate request: %v", err)
+	}
+	return csr.CheckSignature()
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"evsys/ocpp"
+	"evsys/ocpp/v201"
+	"evsys/ocpp/v201/authorization"
+	"evsys/ocpp/v201/security"
+	"evsys/pki"
+)
+
+// stubContracts decides every contract the same way, and signs by echoing the request.
+type stubContracts struct {
+	pki.ContractProvider
+	status   pki.ContractStatus
+	verified []string
+}
+
+func (s *stubContracts) VerifyContract(emaid, _ string, _ []pki.CertificateHashData) (pki.ContractStatus, error) {
+	s.verified = append(s.verified, emaid)
+	return s.status, nil
+}
+
+func (s *stubContracts) SignV2GCertificate(csrPEM string) (string, error) {
+	return "signed " + csrPEM, nil
+}
+
+// v2gCharger accepts the 2.0.1 certificates it is sent, and passes them on.
+type v2gCharger struct {
+	signed chan *security.CertificateSignedRequest
+}
+
+func (c *v2gCharger) SendRequest(_ string, _ ocpp.Request) (string, error) {
+	return "", nil
+}
+
+func (c *v2gCharger) SendRequestSync(_ string, request ocpp.Request, _ time.Duration) (string, error) {
+	r, ok := request.(*security.CertificateSignedRequest)
+	if !ok {
+		return "", fmt.Errorf("unexpected request %T", request)
+	}
+	c.signed <- r
+	data, err := json.Marshal(security.CertificateSignedResponse{Status: security.CertificateSignedStatusAccepted})
+	return string(data), err
+}
+
+func TestAuthorizeChecksTheContractCertificate(t *testing.T) {
+	h := newSecurityHandler()
+	h.protocolAdapter = NewProtocolAdapter()
+	h.acceptTags = true
+	handlers := NewV201Handlers(h, stopStubLogger{})
+	request := &authorization.AuthorizeRequest{
+		IdToken: v201.IdToken{IdToken: "DE8ACC12E46L89", Type: v201.IdTokenTypeEMAID},
+		Iso15118CertificateHashData: []v201.OCSPRequestDataType{{
+			HashAlgorithm: v201.HashAlgorithmSHA256, IssuerNameHash: "a1", IssuerKeyHash: "b2", SerialNumber: "0f",
+		}},
+	}
+	check := func(name string, wantCertificate v201.AuthorizeCertificateStatusType, wantToken v201.AuthorizationStatusType) {
+		t.Helper()
+		response, err := handlers.OnAuthorize("CP1", request)
+		if err != nil {
+			t.Fatalf("%s: OnAuthorize: %v", name, err)
+		}
+		if response.CertificateStatus != wantCertificate || response.IdTokenInfo.Status != wantToken {
+			t.Errorf("%s: certificate %s, token %s; want %s, %s", name,
+				response.CertificateStatus, response.IdTokenInfo.Status, wantCertificate, wantToken)
+		}
+	}
+
+	check("no provider", v201.AuthorizeCertificateStatusNoCertificateAvailable, v201.AuthorizationStatusInvalid)
+
+	contracts := &stubContracts{status: pki.ContractRevoked}
+	h.SetContractProvider(contracts)
+	check("revoked", v201.AuthorizeCertificateStatusCertificateRevoked, v201.AuthorizationStatusInvalid)
+
+	contracts.status = pki.ContractAccepted
+	check("accepted", v201.AuthorizeCertificateStatusAccepted, v201.AuthorizationStatusAccepted)
+	if len(contracts.verified) != 2 || contracts.verified[1] != "DE8ACC12E46L89" {
+		t.Errorf("verified %v, want the eMAID of the token", contracts.verified)
+	}
+
+	request.Iso15118CertificateHashData = nil
+	contracts.status = pki.ContractRevoked
+	check("plain token", "", v201.AuthorizationStatusAccepted)
+}
+
+func TestSignV2GCertificateSendsTheProviderChain(t *testing.T) {
+	h := newSecurityHandler()
+	charger := &v2gCharger{signed: make(chan *security.CertificateSignedRequest, 1)}
+	h.server = charger
+	handlers := NewV201Handlers(h, stopStubLogger{})
+	csr := newCsr(t, "SECC-CP1", "Operator")
+	request := &security.SignCertificateRequest{Csr: csr, CertificateType: security.CertificateSigningUseV2G}
+
+	response, err := handlers.OnSignCertificate("CP1", request)
+	if err != nil || response.Status != security.GenericStatusRejected {
+		t.Fatalf("status %v (%v) without a provider, want rejected", response.Status, err)
+	}
+
+	h.SetContractProvider(&stubContracts{})
+	response, err = handlers.OnSignCertificate("CP1", request)
+	if err != nil || response.Status != security.GenericStatusAccepted {
+		t.Fatalf("status %v (%v), want accepted", response.Status, err)
+	}
+	select {
+	case signed := <-charger.signed:
+		if signed.CertificateType != security.CertificateSigningUseV2G || signed.CertificateChain != "signed "+csr {
+			t.Errorf("sent %+v, want the V2G chain of the provider", signed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no certificate sent")
+	}
+}
@@ -1,7 +1,12 @@
 package server
 
+import "evsys/ocpp/v201"
+
 type PowerManager interface {
 	OnSystemStart()
 	OnChargePointBoot(chargePointId string)
 	CheckPowerLimit(chargePointId string)
+	// ScheduleFits tells whether the charging schedule an ISO 15118 EV proposes keeps to the
+	// location limit and the external limits on its EVSE; the reason is set when it does not
+	ScheduleFits(chargePointId string, evseId int, schedule v201.ChargingSchedule) (bool, string)
 }
@@ -85,6 +85,7 @@ func (pa *ProtocolAdapter) TransactionEventToEntity(
 	transactionInfo v201.Transaction,
 	idToken *v201.IdToken,
 	evse *v201.EVSE,
+	reservationId *int,
 	meterValues []v201.MeterValue,
 	timestamp time.Time,
 	chargePointId string,
@@ -96,6 +97,7 @@ func (pa *ProtocolAdapter) TransactionEventToEntity(
 		ProtocolVersion: string(common.OCPP201),
 		TimeStart:       timestamp,
 		IsFinished:      false,
+		ReservationId:   reservationId,
 	}
 
 	// Map IdToken to IdTag
@@ -356,7 +358,7 @@ func (pa *ProtocolAdapter) ValidateProtocolVersion(version string) error {
 
 // IsOCPP201OrHigher checks if the protocol version is OCPP 2.0.1 or higher
 func (pa *ProtocolAdapter) IsOCPP201OrHigher(version string) bool {
-	return version == string(common.OCPP201) || version == string(common.OCPP21)
+	return common.ProtocolVersion(version).IsOCPP2()
 }
 
 // IsOCPP16 checks if the protocol version is OCPP 1.6J
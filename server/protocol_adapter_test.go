@@ -217,12 +217,14 @@ func TestProtocolAdapter_TransactionEventToEntity_Started(t *testing.T) {
 	transactionInfo := v201.Transaction{
 		TransactionId: "TX123",
 	}
+	reservationId := 7
 
 	transaction, err := adapter.TransactionEventToEntity(
 		v201.TransactionEventStarted,
 		transactionInfo,
 		idToken,
 		evse,
+		&reservationId,
 		nil,
 		now,
 		"CP001",
@@ -252,6 +254,9 @@ func TestProtocolAdapter_TransactionEventToEntity_Started(t *testing.T) {
 	if transaction.EvseId == nil || *transaction.EvseId != 1 {
 		t.Errorf("EvseId = %v, want 1", transaction.EvseId)
 	}
+	if transaction.ReservationId == nil || *transaction.ReservationId != 7 {
+		t.Errorf("ReservationId = %v, want 7", transaction.ReservationId)
+	}
 }
 
 func TestProtocolAdapter_TransactionEventToEntity_Ended(t *testing.T) {
@@ -294,6 +299,7 @@ func TestProtocolAdapter_TransactionEventToEntity_Ended(t *testing.T) {
 		transactionInfo,
 		idToken,
 		evse,
+		nil,
 		meterValues,
 		now,
 		"CP001",
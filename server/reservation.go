@@ -0,0 +1,326 @@
+package server
+
+import (
+	"encoding/json"
+	"evsys/entity"
+	"evsys/ocpp"
+	"evsys/ocpp/v16/core"
+	"evsys/ocpp/v16/reservation"
+	"evsys/ocpp/v201"
+	reservation201 "evsys/ocpp/v201/reservation"
+	"evsys/types"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultReservationDuration applies when a ReserveNow command names neither an expiry date nor
+	// a duration; long enough to drive to a charger across town.
+	defaultReservationDuration = 30 * time.Minute
+	// reservationSweepInterval is how often reservations past their expiry date are closed.
+	reservationSweepInterval = time.Minute
+)
+
+func (h *SystemHandler) SetReservationService(reservations *ReservationService) {
+	h.reservations = reservations
+}
+
+func (h *SystemHandler) OnReserveNow(chargePointId string, connectorId int, payload string) (*reservation.ReserveNowRequest, error) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	state, ok := h.getChargePoint(chargePointId)
+	if !ok {
+		return nil, fmt.Errorf("charge point not found")
+	}
+	if h.reservations == nil {
+		return nil, fmt.Errorf("reservations are not enabled")
+	}
+	if connectorId < 0 {
+		return nil, fmt.Errorf("invalid connector id")
+	}
+	if connectorId > 0 {
+		if _, ok = state.connectors[connectorId]; !ok {
+			return nil, fmt.Errorf("connector %d not found", connectorId)
+		}
+	}
+	var query reserveNowQuery
+	if err := json.Unmarshal([]byte(payload), &query); err != nil {
+		return nil, fmt.Errorf("invalid payload")
+	}
+	if len(strings.TrimSpace(query.IdTag)) > 20 {
+		return nil, fmt.Errorf("invalid id tag")
+	}
+	record, err := h.reservations.Reserve(entity.Reservation{ChargePointId: chargePointId, ConnectorId: connectorId}, &query)
+	if err != nil {
+		return nil, err
+	}
+
+	request := reservation.NewReserveNowRequest(connectorId, types.NewDateTime(record.ExpiryDate), record.IdTag, record.Id)
+	request.ParentIdTag = record.ParentIdTag
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId,
+		fmt.Sprintf("reservation #%d on connector %d for %s until %s", record.Id, connectorId, record.IdTag, record.ExpiryDate.Format(time.RFC3339)))
+	return request, nil
+}
+
+// OnReserveNowResponse records the charge point's answer; anything but Accepted means the connector
+// is not held, and the stored reservation must not block other users.
+func (h *SystemHandler) OnReserveNowResponse(chargePointId string, request *reservation.ReserveNowRequest, response *reservation.ReserveNowResponse) {
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("reservation #%d: %s", request.ReservationId, response.Status))
+	if response.Status == reservation.StatusAccepted || h.reservations == nil {
+		return
+	}
+	h.reservations.Close(request.ReservationId, entity.ReservationRejected, string(response.Status))
+}
+
+func (h *SystemHandler) OnCancelReservation(chargePointId string, payload string) (*reservation.CancelReservationRequest, error) {
+	reservationId, err := h.checkCancelReservation(chargePointId, payload)
+	if err != nil {
+		return nil, err
+	}
+	request := reservation.NewCancelReservationRequest(reservationId)
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("cancel reservation #%d", reservationId))
+	return request, nil
+}
+
+// checkCancelReservation reads the reservation id of a CancelReservation command, which must be one
+// of the charge point's own reservations
+func (h *SystemHandler) checkCancelReservation(chargePointId string, payload string) (int, error) {
+	h.mux.Lock()
+	_, ok := h.getChargePoint(chargePointId)
+	h.mux.Unlock()
+	if !ok {
+		return 0, fmt.Errorf("charge point not found")
+	}
+	if h.reservations == nil {
+		return 0, fmt.Errorf("reservations are not enabled")
+	}
+	reservationId, err := strconv.Atoi(strings.TrimSpace(payload))
+	if err != nil {
+		return 0, fmt.Errorf("invalid reservation id")
+	}
+	if _, err = h.reservations.Find(chargePointId, reservationId); err != nil {
+		return 0, err
+	}
+	return reservationId, nil
+}
+
+// OnCancelReservationResponse closes the stored reservation. A Rejected answer means the charge
+// point holds no such reservation - already used or expired on its side - so there is nothing
+// left to keep active here either.
+func (h *SystemHandler) OnCancelReservationResponse(chargePointId string, request *reservation.CancelReservationRequest, response *reservation.CancelReservationResponse) {
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("reservation #%d: %s", request.ReservationId, response.Status))
+	h.closeCancelledReservation(request.ReservationId, response.Status == reservation.CancelReservationStatusAccepted)
+}
+
+func (h *SystemHandler) closeCancelledReservation(reservationId int, accepted bool) {
+	if h.reservations == nil {
+		return
+	}
+	info := ""
+	if !accepted {
+		info = "not known to the charge point"
+	}
+	h.reservations.Close(reservationId, entity.ReservationCancelled, info)
+}
+
+// claimReservation checks a StartTransaction against the reservations held on the charge point.
+// It returns the reservation the start consumes, if any, and whether the start may go ahead.
+func (h *SystemHandler) claimReservation(chargePointId string, request *core.StartTransactionRequest) (*entity.Reservation, bool) {
+	if h.reservations == nil {
+		return nil, true
+	}
+	held, blocking := h.reservations.Claim(chargePointId, request.IdTag, h.parentIdTag(request.IdTag), request.ReservationId, func(r *entity.Reservation) bool {
+		return r.Covers(request.ConnectorId)
+	})
+	if blocking != nil {
+		h.logger.FeatureEvent(request.GetFeatureName(), chargePointId,
+			fmt.Sprintf("connector %d is reserved by #%d; id tag %s refused", request.ConnectorId, blocking.Id, request.IdTag))
+		return nil, false
+	}
+	return held, true
+}
+
+// parentIdTag is the parent id tag stored for an id tag, empty when there is none; read only, unlike
+// getUserTag, as a starting session registers its tag elsewhere
+func (h *SystemHandler) parentIdTag(idTag string) string {
+	if h.database == nil {
+		return ""
+	}
+	_, id := entity.SplitIdTag(idTag)
+	userTag, err := h.database.GetUserTag(id)
+	if err != nil || userTag == nil {
+		return ""
+	}
+	return userTag.ParentIdTag
+}
+
+// ============================================================================
+// OCPP 2.0.1 RESERVATIONS
+// ============================================================================
+
+// reserveNowQuery201 is the API payload of a 2.0.1 ReserveNow command: the 1.6 fields, the type of
+// the id token, ISO14443 when omitted, and the connector type a reservation of any EVSE must have
+type reserveNowQuery201 struct {
+	reserveNowQuery
+	TokenType     string `json:"tokenType,omitempty"`
+	ConnectorType string `json:"connectorType,omitempty"`
+}
+
+// OnReserveNow creates a ReserveNow request for OCPP 2.0.1; evseId 0 reserves any EVSE, of the
+// connector type when the payload names one
+func (h *V201Handlers) OnReserveNow(chargePointId string, evseId int, payload string) (ocpp.Request, error) {
+	h.systemHandler.mux.Lock()
+	state, ok := h.systemHandler.getChargePoint(chargePointId)
+	found := ok && len(evseConnectors(state, evseId)) > 0
+	h.systemHandler.mux.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("charge point not found")
+	}
+	if h.systemHandler.reservations == nil {
+		return nil, fmt.Errorf("reservations are not enabled")
+	}
+	if evseId < 0 {
+		return nil, fmt.Errorf("invalid EVSE id")
+	}
+	if evseId > 0 && !found {
+		return nil, fmt.Errorf("EVSE %d not found", evseId)
+	}
+
+	var query reserveNowQuery201
+	if err := json.Unmarshal([]byte(payload), &query); err != nil {
+		return nil, fmt.Errorf("invalid payload")
+	}
+	tokenType := v201.IdTokenTypeISO14443
+	if query.TokenType != "" {
+		tokenType = v201.IdTokenType(query.TokenType)
+	}
+	connectorType := reservation201.ConnectorType(query.ConnectorType)
+	if connectorType != "" && !connectorType.IsValid() {
+		return nil, fmt.Errorf("unknown connector type %q", query.ConnectorType)
+	}
+	idToken := v201.IdToken{IdToken: strings.TrimSpace(query.IdTag), Type: tokenType}
+	if err := idToken.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid id token: %v", err)
+	}
+
+	target := entity.Reservation{ChargePointId: chargePointId, ConnectorType: query.ConnectorType}
+	if evseId > 0 {
+		target.EvseId = &evseId
+	}
+	record, err := h.systemHandler.reservations.Reserve(target, &query.reserveNowQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &reservation201.ReserveNowRequest{
+		Id:             record.Id,
+		ExpiryDateTime: record.ExpiryDate,
+		ConnectorType:  connectorType,
+		IdToken:        idToken,
+		EvseId:         target.EvseId,
+	}
+	if record.ParentIdTag != "" {
+		request.GroupIdToken = &v201.IdToken{IdToken: record.ParentIdTag, Type: v201.IdTokenTypeCentral}
+	}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: reservation #%d on %s for %s until %s",
+		record.Id, reservationTarget(record), record.IdTag, record.ExpiryDate.Format(time.RFC3339)))
+	return request, nil
+}
+
+// OnCancelReservation creates a CancelReservation request for OCPP 2.0.1; the payload is the
+// reservation id
+func (h *V201Handlers) OnCancelReservation(chargePointId string, payload string) (ocpp.Request, error) {
+	reservationId, err := h.systemHandler.checkCancelReservation(chargePointId, payload)
+	if err != nil {
+		return nil, err
+	}
+	request := &reservation201.CancelReservationRequest{ReservationId: reservationId}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: cancel reservation #%d", reservationId))
+	return request, nil
+}
+
+// onReservationResponse closes the stored reservation when the charge point refused to hold it, or
+// answered its cancellation
+func (h *V201Handlers) onReservationResponse(chargePointId string, request ocpp.Request, payload []byte) error {
+	switch r := request.(type) {
+	case *reservation201.ReserveNowRequest:
+		var response reservation201.ReserveNowResponse
+		if err := json.Unmarshal(payload, &response); err != nil {
+			return fmt.Errorf("invalid %s response", request.GetFeatureName())
+		}
+		h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: reservation #%d: %s", r.Id, response.Status))
+		if response.Status != reservation201.ReserveNowStatusAccepted && h.systemHandler.reservations != nil {
+			h.systemHandler.reservations.Close(r.Id, entity.ReservationRejected, string(response.Status))
+		}
+	case *reservation201.CancelReservationRequest:
+		var response reservation201.CancelReservationResponse
+		if err := json.Unmarshal(payload, &response); err != nil {
+			return fmt.Errorf("invalid %s response", request.GetFeatureName())
+		}
+		h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: reservation #%d: %s", r.ReservationId, response.Status))
+		h.systemHandler.closeCancelledReservation(r.ReservationId, response.Status == reservation201.CancelReservationStatusAccepted)
+	}
+	return nil
+}
+
+// OnReservationStatusUpdate handles OCPP 2.0.1 ReservationStatusUpdate requests: the charge point
+// let a reservation expire, or removed it, and it no longer holds an EVSE
+func (h *V201Handlers) OnReservationStatusUpdate(chargePointId string, request *reservation201.ReservationStatusUpdateRequest) (*reservation201.ReservationStatusUpdateResponse, error) {
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: reservation #%d: %s",
+		request.ReservationId, request.ReservationUpdateStatus))
+	if h.systemHandler.reservations == nil {
+		return &reservation201.ReservationStatusUpdateResponse{}, nil
+	}
+	if _, err := h.systemHandler.reservations.Find(chargePointId, request.ReservationId); err != nil {
+		h.logger.Warn(fmt.Sprintf("reservation status update from %s: %v", chargePointId, err))
+		return &reservation201.ReservationStatusUpdateResponse{}, nil
+	}
+	if request.ReservationUpdateStatus == reservation201.ReservationUpdateStatusExpired {
+		h.systemHandler.reservations.Close(request.ReservationId, entity.ReservationExpired, "")
+	} else {
+		h.systemHandler.reservations.Close(request.ReservationId, entity.ReservationCancelled, "removed by the charge point")
+	}
+	return &reservation201.ReservationStatusUpdateResponse{}, nil
+}
+
+// claimReservation201 checks a 2.0.1 session starting on the connector against the reservations held
+// on the charge point, the way claimReservation does for 1.6
+func (h *SystemHandler) claimReservation201(chargePointId string, connector *entity.Connector, transaction *entity.Transaction) (*entity.Reservation, bool) {
+	if h.reservations == nil {
+		return nil, true
+	}
+	evseId := connector.Id
+	if transaction.EvseId != nil {
+		evseId = *transaction.EvseId
+	} else if connector.EvseId != nil {
+		evseId = *connector.EvseId
+	}
+	held, blocking := h.reservations.Claim(chargePointId, transaction.IdTag, h.parentIdTag(transaction.IdTag), transaction.ReservationId, func(r *entity.Reservation) bool {
+		return r.CoversEvse(evseId, connector.Type)
+	})
+	if blocking != nil {
+		h.logger.FeatureEvent("TransactionEvent", chargePointId,
+			fmt.Sprintf("v2.0.1: EVSE %d is reserved by #%d; id token %s refused", evseId, blocking.Id, transaction.IdTag))
+		return nil, false
+	}
+	return held, true
+}
+
+// consumeReservation201 marks a reservation as used by a transaction that names it after its start
+func (h *SystemHandler) consumeReservation201(chargePointId string, reservationId int, transaction *entity.Transaction) {
+	record, err := h.reservations.Find(chargePointId, reservationId)
+	if err != nil || record == nil || record.Status != entity.ReservationActive {
+		return
+	}
+	transaction.Lock()
+	h.reservations.Consume(record, transaction)
+	transaction.Unlock()
+	if h.database != nil {
+		if err = h.database.UpdateTransaction(transaction); err != nil {
+			h.logger.Error("update transaction", err)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package server
+
+import (
+	"evsys/entity"
+	"evsys/internal"
+	"evsys/ocpp/v16/reservation"
+	"evsys/types"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ReservationRepository is the part of the database the reservation service works with
+type ReservationRepository interface {
+	GetLastReservation() (*entity.Reservation, error)
+	GetReservation(id int) (*entity.Reservation, error)
+	AddReservation(reservation *entity.Reservation) error
+	UpdateReservation(reservation *entity.Reservation) error
+	GetActiveReservations(chargePointId string, now time.Time) ([]*entity.Reservation, error)
+	GetExpiredReservations(now time.Time) ([]*entity.Reservation, error)
+}
+
+// reserveNowQuery is the API payload of a ReserveNow command. The reservation id is assigned here,
+// so callers only say who the connector is held for and until when.
+type reserveNowQuery struct {
+	IdTag       string          `json:"idTag"`
+	ParentIdTag string          `json:"parentIdTag,omitempty"`
+	ExpiryDate  *types.DateTime `json:"expiryDate,omitempty"`
+	// Duration, in seconds, is an alternative to ExpiryDate for callers that do not want to deal
+	// with the charge point's clock
+	Duration int `json:"duration,omitempty"`
+}
+
+// ReservationService keeps the reservations of the charge points of both protocol versions. It
+// numbers and stores them, decides which reservation a starting session consumes or is refused by,
+// and closes the ones that run past their expiry date; the charge point drops those by itself, the
+// sweep only brings the stored status in line. Without a repository nothing is held or refused.
+type ReservationService struct {
+	database ReservationRepository
+	log      internal.LogHandler
+	lastId   int
+	now      func() time.Time
+	mutex    sync.Mutex
+}
+
+func NewReservationService(database ReservationRepository, log internal.LogHandler, now func() time.Time) *ReservationService {
+	return &ReservationService{
+		database: database,
+		log:      log,
+		now:      now,
+	}
+}
+
+// Load continues the ids of the previous process. It has to run before the servers accept
+// requests: a reservation numbered from scratch would share its id with a stored one, and cancelling
+// or consuming it by id would act on both.
+func (s *ReservationService) Load() error {
+	if s.database == nil {
+		return nil
+	}
+	last, err := s.database.GetLastReservation()
+	if err != nil {
+		return fmt.Errorf("load last reservation: %v", err)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if last != nil {
+		s.lastId = last.Id
+	}
+	return nil
+}
+
+// OnSystemStart expires reservations for the lifetime of the process.
+func (s *ReservationService) OnSystemStart() {
+	ticker := time.NewTicker(reservationSweepInterval)
+	defer ticker.Stop()
+	for {
+		s.expire()
+		<-ticker.C
+	}
+}
+
+// Reserve stores an active reservation for the target under a new id. The target names the charge
+// point and what is reserved on it: a connector, or an EVSE and connector type. The reservation is
+// stored before the request goes out, as a charge point that accepts may start the session before
+// its answer has been processed here.
+func (s *ReservationService) Reserve(target entity.Reservation, query *reserveNowQuery) (*entity.Reservation, error) {
+	query.IdTag = strings.TrimSpace(query.IdTag)
+	if query.IdTag == "" {
+		return nil, fmt.Errorf("invalid id tag")
+	}
+	now := s.now()
+	expiry := now.Add(defaultReservationDuration)
+	if query.ExpiryDate != nil {
+		expiry = query.ExpiryDate.Time
+	} else if query.Duration > 0 {
+		expiry = now.Add(time.Duration(query.Duration) * time.Second)
+	}
+	if !expiry.After(now) {
+		return nil, fmt.Errorf("expiry date is in the past")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.lastId++
+	record := &target
+	record.Id = s.lastId
+	record.IdTag = query.IdTag
+	record.ParentIdTag = query.ParentIdTag
+	record.ExpiryDate = expiry
+	record.Status = entity.ReservationActive
+	record.TimeCreated = now
+	record.TimeUpdated = now
+	if s.database != nil {
+		if err := s.database.AddReservation(record); err != nil {
+			return nil, fmt.Errorf("save reservation: %v", err)
+		}
+	}
+	return record, nil
+}
+
+// Find returns a stored reservation of the charge point; without a repository there is nothing to
+// check against, and it returns nil.
+func (s *ReservationService) Find(chargePointId string, id int) (*entity.Reservation, error) {
+	if s.database == nil {
+		return nil, nil
+	}
+	record, err := s.database.GetReservation(id)
+	if err != nil {
+		return nil, fmt.Errorf("reservation %d not found", id)
+	}
+	if record.ChargePointId != chargePointId {
+		return nil, fmt.Errorf("reservation %d belongs to %s", id, record.ChargePointId)
+	}
+	return record, nil
+}
+
+// Close ends a reservation that is still active; one already used, cancelled or expired keeps its
+// status.
+func (s *ReservationService) Close(id int, status, info string) {
+	if s.database == nil {
+		return
+	}
+	record, err := s.database.GetReservation(id)
+	if err != nil {
+		s.log.Error("get reservation", err)
+		return
+	}
+	if record.Status != entity.ReservationActive {
+		return
+	}
+	s.close(record, status, info)
+}
+
+/*
+Claim checks a starting session against the reservations held on the charge point; covers tells
+whether a reservation applies to where the session starts. It returns the reservation the session
+consumes, if any, and the one that refuses it, if any. A reservation is held by its id tag and by
+every tag whose parent id tag is the reservation's.
+
+A reservation of a specific connector or EVSE refuses every other id tag there. A reservation of the
+whole charge point, or of any EVSE with a connector type, does not refuse anyone: the charge point
+keeps a connector free for it, and the central system cannot tell which one. Its holder still
+consumes it wherever they start. A session naming a reservation that is not its own is refused by it.
+
+A failed read lets the session through; a database hiccup must not stop people from charging.
+*/
+func (s *ReservationService) Claim(chargePointId, idTag, parentIdTag string, reservationId *int, covers func(*entity.Reservation) bool) (held, blocking *entity.Reservation) {
+	if s.database == nil {
+		return nil, nil
+	}
+	reservations, err := s.database.GetActiveReservations(chargePointId, s.now())
+	if err != nil {
+		s.log.Error("get active reservations", err)
+		return nil, nil
+	}
+	heldBy := func(r *entity.Reservation) bool {
+		return r.IsHeldBy(idTag) || r.IsHeldByGroup(parentIdTag)
+	}
+	for _, r := range reservations {
+		if !covers(r) {
+			continue
+		}
+		if (reservationId != nil && *reservationId == r.Id) || heldBy(r) {
+			if held == nil || r.IsExclusive() {
+				held = r
+			}
+			continue
+		}
+		if r.IsExclusive() {
+			blocking = r
+		}
+	}
+	if held != nil && !heldBy(held) {
+		// the charge point claims the reservation for a different tag
+		return nil, held
+	}
+	if blocking != nil {
+		return nil, blocking
+	}
+	return held, nil
+}
+
+// Consume marks the reservation as used by the transaction that has just started.
+func (s *ReservationService) Consume(record *entity.Reservation, transaction *entity.Transaction) {
+	transaction.ReservationId = &record.Id
+	record.TransactionId = transaction.Id
+	s.close(record, entity.ReservationUsed, "")
+}
+
+func (s *ReservationService) close(record *entity.Reservation, status, info string) {
+	record.Status = status
+	record.Info = info
+	record.TimeUpdated = s.now()
+	if err := s.database.UpdateReservation(record); err != nil {
+		s.log.Error("update reservation", err)
+	}
+}
+
+func (s *ReservationService) expire() {
+	if s.database == nil {
+		return
+	}
+	expired, err := s.database.GetExpiredReservations(s.now())
+	if err != nil {
+		s.log.Error("get expired reservations", err)
+		return
+	}
+	for _, record := range expired {
+		s.close(record, entity.ReservationExpired, "")
+		s.log.FeatureEvent(reservation.ReserveNowFeatureName, record.ChargePointId,
+			fmt.Sprintf("reservation #%d on %s expired", record.Id, reservationTarget(record)))
+	}
+}
+
+// reservationTarget describes what a reservation holds, for the log
+func reservationTarget(r *entity.Reservation) string {
+	switch {
+	case r.EvseId != nil:
+		return fmt.Sprintf("EVSE %d", *r.EvseId)
+	case r.ConnectorType != "":
+		return "any " + r.ConnectorType + " connector"
+	case r.ConnectorId != 0:
+		return fmt.Sprintf("connector %d", r.ConnectorId)
+	}
+	return "any connector"
+}
@@ -0,0 +1,400 @@
+package server
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"evsys/entity"
+	"evsys/internal"
+	"evsys/ocpp/v16/core"
+	"evsys/ocpp/v201"
+	"evsys/ocpp/v201/reservation"
+	"evsys/ocpp/v201/transactions"
+	"evsys/types"
+)
+
+// reservationStubDB serves the reservations of a single charge point and records what
+// OnStartTransaction writes back.
+type reservationStubDB struct {
+	internal.Database
+	reservations []*entity.Reservation
+	lastErr      error
+	parents      map[string]string // id tag → parent id tag
+	updated      []*entity.Reservation
+	transactions []*entity.Transaction
+}
+
+func (s *reservationStubDB) GetActiveReservations(_ string, now time.Time) ([]*entity.Reservation, error) {
+	active := make([]*entity.Reservation, 0)
+	for _, r := range s.reservations {
+		if r.IsActiveAt(now) {
+			active = append(active, r)
+		}
+	}
+	return active, nil
+}
+
+func (s *reservationStubDB) GetLastReservation() (*entity.Reservation, error) {
+	if s.lastErr != nil || len(s.reservations) == 0 {
+		return nil, s.lastErr
+	}
+	return s.reservations[len(s.reservations)-1], nil
+}
+
+func (s *reservationStubDB) GetReservation(id int) (*entity.Reservation, error) {
+	for _, r := range s.reservations {
+		if r.Id == id {
+			return r, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+func (s *reservationStubDB) AddReservation(r *entity.Reservation) error {
+	s.reservations = append(s.reservations, r)
+	return nil
+}
+
+func (s *reservationStubDB) UpdateReservation(r *entity.Reservation) error {
+	s.updated = append(s.updated, r)
+	return nil
+}
+
+func (s *reservationStubDB) GetUserTag(idTag string) (*entity.UserTag, error) {
+	return &entity.UserTag{IdTag: idTag, ParentIdTag: s.parents[idTag], IsEnabled: true}, nil
+}
+
+func (s *reservationStubDB) UpdateTagLastSeen(*entity.UserTag) error { return nil }
+func (s *reservationStubDB) UpdateConnector(*entity.Connector) error { return nil }
+
+func (s *reservationStubDB) AddTransaction(t *entity.Transaction) error {
+	s.transactions = append(s.transactions, t)
+	return nil
+}
+
+func (s *reservationStubDB) GetTransactionBySessionId(_, sessionId string) (*entity.Transaction, error) {
+	for _, t := range s.transactions {
+		if t.SessionId == sessionId {
+			return t, nil
+		}
+	}
+	return nil, nil
+}
+
+func newReservationHandler(db internal.Database) *SystemHandler {
+	h := &SystemHandler{
+		chargePoints: map[string]*ChargePointState{},
+		lastMeter:    map[int]*entity.TransactionMeter{},
+		database:     db,
+		billing:      stopStubBilling{},
+		logger:       stopStubLogger{},
+		location:     time.UTC,
+	}
+	h.reservations = NewReservationService(db, h.logger, h.getTime)
+	state := newChargePointState(&entity.ChargePoint{Id: "CP1", IsEnabled: true})
+	state.connectors[1] = entity.NewConnector(1, "CP1")
+	state.connectors[2] = entity.NewConnector(2, "CP1")
+	h.chargePoints["CP1"] = state
+	return h
+}
+
+func startRequest(connectorId int, idTag string) *core.StartTransactionRequest {
+	return &core.StartTransactionRequest{
+		ConnectorId: connectorId,
+		IdTag:       idTag,
+		Timestamp:   types.NewDateTime(time.Now().UTC()),
+	}
+}
+
+func TestStartTransactionOnReservedConnector(t *testing.T) {
+	expiry := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name        string
+		reservation entity.Reservation
+		connectorId int
+		idTag       string
+		named       bool // the start names the reservation
+		wantStatus  types.AuthorizationStatus
+		wantUsed    bool
+	}{
+		{
+			name:        "holder consumes the reservation",
+			reservation: entity.Reservation{Id: 7, ConnectorId: 1, IdTag: "ALICE"},
+			connectorId: 1, idTag: "ALICE",
+			wantStatus: types.AuthorizationStatusAccepted, wantUsed: true,
+		},
+		{
+			name:        "holder with source prefix",
+			reservation: entity.Reservation{Id: 7, ConnectorId: 1, IdTag: "ALICE"},
+			connectorId: 1, idTag: "app:ALICE",
+			wantStatus: types.AuthorizationStatusAccepted, wantUsed: true,
+		},
+		{
+			name:        "other tag is refused",
+			reservation: entity.Reservation{Id: 7, ConnectorId: 1, IdTag: "ALICE"},
+			connectorId: 1, idTag: "BOB",
+			wantStatus: types.AuthorizationStatusInvalid,
+		},
+		{
+			name:        "other connector is free",
+			reservation: entity.Reservation{Id: 7, ConnectorId: 1, IdTag: "ALICE"},
+			connectorId: 2, idTag: "BOB",
+			wantStatus: types.AuthorizationStatusAccepted,
+		},
+		{
+			name:        "tag of the reservation's group uses it",
+			reservation: entity.Reservation{Id: 7, ConnectorId: 1, IdTag: "ALICE", ParentIdTag: "FLEET"},
+			connectorId: 1, idTag: "CAROL", named: true,
+			wantStatus: types.AuthorizationStatusAccepted, wantUsed: true,
+		},
+		{
+			name:        "tag of another group naming the reservation is refused",
+			reservation: entity.Reservation{Id: 7, ConnectorId: 1, IdTag: "ALICE", ParentIdTag: "FLEET"},
+			connectorId: 1, idTag: "DAVE", named: true,
+			wantStatus: types.AuthorizationStatusInvalid,
+		},
+		{
+			name:        "whole charge point reservation does not refuse others",
+			reservation: entity.Reservation{Id: 7, ConnectorId: 0, IdTag: "ALICE"},
+			connectorId: 2, idTag: "BOB",
+			wantStatus: types.AuthorizationStatusAccepted,
+		},
+		{
+			name:        "whole charge point reservation is consumed on any connector",
+			reservation: entity.Reservation{Id: 7, ConnectorId: 0, IdTag: "ALICE"},
+			connectorId: 2, idTag: "ALICE",
+			wantStatus: types.AuthorizationStatusAccepted, wantUsed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.reservation
+			r.ChargePointId = "CP1"
+			r.Status = entity.ReservationActive
+			r.ExpiryDate = expiry
+			db := &reservationStubDB{reservations: []*entity.Reservation{&r}, parents: map[string]string{"CAROL": "FLEET", "DAVE": "OTHERS"}}
+			h := newReservationHandler(db)
+
+			request := startRequest(tt.connectorId, tt.idTag)
+			if tt.named {
+				request.ReservationId = &r.Id
+			}
+			response, err := h.OnStartTransaction("CP1", request)
+			if err != nil {
+				t.Fatalf("OnStartTransaction: %v", err)
+			}
+			if response.IdTagInfo.Status != tt.wantStatus {
+				t.Fatalf("status = %s, want %s", response.IdTagInfo.Status, tt.wantStatus)
+			}
+			if !tt.wantUsed {
+				if len(db.updated) != 0 {
+					t.Errorf("reservation was updated to %s, want it left active", r.Status)
+				}
+				return
+			}
+			if r.Status != entity.ReservationUsed {
+				t.Errorf("reservation status = %s, want %s", r.Status, entity.ReservationUsed)
+			}
+			if len(db.transactions) != 1 {
+				t.Fatalf("expected one stored transaction, got %d", len(db.transactions))
+			}
+			transaction := db.transactions[0]
+			if transaction.ReservationId == nil || *transaction.ReservationId != r.Id {
+				t.Errorf("transaction reservation id = %v, want %d", transaction.ReservationId, r.Id)
+			}
+			if r.TransactionId != transaction.Id {
+				t.Errorf("reservation transaction id = %d, want %d", r.TransactionId, transaction.Id)
+			}
+		})
+	}
+}
+
+func TestStartTransactionIgnoresExpiredReservation(t *testing.T) {
+	r := &entity.Reservation{
+		Id: 3, ChargePointId: "CP1", ConnectorId: 1, IdTag: "ALICE",
+		Status: entity.ReservationActive, ExpiryDate: time.Now().Add(-time.Minute),
+	}
+	db := &reservationStubDB{reservations: []*entity.Reservation{r}}
+	h := newReservationHandler(db)
+
+	response, err := h.OnStartTransaction("CP1", startRequest(1, "BOB"))
+	if err != nil {
+		t.Fatalf("OnStartTransaction: %v", err)
+	}
+	if response.IdTagInfo.Status != types.AuthorizationStatusAccepted {
+		t.Errorf("status = %s, want Accepted once the reservation has expired", response.IdTagInfo.Status)
+	}
+}
+
+func startEvent(evseId int, idToken string) *transactions.TransactionEventRequest {
+	connectorId := 1
+	return &transactions.TransactionEventRequest{
+		EventType:       v201.TransactionEventStarted,
+		Timestamp:       time.Now(),
+		TriggerReason:   v201.TriggerReasonAuthorized,
+		SeqNo:           0,
+		TransactionInfo: v201.Transaction{TransactionId: "TX-" + idToken},
+		IdToken:         &v201.IdToken{IdToken: idToken, Type: v201.IdTokenTypeISO14443},
+		Evse:            &v201.EVSE{Id: evseId, ConnectorId: &connectorId},
+	}
+}
+
+func TestReserveNow201TargetsEvseOrConnectorType(t *testing.T) {
+	db := &reservationStubDB{}
+	h := newReservationHandler(db)
+	handlers := NewV201Handlers(h, stopStubLogger{})
+
+	request, err := handlers.OnReserveNow("CP1", 2, `{"idTag":"ALICE","duration":600}`)
+	if err != nil {
+		t.Fatalf("OnReserveNow: %v", err)
+	}
+	reserve := request.(*reservation.ReserveNowRequest)
+	if reserve.EvseId == nil || *reserve.EvseId != 2 || reserve.IdToken.Type != v201.IdTokenTypeISO14443 {
+		t.Errorf("request %+v, want EVSE 2 for an ISO14443 token", reserve)
+	}
+	if err = reserve.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	request, err = handlers.OnReserveNow("CP1", 0, `{"idTag":"BOB","connectorType":"cCCS2"}`)
+	if err != nil {
+		t.Fatalf("OnReserveNow: %v", err)
+	}
+	reserve = request.(*reservation.ReserveNowRequest)
+	if reserve.EvseId != nil || reserve.ConnectorType != reservation.ConnectorTypeCCS2 || reserve.Id == 1 {
+		t.Errorf("request %+v, want any CCS2 EVSE under a new id", reserve)
+	}
+	if len(db.reservations) != 2 || db.reservations[1].ConnectorType != "cCCS2" || db.reservations[0].EvseId == nil {
+		t.Fatalf("stored %d reservations, want both with their targets", len(db.reservations))
+	}
+
+	if _, err = handlers.OnReserveNow("CP1", 5, `{"idTag":"ALICE"}`); err == nil {
+		t.Error("OnReserveNow accepted an unknown EVSE")
+	}
+	if _, err = handlers.OnReserveNow("CP1", 0, `{"idTag":"ALICE","connectorType":"Type9"}`); err == nil {
+		t.Error("OnReserveNow accepted an unknown connector type")
+	}
+}
+
+func TestTransactionEventOnReservedEvse(t *testing.T) {
+	expiry := time.Now().Add(time.Hour)
+	evseId := 1
+
+	tests := []struct {
+		name        string
+		reservation entity.Reservation
+		evseId      int
+		idToken     string
+		wantRefused bool
+		wantUsed    bool
+	}{
+		{"holder consumes the reservation", entity.Reservation{EvseId: &evseId, IdTag: "ALICE"}, 1, "ALICE", false, true},
+		{"other token is refused", entity.Reservation{EvseId: &evseId, IdTag: "ALICE"}, 1, "BOB", true, false},
+		{"other EVSE is free", entity.Reservation{EvseId: &evseId, IdTag: "ALICE"}, 2, "BOB", false, false},
+		{"connector type reservation does not refuse others", entity.Reservation{ConnectorType: "cCCS2", IdTag: "ALICE"}, 1, "BOB", false, false},
+		{"connector type reservation is consumed by its holder", entity.Reservation{ConnectorType: "cCCS2", IdTag: "ALICE"}, 2, "ALICE", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.reservation
+			r.Id, r.ChargePointId, r.Status, r.ExpiryDate = 9, "CP1", entity.ReservationActive, expiry
+			db := &reservationStubDB{reservations: []*entity.Reservation{&r}}
+			h := newReservationHandler(db)
+			handlers := NewV201Handlers(h, stopStubLogger{})
+
+			response, err := handlers.OnTransactionEvent("CP1", startEvent(tt.evseId, tt.idToken))
+			if err != nil {
+				t.Fatalf("OnTransactionEvent: %v", err)
+			}
+			refused := response.IdTokenInfo != nil && response.IdTokenInfo.Status == v201.AuthorizationStatusInvalid
+			if refused != tt.wantRefused {
+				t.Fatalf("refused = %v, want %v", refused, tt.wantRefused)
+			}
+			if (r.Status == entity.ReservationUsed) != tt.wantUsed {
+				t.Fatalf("reservation status = %s, want used %v", r.Status, tt.wantUsed)
+			}
+			if tt.wantUsed {
+				transaction := db.transactions[0]
+				if transaction.ReservationId == nil || *transaction.ReservationId != 9 || r.TransactionId != transaction.Id {
+					t.Errorf("transaction %d with reservation %v, reservation for transaction %d", transaction.Id, transaction.ReservationId, r.TransactionId)
+				}
+			}
+		})
+	}
+}
+
+func TestRefusedStartRecordsNoTransaction(t *testing.T) {
+	evseId := 1
+	r := &entity.Reservation{Id: 9, ChargePointId: "CP1", EvseId: &evseId, IdTag: "ALICE",
+		Status: entity.ReservationActive, ExpiryDate: time.Now().Add(time.Hour)}
+	db := &reservationStubDB{reservations: []*entity.Reservation{r}}
+	handlers := NewV201Handlers(newReservationHandler(db), stopStubLogger{})
+
+	start := startEvent(1, "BOB")
+	response, err := handlers.OnTransactionEvent("CP1", start)
+	if err != nil {
+		t.Fatalf("OnTransactionEvent: %v", err)
+	}
+	if response.IdTokenInfo == nil || response.IdTokenInfo.Status != v201.AuthorizationStatusInvalid {
+		t.Fatalf("start of BOB on ALICE's EVSE was not refused")
+	}
+
+	for seqNo, eventType := range []v201.TransactionEventType{v201.TransactionEventUpdated, v201.TransactionEventEnded} {
+		event := *start
+		event.EventType, event.SeqNo, event.IdToken = eventType, seqNo+1, nil
+		if _, err = handlers.OnTransactionEvent("CP1", &event); err != nil {
+			t.Fatalf("OnTransactionEvent(%s): %v", eventType, err)
+		}
+	}
+	if len(db.transactions) != 0 {
+		t.Errorf("recorded %d transactions of a refused session, want none", len(db.transactions))
+	}
+	if r.Status != entity.ReservationActive {
+		t.Errorf("reservation is %s, want it still held for ALICE", r.Status)
+	}
+}
+
+func TestReservationStatusUpdateClosesTheReservation(t *testing.T) {
+	expiry := time.Now().Add(time.Hour)
+	removed := &entity.Reservation{Id: 4, ChargePointId: "CP1", IdTag: "ALICE", Status: entity.ReservationActive, ExpiryDate: expiry}
+	foreign := &entity.Reservation{Id: 5, ChargePointId: "CP2", IdTag: "BOB", Status: entity.ReservationActive, ExpiryDate: expiry}
+	db := &reservationStubDB{reservations: []*entity.Reservation{removed, foreign}}
+	handlers := NewV201Handlers(newReservationHandler(db), stopStubLogger{})
+
+	for _, id := range []int{4, 5} {
+		request := &reservation.ReservationStatusUpdateRequest{ReservationId: id, ReservationUpdateStatus: reservation.ReservationUpdateStatusRemoved}
+		if _, err := handlers.OnReservationStatusUpdate("CP1", request); err != nil {
+			t.Fatalf("OnReservationStatusUpdate: %v", err)
+		}
+	}
+	if removed.Status != entity.ReservationCancelled || removed.Info == "" {
+		t.Errorf("reservation 4 is %s (%s), want cancelled by the charge point", removed.Status, removed.Info)
+	}
+	if foreign.Status != entity.ReservationActive {
+		t.Errorf("reservation 5 of another charge point is %s, want it left active", foreign.Status)
+	}
+}
+
+func TestReservationServiceContinuesStoredIds(t *testing.T) {
+	db := &reservationStubDB{reservations: []*entity.Reservation{{Id: 41, ChargePointId: "CP1"}}}
+	service := NewReservationService(db, stopStubLogger{}, time.Now)
+	if err := service.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	record, err := service.Reserve(entity.Reservation{ChargePointId: "CP1", ConnectorId: 1}, &reserveNowQuery{IdTag: "ALICE"})
+	if err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	if record.Id != 42 {
+		t.Errorf("reservation id = %d, want 42", record.Id)
+	}
+
+	failing := NewReservationService(&reservationStubDB{lastErr: errors.New("timeout")}, stopStubLogger{}, time.Now)
+	if err = failing.Load(); err == nil {
+		t.Error("Load ignored a failing database")
+	}
+}
@@ -0,0 +1,335 @@
+package server
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"evsys/entity"
+	"evsys/internal"
+	"evsys/ocpp/v16/firmware"
+	"evsys/ocpp/v16/security"
+	"evsys/pki"
+	"evsys/types"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// certificateSignedTimeout bounds the wait for a charge point to install a signed certificate
+const certificateSignedTimeout = 30 * time.Second
+
+// SetCertificateAuthority enables SignCertificate; organization, when set, has to be the O of
+// every certificate signing request, as the security whitepaper asks.
+func (h *SystemHandler) SetCertificateAuthority(ca pki.CertificateAuthority, organization string) {
+	h.certificateAuthority = ca
+	h.certificateOrganization = organization
+}
+
+func (h *SystemHandler) OnSecurityEventNotification(chargePointId string, request *security.SecurityEventNotificationRequest) (*security.SecurityEventNotificationResponse, error) {
+	event := &entity.SecurityEvent{
+		ChargePointId: chargePointId,
+		Type:          request.Type,
+		TechInfo:      request.TechInfo,
+		TimeReceived:  h.getTime(),
+	}
+	event.Timestamp = event.TimeReceived
+	if request.Timestamp != nil {
+		event.Timestamp = request.Timestamp.Time
+	}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("%s: %s", request.Type, request.TechInfo))
+	if h.database != nil {
+		if err := h.database.AddSecurityEvent(event); err != nil {
+			h.logger.Error("add security event", err)
+		}
+	}
+
+	// a charge point only sends the events the whitepaper marks critical, so every one is an alert
+	info := fmt.Sprintf("security event %s", request.Type)
+	if request.TechInfo != "" {
+		info = fmt.Sprintf("%s: %s", info, request.TechInfo)
+	}
+	eventMessage := &internal.EventMessage{
+		ChargePointId: chargePointId,
+		Time:          event.TimeReceived,
+		Status:        request.Type,
+		Info:          info,
+	}
+	go h.notifyEventListeners(internal.Alert, eventMessage)
+	return security.NewSecurityEventNotificationResponse(), nil
+}
+
+// OnSignCertificate accepts a well-formed request of a known charge point and answers it with a
+// CertificateSigned as soon as the certificate authority has signed it.
+func (h *SystemHandler) OnSignCertificate(chargePointId string, request *security.SignCertificateRequest) (*security.SignCertificateResponse, error) {
+	state, ok := h.getChargePoint(chargePointId)
+	if !ok {
+		h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, "unknown charge point; rejected")
+		return security.NewSignCertificateResponse(security.GenericStatusRejected), nil
+	}
+	if h.certificateAuthority == nil || h.server == nil {
+		h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, "no certificate authority configured; rejected")
+		return security.NewSignCertificateResponse(security.GenericStatusRejected), nil
+	}
+	if err := checkCsrSubject(request.Csr, state.model, h.certificateOrganization); err != nil {
+		h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("rejected: %v", err))
+		return security.NewSignCertificateResponse(security.GenericStatusRejected), nil
+	}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, "accepted")
+	go h.sendSignedCertificate(chargePointId, request.Csr)
+	return security.NewSignCertificateResponse(security.GenericStatusAccepted), nil
+}
+
+func (h *SystemHandler) sendSignedCertificate(chargePointId, csr string) {
+	chain, err := h.certificateAuthority.Sign(csr)
+	if err != nil {
+		h.logger.Warn(fmt.Sprintf("sign certificate for %s: %v", chargePointId, err))
+		return
+	}
+	request := security.NewCertificateSignedRequest(chain)
+	payload, err := h.server.SendRequestSync(chargePointId, request, certificateSignedTimeout)
+	if err != nil {
+		h.logger.Warn(fmt.Sprintf("send signed certificate to %s: %v", chargePointId, err))
+		return
+	}
+	var response security.CertificateSignedResponse
+	if err = json.Unmarshal([]byte(payload), &response); err != nil {
+		h.logger.Warn(fmt.Sprintf("invalid %s response from %s: %s", request.GetFeatureName(), chargePointId, payload))
+		return
+	}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("certificate %s", response.Status))
+	if response.Status != security.CertificateSignedStatusAccepted {
+		eventMessage := &internal.EventMessage{
+			ChargePointId: chargePointId,
+			Time:          h.getTime(),
+			Status:        string(response.Status),
+			Info:          "charge point refused its signed certificate",
+		}
+		go h.notifyEventListeners(internal.Alert, eventMessage)
+	}
+}
+
+// checkCsrSubject applies the whitepaper's rules on the subject of a charge point certificate: the
+// CN is the charge point's serial number, and the O the operator's name. Charge points without a
+// known serial number may use their id.
+func checkCsrSubject(csrPEM string, chargePoint *entity.ChargePoint, organization string) error {
+	block, _ := pem.Decode([]byte(csrPEM))
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return fmt.Errorf("no certificate request in PEM")
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("parse certificate request: %v", err)
+	}
+	commonName := csr.Subject.CommonName
+	if commonName == "" || (commonName != chargePoint.SerialNumber && commonName != chargePoint.Id) {
+		return fmt.Errorf("common name %q is neither serial number nor id", commonName)
+	}
+	if organization != "" && (len(csr.Subject.Organization) == 0 || csr.Subject.Organization[0] != organization) {
+		return fmt.Errorf("organization %q does not match %q", strings.Join(csr.Subject.Organization, ","), organization)
+	}
+	return nil
+}
+
+func (h *SystemHandler) OnLogStatusNotification(chargePointId string, request *security.LogStatusNotificationRequest) (*security.LogStatusNotificationResponse, error) {
+	info := fmt.Sprintf("log upload %s", request.Status)
+	if request.RequestId != nil {
+		info = fmt.Sprintf("%s; request #%d", info, *request.RequestId)
+	}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, info)
+	h.recordProgress(chargePointId, progressLog, request.RequestId, string(request.Status))
+	return security.NewLogStatusNotificationResponse(), nil
+}
+
+func (h *SystemHandler) OnSignedFirmwareStatusNotification(chargePointId string, request *firmware.SignedStatusNotificationRequest) (*firmware.SignedStatusNotificationResponse, error) {
+	state, ok := h.getChargePoint(chargePointId)
+	if !ok {
+		return firmware.NewSignedStatusNotificationResponse(), nil
+	}
+	info := fmt.Sprintf("updated signed firmware status to %v", request.Status)
+	if request.RequestId != nil {
+		info = fmt.Sprintf("%s; request #%d", info, *request.RequestId)
+	}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, info)
+	h.recordProgress(chargePointId, progressFirmware, request.RequestId, string(request.Status))
+
+	// the steps a plain update has too are tracked, and followed by campaigns, the same way
+	if status, ok := request.Status.Status(); ok {
+		state.firmwareStatus = status
+		if h.firmwareListener != nil {
+			h.firmwareListener.OnFirmwareStatus(chargePointId, status)
+		}
+	}
+	return firmware.NewSignedStatusNotificationResponse(), nil
+}
+
+// installCertificateQuery is the API payload of an InstallCertificate command.
+type installCertificateQuery struct {
+	CertificateType security.CertificateUse `json:"certificateType"`
+	Certificate     string                  `json:"certificate"`
+}
+
+func (h *SystemHandler) OnInstallCertificate(chargePointId string, payload string) (*security.InstallCertificateRequest, error) {
+	_, ok := h.getChargePoint(chargePointId)
+	if !ok {
+		return nil, fmt.Errorf("charge point not found")
+	}
+	var query installCertificateQuery
+	if err := json.Unmarshal([]byte(payload), &query); err != nil {
+		return nil, fmt.Errorf("invalid payload")
+	}
+	if !query.CertificateType.IsValid() {
+		return nil, fmt.Errorf("invalid certificate type %q", query.CertificateType)
+	}
+	block, _ := pem.Decode([]byte(query.Certificate))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no certificate in PEM")
+	}
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse certificate: %v", err)
+	}
+	request := security.NewInstallCertificateRequest(query.CertificateType, query.Certificate)
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId,
+		fmt.Sprintf("%s: %s", query.CertificateType, certificate.Subject))
+	return request, nil
+}
+
+// OnGetInstalledCertificateIds takes the certificate type as payload.
+func (h *SystemHandler) OnGetInstalledCertificateIds(chargePointId string, payload string) (*security.GetInstalledCertificateIdsRequest, error) {
+	_, ok := h.getChargePoint(chargePointId)
+	if !ok {
+		return nil, fmt.Errorf("charge point not found")
+	}
+	certificateType := security.CertificateUse(payload)
+	if !certificateType.IsValid() {
+		return nil, fmt.Errorf("invalid certificate type %q", payload)
+	}
+	request := security.NewGetInstalledCertificateIdsRequest(certificateType)
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, payload)
+	return request, nil
+}
+
+// OnDeleteCertificate takes the certificate hash data, as GetInstalledCertificateIds reported it.
+func (h *SystemHandler) OnDeleteCertificate(chargePointId string, payload string) (*security.DeleteCertificateRequest, error) {
+	_, ok := h.getChargePoint(chargePointId)
+	if !ok {
+		return nil, fmt.Errorf("charge point not found")
+	}
+	var hashData security.CertificateHashData
+	if err := json.Unmarshal([]byte(payload), &hashData); err != nil {
+		return nil, fmt.Errorf("invalid payload")
+	}
+	if !hashData.HashAlgorithm.IsValid() {
+		return nil, fmt.Errorf("invalid hash algorithm %q", hashData.HashAlgorithm)
+	}
+	if hashData.IssuerNameHash == "" || hashData.IssuerKeyHash == "" || hashData.SerialNumber == "" {
+		return nil, fmt.Errorf("issuer name hash, issuer key hash and serial number are required")
+	}
+	request := security.NewDeleteCertificateRequest(hashData)
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("serial number %s", hashData.SerialNumber))
+	return request, nil
+}
+
+// getLogQuery is the API payload of a GetLog command.
+type getLogQuery struct {
+	LogType         security.LogType `json:"logType"`
+	RemoteLocation  string           `json:"remoteLocation"`
+	OldestTimestamp *types.DateTime  `json:"oldestTimestamp,omitempty"`
+	LatestTimestamp *types.DateTime  `json:"latestTimestamp,omitempty"`
+	Retries         *int             `json:"retries,omitempty"`
+	RetryInterval   *int             `json:"retryInterval,omitempty"`
+}
+
+func (h *SystemHandler) OnGetLog(chargePointId string, payload string) (*security.GetLogRequest, error) {
+	_, ok := h.getChargePoint(chargePointId)
+	if !ok {
+		return nil, fmt.Errorf("charge point not found")
+	}
+	var query getLogQuery
+	if err := json.Unmarshal([]byte(payload), &query); err != nil {
+		return nil, fmt.Errorf("invalid payload")
+	}
+	if !query.LogType.IsValid() {
+		return nil, fmt.Errorf("invalid log type %q", query.LogType)
+	}
+	if query.RemoteLocation == "" {
+		return nil, fmt.Errorf("empty location")
+	}
+	request := security.NewGetLogRequest(query.LogType, h.nextRequestId(), query.RemoteLocation)
+	request.Log.OldestTimestamp = query.OldestTimestamp
+	request.Log.LatestTimestamp = query.LatestTimestamp
+	request.Retries = query.Retries
+	request.RetryInterval = query.RetryInterval
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId,
+		fmt.Sprintf("%s request #%d; location: %s***", query.LogType, request.RequestId, locationPrefix(query.RemoteLocation)))
+	h.recordProgress(chargePointId, progressLog, &request.RequestId, stepRequested)
+	return request, nil
+}
+
+// OnGetLogResponse adds the charge point's answer to the log progress
+func (h *SystemHandler) OnGetLogResponse(chargePointId string, request *security.GetLogRequest, response *security.GetLogResponse) {
+	h.recordProgress(chargePointId, progressLog, &request.RequestId, string(response.Status))
+}
+
+// signedUpdateFirmwareQuery is the API payload of a SignedUpdateFirmware command.
+type signedUpdateFirmwareQuery struct {
+	Location           string          `json:"location"`
+	RetrieveDateTime   *types.DateTime `json:"retrieveDateTime,omitempty"`
+	InstallDateTime    *types.DateTime `json:"installDateTime,omitempty"`
+	SigningCertificate string          `json:"signingCertificate"`
+	Signature          string          `json:"signature"`
+	Retries            *int            `json:"retries,omitempty"`
+	RetryInterval      *int            `json:"retryInterval,omitempty"`
+}
+
+func (h *SystemHandler) OnSignedUpdateFirmware(chargePointId string, payload string) (*firmware.SignedUpdateFirmwareRequest, error) {
+	_, ok := h.getChargePoint(chargePointId)
+	if !ok {
+		return nil, fmt.Errorf("charge point not found")
+	}
+	var query signedUpdateFirmwareQuery
+	if err := json.Unmarshal([]byte(payload), &query); err != nil {
+		return nil, fmt.Errorf("invalid payload")
+	}
+	if query.Location == "" {
+		return nil, fmt.Errorf("empty location")
+	}
+	if query.SigningCertificate == "" || query.Signature == "" {
+		return nil, fmt.Errorf("signing certificate and signature are required")
+	}
+	// a missing retrieve date means now
+	retrieveDate := query.RetrieveDateTime
+	if retrieveDate == nil {
+		retrieveDate = types.NewDateTime(h.getTime())
+	}
+	request := firmware.NewSignedUpdateFirmwareRequest(h.nextRequestId(), firmware.Firmware{
+		Location:           query.Location,
+		RetrieveDateTime:   retrieveDate,
+		InstallDateTime:    query.InstallDateTime,
+		SigningCertificate: query.SigningCertificate,
+		Signature:          query.Signature,
+	})
+	request.Retries = query.Retries
+	request.RetryInterval = query.RetryInterval
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId,
+		fmt.Sprintf("request #%d; location: %s***; retrieve at %s", request.RequestId, locationPrefix(query.Location), retrieveDate.Format(time.RFC3339)))
+	h.recordProgress(chargePointId, progressFirmware, &request.RequestId, stepRequested)
+	return request, nil
+}
+
+// OnSignedUpdateFirmwareResponse adds the charge point's answer to the firmware progress
+func (h *SystemHandler) OnSignedUpdateFirmwareResponse(chargePointId string, request *firmware.SignedUpdateFirmwareRequest, response *firmware.SignedUpdateFirmwareResponse) {
+	h.recordProgress(chargePointId, progressFirmware, &request.RequestId, string(response.Status))
+}
+
+// nextRequestId starts from the clock, so ids handed out before a restart are not reused while
+// charge points may still report on them.
+func (h *SystemHandler) nextRequestId() int {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if h.requestId == 0 {
+		h.requestId = int(h.getTime().Unix() % 1000000000)
+	}
+	h.requestId++
+	return h.requestId
+}
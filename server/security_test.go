@@ -0,0 +1,153 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"evsys/entity"
+	"evsys/internal"
+	"evsys/ocpp"
+	"evsys/ocpp/v16/security"
+	"evsys/pki"
+	"evsys/types"
+)
+
+type securityEventDB struct {
+	internal.Database
+	events []*entity.SecurityEvent
+}
+
+func (s *securityEventDB) AddSecurityEvent(event *entity.SecurityEvent) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+// alertListener passes on the alerts it is notified of.
+type alertListener struct {
+	internal.EventHandler
+	alerts chan *internal.EventMessage
+}
+
+func (l *alertListener) OnAlert(event *internal.EventMessage) {
+	l.alerts <- event
+}
+
+// certificateCharger accepts every certificate it is sent, and passes it on.
+type certificateCharger struct {
+	signed chan string
+}
+
+func (c *certificateCharger) SendRequest(_ string, _ ocpp.Request) (string, error) {
+	return "", nil
+}
+
+func (c *certificateCharger) SendRequestSync(_ string, request ocpp.Request, _ time.Duration) (string, error) {
+	r, ok := request.(*security.CertificateSignedRequest)
+	if !ok {
+		return "", fmt.Errorf("unexpected request %T", request)
+	}
+	c.signed <- r.CertificateChain
+	data, err := json.Marshal(security.NewCertificateSignedResponse(security.CertificateSignedStatusAccepted))
+	return string(data), err
+}
+
+func newSecurityHandler() *SystemHandler {
+	h := &SystemHandler{
+		chargePoints: map[string]*ChargePointState{},
+		logger:       stopStubLogger{},
+		location:     time.UTC,
+	}
+	h.chargePoints["CP1"] = newChargePointState(&entity.ChargePoint{Id: "CP1", SerialNumber: "SN-0001", IsEnabled: true})
+	return h
+}
+
+func newCsr(t *testing.T, commonName, organization string) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.CertificateRequest{Subject: pkix.Name{CommonName: commonName, Organization: []string{organization}}}
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}))
+}
+
+func TestSecurityEventIsStoredAndAlerted(t *testing.T) {
+	db := &securityEventDB{}
+	listener := &alertListener{alerts: make(chan *internal.EventMessage, 1)}
+	h := newSecurityHandler()
+	h.database = db
+	h.eventListeners = append(h.eventListeners, listener)
+
+	request := &security.SecurityEventNotificationRequest{
+		Type:      security.EventInvalidCentralSystemCertificate,
+		Timestamp: types.NewDateTime(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)),
+		TechInfo:  "unknown issuer",
+	}
+	if _, err := h.OnSecurityEventNotification("CP1", request); err != nil {
+		t.Fatalf("OnSecurityEventNotification: %v", err)
+	}
+	if len(db.events) != 1 || db.events[0].Type != request.Type || !db.events[0].Timestamp.Equal(request.Timestamp.Time) {
+		t.Errorf("stored %+v, want the event", db.events)
+	}
+	select {
+	case alert := <-listener.alerts:
+		if alert.ChargePointId != "CP1" || alert.Status != request.Type {
+			t.Errorf("alert %+v, want %s of CP1", alert, request.Type)
+		}
+	case <-time.After(time.Second):
+		t.Error("no alert")
+	}
+}
+
+func TestSignCertificateSendsSignedChain(t *testing.T) {
+	dir := t.TempDir()
+	ca, err := pki.LoadOrCreateLocalCA(filepath.Join(dir, "ca.pem"), filepath.Join(dir, "ca-key.pem"), "Operator", 24*time.Hour)
+	if err != nil {
+		t.Fatalf("LoadOrCreateLocalCA: %v", err)
+	}
+	charger := &certificateCharger{signed: make(chan string, 1)}
+	h := newSecurityHandler()
+	h.server = charger
+	h.SetCertificateAuthority(ca, "Operator")
+
+	for _, csr := range []string{newCsr(t, "SN-0002", "Operator"), newCsr(t, "SN-0001", "Other"), "not a request"} {
+		response, _ := h.OnSignCertificate("CP1", &security.SignCertificateRequest{Csr: csr})
+		if response.Status != security.GenericStatusRejected {
+			t.Errorf("status = %s, want a bad request rejected", response.Status)
+		}
+	}
+
+	response, _ := h.OnSignCertificate("CP1", &security.SignCertificateRequest{Csr: newCsr(t, "SN-0001", "Operator")})
+	if response.Status != security.GenericStatusAccepted {
+		t.Fatalf("status = %s, want Accepted", response.Status)
+	}
+	select {
+	case chain := <-charger.signed:
+		block, _ := pem.Decode([]byte(chain))
+		if block == nil {
+			t.Fatalf("chain %q holds no certificate", chain)
+		}
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatalf("parse signed certificate: %v", err)
+		}
+		if certificate.Subject.CommonName != "SN-0001" {
+			t.Errorf("signed for %s, want SN-0001", certificate.Subject.CommonName)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no CertificateSigned sent")
+	}
+}
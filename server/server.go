@@ -2,6 +2,8 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"evsys/internal"
@@ -12,6 +14,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"os"
 	"sync"
 	"time"
 
@@ -32,6 +35,7 @@ type envelope struct {
 	recipient   string
 	callRequest *CallRequest
 	callResult  *CallResult
+	callError   *CallError
 }
 
 func (e *envelope) getMessageData() ([]byte, error) {
@@ -41,6 +45,9 @@ func (e *envelope) getMessageData() ([]byte, error) {
 	if e.callResult != nil {
 		return e.callResult.MarshalJSON()
 	}
+	if e.callError != nil {
+		return e.callError.MarshalJSON()
+	}
 	return nil, fmt.Errorf("envelope has no message data")
 }
 
@@ -52,10 +59,14 @@ type Server struct {
 	messageHandler func(ws ocpp.WebSocket, data []byte) error
 	logger         internal.LogHandler
 	watchdog       internal.StatusHandler
+	// authenticator, when set, has to accept a charge point's Basic credentials before the upgrade
+	authenticator internal.Authenticator
+	// certificateAuthenticator, when set, checks the client certificate of a mutual TLS connection
+	certificateAuthenticator internal.CertificateAuthenticator
 	// pending maps a request's unique id to the caller waiting for its
-	// CallResult. Guarded by pendingMutex: entries are created on the caller's
-	// goroutine and resolved on the connection's read pump.
-	pending map[string]chan string
+	// CallResult or CallError. Guarded by pendingMutex: entries are created on
+	// the caller's goroutine and resolved on the connection's read pump.
+	pending map[string]chan ocpp.Answer
 	// fireAndForget holds unique ids of requests sent without a waiting caller
 	// (via SendRequest). Their CallResult is expected but discarded, so it must
 	// not be logged as unmatched. Guarded by pendingMutex.
@@ -209,7 +220,7 @@ func NewServer(conf *config.Config, logger internal.LogHandler) *Server {
 		upgrader:      websocket.Upgrader{Subprotocols: []string{}},
 		pool:          pool,
 		logger:        logger,
-		pending:       make(map[string]chan string),
+		pending:       make(map[string]chan ocpp.Answer),
 		fireAndForget: make(map[string]struct{}),
 	}
 
@@ -239,6 +250,14 @@ func (s *Server) SetWatchdog(handler internal.StatusHandler) {
 	s.watchdog = handler
 }
 
+func (s *Server) SetAuthenticator(authenticator internal.Authenticator) {
+	s.authenticator = authenticator
+}
+
+func (s *Server) SetCertificateAuthenticator(authenticator internal.CertificateAuthenticator) {
+	s.certificateAuthenticator = authenticator
+}
+
 func (s *Server) Register(router *httprouter.Router) {
 	router.GET(wsEndpoint, s.handleWsRequest)
 }
@@ -247,6 +266,23 @@ func (s *Server) handleWsRequest(w http.ResponseWriter, r *http.Request, params
 	id := params.ByName("id")
 	//s.logger.Debug(fmt.Sprintf("connection initiated from remote %s", r.RemoteAddr))
 
+	// authenticate before anything else, or an impostor could drop the connection of a real charge point
+	if s.certificateAuthenticator != nil {
+		// the handshake has verified the chain already; what is left is whose certificate it is
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 || !s.certificateAuthenticator.AuthenticateCertificate(id, r.TLS.PeerCertificates[0]) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+	}
+	if s.authenticator != nil {
+		username, password, _ := r.BasicAuth()
+		if !s.authenticator.AuthenticateChargePoint(id, username, password) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="evsys"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+	}
+
 	// check id above existed connections
 	for _, client := range s.pool.clients {
 		if client.id == id {
@@ -393,6 +429,16 @@ func (s *Server) Start() error {
 	if err != nil {
 		return err
 	}
+	if s.conf.Listen.ClientCAFile != "" {
+		if !s.conf.Listen.TLS {
+			return fmt.Errorf("client certificates need tls_enabled")
+		}
+		tlsConfig, err := clientCertificateConfig(s.conf.Listen.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		s.httpServer.TLSConfig = tlsConfig
+	}
 	if s.conf.Listen.TLS {
 		s.logger.Debug("starting https TLS server")
 		err = s.httpServer.ServeTLS(listener, s.conf.Listen.CertFile, s.conf.Listen.KeyFile)
@@ -403,6 +449,24 @@ func (s *Server) Start() error {
 	return err
 }
 
+// clientCertificateConfig requires every charge point to present a certificate issued by one of the
+// CAs in caFile (security profile 3).
+func clientCertificateConfig(caFile string) (*tls.Config, error) {
+	data, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("read client CA bundle: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates in client CA bundle %s", caFile)
+	}
+	return &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  pool,
+		MinVersion: tls.VersionTLS12,
+	}, nil
+}
+
 func (s *Server) SendResponse(ws ocpp.WebSocket, response ocpp.Response) error {
 	callResult, _ := CreateCallResult(response, ws.UniqueId())
 	env := &envelope{
@@ -413,6 +477,18 @@ func (s *Server) SendResponse(ws ocpp.WebSocket, response ocpp.Response) error {
 	return nil
 }
 
+// SendCallError answers a charge point's request that could not be handled.
+// The unique id is passed in: a request that failed to parse never set it on
+// the connection.
+func (s *Server) SendCallError(ws ocpp.WebSocket, uniqueId string, callError *CallError) error {
+	callError.UniqueId = uniqueId
+	s.pool.send <- &envelope{
+		recipient: ws.ID(),
+		callError: callError,
+	}
+	return nil
+}
+
 // SendRequest send request to the websocket and return the unique id of the request.
 // The charge point's answer is discarded; use SendRequestWithResponse or
 // SendRequestSync when the answer matters.
@@ -434,14 +510,15 @@ func (s *Server) SendRequest(clientId string, request ocpp.Request) (string, err
 }
 
 // SendRequestWithResponse queues a request and returns the channel that will
-// receive the charge point's raw CallResult payload. The error reports only
+// receive the charge point's answer: its raw CallResult payload, or its
+// CallError. The error reports only
 // whether the request could be queued, so a caller can distinguish an offline
 // charge point from one that simply has not answered yet, and wait for the
 // answer off its hot path.
 //
 // release must be called once the caller stops listening, otherwise the pending
 // entry leaks.
-func (s *Server) SendRequestWithResponse(clientId string, request ocpp.Request) (response <-chan string, release func(), err error) {
+func (s *Server) SendRequestWithResponse(clientId string, request ocpp.Request) (response <-chan ocpp.Answer, release func(), err error) {
 	if !s.pool.recipientAvailable(clientId) {
 		return nil, nil, fmt.Errorf("%s is not available", clientId)
 	}
@@ -461,8 +538,8 @@ func (s *Server) SendRequestWithResponse(clientId string, request ocpp.Request)
 }
 
 // SendRequestSync queues a request and blocks until the charge point answers,
-// returning the raw CallResult payload. It reports ErrResponseTimeout if the
-// answer does not arrive within timeout.
+// returning the raw CallResult payload. A CallError is returned as the
+// *ocpp.CallError error; ErrResponseTimeout if no answer arrives within timeout.
 func (s *Server) SendRequestSync(clientId string, request ocpp.Request, timeout time.Duration) (string, error) {
 	response, release, err := s.SendRequestWithResponse(clientId, request)
 	if err != nil {
@@ -470,8 +547,11 @@ func (s *Server) SendRequestSync(clientId string, request ocpp.Request, timeout
 	}
 	defer release()
 	select {
-	case payload := <-response:
-		return payload, nil
+	case answer := <-response:
+		if answer.Err != nil {
+			return "", answer.Err
+		}
+		return answer.Payload, nil
 	case <-time.After(timeout):
 		return "", ErrResponseTimeout
 	}
@@ -480,10 +560,21 @@ func (s *Server) SendRequestSync(clientId string, request ocpp.Request, timeout
 // ResolveResponse hands a CallResult payload to the caller waiting on it and
 // reports whether anyone was waiting.
 func (s *Server) ResolveResponse(uniqueId, payload string) bool {
+	return s.resolve(uniqueId, ocpp.Answer{Payload: payload})
+}
+
+// ResolveError hands a CallError to the caller waiting on the request it
+// answers, so the caller learns of the refusal instead of waiting out its
+// timeout. It reports whether anyone was waiting.
+func (s *Server) ResolveError(uniqueId string, callError *ocpp.CallError) bool {
+	return s.resolve(uniqueId, ocpp.Answer{Err: callError})
+}
+
+func (s *Server) resolve(uniqueId string, answer ocpp.Answer) bool {
 	s.pendingMutex.Lock()
 	channel, ok := s.pending[uniqueId]
 	if !ok {
-		// A fire-and-forget request expects a CallResult that nobody waits on;
+		// A fire-and-forget request expects an answer that nobody waits on;
 		// report it as resolved so it is not logged as unmatched.
 		if _, discard := s.fireAndForget[uniqueId]; discard {
 			delete(s.fireAndForget, uniqueId)
@@ -497,12 +588,12 @@ func (s *Server) ResolveResponse(uniqueId, payload string) bool {
 	}
 	// The channel is buffered, so a caller that has already given up waiting
 	// cannot wedge the connection's read pump here.
-	channel <- payload
+	channel <- answer
 	return true
 }
 
-func (s *Server) registerPending(uniqueId string) chan string {
-	channel := make(chan string, 1)
+func (s *Server) registerPending(uniqueId string) chan ocpp.Answer {
+	channel := make(chan ocpp.Answer, 1)
 	s.pendingMutex.Lock()
 	s.pending[uniqueId] = channel
 	s.pendingMutex.Unlock()
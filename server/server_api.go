@@ -4,8 +4,10 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"evsys/internal"
 	"evsys/internal/config"
+	"evsys/ocpp"
 	"fmt"
 	"io"
 	"net/http"
@@ -28,6 +30,9 @@ type Api struct {
 type apiResponse struct {
 	Status string `json:"status"`
 	Error  string `json:"error"`
+	// ErrorCode and ErrorDescription are the CallError the charge point answered the command with
+	ErrorCode        ocpp.ErrorCode `json:"error_code,omitempty"`
+	ErrorDescription string         `json:"error_description,omitempty"`
 }
 
 func NewServerApi(conf *config.Config, logger internal.LogHandler) *Api {
@@ -97,12 +102,21 @@ func (s *Api) handleRoot(w http.ResponseWriter, r *http.Request) {
 			Status: "error",
 			Error:  err.Error(),
 		}
+		var callError *ocpp.CallError
+		refused := errors.As(err, &callError)
+		if refused {
+			rs.ErrorCode = callError.ErrorCode
+			rs.ErrorDescription = callError.ErrorDescription
+		}
 		payload, err := json.Marshal(rs)
 		if err != nil {
 			s.logger.Warn(fmt.Sprintf("api: error encoding response: %s", err))
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		if refused {
+			w.WriteHeader(http.StatusBadGateway)
+		}
 		//w.Header().Add("Content-Type", "application/json; charset=utf-8")
 		_, err = w.Write(payload)
 		if err != nil {
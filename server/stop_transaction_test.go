@@ -62,6 +62,7 @@ type stopStubBilling struct{}
 func (stopStubBilling) OnTransactionStart(*entity.Transaction) error                     { return nil }
 func (stopStubBilling) OnTransactionFinished(*entity.Transaction) error                  { return nil }
 func (stopStubBilling) OnMeterValue(*entity.Transaction, *entity.TransactionMeter) error { return nil }
+func (stopStubBilling) Tariff(string) *entity.PaymentPlan                                { return nil }
 
 type stopStubLogger struct{}
 
@@ -2,18 +2,21 @@ package server
 
 import (
 	"encoding/json"
+	"evsys/datatransfer"
 	"evsys/entity"
 	"evsys/internal"
 	"evsys/metrics/counters"
 	"evsys/ocpp"
+	"evsys/ocpp/common"
 	"evsys/ocpp/v16/core"
 	"evsys/ocpp/v16/firmware"
-	"evsys/ocpp/v16/localauth"
 	"evsys/ocpp/v16/remotetrigger"
 	"evsys/ocpp/v16/smartcharging"
+	"evsys/pki"
 	"evsys/types"
 	"evsys/utility"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -54,12 +57,20 @@ type BillingService interface {
 	OnTransactionStart(transaction *entity.Transaction) error
 	OnTransactionFinished(transaction *entity.Transaction) error
 	OnMeterValue(transaction *entity.Transaction, transactionMeter *entity.TransactionMeter) error
+	// Tariff returns the payment plan a driver would be charged by now, nil if there is none
+	Tariff(username string) *entity.PaymentPlan
 }
 
 type ErrorListener interface {
 	OnError(data *entity.ErrorData)
 }
 
+// FirmwareListener follows charge points through a firmware update.
+type FirmwareListener interface {
+	OnFirmwareStatus(chargePointId string, status firmware.Status)
+	OnBootNotification(chargePointId string, firmwareVersion string)
+}
+
 // RequestSender sends a proactive OCPP request to a connected charge point.
 type RequestSender interface {
 	SendRequest(clientId string, request ocpp.Request) (string, error)
@@ -78,6 +89,14 @@ type ChargePointState struct {
 	errorCode         core.ChargePointErrorCode
 	model             *entity.ChargePoint
 	triggerMessage    bool
+	// monitors holds the severity of the OCPP 2.0.1 monitors seen on the charge point, by monitor id
+	monitors map[int]int
+	// firmwareProgress and logProgress follow the latest firmware update and log upload, see recordProgress
+	firmwareProgress *Progress
+	logProgress      *Progress
+	// refusedSessions holds the station transaction ids of 2.0.1 sessions refused on start, whose
+	// later events are dropped until the session ends
+	refusedSessions map[string]bool
 }
 
 func newChargePointState(chp *entity.ChargePoint) *ChargePointState {
@@ -97,6 +116,13 @@ func (st *ChargePointState) unregisterTransaction(transactionId int) {
 	delete(st.transactions, transactionId)
 }
 
+func (st *ChargePointState) refuseSession(sessionId string) {
+	if st.refusedSessions == nil {
+		st.refusedSessions = make(map[string]bool)
+	}
+	st.refusedSessions[sessionId] = true
+}
+
 func (st *ChargePointState) EvseId(connectorId int) string {
 	if st.model == nil {
 		return ""
@@ -116,28 +142,54 @@ type AuthService interface {
 }
 
 type SystemHandler struct {
-	chargePoints    map[string]*ChargePointState
-	lastMeter       map[int]*entity.TransactionMeter
-	database        internal.Database
-	billing         BillingService
-	auth            AuthService
-	errorListener   ErrorListener
-	logger          internal.LogHandler
-	eventListeners  []internal.EventHandler
-	trigger         *Trigger
-	protocolAdapter *ProtocolAdapter // Adapter for converting between OCPP versions
-	server          RequestSender    // used to push proactive requests to charge points
-	debug           bool
-	acceptTags      bool
-	acceptPoints    bool
+	chargePoints     map[string]*ChargePointState
+	lastMeter        map[int]*entity.TransactionMeter
+	database         internal.Database
+	billing          BillingService
+	auth             AuthService
+	errorListener    ErrorListener
+	firmwareListener FirmwareListener
+	dataTransfer     *datatransfer.Registry // handlers for vendor DataTransfer messages
+	logger           internal.LogHandler
+	eventListeners   []internal.EventHandler
+	trigger          *Trigger
+	protocolAdapter  *ProtocolAdapter // Adapter for converting between OCPP versions
+	server           RequestSender    // used to push proactive requests to charge points
+	debug            bool
+	acceptTags       bool
+	acceptPoints     bool
+	// allowUnsetPassword lets charge points without a stored password connect under basic auth
+	allowUnsetPassword bool
 	// meterSampleInterval, in seconds, is pushed to a charge point on boot to re-assert periodic
 	// metering; 0 disables the push
 	meterSampleInterval int
 	// meterMeasurands are unioned into a charge point's MeterValuesSampledData on boot, so the
 	// electrical readings the diagnostics rely on are actually reported; empty disables the push
 	meterMeasurands []string
-	location        *time.Location
-	mux             sync.Mutex
+	// currency is what the prices of the billing service, in cents, are shown in; costUpdateInterval,
+	// in seconds, is how often the running cost of 2.0.1 transactions is pushed, 0 for never
+	currency           string
+	costUpdateInterval int
+	// localListSyncs marks charge points with a local list sync in progress; two at once would
+	// race on the list version
+	localListSyncs map[string]bool
+	// certificateAuthority signs the certificates charge points ask for with SignCertificate; nil
+	// rejects every request. A CSR has to name certificateOrganization, when set.
+	certificateAuthority    pki.CertificateAuthority
+	certificateOrganization string
+	// contractProvider reaches the ISO 15118 Plug & Charge PKI; nil fails every 2.0.1 certificate
+	// request and refuses contract certificates at Authorize
+	contractProvider pki.ContractProvider
+	// reservations holds the connectors reserved with ReserveNow, for both protocol versions; nil
+	// refuses ReserveNow and holds nothing
+	reservations *ReservationService
+	// requestId numbers the GetLog and SignedUpdateFirmware requests, whose progress notifications
+	// refer back to it
+	requestId int
+	// certificateAlerts holds when a charge point's expiring client certificate was last alerted
+	certificateAlerts map[string]time.Time
+	location          *time.Location
+	mux               sync.Mutex
 
 	// consumedSeries remembers which label pairs the consumed power gauge currently holds, so a
 	// group that drops out of the daily aggregation - yesterday's sessions after midnight - is
@@ -158,6 +210,7 @@ func NewSystemHandler(location *time.Location) *SystemHandler {
 		lastMeter:       make(map[int]*entity.TransactionMeter),
 		eventListeners:  make([]internal.EventHandler, 0),
 		protocolAdapter: NewProtocolAdapter(),
+		dataTransfer:    datatransfer.NewRegistry(),
 		location:        location,
 		mux:             sync.Mutex{},
 	}
@@ -216,6 +269,10 @@ func (h *SystemHandler) SetServer(server RequestSender) {
 	h.server = server
 }
 
+func (h *SystemHandler) SetAllowUnsetPassword(allow bool) {
+	h.allowUnsetPassword = allow
+}
+
 func (h *SystemHandler) SetMeterSampleInterval(seconds int) {
 	h.meterSampleInterval = seconds
 }
@@ -228,6 +285,16 @@ func (h *SystemHandler) SetErrorListener(listener ErrorListener) {
 	h.errorListener = listener
 }
 
+// RegisterDataTransfer adds a handler for DataTransfer messages of a vendor; an empty message id
+// takes every message of the vendor without a handler of its own.
+func (h *SystemHandler) RegisterDataTransfer(vendorId, messageId string, handler datatransfer.HandlerFunc) {
+	h.dataTransfer.Register(vendorId, messageId, handler)
+}
+
+func (h *SystemHandler) SetFirmwareListener(listener FirmwareListener) {
+	h.firmwareListener = listener
+}
+
 // common function for event listeners
 func (h *SystemHandler) notifyEventListeners(event internal.Event, eventData *internal.EventMessage) {
 	for _, listener := range h.eventListeners {
@@ -315,6 +382,9 @@ func (h *SystemHandler) OnStart() error {
 	}
 
 	go h.sweepTransactions()
+	if h.costUpdateInterval > 0 {
+		go h.sendCostUpdates()
+	}
 
 	go h.notifyEventListeners(internal.Information, &internal.EventMessage{
 		Info: fmt.Sprintf("Started with %d charge points, %d connectors", totalPoints, totalConnectors),
@@ -471,11 +541,16 @@ func (h *SystemHandler) OnBootNotification(chargePointId string, request *core.B
 	state, ok := h.getChargePoint(chargePointId)
 	if ok {
 		if h.database != nil {
-			if state.model.SerialNumber != request.ChargePointSerialNumber || state.model.FirmwareVersion != request.FirmwareVersion {
+			protocolChanged := state.model.ProtocolVersion != "" && state.model.ProtocolVersion != string(common.OCPP16)
+			if state.model.SerialNumber != request.ChargePointSerialNumber || state.model.FirmwareVersion != request.FirmwareVersion || protocolChanged {
 				state.model.SerialNumber = request.ChargePointSerialNumber
 				state.model.FirmwareVersion = request.FirmwareVersion
 				state.model.Model = request.ChargePointModel
 				state.model.Vendor = request.ChargePointVendor
+				// a charge point moved back from 2.0.1 must get 1.6 charging profiles again
+				if protocolChanged {
+					state.model.ProtocolVersion = string(common.OCPP16)
+				}
 				err := h.database.UpdateChargePoint(state.model)
 				if err != nil {
 					h.logger.Error("update charge point", err)
@@ -485,6 +560,10 @@ func (h *SystemHandler) OnBootNotification(chargePointId string, request *core.B
 		go h.reconcileChargePointTransactions(chargePointId)
 		go h.enforceMeterValueInterval(chargePointId, state.triggerMessage)
 		go h.enforceMeterMeasurands(chargePointId)
+		go h.enforceAvailability(chargePointId, availabilityRequests(state))
+		if h.firmwareListener != nil {
+			h.firmwareListener.OnBootNotification(chargePointId, request.FirmwareVersion)
+		}
 	} else {
 		regStatus = core.RegistrationStatusRejected
 		h.logger.Debug(fmt.Sprintf("charge point %s not registered", chargePointId))
@@ -664,6 +743,11 @@ func (h *SystemHandler) OnStartTransaction(chargePointId string, request *core.S
 
 	}
 
+	held, allowed := h.claimReservation(chargePointId, request)
+	if !allowed {
+		return core.NewStartTransactionResponse(types.NewIdTagInfo(types.AuthorizationStatusInvalid), 0), nil
+	}
+
 	// TODO: check flow if the transaction is authorized on a charger itself (by credit card for example)
 	//auth := h.authorizeIdTag(chargePointId, request.IdTag)
 	//if auth != types.AuthorizationStatusAccepted {
@@ -691,6 +775,10 @@ func (h *SystemHandler) OnStartTransaction(chargePointId string, request *core.S
 		transaction.SessionId = userTag.IdTag
 	}
 
+	if held != nil {
+		h.reservations.Consume(held, transaction)
+	}
+
 	connector.CurrentTransactionId = transaction.Id
 	connector.CurrentPowerLimit = 0
 	state.registerTransaction(transaction.Id)
@@ -983,25 +1071,9 @@ func (h *SystemHandler) OnMeterValues(chargePointId string, request *core.MeterV
 			// and triggerMessage decides whether we ask for readings, not
 			// which of the ones that arrive are worth keeping.
 			if value.Measurand == types.MeasurandEnergyActiveImportRegister {
-
 				meter.Value = utility.ToInt(value.Value)
 				meter.Unit = string(value.Unit)
 				meter.Measurand = string(value.Measurand)
-
-				consumedTotal := meter.Value - transaction.MeterStart
-				if consumedTotal > 0 {
-					meter.ConsumedEnergy = consumedTotal
-				}
-
-				lastMeter, found := h.lastMeter[transaction.Id]
-				if found {
-					consumed := meter.Value - lastMeter.Value
-					seconds := meter.Time.Sub(lastMeter.Time).Seconds()
-					if consumed > 0 && seconds > 0.0 {
-						meter.PowerRateWh = float64(consumed) * (3600 / 1000) / seconds //used in metrics
-						meter.PowerRate = int(meter.PowerRateWh * 1000)
-					}
-				}
 			}
 
 			if value.Measurand == types.MeasurandSoC {
@@ -1028,31 +1100,52 @@ func (h *SystemHandler) OnMeterValues(chargePointId string, request *core.MeterV
 
 		}
 
-		if meter.Value > 0 {
-			h.lastMeter[transaction.Id] = meter
+		h.recordTransactionMeter(chp, connector, transaction, meter)
+	}
 
-			// replace calculated values if received data from charger
-			if meter.PowerActive > 0 {
-				meter.PowerRate = meter.PowerActive
-				meter.PowerRateWh = float64(meter.PowerActive) / 1000
-			}
+	return core.NewMeterValuesResponse(), nil
+}
 
-			counters.ObservePowerRate(chp.model.LocationId, chargePointId, connector.ID(), meter.PowerRateWh)
+// recordTransactionMeter completes a reading of a running transaction with the energy consumed so far
+// and the power rate since the previous reading, and stores it. A reading without the energy register
+// is dropped: nothing else in it can be priced or charted.
+func (h *SystemHandler) recordTransactionMeter(chp *ChargePointState, connector *entity.Connector, transaction *entity.Transaction, meter *entity.TransactionMeter) {
+	if meter.Value <= 0 {
+		return
+	}
+	consumedTotal := meter.Value - transaction.MeterStart
+	if consumedTotal > 0 {
+		meter.ConsumedEnergy = consumedTotal
+	}
+	lastMeter, found := h.lastMeter[transaction.Id]
+	if found {
+		consumed := meter.Value - lastMeter.Value
+		seconds := meter.Time.Sub(lastMeter.Time).Seconds()
+		if consumed > 0 && seconds > 0.0 {
+			meter.PowerRateWh = float64(consumed) * (3600 / 1000) / seconds //used in metrics
+			meter.PowerRate = int(meter.PowerRateWh * 1000)
+		}
+	}
+	h.lastMeter[transaction.Id] = meter
 
-			// billing calculates charge price and must be called before meter value save
-			err = h.billing.OnMeterValue(transaction, meter)
-			if err != nil {
-				h.logger.Error("billing on meter value", err)
-			}
+	// replace calculated values if received data from charger
+	if meter.PowerActive > 0 {
+		meter.PowerRate = meter.PowerActive
+		meter.PowerRateWh = float64(meter.PowerActive) / 1000
+	}
 
-			err = h.database.AddTransactionMeterValue(meter)
-			if err != nil {
-				h.logger.Error("add transaction meter value", err)
-			}
-		}
+	counters.ObservePowerRate(chp.model.LocationId, chp.model.Id, connector.ID(), meter.PowerRateWh)
+
+	// billing calculates charge price and must be called before meter value save
+	err := h.billing.OnMeterValue(transaction, meter)
+	if err != nil {
+		h.logger.Error("billing on meter value", err)
 	}
 
-	return core.NewMeterValuesResponse(), nil
+	err = h.database.AddTransactionMeterValue(meter)
+	if err != nil {
+		h.logger.Error("add transaction meter value", err)
+	}
 }
 
 // isVehicleSideReading reports whether a sample measures the side of the charge
@@ -1192,8 +1285,69 @@ func (h *SystemHandler) OnDataTransfer(chargePointId string, request *core.DataT
 	if !ok {
 		return core.NewDataTransferResponse(core.DataTransferStatusRejected), nil
 	}
-	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("recieved data #%v", request.Data))
-	return core.NewDataTransferResponse(core.DataTransferStatusAccepted), nil
+	if h.dataTransfer == nil {
+		h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("%s/%s: no handlers; data %v", request.VendorId, request.MessageId, request.Data))
+		return core.NewDataTransferResponse(core.DataTransferStatusUnknownVendorId), nil
+	}
+	message := &datatransfer.Message{
+		ChargePointId: chargePointId,
+		VendorId:      request.VendorId,
+		MessageId:     request.MessageId,
+		Data:          request.Data,
+	}
+	result, err := h.dataTransfer.Handle(message)
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("data transfer %s/%s from %s", request.VendorId, request.MessageId, chargePointId), err)
+	}
+	switch result.Status {
+	case core.DataTransferStatusUnknownVendorId, core.DataTransferStatusUnknownMessageId:
+		// unhandled data is logged, so it can be looked at before somebody writes a handler for it
+		h.logger.FeatureEvent(request.GetFeatureName(), chargePointId,
+			fmt.Sprintf("%s/%s: %s; data %v", request.VendorId, request.MessageId, result.Status, request.Data))
+	default:
+		h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("%s/%s: %s", request.VendorId, request.MessageId, result.Status))
+	}
+	for _, event := range result.Events {
+		if event.Message.ChargePointId == "" {
+			event.Message.ChargePointId = chargePointId
+		}
+		if event.Message.Time.IsZero() {
+			event.Message.Time = h.getTime()
+		}
+		go h.notifyEventListeners(event.Kind, event.Message)
+	}
+	response := core.NewDataTransferResponse(result.Status)
+	response.Data = result.Data
+	return response, nil
+}
+
+// dataTransferQuery is the API payload of a DataTransfer command.
+type dataTransferQuery struct {
+	VendorId  string          `json:"vendorId"`
+	MessageId string          `json:"messageId,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+}
+
+// OnSendDataTransfer builds a DataTransfer to a charge point; the data is passed on as given.
+func (h *SystemHandler) OnSendDataTransfer(chargePointId string, payload string) (*core.DataTransferRequest, error) {
+	_, ok := h.getChargePoint(chargePointId)
+	if !ok {
+		return nil, fmt.Errorf("charge point not found")
+	}
+	var query dataTransferQuery
+	if err := json.Unmarshal([]byte(payload), &query); err != nil {
+		return nil, fmt.Errorf("invalid payload")
+	}
+	request := core.NewDataTransferRequest(query.VendorId)
+	request.MessageId = query.MessageId
+	if len(query.Data) > 0 {
+		request.Data = query.Data
+	}
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("sending %s/%s", request.VendorId, request.MessageId))
+	return request, nil
 }
 
 func (h *SystemHandler) OnDiagnosticsStatusNotification(chargePointId string, request *firmware.DiagnosticsStatusNotificationRequest) (*firmware.DiagnosticsStatusNotificationResponse, error) {
@@ -1201,6 +1355,7 @@ func (h *SystemHandler) OnDiagnosticsStatusNotification(chargePointId string, re
 	if ok {
 		state.diagnosticsStatus = request.Status
 		h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("updated diagnostic status to %v", request.Status))
+		h.recordProgress(chargePointId, progressLog, nil, string(request.Status))
 	}
 	return firmware.NewDiagnosticsStatusNotificationResponse(), nil
 }
@@ -1210,6 +1365,10 @@ func (h *SystemHandler) OnFirmwareStatusNotification(chargePointId string, reque
 	if ok {
 		state.firmwareStatus = request.Status
 		h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("updated firmware status to %v", request.Status))
+		h.recordProgress(chargePointId, progressFirmware, nil, string(request.Status))
+		if h.firmwareListener != nil {
+			h.firmwareListener.OnFirmwareStatus(chargePointId, request.Status)
+		}
 	}
 	return firmware.NewStatusNotificationResponse(), nil
 }
@@ -1224,35 +1383,6 @@ func (h *SystemHandler) OnTriggerMessage(chargePointId string, connectorId int,
 	return request, nil
 }
 
-func (h *SystemHandler) OnSendLocalList(chargePointId string) (*localauth.SendLocalListRequest, error) {
-	state, ok := h.getChargePoint(chargePointId)
-	if !ok {
-		return nil, fmt.Errorf("charge point %s not found", chargePointId)
-	}
-	version := state.model.LocalAuthVersion + 1
-	request := localauth.NewSendLocalListRequest(version, localauth.UpdateTypeFull)
-	authList := make([]localauth.AuthorizationData, 0)
-	if h.database != nil {
-		ids, err := h.database.GetActiveUserTags(chargePointId, version)
-		if err != nil {
-			h.logger.Error("get active user tags", err)
-		} else {
-			for _, id := range ids {
-				authList = append(authList, localauth.AuthorizationData{
-					IdTag: id.IdTag,
-					IdTagInfo: &types.IdTagInfo{
-						//TODO: add expiry date
-						Status: types.AuthorizationStatusAccepted,
-					},
-				})
-			}
-		}
-	}
-	request.LocalAuthorizationList = authList
-	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("sending local auth list #%v", version))
-	return request, nil
-}
-
 func (h *SystemHandler) OnRemoteStartTransaction(chargePointId string, connectorId int, idTag string) (*core.RemoteStartTransactionRequest, error) {
 	h.mux.Lock()
 	defer h.mux.Unlock()
@@ -1405,6 +1535,41 @@ func (h *SystemHandler) OnGetDiagnostics(chargePointId string, payload string) (
 	// out of the log; a short location must not slice out of bounds.
 	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId,
 		fmt.Sprintf("location: %s***", locationPrefix(payload)))
+	h.recordProgress(chargePointId, progressLog, nil, stepRequested)
+	return request, nil
+}
+
+// updateFirmwareQuery is the API payload of an UpdateFirmware command for a single charge point.
+type updateFirmwareQuery struct {
+	Location      string          `json:"location"`
+	RetrieveDate  *types.DateTime `json:"retrieveDate,omitempty"`
+	Retries       *int            `json:"retries,omitempty"`
+	RetryInterval *int            `json:"retryInterval,omitempty"`
+}
+
+func (h *SystemHandler) OnUpdateFirmware(chargePointId string, payload string) (*firmware.UpdateFirmwareRequest, error) {
+	_, ok := h.getChargePoint(chargePointId)
+	if !ok {
+		return nil, fmt.Errorf("charge point not found")
+	}
+	var query updateFirmwareQuery
+	if err := json.Unmarshal([]byte(payload), &query); err != nil {
+		return nil, fmt.Errorf("invalid payload")
+	}
+	if query.Location == "" {
+		return nil, fmt.Errorf("empty location")
+	}
+	// a missing retrieve date means now
+	retrieveDate := query.RetrieveDate
+	if retrieveDate == nil {
+		retrieveDate = types.NewDateTime(h.getTime())
+	}
+	request := firmware.NewUpdateFirmwareRequest(query.Location, retrieveDate)
+	request.Retries = query.Retries
+	request.RetryInterval = query.RetryInterval
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId,
+		fmt.Sprintf("location: %s***; retrieve at %s", locationPrefix(query.Location), retrieveDate.Format(time.RFC3339)))
+	h.recordProgress(chargePointId, progressFirmware, nil, stepRequested)
 	return request, nil
 }
 
@@ -1879,6 +2044,20 @@ func (h *SystemHandler) getConnectorByEvseAndConnectorId(cps *ChargePointState,
 	return h.getConnector(cps, connectorId)
 }
 
+// evseConnectors lists the connectors of an EVSE, sorted by id. A connector whose EVSE is not known
+// yet is taken to be on the EVSE of its own id, as with a single connector per EVSE.
+// Called with h.mux held.
+func evseConnectors(cps *ChargePointState, evseId int) []*entity.Connector {
+	connectors := make([]*entity.Connector, 0)
+	for _, connector := range cps.connectors {
+		if connector.EvseId != nil && *connector.EvseId == evseId || connector.EvseId == nil && connector.Id == evseId {
+			connectors = append(connectors, connector)
+		}
+	}
+	sort.Slice(connectors, func(i, j int) bool { return connectors[i].Id < connectors[j].Id })
+	return connectors
+}
+
 // updateConnectorEvseId updates the EVSE ID for a connector (OCPP 2.0.1)
 // This is called when we receive EVSE information from a 2.0.1 charge point
 func (h *SystemHandler) updateConnectorEvseId(connector *entity.Connector, evseId *int) error {
@@ -1907,6 +2086,9 @@ func (h *SystemHandler) authorizeIdTag(chargePointId, idTag string) types.Author
 	userTag := h.getUserTag(idTag)
 	if userTag.IsEnabled {
 		authStatus = types.AuthorizationStatusAccepted
+		if userTag.IsExpiredAt(time.Now()) {
+			authStatus = types.AuthorizationStatusExpired
+		}
 	}
 
 	// invalid state indicated that user not listed in local database or not enabled locally
@@ -0,0 +1,220 @@
+package server
+
+import (
+	"evsys/entity"
+	"evsys/internal"
+	"evsys/ocpp/v201"
+	"evsys/ocpp/v201/transactions"
+	"fmt"
+	"time"
+)
+
+// A charge point numbers the TransactionEvent messages of a transaction from 0 with seqNo. It
+// resends a message it got no answer to, and sends the messages it queued while offline once it is
+// back, so an event may arrive twice, after a later one, or after the central system has closed the
+// transaction itself. The transaction keeps the highest seqNo handled and the ones skipped to get
+// there, which tells the three apart.
+
+// metadataStartMissing marks a transaction recorded from a later event before its Started arrived
+const metadataStartMissing = "start_missing"
+
+// maxMissingSeqNos bounds the skipped seqNos kept on a transaction; the oldest are dropped first
+const maxMissingSeqNos = 100
+
+// eventOrder is where a TransactionEvent falls in the sequence of its transaction
+type eventOrder int
+
+const (
+	eventInOrder  eventOrder = iota // later than any handled, possibly after a gap
+	eventLate                       // fills a gap left earlier
+	eventRepeated                   // handled already
+)
+
+// orderSeqNo places seqNo in the sequence of a transaction and records it there; a seqNo in
+// order returns the ones it skipped
+func orderSeqNo(transaction *entity.Transaction, seqNo int) (eventOrder, []int) {
+	if seqNo > transaction.SeqNo {
+		gap := seqNoGap(transaction.SeqNo, seqNo)
+		transaction.SeqNo = seqNo
+		transaction.MissingSeqNos = append(transaction.MissingSeqNos, gap...)
+		if excess := len(transaction.MissingSeqNos) - maxMissingSeqNos; excess > 0 {
+			transaction.MissingSeqNos = transaction.MissingSeqNos[excess:]
+		}
+		return eventInOrder, gap
+	}
+	for i, missing := range transaction.MissingSeqNos {
+		if missing == seqNo {
+			transaction.MissingSeqNos = append(transaction.MissingSeqNos[:i], transaction.MissingSeqNos[i+1:]...)
+			return eventLate, nil
+		}
+	}
+	return eventRepeated, nil
+}
+
+// seqNoGap lists the seqNos between last and next, the latest maxMissingSeqNos of them
+func seqNoGap(last, next int) []int {
+	first := last + 1
+	if next-first > maxMissingSeqNos {
+		first = next - maxMissingSeqNos
+	}
+	var gap []int
+	for seqNo := first; seqNo < next; seqNo++ {
+		gap = append(gap, seqNo)
+	}
+	return gap
+}
+
+// stationTransaction finds the transaction, finished or not, a 2.0.1 charge point knows by sessionId
+func (h *SystemHandler) stationTransaction(chargePointId, sessionId string) *entity.Transaction {
+	if h.database == nil || sessionId == "" {
+		return nil
+	}
+	transaction, err := h.database.GetTransactionBySessionId(chargePointId, sessionId)
+	if err != nil {
+		h.logger.Error("get transaction by session id", err)
+		return nil
+	}
+	return transaction
+}
+
+// sequenceEvent places a TransactionEvent in the sequence of its transaction and stores the
+// result; a repeated event is only logged
+func (h *V201Handlers) sequenceEvent(chargePointId string, transaction *entity.Transaction, seqNo int) eventOrder {
+	last := transaction.SeqNo
+	order, gap := orderSeqNo(transaction, seqNo)
+	switch {
+	case order == eventRepeated:
+		h.logger.FeatureEvent("TransactionEvent", chargePointId, fmt.Sprintf("v2.0.1: transaction #%d: seqNo %d handled already",
+			transaction.Id, seqNo))
+		return order
+	case order == eventLate:
+		h.logger.FeatureEvent("TransactionEvent", chargePointId, fmt.Sprintf("v2.0.1: transaction #%d: seqNo %d arrived late, missing %v",
+			transaction.Id, seqNo, transaction.MissingSeqNos))
+	case len(gap) > 0:
+		h.logger.Warn(fmt.Sprintf("transaction #%d on %s: seqNo %d follows %d, %d events missing",
+			transaction.Id, chargePointId, seqNo, last, seqNo-last-1))
+	}
+	if err := h.systemHandler.database.UpdateTransactionSequence(transaction.Id, transaction.SeqNo, transaction.MissingSeqNos); err != nil {
+		h.logger.Error("update transaction sequence", err)
+	}
+	return order
+}
+
+// startMissingTransaction records the transaction of an Updated or Ended event whose Started has
+// not arrived, from what the event tells; the Started fills in the rest when it comes, see
+// reconcileStart. The session has run on the charge point already, so it is never refused.
+func (h *V201Handlers) startMissingTransaction(chargePointId string, state *ChargePointState, connector *entity.Connector, transaction *entity.Transaction, request *transactions.TransactionEventRequest) *entity.Transaction {
+	if h.systemHandler.database == nil {
+		return nil
+	}
+	h.logger.Warn(fmt.Sprintf("%s event of unknown transaction %s on %s, seqNo %d: recorded without its start",
+		request.EventType, transaction.SessionId, chargePointId, request.SeqNo))
+
+	started := &entity.Transaction{
+		SessionId:       transaction.SessionId,
+		ChargePointId:   chargePointId,
+		ConnectorId:     transaction.ConnectorId,
+		EvseId:          transaction.EvseId,
+		IdTag:           transaction.IdTag,
+		ReservationId:   transaction.ReservationId,
+		TimeStart:       transaction.TimeStart,
+		MeterStart:      transaction.MeterStop,
+		SeqNo:           request.SeqNo,
+		MissingSeqNos:   seqNoGap(-1, request.SeqNo),
+		ProtocolVersion: transaction.ProtocolVersion,
+		Metadata:        transaction.Metadata,
+	}
+	// the earliest reading of the event is the closest to the start there is
+	if reading, at, ok := firstEnergyReading(request.MeterValue); ok {
+		started.MeterStart = reading
+		if at.Before(started.TimeStart) {
+			started.TimeStart = at
+		}
+	}
+	started.Metadata[metadataStartMissing] = true
+	h.startTransaction(chargePointId, state, connector, started, true)
+	return started
+}
+
+// reconcileStart completes a transaction recorded before its Started arrived. The start figures
+// change the price, so an ended transaction is billed again.
+func (h *V201Handlers) reconcileStart(chargePointId string, existingTx, transaction *entity.Transaction) {
+	if missing, _ := existingTx.Metadata[metadataStartMissing].(bool); !missing {
+		h.logger.Warn(fmt.Sprintf("transaction %s on %s started already as #%d", transaction.SessionId, chargePointId, existingTx.Id))
+		return
+	}
+	h.logger.FeatureEvent("TransactionEvent", chargePointId, fmt.Sprintf("v2.0.1: transaction #%d: start arrived late", existingTx.Id))
+
+	existingTx.Lock()
+	delete(existingTx.Metadata, metadataStartMissing)
+	if transaction.TimeStart.Before(existingTx.TimeStart) {
+		existingTx.TimeStart = transaction.TimeStart
+	}
+	if transaction.MeterStart > 0 {
+		existingTx.MeterStart = transaction.MeterStart
+	}
+	if existingTx.IdTag == "" && transaction.IdTag != "" {
+		userTag := h.systemHandler.getUserTag(transaction.IdTag)
+		existingTx.IdTag = transaction.IdTag
+		existingTx.UserTag = userTag
+		existingTx.Username = userTag.Username
+	}
+	if existingTx.IsFinished && h.systemHandler.billing != nil {
+		_ = h.systemHandler.billing.OnTransactionFinished(existingTx)
+	}
+	if err := h.systemHandler.database.UpdateTransaction(existingTx); err != nil {
+		h.logger.Error("update transaction", err)
+	}
+	existingTx.Unlock()
+
+	if transaction.ReservationId != nil && existingTx.ReservationId == nil && h.systemHandler.reservations != nil {
+		h.systemHandler.consumeReservation201(chargePointId, *transaction.ReservationId, existingTx)
+	}
+}
+
+// reconcileEnd decides whether the Ended event of a finished transaction replaces how it ended.
+// It does when the central system closed the transaction itself, as after a reboot of the charge
+// point: the charge point's own figures are the real ones. A transaction the charge point ended
+// already keeps its first end.
+func (h *V201Handlers) reconcileEnd(chargePointId string, state *ChargePointState, existingTx, transaction *entity.Transaction) bool {
+	if !isSystemClosedReason(existingTx.Reason) {
+		h.logger.Warn(fmt.Sprintf("transaction #%d on %s ended already as %q", existingTx.Id, chargePointId, existingTx.Reason))
+		return false
+	}
+	h.logger.Warn(fmt.Sprintf("late end of transaction #%d on %s: system had closed it as %q at %s, charger now reports meter %d, reason %s",
+		existingTx.Id, chargePointId, existingTx.Reason, existingTx.TimeStop.Format(time.RFC3339), transaction.MeterStop, transaction.Reason))
+	go h.systemHandler.notifyEventListeners(internal.Alert, &internal.EventMessage{
+		ChargePointId: chargePointId,
+		ConnectorId:   existingTx.ConnectorId,
+		LocationId:    state.model.LocationId,
+		Evse:          state.EvseId(existingTx.ConnectorId),
+		TransactionId: existingTx.Id,
+		Username:      existingTx.Username,
+		IdTag:         existingTx.IdTag,
+		Info:          fmt.Sprintf("late end after the system closed the transaction as %q", existingTx.Reason),
+	})
+	// an end without a reading keeps the one the system closed with
+	if transaction.MeterStop == 0 {
+		transaction.MeterStop = existingTx.MeterStop
+	}
+	return true
+}
+
+// firstEnergyReading is the earliest energy register reading among meter values
+func firstEnergyReading(meterValues []v201.MeterValue) (int, time.Time, bool) {
+	var reading int
+	var at time.Time
+	found := false
+	for _, meterValue := range meterValues {
+		if found && !meterValue.Timestamp.Before(at) {
+			continue
+		}
+		for _, sampledValue := range meterValue.SampledValue {
+			if sampledValue.Measurand == v201.MeasurandEnergyActiveImportRegister || sampledValue.Measurand == "" {
+				reading, at, found = int(sampledValue.Value), meterValue.Timestamp, true
+				break
+			}
+		}
+	}
+	return reading, at, found
+}
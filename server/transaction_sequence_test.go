@@ -0,0 +1,214 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"evsys/entity"
+	"evsys/internal"
+	"evsys/ocpp/v201"
+	"evsys/ocpp/v201/transactions"
+)
+
+// sequenceStubDB keeps the transactions of one charge point in memory.
+type sequenceStubDB struct {
+	internal.Database
+	transactions []*entity.Transaction
+	sequences    int
+}
+
+func (s *sequenceStubDB) GetTransactionBySessionId(_, sessionId string) (*entity.Transaction, error) {
+	for i := len(s.transactions) - 1; i >= 0; i-- {
+		if s.transactions[i].SessionId == sessionId {
+			return s.transactions[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *sequenceStubDB) UpdateTransactionSequence(int, int, []int) error {
+	s.sequences++
+	return nil
+}
+
+func (s *sequenceStubDB) AddTransaction(t *entity.Transaction) error {
+	s.transactions = append(s.transactions, t)
+	return nil
+}
+
+func (s *sequenceStubDB) UpdateTransaction(*entity.Transaction) error             { return nil }
+func (s *sequenceStubDB) UpdateConnector(*entity.Connector) error                 { return nil }
+func (s *sequenceStubDB) AddTransactionMeterValue(*entity.TransactionMeter) error { return nil }
+func (s *sequenceStubDB) ReadTransactionMeterValue(int) (*entity.TransactionMeter, error) {
+	return nil, nil
+}
+func (s *sequenceStubDB) GetUserTag(idTag string) (*entity.UserTag, error) {
+	return &entity.UserTag{IdTag: idTag, Username: "driver", IsEnabled: true}, nil
+}
+func (s *sequenceStubDB) UpdateTagLastSeen(*entity.UserTag) error { return nil }
+
+func newSequenceHandlers() (*V201Handlers, *sequenceStubDB, *entity.Connector) {
+	db := &sequenceStubDB{}
+	h := NewSystemHandler(time.UTC)
+	h.database = db
+	h.billing = costStubBilling{}
+	h.logger = stopStubLogger{}
+	evseId := 1
+	connector := entity.NewConnector(1, "CP1")
+	connector.EvseId = &evseId
+	state := newChargePointState(&entity.ChargePoint{Id: "CP1", ProtocolVersion: "ocpp2.0.1", IsOnline: true})
+	state.connectors[1] = connector
+	h.chargePoints["CP1"] = state
+	return NewV201Handlers(h, stopStubLogger{}), db, connector
+}
+
+var sequenceStart = time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+// transactionEvent is an event of transaction TX-1 on EVSE 1, minutes after its start, reading wh
+func transactionEvent(eventType v201.TransactionEventType, seqNo int, minutes int, wh float64) *transactions.TransactionEventRequest {
+	connectorId := 1
+	at := sequenceStart.Add(time.Duration(minutes) * time.Minute)
+	request := &transactions.TransactionEventRequest{
+		EventType:       eventType,
+		Timestamp:       at,
+		TriggerReason:   v201.TriggerReasonMeterValuePeriodic,
+		SeqNo:           seqNo,
+		TransactionInfo: v201.Transaction{TransactionId: "TX-1"},
+		Evse:            &v201.EVSE{Id: 1, ConnectorId: &connectorId},
+		MeterValue: []v201.MeterValue{{
+			Timestamp:    at,
+			SampledValue: []v201.SampledValue{{Value: wh, Measurand: v201.MeasurandEnergyActiveImportRegister}},
+		}},
+	}
+	if eventType == v201.TransactionEventStarted {
+		request.IdToken = &v201.IdToken{IdToken: "TAG1", Type: v201.IdTokenTypeISO14443}
+	}
+	if eventType == v201.TransactionEventEnded {
+		request.TransactionInfo.StoppedReason = "EVDisconnected"
+	}
+	return request
+}
+
+func handleEvents(t *testing.T, handlers *V201Handlers, requests ...*transactions.TransactionEventRequest) {
+	t.Helper()
+	for _, request := range requests {
+		if _, err := handlers.OnTransactionEvent("CP1", request); err != nil {
+			t.Fatalf("OnTransactionEvent(%s, seqNo %d): %v", request.EventType, request.SeqNo, err)
+		}
+	}
+}
+
+func TestOrderSeqNo(t *testing.T) {
+	transaction := &entity.Transaction{}
+	steps := []struct {
+		seqNo   int
+		order   eventOrder
+		missing []int
+	}{
+		{1, eventInOrder, nil},
+		{4, eventInOrder, []int{2, 3}},
+		{4, eventRepeated, []int{2, 3}},
+		{3, eventLate, []int{2}},
+		{3, eventRepeated, []int{2}},
+		{0, eventRepeated, []int{2}},
+	}
+	for _, step := range steps {
+		if order, _ := orderSeqNo(transaction, step.seqNo); order != step.order {
+			t.Errorf("seqNo %d: order %d, want %d", step.seqNo, order, step.order)
+		}
+		if !reflect.DeepEqual(transaction.MissingSeqNos, step.missing) {
+			t.Errorf("seqNo %d: missing %v, want %v", step.seqNo, transaction.MissingSeqNos, step.missing)
+		}
+	}
+
+	// a gap far larger than what is kept leaves the latest seqNos of it
+	orderSeqNo(transaction, 1000)
+	if n := len(transaction.MissingSeqNos); n != maxMissingSeqNos || transaction.MissingSeqNos[n-1] != 999 {
+		t.Errorf("kept %d missing seqNos ending %d, want %d ending 999", n, transaction.MissingSeqNos[n-1], maxMissingSeqNos)
+	}
+}
+
+func TestRepeatedStartIsNotRecordedTwice(t *testing.T) {
+	handlers, db, _ := newSequenceHandlers()
+	start := transactionEvent(v201.TransactionEventStarted, 0, 0, 1000)
+	handleEvents(t, handlers, start, start)
+
+	if len(db.transactions) != 1 {
+		t.Fatalf("recorded %d transactions, want 1", len(db.transactions))
+	}
+}
+
+func TestSkippedSeqNosAreRecorded(t *testing.T) {
+	handlers, db, _ := newSequenceHandlers()
+	handleEvents(t, handlers,
+		transactionEvent(v201.TransactionEventStarted, 0, 0, 1000),
+		transactionEvent(v201.TransactionEventUpdated, 3, 15, 4000),
+		transactionEvent(v201.TransactionEventUpdated, 1, 5, 2000),
+	)
+
+	transaction := db.transactions[0]
+	if transaction.SeqNo != 3 || !reflect.DeepEqual(transaction.MissingSeqNos, []int{2}) {
+		t.Errorf("seqNo %d missing %v, want 3 missing [2]", transaction.SeqNo, transaction.MissingSeqNos)
+	}
+	if db.sequences != 2 {
+		t.Errorf("stored the sequence %d times, want 2", db.sequences)
+	}
+}
+
+func TestEventsQueuedOfflineAreReordered(t *testing.T) {
+	handlers, db, connector := newSequenceHandlers()
+	offline := true
+	started := transactionEvent(v201.TransactionEventStarted, 0, 0, 1000)
+	started.Offline = &offline
+
+	// the charge point resends the Started after the events that followed it
+	handleEvents(t, handlers,
+		transactionEvent(v201.TransactionEventUpdated, 1, 30, 6000),
+		transactionEvent(v201.TransactionEventEnded, 2, 60, 11000),
+		started,
+	)
+
+	if len(db.transactions) != 1 {
+		t.Fatalf("recorded %d transactions, want 1", len(db.transactions))
+	}
+	transaction := db.transactions[0]
+	if !transaction.IsFinished || transaction.Reason != "EVDisconnected" {
+		t.Errorf("transaction finished=%v reason %q, want ended by EVDisconnected", transaction.IsFinished, transaction.Reason)
+	}
+	if !transaction.TimeStart.Equal(sequenceStart) || transaction.MeterStart != 1000 || transaction.MeterStop != 11000 {
+		t.Errorf("transaction ran from %s at %d Wh to %d Wh, want from %s at 1000 Wh to 11000 Wh",
+			transaction.TimeStart, transaction.MeterStart, transaction.MeterStop, sequenceStart)
+	}
+	if transaction.IdTag != "TAG1" || transaction.Username != "driver" {
+		t.Errorf("transaction of %q (%q), want TAG1 of driver", transaction.IdTag, transaction.Username)
+	}
+	if _, missing := transaction.Metadata[metadataStartMissing]; missing || len(transaction.MissingSeqNos) != 0 {
+		t.Errorf("start still missing: metadata %v, seqNos %v", transaction.Metadata, transaction.MissingSeqNos)
+	}
+	if connector.CurrentTransactionId != -1 {
+		t.Errorf("connector holds transaction %d, want it released", connector.CurrentTransactionId)
+	}
+}
+
+func TestEndReplacesSystemClose(t *testing.T) {
+	handlers, db, _ := newSequenceHandlers()
+	handleEvents(t, handlers, transactionEvent(v201.TransactionEventStarted, 0, 0, 1000))
+
+	// the central system closed the transaction at the last reading it had
+	transaction := db.transactions[0]
+	transaction.IsFinished = true
+	transaction.Reason = reasonStoppedBySystem
+	transaction.MeterStop = 3000
+
+	handleEvents(t, handlers, transactionEvent(v201.TransactionEventEnded, 4, 90, 9000))
+	if transaction.Reason != "EVDisconnected" || transaction.MeterStop != 9000 {
+		t.Errorf("transaction ended as %q at %d Wh, want EVDisconnected at 9000 Wh", transaction.Reason, transaction.MeterStop)
+	}
+
+	// a second end is not taken
+	handleEvents(t, handlers, transactionEvent(v201.TransactionEventEnded, 5, 95, 9500))
+	if transaction.MeterStop != 9000 {
+		t.Errorf("transaction ended at %d Wh, want the first end of 9000 Wh kept", transaction.MeterStop)
+	}
+}
@@ -9,8 +9,14 @@ import (
 	"evsys/ocpp/v201"
 	"evsys/ocpp/v201/authorization"
 	"evsys/ocpp/v201/availability"
+	"evsys/ocpp/v201/diagnostics"
+	"evsys/ocpp/v201/firmware"
+	"evsys/ocpp/v201/metervalues"
+	"evsys/ocpp/v201/monitoring"
 	"evsys/ocpp/v201/provisioning"
 	"evsys/ocpp/v201/remotecontrol"
+	"evsys/ocpp/v201/reservation"
+	"evsys/ocpp/v201/smartcharging"
 	"evsys/ocpp/v201/transactions"
 	"fmt"
 	"log"
@@ -30,6 +36,16 @@ type V201Handlers struct {
 	systemHandler   *SystemHandler
 	protocolAdapter *ProtocolAdapter
 	logger          internal.LogHandler
+	// alertSeverity, errorSeverity and defaultSeverity grade NotifyEvent, see SetMonitoringSeverities
+	alertSeverity   int
+	errorSeverity   int
+	defaultSeverity int
+	// powerManager judges the charging schedules EVs propose, see SetPowerManager
+	powerManager PowerManager
+	// deviceModel stores the reported variables, see SetDeviceModel
+	deviceModel DeviceModel
+	// customerData stores the reported customer information, see SetCustomerData
+	customerData CustomerData
 }
 
 // NewV201Handlers creates a new set of OCPP 2.0.1 handlers
@@ -38,6 +54,9 @@ func NewV201Handlers(systemHandler *SystemHandler, logger internal.LogHandler) *
 		systemHandler:   systemHandler,
 		protocolAdapter: systemHandler.GetProtocolAdapter(),
 		logger:          logger,
+		alertSeverity:   monitoring.SeveritySystemFailure,
+		errorSeverity:   monitoring.SeverityError,
+		defaultSeverity: monitoring.SeverityError,
 	}
 }
 
@@ -47,8 +66,14 @@ func NewV201Handlers(systemHandler *SystemHandler, logger internal.LogHandler) *
 
 // OnBootNotification handles OCPP 2.0.1 BootNotification requests
 func (h *V201Handlers) OnBootNotification(chargePointId string, request *provisioning.BootNotificationRequest) (*provisioning.BootNotificationResponse, error) {
-	h.logger.FeatureEvent("BootNotification", chargePointId, fmt.Sprintf("v2.0.1: %s %s (reason: %s)",
-		request.ChargingStation.VendorName, request.ChargingStation.Model, request.Reason))
+	return h.onBootNotification(chargePointId, request, common.OCPP201)
+}
+
+// onBootNotification registers a 2.x charge point with the protocol version it booted with, which
+// later decides how the central system talks to it
+func (h *V201Handlers) onBootNotification(chargePointId string, request *provisioning.BootNotificationRequest, protocol common.ProtocolVersion) (*provisioning.BootNotificationResponse, error) {
+	h.logger.FeatureEvent("BootNotification", chargePointId, fmt.Sprintf("%s: %s %s (reason: %s)",
+		protocol, request.ChargingStation.VendorName, request.ChargingStation.Model, request.Reason))
 
 	// Get or create charge point state
 	h.systemHandler.mux.Lock()
@@ -72,12 +97,17 @@ func (h *V201Handlers) OnBootNotification(chargePointId string, request *provisi
 		state.model.Model = request.ChargingStation.Model
 		state.model.SerialNumber = request.ChargingStation.SerialNumber
 		state.model.FirmwareVersion = request.ChargingStation.FirmwareVersion
-		state.model.ProtocolVersion = string(common.OCPP201)
+		state.model.ProtocolVersion = string(protocol)
 
 		// Update in database
 		if h.systemHandler.database != nil {
 			_ = h.systemHandler.database.UpdateChargePoint(state.model)
 		}
+
+		h.systemHandler.mux.Lock()
+		requests := availabilityRequests201(state)
+		h.systemHandler.mux.Unlock()
+		go h.systemHandler.enforceAvailability201(chargePointId, requests)
 	}
 
 	// Send heartbeat interval back
@@ -101,31 +131,15 @@ func (h *V201Handlers) OnHeartbeat(chargePointId string, request *provisioning.H
 	return response, nil
 }
 
-// OnNotifyReport handles OCPP 2.0.1 NotifyReport requests
+// OnNotifyReport handles OCPP 2.0.1 NotifyReport requests; the device model store stitches the
+// parts of a report together
 func (h *V201Handlers) OnNotifyReport(chargePointId string, request *provisioning.NotifyReportRequest) (*provisioning.NotifyReportResponse, error) {
-	h.logger.FeatureEvent("NotifyReport", chargePointId, fmt.Sprintf("v2.0.1: requestId=%d, seqNo=%d",
-		request.RequestId, request.SeqNo))
-
-	// Store device model data in charge point's DeviceModel field
-	h.systemHandler.mux.Lock()
-	state, ok := h.systemHandler.getChargePoint(chargePointId)
-	if ok && state.model != nil {
-		if state.model.DeviceModel == nil {
-			state.model.DeviceModel = make(map[string]interface{})
-		}
-		// Store report data (simplified - in production you'd parse ReportData properly)
-		state.model.DeviceModel["last_report"] = map[string]interface{}{
-			"request_id":   request.RequestId,
-			"seq_no":       request.SeqNo,
-			"generated_at": request.GeneratedAt,
-			"tbc":          request.Tbc,
-		}
+	h.logger.FeatureEvent("NotifyReport", chargePointId, fmt.Sprintf("v2.0.1: requestId=%d, seqNo=%d, %d variables, tbc=%v",
+		request.RequestId, request.SeqNo, len(request.ReportData), request.Tbc))
 
-		if h.systemHandler.database != nil {
-			_ = h.systemHandler.database.UpdateChargePoint(state.model)
-		}
+	if h.deviceModel != nil {
+		h.deviceModel.OnNotifyReport(chargePointId, request)
 	}
-	h.systemHandler.mux.Unlock()
 
 	response := &provisioning.NotifyReportResponse{}
 	return response, nil
@@ -146,6 +160,18 @@ func (h *V201Handlers) OnAuthorize(chargePointId string, request *authorization.
 	// Prepare response
 	response := &authorization.AuthorizeResponse{}
 
+	// a Plug & Charge EV presents its contract; only an accepted one goes on to the eMAID check
+	if request.Certificate != "" || len(request.Iso15118CertificateHashData) > 0 {
+		response.CertificateStatus = h.verifyContract(chargePointId, request)
+		h.logger.FeatureEvent("Authorize", chargePointId, fmt.Sprintf("v2.0.1: contract %s", response.CertificateStatus))
+		if response.CertificateStatus != v201.AuthorizeCertificateStatusAccepted {
+			response.IdTokenInfo = v201.IdTokenInfo{
+				Status: v201.AuthorizationStatusInvalid,
+			}
+			return response, nil
+		}
+	}
+
 	if !userTag.IsEnabled && !h.systemHandler.acceptTags {
 		response.IdTokenInfo = v201.IdTokenInfo{
 			Status: v201.AuthorizationStatusInvalid,
@@ -188,32 +214,29 @@ func (h *V201Handlers) OnAuthorize(chargePointId string, request *authorization.
 		}
 	}
 
-	// Authorization successful
+	// Authorization successful; the driver sees the tariff before plugging in
 	response.IdTokenInfo = v201.IdTokenInfo{
-		Status: v201.AuthorizationStatusAccepted,
+		Status:          v201.AuthorizationStatusAccepted,
+		PersonalMessage: h.systemHandler.tariffMessage(userTag.Username),
 	}
 
 	return response, nil
 }
 
-// OnClearedChargingLimit handles OCPP 2.0.1 ClearedChargingLimit requests
-func (h *V201Handlers) OnClearedChargingLimit(chargePointId string, request *authorization.ClearedChargingLimitRequest) (*authorization.ClearedChargingLimitResponse, error) {
-	h.logger.FeatureEvent("ClearedChargingLimit", chargePointId, fmt.Sprintf("v2.0.1: source=%s", request.ChargingLimitSource))
-
-	// This is primarily informational - charging station notifies that a limit has been cleared
-	response := &authorization.ClearedChargingLimitResponse{}
-	return response, nil
-}
-
 // ============================================================================
 // TRANSACTIONS HANDLER
 // ============================================================================
 
 // OnTransactionEvent handles OCPP 2.0.1 TransactionEvent requests
-// This replaces StartTransaction, StopTransaction, and MeterValues from 1.6J
+// This replaces StartTransaction, StopTransaction, and MeterValues from 1.6J.
+// Events are matched to the transaction by the id the charge point assigned, finished or not, and
+// ordered by seqNo: a repeated event is acknowledged without effect, a skipped seqNo is logged, and
+// the events a charge point queued while offline are reconciled with what the central system did
+// meanwhile, see transaction_sequence.go.
 func (h *V201Handlers) OnTransactionEvent(chargePointId string, request *transactions.TransactionEventRequest) (*transactions.TransactionEventResponse, error) {
-	h.logger.FeatureEvent("TransactionEvent", chargePointId, fmt.Sprintf("v2.0.1: type=%s, trigger=%s, txId=%s",
-		request.EventType, request.TriggerReason, request.TransactionInfo.TransactionId))
+	offline := request.Offline != nil && *request.Offline
+	h.logger.FeatureEvent("TransactionEvent", chargePointId, fmt.Sprintf("v2.0.1: type=%s, trigger=%s, txId=%s, seqNo=%d, offline=%v",
+		request.EventType, request.TriggerReason, request.TransactionInfo.TransactionId, request.SeqNo, offline))
 
 	// Convert OCPP 2.0.1 TransactionEvent to internal Transaction using protocol adapter
 	transaction, err := h.protocolAdapter.TransactionEventToEntity(
@@ -221,6 +244,7 @@ func (h *V201Handlers) OnTransactionEvent(chargePointId string, request *transac
 		request.TransactionInfo,
 		request.IdToken,
 		request.Evse,
+		request.ReservationId,
 		request.MeterValue,
 		request.Timestamp,
 		chargePointId,
@@ -251,131 +275,210 @@ func (h *V201Handlers) OnTransactionEvent(chargePointId string, request *transac
 	if evseId != nil {
 		_ = h.systemHandler.updateConnectorEvseId(connector, evseId)
 	}
+	refused := state.refusedSessions[transaction.SessionId]
+	if refused && request.EventType == v201.TransactionEventEnded {
+		delete(state.refusedSessions, transaction.SessionId)
+	}
 	h.systemHandler.mux.Unlock()
 
 	response := &transactions.TransactionEventResponse{}
 
+	// a session refused on start has no transaction, and its later events must not record one
+	if refused {
+		h.logger.Warn(fmt.Sprintf("%s event of refused transaction %s on %s, seqNo %d ignored",
+			request.EventType, transaction.SessionId, chargePointId, request.SeqNo))
+		if request.IdToken != nil {
+			response.IdTokenInfo = &v201.IdTokenInfo{Status: v201.AuthorizationStatusInvalid}
+		}
+		return response, nil
+	}
+
+	existingTx := h.systemHandler.stationTransaction(chargePointId, transaction.SessionId)
+	order := eventInOrder
+	if existingTx != nil {
+		order = h.sequenceEvent(chargePointId, existingTx, request.SeqNo)
+		if order == eventRepeated {
+			return response, nil
+		}
+	}
+
 	// Handle based on event type
 	switch request.EventType {
 	case v201.TransactionEventStarted:
-		// Transaction starting
-		transaction.Init()
-		h.systemHandler.setTransactionProtocolVersion(transaction, chargePointId)
-
-		// Get user tag and username
-		if transaction.IdTag != "" {
-			userTag := h.systemHandler.getUserTag(transaction.IdTag)
-			transaction.UserTag = userTag
-			transaction.Username = userTag.Username
+		if existingTx != nil {
+			h.reconcileStart(chargePointId, existingTx, transaction)
+			break
 		}
-
-		// Set transaction ID
-		newTransactionId++
-		transaction.Id = newTransactionId
-
-		// Call billing service
-		if h.systemHandler.billing != nil {
-			_ = h.systemHandler.billing.OnTransactionStart(transaction)
+		transaction.SeqNo = request.SeqNo
+		if !h.startTransaction(chargePointId, state, connector, transaction, offline) {
+			h.systemHandler.mux.Lock()
+			state.refuseSession(transaction.SessionId)
+			h.systemHandler.mux.Unlock()
+			response.IdTokenInfo = &v201.IdTokenInfo{Status: v201.AuthorizationStatusInvalid}
 		}
 
-		// Save to database
-		if h.systemHandler.database != nil {
-			_ = h.systemHandler.database.AddTransaction(transaction)
+	case v201.TransactionEventUpdated:
+		// Transaction update (e.g., meter values); the answer carries the running cost for the display
+		if existingTx == nil {
+			existingTx = h.startMissingTransaction(chargePointId, state, connector, transaction, request)
+		}
+		if existingTx == nil {
+			break
+		}
+		if existingTx.IsFinished {
+			h.logger.Warn(fmt.Sprintf("update of transaction #%d on %s after it ended as %q, seqNo %d ignored",
+				existingTx.Id, chargePointId, existingTx.Reason, request.SeqNo))
+			break
+		}
+		// a token authorized after the session started may use up a reservation only now
+		if order == eventInOrder && transaction.ReservationId != nil && existingTx.ReservationId == nil {
+			h.systemHandler.consumeReservation201(chargePointId, *transaction.ReservationId, existingTx)
+		}
+		for _, meterValue := range request.MeterValue {
+			tm, err := h.protocolAdapter.MeterValue201ToTransactionMeter(meterValue, existingTx.Id)
+			if err != nil {
+				h.logger.Warn(fmt.Sprintf("meter value from %s: %v", chargePointId, err))
+				continue
+			}
+			tm.Minute = tm.Time.Unix() / 60
+			tm.ConnectorId = connector.Id
+			tm.ConnectorStatus = connector.Status
+			h.systemHandler.recordTransactionMeter(state, connector, existingTx, tm)
+		}
+		if price, ok := h.systemHandler.runningCost(existingTx); ok {
+			totalCost := cost(price)
+			response.TotalCost = &totalCost
 		}
 
-		// Update connector
-		connector.Lock()
-		connector.CurrentTransactionId = transaction.Id
-		connector.Unlock()
-		if h.systemHandler.database != nil {
-			_ = h.systemHandler.database.UpdateConnector(connector)
+	case v201.TransactionEventEnded:
+		if existingTx == nil {
+			existingTx = h.startMissingTransaction(chargePointId, state, connector, transaction, request)
+		}
+		if existingTx == nil {
+			break
 		}
+		if existingTx.IsFinished && !h.reconcileEnd(chargePointId, state, existingTx, transaction) {
+			break
+		}
+		h.finishTransaction(chargePointId, state, connector, existingTx, transaction, response)
+	}
 
-		// Register transaction
-		state.registerTransaction(transaction.Id)
-		h.systemHandler.updateActiveTransactionsCounter()
+	return response, nil
+}
 
-		// Notify event listeners
-		go h.systemHandler.notifyEventListeners(internal.TransactionStart, &internal.EventMessage{
-			ChargePointId: chargePointId,
-			ConnectorId:   connector.Id,
-			TransactionId: transaction.Id,
-			Username:      transaction.Username,
-			IdTag:         transaction.IdTag,
-			Time:          transaction.TimeStart,
-		})
+// startTransaction records a transaction the charge point started; false when a reservation of
+// another id token holds the EVSE. A session the charge point started while offline has already
+// run, so it is recorded even then.
+func (h *V201Handlers) startTransaction(chargePointId string, state *ChargePointState, connector *entity.Connector, transaction *entity.Transaction, offline bool) bool {
+	transaction.Init()
+	h.systemHandler.setTransactionProtocolVersion(transaction, chargePointId)
 
-		log.Printf("Transaction %d started on %s connector %d (OCPP 2.0.1)", transaction.Id, chargePointId, connector.Id)
+	// A session on a reserved EVSE consumes the reservation of its id token, or is refused
+	held, allowed := h.systemHandler.claimReservation201(chargePointId, connector, transaction)
+	if !allowed && !offline {
+		return false
+	}
 
-	case v201.TransactionEventUpdated:
-		// Transaction update (e.g., meter values)
-		if h.systemHandler.database != nil {
-			// Find existing transaction
-			existingTx, err := h.systemHandler.database.GetTransaction(transaction.Id)
-			if err == nil && existingTx != nil {
-				// Process meter values
-				for _, meterValue := range request.MeterValue {
-					tm, err := h.protocolAdapter.MeterValue201ToTransactionMeter(meterValue, existingTx.Id)
-					if err == nil {
-						// Calculate price
-						if h.systemHandler.billing != nil {
-							_ = h.systemHandler.billing.OnMeterValue(existingTx, tm)
-						}
-						// Save meter value
-						_ = h.systemHandler.database.AddTransactionMeterValue(tm)
-					}
-				}
-			}
-		}
+	// Get user tag and username
+	if transaction.IdTag != "" {
+		userTag := h.systemHandler.getUserTag(transaction.IdTag)
+		transaction.UserTag = userTag
+		transaction.Username = userTag.Username
+	}
 
-	case v201.TransactionEventEnded:
-		// Transaction ended
-		if h.systemHandler.database != nil {
-			existingTx, err := h.systemHandler.database.GetTransaction(transaction.Id)
-			if err == nil && existingTx != nil {
-				existingTx.Lock()
-				existingTx.IsFinished = true
-				existingTx.TimeStop = transaction.TimeStop
-				existingTx.MeterStop = transaction.MeterStop
-				existingTx.Reason = transaction.Reason
-
-				// Call billing service
-				if h.systemHandler.billing != nil {
-					_ = h.systemHandler.billing.OnTransactionFinished(existingTx)
-				}
-
-				// Update in database
-				_ = h.systemHandler.database.UpdateTransaction(existingTx)
-				existingTx.Unlock()
-
-				// Update connector
-				connector.Lock()
-				connector.CurrentTransactionId = -1
-				connector.Unlock()
-				if h.systemHandler.database != nil {
-					_ = h.systemHandler.database.UpdateConnector(connector)
-				}
-
-				// Unregister transaction
-				state.unregisterTransaction(existingTx.Id)
-				h.systemHandler.updateActiveTransactionsCounter()
-
-				// Notify event listeners
-				go h.systemHandler.notifyEventListeners(internal.TransactionStop, &internal.EventMessage{
-					ChargePointId: chargePointId,
-					ConnectorId:   connector.Id,
-					TransactionId: existingTx.Id,
-					Username:      existingTx.Username,
-					IdTag:         existingTx.IdTag,
-					Time:          existingTx.TimeStop,
-				})
-
-				log.Printf("Transaction %d stopped on %s connector %d (OCPP 2.0.1)", existingTx.Id, chargePointId, connector.Id)
-			}
+	// Set transaction ID
+	newTransactionId++
+	transaction.Id = newTransactionId
+	if held != nil {
+		h.systemHandler.reservations.Consume(held, transaction)
+	}
+
+	// Call billing service
+	if h.systemHandler.billing != nil {
+		_ = h.systemHandler.billing.OnTransactionStart(transaction)
+	}
+
+	// Save to database
+	if h.systemHandler.database != nil {
+		_ = h.systemHandler.database.AddTransaction(transaction)
+	}
+
+	// Update connector
+	connector.Lock()
+	connector.CurrentTransactionId = transaction.Id
+	connector.Unlock()
+	if h.systemHandler.database != nil {
+		_ = h.systemHandler.database.UpdateConnector(connector)
+	}
+
+	// Register transaction
+	state.registerTransaction(transaction.Id)
+	h.systemHandler.updateActiveTransactionsCounter()
+
+	// Notify event listeners
+	go h.systemHandler.notifyEventListeners(internal.TransactionStart, &internal.EventMessage{
+		ChargePointId: chargePointId,
+		ConnectorId:   connector.Id,
+		TransactionId: transaction.Id,
+		Username:      transaction.Username,
+		IdTag:         transaction.IdTag,
+		Time:          transaction.TimeStart,
+	})
+
+	log.Printf("Transaction %d started on %s connector %d (OCPP 2.0.1)", transaction.Id, chargePointId, connector.Id)
+	return true
+}
+
+// finishTransaction closes a transaction with the figures of its Ended event
+func (h *V201Handlers) finishTransaction(chargePointId string, state *ChargePointState, connector *entity.Connector, existingTx, transaction *entity.Transaction, response *transactions.TransactionEventResponse) {
+	if h.systemHandler.database == nil {
+		return
+	}
+	existingTx.Lock()
+	existingTx.IsFinished = true
+	existingTx.TimeStop = transaction.TimeStop
+	existingTx.MeterStop = transaction.MeterStop
+	existingTx.Reason = transaction.Reason
+
+	// Call billing service; the final cost goes back to the charge point
+	if h.systemHandler.billing != nil {
+		_ = h.systemHandler.billing.OnTransactionFinished(existingTx)
+		if existingTx.Plan != nil {
+			totalCost := cost(existingTx.PaymentAmount)
+			response.TotalCost = &totalCost
 		}
 	}
 
-	return response, nil
+	// Update in database
+	_ = h.systemHandler.database.UpdateTransaction(existingTx)
+	existingTx.Unlock()
+
+	// Update connector; one that has moved on to another transaction keeps it
+	connector.Lock()
+	released := connector.CurrentTransactionId == existingTx.Id || connector.CurrentTransactionId <= 0
+	if released {
+		connector.CurrentTransactionId = -1
+	}
+	connector.Unlock()
+	if released {
+		_ = h.systemHandler.database.UpdateConnector(connector)
+	}
+
+	// Unregister transaction
+	state.unregisterTransaction(existingTx.Id)
+	h.systemHandler.updateActiveTransactionsCounter()
+
+	// Notify event listeners
+	go h.systemHandler.notifyEventListeners(internal.TransactionStop, &internal.EventMessage{
+		ChargePointId: chargePointId,
+		ConnectorId:   connector.Id,
+		TransactionId: existingTx.Id,
+		Username:      existingTx.Username,
+		IdTag:         existingTx.IdTag,
+		Time:          existingTx.TimeStop,
+	})
+
+	log.Printf("Transaction %d stopped on %s connector %d (OCPP 2.0.1)", existingTx.Id, chargePointId, connector.Id)
 }
 
 // ============================================================================
@@ -431,6 +534,148 @@ func (h *V201Handlers) OnStatusNotification(chargePointId string, request *avail
 	return response, nil
 }
 
+// ============================================================================
+// METER VALUES HANDLER
+// ============================================================================
+
+// OnMeterValues handles OCPP 2.0.1 MeterValues requests. Readings of an EVSE with a running
+// transaction are recorded with it, as 1.6 MeterValues are; all others, including the main meter on
+// EVSE 0, are kept as the latest sample of the charge point.
+func (h *V201Handlers) OnMeterValues(chargePointId string, request *metervalues.MeterValuesRequest) (*metervalues.MeterValuesResponse, error) {
+	h.logger.FeatureEvent(metervalues.MeterValuesFeatureName, chargePointId, fmt.Sprintf("v2.0.1: EVSE=%d, %d values",
+		request.EvseId, len(request.MeterValue)))
+	response := &metervalues.MeterValuesResponse{}
+
+	h.systemHandler.mux.Lock()
+	state, ok := h.systemHandler.getChargePoint(chargePointId)
+	if !ok {
+		h.systemHandler.mux.Unlock()
+		return nil, fmt.Errorf("charge point not found: %s", chargePointId)
+	}
+	var connector *entity.Connector
+	if request.EvseId > 0 {
+		connector = evseConnector(state, request.EvseId)
+	}
+	h.systemHandler.mux.Unlock()
+
+	database := h.systemHandler.database
+	if database == nil {
+		return response, nil
+	}
+
+	var transaction *entity.Transaction
+	if connector != nil && connector.CurrentTransactionId >= 0 {
+		transaction, _ = database.GetTransaction(connector.CurrentTransactionId)
+	}
+
+	for _, meterValue := range request.MeterValue {
+		transactionId := -1
+		if transaction != nil {
+			transactionId = transaction.Id
+		}
+		meter, err := h.protocolAdapter.MeterValue201ToTransactionMeter(meterValue, transactionId)
+		if err != nil {
+			h.logger.Warn(fmt.Sprintf("meter value from %s: %v", chargePointId, err))
+			continue
+		}
+		meter.Minute = meter.Time.Unix() / 60
+		if transaction != nil {
+			meter.ConnectorId = connector.Id
+			meter.ConnectorStatus = connector.Status
+			h.systemHandler.recordTransactionMeter(state, connector, transaction, meter)
+			continue
+		}
+		meter.ChargePointId = chargePointId
+		meter.ConnectorId = request.EvseId
+		if err = database.AddSampleMeterValue(meter); err != nil {
+			h.logger.Error("add sample meter value", err)
+		}
+	}
+	return response, nil
+}
+
+// evseConnector picks the connector of an EVSE that is charging, or else any connector of it
+func evseConnector(state *ChargePointState, evseId int) *entity.Connector {
+	var found *entity.Connector
+	for _, connector := range state.connectors {
+		if connector.EvseId == nil || *connector.EvseId != evseId {
+			continue
+		}
+		if connector.CurrentTransactionId >= 0 {
+			return connector
+		}
+		found = connector
+	}
+	return found
+}
+
+// ============================================================================
+// SMART CHARGING HANDLER
+// ============================================================================
+
+// OnReportChargingProfiles handles OCPP 2.0.1 ReportChargingProfiles requests, the answer to a
+// GetChargingProfiles command. The profiles are logged; the load balancer keeps its own record of the
+// limits it installed.
+func (h *V201Handlers) OnReportChargingProfiles(chargePointId string, request *smartcharging.ReportChargingProfilesRequest) (*smartcharging.ReportChargingProfilesResponse, error) {
+	for _, profile := range request.ChargingProfile {
+		limits := ""
+		for _, schedule := range profile.ChargingSchedule {
+			for _, period := range schedule.ChargingSchedulePeriod {
+				limits += fmt.Sprintf(" %d:%.1f%s", period.StartPeriod, period.Limit, schedule.ChargingRateUnit)
+			}
+		}
+		h.logger.FeatureEvent(smartcharging.ReportChargingProfilesFeatureName, chargePointId, fmt.Sprintf(
+			"v2.0.1: requestId=%d, EVSE=%d, source=%s: profile %d %s at stack level %d;%s",
+			request.RequestId, request.EvseId, request.ChargingLimitSource,
+			profile.Id, profile.ChargingProfilePurpose, profile.StackLevel, limits))
+	}
+	return &smartcharging.ReportChargingProfilesResponse{}, nil
+}
+
+// ============================================================================
+// FIRMWARE HANDLER
+// ============================================================================
+
+// OnFirmwareStatusNotification handles OCPP 2.0.1 FirmwareStatusNotification requests; every step is
+// added to the firmware progress of the charging station
+func (h *V201Handlers) OnFirmwareStatusNotification(chargePointId string, request *firmware.FirmwareStatusNotificationRequest) (*firmware.FirmwareStatusNotificationResponse, error) {
+	info := fmt.Sprintf("v2.0.1: firmware status %s", request.Status)
+	if request.RequestId != nil {
+		info = fmt.Sprintf("%s; request #%d", info, *request.RequestId)
+	}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, info)
+	h.systemHandler.recordProgress(chargePointId, progressFirmware, request.RequestId, string(request.Status))
+	return &firmware.FirmwareStatusNotificationResponse{}, nil
+}
+
+// ============================================================================
+// DIAGNOSTICS HANDLER
+// ============================================================================
+
+// OnLogStatusNotification handles OCPP 2.0.1 LogStatusNotification requests; every step is added to
+// the log progress of the charging station
+func (h *V201Handlers) OnLogStatusNotification(chargePointId string, request *diagnostics.LogStatusNotificationRequest) (*diagnostics.LogStatusNotificationResponse, error) {
+	info := fmt.Sprintf("v2.0.1: log upload %s", request.Status)
+	if request.RequestId != nil {
+		info = fmt.Sprintf("%s; request #%d", info, *request.RequestId)
+	}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, info)
+	h.systemHandler.recordProgress(chargePointId, progressLog, request.RequestId, string(request.Status))
+	return &diagnostics.LogStatusNotificationResponse{}, nil
+}
+
+// OnNotifyCustomerInformation handles OCPP 2.0.1 NotifyCustomerInformation requests; the customer
+// data manager stitches the parts of a report together. The data is not logged: it is the customer's.
+func (h *V201Handlers) OnNotifyCustomerInformation(chargePointId string, request *diagnostics.NotifyCustomerInformationRequest) (*diagnostics.NotifyCustomerInformationResponse, error) {
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: requestId=%d, seqNo=%d, tbc=%v",
+		request.RequestId, request.SeqNo, request.Tbc))
+
+	if h.customerData != nil {
+		h.customerData.OnNotifyCustomerInformation(chargePointId, request)
+	}
+	return &diagnostics.NotifyCustomerInformationResponse{}, nil
+}
+
 // ============================================================================
 // API COMMAND HANDLERS (CSMS → Charging Station)
 // ============================================================================
@@ -577,3 +822,174 @@ func (h *V201Handlers) OnTriggerMessage(chargePointId string, payload string) (o
 	// For now, return an error as TriggerMessage is not yet fully implemented
 	return nil, fmt.Errorf("TriggerMessage not yet implemented for OCPP 2.0.1")
 }
+
+// OnSetChargingProfile creates a SetChargingProfile request for OCPP 2.0.1; the payload is the
+// chargingProfile object and the connector id of the command is the EVSE
+func (h *V201Handlers) OnSetChargingProfile(chargePointId string, evseId int, payload string) (ocpp.Request, error) {
+	var profile v201.ChargingProfile
+	if err := json.Unmarshal([]byte(payload), &profile); err != nil {
+		return nil, fmt.Errorf("invalid payload")
+	}
+	request := smartcharging.NewSetChargingProfileRequest(evseId, profile)
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: EVSE=%d, profile %d %s",
+		evseId, profile.Id, profile.ChargingProfilePurpose))
+	return request, nil
+}
+
+// OnGetChargingProfiles creates a GetChargingProfiles request for OCPP 2.0.1; an empty payload asks for
+// every profile, otherwise it is the chargingProfile criterion. The profiles arrive in
+// ReportChargingProfiles.
+func (h *V201Handlers) OnGetChargingProfiles(chargePointId string, evseId int, payload string) (ocpp.Request, error) {
+	request := &smartcharging.GetChargingProfilesRequest{RequestId: h.systemHandler.nextRequestId()}
+	if payload != "" {
+		if err := json.Unmarshal([]byte(payload), &request.ChargingProfile); err != nil {
+			return nil, fmt.Errorf("invalid payload")
+		}
+	}
+	if evseId > 0 {
+		request.EvseId = &evseId
+	}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: requestId=%d, EVSE=%d",
+		request.RequestId, evseId))
+	return request, nil
+}
+
+// OnClearChargingProfile creates a ClearChargingProfile request for OCPP 2.0.1 from a JSON payload
+// holding chargingProfileId or chargingProfileCriteria
+func (h *V201Handlers) OnClearChargingProfile(chargePointId string, payload string) (ocpp.Request, error) {
+	var request smartcharging.ClearChargingProfileRequest
+	if err := json.Unmarshal([]byte(payload), &request); err != nil {
+		return nil, fmt.Errorf("invalid payload")
+	}
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: payload=%s", payload))
+	return &request, nil
+}
+
+// OnGetCompositeSchedule creates a GetCompositeSchedule request for OCPP 2.0.1, taking the same
+// payload as on 1.6: a duration, or an object with duration and chargingRateUnit
+func (h *V201Handlers) OnGetCompositeSchedule(chargePointId string, evseId int, payload string) (ocpp.Request, error) {
+	query, err := parseCompositeScheduleQuery(payload)
+	if err != nil {
+		return nil, err
+	}
+	request := smartcharging.NewGetCompositeScheduleRequest(evseId, query.Duration)
+	request.ChargingRateUnit = v201.ChargingRateUnitType(query.ChargingRateUnit)
+	if err = request.Validate(); err != nil {
+		return nil, err
+	}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: EVSE=%d, duration=%d, unit=%q",
+		evseId, request.Duration, request.ChargingRateUnit))
+	return request, nil
+}
+
+// HandleResponse records the outcome of an API command the charge point has answered, for the
+// commands that change what the central system knows about it
+func (h *V201Handlers) HandleResponse(chargePointId string, request ocpp.Request, payload []byte) error {
+	switch r := request.(type) {
+	case *reservation.ReserveNowRequest, *reservation.CancelReservationRequest:
+		return h.onReservationResponse(chargePointId, request, payload)
+	case *monitoring.SetVariableMonitoringRequest, *monitoring.ClearVariableMonitoringRequest, *monitoring.SetMonitoringBaseRequest:
+		return h.onMonitoringResponse(chargePointId, request, payload)
+	case *availability.ChangeAvailabilityRequest:
+		return h.onChangeAvailabilityResponse(chargePointId, r, payload)
+	case *firmware.UpdateFirmwareRequest:
+		var response firmware.UpdateFirmwareResponse
+		if err := json.Unmarshal(payload, &response); err != nil {
+			return fmt.Errorf("invalid %s response", request.GetFeatureName())
+		}
+		h.systemHandler.recordProgress(chargePointId, progressFirmware, &r.RequestId, string(response.Status))
+	case *diagnostics.GetLogRequest:
+		var response diagnostics.GetLogResponse
+		if err := json.Unmarshal(payload, &response); err != nil {
+			return fmt.Errorf("invalid %s response", request.GetFeatureName())
+		}
+		h.systemHandler.recordProgress(chargePointId, progressLog, &r.RequestId, string(response.Status))
+	}
+	return nil
+}
+
+// updateFirmwareQuery201 is the API payload of a 2.0.1 UpdateFirmware command; the signature fields
+// are optional, as the charging station decides whether it needs them
+type updateFirmwareQuery201 struct {
+	Location           string     `json:"location"`
+	RetrieveDateTime   *time.Time `json:"retrieveDateTime,omitempty"`
+	InstallDateTime    *time.Time `json:"installDateTime,omitempty"`
+	SigningCertificate string     `json:"signingCertificate,omitempty"`
+	Signature          string     `json:"signature,omitempty"`
+	Retries            *int       `json:"retries,omitempty"`
+	RetryInterval      *int       `json:"retryInterval,omitempty"`
+}
+
+// OnUpdateFirmware creates an UpdateFirmware request for OCPP 2.0.1; a missing retrieve time means now.
+// The request starts a new firmware progress record.
+func (h *V201Handlers) OnUpdateFirmware(chargePointId string, payload string) (ocpp.Request, error) {
+	var query updateFirmwareQuery201
+	if err := json.Unmarshal([]byte(payload), &query); err != nil {
+		return nil, fmt.Errorf("invalid payload")
+	}
+	retrieveDateTime := h.systemHandler.getTime()
+	if query.RetrieveDateTime != nil {
+		retrieveDateTime = *query.RetrieveDateTime
+	}
+	request := &firmware.UpdateFirmwareRequest{
+		Retries:       query.Retries,
+		RetryInterval: query.RetryInterval,
+		Firmware: firmware.FirmwareType{
+			Location:           query.Location,
+			RetrieveDateTime:   retrieveDateTime,
+			InstallDateTime:    query.InstallDateTime,
+			SigningCertificate: query.SigningCertificate,
+			Signature:          query.Signature,
+		},
+	}
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	request.RequestId = h.systemHandler.nextRequestId()
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: request #%d; location: %s***; retrieve at %s; signed: %v",
+		request.RequestId, locationPrefix(query.Location), retrieveDateTime.Format(time.RFC3339), query.Signature != ""))
+	h.systemHandler.recordProgress(chargePointId, progressFirmware, &request.RequestId, stepRequested)
+	return request, nil
+}
+
+// getLogQuery201 is the API payload of a 2.0.1 GetLog command, the same as on 1.6
+type getLogQuery201 struct {
+	LogType         diagnostics.LogType `json:"logType"`
+	RemoteLocation  string              `json:"remoteLocation"`
+	OldestTimestamp *time.Time          `json:"oldestTimestamp,omitempty"`
+	LatestTimestamp *time.Time          `json:"latestTimestamp,omitempty"`
+	Retries         *int                `json:"retries,omitempty"`
+	RetryInterval   *int                `json:"retryInterval,omitempty"`
+}
+
+// OnGetLog creates a GetLog request for OCPP 2.0.1; the request starts a new log progress record
+func (h *V201Handlers) OnGetLog(chargePointId string, payload string) (ocpp.Request, error) {
+	var query getLogQuery201
+	if err := json.Unmarshal([]byte(payload), &query); err != nil {
+		return nil, fmt.Errorf("invalid payload")
+	}
+	request := &diagnostics.GetLogRequest{
+		Log: diagnostics.LogParametersType{
+			RemoteLocation:  query.RemoteLocation,
+			OldestTimestamp: query.OldestTimestamp,
+			LatestTimestamp: query.LatestTimestamp,
+		},
+		LogType:       query.LogType,
+		Retries:       query.Retries,
+		RetryInterval: query.RetryInterval,
+	}
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	request.RequestId = h.systemHandler.nextRequestId()
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.0.1: %s request #%d; location: %s***",
+		query.LogType, request.RequestId, locationPrefix(query.RemoteLocation)))
+	h.systemHandler.recordProgress(chargePointId, progressLog, &request.RequestId, stepRequested)
+	return request, nil
+}
@@ -0,0 +1,194 @@
+package server
+
+import (
+	"encoding/json"
+	"evsys/ocpp"
+	"evsys/ocpp/common"
+	"evsys/ocpp/v201"
+	"evsys/ocpp/v201/provisioning"
+	smartcharging21 "evsys/ocpp/v21/smartcharging"
+	"evsys/ocpp/v21/tariff"
+	transactions21 "evsys/ocpp/v21/transactions"
+	"fmt"
+	"strings"
+)
+
+// ============================================================================
+// OCPP 2.1 Business Logic Handlers
+// ============================================================================
+// A 2.1 charging station sends the 2.0.1 messages 2.1 left unchanged, and
+// those go to the 2.0.1 handlers. V21Handlers takes the messages 2.1
+// extended or added, and hands the 2.0.1 part of an extended message to the
+// 2.0.1 handler, so both versions share one business logic.
+// ============================================================================
+
+// Keys of the 2.1 transaction fields kept in the transaction metadata
+const (
+	metadataOperationMode    = "operation_mode"
+	metadataTariffId         = "tariff_id"
+	metadataTransactionLimit = "transaction_limit"
+	metadataCostDetails      = "cost_details"
+)
+
+// V21Handlers implements the OCPP 2.1 handler interfaces on top of the 2.0.1 ones
+type V21Handlers struct {
+	*V201Handlers
+}
+
+// NewV21Handlers creates the OCPP 2.1 handlers around the 2.0.1 ones
+func NewV21Handlers(v201Handlers *V201Handlers) *V21Handlers {
+	return &V21Handlers{V201Handlers: v201Handlers}
+}
+
+// OnBootNotification handles OCPP 2.1 BootNotification requests, which are 2.0.1 ones on the wire
+func (h *V21Handlers) OnBootNotification(chargePointId string, request *provisioning.BootNotificationRequest) (*provisioning.BootNotificationResponse, error) {
+	return h.onBootNotification(chargePointId, request, common.OCPP21)
+}
+
+// OnTransactionEvent handles OCPP 2.1 TransactionEvent requests. The transaction is handled as a
+// 2.0.1 one, and the billing service stays in charge of the price; the fields 2.1 added are kept
+// in the transaction metadata, including the cost the station calculated with a local tariff.
+func (h *V21Handlers) OnTransactionEvent(chargePointId string, request *transactions21.TransactionEventRequest) (*transactions21.TransactionEventResponse, error) {
+	// an ended transaction is no longer found among the running ones, so its fields go first
+	if request.EventType == v201.TransactionEventEnded {
+		h.recordTransactionFields(chargePointId, request)
+	}
+	response, err := h.V201Handlers.OnTransactionEvent(chargePointId, request.Base())
+	if err != nil {
+		return nil, err
+	}
+	if request.EventType != v201.TransactionEventEnded {
+		h.recordTransactionFields(chargePointId, request)
+	}
+	return &transactions21.TransactionEventResponse{TransactionEventResponse: *response}, nil
+}
+
+// recordTransactionFields stores the 2.1 fields of a TransactionEvent on the transaction
+func (h *V21Handlers) recordTransactionFields(chargePointId string, request *transactions21.TransactionEventRequest) {
+	info := request.TransactionInfo
+	fields := make(map[string]interface{})
+	if info.OperationMode != "" {
+		fields[metadataOperationMode] = string(info.OperationMode)
+	}
+	if info.TariffId != "" {
+		fields[metadataTariffId] = info.TariffId
+	}
+	if info.TransactionLimit != nil {
+		fields[metadataTransactionLimit] = info.TransactionLimit
+	}
+	if details := request.CostDetails; details != nil {
+		fields[metadataCostDetails] = details
+		if details.Failed() {
+			h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.1: transaction %s: station failed to calculate the cost: %s",
+				info.TransactionId, details.FailureReason))
+		} else if total := details.TotalCost.Total.InclTax; total != nil {
+			h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.1: transaction %s: station calculated %.2f %s",
+				info.TransactionId, *total, details.TotalCost.Currency))
+		}
+	}
+	if len(fields) == 0 || h.systemHandler.database == nil {
+		return
+	}
+
+	transaction := h.systemHandler.sessionTransaction(chargePointId, info.TransactionId)
+	if transaction == nil {
+		return
+	}
+	if transaction.Metadata == nil {
+		transaction.Metadata = make(map[string]interface{})
+	}
+	for key, value := range fields {
+		transaction.Metadata[key] = value
+	}
+	if err := h.systemHandler.database.UpdateTransaction(transaction); err != nil {
+		h.logger.Error("update transaction metadata", err)
+	}
+}
+
+// OnNotifyPriorityCharging handles OCPP 2.1 NotifyPriorityCharging requests. The flag is stored on
+// the transaction, where the load balancer reads it to give the session its highest slot.
+func (h *V21Handlers) OnNotifyPriorityCharging(chargePointId string, request *smartcharging21.NotifyPriorityChargingRequest) (*smartcharging21.NotifyPriorityChargingResponse, error) {
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.1: transaction %s: activated=%v",
+		request.TransactionId, request.Activated))
+
+	response := &smartcharging21.NotifyPriorityChargingResponse{}
+	if h.systemHandler.database == nil {
+		return response, nil
+	}
+	transaction := h.systemHandler.sessionTransaction(chargePointId, request.TransactionId)
+	if transaction == nil {
+		h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.1: unknown transaction %s", request.TransactionId))
+		return response, nil
+	}
+	if err := h.systemHandler.database.UpdateTransactionPriorityCharging(transaction.Id, request.Activated); err != nil {
+		h.logger.Error("update priority charging", err)
+	}
+	return response, nil
+}
+
+// ============================================================================
+// API COMMANDS (CSMS → Charging Station)
+// ============================================================================
+
+// OnSetChargingProfile creates a 2.1 SetChargingProfile request; the payload is the 2.1
+// chargingProfile object, which may discharge the EV, and the connector id of the command is the EVSE
+func (h *V21Handlers) OnSetChargingProfile(chargePointId string, evseId int, payload string) (ocpp.Request, error) {
+	request := &smartcharging21.SetChargingProfileRequest{EvseId: evseId}
+	if err := json.Unmarshal([]byte(payload), &request.ChargingProfile); err != nil {
+		return nil, fmt.Errorf("invalid payload")
+	}
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	profile := request.ChargingProfile
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.1: EVSE=%d, profile %d %s",
+		evseId, profile.Id, profile.ChargingProfilePurpose))
+	return request, nil
+}
+
+// OnSetDefaultTariff creates a SetDefaultTariff request for the EVSE of the command; the payload is
+// the tariff object, or empty to install the default payment plan of the billing service
+func (h *V21Handlers) OnSetDefaultTariff(chargePointId string, evseId int, payload string) (ocpp.Request, error) {
+	request := &tariff.SetDefaultTariffRequest{EvseId: evseId}
+	if strings.TrimSpace(payload) == "" {
+		defaultTariff, err := h.systemHandler.defaultTariff()
+		if err != nil {
+			return nil, err
+		}
+		request.Tariff = *defaultTariff
+	} else if err := json.Unmarshal([]byte(payload), &request.Tariff); err != nil {
+		return nil, fmt.Errorf("invalid payload")
+	}
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.1: EVSE=%d, tariff %s in %s",
+		evseId, request.Tariff.TariffId, request.Tariff.Currency))
+	return request, nil
+}
+
+// OnGetTariffs creates a GetTariffs request for the EVSE of the command; EVSE 0 asks for every EVSE
+func (h *V21Handlers) OnGetTariffs(chargePointId string, evseId int) (ocpp.Request, error) {
+	request := &tariff.GetTariffsRequest{EvseId: evseId}
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.1: EVSE=%d", evseId))
+	return request, nil
+}
+
+// OnClearTariffs creates a ClearTariffs request from a JSON payload holding tariffIds and evseId;
+// an empty payload clears every tariff
+func (h *V21Handlers) OnClearTariffs(chargePointId string, payload string) (ocpp.Request, error) {
+	request := &tariff.ClearTariffsRequest{}
+	if strings.TrimSpace(payload) != "" {
+		if err := json.Unmarshal([]byte(payload), request); err != nil {
+			return nil, fmt.Errorf("invalid payload")
+		}
+	}
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	h.logger.FeatureEvent(request.GetFeatureName(), chargePointId, fmt.Sprintf("v2.1: tariffs %v", request.TariffIds))
+	return request, nil
+}
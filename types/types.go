@@ -5,7 +5,11 @@ import (
 	"time"
 )
 
-const SubProtocol16 = "ocpp1.6"
+const (
+	SubProtocol16  = "ocpp1.6"
+	SubProtocol201 = "ocpp2.0.1"
+	SubProtocol21  = "ocpp2.1"
+)
 
 type AuthorizationStatus string
 